@@ -0,0 +1,539 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// AWSIdentity is the identity of a set of AWS credentials, as returned by sts:GetCallerIdentity.
+type AWSIdentity struct {
+	AccountID string
+	ARN       string
+	UserID    string
+}
+
+// AWSIdentityProvider returns the identity of the AWS credentials in use.
+type AWSIdentityProvider interface {
+	CallerIdentity(ctx context.Context) (AWSIdentity, error)
+}
+
+// StaticAWSIdentity is an AWSIdentityProvider returning a fixed identity, for offline evaluation.
+type StaticAWSIdentity AWSIdentity
+
+func (identity StaticAWSIdentity) CallerIdentity(ctx context.Context) (AWSIdentity, error) {
+	return AWSIdentity(identity), nil
+}
+
+// AWSCredentials are AWS access keys, with the session token and the expiration time of temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Expiration is zero for credentials that do not expire.
+	Expiration time.Time
+}
+
+// AWSCredentialsProvider returns the AWS credentials to sign requests with.
+type AWSCredentialsProvider interface {
+	Credentials(ctx context.Context) (AWSCredentials, error)
+}
+
+// EnvAWSCredentials is an AWSCredentialsProvider reading the credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+type EnvAWSCredentials struct{}
+
+func (EnvAWSCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	credentials := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return credentials, nil
+}
+
+// StaticAWSCredentials is an AWSCredentialsProvider returning fixed credentials.
+type StaticAWSCredentials AWSCredentials
+
+func (credentials StaticAWSCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	return AWSCredentials(credentials), nil
+}
+
+// AWSRole is an IAM role to assume, as configured by the iam_role attribute of a configuration or the role_arn of an
+// s3 remote_state block.
+type AWSRole struct {
+	ARN string
+
+	// SessionName is the name of the role session. Defaults to DefaultRoleSessionName.
+	SessionName string
+
+	// Duration is the validity of the temporary credentials. Defaults to the maximum session duration of the role.
+	Duration time.Duration
+}
+
+// DefaultRoleSessionName is the name of the sessions of the roles assumed without a session name.
+const DefaultRoleSessionName = "terragrunt-utils"
+
+// AssumedRole holds the temporary credentials of an assumed role, and the identity they belong to.
+type AssumedRole struct {
+	Credentials AWSCredentials
+	Identity    AWSIdentity
+}
+
+// RoleAssumer assumes IAM roles. STSClient implements it with sts:AssumeRole; other implementations can be injected
+// in tests or to reuse an existing AWS SDK client.
+type RoleAssumer interface {
+	AssumeRole(ctx context.Context, role AWSRole) (AssumedRole, error)
+}
+
+// STSClient calls the AWS STS query API, signing requests with the given credentials, when set. It is an
+// AWSIdentityProvider and a RoleAssumer.
+type STSClient struct {
+	Credentials AWSCredentialsProvider
+
+	// Region is the region of the STS endpoint to use. Defaults to us-east-1, with the global endpoint.
+	Region string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// RateLimiter, when set, limits the rate of the requests, retries included, e.g. to share the STS quota of an
+	// account among many parses.
+	RateLimiter *RateLimiter
+}
+
+func (client STSClient) CallerIdentity(ctx context.Context) (AWSIdentity, error) {
+	var response struct {
+		Result struct {
+			Account string `xml:"Account"`
+			Arn     string `xml:"Arn"`
+			UserID  string `xml:"UserId"`
+		} `xml:"GetCallerIdentityResult"`
+	}
+	if err := client.call(ctx, url.Values{"Action": {"GetCallerIdentity"}}, &response); err != nil {
+		return AWSIdentity{}, err
+	}
+	return AWSIdentity{AccountID: response.Result.Account, ARN: response.Result.Arn, UserID: response.Result.UserID}, nil
+}
+
+func (client STSClient) AssumeRole(ctx context.Context, role AWSRole) (AssumedRole, error) {
+	sessionName := role.SessionName
+	if sessionName == "" {
+		sessionName = DefaultRoleSessionName
+	}
+	params := url.Values{"Action": {"AssumeRole"}, "RoleArn": {role.ARN}, "RoleSessionName": {sessionName}}
+	if role.Duration > 0 {
+		params.Set("DurationSeconds", strconv.Itoa(int(role.Duration.Seconds())))
+	}
+
+	var response struct {
+		Result assumeRoleResult `xml:"AssumeRoleResult"`
+	}
+	if err := client.call(ctx, params, &response); err != nil {
+		return AssumedRole{}, err
+	}
+	return response.Result.assumedRole(), nil
+}
+
+// AssumeRoleWithWebIdentity assumes the given role with sts:AssumeRoleWithWebIdentity, authenticating with the given
+// OIDC token instead of credentials.
+func (client STSClient) AssumeRoleWithWebIdentity(ctx context.Context, role AWSRole, token string) (AssumedRole, error) {
+	sessionName := role.SessionName
+	if sessionName == "" {
+		sessionName = DefaultRoleSessionName
+	}
+	params := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"RoleArn":          {role.ARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {token},
+	}
+	if role.Duration > 0 {
+		params.Set("DurationSeconds", strconv.Itoa(int(role.Duration.Seconds())))
+	}
+
+	// The request is authenticated by the token, and must not be signed.
+	client.Credentials = nil
+	var response struct {
+		Result assumeRoleResult `xml:"AssumeRoleWithWebIdentityResult"`
+	}
+	if err := client.call(ctx, params, &response); err != nil {
+		return AssumedRole{}, err
+	}
+	return response.Result.assumedRole(), nil
+}
+
+// assumeRoleResult is the result of the sts:AssumeRole and sts:AssumeRoleWithWebIdentity actions.
+type assumeRoleResult struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"Credentials"`
+	User struct {
+		Arn           string `xml:"Arn"`
+		AssumedRoleID string `xml:"AssumedRoleId"`
+	} `xml:"AssumedRoleUser"`
+}
+
+func (result assumeRoleResult) assumedRole() AssumedRole {
+	return AssumedRole{
+		Credentials: AWSCredentials{
+			AccessKeyID:     result.Credentials.AccessKeyID,
+			SecretAccessKey: result.Credentials.SecretAccessKey,
+			SessionToken:    result.Credentials.SessionToken,
+			Expiration:      result.Credentials.Expiration,
+		},
+		Identity: AWSIdentity{AccountID: arnAccountID(result.User.Arn), ARN: result.User.Arn, UserID: result.User.AssumedRoleID},
+	}
+}
+
+// arnAccountID returns the account ID of the given ARN, in the arn:partition:service:region:account-id:resource
+// format, or an empty string when it has none.
+func arnAccountID(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// call sends a signed request for the given STS action, retrying it on transient failures, and decodes the xml
+// response into out.
+func (client STSClient) call(ctx context.Context, params url.Values, out interface{}) error {
+	return client.Retry.Do(ctx, func() error {
+		if err := client.RateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+		return client.callOnce(ctx, params, out)
+	})
+}
+
+func (client STSClient) callOnce(ctx context.Context, params url.Values, out interface{}) error {
+	region, host := client.Region, "sts.amazonaws.com"
+	if region == "" {
+		region = "us-east-1"
+	} else {
+		host = "sts." + region + ".amazonaws.com"
+	}
+	params.Set("Version", "2011-06-15")
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	if client.Credentials != nil {
+		credentials, err := client.Credentials.Credentials(ctx)
+		if err != nil {
+			return err
+		}
+		signAWSRequest(request, []byte(params.Encode()), credentials, region, "sts", time.Now())
+	}
+
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("sts %s: %w", params.Get("Action"), newHTTPStatusError(request, response))
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, out)
+}
+
+// awsJSONRequest is a request to an AWS json API, such as KMS or SSM.
+type awsJSONRequest struct {
+	// Service is the signing name of the service, which is also the prefix of its endpoint (e.g. kms).
+	Service string
+
+	// Region defaults to us-east-1.
+	Region string
+
+	// Target is the action to call, prefixed with the API name, e.g. TrentService.Decrypt.
+	Target string
+
+	// Credentials default to EnvAWSCredentials, and HTTPClient to http.DefaultClient.
+	Credentials AWSCredentialsProvider
+	HTTPClient  *http.Client
+}
+
+// callAWSJSON sends the given json body as a signed request to an AWS json API, and decodes the json response into
+// out.
+func callAWSJSON(ctx context.Context, awsRequest awsJSONRequest, body []byte, out interface{}) error {
+	region := awsRequest.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := "https://" + awsRequest.Service + "." + region + ".amazonaws.com/"
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", awsRequest.Target)
+
+	credentialsProvider := awsRequest.Credentials
+	if credentialsProvider == nil {
+		credentialsProvider = EnvAWSCredentials{}
+	}
+	credentials, err := credentialsProvider.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	signAWSRequest(request, body, credentials, region, awsRequest.Service, time.Now())
+
+	httpClient := awsRequest.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		_, action, _ := strings.Cut(awsRequest.Target, ".")
+		return fmt.Errorf("%s %s: %w", awsRequest.Service, action, newHTTPStatusError(request, response))
+	}
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(responseBody, out)
+}
+
+// signAWSRequest signs the given request with the AWS signature version 4.
+func signAWSRequest(request *http.Request, body []byte, credentials AWSCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	// Only S3 requires the hash of the payload as a header, which the other services sign as any other header.
+	if service == "s3" {
+		request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(request.Header.Get(name))
+	}
+	var headerNames []string
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	// S3 signs the object key as is, while the other services sign the normalized path.
+	canonicalPath := request.URL.Path
+	if service != "s3" {
+		canonicalPath = normalizeAWSPath(canonicalPath)
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsEscapePath(canonicalPath),
+		awsCanonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsEscapePath returns the given path with each segment URI encoded as required by the AWS signature version 4:
+// every byte but the unreserved characters is percent encoded, including the reserved ones url.URL leaves as is in
+// paths, such as ':' and '='.
+func awsEscapePath(requestPath string) string {
+	if requestPath == "" {
+		return "/"
+	}
+	segments := strings.Split(requestPath, "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// normalizeAWSPath removes the empty, "." and ".." segments of the given path, keeping its trailing slash.
+func normalizeAWSPath(requestPath string) string {
+	normalized := path.Clean("/" + requestPath)
+	if strings.HasSuffix(requestPath, "/") && normalized != "/" {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// awsCanonicalQuery returns the canonical query string of the AWS signature version 4: the URI encoded parameters,
+// sorted by name, then by value.
+func awsCanonicalQuery(query url.Values) string {
+	type parameter struct{ name, value string }
+	var parameters []parameter
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, parameter{awsURIEncode(name), awsURIEncode(value)})
+		}
+	}
+	sort.Slice(parameters, func(i, j int) bool {
+		if parameters[i].name != parameters[j].name {
+			return parameters[i].name < parameters[j].name
+		}
+		return parameters[i].value < parameters[j].value
+	})
+
+	encoded := make([]string, len(parameters))
+	for i, parameter := range parameters {
+		encoded[i] = parameter.name + "=" + parameter.value
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsURIEncode percent encodes every byte of the given string but the unreserved characters A-Z, a-z, 0-9, '-', '.',
+// '_' and '~'.
+func awsURIEncode(value string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			encoded.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", c)
+	}
+	return encoded.String()
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// memoizedAWSIdentity caches the identity returned by an AWSIdentityProvider, so that it is only retrieved once per
+// parse no matter how many times the identity functions are called. Errors are not cached: a failed retrieval, e.g. a
+// throttled or cancelled STS call, is retried by the next call.
+type memoizedAWSIdentity struct {
+	provider AWSIdentityProvider
+
+	mu       sync.Mutex
+	found    bool
+	identity AWSIdentity
+}
+
+func (memoized *memoizedAWSIdentity) CallerIdentity(ctx context.Context) (AWSIdentity, error) {
+	memoized.mu.Lock()
+	defer memoized.mu.Unlock()
+	if memoized.found {
+		return memoized.identity, nil
+	}
+	identity, err := memoized.provider.CallerIdentity(ctx)
+	if err != nil {
+		return AWSIdentity{}, err
+	}
+	memoized.identity, memoized.found = identity, true
+	return identity, nil
+}
+
+// awsIdentity returns the identity backing the AWS identity functions: the one of the role declared by the
+// configuration when roles are assumed, or else the one of the AWSIdentityProvider.
+func (opts *ParseOptions) awsIdentity() (AWSIdentity, error) {
+	if opts.AssumeRoleCache != nil {
+		if opts.iamRole != nil {
+			assumed, err := opts.AssumeRoleCache.AssumeRole(opts.Context, opts.originalDir(), *opts.iamRole)
+			return assumed.Identity, err
+		}
+		if !opts.iamRoleDecoded {
+			opts.identityBeforeIAMRole = true
+		}
+	}
+	if opts.AWSIdentityProvider == nil {
+		return AWSIdentity{}, errors.New("no AWS identity provider is configured")
+	}
+	return opts.AWSIdentityProvider.CallerIdentity(opts.Context)
+}
+
+// awsIdentityFunctions returns the get_aws_account_id(), get_aws_caller_identity_arn() and
+// get_aws_caller_identity_user_id() functions, backed by the configured AWSIdentityProvider, or the role of the
+// configuration. See awsIdentity.
+func awsIdentityFunctions(opts *ParseOptions) map[string]function.Function {
+	identityFunc := func(field func(AWSIdentity) string) function.Function {
+		return function.New(&function.Spec{
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				identity, err := opts.awsIdentity()
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(field(identity)), nil
+			},
+		})
+	}
+
+	return map[string]function.Function{
+		"get_aws_account_id":              identityFunc(func(identity AWSIdentity) string { return identity.AccountID }),
+		"get_aws_caller_identity_arn":     identityFunc(func(identity AWSIdentity) string { return identity.ARN }),
+		"get_aws_caller_identity_user_id": identityFunc(func(identity AWSIdentity) string { return identity.UserID }),
+	}
+}
@@ -0,0 +1,376 @@
+package terragrunt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// SharedConfigCredentials is an AWSCredentialsProvider reading the credentials of a named profile from the shared
+// AWS config and credentials files, as the AWS CLI does. Profiles can hold static keys, or assume a role with the
+// credentials of a source_profile, of the environment (credential_source = Environment) or with a
+// web_identity_token_file. SSO and credential_process profiles are not supported. Temporary credentials are cached
+// until shortly before they expire, and it is safe for concurrent use.
+type SharedConfigCredentials struct {
+	// Profile is the name of the profile. Defaults to the AWS_PROFILE environment variable, or else default.
+	Profile string
+
+	// CredentialsFile and ConfigFile are the paths of the shared files. They default to the AWS_SHARED_CREDENTIALS_FILE
+	// and AWS_CONFIG_FILE environment variables, or else to ~/.aws/credentials and ~/.aws/config.
+	CredentialsFile string
+	ConfigFile      string
+
+	// STS is the client the roles of the profiles are assumed with. Its credentials are the ones of the source of
+	// each role.
+	STS STSClient
+
+	cache refreshingCredentials
+}
+
+// maxSourceProfileDepth bounds the chains of source_profile, which can loop.
+const maxSourceProfileDepth = 5
+
+func (provider *SharedConfigCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	return provider.cache.get(ctx, func(ctx context.Context) (AWSCredentials, error) {
+		profile := provider.Profile
+		if profile == "" {
+			profile = os.Getenv("AWS_PROFILE")
+		}
+		if profile == "" {
+			profile = "default"
+		}
+		return provider.profileCredentials(ctx, profile, 0)
+	})
+}
+
+func (provider *SharedConfigCredentials) profileCredentials(ctx context.Context, name string, depth int) (AWSCredentials, error) {
+	if depth > maxSourceProfileDepth {
+		return AWSCredentials{}, fmt.Errorf("too many nested source_profile, the last being %s", name)
+	}
+	settings, err := provider.profileSettings(name)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	roleARN := settings["role_arn"]
+	if roleARN == "" || (settings["source_profile"] == name && settings["aws_access_key_id"] != "") {
+		if settings["aws_access_key_id"] == "" || settings["aws_secret_access_key"] == "" {
+			return AWSCredentials{}, fmt.Errorf("AWS profile %s has no credentials (sso and credential_process profiles are not supported)", name)
+		}
+		return AWSCredentials{
+			AccessKeyID:     settings["aws_access_key_id"],
+			SecretAccessKey: settings["aws_secret_access_key"],
+			SessionToken:    settings["aws_session_token"],
+		}, nil
+	}
+
+	role := AWSRole{ARN: roleARN, SessionName: settings["role_session_name"]}
+	if seconds, err := strconv.Atoi(settings["duration_seconds"]); err == nil {
+		role.Duration = time.Duration(seconds) * time.Second
+	}
+
+	if tokenFile := settings["web_identity_token_file"]; tokenFile != "" {
+		assumed, err := assumeRoleWithWebIdentityFile(ctx, provider.STS, role, tokenFile)
+		return assumed.Credentials, err
+	}
+
+	sts := provider.STS
+	switch {
+	case settings["source_profile"] != "":
+		source, err := provider.profileCredentials(ctx, settings["source_profile"], depth+1)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		sts.Credentials = StaticAWSCredentials(source)
+	case settings["credential_source"] == "Environment":
+		sts.Credentials = EnvAWSCredentials{}
+	default:
+		return AWSCredentials{}, fmt.Errorf("AWS profile %s has a role_arn, but no source_profile, supported credential_source or web_identity_token_file", name)
+	}
+	assumed, err := sts.AssumeRole(ctx, role)
+	return assumed.Credentials, err
+}
+
+// profileSettings returns the settings of the given profile, from the config file, overridden by the credentials
+// file.
+func (provider *SharedConfigCredentials) profileSettings(name string) (map[string]string, error) {
+	configFile := sharedAWSFile(provider.ConfigFile, "AWS_CONFIG_FILE", "config")
+	credentialsFile := sharedAWSFile(provider.CredentialsFile, "AWS_SHARED_CREDENTIALS_FILE", "credentials")
+
+	configSections, err := readINIFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	credentialsSections, err := readINIFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles are named "profile <name>" in the config file, but for the default one.
+	configSection, found := configSections["profile "+name]
+	if name == "default" && !found {
+		configSection, found = configSections["default"]
+	}
+	credentialsSection, inCredentials := credentialsSections[name]
+	if !found && !inCredentials {
+		return nil, fmt.Errorf("AWS profile %s not found in %s or %s", name, configFile, credentialsFile)
+	}
+
+	settings := map[string]string{}
+	for key, value := range configSection {
+		settings[key] = value
+	}
+	for key, value := range credentialsSection {
+		settings[key] = value
+	}
+	return settings, nil
+}
+
+// sharedAWSFile returns the path of a shared AWS file: the given path, the one of the given environment variable, or
+// else the given file of the ~/.aws directory.
+func sharedAWSFile(path string, envVar string, name string) string {
+	if path != "" {
+		return path
+	}
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".aws", name)
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readINIFile reads the sections of the given ini file, keyed by name, as maps of their keys to their values. A
+// missing file has no sections. Nested settings (e.g. of the s3 section of a profile) are not supported, and ignored.
+func readINIFile(path string) (map[string]map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			section = map[string]string{}
+			sections[name] = section
+		case section != nil && !startsWithSpace(scanner.Text()):
+			if key, value, found := strings.Cut(line, "="); found {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return sections, scanner.Err()
+}
+
+func startsWithSpace(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// WebIdentityCredentials is an AWSCredentialsProvider assuming a role with an OIDC token read from a file, e.g. the
+// token of a Kubernetes service account or of a CI job. The token file is read again each time the credentials are
+// renewed, as it is rotated. It is safe for concurrent use.
+type WebIdentityCredentials struct {
+	// Role is the role to assume. Its ARN defaults to the AWS_ROLE_ARN environment variable, and its session name to
+	// AWS_ROLE_SESSION_NAME.
+	Role AWSRole
+
+	// TokenFile is the path of the file holding the token. Defaults to the AWS_WEB_IDENTITY_TOKEN_FILE environment
+	// variable.
+	TokenFile string
+
+	// STS is the client the role is assumed with. Its credentials are not used.
+	STS STSClient
+
+	cache refreshingCredentials
+}
+
+func (provider *WebIdentityCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	return provider.cache.get(ctx, func(ctx context.Context) (AWSCredentials, error) {
+		role := provider.Role
+		if role.ARN == "" {
+			role.ARN = os.Getenv("AWS_ROLE_ARN")
+		}
+		if role.SessionName == "" {
+			role.SessionName = os.Getenv("AWS_ROLE_SESSION_NAME")
+		}
+		tokenFile := provider.TokenFile
+		if tokenFile == "" {
+			tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		}
+		if role.ARN == "" || tokenFile == "" {
+			return AWSCredentials{}, fmt.Errorf("the role and the token file of the web identity are not set")
+		}
+		assumed, err := assumeRoleWithWebIdentityFile(ctx, provider.STS, role, tokenFile)
+		return assumed.Credentials, err
+	})
+}
+
+func assumeRoleWithWebIdentityFile(ctx context.Context, sts STSClient, role AWSRole, tokenFile string) (AssumedRole, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AssumedRole{}, fmt.Errorf("reading the web identity token: %w", err)
+	}
+	return sts.AssumeRoleWithWebIdentity(ctx, role, strings.TrimSpace(string(token)))
+}
+
+// refreshingCredentials caches temporary credentials until shortly before they expire.
+type refreshingCredentials struct {
+	mu          sync.Mutex
+	credentials AWSCredentials
+}
+
+// get returns the cached credentials, or the ones returned by fetch when they are missing or about to expire.
+// Failures are not cached.
+func (cache *refreshingCredentials) get(ctx context.Context, fetch func(ctx context.Context) (AWSCredentials, error)) (AWSCredentials, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if credentialsFresh(cache.credentials) {
+		return cache.credentials, nil
+	}
+	credentials, err := fetch(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	cache.credentials = credentials
+	return credentials, nil
+}
+
+// UnitAWSCredentials selects the AWS credentials of each unit, by profile or web identity, so that a single process
+// resolves the outputs of units living in several accounts. The credentials of a unit are the ones of the first rule
+// matching it, or else the ones of the profile or the web identity of its s3 remote_state config, if any. It is safe
+// for concurrent use, and the credentials of each profile and web identity are shared by the units using them.
+type UnitAWSCredentials struct {
+	Rules []AWSCredentialsRule
+
+	// STS is the client the roles of the profiles and web identities are assumed with.
+	STS STSClient
+
+	mu        sync.Mutex
+	providers map[string]AWSCredentialsProvider
+}
+
+// AWSCredentialsRule selects the credentials of the units matching a pattern: the ones of a profile, or of a web
+// identity.
+type AWSCredentialsRule struct {
+	// Units is matched against the absolute path of the directory of units, with * matching any sequence of
+	// characters, slashes included (e.g. */live/prod/*).
+	Units string
+
+	// Profile is the name of the profile of the units, in the shared AWS files.
+	Profile string
+
+	// WebIdentity is the web identity of the units, when Profile is not set.
+	WebIdentity *WebIdentityCredentials
+}
+
+// ForUnit returns the credentials selected for the unit with the given config path and configuration, or nil when
+// none is.
+func (selector *UnitAWSCredentials) ForUnit(configPath string, config *TerragruntConfig) AWSCredentialsProvider {
+	unitDir := configDir(configPath)
+	if absDir, err := filepath.Abs(unitDir); err == nil {
+		unitDir = absDir
+	}
+	for _, rule := range selector.Rules {
+		if matchingSourcePattern(sourcePatterns([]string{rule.Units}), filepath.ToSlash(unitDir)) == "" {
+			continue
+		}
+		if rule.Profile != "" {
+			return selector.profile(rule.Profile, "", "")
+		}
+		if rule.WebIdentity != nil {
+			return rule.WebIdentity
+		}
+	}
+
+	if config == nil || config.RemoteState == nil || config.RemoteState.Backend != "s3" {
+		return nil
+	}
+	backend, err := backendConfig(config.RemoteState)
+	if err != nil {
+		return nil
+	}
+	if webIdentity, found := backend["assume_role_with_web_identity"]; found && webIdentity.CanIterateElements() {
+		attributes := webIdentity.AsValueMap()
+		role := AWSRole{ARN: backendString(attributes, "role_arn"), SessionName: backendString(attributes, "session_name")}
+		if duration, err := time.ParseDuration(backendString(attributes, "duration")); err == nil {
+			role.Duration = duration
+		}
+		return selector.webIdentity(role, backendString(attributes, "web_identity_token_file"))
+	}
+	if profile := backendString(backend, "profile"); profile != "" {
+		return selector.profile(profile, firstBackendString(backend, "shared_credentials_files", "shared_credentials_file"), firstBackendString(backend, "shared_config_files"))
+	}
+	return nil
+}
+
+// profile returns the provider of the given profile, read from the given files, sharing it with the other units.
+func (selector *UnitAWSCredentials) profile(name string, credentialsFile string, configFile string) AWSCredentialsProvider {
+	key := strings.Join([]string{"profile", name, credentialsFile, configFile}, "\x00")
+	return selector.provider(key, func() AWSCredentialsProvider {
+		return &SharedConfigCredentials{Profile: name, CredentialsFile: credentialsFile, ConfigFile: configFile, STS: selector.STS}
+	})
+}
+
+// webIdentity returns the provider of the given web identity, sharing it with the other units.
+func (selector *UnitAWSCredentials) webIdentity(role AWSRole, tokenFile string) AWSCredentialsProvider {
+	key := strings.Join([]string{"web_identity", role.ARN, role.SessionName, role.Duration.String(), tokenFile}, "\x00")
+	return selector.provider(key, func() AWSCredentialsProvider {
+		return &WebIdentityCredentials{Role: role, TokenFile: tokenFile, STS: selector.STS}
+	})
+}
+
+func (selector *UnitAWSCredentials) provider(key string, newProvider func() AWSCredentialsProvider) AWSCredentialsProvider {
+	selector.mu.Lock()
+	defer selector.mu.Unlock()
+
+	if provider, found := selector.providers[key]; found {
+		return provider
+	}
+	if selector.providers == nil {
+		selector.providers = map[string]AWSCredentialsProvider{}
+	}
+	provider := newProvider()
+	selector.providers[key] = provider
+	return provider
+}
+
+// firstBackendString returns the first string of the first of the given keys of the backend config that is set,
+// either to a string or to a list of strings.
+func firstBackendString(config map[string]cty.Value, keys ...string) string {
+	for _, key := range keys {
+		value, found := config[key]
+		if !found || value.IsNull() {
+			continue
+		}
+		if value.Type() == cty.String {
+			return value.AsString()
+		}
+		if value.CanIterateElements() && value.LengthInt() > 0 {
+			if first := value.AsValueSlice()[0]; first.Type() == cty.String && !first.IsNull() {
+				return first.AsString()
+			}
+		}
+	}
+	return ""
+}
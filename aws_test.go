@@ -0,0 +1,107 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignAWSRequest checks the signatures against the AWS signature version 4 test suite.
+func TestSignAWSRequest(t *testing.T) {
+	credentials := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{name: "get-vanilla", url: "/", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-utf8", url: "/ሴ", signature: "8318018e0b0f223aa2bbf98705b62bb787dc9c0e678f255a891fd03141be5d85"},
+		{name: "get-slash", url: "//", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-slashes", url: "//example//", signature: "9a624bd73a37c9a373b5312afbebe7a714a789de108f0bdfe846570885f57e84"},
+		{name: "get-slash-dot-slash", url: "/./", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-relative-relative", url: "/example1/example2/../..", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-space", url: "/example space/", signature: "652487583200325589f1fba4c7e578f72c47cb61beeca81406b39ddec1366741"},
+		{name: "get-unreserved", url: "/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", signature: "07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{name: "get-vanilla-empty-query-key", url: "/?Param1=value1", signature: "a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb"},
+		{name: "get-vanilla-query-order-key-case", url: "/?Param2=value2&Param1=value1", signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{name: "get-vanilla-query-unreserved", url: "/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", signature: "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The paths of the test suite are not valid URLs, and are set as is.
+			path, query, _ := strings.Cut(test.url, "?")
+			request.URL.Path, request.URL.RawQuery = path, query
+
+			signAWSRequest(request, nil, credentials, "us-east-1", "service", now)
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + test.signature
+			if got := request.Header.Get("Authorization"); got != want {
+				t.Errorf("got authorization %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAWSCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: ""},
+		{query: "Param2=value2&Param1=value1", want: "Param1=value1&Param2=value2"},
+		{query: "Param1=value2&Param1=Value1", want: "Param1=Value1&Param1=value2"},
+		{query: "b=1&a-b=2&a=3", want: "a=3&a-b=2&b=1"},
+		{query: "key=a+b%3Dc%2F~", want: "key=a%20b%3Dc%2F~"},
+	}
+
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := awsCanonicalQuery(query); got != test.want {
+			t.Errorf("awsCanonicalQuery(%q): got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+// flakyAWSIdentity is an AWSIdentityProvider failing its first call, counting the calls.
+type flakyAWSIdentity struct {
+	calls int
+}
+
+func (provider *flakyAWSIdentity) CallerIdentity(ctx context.Context) (AWSIdentity, error) {
+	provider.calls++
+	if provider.calls == 1 {
+		return AWSIdentity{}, errors.New("Throttling: Rate exceeded")
+	}
+	return AWSIdentity{AccountID: "123456789012"}, nil
+}
+
+func TestMemoizedAWSIdentity(t *testing.T) {
+	provider := &flakyAWSIdentity{}
+	memoized := &memoizedAWSIdentity{provider: provider}
+
+	if _, err := memoized.CallerIdentity(context.Background()); err == nil || err.Error() != "Throttling: Rate exceeded" {
+		t.Fatalf("got error %v, want the error of the provider", err)
+	}
+	for i := 0; i < 2; i++ {
+		identity, err := memoized.CallerIdentity(context.Background())
+		if err != nil || identity.AccountID != "123456789012" {
+			t.Errorf("got identity %+v and error %v after the failure, want the identity", identity, err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("got %d calls of the provider, want the failed one and a single successful one", provider.calls)
+	}
+}
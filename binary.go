@@ -0,0 +1,110 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Binary is the terraform or OpenTofu binary running the terraform commands of a unit.
+type Binary struct {
+	// Name is the binary as configured by terraform_binary, or as detected (tofu or terraform).
+	Name string
+
+	// Path is the absolute path of the binary.
+	Path string
+
+	// OpenTofu is true when the binary is OpenTofu rather than Terraform.
+	OpenTofu bool
+
+	// Version is the version reported by the binary, without the v prefix (e.g. 1.6.2).
+	Version string
+}
+
+// ResolveBinary returns the binary running the terraform commands of the unit with the given configuration, as
+// terragrunt selects it: the terraform_binary of the configuration, which is inherited from its includes, or else the
+// binary set by the TG_TF_PATH (or TERRAGRUNT_TFPATH) environment variable, or else tofu when it is on the PATH, and
+// terraform otherwise. It fails when the binary can not be found, or does not report its version. The binary is looked
+// up and run with the CommandRunner of the given options (see WithCommandRunner).
+func ResolveBinary(config *TerragruntConfig, opts ...Option) (*Binary, error) {
+	runner := commandRunner(newParseOptions(opts).CommandRunner)
+	name := config.TerraformBinary
+	for _, envVar := range []string{"TG_TF_PATH", "TERRAGRUNT_TFPATH"} {
+		if name == "" {
+			name = os.Getenv(envVar)
+		}
+	}
+	if name == "" {
+		name = "terraform"
+		if _, err := runner.LookPath("tofu"); err == nil {
+			name = "tofu"
+		}
+	}
+
+	path, err := runner.LookPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("resolving the terraform binary %s: %w", name, err)
+	}
+	if absPath, err := filepath.Abs(path); err == nil && filepath.Base(path) != path {
+		path = absPath
+	}
+
+	binary, err := binaryVersion(runner, path)
+	if err != nil {
+		return nil, err
+	}
+	binary.Name = name
+	return &binary, nil
+}
+
+// Satisfies returns whether the version of the binary satisfies the given comma separated version constraints (e.g.
+// ">= 1.5, < 2.0"), such as the terraform_version_constraint of a configuration or the required_version of a module.
+func (binary *Binary) Satisfies(constraints string) bool {
+	var versions versionRange
+	versions.add(constraints)
+	return versions.contains(binary.Version)
+}
+
+// binaryVersionPattern matches the first line of the output of the version command of terraform and OpenTofu.
+var binaryVersionPattern = regexp.MustCompile(`^(Terraform|OpenTofu) v(\S+)`)
+
+// binaryVersions caches the binaries run by ExecCommandRunner by path, so that the version command only runs once per
+// binary, no matter how many units use it.
+var (
+	binaryVersionsMutex sync.Mutex
+	binaryVersions      = map[string]Binary{}
+)
+
+// binaryVersion returns the binary at the given path, with its version, as reported by running it with the given
+// runner.
+func binaryVersion(runner CommandRunner, path string) (Binary, error) {
+	_, cached := runner.(ExecCommandRunner)
+	if cached {
+		binaryVersionsMutex.Lock()
+		defer binaryVersionsMutex.Unlock()
+
+		if binary, found := binaryVersions[path]; found {
+			return binary, nil
+		}
+	}
+
+	output, err := runner.Run(context.Background(), Command{Name: path, Args: []string{"version"}})
+	if err != nil {
+		return Binary{}, fmt.Errorf("%s version: %w: %s", path, err, bytes.TrimSpace(output.Stderr))
+	}
+
+	match := binaryVersionPattern.FindSubmatch(bytes.TrimSpace(output.Stdout))
+	if match == nil {
+		return Binary{}, fmt.Errorf("%s version: unexpected output %q", path, strings.SplitN(string(output.Stdout), "\n", 2)[0])
+	}
+	binary := Binary{Path: path, OpenTofu: string(match[1]) == "OpenTofu", Version: string(match[2])}
+	if cached {
+		binaryVersions[path] = binary
+	}
+	return binary, nil
+}
@@ -0,0 +1,301 @@
+package terragrunt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutputCache stores the outputs of dependencies, in the json format produced by `terraform output -json`, keyed by
+// the absolute config path of the dependency.
+type OutputCache interface {
+	// Get returns the outputs stored for the given key, and whether they were found and have not expired.
+	Get(key string) ([]byte, bool, error)
+
+	// Set stores the outputs for the given key. They expire after the given ttl, or never when it is zero.
+	Set(key string, outputs []byte, ttl time.Duration) error
+}
+
+// CachingOutputResolver wraps an OutputResolver, caching the outputs it returns. Concurrent lookups of the same
+// dependency are deduplicated, so that parsing many units that depend on the same module only retrieves its outputs
+// once.
+type CachingOutputResolver struct {
+	Resolver OutputResolver
+	Cache    OutputCache
+	TTL      time.Duration
+
+	// Logger receives the cache hit and miss events. Events are discarded when it is nil.
+	Logger Logger
+
+	// Metrics receive the MetricCacheRequests counter. It is discarded when nil.
+	Metrics Metrics
+
+	group singleflightGroup
+}
+
+// NewCachingOutputResolver returns a CachingOutputResolver caching the outputs of the given resolver in the given
+// cache for the given ttl.
+func NewCachingOutputResolver(resolver OutputResolver, cache OutputCache, ttl time.Duration) *CachingOutputResolver {
+	return &CachingOutputResolver{
+		Resolver: resolver,
+		Cache:    cache,
+		TTL:      ttl,
+	}
+}
+
+func (resolver *CachingOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	return resolver.resolve(ctx, configPath, configPath, func(ctx context.Context) ([]byte, error) {
+		return resolver.Resolver.ResolveOutputs(ctx, configPath)
+	})
+}
+
+// ResolveOutputKeys returns the given outputs, filtered from every output of the module when they are cached, and
+// else retrieved and cached on their own when the Resolver is a KeyedOutputResolver. The outputs of other resolvers
+// are retrieved and cached whole, then filtered.
+func (resolver *CachingOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	keyedResolver, isKeyed := resolver.Resolver.(KeyedOutputResolver)
+	if !isKeyed {
+		return resolveOutputKeys(ctx, OutputResolverFunc(resolver.ResolveOutputs), configPath, keys)
+	}
+	// Only a hit on the whole outputs is recorded here: a miss is recorded once, by the lookup of the given keys.
+	if outputs, found, err := resolver.Cache.Get(configPath); err == nil && found {
+		resolver.record(configPath, true)
+		return filterOutputKeys(outputs, keys)
+	}
+	return resolver.resolve(ctx, configPath, outputKeysCacheKey(configPath, keys), func(ctx context.Context) ([]byte, error) {
+		return keyedResolver.ResolveOutputKeys(ctx, configPath, keys)
+	})
+}
+
+// resolve returns the outputs of the dependency at the given config path cached under the given key, retrieving and
+// caching them with the given function when they are not. The retrieval of a concurrent caller whose context was
+// cancelled is retried, unless the context of this caller is done too.
+func (resolver *CachingOutputResolver) resolve(ctx context.Context, configPath string, key string, retrieve func(context.Context) ([]byte, error)) ([]byte, error) {
+	for {
+		ran := false
+		outputs, err := resolver.group.do(key, func() ([]byte, error) {
+			ran = true
+			outputs, found, err := resolver.get(configPath, key)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				return outputs, nil
+			}
+			resolver.logger().Log(EventCacheMiss, "config_path", configPath)
+
+			outputs, err = retrieve(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if err := resolver.Cache.Set(key, outputs, resolver.TTL); err != nil {
+				return nil, err
+			}
+			return outputs, nil
+		})
+		if !ran && isContextError(err) && ctx.Err() == nil {
+			continue
+		}
+		return outputs, err
+	}
+}
+
+// get returns the outputs of the dependency at the given config path cached under the given key, and whether they
+// were found, recording the request.
+func (resolver *CachingOutputResolver) get(configPath string, key string) ([]byte, bool, error) {
+	outputs, found, err := resolver.Cache.Get(key)
+	if err != nil {
+		return nil, false, err
+	}
+	resolver.record(configPath, found)
+	return outputs, found, nil
+}
+
+// record counts a request of the outputs of the dependency at the given config path, and logs it when it is a hit.
+func (resolver *CachingOutputResolver) record(configPath string, found bool) {
+	metrics := resolver.Metrics
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+	metrics.IncCounter(MetricCacheRequests, map[string]string{"cache": "output", "result": cacheResult(found)})
+	if found {
+		resolver.logger().Log(EventCacheHit, "config_path", configPath)
+	}
+}
+
+func (resolver *CachingOutputResolver) logger() Logger {
+	if resolver.Logger == nil {
+		return nopLogger{}
+	}
+	return resolver.Logger
+}
+
+// MemoryOutputCache is an OutputCache keeping the outputs in memory. It is safe for concurrent use.
+type MemoryOutputCache struct {
+	mu      sync.Mutex
+	entries map[string]outputCacheEntry
+}
+
+// NewMemoryOutputCache returns an empty MemoryOutputCache.
+func NewMemoryOutputCache() *MemoryOutputCache {
+	return &MemoryOutputCache{entries: map[string]outputCacheEntry{}}
+}
+
+func (cache *MemoryOutputCache) Get(key string) ([]byte, bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, found := cache.entries[key]
+	if !found {
+		return nil, false, nil
+	}
+	if entry.expired(time.Now()) {
+		delete(cache.entries, key)
+		return nil, false, nil
+	}
+	return entry.Outputs, true, nil
+}
+
+func (cache *MemoryOutputCache) Set(key string, outputs []byte, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.entries[key] = newOutputCacheEntry(outputs, ttl)
+	return nil
+}
+
+// DiskOutputCache is an OutputCache storing the outputs as files in a directory, so that they can be reused across
+// processes.
+type DiskOutputCache struct {
+	Dir string
+}
+
+// NewDiskOutputCache returns a DiskOutputCache storing its entries in the given directory, which is created if needed.
+func NewDiskOutputCache(dir string) (*DiskOutputCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskOutputCache{Dir: dir}, nil
+}
+
+func (cache *DiskOutputCache) Get(key string) ([]byte, bool, error) {
+	content, err := os.ReadFile(cache.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry outputCacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		// A corrupted entry is treated as a miss, so that it gets replaced.
+		return nil, false, nil
+	}
+	if entry.expired(time.Now()) {
+		return nil, false, nil
+	}
+	return entry.Outputs, true, nil
+}
+
+func (cache *DiskOutputCache) Set(key string, outputs []byte, ttl time.Duration) error {
+	content, err := json.Marshal(newOutputCacheEntry(outputs, ttl))
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent readers never see a partially written entry.
+	tmpFile, err := os.CreateTemp(cache.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), cache.path(key))
+}
+
+func (cache *DiskOutputCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(cache.Dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// outputCacheEntry is a cached set of outputs along with its expiration time. A zero ExpiresAt never expires.
+type outputCacheEntry struct {
+	Outputs   json.RawMessage `json:"outputs"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+func newOutputCacheEntry(outputs []byte, ttl time.Duration) outputCacheEntry {
+	entry := outputCacheEntry{Outputs: outputs}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	return entry
+}
+
+func (entry outputCacheEntry) expired(now time.Time) bool {
+	return !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt)
+}
+
+// singleflightGroup deduplicates concurrent calls sharing the same key: while a call is in flight, other callers with
+// the same key wait for it and receive its result.
+type singleflightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*singleflightCall
+}
+
+type singleflightCall struct {
+	wg     sync.WaitGroup
+	result []byte
+	err    error
+}
+
+func (group *singleflightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	group.mu.Lock()
+	if group.calls == nil {
+		group.calls = map[string]*singleflightCall{}
+	}
+	if call, found := group.calls[key]; found {
+		group.mu.Unlock()
+		call.wg.Wait()
+		return call.result, call.err
+	}
+
+	call := &singleflightCall{}
+	call.wg.Add(1)
+	group.calls[key] = call
+	group.mu.Unlock()
+
+	// The call is released even when fn panics, so that the waiters and the later callers are not blocked. The panic
+	// goes on in this caller, and the waiters receive an error instead of an empty result.
+	returned := false
+	defer func() {
+		if !returned {
+			call.err = errSingleflightPanic
+		}
+		group.mu.Lock()
+		delete(group.calls, key)
+		group.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.result, call.err = fn()
+	returned = true
+	return call.result, call.err
+}
+
+// errSingleflightPanic is the error of the callers waiting for a call that panicked.
+var errSingleflightPanic = errors.New("the concurrent call sharing this key panicked")
@@ -0,0 +1,177 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleflightGroupPanic(t *testing.T) {
+	var group singleflightGroup
+	started, release := make(chan struct{}), make(chan struct{})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		group.do("key", func() ([]byte, error) {
+			close(started)
+			<-release
+			panic("resolver bug")
+		})
+	}()
+
+	<-started
+	waiterErr := make(chan error)
+	go func() {
+		_, err := group.do("key", func() ([]byte, error) { return []byte("not shared"), nil })
+		waiterErr <- err
+	}()
+	// Give the waiter time to wait for the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if value := <-panicked; value != "resolver bug" {
+		t.Errorf("got panic %v, want the panic of the resolver", value)
+	}
+	select {
+	case err := <-waiterErr:
+		if !errors.Is(err, errSingleflightPanic) {
+			t.Errorf("got error %v for the waiter, want %v", err, errSingleflightPanic)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter is blocked after the call panicked")
+	}
+
+	result, err := group.do("key", func() ([]byte, error) { return []byte("outputs"), nil })
+	if err != nil || string(result) != "outputs" {
+		t.Errorf("got %q and error %v after the panic, want the result of a new call", result, err)
+	}
+}
+
+func TestCachingOutputResolverLeaderCancellation(t *testing.T) {
+	started := make(chan struct{})
+	var calls int32
+	resolver := NewCachingOutputResolver(OutputResolverFunc(func(ctx context.Context, configPath string) ([]byte, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte(`{"name":{"value":"vpc"}}`), nil
+	}), NewMemoryOutputCache(), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := resolver.ResolveOutputs(ctx, "/vpc/terragrunt.hcl")
+		leaderErr <- err
+	}()
+	<-started
+
+	type result struct {
+		outputs []byte
+		err     error
+	}
+	waiterResult := make(chan result)
+	go func() {
+		outputs, err := resolver.ResolveOutputs(context.Background(), "/vpc/terragrunt.hcl")
+		waiterResult <- result{outputs, err}
+	}()
+	// Give the waiter time to wait for the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v for the cancelled caller, want the cancellation", err)
+	}
+	select {
+	case result := <-waiterResult:
+		if result.err != nil || string(result.outputs) != `{"name":{"value":"vpc"}}` {
+			t.Errorf("got %s and error %v for the waiter, want the outputs", result.outputs, result.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter is blocked after the cancellation")
+	}
+}
+
+// countingMetrics is a Metrics counting the increments of each counter by result label.
+type countingMetrics struct {
+	mu       sync.Mutex
+	counters map[string]int
+}
+
+func (metrics *countingMetrics) IncCounter(name string, labels map[string]string) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if metrics.counters == nil {
+		metrics.counters = map[string]int{}
+	}
+	metrics.counters[name+" "+labels["result"]]++
+}
+
+func (metrics *countingMetrics) ObserveHistogram(name string, value float64, labels map[string]string) {
+}
+
+func TestCachingOutputResolverKeyedRequests(t *testing.T) {
+	keyed := &recordingResolver{}
+	metrics := &countingMetrics{}
+	resolver := NewCachingOutputResolver(keyed, NewMemoryOutputCache(), 0)
+	resolver.Metrics = metrics
+
+	steps := []struct {
+		name    string
+		resolve func() ([]byte, error)
+		hits    int
+		misses  int
+	}{
+		{
+			name: "keys not cached",
+			resolve: func() ([]byte, error) {
+				return resolver.ResolveOutputKeys(context.Background(), "/vpc", []string{"vpc_id"})
+			},
+			misses: 1,
+		},
+		{
+			name: "keys cached",
+			resolve: func() ([]byte, error) {
+				return resolver.ResolveOutputKeys(context.Background(), "/vpc", []string{"vpc_id"})
+			},
+			hits:   1,
+			misses: 1,
+		},
+		{
+			name:    "outputs not cached",
+			resolve: func() ([]byte, error) { return resolver.ResolveOutputs(context.Background(), "/vpc") },
+			hits:    1,
+			misses:  2,
+		},
+		{
+			name: "keys filtered from the cached outputs",
+			resolve: func() ([]byte, error) {
+				return resolver.ResolveOutputKeys(context.Background(), "/vpc", []string{"subnet_ids"})
+			},
+			hits:   2,
+			misses: 2,
+		},
+	}
+	for _, step := range steps {
+		if _, err := step.resolve(); err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		want := map[string]int{}
+		if step.hits > 0 {
+			want[MetricCacheRequests+" hit"] = step.hits
+		}
+		want[MetricCacheRequests+" miss"] = step.misses
+		if !reflect.DeepEqual(metrics.counters, want) {
+			t.Errorf("%s: got counters %v, want %v", step.name, metrics.counters, want)
+		}
+	}
+	if want := [][]string{{"vpc_id"}, nil}; !reflect.DeepEqual(keyed.calls, want) {
+		t.Errorf("got calls %q, want %q", keyed.calls, want)
+	}
+}
@@ -0,0 +1,194 @@
+package terragrunt
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CatalogConfig is the catalog block, which lists the repositories of modules terragrunt catalog offers to scaffold
+// units from, usually declared by the root configuration.
+type CatalogConfig struct {
+	URLs            []string `hcl:"urls,attr"`
+	DefaultTemplate *string  `hcl:"default_template,attr"`
+}
+
+// catalogModulesDir is the directory of the repositories of a catalog holding their modules.
+const catalogModulesDir = "modules"
+
+// CatalogModule is a module of a catalog repository.
+type CatalogModule struct {
+	// URL is the url of the catalog repository holding the module, as written in the catalog block.
+	URL string
+
+	// Path is the directory of the module within the repository, with forward slashes. It is empty for the module at
+	// the root of the repository.
+	Path string
+
+	// Dir is the local directory of the module.
+	Dir string
+
+	// Source is the terraform source of the module, to use in the terraform block of the units scaffolded from it.
+	Source string
+
+	// Title and Description are read from the README.md of the module: its first heading, and its first paragraph.
+	// They are empty when the module has no README.md.
+	Title       string
+	Description string
+}
+
+// CatalogModules fetches the repositories at the given catalog urls with the fetcher, and returns their modules, as
+// listed by terragrunt catalog: the root of each repository when it holds terraform files, and every directory holding
+// terraform files under its modules directory. Local urls are resolved relative to the given base directory, which is
+// usually the directory of the configuration declaring the catalog block.
+func (fetcher *SourceFetcher) CatalogModules(ctx context.Context, urls []string, baseDir string) ([]*CatalogModule, error) {
+	var modules []*CatalogModule
+	for _, url := range urls {
+		source, err := ParseSource(url)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := fetcher.Fetch(ctx, url, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		repositoryModules, err := catalogRepositoryModules(dir)
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", url, err)
+		}
+		for _, module := range repositoryModules {
+			module.URL = url
+			module.Source = catalogModuleSource(source, module.Path)
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
+
+// catalogRepositoryModules returns the modules of the catalog repository in the given directory, sorted by path.
+func catalogRepositoryModules(dir string) ([]*CatalogModule, error) {
+	var modules []*CatalogModule
+	if hasTerraformFiles(dir) {
+		module, err := newCatalogModule(dir, "")
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, module)
+	}
+
+	modulesDir := filepath.Join(dir, catalogModulesDir)
+	if _, err := os.Stat(modulesDir); err != nil {
+		return modules, nil
+	}
+	err := filepath.WalkDir(modulesDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !hasTerraformFiles(filePath) {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		module, err := newCatalogModule(filePath, filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+		modules = append(modules, module)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+	return modules, nil
+}
+
+// hasTerraformFiles returns whether the given directory holds .tf files.
+func hasTerraformFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	return len(matches) > 0
+}
+
+func newCatalogModule(dir string, modulePath string) (*CatalogModule, error) {
+	module := &CatalogModule{Path: modulePath, Dir: dir}
+	file, err := os.Open(filepath.Join(dir, "README.md"))
+	if os.IsNotExist(err) {
+		return module, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	module.Title, module.Description, err = readmeSummary(file)
+	if err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// readmeSummary returns the first heading of the given markdown document, and its first paragraph that is not a
+// heading, an image, a badge or a code block.
+func readmeSummary(reader io.Reader) (string, string, error) {
+	var title string
+	var paragraph []string
+	inCode := false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		switch {
+		case inCode:
+		case strings.HasPrefix(line, "#"):
+			if title == "" {
+				title = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			}
+			if len(paragraph) > 0 {
+				return title, strings.Join(paragraph, " "), nil
+			}
+		case line == "":
+			if len(paragraph) > 0 {
+				return title, strings.Join(paragraph, " "), nil
+			}
+		case strings.HasPrefix(line, "!["), strings.HasPrefix(line, "[!["), strings.HasPrefix(line, "<"):
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	return title, strings.Join(paragraph, " "), scanner.Err()
+}
+
+// catalogModuleSource returns the terraform source of the module at the given path of the catalog repository with the
+// given source. The sources of local repositories are kept relative as written.
+func catalogModuleSource(source *Source, modulePath string) string {
+	module := *source
+	module.Submodule = path.Join(source.Submodule, modulePath)
+	if module.Kind != SourceLocal {
+		return module.String()
+	}
+	if module.Submodule == "" || module.Submodule == "." {
+		return source.Path
+	}
+	return strings.TrimSuffix(source.Path, "/") + "/" + module.Submodule
+}
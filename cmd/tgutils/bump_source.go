@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runBumpSource(args []string) error {
+	flagSet := flag.NewFlagSet("bump-source", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	module := flagSet.String("module", "", "source of the module to bump, without version (e.g. git::git@github.com:org/modules.git//vpc)")
+	version := flagSet.String("to", "", "version to set")
+	pathGlob := flagSet.String("path", "", "only bump units whose path, relative to the directory, matches this glob")
+	dryRun := flagSet.Bool("dry-run", false, "print the changes without writing them")
+	flagSet.Parse(args)
+
+	if *module == "" || *version == "" {
+		return errors.New("-module and -to are required")
+	}
+	if *pathGlob != "" {
+		if _, err := path.Match(*pathGlob, ""); err != nil {
+			return fmt.Errorf("invalid -path glob: %w", err)
+		}
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	bumped := 0
+	for _, unit := range stack.Units {
+		relPath := relativePath(stack, unit.Path)
+		if *pathGlob != "" {
+			if matched, _ := path.Match(*pathGlob, relPath); !matched {
+				continue
+			}
+		}
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %s\n", relPath, unit.Err)
+			continue
+		}
+		if unit.Config.Terraform == nil || unit.Config.Terraform.Source == nil || !sourceMatches(*unit.Config.Terraform.Source, *module) {
+			continue
+		}
+
+		previousVersion := terragrunt.SourceVersion(*unit.Config.Terraform.Source)
+		if previousVersion == *version {
+			continue
+		}
+
+		content, err := os.ReadFile(unit.ConfigPath)
+		if err != nil {
+			return err
+		}
+		updated, changed, err := terragrunt.SetTerraformSourceVersion(content, *version)
+		if err != nil {
+			return fmt.Errorf("%s: %w", relPath, err)
+		}
+		if !changed {
+			fmt.Fprintf(os.Stderr, "skipping %s: the source version is not a literal that can be rewritten\n", relPath)
+			continue
+		}
+
+		if !*dryRun {
+			if err := os.WriteFile(unit.ConfigPath, updated, 0o644); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("%s: %s -> %s\n", relPath, previousVersion, *version)
+		bumped++
+	}
+
+	fmt.Printf("%d units bumped to %s\n", bumped, *version)
+	return nil
+}
+
+// sourceMatches returns whether the given unit source points to the given module, ignoring the version. A module
+// without a submodule path also matches the sources pointing to its submodules.
+func sourceMatches(source string, module string) bool {
+	source = strings.SplitN(source, "?", 2)[0]
+	module = strings.SplitN(module, "?", 2)[0]
+	return source == module || strings.HasPrefix(source, module+"//")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runCatalog(args []string) error {
+	flagSet := flag.NewFlagSet("catalog", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils catalog [config]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Lists the modules of the repositories of the catalog block of the given configuration, root.hcl by default.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	configPath := "root.hcl"
+	if flagSet.NArg() > 0 {
+		configPath = flagSet.Arg(0)
+	}
+	config, err := terragrunt.ParseConfigFile(configPath)
+	if err != nil {
+		return err
+	}
+	if config.Catalog == nil {
+		return fmt.Errorf("%s: no catalog block", configPath)
+	}
+
+	fetcher := &terragrunt.SourceFetcher{Registry: &terragrunt.RegistryClient{}}
+	modules, err := fetcher.CatalogModules(context.Background(), config.Catalog.URLs, filepath.Dir(configPath))
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		fmt.Println(module.Source)
+		if module.Title != "" {
+			fmt.Printf("  %s\n", module.Title)
+		}
+		if module.Description != "" {
+			fmt.Printf("  %s\n", module.Description)
+		}
+	}
+	return nil
+}
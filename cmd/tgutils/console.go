@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	terragrunt "terragrunt-utils"
+)
+
+func runConsole(args []string) error {
+	flagSet := flag.NewFlagSet("console", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils console [flags] [dir]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Reads HCL expressions from the standard input, and prints their value in the context of the unit in the")
+		fmt.Fprintln(os.Stderr, "given directory, e.g. 'local.env' or 'dependency.vpc.outputs.vpc_id', as terraform console does.")
+		fmt.Fprintln(os.Stderr, "Type exit, or end the input, to quit.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	dir := "."
+	switch flagSet.NArg() {
+	case 0:
+	case 1:
+		dir = flagSet.Arg(0)
+	default:
+		flagSet.Usage()
+		return errors.New("expected a single unit directory")
+	}
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+	config, err := terragrunt.ParseConfigFile(filepath.Join(dir, terragrunt.DefaultConfigFilename), opts...)
+	if err != nil {
+		return err
+	}
+
+	return runConsoleLoop(config, os.Stdin, os.Stdout)
+}
+
+// runConsoleLoop evaluates the expressions read from in, one per line, and writes their value to out. Expressions
+// spanning several lines are read until their brackets are balanced.
+func runConsoleLoop(config *terragrunt.TerragruntConfig, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	var expression strings.Builder
+	fmt.Fprint(out, "> ")
+	for scanner.Scan() {
+		expression.WriteString(scanner.Text() + "\n")
+		if openBrackets(expression.String()) > 0 {
+			fmt.Fprint(out, ". ")
+			continue
+		}
+
+		line := strings.TrimSpace(expression.String())
+		expression.Reset()
+		switch line {
+		case "":
+		case "exit":
+			return nil
+		default:
+			value, err := config.Eval(line)
+			switch {
+			case err != nil:
+				fmt.Fprintf(out, "error: %s\n", err)
+			case !value.IsWhollyKnown():
+				fmt.Fprintln(out, "(known after apply)")
+			default:
+				fmt.Fprintf(out, "%s\n", hclwrite.TokensForValue(terragrunt.Redact(value)).Bytes())
+			}
+		}
+		fmt.Fprint(out, "> ")
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// openBrackets returns the number of brackets, braces and parentheses opened and not closed yet by the given
+// expression, ignoring the ones in quoted strings.
+func openBrackets(expression string) int {
+	open := 0
+	inString := false
+	for i := 0; i < len(expression); i++ {
+		switch c := expression[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			open++
+		case c == ')' || c == ']' || c == '}':
+			open--
+		}
+	}
+	return open
+}
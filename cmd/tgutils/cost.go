@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runCost(args []string) error {
+	flagSet := flag.NewFlagSet("cost", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	var targets stringsFlag
+	flagSet.Var(&targets, "unit", "unit to estimate, relative to the directory, instead of every unit, e.g. the units affected by a change (can be repeated)")
+	command := flagSet.String("infracost", "infracost", "infracost binary to run")
+	infracostJSON := flagSet.String("infracost-json", "", "read the estimates from this output of infracost --format json, with a project per unit, instead of running infracost")
+	compareTo := flagSet.String("compare-to", "", "compare the estimates with the ones of this output of tgutils cost -format json or infracost --format json, e.g. of the base branch")
+	parallelism := flagSet.Int("parallelism", 4, "maximum number of units to estimate at once")
+	format := flagSet.String("format", "text", "output format of the estimates: text, or json in the format of infracost")
+	reportPath := flagSet.String("report", "", "also write the estimates to the given file as a markdown report for pull request comments")
+	flagSet.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+	units := stack.Units
+	if len(targets) > 0 {
+		units = nil
+		for _, target := range targets {
+			unit := stack.Unit(filepath.Join(stack.Root, filepath.FromSlash(target)))
+			if unit == nil {
+				return fmt.Errorf("unit %s is not in the stack", target)
+			}
+			units = append(units, unit)
+		}
+	}
+
+	var estimates map[string]*terragrunt.CostEstimate
+	var failures map[string]error
+	if *infracostJSON != "" {
+		estimates, err = readCostEstimates(stack, units, *infracostJSON, true)
+	} else {
+		estimates, failures, err = estimateCosts(stack, units, &terragrunt.Infracost{Command: *command}, *parallelism)
+	}
+	if err != nil {
+		return err
+	}
+	if *compareTo != "" {
+		baseline, err := readCostEstimates(stack, units, *compareTo, false)
+		if err != nil {
+			return err
+		}
+		estimates = terragrunt.CompareCosts(estimates, baseline)
+	}
+
+	paths := sortedKeys(estimates)
+	output := &terragrunt.InfracostOutput{}
+	for _, path := range paths {
+		estimate := estimates[path]
+		output.Currency = estimate.Currency
+		output.Total.Currency = estimate.Currency
+		output.Total.MonthlyCost += estimate.MonthlyCost
+		output.Total.PastMonthlyCost += estimate.PastMonthlyCost
+		output.Projects = append(output.Projects, terragrunt.InfracostProject{Name: relativePath(stack, path), Path: relativePath(stack, path), Estimate: *estimate})
+	}
+
+	if *format == "json" {
+		err = terragrunt.WriteInfracostJSON(os.Stdout, output)
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "UNIT\tMONTHLY COST\tCHANGE")
+		for _, project := range output.Projects {
+			fmt.Fprintf(writer, "%s\t%.2f %s\t%+.2f\n", project.Path, project.Estimate.MonthlyCost, project.Estimate.Currency, project.Estimate.Delta())
+		}
+		fmt.Fprintf(writer, "total\t%.2f %s\t%+.2f\n", output.Total.MonthlyCost, output.Total.Currency, output.Total.Delta())
+		err = writer.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	if *reportPath != "" {
+		report := &terragrunt.Report{Title: "Cost estimate", BaseDir: "."}
+		for _, path := range paths {
+			report.Unit(path).Cost = estimates[path]
+		}
+		for path, failure := range failures {
+			report.Unit(path).Err = failure
+		}
+		if err := writeReport(*reportPath, report); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		for _, path := range sortedKeys(failures) {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, path), failures[path])
+		}
+		return errFailed
+	}
+	return nil
+}
+
+// estimateCosts runs infracost against the given units, at most parallelism at once, returning the estimates and the
+// errors of the units, keyed by unit path.
+func estimateCosts(stack *terragrunt.Stack, units []*terragrunt.Unit, infracost *terragrunt.Infracost, parallelism int) (map[string]*terragrunt.CostEstimate, map[string]error, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	batch := make([]string, len(units))
+	for i, unit := range units {
+		batch[i] = unit.Path
+	}
+	sort.Strings(batch)
+
+	estimates := map[string]*terragrunt.CostEstimate{}
+	failures := map[string]error{}
+	var mutex sync.Mutex
+	err := terragrunt.RunBatches([][]string{batch}, parallelism, func(ctx context.Context, path string) error {
+		estimate, err := infracost.EstimateUnit(ctx, stack.Unit(path))
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			failures[path] = err
+		} else {
+			estimates[path] = estimate
+		}
+		return nil
+	}, terragrunt.WithContext(ctx))
+	return estimates, failures, err
+}
+
+// readCostEstimates reads the estimates of the given units from the infracost output in the given file, matching
+// their projects by the path of the unit: absolute, relative to the root of the stack or relative to the current
+// directory. Units without project are an error when required, and left out otherwise.
+func readCostEstimates(stack *terragrunt.Stack, units []*terragrunt.Unit, path string, required bool) (map[string]*terragrunt.CostEstimate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	output, err := terragrunt.ParseInfracostJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	estimates := map[string]*terragrunt.CostEstimate{}
+	for _, unit := range units {
+		candidates := []string{unit.Path, relativePath(stack, unit.Path)}
+		if cwd, err := os.Getwd(); err == nil {
+			if relPath, err := filepath.Rel(cwd, unit.Path); err == nil {
+				candidates = append(candidates, relPath)
+			}
+		}
+
+		found := false
+		for _, candidate := range candidates {
+			if project, ok := output.Project(candidate); ok {
+				estimate := project.Estimate
+				estimates[unit.Path] = &estimate
+				found = true
+				break
+			}
+		}
+		if !found && required {
+			return nil, fmt.Errorf("%s: no project for unit %s", path, relativePath(stack, unit.Path))
+		}
+	}
+	return estimates, nil
+}
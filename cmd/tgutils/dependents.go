@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runDependents(args []string) error {
+	flagSet := flag.NewFlagSet("dependents", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	root := flagSet.String("root", ".", "directory to search for dependent units")
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		return errors.New("expected the directory of a single unit")
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+	stack, err := terragrunt.ParseStack(*root, opts...)
+	if err != nil {
+		return err
+	}
+	dependents, err := stack.Dependents(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, dependent := range dependents {
+		outputs := "no outputs"
+		switch {
+		case dependent.AllOutputs:
+			outputs = "all outputs"
+		case len(dependent.OutputKeys) > 0:
+			outputs = strings.Join(dependent.OutputKeys, ", ")
+		}
+		fmt.Printf("%s (dependency %q): %s\n", relativePath(stack, dependent.Unit.Path), dependent.Dependency, outputs)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runDestroyPlan(args []string) error {
+	flagSet := flag.NewFlagSet("destroy-plan", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	var targets stringsFlag
+	flagSet.Var(&targets, "target", "unit to destroy, relative to the directory, instead of every unit (can be repeated)")
+	allowPreventDestroy := flagSet.Bool("allow-prevent-destroy", false, "destroy the units setting prevent_destroy = true like the other ones")
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	opts := terragrunt.DestroyOptions{AllowPreventDestroy: *allowPreventDestroy}
+	for _, target := range targets {
+		opts.Targets = append(opts.Targets, filepath.Join(stack.Root, filepath.FromSlash(target)))
+	}
+	plan, err := stack.Graph().DestroyPlan(opts)
+	if err != nil {
+		return err
+	}
+
+	for i, batch := range plan.Batches {
+		fmt.Printf("batch %d:\n", i+1)
+		for _, path := range batch {
+			fmt.Printf("  %s\n", relativePath(stack, path))
+		}
+	}
+	if len(plan.Blocked) == 0 {
+		return nil
+	}
+
+	fmt.Println("blocked:")
+	for _, blocked := range plan.Blocked {
+		switch blocked.Reason {
+		case terragrunt.DestroyBlockedByPreventDestroy:
+			fmt.Printf("  %s: prevent_destroy is set\n", relativePath(stack, blocked.Path))
+		default:
+			dependents := make([]string, len(blocked.Dependents))
+			for i, dependent := range blocked.Dependents {
+				dependents[i] = relativePath(stack, dependent)
+			}
+			fmt.Printf("  %s: still used by %s\n", relativePath(stack, blocked.Path), strings.Join(dependents, ", "))
+		}
+	}
+	return errFailed
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	terragrunt "terragrunt-utils"
+)
+
+type driftJSON struct {
+	Time  time.Time       `json:"time"`
+	Units []unitDriftJSON `json:"units"`
+}
+
+type unitDriftJSON struct {
+	Path      string                `json:"path"`
+	Drifted   bool                  `json:"drifted"`
+	Error     string                `json:"error,omitempty"`
+	Add       int                   `json:"add"`
+	Change    int                   `json:"change"`
+	Destroy   int                   `json:"destroy"`
+	Resources []plannedResourceJSON `json:"resources"`
+	Drift     []plannedResourceJSON `json:"drift"`
+}
+
+type plannedResourceJSON struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+func runDrift(args []string) error {
+	flagSet := flag.NewFlagSet("drift", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	var targets stringsFlag
+	flagSet.Var(&targets, "unit", "unit to check, relative to the directory, instead of every unit (can be repeated)")
+	var driftOpts terragrunt.DriftOptions
+	flagSet.StringVar(&driftOpts.Command, "terragrunt", "terragrunt", "terragrunt binary to run")
+	flagSet.BoolVar(&driftOpts.RefreshOnly, "refresh-only", false, "run refresh-only plans, only reporting the changes made outside of terraform")
+	flagSet.IntVar(&driftOpts.Parallelism, "parallelism", 4, "maximum number of units to plan at once")
+	format := flagSet.String("format", "text", "output format of the drift: text or json")
+	reportPath := flagSet.String("report", "", "also write the drifted units to the given file as a markdown report")
+	flagSet.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		driftOpts.Targets = append(driftOpts.Targets, filepath.Join(stack.Root, filepath.FromSlash(target)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	drift, err := terragrunt.DetectDrift(stack, driftOpts, terragrunt.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		err = writeDriftJSON(stack, drift)
+	} else {
+		for _, unit := range drift.Units {
+			printUnitDrift(stack, unit)
+		}
+		fmt.Printf("%d of %d units drifted\n", len(drift.Drifted()), len(drift.Units))
+	}
+	if err != nil {
+		return err
+	}
+
+	if *reportPath != "" {
+		report := &terragrunt.Report{Title: "Drift", BaseDir: "."}
+		for _, unit := range drift.Units {
+			unitReport := report.Unit(unit.Path)
+			unitReport.Plan, unitReport.Err = unit.Plan, unit.Err
+		}
+		if err := writeReport(*reportPath, report); err != nil {
+			return err
+		}
+	}
+
+	if len(drift.Drifted()) > 0 || len(drift.Failed()) > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func printUnitDrift(stack *terragrunt.Stack, unit terragrunt.UnitDrift) {
+	path := relativePath(stack, unit.Path)
+	switch {
+	case unit.Err != nil:
+		fmt.Printf("%s: failed: %s\n", path, unit.Err)
+		return
+	case !unit.Drifted:
+		fmt.Printf("%s: in sync\n", path)
+		return
+	}
+
+	fmt.Printf("%s: drifted, %s\n", path, unit.Plan)
+	for _, resource := range unit.Plan.Drift {
+		fmt.Printf("  changed outside of terraform: %s %s\n", resource.Action, resource.Address)
+	}
+	for _, resource := range unit.Plan.Resources {
+		fmt.Printf("  %s %s\n", resource.Action, resource.Address)
+	}
+}
+
+func writeDriftJSON(stack *terragrunt.Stack, drift *terragrunt.DriftReport) error {
+	document := driftJSON{Time: drift.Time, Units: []unitDriftJSON{}}
+	for _, unit := range drift.Units {
+		rendered := unitDriftJSON{
+			Path:      relativePath(stack, unit.Path),
+			Drifted:   unit.Drifted,
+			Resources: []plannedResourceJSON{},
+			Drift:     []plannedResourceJSON{},
+		}
+		if unit.Err != nil {
+			rendered.Error = unit.Err.Error()
+		}
+		if unit.Plan != nil {
+			rendered.Add, rendered.Change, rendered.Destroy = unit.Plan.Add, unit.Plan.Change, unit.Plan.Destroy
+			for _, resource := range unit.Plan.Resources {
+				rendered.Resources = append(rendered.Resources, plannedResourceJSON{Address: resource.Address, Action: string(resource.Action)})
+			}
+			for _, resource := range unit.Plan.Drift {
+				rendered.Drift = append(rendered.Drift, plannedResourceJSON{Address: resource.Address, Action: string(resource.Action)})
+			}
+		}
+		document.Units = append(document.Units, rendered)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runExplain(args []string) error {
+	flagSet := flag.NewFlagSet("explain", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils explain [flags] <path> [dir]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Prints where the attribute with the given path (e.g. inputs.region or terraform.source) is set, for every")
+		fmt.Fprintln(os.Stderr, "unit. A block or attribute path (e.g. inputs) lists every attribute under it.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return errors.New("missing attribute path")
+	}
+	path := flagSet.Arg(0)
+	flagSet.Parse(flagSet.Args()[1:])
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range stack.Units {
+		relPath := relativePath(stack, unit.Path)
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %s\n", relPath, unit.Err)
+			continue
+		}
+
+		found := false
+		for _, origin := range unit.Config.Provenance() {
+			if origin.Path == path || strings.HasPrefix(origin.Path, path+".") {
+				printOrigin(stack, relPath, origin)
+				found = true
+			}
+		}
+		if !found {
+			if origin, ok := unit.Config.Origin(path); ok {
+				printOrigin(stack, relPath, origin)
+			}
+		}
+	}
+	return nil
+}
+
+func printOrigin(stack *terragrunt.Stack, relPath string, origin terragrunt.Origin) {
+	fmt.Printf("%s\t%s\t%s:%d,%d-%d,%d\n", relPath, origin.Path, relativePath(stack, origin.Range.Filename),
+		origin.Range.Start.Line, origin.Range.Start.Column, origin.Range.End.Line, origin.Range.End.Column)
+}
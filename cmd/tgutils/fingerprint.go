@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func runFingerprint(args []string) error {
+	flagSet := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range stack.Units {
+		fingerprint, err := unit.Fingerprint()
+		if err != nil {
+			return fmt.Errorf("%s: %w", relativePath(stack, unit.Path), err)
+		}
+		fmt.Printf("%s  %s\n", fingerprint, relativePath(stack, unit.Path))
+	}
+	return nil
+}
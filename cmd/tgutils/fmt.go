@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runFmt(args []string) error {
+	flagSet := flag.NewFlagSet("fmt", flag.ExitOnError)
+	recursive := flagSet.Bool("r", false, "also format the files in subdirectories")
+	check := flagSet.Bool("check", false, "list the files that are not formatted instead of rewriting them, and fail if there are any")
+	order := flagSet.Bool("order", false, "also reorder the top level blocks and attributes in the conventional order")
+	flagSet.Parse(args)
+
+	paths := flagSet.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var opts []terragrunt.FormatOption
+	if *order {
+		opts = append(opts, terragrunt.WithCanonicalOrder())
+	}
+
+	failed := false
+	for _, path := range paths {
+		files, err := hclFiles(path, *recursive)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			unformatted, err := formatFile(file, *check, opts)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				continue
+			}
+			if unformatted {
+				fmt.Println(file)
+				failed = failed || *check
+			}
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// formatFile formats the file at the given path, unless only checking it, and returns whether it was not formatted.
+func formatFile(path string, check bool, opts []terragrunt.FormatOption) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	formatted, err := terragrunt.Format(content, append(opts, terragrunt.WithFormatFilename(path))...)
+	if err != nil {
+		return false, err
+	}
+	if string(formatted) == string(content) {
+		return false, nil
+	}
+
+	if !check {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(path, formatted, info.Mode()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// hclFiles returns the .hcl files at the given path: the path itself when it is a file, or the files of the directory,
+// including the ones of its subdirectories when recursive. Hidden directories, such as .terragrunt-cache, are skipped.
+func hclFiles(path string, recursive bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if filePath != path && (!recursive || strings.HasPrefix(entry.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(filePath) == ".hcl" {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunFmt(t *testing.T) {
+	golden := filepath.Join("..", "..", "testdata", "format")
+	dir := t.TempDir()
+	files := map[string]string{
+		"terragrunt.hcl":                   "unordered",
+		"vpc/terragrunt.hcl":               "spacing",
+		".terragrunt-cache/terragrunt.hcl": "unordered",
+	}
+	for path, name := range files {
+		content, err := os.ReadFile(filepath.Join(golden, name+".hcl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertFiles := func(t *testing.T, suffix map[string]string) {
+		t.Helper()
+		for path, name := range files {
+			want, err := os.ReadFile(filepath.Join(golden, name+suffix[path]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got %s:\n%s\nwant:\n%s", path, got, want)
+			}
+		}
+	}
+
+	if err := runFmt([]string{"-check", "-r", dir}); err != errFailed {
+		t.Fatalf("got %v checking the unformatted files, want %v", err, errFailed)
+	}
+	assertFiles(t, map[string]string{"terragrunt.hcl": ".hcl", "vpc/terragrunt.hcl": ".hcl", ".terragrunt-cache/terragrunt.hcl": ".hcl"})
+
+	if err := runFmt([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, map[string]string{"terragrunt.hcl": ".golden", "vpc/terragrunt.hcl": ".hcl", ".terragrunt-cache/terragrunt.hcl": ".hcl"})
+
+	if err := runFmt([]string{"-order", "-r", dir}); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, map[string]string{"terragrunt.hcl": ".ordered.golden", "vpc/terragrunt.hcl": ".ordered.golden", ".terragrunt-cache/terragrunt.hcl": ".hcl"})
+
+	if err := runFmt([]string{"-check", "-order", "-r", dir}); err != nil {
+		t.Fatalf("got %v checking the formatted files, want none", err)
+	}
+}
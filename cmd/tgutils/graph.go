@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func runGraph(args []string) error {
+	flagSet := flag.NewFlagSet("graph", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	format := flagSet.String("format", "dot", "output format: dot, mermaid, or batches to list the units in run order")
+	action := flagSet.String("action", "", "with -format batches, leave out the units excluded from the given action (e.g. plan) by their exclude block")
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	graph := stack.Graph()
+	switch *format {
+	case "dot":
+		fmt.Print(graph.DOT())
+	case "mermaid":
+		fmt.Print(graph.Mermaid())
+	case "batches":
+		batches, err := graph.Batches()
+		if *action != "" {
+			batches, err = graph.BatchesFor(*action)
+		}
+		if err != nil {
+			return err
+		}
+		for i, batch := range batches {
+			fmt.Printf("batch %d:\n", i+1)
+			for _, path := range batch {
+				fmt.Printf("  %s\n", relativePath(stack, path))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return nil
+}
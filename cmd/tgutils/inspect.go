@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	terragrunt "terragrunt-utils"
+)
+
+func runInspect(args []string) error {
+	flagSet := flag.NewFlagSet("inspect", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for i, unit := range stack.Units {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("== %s\n", relativePath(stack, unit.Path))
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, unit.ConfigPath), unit.Err)
+			failed = true
+			continue
+		}
+		printConfig(unit.Config)
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func printConfig(config *terragrunt.TerragruntConfig) {
+	if config.Terraform != nil && config.Terraform.Source != nil {
+		fmt.Printf("source:           %s\n", *config.Terraform.Source)
+	}
+	if config.TerraformBinary != "" {
+		fmt.Printf("terraform_binary: %s\n", config.TerraformBinary)
+	}
+	if config.IamRole != "" {
+		fmt.Printf("iam_role:         %s\n", config.IamRole)
+	}
+	if config.Engine != nil {
+		engine := config.Engine.Source
+		if config.Engine.Version != nil {
+			engine += "@" + *config.Engine.Version
+		}
+		fmt.Printf("engine:           %s\n", engine)
+	}
+	if config.Catalog != nil {
+		fmt.Printf("catalog:          %s\n", strings.Join(config.Catalog.URLs, ", "))
+	}
+	if config.Exclude != nil {
+		fmt.Printf("exclude:          if=%t actions=%s\n", config.Exclude.If, strings.Join(config.Exclude.Actions, ","))
+	}
+
+	if len(config.FeatureFlags) > 0 {
+		fmt.Println("features:")
+		for _, name := range sortedKeys(config.FeatureFlags) {
+			fmt.Printf("  %s = %s\n", name, hclwrite.TokensForValue(terragrunt.Redact(config.FeatureFlags[name])).Bytes())
+		}
+	}
+
+	if len(config.TerragruntDependencies) > 0 {
+		fmt.Println("dependencies:")
+		for _, dependency := range config.TerragruntDependencies {
+			if !dependency.IsEnabled() {
+				fmt.Printf("  %s -> %s (disabled)\n", dependency.Name, dependency.ConfigPath)
+				continue
+			}
+			fmt.Printf("  %s -> %s\n", dependency.Name, dependency.ConfigPath)
+		}
+	}
+
+	if len(config.InputsCty) > 0 {
+		fmt.Println("inputs:")
+		for _, name := range sortedKeys(config.InputsCty) {
+			value := terragrunt.Redact(config.InputsCty[name])
+			if !value.IsWhollyKnown() {
+				fmt.Printf("  %s = (known after apply)\n", name)
+				continue
+			}
+			fmt.Printf("  %s = %s\n", name, hclwrite.TokensForValue(value).Bytes())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runLockDrift(args []string) error {
+	flagSet := flag.NewFlagSet("lock-drift", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	baseline := flagSet.String("baseline", "", "lock file (or directory holding it) the lock files of the units must match")
+	flagSet.Parse(args)
+
+	if *baseline == "" {
+		return errors.New("the -baseline flag is required")
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+	drifts, err := terragrunt.CompareLockfiles(stack, *baseline)
+	if err != nil {
+		return err
+	}
+
+	for _, drift := range drifts {
+		fmt.Printf("%s: %s\n", relativePath(stack, drift.Unit.Path), drift)
+	}
+	if len(drifts) > 0 {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+// Command tgutils exposes the capabilities of the terragrunt-utils package on the command line, so that they can be
+// used without writing Go code.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	terragrunt "terragrunt-utils"
+)
+
+// command is a tgutils subcommand, run with the arguments following its name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"inspect", "print the resolved configuration of the units under a directory", runInspect},
+	{"graph", "print the dependency graph of the units under a directory", runGraph},
+	{"destroy-plan", "list the units under a directory in the order run-all destroy destroys them", runDestroyPlan},
+	{"validate", "check that the configuration of every unit under a directory is valid", runValidate},
+	{"render-json", "print the resolved configuration of the units under a directory as json", runRenderJSON},
+	{"query", "evaluate an expression against the units under a directory", runQuery},
+	{"list-inputs", "list the inputs of the units under a directory", runListInputs},
+	{"policy", "evaluate rego policies against the configuration of the units under a directory", runPolicy},
+	{"explain", "print where an attribute of the units under a directory is set", runExplain},
+	{"providers", "list the providers required by the modules of the units under a directory", runProviders},
+	{"lock-drift", "report the units whose lock file differs from a baseline lock file", runLockDrift},
+	{"watch", "print the changes of the units under a directory as their files change", runWatch},
+	{"dependents", "list the units that depend on a unit, and the outputs they use", runDependents},
+	{"fmt", "format the terragrunt configuration files under a directory", runFmt},
+	{"bump-source", "rewrite the version of a module source across the units under a directory", runBumpSource},
+	{"fingerprint", "print a hash of the files each unit under a directory is built from", runFingerprint},
+	{"snapshot", "write the parsed units under a directory to a json snapshot", runSnapshot},
+	{"catalog", "list the modules of the catalog repositories of a configuration", runCatalog},
+	{"scaffold", "render a boilerplate template into a new unit", runScaffold},
+	{"migrate-tfvars", "convert a legacy terraform.tfvars terragrunt configuration to terragrunt.hcl", runMigrateTfvars},
+	{"upgrade", "rewrite the deprecated constructs of a configuration", runUpgrade},
+	{"console", "evaluate expressions interactively in the context of a unit", runConsole},
+	{"schema", "print the json schema of the configuration files", runSchema},
+	{"run", "run a terragrunt command in every unit under a directory, in dependency order", runRun},
+	{"cost", "estimate the monthly cost of the units under a directory with infracost", runCost},
+	{"drift", "plan the units under a directory and report the ones that drifted", runDrift},
+	{"output-types", "print the type of the outputs of the dependencies of the units under a directory", runOutputTypes},
+}
+
+// errFailed is returned by commands that already reported why they failed.
+var errFailed = errors.New("failed")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			if err != errFailed {
+				fmt.Fprintf(os.Stderr, "tgutils %s: %s\n", name, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "tgutils: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: tgutils <command> [flags] [dir]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// stackFlags are the flags shared by the commands operating on the units under a directory.
+type stackFlags struct {
+	resolveOutputs    bool
+	outputsFromSource bool
+	outputsFromState  bool
+	awsProfiles       stringsFlag
+	features          stringsFlag
+	workspace         string
+	deterministic     bool
+	snapshot          string
+	parseCache        string
+	outputCache       string
+	outputRateLimit   float64
+	outputKeys        int
+	defaultMocks      string
+	mockOverrides     string
+	strictOutputs     bool
+	strictIncludes    bool
+	followSymlinks    bool
+	excludes          stringsFlag
+}
+
+func (flags *stackFlags) register(flagSet *flag.FlagSet) {
+	flagSet.BoolVar(&flags.resolveOutputs, "resolve-outputs", false, "retrieve dependency outputs by running `terragrunt output`, instead of only using mock outputs")
+	flagSet.BoolVar(&flags.outputsFromSource, "outputs-from-source", false, "derive dependency outputs from the configuration and module of the dependencies, without any state")
+	flagSet.BoolVar(&flags.outputsFromState, "outputs-from-state", false, "read dependency outputs from their s3 or gcs state, assuming their iam_role or role_arn, instead of running `terragrunt output`")
+	flagSet.Var(&flags.awsProfiles, "aws-profile", "AWS profile to read the state of the units matching a pattern with, as pattern=profile, with * matching anything (can be repeated)")
+	flagSet.StringVar(&flags.workspace, "workspace", "", "terraform workspace to retrieve dependency outputs from with -resolve-outputs or -outputs-from-state, instead of the one selected by each unit")
+	flagSet.StringVar(&flags.outputCache, "output-cache", "", "cache the outputs retrieved by -resolve-outputs or -outputs-from-state in this directory, or in the Redis server at this redis:// or rediss:// url, instead of in memory")
+	flagSet.Float64Var(&flags.outputRateLimit, "output-rate-limit", 0, "limit the outputs retrieved by -resolve-outputs or -outputs-from-state to this number per second, to avoid the throttling of the state backend")
+	flagSet.IntVar(&flags.outputKeys, "selective-output-keys", 0, "only retrieve the referenced outputs of the dependencies whose outputs a unit references at most this number of, with terragrunt output -json KEY or by filtering their state")
+	flagSet.StringVar(&flags.defaultMocks, "default-mock-outputs", "", "json file of the mock outputs of the dependencies without mock_outputs, as an object of outputs")
+	flagSet.StringVar(&flags.mockOverrides, "mock-output-overrides", "", "json file of the mock outputs to lay over the ones of dependencies, as an object keyed by dependency name or unit path of objects of outputs")
+	flagSet.BoolVar(&flags.strictOutputs, "strict-outputs", false, "fail on the references to outputs that dependencies have neither in their state nor in their mock outputs, naming the dependency, the output and the position of the reference")
+	flagSet.BoolVar(&flags.strictIncludes, "strict-includes", false, "fail on the attributes set by several included configurations, naming both locations, instead of letting the later include take precedence")
+	flagSet.Var(&flags.features, "feature", "override the value of a feature flag, as name=value (can be repeated)")
+	flagSet.BoolVar(&flags.deterministic, "deterministic", false, "freeze timestamp(), uuid() and get_env() so that the output is reproducible")
+	flagSet.StringVar(&flags.parseCache, "parse-cache", "", "cache the parsed units in this directory, only parsing again the units whose files changed")
+	flagSet.BoolVar(&flags.followSymlinks, "follow-symlinks", false, "follow the symlinks to directories when discovering units, discovering the units reachable through several paths once")
+	flagSet.Var(&flags.excludes, "exclude", "exclude the paths matching this gitignore-style glob from the discovery of units, along with the ones listed in .terragrunt-ignore files (can be repeated)")
+	flagSet.StringVar(&flags.snapshot, "snapshot", "", "load the units from a snapshot written by `tgutils snapshot`, instead of parsing a directory")
+}
+
+// parseStack parses the units under the directory given as the only positional argument, defaulting to the current
+// directory, or loads them from the snapshot selected by the flags. The given options are added to the ones of the
+// flags when parsing.
+func (flags *stackFlags) parseStack(flagSet *flag.FlagSet, extra ...terragrunt.Option) (*terragrunt.Stack, error) {
+	if flags.snapshot != "" {
+		if flagSet.NArg() > 0 {
+			return nil, errors.New("a directory can not be given along with -snapshot")
+		}
+		data, err := os.ReadFile(flags.snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return terragrunt.LoadSnapshot(data)
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := "."
+	switch flagSet.NArg() {
+	case 0:
+	case 1:
+		dir = flagSet.Arg(0)
+	default:
+		return nil, fmt.Errorf("expected a single directory, got %s", strings.Join(flagSet.Args(), " "))
+	}
+
+	return terragrunt.ParseStack(dir, append(opts, extra...)...)
+}
+
+// options returns the parse options selected by the flags.
+func (flags *stackFlags) options() ([]terragrunt.Option, error) {
+	var opts []terragrunt.Option
+	if len(flags.features) > 0 {
+		features := map[string]cty.Value{}
+		for _, feature := range flags.features {
+			name, value, found := strings.Cut(feature, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid -feature %q, expected name=value", feature)
+			}
+			features[name] = cty.StringVal(value)
+		}
+		opts = append(opts, terragrunt.WithFeatureFlags(features))
+	}
+	if flags.deterministic {
+		opts = append(opts, terragrunt.WithDeterministic(terragrunt.DeterministicValues{}))
+	}
+	if flags.followSymlinks {
+		opts = append(opts, terragrunt.WithFollowSymlinks())
+	}
+	if len(flags.excludes) > 0 {
+		opts = append(opts, terragrunt.WithExcludeGlobs(flags.excludes...))
+	}
+	switch {
+	case flags.outputsFromSource:
+		fetcher := &terragrunt.SourceFetcher{Registry: &terragrunt.RegistryClient{}}
+		opts = append(opts, terragrunt.WithOutputResolver(terragrunt.NewConfigOutputResolver(fetcher, opts...)))
+	case flags.resolveOutputs || flags.outputsFromState:
+		resolver, err := flags.stateOutputResolver(opts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, terragrunt.WithOutputResolver(resolver))
+	}
+	if flags.defaultMocks != "" {
+		outputs, err := readJSONObject(flags.defaultMocks)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, terragrunt.WithDefaultMockOutputs(outputs))
+	}
+	if flags.mockOverrides != "" {
+		overrides, err := readJSONObject(flags.mockOverrides)
+		if err != nil {
+			return nil, err
+		}
+		for dependency, outputs := range overrides {
+			if !outputs.Type().IsObjectType() {
+				return nil, fmt.Errorf("%s: the overrides of %s are not an object", flags.mockOverrides, dependency)
+			}
+			opts = append(opts, terragrunt.WithMockOutputOverrides(dependency, outputs.AsValueMap()))
+		}
+	}
+	if flags.strictOutputs {
+		opts = append(opts, terragrunt.WithStrictDependencyOutputs())
+	}
+	if flags.strictIncludes {
+		opts = append(opts, terragrunt.WithStrictIncludes())
+	}
+	if flags.outputKeys > 0 {
+		opts = append(opts, terragrunt.WithSelectiveOutputKeys(flags.outputKeys))
+	}
+	if flags.parseCache != "" {
+		parseCache, err := terragrunt.NewDiskParseCache(flags.parseCache)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, terragrunt.WithParseCache(parseCache))
+	}
+	return opts, nil
+}
+
+// stateOutputResolver returns the resolver of the outputs of the state of the units selected by -resolve-outputs or
+// -outputs-from-state, which parses the configurations it reads the state of with the given options.
+func (flags *stackFlags) stateOutputResolver(opts []terragrunt.Option) (terragrunt.OutputResolver, error) {
+	cache, err := flags.newOutputCache()
+	if err != nil {
+		return nil, err
+	}
+	var workspace terragrunt.WorkspaceFunc
+	if flags.workspace != "" {
+		workspace = func(string) string { return flags.workspace }
+	}
+	var outputResolver terragrunt.OutputResolver = terragrunt.ExecOutputResolver{Workspace: workspace}
+	if flags.outputsFromState {
+		unitCredentials := &terragrunt.UnitAWSCredentials{}
+		for _, awsProfile := range flags.awsProfiles {
+			pattern, profile, found := strings.Cut(awsProfile, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid -aws-profile %q, expected pattern=profile", awsProfile)
+			}
+			unitCredentials.Rules = append(unitCredentials.Rules, terragrunt.AWSCredentialsRule{Units: pattern, Profile: profile})
+		}
+		outputResolver = terragrunt.StateOutputResolver{
+			S3:  &terragrunt.S3StateOutputResolver{UnitCredentials: unitCredentials, Workspace: workspace, Options: opts},
+			GCS: &terragrunt.GCSStateOutputResolver{Workspace: workspace, Options: opts},
+		}
+	}
+	if flags.outputRateLimit > 0 {
+		burst := int(flags.outputRateLimit)
+		outputResolver = &terragrunt.RateLimitedOutputResolver{Resolver: outputResolver, Default: terragrunt.NewRateLimiter(flags.outputRateLimit, burst)}
+	}
+	return terragrunt.NewCachingOutputResolver(outputResolver, cache, time.Hour), nil
+}
+
+// readJSONObject reads the json object in the given file as cty values, keyed by attribute name.
+func readJSONObject(path string) (map[string]cty.Value, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	valueType, err := ctyjson.ImpliedType(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !valueType.IsObjectType() {
+		return nil, fmt.Errorf("%s: expected a json object", path)
+	}
+	value, err := ctyjson.Unmarshal(data, valueType)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return value.AsValueMap(), nil
+}
+
+// newOutputCache returns the cache of the dependency outputs selected by the flags.
+func (flags *stackFlags) newOutputCache() (terragrunt.OutputCache, error) {
+	switch {
+	case flags.outputCache == "":
+		return terragrunt.NewMemoryOutputCache(), nil
+	case strings.HasPrefix(flags.outputCache, "redis://") || strings.HasPrefix(flags.outputCache, "rediss://"):
+		return terragrunt.NewRedisOutputCache(flags.outputCache)
+	default:
+		return terragrunt.NewDiskOutputCache(flags.outputCache)
+	}
+}
+
+// moduleLoader fetches and parses the modules of units, once per unit.
+type moduleLoader struct {
+	fetcher *terragrunt.SourceFetcher
+	modules map[string]*terragrunt.Module
+}
+
+func newModuleLoader() *moduleLoader {
+	return &moduleLoader{
+		fetcher: &terragrunt.SourceFetcher{Registry: &terragrunt.RegistryClient{}},
+		modules: map[string]*terragrunt.Module{},
+	}
+}
+
+func (loader *moduleLoader) load(unit *terragrunt.Unit) (*terragrunt.Module, error) {
+	if module, found := loader.modules[unit.Path]; found {
+		return module, nil
+	}
+
+	moduleDir, err := loader.fetcher.FetchUnit(context.Background(), unit)
+	if err != nil {
+		return nil, err
+	}
+	module, err := terragrunt.ParseModule(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	loader.modules[unit.Path] = module
+	return module, nil
+}
+
+// relativePath returns the path of the unit relative to the stack root, for display.
+func relativePath(stack *terragrunt.Stack, path string) string {
+	relPath, err := filepath.Rel(stack.Root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runMigrateTfvars(args []string) error {
+	flagSet := flag.NewFlagSet("migrate-tfvars", flag.ExitOnError)
+	write := flagSet.Bool("w", false, "write the converted configuration to terragrunt.hcl next to the file instead of printing it")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils migrate-tfvars [-w] <terraform.tfvars>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Converts a terraform.tfvars file with a terragrunt variable, as used before terragrunt 0.19, to terragrunt.hcl.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return errors.New("missing terraform.tfvars file")
+	}
+	path := flagSet.Arg(0)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	converted, err := terragrunt.ConvertLegacyTFVars(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !*write {
+		fmt.Print(string(converted))
+		return nil
+	}
+	configPath := filepath.Join(filepath.Dir(path), terragrunt.DefaultConfigFilename)
+	if _, err := os.Stat(configPath); err == nil {
+		return fmt.Errorf("%s already exists", configPath)
+	}
+	return os.WriteFile(configPath, converted, 0o644)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	terragrunt "terragrunt-utils"
+)
+
+type outputTypeJSON struct {
+	Type      string `json:"type"`
+	Sensitive bool   `json:"sensitive"`
+	Source    string `json:"source"`
+}
+
+func runOutputTypes(args []string) error {
+	flagSet := flag.NewFlagSet("output-types", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	var targets stringsFlag
+	flagSet.Var(&targets, "unit", "unit whose dependencies to describe, relative to the directory, instead of every unit (can be repeated)")
+	format := flagSet.String("format", "text", "output format of the types: text or json")
+	flagSet.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+	units := stack.Units
+	if len(targets) > 0 {
+		units = nil
+		for _, target := range targets {
+			unit := stack.Unit(filepath.Join(stack.Root, filepath.FromSlash(target)))
+			if unit == nil {
+				return fmt.Errorf("unit %s is not in the stack", target)
+			}
+			units = append(units, unit)
+		}
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+	schemaOpts := terragrunt.OutputSchemaOptions{
+		Fetcher: &terragrunt.SourceFetcher{Registry: &terragrunt.RegistryClient{}},
+		Options: opts,
+	}
+	if flags.resolveOutputs || flags.outputsFromState {
+		if schemaOpts.Resolver, err = flags.stateOutputResolver(opts); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Units, then dependency names, then output names.
+	document := map[string]map[string]map[string]outputTypeJSON{}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, unit := range units {
+		if len(unit.DependencyPaths()) == 0 {
+			continue
+		}
+		schemas, err := stack.DependencyOutputSchemas(ctx, unit, schemaOpts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", relativePath(stack, unit.Path), err)
+		}
+
+		path := relativePath(stack, unit.Path)
+		document[path] = map[string]map[string]outputTypeJSON{}
+		for _, name := range sortedKeys(schemas) {
+			document[path][name] = map[string]outputTypeJSON{}
+			for _, outputName := range sortedKeys(schemas[name]) {
+				schema := schemas[name][outputName]
+				rendered := outputTypeJSON{Type: typeexpr.TypeString(schema.Type), Sensitive: schema.Sensitive, Source: string(schema.Source)}
+				document[path][name][outputName] = rendered
+				if *format == "text" {
+					sensitive := ""
+					if schema.Sensitive {
+						sensitive = " (sensitive)"
+					}
+					fmt.Fprintf(writer, "%s\tdependency.%s.outputs.%s\t%s\t%s%s\n", path, name, outputName, rendered.Type, rendered.Source, sensitive)
+				}
+			}
+		}
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	}
+	return writer.Flush()
+}
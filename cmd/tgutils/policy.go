@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+// stringsFlag is a flag that can be repeated, collecting every value.
+type stringsFlag []string
+
+func (values *stringsFlag) String() string {
+	return strings.Join(*values, ",")
+}
+
+func (values *stringsFlag) Set(value string) error {
+	*values = append(*values, value)
+	return nil
+}
+
+func runPolicy(args []string) error {
+	flagSet := flag.NewFlagSet("policy", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	var policies stringsFlag
+	flagSet.Var(&policies, "policy", "rego file, or directory of rego files, to evaluate (can be repeated)")
+	pkg := flagSet.String("package", "terragrunt", "package of the deny, violation and warn rules")
+	opa := flagSet.String("opa", "", "opa binary to evaluate the policies with, by name or by path")
+	flagSet.Parse(args)
+
+	if len(policies) == 0 {
+		return errors.New("at least one -policy is required")
+	}
+	if *opa == "" {
+		return errors.New("-opa is required: the policies are evaluated by running the given opa binary")
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	evaluator := terragrunt.ExecOPAEvaluator{Command: *opa, Policies: policies, Package: *pkg}
+	violations, err := terragrunt.EvaluatePolicies(context.Background(), stack, evaluator)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, unit := range stack.Units {
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, unit.ConfigPath), unit.Err)
+			failed = true
+		}
+	}
+	for _, violation := range violations {
+		fmt.Printf("%s: %s: %s (%s)\n", relativePath(stack, violation.Unit.Path), violation.Severity, violation.Message, violation.Rule)
+		failed = failed || violation.Severity == terragrunt.SeverityError
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runProviders(args []string) error {
+	flagSet := flag.NewFlagSet("providers", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	loader := newModuleLoader()
+	modules := map[string]*terragrunt.Module{}
+	for _, unit := range stack.Units {
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, unit.ConfigPath), unit.Err)
+			failed = true
+			continue
+		}
+		module, err := loader.load(unit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, unit.ConfigPath), err)
+			failed = true
+			continue
+		}
+		modules[relativePath(stack, unit.Path)] = module
+	}
+
+	for _, usage := range terragrunt.AggregateProviders(modules) {
+		conflict := ""
+		if usage.Conflict {
+			conflict = " (conflicting version constraints)"
+			failed = true
+		}
+		fmt.Printf("%s%s\n", usage.Source, conflict)
+		for _, unit := range usage.Units {
+			constraints := strings.Join(unit.VersionConstraints, ", ")
+			if constraints == "" {
+				constraints = "any version"
+			}
+			fmt.Printf("  %s: %s\n", unit.Unit, constraints)
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	terragrunt "terragrunt-utils"
+)
+
+func runQuery(args []string) error {
+	flagSet := flag.NewFlagSet("query", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils query [flags] <expr> [dir]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Evaluates the HCL expression against every unit. Boolean expressions select the units for which they")
+		fmt.Fprintln(os.Stderr, "are true, e.g. 'inputs.instance_type == \"m5.large\"'. Other expressions print their value per unit.")
+		fmt.Fprintln(os.Stderr, "Use try() for inputs that are not set by every unit, e.g. 'try(inputs.instance_type, null)'.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return errors.New("missing expression")
+	}
+	expression := flagSet.Arg(0)
+	flagSet.Parse(flagSet.Args()[1:])
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, unit := range stack.Units {
+		relPath := relativePath(stack, unit.Path)
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %s\n", relPath, unit.Err)
+			continue
+		}
+
+		value, err := terragrunt.Query(unit.Config, expression)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relPath, err)
+			failed = true
+			continue
+		}
+
+		value = terragrunt.Redact(value)
+		switch {
+		case value.Type() == cty.Bool && value.IsKnown() && !value.IsNull():
+			if value.True() {
+				fmt.Println(relPath)
+			}
+		case !value.IsWhollyKnown():
+			fmt.Printf("%s\t(known after apply)\n", relPath)
+		default:
+			fmt.Printf("%s\t%s\n", relPath, hclwrite.TokensForValue(value).Bytes())
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func runListInputs(args []string) error {
+	flagSet := flag.NewFlagSet("list-inputs", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range stack.Units {
+		relPath := relativePath(stack, unit.Path)
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %s\n", relPath, unit.Err)
+			continue
+		}
+
+		for _, name := range sortedKeys(unit.Config.InputsCty) {
+			value := terragrunt.Redact(unit.Config.InputsCty[name])
+			if !value.IsWhollyKnown() {
+				fmt.Printf("%s\t%s\t(known after apply)\n", relPath, name)
+				continue
+			}
+			fmt.Printf("%s\t%s\t%s\n", relPath, name, hclwrite.TokensForValue(value).Bytes())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runRenderJSON(args []string) error {
+	flagSet := flag.NewFlagSet("render-json", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	// The rendered units are keyed by their path relative to the directory, so that the output can be processed
+	// the same way whether it holds one or many units.
+	rendered := map[string]json.RawMessage{}
+	failed := false
+	for _, unit := range stack.Units {
+		if unit.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", relativePath(stack, unit.ConfigPath), unit.Err)
+			failed = true
+			continue
+		}
+
+		renderedUnit, err := terragrunt.RenderJSON(unit.Config)
+		if err != nil {
+			return err
+		}
+		rendered[relativePath(stack, unit.Path)] = renderedUnit
+	}
+
+	out, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+
+	terragrunt "terragrunt-utils"
+)
+
+// findingsReport returns the report of the given findings of the units of the given stack, grouped by the unit whose
+// configuration file they are located in.
+func findingsReport(stack *terragrunt.Stack, findings []terragrunt.Finding) *terragrunt.Report {
+	report := &terragrunt.Report{Title: "Terragrunt validation", BaseDir: "."}
+	units := map[string]*terragrunt.UnitReport{}
+	for _, unit := range stack.Units {
+		units[unit.ConfigPath] = report.Unit(unit.Path)
+	}
+	for _, finding := range findings {
+		if unit, found := units[finding.Range.Filename]; found {
+			unit.Findings = append(unit.Findings, finding)
+			continue
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	return report
+}
+
+// writeReport writes the given report as markdown to the file at the given path.
+func writeReport(path string, report *terragrunt.Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteMarkdown(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
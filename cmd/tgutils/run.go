@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	terragrunt "terragrunt-utils"
+)
+
+// runView renders the progress of the run command: the parsing of the units, the dependency graph they are run in and
+// the logs of their commands.
+type runView interface {
+	// setGraph is called once the units are parsed, with the batches they run in.
+	setGraph(stack *terragrunt.Stack, batches [][]string, blocked []terragrunt.BlockedDestroy)
+	event(event terragrunt.ProgressEvent)
+	log(unit, line string)
+	close()
+}
+
+func runRun(args []string) error {
+	flagSet := flag.NewFlagSet("run", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	command := flagSet.String("command", "plan", "terragrunt command to run in every unit, with its arguments (e.g. \"apply -auto-approve\")")
+	settings := runSettings{flags: &flags, flagSet: flagSet}
+	flagSet.StringVar(&settings.binary, "terragrunt", "terragrunt", "terragrunt binary to run")
+	flagSet.IntVar(&settings.parallelism, "parallelism", 0, "maximum number of units to run at once, every unit of a batch when 0")
+	flagSet.BoolVar(&settings.allowPreventDestroy, "allow-prevent-destroy", false, "destroy the units setting prevent_destroy = true like the other ones")
+	tui := flagSet.Bool("tui", false, "show the dependency graph, the status of every unit and the logs in a terminal UI")
+	reportPath := flagSet.String("report", "", "write the plan summaries, errors and outputs of the units to the given file as a markdown report for pull request comments")
+	flagSet.Parse(args)
+
+	settings.commandArgs = strings.Fields(*command)
+	if len(settings.commandArgs) == 0 {
+		return errors.New("-command can not be empty")
+	}
+	if *reportPath != "" {
+		settings.report = &terragrunt.Report{Title: "terragrunt " + *command, BaseDir: "."}
+	}
+
+	root, err := filepath.Abs(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var view runView
+	if *tui {
+		view = newTerminalView(os.Stdout, root, *command, stop)
+	} else {
+		view = &lineView{out: os.Stderr, root: root}
+	}
+
+	events := make(chan terragrunt.ProgressEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			view.event(event)
+		}
+	}()
+	err = runUnits(ctx, view, settings, events)
+	close(events)
+	<-done
+	view.close()
+	if settings.report != nil {
+		if reportErr := writeReport(*reportPath, settings.report); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}
+	return err
+}
+
+// runSettings are the settings of the run command.
+type runSettings struct {
+	flags               *stackFlags
+	flagSet             *flag.FlagSet
+	commandArgs         []string
+	binary              string
+	parallelism         int
+	allowPreventDestroy bool
+
+	// report, when set, receives the errors and outputs of the units, and the summaries of their plans.
+	report *terragrunt.Report
+}
+
+// runUnits parses the units and runs the command in each of them, in the order of the batches of the action of the
+// command.
+func runUnits(ctx context.Context, view runView, settings runSettings, events chan<- terragrunt.ProgressEvent) error {
+	stack, err := settings.flags.parseStack(settings.flagSet, terragrunt.WithContext(ctx), terragrunt.WithProgress(events))
+	if err != nil {
+		return err
+	}
+
+	var batches [][]string
+	var blocked []terragrunt.BlockedDestroy
+	if settings.commandArgs[0] == "destroy" {
+		plan, err := stack.Graph().DestroyPlan(terragrunt.DestroyOptions{AllowPreventDestroy: settings.allowPreventDestroy})
+		if err != nil {
+			return err
+		}
+		batches, blocked = plan.Batches, plan.Blocked
+	} else if batches, err = stack.Graph().BatchesFor(settings.commandArgs[0]); err != nil {
+		return err
+	}
+	view.setGraph(stack, batches, blocked)
+
+	invalid := false
+	for _, unit := range stack.Units {
+		if settings.report != nil {
+			settings.report.Unit(unit.Path).Err = unit.Err
+		}
+		invalid = invalid || unit.Err != nil
+	}
+	if invalid {
+		return errFailed
+	}
+
+	runner := terragrunt.ExecCommandRunner{}
+	var reportMutex sync.Mutex
+	err = terragrunt.RunBatches(batches, settings.parallelism, func(ctx context.Context, path string) error {
+		logs := &lineWriter{emit: func(line string) { view.log(path, line) }}
+		defer logs.flush()
+		output, err := runner.Run(ctx, terragrunt.Command{
+			Name:   settings.binary,
+			Args:   settings.commandArgs,
+			Dir:    path,
+			Env:    []string{"TF_INPUT=0", "TERRAGRUNT_NON_INTERACTIVE=true"},
+			Stdout: logs,
+			Stderr: logs,
+		})
+
+		if settings.report != nil {
+			reportMutex.Lock()
+			defer reportMutex.Unlock()
+			unit := settings.report.Unit(path)
+			unit.Err = err
+			unit.Output = string(output.Stdout) + string(output.Stderr)
+			if settings.commandArgs[0] == "plan" && err == nil {
+				unit.Plan, _ = terragrunt.ParsePlanOutput(output.Stdout)
+			}
+		}
+		return err
+	}, terragrunt.WithContext(ctx), terragrunt.WithProgress(events))
+	var runErr *terragrunt.UnitRunError
+	if errors.As(err, &runErr) || len(blocked) > 0 {
+		return errFailed
+	}
+	return err
+}
+
+// lineWriter calls emit with every line written to it. It is safe for concurrent use, so that the standard output and
+// error of a command can both be written to it.
+type lineWriter struct {
+	emit func(line string)
+
+	mutex  sync.Mutex
+	buffer []byte
+}
+
+func (writer *lineWriter) Write(data []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.buffer = append(writer.buffer, data...)
+	for {
+		end := bytes.IndexByte(writer.buffer, '\n')
+		if end < 0 {
+			return len(data), nil
+		}
+		writer.emit(strings.TrimRight(string(writer.buffer[:end]), "\r"))
+		writer.buffer = writer.buffer[end+1:]
+	}
+}
+
+// flush emits the last line written, when it does not end with a newline.
+func (writer *lineWriter) flush() {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if len(writer.buffer) > 0 {
+		writer.emit(string(writer.buffer))
+		writer.buffer = nil
+	}
+}
+
+// lineView prints the progress of the run command as plain lines, for logs and terminals without -tui.
+type lineView struct {
+	out  io.Writer
+	root string
+
+	mutex sync.Mutex
+}
+
+func (view *lineView) printf(format string, args ...interface{}) {
+	view.mutex.Lock()
+	defer view.mutex.Unlock()
+	fmt.Fprintf(view.out, format, args...)
+}
+
+func (view *lineView) path(path string) string {
+	return unitRelativePath(view.root, path)
+}
+
+func (view *lineView) setGraph(stack *terragrunt.Stack, batches [][]string, blocked []terragrunt.BlockedDestroy) {
+	view.root = stack.Root
+	for i, batch := range batches {
+		paths := make([]string, len(batch))
+		for j, path := range batch {
+			paths[j] = view.path(path)
+		}
+		view.printf("batch %d: %s\n", i+1, strings.Join(paths, ", "))
+	}
+	for _, unit := range blocked {
+		view.printf("%s: not destroyed (%s)\n", view.path(unit.Path), unit.Reason)
+	}
+}
+
+func (view *lineView) event(event terragrunt.ProgressEvent) {
+	switch event.Type {
+	case terragrunt.ProgressUnitStarted:
+		if event.Batch > 0 {
+			view.printf("%s: started\n", view.path(event.Unit))
+		}
+	case terragrunt.ProgressUnitFinished:
+		switch {
+		case event.Err != nil && event.Batch > 0:
+			view.printf("%s: failed after %s: %s\n", view.path(event.Unit), event.Duration.Round(time.Millisecond), event.Err)
+		case event.Err != nil:
+			view.printf("%s: %s\n", view.path(event.Unit), event.Err)
+		case event.Batch > 0:
+			view.printf("%s: finished in %s\n", view.path(event.Unit), event.Duration.Round(time.Millisecond))
+		}
+	case terragrunt.ProgressBatchCompleted:
+		view.printf("batch %d: completed in %s\n", event.Batch, event.Duration.Round(time.Millisecond))
+	}
+}
+
+func (view *lineView) log(unit, line string) {
+	view.printf("[%s] %s\n", view.path(unit), line)
+}
+
+func (view *lineView) close() {}
+
+// unitRelativePath returns the given path relative to the given root, like relativePath, before the stack is parsed.
+func unitRelativePath(root, path string) string {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	terragrunt "terragrunt-utils"
+)
+
+func runScaffold(args []string) error {
+	flagSet := flag.NewFlagSet("scaffold", flag.ExitOnError)
+	templateDir := flagSet.String("template", "", "directory of the boilerplate template to render")
+	var vars stringsFlag
+	flagSet.Var(&vars, "var", "value of a variable of the template, as name=value (can be repeated)")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils scaffold -template dir [-var name=value] <dir>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Renders the template into the given directory, which must not hold the rendered files yet.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if *templateDir == "" || flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return errors.New("missing template or target directory")
+	}
+
+	values := map[string]cty.Value{}
+	for _, variable := range vars {
+		name, value, found := strings.Cut(variable, "=")
+		if !found {
+			return fmt.Errorf("invalid -var %q, expected name=value", variable)
+		}
+		values[name] = cty.StringVal(value)
+	}
+
+	files, err := terragrunt.Scaffold(*templateDir, flagSet.Arg(0), values)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Println(file)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runSchema(args []string) error {
+	flagSet := flag.NewFlagSet("schema", flag.ExitOnError)
+	format := flagSet.String("format", "json-schema", "output format: json-schema, for terragrunt.hcl.json files, or blocks, listing the blocks and attributes with their type for completion")
+	stackFile := flagSet.Bool("stack-file", false, "describe terragrunt.stack.hcl instead of terragrunt.hcl")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils schema [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Prints the schema of the configuration files, as decoded by this package.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	schema := terragrunt.TerragruntSchema()
+	if *stackFile {
+		schema = terragrunt.StackFileSchema()
+	}
+
+	var out []byte
+	var err error
+	switch *format {
+	case "json-schema":
+		out, err = terragrunt.JSONSchema(schema)
+	case "blocks":
+		out, err = json.MarshalIndent(schema, "", "  ")
+	default:
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
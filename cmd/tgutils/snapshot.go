@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func runSnapshot(args []string) error {
+	flagSet := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	output := flagSet.String("o", "", "write the snapshot to this file instead of stdout")
+	flagSet.Parse(args)
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := stack.Snapshot()
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		return os.WriteFile(*output, append(snapshot, '\n'), 0o644)
+	}
+	fmt.Println(string(snapshot))
+	return nil
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	terragrunt "terragrunt-utils"
+)
+
+// ansiSequence matches the escape sequences of the logs, which would break the layout of the terminal view.
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// tuiRefresh is the interval at which the spinners and durations of the terminal view are updated.
+const tuiRefresh = 100 * time.Millisecond
+
+// tuiLogLines is the number of log lines the terminal view keeps.
+const tuiLogLines = 1000
+
+// tuiFailedLogLines is the number of log lines of every failed unit printed once the terminal view is closed.
+const tuiFailedLogLines = 20
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// The styles the terminal view is drawn with.
+var (
+	boldStyle   = lipgloss.NewStyle().Bold(true)
+	dimStyle    = lipgloss.NewStyle().Faint(true)
+	redStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	greenStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	yellowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	cyanStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+)
+
+type unitStatus int
+
+const (
+	unitParsing unitStatus = iota
+	unitInvalid
+	unitPending
+	unitRunning
+	unitSucceeded
+	unitFailed
+	unitNotRun
+)
+
+// tuiUnit is the state of a unit in the terminal view.
+type tuiUnit struct {
+	path         string
+	status       unitStatus
+	dependencies []string
+	started      time.Time
+	duration     time.Duration
+	err          error
+
+	// reason is why a unit of the graph is not run.
+	reason string
+}
+
+// The messages sent to the terminal view program.
+type (
+	tuiTickMsg  time.Time
+	tuiEventMsg terragrunt.ProgressEvent
+	tuiCloseMsg struct{}
+	tuiLogMsg   struct{ unit, line string }
+	tuiGraphMsg struct {
+		stack   *terragrunt.Stack
+		batches [][]string
+		blocked []terragrunt.BlockedDestroy
+	}
+)
+
+// terminalView draws the dependency graph of the run command, the status of every unit and the tail of the logs on
+// the alternate screen of the terminal with bubbletea, redrawn as the units progress. The logs are scrolled with the
+// arrow and page keys, and ctrl+c interrupts the run. Once closed, it prints the graph and the errors on the main
+// screen.
+type terminalView struct {
+	out     io.Writer
+	program *tea.Program
+	model   *tuiModel
+	done    chan struct{}
+}
+
+func newTerminalView(out io.Writer, root, command string, interrupt func()) *terminalView {
+	model := &tuiModel{
+		root:      root,
+		command:   command,
+		start:     time.Now(),
+		units:     map[string]*tuiUnit{},
+		width:     80,
+		height:    24,
+		interrupt: interrupt,
+	}
+	view := &terminalView{
+		out:     out,
+		program: tea.NewProgram(model, tea.WithOutput(out), tea.WithAltScreen(), tea.WithoutSignalHandler()),
+		model:   model,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(view.done)
+		view.program.Run()
+	}()
+	return view
+}
+
+func (view *terminalView) setGraph(stack *terragrunt.Stack, batches [][]string, blocked []terragrunt.BlockedDestroy) {
+	view.program.Send(tuiGraphMsg{stack: stack, batches: batches, blocked: blocked})
+}
+
+func (view *terminalView) event(event terragrunt.ProgressEvent) {
+	view.program.Send(tuiEventMsg(event))
+}
+
+func (view *terminalView) log(unit, line string) {
+	view.program.Send(tuiLogMsg{unit: unit, line: line})
+}
+
+func (view *terminalView) close() {
+	view.program.Send(tuiCloseMsg{})
+	<-view.done
+
+	model := view.model
+	lines := model.graphLines(time.Now())
+	for _, path := range model.order {
+		unit := model.units[path]
+		if unit.err != nil {
+			lines = append(lines, "", redStyle.Render(unitRelativePath(model.root, path)+":"))
+			for _, line := range strings.Split(strings.TrimRight(unit.err.Error(), "\n"), "\n") {
+				lines = append(lines, "  "+line)
+			}
+			lines = append(lines, model.unitLogs(path, tuiFailedLogLines)...)
+		}
+	}
+	fmt.Fprintln(view.out, strings.Join(lines, "\n"))
+}
+
+// tuiModel is the bubbletea model of the terminal view. It is only accessed by the program, and by terminalView once
+// the program is done.
+type tuiModel struct {
+	root      string
+	command   string
+	start     time.Time
+	interrupt func()
+
+	units    map[string]*tuiUnit
+	order    []string
+	batches  [][]string
+	batch    int
+	logs     []string
+	hasGraph bool
+	width    int
+	height   int
+	frame    int
+
+	// scroll is the number of log lines hidden below the screen, 0 to follow the logs.
+	scroll int
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(tuiRefresh, func(now time.Time) tea.Msg {
+		return tuiTickMsg(now)
+	})
+}
+
+func (model *tuiModel) Init() tea.Cmd {
+	return tuiTick()
+}
+
+func (model *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tuiTickMsg:
+		model.frame++
+		return model, tuiTick()
+	case tea.WindowSizeMsg:
+		model.width, model.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			model.interrupt()
+		case "up", "k":
+			model.scrollLogs(1)
+		case "down", "j":
+			model.scrollLogs(-1)
+		case "pgup":
+			model.scrollLogs(model.logHeight())
+		case "pgdown":
+			model.scrollLogs(-model.logHeight())
+		case "home", "g":
+			model.scrollLogs(len(model.logs))
+		case "end", "G":
+			model.scroll = 0
+		}
+	case tuiGraphMsg:
+		model.setGraph(msg.stack, msg.batches, msg.blocked)
+	case tuiEventMsg:
+		model.event(terragrunt.ProgressEvent(msg))
+	case tuiLogMsg:
+		model.addLog(msg.unit, msg.line)
+	case tuiCloseMsg:
+		return model, tea.Quit
+	}
+	return model, nil
+}
+
+// unit returns the state of the unit at the given path, adding it when new.
+func (model *tuiModel) unit(path string) *tuiUnit {
+	unit, found := model.units[path]
+	if !found {
+		unit = &tuiUnit{path: path}
+		model.units[path] = unit
+		model.order = append(model.order, path)
+	}
+	return unit
+}
+
+func (model *tuiModel) setGraph(stack *terragrunt.Stack, batches [][]string, blocked []terragrunt.BlockedDestroy) {
+	model.root = stack.Root
+	model.batches = batches
+	model.hasGraph = true
+
+	scheduled := map[string]bool{}
+	for _, batch := range batches {
+		for _, path := range batch {
+			scheduled[path] = true
+		}
+	}
+	reasons := map[string]string{}
+	for _, unit := range blocked {
+		reasons[unit.Path] = string(unit.Reason)
+	}
+	graph := stack.Graph()
+	for _, unit := range stack.Units {
+		state := model.unit(unit.Path)
+		state.dependencies = graph.Dependencies(unit.Path)
+		switch {
+		case unit.Err != nil:
+			state.status, state.err = unitInvalid, unit.Err
+		case scheduled[unit.Path]:
+			state.status = unitPending
+		default:
+			state.status, state.reason = unitNotRun, reasons[unit.Path]
+		}
+	}
+}
+
+func (model *tuiModel) event(event terragrunt.ProgressEvent) {
+	switch event.Type {
+	case terragrunt.ProgressUnitStarted:
+		unit := model.unit(event.Unit)
+		unit.started = event.Time
+		if event.Batch > 0 {
+			unit.status = unitRunning
+			model.batch = event.Batch
+		}
+	case terragrunt.ProgressUnitFinished:
+		unit := model.unit(event.Unit)
+		if event.Batch == 0 && model.hasGraph {
+			// The status of the parsed units is set by setGraph.
+			break
+		}
+		unit.duration, unit.err = event.Duration, event.Err
+		switch {
+		case event.Batch == 0 && event.Err != nil:
+			unit.status = unitInvalid
+		case event.Batch == 0:
+			unit.status = unitPending
+		case event.Err != nil:
+			unit.status = unitFailed
+		default:
+			unit.status = unitSucceeded
+		}
+	case terragrunt.ProgressOutputFetched:
+		model.addLog(event.Unit, fmt.Sprintf("outputs of dependency %q fetched from %s", event.Dependency, unitRelativePath(model.root, event.DependencyPath)))
+	case terragrunt.ProgressDiagnostics:
+		for _, diag := range event.Diagnostics {
+			model.addLog(event.Unit, diag.Error())
+		}
+	}
+}
+
+func (model *tuiModel) addLog(unit, line string) {
+	line = strings.ReplaceAll(ansiSequence.ReplaceAllString(line, ""), "\t", "    ")
+	model.logs = append(model.logs, "["+unitRelativePath(model.root, unit)+"] "+line)
+	if len(model.logs) > tuiLogLines {
+		model.logs = model.logs[len(model.logs)-tuiLogLines:]
+	}
+	if model.scroll > 0 {
+		// The scrolled logs stay in place as new lines are added.
+		model.scrollLogs(1)
+	}
+}
+
+// scrollLogs scrolls the logs up by the given number of lines, or down when negative.
+func (model *tuiModel) scrollLogs(lines int) {
+	model.scroll += lines
+	if max := len(model.logs) - model.logHeight(); model.scroll > max {
+		model.scroll = max
+	}
+	if model.scroll < 0 {
+		model.scroll = 0
+	}
+}
+
+// unitLogs returns the last lines of the logs of the given unit, at most max.
+func (model *tuiModel) unitLogs(path string, max int) []string {
+	prefix := "[" + unitRelativePath(model.root, path) + "] "
+	var logs []string
+	for _, line := range model.logs {
+		if strings.HasPrefix(line, prefix) {
+			logs = append(logs, "  "+line)
+		}
+	}
+	if len(logs) > max {
+		logs = logs[len(logs)-max:]
+	}
+	return logs
+}
+
+// graphHeight returns the number of lines the graph takes at the top of the screen, leaving at least a few lines to
+// the logs.
+func (model *tuiModel) graphHeight(graph []string) int {
+	maxGraph := model.height - 6
+	if maxGraph < 3 {
+		maxGraph = 3
+	}
+	if len(graph) > maxGraph {
+		return maxGraph
+	}
+	return len(graph)
+}
+
+// logHeight returns the number of log lines shown below the graph.
+func (model *tuiModel) logHeight() int {
+	logLines := model.height - model.graphHeight(model.graphLines(time.Now())) - 3
+	if logLines < 0 {
+		return 0
+	}
+	return logLines
+}
+
+func (model *tuiModel) View() string {
+	now := time.Now()
+	graph := model.graphLines(now)
+	if height := model.graphHeight(graph); height < len(graph) {
+		hidden := len(graph) - height + 1
+		graph = append(graph[:height-1], dimStyle.Render(fmt.Sprintf("  … %d more lines", hidden)))
+	}
+
+	title := "logs"
+	if model.scroll > 0 {
+		title += fmt.Sprintf(" (%d lines below, end to follow)", model.scroll)
+	}
+	lines := append(graph, "", boldStyle.Render(title))
+	logHeight := model.logHeight()
+	end := len(model.logs) - model.scroll
+	start := end - logHeight
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range model.logs[start:end] {
+		lines = append(lines, "  "+line)
+	}
+
+	line := lipgloss.NewStyle().MaxWidth(model.width)
+	for i := range lines {
+		lines[i] = line.Render(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// graphLines returns the header and the units of the view, grouped by the batch they run in once known.
+func (model *tuiModel) graphLines(now time.Time) []string {
+	counts := map[unitStatus]int{}
+	for _, unit := range model.units {
+		counts[unit.status]++
+	}
+	header := boldStyle.Render("tgutils run "+model.command) + "  " + now.Sub(model.start).Round(time.Second).String()
+	switch {
+	case !model.hasGraph:
+		header += fmt.Sprintf("  parsing, %d units", len(model.units))
+	default:
+		if model.batch > 0 {
+			header += fmt.Sprintf("  batch %d/%d", model.batch, len(model.batches))
+		}
+		header += fmt.Sprintf("  %d succeeded, %d failed, %d running, %d pending", counts[unitSucceeded], counts[unitFailed], counts[unitRunning], counts[unitPending])
+	}
+	lines := []string{header}
+
+	if !model.hasGraph {
+		for _, path := range model.order {
+			lines = append(lines, model.unitLine(model.units[path], now))
+		}
+		return lines
+	}
+
+	listed := map[string]bool{}
+	for i, batch := range model.batches {
+		lines = append(lines, boldStyle.Render(fmt.Sprintf("batch %d", i+1)))
+		for _, path := range batch {
+			listed[path] = true
+			lines = append(lines, model.unitLine(model.units[path], now))
+		}
+	}
+	var others []string
+	for _, path := range model.order {
+		if !listed[path] {
+			others = append(others, model.unitLine(model.units[path], now))
+		}
+	}
+	if len(others) > 0 {
+		lines = append(lines, boldStyle.Render("not run"))
+		lines = append(lines, others...)
+	}
+	return lines
+}
+
+// unitLine returns the line of the given unit: its status, path, dependencies and duration.
+func (model *tuiModel) unitLine(unit *tuiUnit, now time.Time) string {
+	var symbol, status string
+	switch unit.status {
+	case unitParsing:
+		symbol, status = cyanStyle.Render(spinnerFrames[model.frame%len(spinnerFrames)]), "parsing"
+	case unitInvalid:
+		symbol, status = redStyle.Render("✗"), "invalid"
+	case unitPending:
+		symbol, status = dimStyle.Render("·"), "pending"
+	case unitRunning:
+		symbol, status = yellowStyle.Render(spinnerFrames[model.frame%len(spinnerFrames)]), "running "+now.Sub(unit.started).Round(100*time.Millisecond).String()
+	case unitSucceeded:
+		symbol, status = greenStyle.Render("✓"), unit.duration.Round(100*time.Millisecond).String()
+	case unitFailed:
+		symbol, status = redStyle.Render("✗"), "failed after "+unit.duration.Round(100*time.Millisecond).String()
+	case unitNotRun:
+		symbol, status = dimStyle.Render("-"), "not run"
+		if unit.reason != "" {
+			status += " (" + unit.reason + ")"
+		}
+	}
+
+	line := "  " + symbol + " " + unitRelativePath(model.root, unit.path)
+	if len(unit.dependencies) > 0 {
+		dependencies := make([]string, len(unit.dependencies))
+		for i, dependency := range unit.dependencies {
+			dependencies[i] = unitRelativePath(model.root, dependency)
+		}
+		line += dimStyle.Render(" ← " + strings.Join(dependencies, ", "))
+	}
+	return line + "  " + status
+}
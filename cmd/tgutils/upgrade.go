@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runUpgrade(args []string) error {
+	flagSet := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	write := flagSet.Bool("w", false, "write the upgraded configuration to the file instead of printing the diff")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tgutils upgrade [-w] <config>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Rewrites the deprecated constructs of the given configuration, and reports the ones to upgrade by hand.")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return errors.New("missing configuration file")
+	}
+	path := flagSet.Arg(0)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	result, err := terragrunt.Upgrade(content)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range result.Notes {
+		kind := "upgraded"
+		if note.Manual {
+			kind = "manual"
+		}
+		fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", path, note.Range.Start.Line, kind, note.Message)
+	}
+	if !*write {
+		fmt.Print(result.Diff)
+		return nil
+	}
+	if result.Diff == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, result.Content, info.Mode().Perm())
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	terragrunt "terragrunt-utils"
+)
+
+func runValidate(args []string) error {
+	flagSet := flag.NewFlagSet("validate", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	checkInputs := flagSet.Bool("check-inputs", false, "fetch the module of every unit and check its inputs against the module variables")
+	checkOutputs := flagSet.Bool("check-outputs", false, "fetch the module of every unit and check the dependency outputs used against the module outputs")
+	var allowSources, denySources, allowEngines stringsFlag
+	flagSet.Var(&allowSources, "allow-source", "pattern of the allowed terraform sources, with * matching anything (can be repeated)")
+	flagSet.Var(&denySources, "deny-source", "pattern of the denied terraform sources, with * matching anything (can be repeated)")
+	flagSet.Var(&allowEngines, "allow-engine", "pattern of the allowed engine sources, or source@version, with * matching anything (can be repeated)")
+	scanSecrets := flagSet.Bool("scan-secrets", false, "check the literal strings of the inputs, locals and generate blocks for hardcoded secrets")
+	format := flagSet.String("format", "text", "output format of the findings: text, json or sarif")
+	reportPath := flagSet.String("report", "", "also write the findings to the given file as a markdown report for pull request comments")
+	flagSet.Parse(args)
+
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	stack, err := flags.parseStack(flagSet)
+	if err != nil {
+		return err
+	}
+
+	var findings []terragrunt.Finding
+	invalid := 0
+	for _, unit := range stack.Units {
+		if unit.Err != nil {
+			findings = append(findings, terragrunt.FindingsFromError(unit.Err, unit.ConfigPath)...)
+			invalid++
+		}
+		if unit.Config != nil {
+			findings = append(findings, unit.Config.Warnings...)
+		}
+	}
+
+	if *checkInputs || *checkOutputs {
+		moduleFindings, moduleInvalid := checkUnitModules(stack, *checkInputs, *checkOutputs)
+		findings = append(findings, moduleFindings...)
+		invalid += moduleInvalid
+	}
+
+	if len(allowSources) > 0 || len(denySources) > 0 {
+		ruleFindings := terragrunt.NewRuleRunner(terragrunt.SourcePolicyRule(allowSources, denySources)).Run(stack)
+		findings = append(findings, ruleFindings...)
+		invalid += countInvalidFiles(ruleFindings)
+	}
+
+	if len(allowEngines) > 0 {
+		ruleFindings := terragrunt.NewRuleRunner(terragrunt.EnginePolicyRule(allowEngines)).Run(stack)
+		findings = append(findings, ruleFindings...)
+		invalid += countInvalidFiles(ruleFindings)
+	}
+
+	if *scanSecrets {
+		secretFindings, err := scanUnitSecrets(stack)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, secretFindings...)
+		invalid += countInvalidFiles(secretFindings)
+	}
+
+	if _, err := stack.Graph().Batches(); err != nil {
+		findings = append(findings, terragrunt.Finding{
+			RuleID:   terragrunt.RuleDependencyCycle,
+			Severity: terragrunt.SeverityError,
+			Message:  err.Error(),
+		})
+		invalid++
+	}
+
+	switch *format {
+	case "json":
+		err = terragrunt.WriteFindingsJSON(os.Stdout, findings, ".")
+	case "sarif":
+		err = terragrunt.WriteFindingsSARIF(os.Stdout, findings, ".")
+	default:
+		for _, finding := range findings {
+			if finding.Range.Filename == "" {
+				fmt.Fprintln(os.Stderr, finding.Message)
+				continue
+			}
+			fmt.Fprintln(os.Stderr, finding)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, findingsReport(stack, findings)); err != nil {
+			return err
+		}
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d units are invalid\n", invalid, len(stack.Units))
+		return errFailed
+	}
+	if *format == "text" {
+		fmt.Printf("%d units are valid\n", len(stack.Units))
+	}
+	return nil
+}
+
+// checkUnitModules returns the findings for the inputs of the units that do not match the variables of their module,
+// and for the dependency outputs they use that are not outputs of the module of their dependency, along with the
+// number of units with errors, not counting the units that could not be parsed, which are already reported. The
+// dependency outputs are checked statically, even for those.
+func checkUnitModules(stack *terragrunt.Stack, checkInputs bool, checkOutputs bool) ([]terragrunt.Finding, int) {
+	modules := newModuleLoader()
+
+	var allFindings []terragrunt.Finding
+	invalid := 0
+	for _, unit := range stack.Units {
+		var findings []terragrunt.Finding
+		var err error
+		if checkInputs && unit.Err == nil {
+			findings, err = unitInputFindings(modules, unit)
+		}
+		if checkOutputs && err == nil {
+			var outputFindings []terragrunt.Finding
+			outputFindings, err = unitOutputFindings(stack, modules, unit)
+			findings = append(findings, outputFindings...)
+		}
+		if err != nil {
+			findings = append(findings, terragrunt.Finding{
+				RuleID:   moduleUnavailable,
+				Severity: terragrunt.SeverityError,
+				Message:  err.Error(),
+				Range:    hcl.Range{Filename: unit.ConfigPath},
+			})
+		}
+
+		hasErrors := false
+		for _, finding := range findings {
+			hasErrors = hasErrors || finding.Severity == terragrunt.SeverityError
+		}
+		if hasErrors && unit.Err == nil {
+			invalid++
+		}
+		allFindings = append(allFindings, findings...)
+	}
+	return allFindings, invalid
+}
+
+// scanUnitSecrets returns the findings for the hardcoded secrets of the units that could be parsed.
+func scanUnitSecrets(stack *terragrunt.Stack) ([]terragrunt.Finding, error) {
+	var findings []terragrunt.Finding
+	for _, unit := range stack.Units {
+		if unit.Err != nil {
+			continue
+		}
+		content, err := os.ReadFile(unit.ConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		unitFindings, err := terragrunt.ScanSecrets(content, terragrunt.WithConfigPath(unit.ConfigPath))
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, unitFindings...)
+	}
+	return findings, nil
+}
+
+// countInvalidFiles returns the number of files with error findings.
+func countInvalidFiles(findings []terragrunt.Finding) int {
+	files := map[string]bool{}
+	for _, finding := range findings {
+		if finding.Severity == terragrunt.SeverityError {
+			files[finding.Range.Filename] = true
+		}
+	}
+	return len(files)
+}
+
+// moduleUnavailable is the rule of the findings reporting modules that could not be fetched or parsed.
+const moduleUnavailable = "module-unavailable"
+
+func unitInputFindings(modules *moduleLoader, unit *terragrunt.Unit) ([]terragrunt.Finding, error) {
+	module, err := modules.load(unit)
+	if err != nil {
+		return nil, err
+	}
+	return terragrunt.ValidateInputs(unit.Config, module), nil
+}
+
+func unitOutputFindings(stack *terragrunt.Stack, modules *moduleLoader, unit *terragrunt.Unit) ([]terragrunt.Finding, error) {
+	dependencyModules := map[string]*terragrunt.Module{}
+	for name, path := range unit.DependencyPaths() {
+		target := stack.Unit(path)
+		if target == nil || target.Err != nil {
+			continue
+		}
+
+		module, err := modules.load(target)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %q: %w", name, err)
+		}
+		dependencyModules[name] = module
+	}
+
+	content, err := os.ReadFile(unit.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return terragrunt.ValidateDependencyOutputs(content, dependencyModules, terragrunt.WithConfigPath(unit.ConfigPath))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	terragrunt "terragrunt-utils"
+)
+
+func runWatch(args []string) error {
+	flagSet := flag.NewFlagSet("watch", flag.ExitOnError)
+	var flags stackFlags
+	flags.register(flagSet)
+	debounce := flagSet.Duration("debounce", terragrunt.DefaultWatchDebounce, "delay to wait for after a change before parsing the units again")
+	flagSet.Parse(args)
+
+	dir := "."
+	if flagSet.NArg() > 0 {
+		dir = flagSet.Arg(0)
+	}
+
+	opts, err := flags.options()
+	if err != nil {
+		return err
+	}
+	watcher, err := terragrunt.NewWatcher(dir, opts...)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	watcher.Debounce = *debounce
+
+	stack := watcher.Stack()
+	fmt.Printf("watching %d units\n", len(stack.Units))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = watcher.Run(ctx, func(events []terragrunt.WatchEvent) {
+		for _, event := range events {
+			printWatchEvent(stack, event)
+		}
+	})
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
+
+func printWatchEvent(stack *terragrunt.Stack, event terragrunt.WatchEvent) {
+	path := relativePath(stack, event.Path)
+	if event.Unit != nil && event.Unit.Err != nil {
+		fmt.Printf("%s %s: %s\n", event.Kind, path, event.Unit.Err)
+		return
+	}
+
+	fmt.Printf("%s %s\n", event.Kind, path)
+	for _, change := range event.Changes {
+		fmt.Printf("  %s %s\n", change.Kind, change.Path)
+	}
+}
@@ -0,0 +1,193 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Command is a command run by a CommandRunner.
+type Command struct {
+	Name string
+	Args []string
+
+	// Dir is the directory the command runs in, the current directory when empty.
+	Dir string
+
+	// Env holds the environment variables, in the KEY=value form, set on top of the environment of the current
+	// process.
+	Env []string
+
+	// Stdin is the standard input of the command, if any.
+	Stdin []byte
+
+	// Stdout and Stderr, when set, receive the output of the command as it runs, e.g. to stream its logs, in addition
+	// to the CommandOutput returned once it exits.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// String returns the command line of the command: its name and arguments joined by spaces.
+func (command Command) String() string {
+	return strings.Join(append([]string{command.Name}, command.Args...), " ")
+}
+
+// CommandOutput is the output of a command run by a CommandRunner.
+type CommandOutput struct {
+	Stdout []byte
+	Stderr []byte
+}
+
+// CommandRunner runs the external commands this package shells out to: terragrunt output, run_cmd(), sops, git, opa
+// and the terraform binary. Run returns an error when the command can not be started or fails, along with the output
+// of the command, and the errors of the commands exiting with a non-zero code implement ExitCode() int, as
+// *exec.ExitError does. ExecCommandRunner runs the commands, and FakeCommandRunner replaces it in tests.
+type CommandRunner interface {
+	Run(ctx context.Context, command Command) (CommandOutput, error)
+
+	// LookPath returns the path of the executable with the given name, as exec.LookPath.
+	LookPath(name string) (string, error)
+}
+
+// WithCommandRunner sets the CommandRunner run_cmd() and ResolveBinary run commands with. The resolvers, decryptors and
+// getters shelling out have a Runner field instead.
+func WithCommandRunner(runner CommandRunner) Option {
+	return func(opts *ParseOptions) {
+		opts.CommandRunner = runner
+	}
+}
+
+// commandRunner returns the given runner, or ExecCommandRunner when it is nil.
+func commandRunner(runner CommandRunner) CommandRunner {
+	if runner == nil {
+		return ExecCommandRunner{}
+	}
+	return runner
+}
+
+// ExecCommandRunner is the CommandRunner running the commands with os/exec.
+type ExecCommandRunner struct{}
+
+func (ExecCommandRunner) Run(ctx context.Context, command Command) (CommandOutput, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Dir = command.Dir
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+	if command.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(command.Stdin)
+	}
+	cmd.Stdout = teeWriter(&stdout, command.Stdout)
+	cmd.Stderr = teeWriter(&stderr, command.Stderr)
+	err := cmd.Run()
+	return CommandOutput{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}, err
+}
+
+// teeWriter returns a writer writing to the given buffer, and to the given writer when set.
+func teeWriter(buffer *bytes.Buffer, writer io.Writer) io.Writer {
+	if writer == nil {
+		return buffer
+	}
+	return io.MultiWriter(buffer, writer)
+}
+
+func (ExecCommandRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// ExitError is the error of a command exiting with a non-zero code, as returned by FakeCommandRunner.
+type ExitError struct {
+	Code int
+}
+
+func (err *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", err.Code)
+}
+
+func (err *ExitError) ExitCode() int {
+	return err.Code
+}
+
+// FakeCommandResponse is the scripted response of FakeCommandRunner to a command. A non-zero ExitCode fails the
+// command with an ExitError, and Err fails it with that error instead.
+type FakeCommandResponse struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+// FakeCommandRunner is a CommandRunner for tests, which does not run anything: it records the commands it is given,
+// and replies with the responses scripted for them, so that tools shelling out through this package can be tested
+// without terraform, terragrunt or the other binaries installed. It is safe for concurrent use.
+type FakeCommandRunner struct {
+	// Responses are the responses to the commands, keyed by command line (see Command.String), e.g.
+	// "terragrunt output -json".
+	Responses map[string]FakeCommandResponse
+
+	// Handler, when set, replies to the commands that have no response in Responses. Otherwise they fail.
+	Handler func(ctx context.Context, command Command) (CommandOutput, error)
+
+	// Paths are the paths LookPath returns, keyed by executable name. The names without path are returned as is, as
+	// if every executable was installed, unless Missing lists them.
+	Paths   map[string]string
+	Missing []string
+
+	mutex    sync.Mutex
+	commands []Command
+}
+
+func (runner *FakeCommandRunner) Run(ctx context.Context, command Command) (CommandOutput, error) {
+	runner.mutex.Lock()
+	runner.commands = append(runner.commands, command)
+	response, found := runner.Responses[command.String()]
+	runner.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return CommandOutput{}, err
+	}
+	if !found {
+		if runner.Handler != nil {
+			return runner.Handler(ctx, command)
+		}
+		return CommandOutput{}, fmt.Errorf("unexpected command %s", command)
+	}
+
+	output := CommandOutput{Stdout: []byte(response.Stdout), Stderr: []byte(response.Stderr)}
+	if command.Stdout != nil {
+		command.Stdout.Write(output.Stdout)
+	}
+	if command.Stderr != nil {
+		command.Stderr.Write(output.Stderr)
+	}
+	switch {
+	case response.Err != nil:
+		return output, response.Err
+	case response.ExitCode != 0:
+		return output, &ExitError{Code: response.ExitCode}
+	}
+	return output, nil
+}
+
+func (runner *FakeCommandRunner) LookPath(name string) (string, error) {
+	if containsString(runner.Missing, name) {
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	if path, found := runner.Paths[name]; found {
+		return path, nil
+	}
+	return name, nil
+}
+
+// Commands returns the commands run so far, in order.
+func (runner *FakeCommandRunner) Commands() []Command {
+	runner.mutex.Lock()
+	defer runner.mutex.Unlock()
+	return append([]Command(nil), runner.commands...)
+}
@@ -0,0 +1,267 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ConfigOutputResolver is an OutputResolver deriving the outputs of dependencies from source, without any state: it
+// parses the configuration of the dependency unit, resolving the outputs of its own dependencies the same way, fetches
+// its module, and evaluates the output values of the module against the inputs of the unit. Outputs whose value is
+// not known before apply (e.g. resource attributes) are null.
+//
+// Multi-hop stacks are thereby resolved from source in one call. Each unit is resolved once, even by concurrent
+// callers, and dependency cycles are reported as errors. The resolutions interrupted by the cancellation of their
+// context are not kept, so that they are retried by the next callers.
+type ConfigOutputResolver struct {
+	// Fetcher fetches the modules of the dependency units.
+	Fetcher *SourceFetcher
+
+	// Options are the options the configurations of the dependency units are parsed with. The output resolver is
+	// always the ConfigOutputResolver itself.
+	Options []Option
+
+	mutex    sync.Mutex
+	resolved map[string]configResolution
+	group    singleflightGroup
+
+	// waits are the configurations the resolutions in flight are waiting for, from the configuration being resolved
+	// to the configurations it depends on, counted by caller. They detect the cycles spanning several callers, which
+	// their own resolution chains do not show.
+	waits map[string]map[string]int
+}
+
+type configResolution struct {
+	outputs []byte
+	err     error
+}
+
+// NewConfigOutputResolver returns a ConfigOutputResolver fetching modules with the given fetcher, and parsing the
+// configurations of the dependency units with the given options.
+func NewConfigOutputResolver(fetcher *SourceFetcher, opts ...Option) *ConfigOutputResolver {
+	return &ConfigOutputResolver{Fetcher: fetcher, Options: opts}
+}
+
+// configResolutionChainKey is the context key of the configurations being resolved by a ConfigOutputResolver, from
+// the outermost to the innermost one, to detect dependency cycles.
+type configResolutionChainKey struct{}
+
+func (resolver *ConfigOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	configPath = filepath.Join(configDir(configPath), DefaultConfigFilename)
+
+	resolver.mutex.Lock()
+	resolution, found := resolver.resolved[configPath]
+	resolver.mutex.Unlock()
+	if found {
+		return resolution.outputs, resolution.err
+	}
+
+	chain, _ := ctx.Value(configResolutionChainKey{}).([]string)
+	for i, path := range chain {
+		if path == configPath {
+			cycle := append(append([]string(nil), chain[i:]...), configPath)
+			return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if len(chain) > 0 {
+		waiter := chain[len(chain)-1]
+		if err := resolver.addWait(chain, waiter, configPath); err != nil {
+			return nil, err
+		}
+		defer resolver.removeWait(waiter, configPath)
+	}
+	ctx = context.WithValue(ctx, configResolutionChainKey{}, append(append([]string(nil), chain...), configPath))
+
+	for {
+		ran := false
+		outputs, err := resolver.group.do(configPath, func() ([]byte, error) {
+			ran = true
+			outputs, err := resolver.resolve(ctx, configPath)
+			if err != nil && ctx.Err() != nil {
+				// The parse errors caused by the cancellation do not always wrap it.
+				return nil, fmt.Errorf("resolving %s: %w", configPath, ctx.Err())
+			}
+			if isContextError(err) {
+				return outputs, err
+			}
+
+			resolver.mutex.Lock()
+			if resolver.resolved == nil {
+				resolver.resolved = map[string]configResolution{}
+			}
+			resolver.resolved[configPath] = configResolution{outputs: outputs, err: err}
+			resolver.mutex.Unlock()
+			return outputs, err
+		})
+		// The resolution of a concurrent caller whose context was cancelled is retried, unless this caller is
+		// cancelled too.
+		if !ran && isContextError(err) && ctx.Err() == nil {
+			continue
+		}
+		return outputs, err
+	}
+}
+
+// addWait records that the resolution of the waiter, innermost in the given chain, waits for the given configuration.
+// It fails instead when the configuration waits, through the resolutions in flight of any caller, for a
+// configuration of the chain: waiting would then never end.
+func (resolver *ConfigOutputResolver) addWait(chain []string, waiter string, configPath string) error {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+
+	if path := resolver.waitPath(configPath, chain); path != nil {
+		for i, chainPath := range chain {
+			if chainPath == path[len(path)-1] {
+				cycle := append(append([]string(nil), chain[i:]...), path...)
+				return fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+			}
+		}
+	}
+
+	if resolver.waits == nil {
+		resolver.waits = map[string]map[string]int{}
+	}
+	if resolver.waits[waiter] == nil {
+		resolver.waits[waiter] = map[string]int{}
+	}
+	resolver.waits[waiter][configPath]++
+	return nil
+}
+
+// removeWait removes a wait recorded by addWait.
+func (resolver *ConfigOutputResolver) removeWait(waiter string, configPath string) {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+
+	resolver.waits[waiter][configPath]--
+	if resolver.waits[waiter][configPath] == 0 {
+		delete(resolver.waits[waiter], configPath)
+	}
+	if len(resolver.waits[waiter]) == 0 {
+		delete(resolver.waits, waiter)
+	}
+}
+
+// waitPath returns the configurations the given one waits for, from itself to one of the given targets, or nil when
+// it waits for none of them. The mutex must be held.
+func (resolver *ConfigOutputResolver) waitPath(configPath string, targets []string) []string {
+	visited := map[string]bool{}
+	var walk func(path []string) []string
+	walk = func(path []string) []string {
+		current := path[len(path)-1]
+		for _, target := range targets {
+			if current == target {
+				return path
+			}
+		}
+		if visited[current] {
+			return nil
+		}
+		visited[current] = true
+		for next := range resolver.waits[current] {
+			if found := walk(append(path[:len(path):len(path)], next)); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk([]string{configPath})
+}
+
+// isContextError returns whether the given error is the cancellation or the expiration of a context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// resolve returns the outputs of the unit with the given configuration, in the format of terraform output -json.
+func (resolver *ConfigOutputResolver) resolve(ctx context.Context, configPath string) ([]byte, error) {
+	opts := append(append([]Option(nil), resolver.Options...), WithContext(ctx), WithOutputResolver(resolver))
+	config, err := ParseConfigFile(configPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcher := resolver.Fetcher
+	if fetcher == nil {
+		fetcher = &SourceFetcher{}
+	}
+	unit := &Unit{Path: filepath.Dir(configPath), ConfigPath: configPath, Config: config}
+	moduleDir, err := fetcher.FetchUnit(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+	module, err := ParseModule(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	type terraformOutput struct {
+		Sensitive bool            `json:"sensitive"`
+		Type      json.RawMessage `json:"type"`
+		Value     json.RawMessage `json:"value"`
+	}
+	outputs := map[string]terraformOutput{}
+	evalContext := moduleEvalContext(module, config)
+	for name, output := range module.Outputs {
+		value, marks := evaluateModuleOutput(output, evalContext).UnmarkDeep()
+		_, sensitive := marks[SensitiveMark]
+
+		valueType, err := ctyjson.MarshalType(value.Type())
+		if err != nil {
+			return nil, fmt.Errorf("output %s: %w", name, err)
+		}
+		valueJSON, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return nil, fmt.Errorf("output %s: %w", name, err)
+		}
+		outputs[name] = terraformOutput{Sensitive: output.Sensitive || sensitive, Type: valueType, Value: valueJSON}
+	}
+	return json.Marshal(outputs)
+}
+
+// moduleEvalContext returns the context to evaluate the output values of the given module with, for the unit with the
+// given configuration: the variables are set to the inputs of the unit, or to their default value.
+func moduleEvalContext(module *Module, config *TerragruntConfig) *hcl.EvalContext {
+	variables := map[string]cty.Value{}
+	for name, variable := range module.Variables {
+		value, found := config.InputsCty[name]
+		if !found {
+			value = variable.Default
+			if value == cty.NilVal {
+				value = cty.NullVal(variable.Type)
+			}
+		}
+		if converted, err := convert.Convert(value, variable.Type); err == nil {
+			value = converted
+		}
+		variables[name] = value
+	}
+
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(variables)},
+		Functions: stdlibFunctions(),
+	}
+}
+
+// evaluateModuleOutput returns the value of the given output, or a null value when it can not be determined before
+// apply.
+func evaluateModuleOutput(output *ModuleOutput, evalContext *hcl.EvalContext) cty.Value {
+	if output.Value == nil {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+	value, diags := output.Value.Value(evalContext)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+	return value
+}
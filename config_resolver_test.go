@@ -0,0 +1,162 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// moduleGetter is a Getter writing a module with a single output, failing when its context is done.
+type moduleGetter struct{}
+
+func (moduleGetter) Get(ctx context.Context, dst string, source *Source) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dst, "main.tf"), []byte("variable \"name\" {}\noutput \"name\" {\n  value = var.name\n}\n"), 0o644)
+}
+
+// blockingLogger counts the configurations parsed, blocking the first parse until release is closed.
+type blockingLogger struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	parses int
+}
+
+func (logger *blockingLogger) Log(event string, keysAndValues ...interface{}) {
+	if event != EventFileParsed {
+		return
+	}
+	logger.mu.Lock()
+	logger.parses++
+	first := logger.parses == 1
+	logger.mu.Unlock()
+	if first {
+		close(logger.started)
+		<-logger.release
+	}
+}
+
+func newTestConfigOutputResolver(t *testing.T, opts ...Option) (*ConfigOutputResolver, string) {
+	dir := writeFiles(t, map[string]string{
+		"vpc/terragrunt.hcl": "terraform {\n  source = \"git::https://example.com/modules.git\"\n}\ninputs = { name = \"vpc\" }\n",
+	})
+	fetcher := &SourceFetcher{
+		CacheDir: filepath.Join(dir, "cache"),
+		Getters:  map[SourceKind]Getter{SourceGit: moduleGetter{}},
+		Retry:    &RetryPolicy{MaxAttempts: 1},
+	}
+	return NewConfigOutputResolver(fetcher, opts...), filepath.Join(dir, "vpc", DefaultConfigFilename)
+}
+
+func TestConfigOutputResolverCancellation(t *testing.T) {
+	resolver, configPath := newTestConfigOutputResolver(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := resolver.ResolveOutputs(ctx, configPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want the cancellation", err)
+	}
+
+	outputs, err := resolver.ResolveOutputs(context.Background(), configPath)
+	if err != nil {
+		t.Fatalf("the cancellation of the previous call is cached: %v", err)
+	}
+	if want := `{"name":{"sensitive":false,"type":"string","value":"vpc"}}`; string(outputs) != want {
+		t.Errorf("got outputs %s, want %s", outputs, want)
+	}
+}
+
+func TestConfigOutputResolverConcurrentCallers(t *testing.T) {
+	logger := &blockingLogger{started: make(chan struct{}), release: make(chan struct{})}
+	resolver, configPath := newTestConfigOutputResolver(t, WithLogger(logger))
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([][]byte, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = resolver.ResolveOutputs(context.Background(), configPath)
+		}(i)
+	}
+	<-logger.started
+	// Give the other callers time to wait for the resolution in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(logger.release)
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil || string(results[i]) != string(results[0]) {
+			t.Errorf("caller %d: got %s and error %v, want %s", i, results[i], errs[i], results[0])
+		}
+	}
+	if logger.parses != 1 {
+		t.Errorf("the unit is parsed %d times, want once", logger.parses)
+	}
+}
+
+// barrierLogger blocks the parses of the first configurations until all of them are parsed.
+type barrierLogger struct {
+	configs int
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	parses int
+}
+
+func newBarrierLogger(configs int) *barrierLogger {
+	logger := &barrierLogger{configs: configs}
+	logger.wg.Add(configs)
+	return logger
+}
+
+func (logger *barrierLogger) Log(event string, keysAndValues ...interface{}) {
+	if event != EventFileParsed {
+		return
+	}
+	logger.mu.Lock()
+	logger.parses++
+	blocked := logger.parses <= logger.configs
+	logger.mu.Unlock()
+	if blocked {
+		logger.wg.Done()
+		logger.wg.Wait()
+	}
+}
+
+func TestConfigOutputResolverConcurrentCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a/terragrunt.hcl": "dependency \"b\" {\n  config_path = \"../b\"\n}\ninputs = { name = dependency.b.outputs.name }\n",
+		"b/terragrunt.hcl": "dependency \"a\" {\n  config_path = \"../a\"\n}\ninputs = { name = dependency.a.outputs.name }\n",
+	})
+	resolver := NewConfigOutputResolver(&SourceFetcher{CacheDir: filepath.Join(dir, "cache")}, WithLogger(newBarrierLogger(2)))
+
+	// Each caller starts resolving its own unit before resolving the other one, which is then in flight.
+	errs := make(chan error, 2)
+	for _, unit := range []string{"a", "b"} {
+		go func(unit string) {
+			_, err := resolver.ResolveOutputs(context.Background(), filepath.Join(dir, unit, DefaultConfigFilename))
+			errs <- err
+		}(unit)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil || !strings.Contains(err.Error(), "dependency cycle detected") {
+				t.Errorf("got error %v, want the dependency cycle", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the callers wait for each other")
+		}
+	}
+}
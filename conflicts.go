@@ -0,0 +1,132 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// singletonBlocksSchema selects the blocks a configuration can only declare once, along with the generate blocks,
+// whose names must be unique.
+var singletonBlocksSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "remote_state"},
+		{Type: "errors"},
+		{Type: "engine"},
+		{Type: "exclude"},
+		{Type: "catalog"},
+		{Type: "generate", LabelNames: []string{"name"}},
+	},
+}
+
+// checkDuplicateBlocks reports the blocks of the body that can only be declared once, such as terraform and
+// remote_state, and the generate blocks sharing a name. All the duplicates are reported at once, each naming the
+// block it duplicates, rather than failing on the first one when decoding.
+func checkDuplicateBlocks(body hcl.Body) error {
+	content, _, _ := body.PartialContent(singletonBlocksSchema)
+	if content == nil {
+		return nil
+	}
+
+	var diags hcl.Diagnostics
+	declared := map[string]hcl.Range{}
+	for _, block := range content.Blocks {
+		key := strings.Join(append([]string{block.Type}, block.Labels...), ".")
+		existing, found := declared[key]
+		if !found {
+			declared[key] = block.DefRange
+			continue
+		}
+
+		diag := &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("Duplicate %s block", block.Type),
+			Detail: fmt.Sprintf("Only one %s block is allowed, and one was already declared at %s. Merge the two blocks.",
+				block.Type, existing),
+			Subject: block.DefRange.Ptr(),
+		}
+		if len(block.Labels) > 0 {
+			diag.Detail = fmt.Sprintf("A %s block named %q was already declared at %s. The names of %s blocks must be "+
+				"unique, so rename one of them.", block.Type, block.Labels[0], existing, block.Type)
+		}
+		diags = append(diags, diag)
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
+
+// RuleIncludeOverlap is the rule of the warnings about the attributes set by several included configurations.
+const RuleIncludeOverlap = "include-overlap"
+
+// includeConflicts returns the errors of the given include overlaps, reported with WithStrictIncludes.
+func includeConflicts(overlaps []Finding) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, overlap := range overlaps {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Conflicting include attribute",
+			Detail:   overlap.Message + ". Set it in one of them, or in this configuration to override both.",
+			Subject:  overlap.Range.Ptr(),
+		})
+	}
+	return diags
+}
+
+// includeOrigin is where an attribute is set by an included configuration.
+type includeOrigin struct {
+	include string
+	origin  hcl.Range
+}
+
+// includeOverlaps returns the warnings about the attributes the configuration included by the given include block
+// sets, which are already set by a later include block, given in setBy, and records its own attributes in setBy.
+// The later include block takes precedence, as terragrunt merges includes in declaration order, which is how a root
+// configuration is commonly layered with an environment one, but it can also be a mistake. Attributes the including
+// configuration sets, given in overridden, are not reported, as it overrides both, and neither are the attributes the
+// include block merges with a deep strategy, the inputs and the remote_state config, nor the blocks merged attribute by
+// attribute, such as terraform.
+func includeOverlaps(include includeConfig, included *TerragruntConfig, overridden map[string]hcl.Range, setBy map[string]includeOrigin) []Finding {
+	paths := make([]string, 0, len(included.provenance))
+	for path := range included.provenance {
+		if _, found := overridden[path]; !found && overlappingPath(path, include.MergeStrategy) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var warnings []Finding
+	for _, path := range paths {
+		origin := included.provenance[path]
+		if later, found := setBy[path]; found {
+			warnings = append(warnings, Finding{
+				RuleID:   RuleIncludeOverlap,
+				Severity: SeverityWarning,
+				Message: fmt.Sprintf("%s is set by both the include %q, at %s, and the include %q, at %s, which is "+
+					"declared later and takes precedence", path, include.Name, origin, later.include, later.origin),
+				Range: origin,
+			})
+		}
+	}
+	for _, path := range paths {
+		setBy[path] = includeOrigin{include: include.Name, origin: included.provenance[path]}
+	}
+	return warnings
+}
+
+// overlappingPath returns whether the attribute with the given provenance path replaces the one of a previous
+// include block when merged with the given strategy.
+func overlappingPath(path string, strategy MergeStrategy) bool {
+	if !strings.Contains(path, ".") || strings.HasPrefix(path, "locals.") {
+		// Whole blocks and inputs are merged, and the locals are not merged at all.
+		return path == "terraform_binary" || path == "skip" || path == "prevent_destroy"
+	}
+	if strategy.deep() && (strings.HasPrefix(path, "inputs.") || path == "remote_state.config") {
+		return false
+	}
+	return true
+}
@@ -3,12 +3,13 @@ package terragrunt
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
-	"github.com/zclconf/go-cty/cty/gocty"
 	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
@@ -18,6 +19,12 @@ type EvalContextExtensions struct {
 	// - outputs: The map of outputs from the terraform state obtained by running `terragrunt output` on that target
 	//            config.
 	DecodedDependencies *cty.Value
+
+	// Locals are the evaluated locals of the config, exposed as local.
+	Locals *cty.Value
+
+	// FeatureFlags are the feature flags of the config, exposed as feature.
+	FeatureFlags *cty.Value
 }
 
 // terragruntDependency is a struct that can be used to only decode the dependency blocks in the terragrunt config
@@ -26,69 +33,172 @@ type terragruntDependency struct {
 	Remain       hcl.Body     `hcl:",remain"`
 }
 
-// Decode the dependency blocks from the file, and then retrieve all the outputs from the remote state. Then encode the
-// resulting map as a cty.Value object.
-// TODO: In the future, consider allowing importing dependency blocks from included config
+// Decode the dependency blocks from the body, and then retrieve all the outputs from the remote state. Then encode the
+// resulting map as a cty.Value object. The decoded dependency blocks are returned along with the remaining body, which
+// holds everything but the dependency blocks. The dependency blocks of the included configurations are merged in, so
+// that the configuration can reference them.
 // NOTE FOR MAINTAINER: When implementing importation of other config blocks (e.g referencing inputs), carefully
 //                      consider whether or not the implementation of the cyclic dependency detection still makes sense.
-func decodeAndRetrieveOutputs(file *hcl.File, extensions EvalContextExtensions) (*cty.Value, error) {
+func decodeAndRetrieveOutputs(body hcl.Body, opts *ParseOptions, extensions EvalContextExtensions) ([]Dependency, *cty.Value, hcl.Body, error) {
 	decodedDependency := terragruntDependency{}
-	if err := decodeHCL(file, &decodedDependency, extensions); err != nil {
+	if err := decodeHCL(body, &decodedDependency, opts, extensions); err != nil {
+		return nil, nil, nil, err
+	}
 
-		return nil, err
+	if err := checkDependencyBlocks(body, decodedDependency.Dependencies, opts); err != nil {
+		return nil, nil, nil, err
+	}
+	dependencies, err := mergeIncludedDependencies(body, decodedDependency.Dependencies, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Only the dependencies that are actually referenced need their outputs resolved. When the references can not be
+	// determined (e.g. for json configurations), every dependency is resolved.
+	references, analyzed := findDependencyReferences(body)
+	if opts.unitDependencies != nil && opts.originalConfigPath == "" {
+		opts.unitDependencies.record(dependencies, references)
+	}
+	if !analyzed {
+		references = nil
 	}
 
-	return dependencyBlocksToCtyValue(decodedDependency.Dependencies)
+	retrievedOutputs, err := dependencyBlocksToCtyValue(dependencies, references, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if opts.StrictDependencyOutputs {
+		if err := checkDependencyOutputReferences(body, *retrievedOutputs); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return dependencies, retrievedOutputs, decodedDependency.Remain, nil
+}
+
+// dependencyBlocksSchema selects the dependency blocks of a configuration, to locate them in diagnostics.
+var dependencyBlocksSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "dependency", LabelNames: []string{"name"}}},
+}
+
+// checkDependencyBlocks reports the dependency blocks sharing a name, and the ones whose config_path resolves to the
+// unit of the configuration itself, which would otherwise fail later with confusing errors (the outputs of the wrong
+// dependency, or a dependency cycle). The dependencies are the blocks of the body, decoded in order.
+func checkDependencyBlocks(body hcl.Body, dependencies []Dependency, opts *ParseOptions) error {
+	content, _, _ := body.PartialContent(dependencyBlocksSchema)
+	if content == nil || len(content.Blocks) != len(dependencies) {
+		return nil
+	}
+
+	var diags hcl.Diagnostics
+	declared := map[string]hcl.Range{}
+	for i, dependency := range dependencies {
+		block := content.Blocks[i]
+		if existing, found := declared[dependency.Name]; found {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate dependency block",
+				Detail: fmt.Sprintf("A dependency named %q was already declared at %s. Dependency names must be unique, so "+
+					"rename one of the blocks.", dependency.Name, existing),
+				Subject: block.DefRange.Ptr(),
+			})
+			continue
+		}
+		declared[dependency.Name] = block.DefRange
+
+		if dependency.IsEnabled() && configDir(dependencyConfigPath(dependency, opts)) == opts.workingDir() {
+			subject := block.DefRange
+			if attributes, _, _ := block.Body.PartialContent(&hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "config_path"}}}); attributes != nil {
+				if attribute, found := attributes.Attributes["config_path"]; found {
+					subject = attribute.Expr.Range()
+				}
+			}
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Self dependency",
+				Detail: fmt.Sprintf("The config_path of the dependency %q, %q, resolves to the directory of this configuration, "+
+					"and a unit can not depend on itself. Point it to the directory of another unit.", dependency.Name, dependency.ConfigPath),
+				Subject: subject.Ptr(),
+			})
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	return nil
 }
 
 // Encode the list of dependency blocks into a single cty.Value object that maps the dependency block name to the
 // encoded dependency mapping. The encoded dependency mapping should have the attributes:
 // - outputs: The map of outputs of the corresponding terraform module that lives at the target config of the dependency.
-func dependencyBlocksToCtyValue(dependencyConfigs []Dependency) (*cty.Value, error) {
+// When references is not nil, the outputs are only resolved for the dependencies it contains. Disabled dependencies are
+// left out, so referencing them is an error.
+func dependencyBlocksToCtyValue(dependencyConfigs []Dependency, references dependencyReferences, opts *ParseOptions) (*cty.Value, error) {
 	// dependencyMap is the top level map that maps dependency block names to the encoded version, which includes
 	// various attributes for accessing information about the target config (including the module outputs).
 	dependencyMap := map[string]cty.Value{}
 
-	for _, dependencyConfig := range dependencyConfigs {
+	for i := range dependencyConfigs {
+		dependencyConfig := &dependencyConfigs[i]
+		if !dependencyConfig.IsEnabled() {
+			continue
+		}
+
 		// Loose struct to hold the attributes of the dependency. This includes:
 		// - outputs: The module outputs of the target config
 		dependencyEncodingMap := map[string]cty.Value{}
 
 		// Encode the outputs and nest under `outputs` attribute if we should get the outputs or the `mock_outputs`
-		if err := dependencyConfig.setRenderedOutputs(); err != nil {
-			return nil, err
-		}
+		if references.includes(dependencyConfig.Name) {
+			var keys []string
+			if outputKeys := references.outputKeys(dependencyConfig.Name); len(outputKeys) <= opts.SelectiveOutputKeys {
+				keys = outputKeys
+			}
+			if err := dependencyConfig.setRenderedOutputs(keys, opts); err != nil {
+				return nil, err
+			}
+			opts.Logger.Log(EventDependencyOutputFetched, "dependency", dependencyConfig.Name, "config_path", dependencyConfig.ConfigPath)
+			opts.sendProgress(ProgressEvent{
+				Type:           ProgressOutputFetched,
+				Unit:           opts.unitPath(),
+				Dependency:     dependencyConfig.Name,
+				DependencyPath: configDir(dependencyConfigPath(*dependencyConfig, opts)),
+			})
 
-		if dependencyConfig.RenderedOutputs != nil {
-			dependencyEncodingMap["outputs"] = *dependencyConfig.RenderedOutputs
+			if opts.OnlyReferencedOutputKeys {
+				dependencyConfig.RenderedOutputs = references.filterOutputs(dependencyConfig.Name, dependencyConfig.RenderedOutputs)
+			}
 		}
 
-		// Once the dependency is encoded into a map, we need to convert to a cty.Value again so that it can be fed to
-		// the higher order dependency map.
-		dependencyEncodingMapEncoded, err := gocty.ToCtyValue(dependencyEncodingMap, generateTypeFromValuesMap(dependencyEncodingMap))
-		if err != nil {
-			return nil, err
+		if dependencyConfig.RenderedOutputs != nil && *dependencyConfig.RenderedOutputs != cty.NilVal {
+			dependencyEncodingMap["outputs"] = *dependencyConfig.RenderedOutputs
 		}
 
-		// Finally, feed the encoded dependency into the higher order map under the block name
-		dependencyMap[dependencyConfig.Name] = dependencyEncodingMapEncoded
+		// Finally, feed the encoded dependency into the higher order map under the block name. The maps are converted
+		// with cty.ObjectVal rather than gocty, which can not convert unknown values.
+		dependencyMap[dependencyConfig.Name] = objectVal(dependencyEncodingMap)
 	}
 
 	// We need to convert the value map to a single cty.Value at the end so that it can be used in the execution context
-	convertedOutput, err := gocty.ToCtyValue(dependencyMap, generateTypeFromValuesMap(dependencyMap))
-	if err != nil {
-		return nil, err
-	}
-
+	convertedOutput := objectVal(dependencyMap)
 	return &convertedOutput, nil
 }
 
-func (dependencyConfig *Dependency) setRenderedOutputs() error {
+// objectVal returns the object value of the given attributes, cty.EmptyObjectVal when there are none.
+func objectVal(attributes map[string]cty.Value) cty.Value {
+	if len(attributes) == 0 {
+		return cty.EmptyObjectVal
+	}
+	return cty.ObjectVal(attributes)
+}
+
+// setRenderedOutputs sets the outputs of the dependency, only retrieving the given keys when there are any.
+func (dependencyConfig *Dependency) setRenderedOutputs(keys []string, opts *ParseOptions) error {
 	if dependencyConfig == nil {
 		return nil
 	}
 
-	outputVal, err := getTerragruntOutputIfAppliedElseConfiguredDefault(*dependencyConfig)
+	outputVal, err := getTerragruntOutputIfAppliedElseConfiguredDefault(*dependencyConfig, keys, opts)
 	if err != nil {
 		return err
 	}
@@ -98,69 +208,306 @@ func (dependencyConfig *Dependency) setRenderedOutputs() error {
 }
 
 // This will attempt to get the outputs from the target terragrunt config if it is applied. If it is not applied,
-// the behavior is different depending on the configuration of the dependency.
-func getTerragruntOutputIfAppliedElseConfiguredDefault(dependencyConfig Dependency) (*cty.Value, error) {
-	outputVal, isEmpty, err := getTerragruntOutput(dependencyConfig)
+// the behavior is different depending on the configuration of the dependency: the mock outputs are used when they are
+// set (see effectiveMockOutputs), otherwise the empty outputs are returned. Only the given keys of the outputs are
+// retrieved when there are any.
+func getTerragruntOutputIfAppliedElseConfiguredDefault(dependencyConfig Dependency, keys []string, opts *ParseOptions) (*cty.Value, error) {
+	effectiveMocks, err := effectiveMockOutputs(dependencyConfig, opts)
 	if err != nil {
 		return nil, err
 	}
+	dependencyConfig.MockOutputs = effectiveMocks
+
+	if dependencyConfig.SkipOutputs != nil && *dependencyConfig.SkipOutputs {
+		return getMockOutputs(dependencyConfig)
+	}
+
+	outputVal, isEmpty, err := getTerragruntOutput(dependencyConfig, keys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if dependencyConfig.MockOutputs == nil {
+		return outputVal, nil
+	}
+	if isEmpty {
+		return getMockOutputs(dependencyConfig)
+	}
 
-	if !isEmpty {
+	strategy := dependencyConfig.mockOutputsMergeStrategy()
+	if strategy == MergeNoMerge {
 		return outputVal, nil
 	}
+	mockOutputs, err := getMockOutputs(dependencyConfig)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := MergeCtyValues(*mockOutputs, *outputVal, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("mock_outputs_merge_strategy_with_state of dependency %s: %w", dependencyConfig.Name, err)
+	}
+	return &merged, nil
+}
 
-	return outputVal, err
+// mockOutputsMergeStrategy returns the strategy the mock outputs of the dependency are merged with its outputs with,
+// when they are not empty: mock_outputs_merge_strategy_with_state, or else shallow when the deprecated
+// mock_outputs_merge_with_state is set, or else no_merge.
+func (dependencyConfig Dependency) mockOutputsMergeStrategy() MergeStrategy {
+	switch {
+	case dependencyConfig.MockOutputsMergeStrategyWithState != nil:
+		return MergeStrategy(*dependencyConfig.MockOutputsMergeStrategyWithState)
+	case dependencyConfig.MockOutputsMergeWithState != nil && *dependencyConfig.MockOutputsMergeWithState:
+		return MergeShallow
+	}
+	return MergeNoMerge
 }
 
-// Return the output from the state of another module, managed by terragrunt. This function will parse the provided
-// terragrunt config and extract the desired output from the remote state. Note that this will error if the targetted
-// module hasn't been applied yet.
-func getTerragruntOutput(dependencyConfig Dependency) (*cty.Value, bool, error) {
-	type OutputMeta struct {
-		Sensitive bool   `json:"sensitive"`
-		Type      string `json:"type"`
-		Value     string `json:"value"`
+// InvalidOutputsError is returned when the outputs of a dependency, or its mock outputs, can not be converted to cty
+// values. Output is the name of the offending output, empty when the outputs as a whole are invalid.
+type InvalidOutputsError struct {
+	Dependency string
+	Output     string
+	Err        error
+}
+
+func (err *InvalidOutputsError) Error() string {
+	if err.Output == "" {
+		return fmt.Sprintf("invalid outputs of dependency %s: %s", err.Dependency, err.Err)
 	}
-	outputs := make(map[string]OutputMeta)
+	return fmt.Sprintf("invalid output %q of dependency %s: %s", err.Output, err.Dependency, err.Err)
+}
 
-	mockOutputs, err := parseCtyValueToMap(*dependencyConfig.MockOutputs)
-	if err != nil {
+func (err *InvalidOutputsError) Unwrap() error {
+	return err.Err
+}
 
-		return nil, false, err
+// MissingDependencyOutput is a reference to an output that a dependency does not have.
+type MissingDependencyOutput struct {
+	Dependency string
+	Output     string
+
+	// Range is the range of the reference.
+	Range hcl.Range
+
+	// NoOutputs reports whether the dependency has no outputs at all: it is not applied, or its outputs are not
+	// retrieved, and it has no mock outputs.
+	NoOutputs bool
+}
+
+// MissingDependencyOutputsError is returned with WithStrictDependencyOutputs when a configuration references outputs
+// that its dependencies do not have. It unwraps to the diagnostics of the references.
+type MissingDependencyOutputsError struct {
+	Missing []MissingDependencyOutput
+}
+
+func (err *MissingDependencyOutputsError) Error() string {
+	return err.diagnostics().Error()
+}
+
+func (err *MissingDependencyOutputsError) Unwrap() error {
+	return err.diagnostics()
+}
+
+func (err *MissingDependencyOutputsError) diagnostics() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, missing := range err.Missing {
+		detail := fmt.Sprintf("The dependency %q has no output %q.", missing.Dependency, missing.Output)
+		if missing.NoOutputs {
+			detail = fmt.Sprintf("The dependency %q has no outputs, so it has no output %q: it is not applied, or its "+
+				"outputs are not retrieved, and it has no mock_outputs. Apply it, or set its mock_outputs.", missing.Dependency, missing.Output)
+		}
+		subject := missing.Range
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing dependency output",
+			Detail:   detail,
+			Subject:  &subject,
+		})
+	}
+	return diags
+}
+
+// checkDependencyOutputReferences returns a MissingDependencyOutputsError when the expressions of the given body,
+// outside of the dependency blocks, reference outputs that are not in the given outputs of the dependencies. The
+// references guarded by try() or can() are not checked, nor are the bodies that can not be analyzed
+// statically, nor the dependencies whose outputs are unknown.
+func checkDependencyOutputReferences(body hcl.Body, dependencies cty.Value) error {
+	syntaxBody, isSyntaxBody := body.(*hclsyntax.Body)
+	if !isSyntaxBody || !dependencies.Type().IsObjectType() {
+		return nil
 	}
-	for k, v := range mockOutputs {
-		fmt.Println(k, v)
-		outputs[k] = OutputMeta{
-			Type:  reflect.TypeOf(v).String(),
-			Value: fmt.Sprintf("%s", v),
+
+	var missing []MissingDependencyOutput
+	for _, traversal := range unguardedVariables(syntaxBody, "dependency") {
+		if traversal.RootName() != "dependency" || len(traversal) < 4 {
+			continue
+		}
+		name, isName := traversalStepName(traversal[1])
+		attribute, _ := traversalStepName(traversal[2])
+		key, isKey := traversalStepName(traversal[3])
+		if !isName || !isKey || attribute != "outputs" || !dependencies.Type().HasAttribute(name) {
+			continue
 		}
+		dependency := dependencies.GetAttr(name)
+		if !dependency.Type().IsObjectType() || !dependency.Type().HasAttribute("outputs") {
+			continue
+		}
+		outputs, _ := dependency.GetAttr("outputs").Unmark()
+		if !outputs.IsKnown() || outputs.IsNull() || !(outputs.Type().IsObjectType() || outputs.Type().IsMapType()) {
+			continue
+		}
+		if _, found := outputs.AsValueMap()[key]; found {
+			continue
+		}
+		missing = append(missing, MissingDependencyOutput{
+			Dependency: name,
+			Output:     key,
+			Range:      traversal.SourceRange(),
+			NoOutputs:  outputs.LengthInt() == 0,
+		})
 	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Range.Start.Byte < missing[j].Range.Start.Byte
+	})
+	return &MissingDependencyOutputsError{Missing: missing}
+}
 
-	out, err := json.Marshal(outputs)
+// outputResolutionError is returned when the OutputResolver fails to retrieve the outputs of a dependency.
+type outputResolutionError struct {
+	dependency string
+	err        error
+}
+
+func (err *outputResolutionError) Error() string {
+	return fmt.Sprintf("failed to retrieve outputs of dependency %s: %s", err.dependency, err.err)
+}
+
+func (err *outputResolutionError) Unwrap() error {
+	return err.err
+}
+
+// Return the output from the state of another module, managed by terragrunt. The outputs are retrieved through the
+// configured OutputResolver, and are reported as empty when there is none or when the targetted module hasn't been
+// applied yet. Only the given keys of the outputs are retrieved when there are any (see KeyedOutputResolver).
+func getTerragruntOutput(dependencyConfig Dependency, keys []string, opts *ParseOptions) (*cty.Value, bool, error) {
+	if opts.OutputResolver == nil {
+		return &cty.EmptyObjectVal, true, nil
+	}
+
+	start := time.Now()
+	var out []byte
+	var err error
+	if len(keys) > 0 {
+		out, err = resolveOutputKeys(opts.Context, opts.OutputResolver, dependencyConfigPath(dependencyConfig, opts), keys)
+	} else {
+		out, err = opts.OutputResolver.ResolveOutputs(opts.Context, dependencyConfigPath(dependencyConfig, opts))
+	}
 	if err != nil {
-		return nil, false, err
+		observeDuration(opts.Metrics, MetricResolveDuration, start, map[string]string{"result": "error"})
+		return nil, false, &outputResolutionError{dependency: dependencyConfig.Name, err: err}
 	}
+	observeDuration(opts.Metrics, MetricResolveDuration, start, map[string]string{"result": "success"})
 
 	jsonBytes := []byte(strings.TrimSpace(string(out)))
-	isEmpty := string(jsonBytes) == "{}"
-
 	outputMap, err := terraformOutputJsonToCtyValueMap(jsonBytes)
 	if err != nil {
-		return nil, isEmpty, err
+		if outputsErr, ok := err.(*InvalidOutputsError); ok {
+			outputsErr.Dependency = dependencyConfig.Name
+		}
+		return nil, false, err
+	}
+	if len(outputMap) == 0 {
+		return &cty.EmptyObjectVal, true, nil
 	}
 
 	// We need to convert the value map to a single cty.Value at the end for use in the terragrunt config.
-	convertedOutput, err := gocty.ToCtyValue(outputMap, generateTypeFromValuesMap(outputMap))
-	if err != nil {
-		return nil, isEmpty, err
+	convertedOutput := cty.ObjectVal(outputMap)
+	return &convertedOutput, false, nil
+}
+
+// effectiveMockOutputs returns the mock outputs of the dependency: the mock_outputs of its block, or else the default
+// mock outputs of the options, with the overrides of the options for the dependency laid over them. It returns nil
+// when there are none.
+func effectiveMockOutputs(dependencyConfig Dependency, opts *ParseOptions) (*cty.Value, error) {
+	mockOutputs := dependencyConfig.MockOutputs
+	if mockOutputs == nil || *mockOutputs == cty.NilVal || mockOutputs.IsNull() {
+		mockOutputs = nil
+		if len(opts.DefaultMockOutputs) > 0 {
+			defaults := cty.ObjectVal(opts.DefaultMockOutputs)
+			mockOutputs = &defaults
+		}
+	}
+
+	dependencyPath := configDir(dependencyConfigPath(dependencyConfig, opts))
+	for _, key := range []string{dependencyConfig.Name, dependencyPath} {
+		overrides, found := opts.MockOutputOverrides[key]
+		if !found {
+			continue
+		}
+		base := cty.EmptyObjectVal
+		if mockOutputs != nil {
+			validated, err := getMockOutputs(Dependency{Name: dependencyConfig.Name, MockOutputs: mockOutputs})
+			if err != nil {
+				return nil, err
+			}
+			base = *validated
+		}
+		merged, err := MergeCtyValues(base, objectVal(overrides), MergeShallow)
+		if err != nil {
+			return nil, err
+		}
+		mockOutputs = &merged
+	}
+	return mockOutputs, nil
+}
+
+// getMockOutputs returns the mock outputs configured on the dependency block.
+func getMockOutputs(dependencyConfig Dependency) (*cty.Value, error) {
+	if dependencyConfig.MockOutputs == nil {
+		return &cty.EmptyObjectVal, nil
+	}
+
+	// The mock outputs are already cty values, so they are used as is instead of going through the terraform output
+	// json format, which would lose their type information. A null value, e.g. from a local set to null, is the same as
+	// no mock outputs, and an unknown one is kept as an unknown object.
+	mockOutputs, marks := dependencyConfig.MockOutputs.Unmark()
+	if mockOutputs == cty.NilVal || mockOutputs.IsNull() {
+		return &cty.EmptyObjectVal, nil
+	}
+	mockType := mockOutputs.Type()
+	if mockType != cty.DynamicPseudoType && !mockType.IsObjectType() && !mockType.IsMapType() {
+		return nil, &InvalidOutputsError{
+			Dependency: dependencyConfig.Name,
+			Err:        fmt.Errorf("mock_outputs must be an object, got %s", mockType.FriendlyName()),
+		}
+	}
+	if !mockOutputs.IsKnown() {
+		convertedOutput := cty.DynamicVal.WithMarks(marks)
+		return &convertedOutput, nil
 	}
 
-	return &convertedOutput, isEmpty, nil
+	convertedOutput := objectVal(mockOutputs.AsValueMap()).WithMarks(marks)
+	return &convertedOutput, nil
+}
+
+// dependencyConfigPath returns the absolute path of the configuration targeted by the dependency. Relative paths are
+// resolved against the directory of the configuration being parsed.
+func dependencyConfigPath(dependencyConfig Dependency, opts *ParseOptions) string {
+	return resolvePath(opts.workingDir(), dependencyConfig.ConfigPath)
 }
 
 // terraformOutputJsonToCtyValueMap takes the terraform output json and converts to a mapping between output keys to the
-// parsed cty.Value encoding of the json objects.
+// parsed cty.Value encoding of the json objects. Empty and null json result in no outputs. The type of an output
+// without one is inferred from its value, and an output without value is null. Errors are returned as
+// InvalidOutputsError, without the name of the dependency.
 func terraformOutputJsonToCtyValueMap(jsonBytes []byte) (map[string]cty.Value, error) {
+	if len(jsonBytes) == 0 {
+		return nil, nil
+	}
+
 	// When getting all outputs, terraform returns a json with the data containing metadata about the types, so we
 	// can't quite return the data directly. Instead, we will need further processing to get the output we want.
 	// To do so, we first Unmarshal the json into a simple go map to a OutputMeta struct.
@@ -173,19 +520,40 @@ func terraformOutputJsonToCtyValueMap(jsonBytes []byte) (map[string]cty.Value, e
 
 	err := json.Unmarshal(jsonBytes, &outputs)
 	if err != nil {
-		return nil, err
+		return nil, &InvalidOutputsError{Err: err}
 	}
 	flattenedOutput := map[string]cty.Value{}
 	for k, v := range outputs {
-		outputType, err := ctyjson.UnmarshalType(v.Type)
+		outputVal, err := terraformOutputValue(v.Type, v.Value)
 		if err != nil {
-			return nil, err
+			return nil, &InvalidOutputsError{Output: k, Err: err}
 		}
-		outputVal, err := ctyjson.Unmarshal(v.Value, outputType)
-		if err != nil {
-			return nil, err
+		if v.Sensitive {
+			outputVal = outputVal.Mark(SensitiveMark)
 		}
 		flattenedOutput[k] = outputVal
 	}
 	return flattenedOutput, nil
 }
+
+// terraformOutputValue returns the cty value of an output in the terraform output json, from its type and value.
+func terraformOutputValue(rawType json.RawMessage, rawValue json.RawMessage) (cty.Value, error) {
+	hasType := len(rawType) > 0 && string(rawType) != "null"
+	hasValue := len(rawValue) > 0 && string(rawValue) != "null"
+
+	outputType := cty.DynamicPseudoType
+	var err error
+	switch {
+	case hasType:
+		outputType, err = ctyjson.UnmarshalType(rawType)
+	case hasValue:
+		outputType, err = ctyjson.ImpliedType(rawValue)
+	}
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if !hasValue {
+		return cty.NullVal(outputType), nil
+	}
+	return ctyjson.Unmarshal(rawValue, outputType)
+}
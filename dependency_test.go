@@ -0,0 +1,223 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+const vpcOutputs = `{"vpc_id": {"value": "vpc-1", "type": "string"}, "subnet_ids": {"value": ["subnet-1"], "type": ["list", "string"]}}`
+
+// recordingResolver is a KeyedOutputResolver returning vpcOutputs, and recording the keys of each call, nil when all
+// the outputs are retrieved.
+type recordingResolver struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (resolver *recordingResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	resolver.calls = append(resolver.calls, nil)
+	return []byte(vpcOutputs), nil
+}
+
+func (resolver *recordingResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	resolver.calls = append(resolver.calls, keys)
+	return filterOutputKeys([]byte(vpcOutputs), keys)
+}
+
+func TestSelectiveOutputKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxKeys    int
+		keyed      bool
+		references string
+		calls      [][]string
+		outputs    []string
+	}{
+		{name: "disabled", maxKeys: 0, keyed: true, calls: [][]string{nil}, outputs: []string{"subnet_ids", "vpc_id"}},
+		{name: "keyed resolver", maxKeys: 1, keyed: true, calls: [][]string{{"vpc_id"}}, outputs: []string{"vpc_id"}},
+		{name: "too many keys", maxKeys: 1, keyed: true, references: ", subnets = dependency.vpc.outputs.subnet_ids", calls: [][]string{nil}, outputs: []string{"subnet_ids", "vpc_id"}},
+		{name: "filtered outputs", maxKeys: 1, keyed: false, calls: [][]string{nil}, outputs: []string{"vpc_id"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inputs := "inputs = { id = dependency.vpc.outputs.vpc_id" + test.references + " }\n"
+			dir := writeFiles(t, map[string]string{
+				"vpc/terragrunt.hcl": "",
+				"app/terragrunt.hcl": "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n" + inputs,
+			})
+
+			recording := &recordingResolver{}
+			var resolver OutputResolver = recording
+			if !test.keyed {
+				resolver = OutputResolverFunc(recording.ResolveOutputs)
+			}
+			config, err := ParseConfigFile(filepath.Join(dir, "app", DefaultConfigFilename),
+				WithOutputResolver(resolver), WithSelectiveOutputKeys(test.maxKeys))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(recording.calls, test.calls) {
+				t.Errorf("got calls %q, want %q", recording.calls, test.calls)
+			}
+			var outputs []string
+			for name := range config.TerragruntDependencies[0].RenderedOutputs.AsValueMap() {
+				outputs = append(outputs, name)
+			}
+			sort.Strings(outputs)
+			if !reflect.DeepEqual(outputs, test.outputs) {
+				t.Errorf("got outputs %q, want %q", outputs, test.outputs)
+			}
+			if id := config.InputsCty["id"]; !id.RawEquals(cty.StringVal("vpc-1")) {
+				t.Errorf("got id %#v, want vpc-1", id)
+			}
+		})
+	}
+}
+
+func TestMockOutputOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockOutputs string
+		options     func(dir string) []Option
+		want        map[string]cty.Value
+	}{
+		{
+			name:        "block mock outputs",
+			mockOutputs: `mock_outputs = { vpc_id = "mock" }`,
+			want:        map[string]cty.Value{"vpc_id": cty.StringVal("mock")},
+		},
+		{
+			name: "default mock outputs",
+			options: func(dir string) []Option {
+				return []Option{WithDefaultMockOutputs(map[string]cty.Value{"vpc_id": cty.StringVal("default")})}
+			},
+			want: map[string]cty.Value{"vpc_id": cty.StringVal("default")},
+		},
+		{
+			name:        "block mock outputs take precedence over defaults",
+			mockOutputs: `mock_outputs = { vpc_id = "mock" }`,
+			options: func(dir string) []Option {
+				return []Option{WithDefaultMockOutputs(map[string]cty.Value{"vpc_id": cty.StringVal("default"), "other": cty.True})}
+			},
+			want: map[string]cty.Value{"vpc_id": cty.StringVal("mock")},
+		},
+		{
+			name:        "overrides by block name",
+			mockOutputs: `mock_outputs = { vpc_id = "mock", cidr = "10.0.0.0/16" }`,
+			options: func(dir string) []Option {
+				return []Option{WithMockOutputOverrides("vpc", map[string]cty.Value{"vpc_id": cty.StringVal("override")})}
+			},
+			want: map[string]cty.Value{"vpc_id": cty.StringVal("override"), "cidr": cty.StringVal("10.0.0.0/16")},
+		},
+		{
+			name: "overrides by unit path over overrides by block name",
+			options: func(dir string) []Option {
+				return []Option{
+					WithMockOutputOverrides(filepath.Join(dir, "vpc"), map[string]cty.Value{"vpc_id": cty.StringVal("by path")}),
+					WithMockOutputOverrides("vpc", map[string]cty.Value{"vpc_id": cty.StringVal("by name"), "cidr": cty.StringVal("10.0.0.0/16")}),
+				}
+			},
+			want: map[string]cty.Value{"vpc_id": cty.StringVal("by path"), "cidr": cty.StringVal("10.0.0.0/16")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"vpc/terragrunt.hcl": "",
+				"app/terragrunt.hcl": "dependency \"vpc\" {\n  config_path = \"../vpc\"\n  " + test.mockOutputs + "\n}\ninputs = { outputs = dependency.vpc.outputs }\n",
+			})
+			var opts []Option
+			if test.options != nil {
+				opts = test.options(dir)
+			}
+			config, err := ParseConfigFile(filepath.Join(dir, "app", DefaultConfigFilename), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := config.InputsCty["outputs"]; !got.RawEquals(cty.ObjectVal(test.want)) {
+				t.Errorf("got outputs %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStrictDependencyOutputs(t *testing.T) {
+	tests := []struct {
+		name        string
+		mockOutputs string
+		inputs      string
+		missing     []MissingDependencyOutput
+	}{
+		{
+			name:    "no outputs",
+			inputs:  "inputs = { id = dependency.vpc.outputs.vpc_id }",
+			missing: []MissingDependencyOutput{{Dependency: "vpc", Output: "vpc_id", NoOutputs: true}},
+		},
+		{
+			name:        "missing output",
+			mockOutputs: `mock_outputs = { cidr = "10.0.0.0/16" }`,
+			inputs:      "inputs = { id = dependency.vpc.outputs.vpc_id, cidr = dependency.vpc.outputs.cidr, name = dependency.vpc.outputs.name }",
+			missing: []MissingDependencyOutput{
+				{Dependency: "vpc", Output: "vpc_id"},
+				{Dependency: "vpc", Output: "name"},
+			},
+		},
+		{
+			name:        "present output",
+			mockOutputs: `mock_outputs = { vpc_id = "mock" }`,
+			inputs:      "inputs = { id = dependency.vpc.outputs.vpc_id }",
+		},
+		{
+			name:   "guarded reference",
+			inputs: `inputs = { id = try(dependency.vpc.outputs.vpc_id, "none") }`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"vpc/terragrunt.hcl": "",
+				"app/terragrunt.hcl": "dependency \"vpc\" {\n  config_path = \"../vpc\"\n  " + test.mockOutputs + "\n}\n" + test.inputs + "\n",
+			})
+			_, err := ParseConfigFile(filepath.Join(dir, "app", DefaultConfigFilename), WithStrictDependencyOutputs())
+
+			var missingErr *MissingDependencyOutputsError
+			if len(test.missing) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("got error %v, want a MissingDependencyOutputsError", err)
+			}
+			if len(missingErr.Missing) != len(test.missing) {
+				t.Fatalf("got missing outputs %+v, want %+v", missingErr.Missing, test.missing)
+			}
+			for i, missing := range missingErr.Missing {
+				want := test.missing[i]
+				if missing.Dependency != want.Dependency || missing.Output != want.Output || missing.NoOutputs != want.NoOutputs {
+					t.Errorf("got missing output %+v, want %+v", missing, want)
+				}
+				if !strings.HasSuffix(missing.Range.Filename, DefaultConfigFilename) {
+					t.Errorf("got range %s, want a range in the configuration", missing.Range)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DestroyOptions selects the units of a destroy plan. See Graph.DestroyPlan.
+type DestroyOptions struct {
+	// Targets are the absolute paths of the units requested to be destroyed. Every unit of the graph is requested
+	// when empty.
+	Targets []string
+
+	// AllowPreventDestroy overrides prevent_destroy, so that the units setting it are destroyed like the other ones.
+	AllowPreventDestroy bool
+}
+
+// DestroyBlockReason is the reason a unit requested to be destroyed is not scheduled.
+type DestroyBlockReason string
+
+const (
+	// DestroyBlockedByPreventDestroy is the reason of the units setting prevent_destroy = true.
+	DestroyBlockedByPreventDestroy DestroyBlockReason = "prevent_destroy"
+
+	// DestroyBlockedByDependents is the reason of the units other units still depend on, as they are not destroyed.
+	DestroyBlockedByDependents DestroyBlockReason = "dependents"
+)
+
+// BlockedDestroy is a unit requested to be destroyed that a destroy plan does not schedule.
+type BlockedDestroy struct {
+	Path   string
+	Reason DestroyBlockReason
+
+	// Dependents are, for DestroyBlockedByDependents, the units depending directly on the unit that are not destroyed,
+	// sorted.
+	Dependents []string
+}
+
+// DestroyPlan is the order run-all destroy destroys units in.
+type DestroyPlan struct {
+	// Batches group the units to destroy, such that every unit is destroyed before the units it depends on, in a
+	// previous batch.
+	Batches [][]string
+
+	// Blocked are the units requested to be destroyed that are not scheduled, sorted by path.
+	Blocked []BlockedDestroy
+}
+
+// DestroyPlan returns the plan destroying the units requested by the given options, in the reverse order of Batches.
+// The units left out of destroy by run-all commands (see BatchesFor) are left out of the plan. The units setting
+// prevent_destroy are not scheduled unless AllowPreventDestroy is set, and neither are the units other units still
+// depend on: the units not requested, the ones excluded from destroy by their exclude block, and the ones blocked
+// themselves. Skipped units do not block their dependencies. It returns an error naming the units involved when the
+// graph has a cycle.
+func (graph *Graph) DestroyPlan(opts DestroyOptions) (*DestroyPlan, error) {
+	requested := opts.Targets
+	if len(requested) == 0 {
+		requested = graph.paths
+	}
+
+	leftOut := graph.leftOut("destroy")
+	planned := map[string]bool{}
+	for _, path := range requested {
+		if _, found := graph.dependencies[path]; !found {
+			return nil, fmt.Errorf("unit %s is not in the graph", graph.relativePath(path))
+		}
+		if !leftOut[path] {
+			planned[path] = true
+		}
+	}
+
+	// dependents holds the units depending directly on each unit, that are sorted as the paths of the graph are.
+	dependents := map[string][]string{}
+	for _, path := range graph.paths {
+		for _, dependency := range graph.dependencies[path] {
+			dependents[dependency] = append(dependents[dependency], path)
+		}
+	}
+
+	plan := &DestroyPlan{}
+	for _, path := range graph.paths {
+		if planned[path] && graph.preventDestroy[path] && !opts.AllowPreventDestroy {
+			delete(planned, path)
+			plan.Blocked = append(plan.Blocked, BlockedDestroy{Path: path, Reason: DestroyBlockedByPreventDestroy})
+		}
+	}
+	// Blocking a unit can block the units it depends on in turn.
+	for blocked := true; blocked; {
+		blocked = false
+		for _, path := range graph.paths {
+			if !planned[path] {
+				continue
+			}
+			var remaining []string
+			for _, dependent := range dependents[path] {
+				if !planned[dependent] && !graph.skipped[dependent] {
+					remaining = append(remaining, dependent)
+				}
+			}
+			if len(remaining) > 0 {
+				delete(planned, path)
+				plan.Blocked = append(plan.Blocked, BlockedDestroy{Path: path, Reason: DestroyBlockedByDependents, Dependents: remaining})
+				blocked = true
+			}
+		}
+	}
+	sort.Slice(plan.Blocked, func(i, j int) bool {
+		return plan.Blocked[i].Path < plan.Blocked[j].Path
+	})
+
+	// The units are destroyed in the order of the batches of the reversed graph, where each unit depends on its
+	// dependents.
+	reversed := &Graph{Root: graph.Root, paths: graph.paths, dependencies: dependents}
+	done := map[string]bool{}
+	for _, path := range graph.paths {
+		if !planned[path] {
+			done[path] = true
+		}
+	}
+	batches, err := reversed.batches(done)
+	if err != nil {
+		return nil, err
+	}
+	plan.Batches = batches
+	return plan, nil
+}
@@ -0,0 +1,76 @@
+package terragrunt
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// DeterministicValues are the values returned by the nondeterministic functions in deterministic mode, so that two
+// evaluations of the same configuration produce the same result.
+type DeterministicValues struct {
+	// Time is returned by timestamp(). Defaults to the Unix epoch.
+	Time time.Time
+
+	// UUID is returned by every call to uuid(). Defaults to the nil UUID.
+	UUID string
+
+	// Env holds the environment variables visible to get_env(), in place of the environment of the process.
+	Env map[string]string
+}
+
+// nilUUID is the UUID returned by uuid() in deterministic mode when none is configured.
+const nilUUID = "00000000-0000-0000-0000-000000000000"
+
+// timestampFunc returns the timestamp() function, which returns the current UTC time in the RFC 3339 format.
+func timestampFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			now := time.Now()
+			if opts.Deterministic != nil {
+				now = opts.Deterministic.Time
+				if now.IsZero() {
+					now = time.Unix(0, 0)
+				}
+			}
+			return cty.StringVal(now.UTC().Format(time.RFC3339)), nil
+		},
+	})
+}
+
+// uuidFunc returns the uuid() function, which returns a random version 4 UUID.
+func uuidFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if opts.Deterministic != nil {
+				if opts.Deterministic.UUID == "" {
+					return cty.StringVal(nilUUID), nil
+				}
+				return cty.StringVal(opts.Deterministic.UUID), nil
+			}
+
+			var uuid [16]byte
+			if _, err := rand.Read(uuid[:]); err != nil {
+				return cty.NilVal, err
+			}
+			uuid[6] = uuid[6]&0x0f | 0x40
+			uuid[8] = uuid[8]&0x3f | 0x80
+			return cty.StringVal(fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])), nil
+		},
+	})
+}
+
+// lookupEnv returns the value of the given environment variable, from the frozen environment in deterministic mode.
+func (opts *ParseOptions) lookupEnv(name string) (string, bool) {
+	if opts.Deterministic != nil {
+		value, found := opts.Deterministic.Env[name]
+		return value, found
+	}
+	return os.LookupEnv(name)
+}
@@ -0,0 +1,220 @@
+package terragrunt
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ChangeKind is the kind of a Change between two configurations.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference between two configurations.
+type Change struct {
+	// Path is the path of the attribute that changed, such as inputs.region, terraform.source,
+	// dependency.vpc.config_path or generate.provider.contents. Dependency and generate blocks that are added or
+	// removed as a whole are reported with the path of the block, such as dependency.vpc.
+	Path string
+	Kind ChangeKind
+
+	// Old and New are the values of the attribute in each configuration. Old is cty.NilVal for added attributes, and
+	// New is cty.NilVal for removed ones. Sensitive values keep their marks, so that they can be redacted.
+	Old cty.Value
+	New cty.Value
+}
+
+// Diff returns the changes between the configurations a and b, in their inputs, terraform source and binary,
+// dependency blocks and generate blocks, sorted by path.
+func Diff(a, b *TerragruntConfig) []Change {
+	oldAttributes, newAttributes := diffableAttributes(a), diffableAttributes(b)
+
+	var changes []Change
+	for path, oldValue := range oldAttributes {
+		newValue, found := newAttributes[path]
+		switch {
+		case !found:
+			changes = append(changes, Change{Path: path, Kind: ChangeRemoved, Old: oldValue})
+		case isBlockPath(path):
+			changes = append(changes, blockChanges(path, oldValue, newValue)...)
+		case !valuesEqual(oldValue, newValue):
+			changes = append(changes, Change{Path: path, Kind: ChangeChanged, Old: oldValue, New: newValue})
+		}
+	}
+	for path, newValue := range newAttributes {
+		if _, found := oldAttributes[path]; !found {
+			changes = append(changes, Change{Path: path, Kind: ChangeAdded, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diffableAttributes returns the attributes of the configuration compared by Diff, keyed by path. Dependency and
+// generate blocks are returned as objects, keyed by the path of the block.
+func diffableAttributes(config *TerragruntConfig) map[string]cty.Value {
+	attributes := map[string]cty.Value{}
+	if config == nil {
+		return attributes
+	}
+
+	for name, value := range config.InputsCty {
+		attributes["inputs."+name] = value
+	}
+	if config.Terraform != nil && config.Terraform.Source != nil {
+		attributes["terraform.source"] = cty.StringVal(*config.Terraform.Source)
+	}
+	if config.TerraformBinary != "" {
+		attributes["terraform_binary"] = cty.StringVal(config.TerraformBinary)
+	}
+	if config.Skip {
+		attributes["skip"] = cty.True
+	}
+	if config.PreventDestroy {
+		attributes["prevent_destroy"] = cty.True
+	}
+	if config.IamRole != "" {
+		attributes["iam_role"] = cty.StringVal(config.IamRole)
+	}
+	if engine := config.Engine; engine != nil {
+		attributes["engine.source"] = cty.StringVal(engine.Source)
+		if engine.Version != nil {
+			attributes["engine.version"] = cty.StringVal(*engine.Version)
+		}
+		if engine.Type != nil {
+			attributes["engine.type"] = cty.StringVal(*engine.Type)
+		}
+		if engine.Meta != nil {
+			attributes["engine.meta"] = *engine.Meta
+		}
+	}
+	if exclude := config.Exclude; exclude != nil {
+		attributes["exclude.if"] = cty.BoolVal(exclude.If)
+		attributes["exclude.actions"] = stringListValue(exclude.Actions)
+		if exclude.ExcludeDependencies != nil {
+			attributes["exclude.exclude_dependencies"] = cty.BoolVal(*exclude.ExcludeDependencies)
+		}
+	}
+	if catalog := config.Catalog; catalog != nil {
+		attributes["catalog.urls"] = stringListValue(catalog.URLs)
+		if catalog.DefaultTemplate != nil {
+			attributes["catalog.default_template"] = cty.StringVal(*catalog.DefaultTemplate)
+		}
+	}
+	for name, value := range config.FeatureFlags {
+		attributes["feature."+name] = value
+	}
+	if config.Errors != nil {
+		for _, retry := range config.Errors.Retry {
+			attributes["errors.retry."+retry.Label] = cty.ObjectVal(map[string]cty.Value{
+				"retryable_errors":   stringListValue(retry.RetryableErrors),
+				"max_attempts":       cty.NumberIntVal(int64(retry.MaxAttempts)),
+				"sleep_interval_sec": cty.NumberIntVal(int64(retry.SleepIntervalSec)),
+			})
+		}
+		for _, ignore := range config.Errors.Ignore {
+			block := map[string]cty.Value{"ignorable_errors": stringListValue(ignore.IgnorableErrors)}
+			if ignore.Message != nil {
+				block["message"] = cty.StringVal(*ignore.Message)
+			}
+			if ignore.Signals != nil {
+				block["signals"] = *ignore.Signals
+			}
+			attributes["errors.ignore."+ignore.Label] = cty.ObjectVal(block)
+		}
+	}
+
+	for _, dependency := range config.TerragruntDependencies {
+		block := map[string]cty.Value{
+			"config_path": cty.StringVal(dependency.ConfigPath),
+		}
+		if dependency.Enabled != nil {
+			block["enabled"] = cty.BoolVal(*dependency.Enabled)
+		}
+		if dependency.SkipOutputs != nil {
+			block["skip_outputs"] = cty.BoolVal(*dependency.SkipOutputs)
+		}
+		if dependency.MockOutputs != nil {
+			block["mock_outputs"] = *dependency.MockOutputs
+		}
+		attributes["dependency."+dependency.Name] = cty.ObjectVal(block)
+	}
+
+	for name, generate := range config.GenerateConfigs {
+		block := map[string]cty.Value{
+			"path":      cty.StringVal(generate.Path),
+			"if_exists": cty.StringVal(generate.IfExists),
+			"contents":  cty.StringVal(generate.Contents),
+		}
+		if generate.IfDisabled != nil {
+			block["if_disabled"] = cty.StringVal(*generate.IfDisabled)
+		}
+		if generate.CommentPrefix != nil {
+			block["comment_prefix"] = cty.StringVal(*generate.CommentPrefix)
+		}
+		if generate.DisableSignature != nil {
+			block["disable_signature"] = cty.BoolVal(*generate.DisableSignature)
+		}
+		if generate.Disable != nil {
+			block["disable"] = cty.BoolVal(*generate.Disable)
+		}
+		attributes["generate."+name] = cty.ObjectVal(block)
+	}
+	return attributes
+}
+
+// isBlockPath returns whether the given path, as returned by diffableAttributes, is the one of a block.
+func isBlockPath(path string) bool {
+	prefix := parentPath(path)
+	return prefix == "dependency" || prefix == "generate" || prefix == "errors.retry" || prefix == "errors.ignore"
+}
+
+// stringListValue returns the given strings as a cty list.
+func stringListValue(values []string) cty.Value {
+	if len(values) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	list := make([]cty.Value, 0, len(values))
+	for _, value := range values {
+		list = append(list, cty.StringVal(value))
+	}
+	return cty.ListVal(list)
+}
+
+// blockChanges returns the changes between the attributes of two versions of a block.
+func blockChanges(path string, oldBlock cty.Value, newBlock cty.Value) []Change {
+	oldAttributes, newAttributes := oldBlock.AsValueMap(), newBlock.AsValueMap()
+
+	var changes []Change
+	for name, oldValue := range oldAttributes {
+		newValue, found := newAttributes[name]
+		switch {
+		case !found:
+			changes = append(changes, Change{Path: path + "." + name, Kind: ChangeRemoved, Old: oldValue})
+		case !valuesEqual(oldValue, newValue):
+			changes = append(changes, Change{Path: path + "." + name, Kind: ChangeChanged, Old: oldValue, New: newValue})
+		}
+	}
+	for name, newValue := range newAttributes {
+		if _, found := oldAttributes[name]; !found {
+			changes = append(changes, Change{Path: path + "." + name, Kind: ChangeAdded, New: newValue})
+		}
+	}
+	return changes
+}
+
+// valuesEqual returns whether the two values are the same, ignoring their marks. Unknown values are only equal to
+// unknown values of the same type.
+func valuesEqual(a cty.Value, b cty.Value) bool {
+	a, _ = a.UnmarkDeep()
+	b, _ = b.UnmarkDeep()
+	return a.RawEquals(b)
+}
@@ -0,0 +1,202 @@
+package terragrunt
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriftOptions configures DetectDrift.
+type DriftOptions struct {
+	// Command is the terragrunt binary to run. Defaults to terragrunt.
+	Command string
+
+	// Args are additional arguments of terragrunt plan, e.g. -parallelism=20.
+	Args []string
+
+	// RefreshOnly runs refresh-only plans, which only report the changes made outside of terraform, instead of plans
+	// also reporting the changes of the configuration that are not applied yet.
+	RefreshOnly bool
+
+	// Parallelism is the maximum number of units planned at once, every unit at once when not positive.
+	Parallelism int
+
+	// Targets are the absolute paths of the units to check. Every unit of the stack is checked when empty.
+	Targets []string
+}
+
+// UnitDrift is the drift of a unit.
+type UnitDrift struct {
+	Path string
+
+	// Drifted reports whether the plan of the unit has changes: the unit drifted, or its configuration changed and is
+	// not applied yet, unless the plan is refresh-only.
+	Drifted bool
+
+	// Plan is the summary of the plan of the unit, with the resources it changes and the ones that drifted, or nil when
+	// the plan failed.
+	Plan *PlanSummary
+
+	// Err is the error of the plan of the unit, if it failed.
+	Err error
+
+	Duration time.Duration
+}
+
+// DriftReport is the result of DetectDrift.
+type DriftReport struct {
+	// Time is the time the detection started.
+	Time time.Time
+
+	// Units are the drifts of the units checked, sorted by path.
+	Units []UnitDrift
+}
+
+// Drifted returns the units that drifted.
+func (report *DriftReport) Drifted() []UnitDrift {
+	var drifted []UnitDrift
+	for _, unit := range report.Units {
+		if unit.Drifted {
+			drifted = append(drifted, unit)
+		}
+	}
+	return drifted
+}
+
+// Failed returns the units whose plan failed.
+func (report *DriftReport) Failed() []UnitDrift {
+	var failed []UnitDrift
+	for _, unit := range report.Units {
+		if unit.Err != nil {
+			failed = append(failed, unit)
+		}
+	}
+	return failed
+}
+
+// DetectDrift plans the units of the given stack with terragrunt plan -detailed-exitcode, and reports the units
+// whose plan has changes, with the resources the plans change and the ones that drifted, as read with terragrunt show
+// -json. It is meant for drift detection services running on a schedule: plans are run without locking the state
+// unless Args say otherwise, never apply anything, and run concurrently, as planning a unit does not depend on the
+// plan of another. The units left out of plan by run-all commands (see BatchesFor) are not checked. The plans failing
+// are reported with their error, and do not stop the others. It returns an error when a unit can not be checked at
+// all, such as a target that is not in the stack. Pass WithCommandRunner to run terragrunt with another runner,
+// WithProgress to receive the unit_started and unit_finished events, and WithContext to cancel the plans.
+func DetectDrift(stack *Stack, driftOpts DriftOptions, opts ...Option) (*DriftReport, error) {
+	parseOptions := newParseOptions(opts)
+	report := &DriftReport{Time: time.Now()}
+
+	graph := stack.Graph()
+	batches, err := graph.BatchesFor("plan")
+	if err != nil {
+		return nil, err
+	}
+	planned := map[string]bool{}
+	for _, batch := range batches {
+		for _, path := range batch {
+			planned[path] = true
+		}
+	}
+	var paths []string
+	if len(driftOpts.Targets) == 0 {
+		for path := range planned {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range driftOpts.Targets {
+		if stack.Unit(path) == nil {
+			return nil, fmt.Errorf("unit %s is not in the stack", graph.relativePath(path))
+		}
+		if planned[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	dir, err := os.MkdirTemp("", "tgdrift-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	drifts := map[string]UnitDrift{}
+	var mutex sync.Mutex
+	err = RunBatches([][]string{paths}, driftOpts.Parallelism, func(ctx context.Context, path string) error {
+		start := time.Now()
+		planFile := filepath.Join(dir, fmt.Sprintf("%x.tfplan", sha256.Sum256([]byte(path))))
+		drift := driftOpts.planUnit(ctx, commandRunner(parseOptions.CommandRunner), path, planFile)
+		drift.Duration = time.Since(start)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		drifts[path] = drift
+		return drift.Err
+	}, opts...)
+	var runErr *UnitRunError
+	if err != nil && !errors.As(err, &runErr) {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		report.Units = append(report.Units, drifts[path])
+	}
+	return report, nil
+}
+
+// planUnit plans the unit at the given path, saving the plan to the given file.
+func (driftOpts DriftOptions) planUnit(ctx context.Context, runner CommandRunner, path string, planFile string) UnitDrift {
+	drift := UnitDrift{Path: path}
+	command := driftOpts.Command
+	if command == "" {
+		command = "terragrunt"
+	}
+
+	args := []string{"plan", "-detailed-exitcode", "-input=false", "-lock=false", "-out=" + planFile}
+	if driftOpts.RefreshOnly {
+		args = append(args, "-refresh-only")
+	}
+	args = append(args, driftOpts.Args...)
+	env := []string{"TF_INPUT=0", "TERRAGRUNT_NON_INTERACTIVE=true"}
+	output, err := runner.Run(ctx, Command{Name: command, Args: args, Dir: path, Env: env})
+
+	var exitErr interface{ ExitCode() int }
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 2:
+		drift.Drifted = true
+	default:
+		drift.Err = commandError(err, output)
+		return drift
+	}
+
+	output, err = runner.Run(ctx, Command{Name: command, Args: []string{"show", "-json", planFile}, Dir: path, Env: env})
+	if err != nil {
+		drift.Err = commandError(err, output)
+		return drift
+	}
+	if drift.Plan, err = ParsePlanJSON(output.Stdout); err != nil {
+		drift.Err = err
+	}
+	return drift
+}
+
+// commandError returns the given error of a command, with the end of its standard error, which holds the reason of
+// its failure.
+func commandError(err error, output CommandOutput) error {
+	stderr := strings.TrimSpace(string(ansiEscape.ReplaceAll(output.Stderr, nil)))
+	if stderr == "" {
+		return err
+	}
+	lines := strings.Split(stderr, "\n")
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	return fmt.Errorf("%w: %s", err, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,142 @@
+package terragrunt
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	stack := &Stack{Root: "/stack", Units: []*Unit{
+		{Path: "/stack/app", Dependencies: []string{"/stack/vpc"}},
+		{Path: "/stack/cache", Config: &TerragruntConfig{Skip: true}},
+		{Path: "/stack/db", Dependencies: []string{"/stack/vpc"}},
+		{Path: "/stack/dns"},
+		{Path: "/stack/vpc"},
+	}}
+
+	// The plans exit with 0 without changes, 2 with changes, and 1 when they fail.
+	planExitCodes := map[string]int{"/stack/app": 0, "/stack/db": 2, "/stack/dns": 1, "/stack/vpc": 2}
+	plans := map[string]string{
+		"/stack/app": `{"resource_changes": [{"address": "aws_instance.app", "change": {"actions": ["no-op"]}}]}`,
+		"/stack/db": `{
+  "resource_changes": [{"address": "aws_db_instance.db", "change": {"actions": ["update"]}}],
+  "resource_drift": [{"address": "aws_db_instance.db", "change": {"actions": ["update"]}}]
+}`,
+		"/stack/vpc": `{"resource_changes": [{"address": "aws_vpc.vpc", "change": {"actions": ["delete", "create"]}}]}`,
+	}
+	runner := &FakeCommandRunner{Handler: func(ctx context.Context, command Command) (CommandOutput, error) {
+		switch command.Args[0] {
+		case "plan":
+			if code := planExitCodes[command.Dir]; code == 1 {
+				return CommandOutput{Stderr: []byte("\x1b[31mError: No valid credential sources found\x1b[0m\n")}, &ExitError{Code: 1}
+			} else if code != 0 {
+				return CommandOutput{}, &ExitError{Code: code}
+			}
+			return CommandOutput{}, nil
+		case "show":
+			return CommandOutput{Stdout: []byte(plans[command.Dir])}, nil
+		}
+		t.Errorf("unexpected command %s", command)
+		return CommandOutput{}, &ExitError{Code: 1}
+	}}
+
+	report, err := DetectDrift(stack, DriftOptions{Args: []string{"-parallelism=20"}}, WithCommandRunner(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []UnitDrift{
+		{Path: "/stack/app", Plan: &PlanSummary{}},
+		{Path: "/stack/db", Drifted: true, Plan: &PlanSummary{
+			Change:    1,
+			Resources: []PlannedResource{{Address: "aws_db_instance.db", Action: PlanUpdate}},
+			Drift:     []PlannedResource{{Address: "aws_db_instance.db", Action: PlanUpdate}},
+		}},
+		{Path: "/stack/dns"},
+		{Path: "/stack/vpc", Drifted: true, Plan: &PlanSummary{
+			Add:       1,
+			Destroy:   1,
+			Resources: []PlannedResource{{Address: "aws_vpc.vpc", Action: PlanReplace}},
+		}},
+	}
+	if len(report.Units) != len(want) {
+		t.Fatalf("got units %+v, want %+v", report.Units, want)
+	}
+	for i, unit := range report.Units {
+		unit.Duration = 0
+		if unit.Path == "/stack/dns" {
+			if unit.Err == nil || unit.Err.Error() != "exit status 1: Error: No valid credential sources found" {
+				t.Errorf("got error %v for %s, want the exit status with the standard error", unit.Err, unit.Path)
+			}
+			unit.Err = nil
+		}
+		if !reflect.DeepEqual(unit, want[i]) {
+			t.Errorf("got drift %+v, want %+v", unit, want[i])
+		}
+	}
+	if drifted := report.Drifted(); len(drifted) != 2 || drifted[0].Path != "/stack/db" || drifted[1].Path != "/stack/vpc" {
+		t.Errorf("got drifted units %+v, want db and vpc", drifted)
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Path != "/stack/dns" {
+		t.Errorf("got failed units %+v, want dns", failed)
+	}
+
+	// Every unit but the skipped one is planned, without locking nor input, and the plan saved is the one shown. The
+	// failed plan is not shown.
+	commands := map[string][]Command{}
+	for _, command := range runner.Commands() {
+		commands[command.Dir] = append(commands[command.Dir], command)
+		if command.Name != "terragrunt" || !reflect.DeepEqual(command.Env, []string{"TF_INPUT=0", "TERRAGRUNT_NON_INTERACTIVE=true"}) {
+			t.Errorf("got command %s with env %q", command, command.Env)
+		}
+	}
+	for path, code := range planExitCodes {
+		unitCommands, want := commands[path], 2
+		if code == 1 {
+			want = 1
+		}
+		if len(unitCommands) != want {
+			t.Fatalf("got commands %v for %s", unitCommands, path)
+		}
+		plan := unitCommands[0].Args
+		if len(plan) != 6 || !reflect.DeepEqual(plan[:4], []string{"plan", "-detailed-exitcode", "-input=false", "-lock=false"}) ||
+			!strings.HasPrefix(plan[4], "-out=") || plan[5] != "-parallelism=20" {
+			t.Errorf("got plan command %q for %s", plan, path)
+		}
+		if want == 2 {
+			if show := unitCommands[1].Args; !reflect.DeepEqual(show, []string{"show", "-json", strings.TrimPrefix(plan[4], "-out=")}) {
+				t.Errorf("got show command %q for %s, want the plan file shown", show, path)
+			}
+		}
+	}
+	if len(commands["/stack/cache"]) > 0 {
+		t.Errorf("got commands %v for the skipped unit, want none", commands["/stack/cache"])
+	}
+}
+
+func TestDetectDriftTargets(t *testing.T) {
+	stack := &Stack{Root: "/stack", Units: []*Unit{{Path: "/stack/app"}, {Path: "/stack/vpc"}}}
+	runner := &FakeCommandRunner{Handler: func(ctx context.Context, command Command) (CommandOutput, error) {
+		if command.Args[0] == "show" {
+			return CommandOutput{Stdout: []byte(`{}`)}, nil
+		}
+		return CommandOutput{}, nil
+	}}
+
+	report, err := DetectDrift(stack, DriftOptions{Command: "tg", RefreshOnly: true, Targets: []string{"/stack/vpc"}}, WithCommandRunner(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Units) != 1 || report.Units[0].Path != "/stack/vpc" || report.Units[0].Drifted {
+		t.Errorf("got units %+v, want vpc without drift", report.Units)
+	}
+	if commands := runner.Commands(); len(commands) != 2 || commands[0].Name != "tg" || commands[0].Args[len(commands[0].Args)-1] != "-refresh-only" {
+		t.Errorf("got commands %v, want a refresh-only plan of vpc with tg", commands)
+	}
+
+	if _, err := DetectDrift(stack, DriftOptions{Targets: []string{"/stack/db"}}, WithCommandRunner(runner)); err == nil || err.Error() != "unit db is not in the stack" {
+		t.Errorf("got error %v, want db not in the stack", err)
+	}
+}
@@ -0,0 +1,81 @@
+package terragrunt
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// The editing functions below rewrite terragrunt configuration content in place through hclwrite, so that comments
+// and formatting of the untouched parts are preserved.
+
+// sourceVersionRegexp matches the version of a module source: the ref query parameter of git sources, or the version
+// query parameter of registry sources.
+var sourceVersionRegexp = regexp.MustCompile(`([?&](?:ref|version)=)([^&"]*)`)
+
+// SetTerraformSource sets the source attribute of the terraform block to the given value, creating the block and the
+// attribute if needed.
+func SetTerraformSource(content []byte, source string) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	block := file.Body().FirstMatchingBlock("terraform", nil)
+	if block == nil {
+		block = file.Body().AppendNewBlock("terraform", nil)
+	}
+	block.Body().SetAttributeValue("source", cty.StringVal(source))
+
+	return file.Bytes(), nil
+}
+
+// SetTerraformSourceVersion replaces the version of the source attribute of the terraform block, which is the ref
+// query parameter of git sources or the version query parameter of registry sources. Only the literal parts of the
+// source are rewritten, so sources built with interpolations keep their expressions. It returns whether the content
+// was changed, and an error if there is no source attribute.
+func SetTerraformSourceVersion(content []byte, version string) ([]byte, bool, error) {
+	file, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, false, diags
+	}
+
+	block := file.Body().FirstMatchingBlock("terraform", nil)
+	if block == nil || block.Body().GetAttribute("source") == nil {
+		return nil, false, errors.New("no terraform source attribute found")
+	}
+	attribute := block.Body().GetAttribute("source")
+
+	changed := false
+	tokens := attribute.Expr().BuildTokens(nil)
+	for _, token := range tokens {
+		if token.Type != hclsyntax.TokenQuotedLit {
+			continue
+		}
+		updated := sourceVersionRegexp.ReplaceAll(token.Bytes, []byte("${1}"+version))
+		if string(updated) != string(token.Bytes) {
+			token.Bytes = updated
+			changed = true
+		}
+	}
+	if !changed {
+		return content, false, nil
+	}
+
+	block.Body().SetAttributeRaw("source", tokens)
+	return file.Bytes(), true, nil
+}
+
+// SourceVersion returns the version of the given module source, which is the ref query parameter of git sources or
+// the version query parameter of registry sources, or the empty string when it is not pinned.
+func SourceVersion(source string) string {
+	match := sourceVersionRegexp.FindStringSubmatch(source)
+	if match == nil {
+		return ""
+	}
+	return match[2]
+}
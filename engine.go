@@ -0,0 +1,54 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// EngineConfig is the engine block, which pins the IaC engine terragrunt runs the terraform commands of the unit with,
+// instead of the terraform or tofu binary. Engines are an experimental terragrunt feature.
+type EngineConfig struct {
+	Source  string     `hcl:"source,attr"`
+	Version *string    `hcl:"version,attr"`
+	Type    *string    `hcl:"type,attr"`
+	Meta    *cty.Value `hcl:"meta,attr"`
+}
+
+const RuleEngineNotAllowed = "engine-not-allowed"
+
+// EnginePolicyRule returns a Rule reporting the units whose engine is not approved: the source of their engine must
+// match one of the allowed patterns, or, when it pins a version, <source>@<version> must, so that patterns can
+// allow any version of an engine (e.g. github.com/gruntwork-io/terragrunt-engine-opentofu) or only some of them
+// (e.g. github.com/gruntwork-io/terragrunt-engine-opentofu@v0.0.*). Patterns are matched as by SourcePolicyRule.
+// Units without an engine block are not checked.
+func EnginePolicyRule(allow []string) Rule {
+	allowed := sourcePatterns(allow)
+
+	return RuleFunc(func(unit *Unit) []Finding {
+		engine := unit.Config.Engine
+		if engine == nil {
+			return nil
+		}
+		if matchingSourcePattern(allowed, engine.Source) != "" {
+			return nil
+		}
+
+		message := fmt.Sprintf("engine %q does not match any of the allowed patterns", engine.Source)
+		if engine.Version != nil {
+			pinned := engine.Source + "@" + *engine.Version
+			if matchingSourcePattern(allowed, pinned) != "" {
+				return nil
+			}
+			message = fmt.Sprintf("engine %q does not match any of the allowed patterns", pinned)
+		}
+
+		origin, _ := unit.Config.Origin("engine.source")
+		return []Finding{{
+			RuleID:   RuleEngineNotAllowed,
+			Severity: SeverityError,
+			Message:  message,
+			Range:    origin.Range,
+		}}
+	})
+}
@@ -0,0 +1,34 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// getEnvFunc returns the get_env(name, default) function, which returns the value of the given environment variable,
+// or the default when it is not set. Without a default, an unset variable is an error.
+func getEnvFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "name", Type: cty.String},
+		},
+		VarParam: &function.Parameter{Name: "default", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			name := args[0].AsString()
+			if value, found := opts.lookupEnv(name); found {
+				return cty.StringVal(value), nil
+			}
+
+			switch len(args) {
+			case 1:
+				return cty.NilVal, fmt.Errorf("environment variable %s is not set", name)
+			case 2:
+				return args[1], nil
+			}
+			return cty.NilVal, fmt.Errorf("get_env expects at most 2 arguments, got %d", len(args))
+		},
+	})
+}
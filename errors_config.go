@@ -0,0 +1,164 @@
+package terragrunt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ErrorsConfig is the errors block, which declares how the errors of the terraform commands of the unit are handled:
+// retried, or ignored. Tools running the commands implement the handling with MatchIgnore and MatchRetry.
+type ErrorsConfig struct {
+	Retry  []ErrorsRetry  `hcl:"retry,block"`
+	Ignore []ErrorsIgnore `hcl:"ignore,block"`
+}
+
+// ErrorsRetry is a retry block of the errors block: the commands failing with an output matching one of its
+// patterns are run again, up to MaxAttempts times in total, waiting SleepIntervalSec seconds between attempts.
+type ErrorsRetry struct {
+	Label            string   `hcl:",label"`
+	RetryableErrors  []string `hcl:"retryable_errors,attr"`
+	MaxAttempts      int      `hcl:"max_attempts,attr"`
+	SleepIntervalSec int      `hcl:"sleep_interval_sec,attr"`
+}
+
+// ErrorsIgnore is an ignore block of the errors block: the failures of the commands with an output matching one of
+// its patterns are ignored, unless the output also matches one of its negative patterns, prefixed with !. The message
+// is shown instead of the error, and the signals are written to a file for the tools reacting to ignored errors.
+type ErrorsIgnore struct {
+	Label           string     `hcl:",label"`
+	IgnorableErrors []string   `hcl:"ignorable_errors,attr"`
+	Message         *string    `hcl:"message,attr"`
+	Signals         *cty.Value `hcl:"signals,attr"`
+}
+
+// MatchIgnore returns the first ignore block ignoring a command failing with the given output, or nil if the failure
+// must not be ignored.
+func (config *ErrorsConfig) MatchIgnore(output string) *ErrorsIgnore {
+	if config == nil {
+		return nil
+	}
+	for i := range config.Ignore {
+		if errorPatternsMatch(config.Ignore[i].IgnorableErrors, output) {
+			return &config.Ignore[i]
+		}
+	}
+	return nil
+}
+
+// MatchRetry returns the first retry block retrying a command failing with the given output, or nil if the command
+// must not be retried. Failures that are ignored (see MatchIgnore) are not retried, as by terragrunt.
+func (config *ErrorsConfig) MatchRetry(output string) *ErrorsRetry {
+	if config == nil || config.MatchIgnore(output) != nil {
+		return nil
+	}
+	for i := range config.Retry {
+		if errorPatternsMatch(config.Retry[i].RetryableErrors, output) {
+			return &config.Retry[i]
+		}
+	}
+	return nil
+}
+
+// errorPatternsMatch returns whether the given output matches one of the given patterns, and none of the negative ones,
+// prefixed with !. The patterns are validated by validateErrorsConfig: invalid ones never match.
+func errorPatternsMatch(patterns []string, output string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negative := strings.HasPrefix(pattern, "!")
+		expression, err := regexp.Compile(strings.TrimPrefix(pattern, "!"))
+		if err != nil || !expression.MatchString(output) {
+			continue
+		}
+		if negative {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// validateErrorsConfig reports the retry and ignore blocks sharing a label, the invalid patterns, and the retry
+// blocks without attempts.
+func validateErrorsConfig(config *ErrorsConfig) error {
+	labels := map[string]bool{}
+	for _, retry := range config.Retry {
+		if labels[retry.Label] {
+			return fmt.Errorf("multiple retry blocks labeled %s", retry.Label)
+		}
+		labels[retry.Label] = true
+		if retry.MaxAttempts < 1 {
+			return fmt.Errorf("retry %s: max_attempts must be at least 1", retry.Label)
+		}
+		if retry.SleepIntervalSec < 0 {
+			return fmt.Errorf("retry %s: sleep_interval_sec can not be negative", retry.Label)
+		}
+		if err := validateErrorPatterns(retry.RetryableErrors); err != nil {
+			return fmt.Errorf("retry %s: %w", retry.Label, err)
+		}
+	}
+
+	labels = map[string]bool{}
+	for _, ignore := range config.Ignore {
+		if labels[ignore.Label] {
+			return fmt.Errorf("multiple ignore blocks labeled %s", ignore.Label)
+		}
+		labels[ignore.Label] = true
+		if err := validateErrorPatterns(ignore.IgnorableErrors); err != nil {
+			return fmt.Errorf("ignore %s: %w", ignore.Label, err)
+		}
+	}
+	return nil
+}
+
+func validateErrorPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(strings.TrimPrefix(pattern, "!")); err != nil {
+			return fmt.Errorf("invalid error pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// mergeErrorsConfigs returns the errors block of the base configuration, if any, with the one of the overlay merged
+// into it: retry and ignore blocks are merged by label, keeping the order of the base configuration first.
+func mergeErrorsConfigs(base *ErrorsConfig, overlay *ErrorsConfig) *ErrorsConfig {
+	if base == nil {
+		return overlay
+	}
+
+	merged := &ErrorsConfig{}
+	for _, retry := range base.Retry {
+		if !hasErrorsRetry(overlay.Retry, retry.Label) {
+			merged.Retry = append(merged.Retry, retry)
+		}
+	}
+	merged.Retry = append(merged.Retry, overlay.Retry...)
+	for _, ignore := range base.Ignore {
+		if !hasErrorsIgnore(overlay.Ignore, ignore.Label) {
+			merged.Ignore = append(merged.Ignore, ignore)
+		}
+	}
+	merged.Ignore = append(merged.Ignore, overlay.Ignore...)
+	return merged
+}
+
+func hasErrorsRetry(retries []ErrorsRetry, label string) bool {
+	for _, retry := range retries {
+		if retry.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+func hasErrorsIgnore(ignores []ErrorsIgnore, label string) bool {
+	for _, ignore := range ignores {
+		if ignore.Label == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Eval evaluates the given HCL expression in the context the configuration was evaluated in: its locals (local), the
+// outputs of its dependencies (dependency), its feature flags (feature), its includes (include.<name>, with their path,
+// and the locals and inputs of the included configuration) and every function available to the configuration. The
+// resolved inputs are exposed too, as inputs, e.g. for ad-hoc queries such as inputs.region == local.region.
+//
+// The outputs of the dependencies the configuration does not reference are resolved on demand, with the options the
+// configuration was parsed with. Configurations that were not parsed in this process, e.g. loaded from a snapshot,
+// only expose the variables of Query.
+func (config *TerragruntConfig) Eval(expression string) (cty.Value, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(expression), "eval", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	queryVariables := queryVariables(config)
+	ctx := &hcl.EvalContext{Variables: queryVariables, Functions: stdlibFunctions()}
+	if config.evalContext != nil {
+		ctx.Functions = config.evalContext.Functions
+		ctx.Variables = map[string]cty.Value{"inputs": queryVariables["inputs"]}
+		for name, value := range config.evalContext.Variables {
+			ctx.Variables[name] = value
+		}
+		dependencies, err := config.resolveReferencedOutputs(expr, ctx.Variables["dependency"])
+		if err != nil {
+			return cty.NilVal, err
+		}
+		ctx.Variables["dependency"] = dependencies
+	}
+
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	return value, nil
+}
+
+// resolveReferencedOutputs returns the given dependency variable, with the outputs of the dependencies the expression
+// references resolved when the configuration did not reference them itself.
+func (config *TerragruntConfig) resolveReferencedOutputs(expr hclsyntax.Expression, dependencies cty.Value) (cty.Value, error) {
+	attributes := map[string]cty.Value{}
+	if dependencies != cty.NilVal && !dependencies.IsNull() && dependencies.Type().IsObjectType() {
+		for name, value := range dependencies.AsValueMap() {
+			attributes[name] = value
+		}
+	}
+
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "dependency" || len(traversal) < 2 {
+			continue
+		}
+		name, isAttribute := traversal[1].(hcl.TraverseAttr)
+		if !isAttribute {
+			continue
+		}
+		if existing, found := attributes[name.Name]; found && existing.Type().HasAttribute("outputs") {
+			continue
+		}
+		for _, dependency := range config.TerragruntDependencies {
+			if dependency.Name != name.Name || !dependency.IsEnabled() {
+				continue
+			}
+			if err := dependency.setRenderedOutputs(nil, config.evalOptions); err != nil {
+				return cty.NilVal, err
+			}
+			if dependency.RenderedOutputs != nil {
+				attributes[name.Name] = cty.ObjectVal(map[string]cty.Value{"outputs": *dependency.RenderedOutputs})
+			}
+		}
+	}
+	return cty.ObjectVal(attributes), nil
+}
+
+// Eval evaluates the given HCL expression in the context of the configuration of the unit (see TerragruntConfig.Eval).
+func (unit *Unit) Eval(expression string) (cty.Value, error) {
+	if unit.Config == nil {
+		return cty.NilVal, fmt.Errorf("%s: the configuration could not be parsed: %w", unit.Path, unit.Err)
+	}
+	return unit.Config.Eval(expression)
+}
@@ -0,0 +1,109 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestUnitEval(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.hcl": `
+locals {
+  org = "acme"
+}
+
+inputs = {
+  org = local.org
+}
+`,
+		"live/vpc/terragrunt.hcl": "",
+		"live/db/terragrunt.hcl":  "",
+		"live/app/terragrunt.hcl": `
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+locals {
+  region = "eu-west-1"
+  name   = "app-${local.region}"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+dependency "db" {
+  config_path = "../db"
+}
+
+inputs = {
+  region = local.region
+  vpc_id = dependency.vpc.outputs.vpc_id
+}
+`,
+		"live/broken/terragrunt.hcl": "inputs = {",
+	})
+	resolver := &recordingResolver{}
+	stack, err := ParseStack(filepath.Join(dir, "live"), WithOutputResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit := stack.Unit(filepath.Join(dir, "live", "app"))
+	if unit == nil || unit.Err != nil {
+		t.Fatalf("got unit %v, want live/app parsed", unit)
+	}
+	parsedCalls := len(resolver.calls)
+
+	tests := []struct {
+		name       string
+		expression string
+		want       cty.Value
+		err        string
+	}{
+		{name: "local", expression: "local.name", want: cty.StringVal("app-eu-west-1")},
+		{name: "inputs", expression: "inputs.region == local.region", want: cty.True},
+		{name: "included inputs", expression: "inputs.org", want: cty.StringVal("acme")},
+		{name: "included locals", expression: "include.root.locals.org", want: cty.StringVal("acme")},
+		{name: "included path", expression: "include.root.path", want: cty.StringVal(filepath.Join(dir, "root.hcl"))},
+		{name: "referenced dependency outputs", expression: "dependency.vpc.outputs.vpc_id", want: cty.StringVal("vpc-1")},
+		{name: "unreferenced dependency outputs", expression: "dependency.db.outputs.subnet_ids[0]", want: cty.StringVal("subnet-1")},
+		{name: "function", expression: "upper(local.region)", want: cty.StringVal("EU-WEST-1")},
+		{name: "terragrunt function", expression: "get_terragrunt_dir()", want: cty.StringVal(filepath.Join(dir, "live", "app"))},
+		{name: "functions over outputs", expression: `join(",", dependency.vpc.outputs.subnet_ids)`, want: cty.StringVal("subnet-1")},
+		{name: "unknown local", expression: "local.missing", err: "Unsupported attribute"},
+		{name: "unknown dependency", expression: "dependency.cache.outputs.id", err: "Unsupported attribute"},
+		{name: "invalid expression", expression: "local.", err: "Invalid attribute name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := unit.Eval(test.expression)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !value.RawEquals(test.want) {
+				t.Errorf("got %#v, want %#v", value, test.want)
+			}
+		})
+	}
+
+	// Only the outputs of the dependency the configuration does not reference are resolved on demand, once per
+	// expression referencing them.
+	if calls := len(resolver.calls) - parsedCalls; calls != 1 {
+		t.Errorf("got %d output resolutions evaluating the expressions, want 1", calls)
+	}
+
+	broken := stack.Unit(filepath.Join(dir, "live", "broken"))
+	if _, err := broken.Eval("1"); err == nil || !strings.Contains(err.Error(), "the configuration could not be parsed") {
+		t.Errorf("got error %v evaluating in an unparsed unit, want the configuration not parsed", err)
+	}
+}
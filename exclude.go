@@ -0,0 +1,44 @@
+package terragrunt
+
+// ExcludeConfig is the exclude block, which excludes the unit from the run-all commands running the given actions when
+// its condition holds, e.g. depending on a feature flag.
+type ExcludeConfig struct {
+	If                  bool     `hcl:"if,attr"`
+	Actions             []string `hcl:"actions,attr"`
+	ExcludeDependencies *bool    `hcl:"exclude_dependencies,attr"`
+}
+
+const (
+	// ExcludeAllActions is the action of the exclude blocks excluding the unit from every command.
+	ExcludeAllActions = "all"
+
+	// ExcludeAllExceptOutput is the action of the exclude blocks excluding the unit from every command but output, so
+	// that the units depending on it can still read its outputs.
+	ExcludeAllExceptOutput = "all_except_output"
+)
+
+// Excludes returns whether the exclude block excludes the unit from the run-all commands running the given action
+// (e.g. plan or apply): its condition must hold, and its actions must list the action, all, or all_except_output for
+// any action but output.
+func (exclude *ExcludeConfig) Excludes(action string) bool {
+	if exclude == nil || !exclude.If {
+		return false
+	}
+	for _, excluded := range exclude.Actions {
+		switch excluded {
+		case action, ExcludeAllActions:
+			return true
+		case ExcludeAllExceptOutput:
+			if action != "output" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludesDependencies returns whether the exclude block also excludes the dependencies of the unit from the run-all
+// commands running the given action.
+func (exclude *ExcludeConfig) excludesDependencies(action string) bool {
+	return exclude.Excludes(action) && exclude.ExcludeDependencies != nil && *exclude.ExcludeDependencies
+}
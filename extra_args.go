@@ -0,0 +1,110 @@
+package terragrunt
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// varFileCommands are the terraform commands the var files of extra_arguments blocks are passed to, as terragrunt
+// passes them.
+var varFileCommands = []string{"apply", "console", "destroy", "import", "plan", "push", "refresh"}
+
+// ExtraArgs are the arguments and environment variables a unit passes to a terraform command, from its extra_arguments
+// blocks.
+type ExtraArgs struct {
+	// Args are the arguments to pass after the command, before the arguments of the user: the arguments of the
+	// blocks, followed by their var files as -var-file=<path>, in the order of the blocks.
+	Args []string
+
+	// VarFiles are the paths of the var files passed in Args, in order.
+	VarFiles []string
+
+	// Env are the environment variables to set, the last block setting a variable winning.
+	Env map[string]string
+}
+
+// Environ returns the environment variables of the arguments in the KEY=value form, sorted, as expected by
+// Command.Env.
+func (args *ExtraArgs) Environ() []string {
+	environ := make([]string, 0, len(args.Env))
+	for name, value := range args.Env {
+		environ = append(environ, name+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// ResolveExtraArgs returns the arguments and environment variables the given resolved configuration passes to the
+// given terraform command (e.g. plan), as terragrunt does: the extra_arguments blocks listing the command apply, in
+// order, and their var files are only passed to the commands accepting them (apply, plan, destroy, ...). Optional var
+// files are only passed when they exist, and a missing required var file is an error. Relative var file paths are
+// resolved against the directory of the configuration, so that terraform can run in any directory, such as the
+// terragrunt cache. Var files are passed to apply even when it applies a saved plan, which terraform rejects: leave
+// them out in that case.
+func ResolveExtraArgs(config *TerragruntConfig, command string) (*ExtraArgs, error) {
+	resolved := &ExtraArgs{Env: map[string]string{}}
+	if config == nil || config.Terraform == nil {
+		return resolved, nil
+	}
+
+	withVarFiles := containsString(varFileCommands, command)
+	for _, extraArguments := range config.Terraform.ExtraArguments {
+		if !containsString(extraArguments.Commands, command) {
+			continue
+		}
+
+		if extraArguments.Arguments != nil {
+			resolved.Args = append(resolved.Args, *extraArguments.Arguments...)
+		}
+		if extraArguments.EnvVars != nil {
+			for name, value := range *extraArguments.EnvVars {
+				resolved.Env[name] = value
+			}
+		}
+		if !withVarFiles {
+			continue
+		}
+
+		if extraArguments.RequiredVarFiles != nil {
+			for _, varFile := range *extraArguments.RequiredVarFiles {
+				path := config.varFilePath(varFile)
+				if _, err := config.statVarFile(path); err != nil {
+					return nil, fmt.Errorf("extra_arguments %q: required var file: %w", extraArguments.Name, err)
+				}
+				resolved.addVarFile(path)
+			}
+		}
+		if extraArguments.OptionalVarFiles != nil {
+			for _, varFile := range *extraArguments.OptionalVarFiles {
+				path := config.varFilePath(varFile)
+				if info, err := config.statVarFile(path); err == nil && !info.IsDir() {
+					resolved.addVarFile(path)
+				}
+			}
+		}
+	}
+	return resolved, nil
+}
+
+func (args *ExtraArgs) addVarFile(path string) {
+	args.Args = append(args.Args, "-var-file="+path)
+	args.VarFiles = append(args.VarFiles, path)
+}
+
+// varFilePath returns the given var file path resolved against the directory of the configuration, when known.
+func (config *TerragruntConfig) varFilePath(path string) string {
+	if config.evalOptions == nil {
+		return path
+	}
+	return config.evalOptions.absolutePath(path)
+}
+
+// statVarFile returns the information of the var file at the given path, from the filesystem the configuration was
+// read from.
+func (config *TerragruntConfig) statVarFile(path string) (os.FileInfo, error) {
+	if config.evalOptions == nil {
+		return os.Stat(path)
+	}
+	return config.evalOptions.stat(path)
+}
@@ -0,0 +1,147 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// FeatureFlag is a feature block, declaring a feature flag along with its default value. The configuration reads its
+// value as feature.<name>.value, which can be overridden with WithFeatureFlags, as with the --feature flag of
+// terragrunt.
+type FeatureFlag struct {
+	Name    string     `hcl:",label"`
+	Default *cty.Value `hcl:"default,attr"`
+}
+
+// terragruntFeatureFlags is a struct that can be used to only decode the feature blocks in the terragrunt config
+type terragruntFeatureFlags struct {
+	FeatureFlags []FeatureFlag `hcl:"feature,block"`
+	Remain       hcl.Body      `hcl:",remain"`
+}
+
+// decodeFeatureFlags decodes the feature blocks of the given body, merged with the ones of the configurations it
+// includes, and returns the values of the flags, along with the remaining body, which holds everything but the feature
+// blocks. The feature blocks are decoded once the locals are evaluated, so that their default can reference them.
+func decodeFeatureFlags(body hcl.Body, opts *ParseOptions, extensions EvalContextExtensions) (map[string]cty.Value, hcl.Body, error) {
+	decoded := terragruntFeatureFlags{}
+	if err := decodeHCL(body, &decoded, opts, extensions); err != nil {
+		return nil, nil, err
+	}
+	flags, err := mergeIncludedFeatureFlags(body, decoded.FeatureFlags, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := featureFlagValues(flags, opts.FeatureFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, decoded.Remain, nil
+}
+
+// mergeIncludedFeatureFlags returns the given feature blocks of the configuration with the given body, merged by name
+// with the feature blocks of the configurations it includes, as mergeIncludedDependencies merges dependency blocks.
+// This makes the flags declared in included configurations (e.g. the root one) available to the including one.
+func mergeIncludedFeatureFlags(body hcl.Body, flags []FeatureFlag, opts *ParseOptions) ([]FeatureFlag, error) {
+	if !hasIncludeBlocks(body) || opts.originalConfigPath != "" {
+		return flags, nil
+	}
+
+	includes, err := decodeIncludes(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := flags
+	for i := len(includes) - 1; i >= 0; i-- {
+		include := includes[i]
+		if include.MergeStrategy == MergeNoMerge {
+			continue
+		}
+
+		content, err := opts.readFile(include.Path)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+		includedFlags, err := decodeFeatureBlocks(content, includedParseOptions(include, opts))
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+		for _, flag := range includedFlags {
+			if !hasFeatureFlag(merged, flag.Name) {
+				merged = append(merged, flag)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// decodeFeatureBlocks decodes the feature blocks of the given configuration, evaluating their default with its
+// locals.
+func decodeFeatureBlocks(content []byte, parseOptions *ParseOptions) ([]FeatureFlag, error) {
+	file, err := parseOptions.parseHCL(content, parseOptions.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	locals, remain, err := decodeAndEvaluateLocals(file.Body, parseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := terragruntFeatureFlags{}
+	if err := decodeHCL(remain, &decoded, parseOptions, EvalContextExtensions{Locals: locals}); err != nil {
+		return nil, err
+	}
+	return decoded.FeatureFlags, nil
+}
+
+func hasFeatureFlag(flags []FeatureFlag, name string) bool {
+	for _, flag := range flags {
+		if flag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// featureFlagValues returns the values of the given feature flags, keyed by name: the given override, converted to the
+// type of the default, or else the default. Flags without a default nor an override are null.
+func featureFlagValues(flags []FeatureFlag, overrides map[string]cty.Value) (map[string]cty.Value, error) {
+	values := map[string]cty.Value{}
+	for _, flag := range flags {
+		if _, found := values[flag.Name]; found {
+			return nil, fmt.Errorf("multiple feature blocks named %s", flag.Name)
+		}
+
+		value := cty.NullVal(cty.DynamicPseudoType)
+		if flag.Default != nil {
+			value = *flag.Default
+		}
+		if override, found := overrides[flag.Name]; found {
+			if value.Type() != cty.DynamicPseudoType {
+				converted, err := convert.Convert(override, value.Type())
+				if err != nil {
+					return nil, fmt.Errorf("invalid value of the feature flag %s: %w", flag.Name, err)
+				}
+				override = converted
+			}
+			value = override
+		}
+		values[flag.Name] = value
+	}
+	return values, nil
+}
+
+// featureFlagsValue returns the given values of the feature flags as the cty object exposed as feature, in which the
+// value of each flag is read as feature.<name>.value.
+func featureFlagsValue(values map[string]cty.Value) *cty.Value {
+	flags := map[string]cty.Value{}
+	for name, value := range values {
+		flags[name] = cty.ObjectVal(map[string]cty.Value{"value": value})
+	}
+	value := cty.ObjectVal(flags)
+	return &value
+}
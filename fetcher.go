@@ -0,0 +1,213 @@
+package terragrunt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	getter "github.com/hashicorp/go-getter/v2"
+)
+
+// Getter downloads the package of a module source into a directory. The destination directory exists and is empty.
+type Getter interface {
+	Get(ctx context.Context, dst string, source *Source) error
+}
+
+// SourceFetcher downloads module sources into a local cache directory, so that the modules used by units can be
+// inspected (e.g. their variables, outputs and providers) without running terragrunt init. Packages are stored under
+// a hash of their address and version, and downloaded only once.
+type SourceFetcher struct {
+	// CacheDir is the directory packages are downloaded into. Defaults to terragrunt-utils/modules in the user cache
+	// directory.
+	CacheDir string
+
+	// Getters download the packages of each kind of source, replacing the default GoGetter.
+	Getters map[SourceKind]Getter
+
+	// Retry is the policy failed downloads are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// Registry resolves registry sources to the address of their package. Defaults to a RegistryClient with no
+	// configuration.
+	Registry *RegistryClient
+}
+
+// FetchUnit fetches the terraform.source of the given unit, and returns the local directory of the module. Units
+// without a source use the terraform code of their own directory, which is returned as is.
+func (fetcher *SourceFetcher) FetchUnit(ctx context.Context, unit *Unit) (string, error) {
+	if unit.Config == nil {
+		return "", fmt.Errorf("%s: %w", unit.ConfigPath, unit.Err)
+	}
+	if unit.Config.Terraform == nil || unit.Config.Terraform.Source == nil {
+		return unit.Path, nil
+	}
+	return fetcher.Fetch(ctx, *unit.Config.Terraform.Source, unit.Path)
+}
+
+// Fetch fetches the given module source, and returns the local directory of the module, including its submodule. Local
+// sources are resolved relative to the given base directory and are not copied into the cache.
+func (fetcher *SourceFetcher) Fetch(ctx context.Context, source string, baseDir string) (string, error) {
+	parsed, err := ParseSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Kind == SourceLocal {
+		return submodulePath(resolvePath(baseDir, parsed.Path), parsed.Submodule)
+	}
+
+	submodule := parsed.Submodule
+	if parsed.Kind == SourceRegistry {
+		if parsed, submodule, err = fetcher.resolveRegistrySource(ctx, parsed); err != nil {
+			return "", err
+		}
+	}
+
+	dir, err := fetcher.fetchPackage(ctx, parsed)
+	if err != nil {
+		return "", fmt.Errorf("fetching %s: %w", source, err)
+	}
+	return submodulePath(dir, submodule)
+}
+
+// submodulePath returns the directory of the given submodule within the package in the given directory, refusing the
+// submodules outside of it (e.g. //../../x), as archivePath does for archive entries.
+func submodulePath(dir string, submodule string) (string, error) {
+	dir = filepath.Clean(dir)
+	target := filepath.Join(dir, filepath.FromSlash(submodule))
+	if target != dir && !strings.HasPrefix(target, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+		return "", fmt.Errorf("submodule %s is outside of the package", submodule)
+	}
+	return target, nil
+}
+
+// resolveRegistrySource pins the given registry source to its latest version when it has none, and returns the
+// source of its package along with the submodule to use within it.
+func (fetcher *SourceFetcher) resolveRegistrySource(ctx context.Context, source *Source) (*Source, string, error) {
+	registry := fetcher.Registry
+	if registry == nil {
+		registry = &RegistryClient{}
+	}
+
+	pinned := *source
+	pinned.Submodule = ""
+	if pinned.Version == "" {
+		latest, err := registry.LatestVersion(ctx, pinned.String())
+		if err != nil {
+			return nil, "", err
+		}
+		pinned.Version = latest
+	}
+
+	location, err := registry.DownloadSource(ctx, pinned.String())
+	if err != nil {
+		return nil, "", err
+	}
+	parsed, err := ParseSource(location)
+	if err != nil {
+		return nil, "", err
+	}
+	if parsed.Kind == SourceLocal || parsed.Kind == SourceRegistry {
+		return nil, "", fmt.Errorf("registry %s returned an unsupported download location for %s: %s", source.Host, pinned.String(), location)
+	}
+	return parsed, path.Join(parsed.Submodule, source.Submodule), nil
+}
+
+// fetchPackage downloads the package of the given source into the cache, unless it already is, and returns its
+// directory.
+func (fetcher *SourceFetcher) fetchPackage(ctx context.Context, source *Source) (string, error) {
+	cacheDir, err := fetcher.cacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	address := *source
+	address.Submodule = ""
+	sum := sha256.Sum256([]byte(address.String()))
+	dir := filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	packageGetter := fetcher.getter(source.Kind)
+
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", err
+	}
+	// Each attempt downloads into a new directory, as the getters expect an empty one.
+	var tmpDir string
+	defer func() {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+	err = fetcher.Retry.Do(ctx, func() error {
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+		var err error
+		if tmpDir, err = os.MkdirTemp(cacheDir, ".download-"); err != nil {
+			return err
+		}
+		return packageGetter.Get(ctx, tmpDir, &address)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The package is moved into place once complete, so that an interrupted download is never used. A concurrent
+	// download of the same package may have won the race, in which case its copy is used.
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func (fetcher *SourceFetcher) cacheDir() (string, error) {
+	if fetcher.CacheDir != "" {
+		return fetcher.CacheDir, nil
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userCacheDir, "terragrunt-utils", "modules"), nil
+}
+
+func (fetcher *SourceFetcher) getter(kind SourceKind) Getter {
+	if getter, ok := fetcher.Getters[kind]; ok {
+		return getter
+	}
+	return GoGetter{}
+}
+
+// GoGetter downloads packages with go-getter, as terraform and terragrunt do: git and mercurial repositories, with
+// their ref (or rev) and depth query parameters, http archives, and the sources of any other getter of Getters
+// selected by its forced getter prefix (e.g. s3::). The refs starting with a dash are rejected, so that git does not
+// parse them as options.
+type GoGetter struct {
+	// Getters are the go-getter getters the packages are downloaded with. Defaults to getter.Getters; add the getters
+	// of the go-getter s3/v2 and gcs/v2 modules to download s3 and gcs sources.
+	Getters []getter.Getter
+}
+
+func (goGetter GoGetter) Get(ctx context.Context, dst string, source *Source) error {
+	if strings.HasPrefix(source.Version, "-") {
+		return fmt.Errorf("invalid ref %q: it must not start with a dash", source.Version)
+	}
+	// go-getter updates the destination when it exists, so that it creates it instead.
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+
+	client := &getter.Client{Getters: goGetter.Getters, DisableSymlinks: true}
+	_, err := client.Get(ctx, &getter.Request{Src: source.String(), Dst: dst, GetMode: getter.ModeDir})
+	return err
+}
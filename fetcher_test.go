@@ -0,0 +1,103 @@
+package terragrunt
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoGetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var archive bytes.Buffer
+		writer := zip.NewWriter(&archive)
+		for name, content := range map[string]string{"main.tf": "output \"id\" {}\n", "modules/vpc/main.tf": ""} {
+			file, err := writer.Create(name)
+			if err != nil {
+				t.Error(err)
+			}
+			file.Write([]byte(content))
+		}
+		writer.Close()
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		source string
+		files  []string
+		err    string
+	}{
+		{
+			source: server.URL + "/modules.zip",
+			files:  []string{"main.tf", "modules/vpc/main.tf"},
+		},
+		{
+			source: server.URL + "/modules?archive=zip",
+			files:  []string{"main.tf", "modules/vpc/main.tf"},
+		},
+		{
+			source: "git::https://example.com/modules.git?ref=--upload-pack=touch /tmp/pwned",
+			err:    "must not start with a dash",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			source, err := ParseSource(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst := filepath.Join(t.TempDir(), "package")
+			if err := os.Mkdir(dst, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			err = GoGetter{}.Get(context.Background(), dst, source)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range test.files {
+				if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name))); err != nil {
+					t.Errorf("the package has no %s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceFetcherSubmoduleOutsidePackage(t *testing.T) {
+	dir := t.TempDir()
+	fetcher := &SourceFetcher{
+		CacheDir: filepath.Join(dir, "cache"),
+		Getters:  map[SourceKind]Getter{SourceGit: moduleGetter{}},
+		Retry:    &RetryPolicy{MaxAttempts: 1},
+	}
+
+	for _, source := range []string{"git::https://example.com/modules.git//../../escape", "./modules//../../escape"} {
+		t.Run(source, func(t *testing.T) {
+			if moduleDir, err := fetcher.Fetch(context.Background(), source, dir); err == nil || !strings.Contains(err.Error(), "outside of the package") {
+				t.Errorf("got directory %s and error %v, want the submodule refused", moduleDir, err)
+			}
+		})
+	}
+
+	moduleDir, err := fetcher.Fetch(context.Background(), "./modules//vpc/../network", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "modules", "network"); moduleDir != want {
+		t.Errorf("got directory %s, want %s", moduleDir, want)
+	}
+}
@@ -0,0 +1,244 @@
+package terragrunt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// readFile reads the file at the given path, from the configured FS or from the filesystem of the operating system.
+func (opts *ParseOptions) readFile(path string) ([]byte, error) {
+	opts.recordFileAccess(path)
+	if opts.MaxConfigSize > 0 {
+		if err := opts.checkFileSize(path); err != nil {
+			return nil, err
+		}
+	}
+	if opts.fileCache != nil && opts.cacheFileContents {
+		return opts.fileCache.readFile(path, opts)
+	}
+	return opts.readFileContent(path)
+}
+
+// readFileContent reads the file at the given path, from the configured FS or from the filesystem of the operating
+// system.
+func (opts *ParseOptions) readFileContent(path string) ([]byte, error) {
+	if opts.FS == nil {
+		return os.ReadFile(path)
+	}
+	return fs.ReadFile(opts.FS, fsPath(path))
+}
+
+// parseHCL is like the parseHCL function, through the file cache when set.
+func (opts *ParseOptions) parseHCL(content []byte, filename string) (*hcl.File, error) {
+	if opts.fileCache != nil {
+		return opts.fileCache.parseHCL(content, filename)
+	}
+	return parseHCL(content, filename)
+}
+
+// checkFileSize returns a *ConfigTooLargeError when the file at the given path is larger than MaxConfigSize, before it
+// is read. Errors retrieving its size are left to the read.
+func (opts *ParseOptions) checkFileSize(path string) error {
+	var info fs.FileInfo
+	var err error
+	if opts.FS == nil {
+		info, err = os.Stat(path)
+	} else {
+		info, err = fs.Stat(opts.FS, fsPath(path))
+	}
+	if err != nil || !info.Mode().IsRegular() || info.Size() <= opts.MaxConfigSize {
+		return nil
+	}
+	return &ConfigTooLargeError{Path: path, Size: info.Size(), Limit: opts.MaxConfigSize}
+}
+
+// stat returns the information of the file at the given path, from the configured FS or from the filesystem of the
+// operating system.
+func (opts *ParseOptions) stat(path string) (fs.FileInfo, error) {
+	opts.recordFileAccess(path)
+	if opts.FS == nil {
+		return os.Stat(path)
+	}
+	return fs.Stat(opts.FS, fsPath(path))
+}
+
+// recordFileAccess records that the file at the given path was accessed, so that the configuration can be parsed again
+// when it changes.
+func (opts *ParseOptions) recordFileAccess(path string) {
+	if opts.accessedFiles == nil {
+		return
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	opts.accessedFiles[path] = true
+}
+
+// fsPath converts a path of the operating system to a path of an fs.FS, in which absolute paths are rooted at the
+// root of the FS: /live/app/terragrunt.hcl is read as live/app/terragrunt.hcl, and so is C:\live\app\terragrunt.hcl on
+// Windows, without its volume name.
+func fsPath(path string) string {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	path = filepath.Clean(path)
+	path = strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(path, filepath.VolumeName(path))), "/")
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// absolutePath resolves the given path against the directory of the configuration being parsed.
+func (opts *ParseOptions) absolutePath(path string) string {
+	return resolvePath(opts.workingDir(), path)
+}
+
+// resolvePath returns the given path, as written in a configuration, resolved against the given directory when it is
+// relative. Forward slashes are separators on every platform, and on Windows, paths rooted without a volume name (e.g.
+// /live/vpc) are on the volume of the directory.
+func resolvePath(dir string, path string) string {
+	path = filepath.FromSlash(path)
+	switch {
+	case filepath.IsAbs(path):
+		return filepath.Clean(path)
+	case isRootedPath(path):
+		return filepath.Join(filepath.VolumeName(dir), path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// isAbsPath returns whether the given path, as written in a configuration, is absolute, including the paths rooted
+// without a volume name on Windows.
+func isAbsPath(path string) bool {
+	path = filepath.FromSlash(path)
+	return filepath.IsAbs(path) || isRootedPath(path)
+}
+
+// isRootedPath returns whether the given path starts with a separator.
+func isRootedPath(path string) bool {
+	return path != "" && os.IsPathSeparator(path[0])
+}
+
+// fileFunc returns the file(path) function, which returns the content of the given file. The content must be valid
+// UTF-8.
+func fileFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			content, err := readUTF8File(args[0].AsString(), opts)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(content), nil
+		},
+	})
+}
+
+// fileExistsFunc returns the fileexists(path) function, which returns whether the given file exists. It is an error
+// for the path to be a directory.
+func fileExistsFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path := opts.absolutePath(args[0].AsString())
+			info, err := opts.stat(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return cty.False, nil
+				}
+				return cty.NilVal, err
+			}
+			if info.IsDir() {
+				return cty.NilVal, fmt.Errorf("%s is a directory, not a file", path)
+			}
+			return cty.True, nil
+		},
+	})
+}
+
+// templateFileFunc returns the templatefile(path, vars) function, which renders the given template file with the
+// given variables. Templates can call the functions returned by functions, except templatefile itself.
+func templateFileFunc(opts *ParseOptions, functions func() map[string]function.Function) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path := args[0].AsString()
+			vars := args[1]
+			if !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+				return cty.NilVal, function.NewArgErrorf(1, "invalid vars value: must be a map")
+			}
+			if !vars.IsWhollyKnown() {
+				return cty.DynamicVal, nil
+			}
+
+			content, err := readUTF8File(path, opts)
+			if err != nil {
+				return cty.NilVal, err
+			}
+
+			template, diags := hclsyntax.ParseTemplate([]byte(content), opts.absolutePath(path), hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+
+			evalContext := &hcl.EvalContext{
+				Variables: map[string]cty.Value{},
+				Functions: map[string]function.Function{},
+			}
+			if !vars.IsNull() {
+				for name, value := range vars.AsValueMap() {
+					evalContext.Variables[name] = value
+				}
+			}
+			for name, fn := range functions() {
+				if name != "templatefile" {
+					evalContext.Functions[name] = fn
+				}
+			}
+
+			for _, traversal := range template.Variables() {
+				if _, found := evalContext.Variables[traversal.RootName()]; !found {
+					return cty.NilVal, fmt.Errorf("%s: vars map does not contain key %q", traversal.SourceRange(), traversal.RootName())
+				}
+			}
+
+			value, diags := template.Value(evalContext)
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+			return value, nil
+		},
+	})
+}
+
+// readUTF8File reads the given file, resolved against the directory of the configuration, as a UTF-8 string.
+func readUTF8File(path string, opts *ParseOptions) (string, error) {
+	content, err := opts.readFile(opts.absolutePath(path))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(content) {
+		return "", fmt.Errorf("contents of %s are not valid UTF-8", path)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,88 @@
+package terragrunt
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// DefaultParentFilename is the file searched for by find_in_parent_folders() when no name is given.
+const DefaultParentFilename = DefaultConfigFilename
+
+// maxParentFoldersToCheck bounds how many folders find_in_parent_folders() goes through when no boundary is set.
+const maxParentFoldersToCheck = 100
+
+// findInParentFoldersFunc returns the find_in_parent_folders([name, [fallback]]) function, which returns the absolute
+// path of the first file with the given name in the parent folders of the configuration. When the file is not found,
+// the fallback is returned if given, otherwise it is an error.
+//
+// The search stops at the configured boundaries (see WithSearchRoot and WithSearchStopAtRepoRoot), which are
+// themselves searched.
+func findInParentFoldersFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Name: "args", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if len(args) > 2 {
+				return cty.NilVal, fmt.Errorf("find_in_parent_folders expects at most 2 arguments, got %d", len(args))
+			}
+
+			name := DefaultParentFilename
+			if len(args) > 0 {
+				name = args[0].AsString()
+			}
+
+			path, found, err := findInParentFolders(name, opts)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			if found {
+				return cty.StringVal(filepath.ToSlash(path)), nil
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return cty.NilVal, fmt.Errorf("could not find a %s file in any of the parent folders of %s", name, opts.originalDir())
+		},
+	})
+}
+
+// findInParentFolders searches the parent folders of the configuration for the file with the given name.
+func findInParentFolders(name string, opts *ParseOptions) (string, bool, error) {
+	var boundaries []string
+	if opts.SearchRoot != "" {
+		searchRoot, err := filepath.Abs(opts.SearchRoot)
+		if err != nil {
+			return "", false, err
+		}
+		boundaries = append(boundaries, searchRoot)
+	}
+	if opts.SearchStopAtRepoRoot {
+		repoRoot, err := findRepoRoot(opts.originalDir(), opts)
+		if err != nil {
+			return "", false, err
+		}
+		boundaries = append(boundaries, repoRoot)
+	}
+
+	currentDir := opts.originalDir()
+	for i := 0; i < maxParentFoldersToCheck; i++ {
+		if containsString(boundaries, currentDir) {
+			return "", false, nil
+		}
+
+		parentDir := filepath.Dir(currentDir)
+		if parentDir == currentDir {
+			return "", false, nil
+		}
+		currentDir = parentDir
+
+		path := filepath.Join(currentDir, name)
+		if info, err := opts.stat(path); err == nil && !info.IsDir() {
+			return path, true, nil
+		}
+	}
+	return "", false, nil
+}
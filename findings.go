@@ -0,0 +1,256 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+const (
+	RuleInvalidConfig   = "invalid-config"
+	RuleDependencyCycle = "dependency-cycle"
+)
+
+// FindingsFromError converts an error parsing the configuration at the given path into findings, so that it can be
+// reported along with the other findings: HCL diagnostics are reported individually at their range, and other errors
+// at the configuration file.
+func FindingsFromError(err error, configPath string) []Finding {
+	var diags hcl.Diagnostics
+	if !errors.As(err, &diags) {
+		return []Finding{{
+			RuleID:   RuleInvalidConfig,
+			Severity: SeverityError,
+			Message:  err.Error(),
+			Range:    hcl.Range{Filename: configPath},
+		}}
+	}
+
+	var findings []Finding
+	for _, diag := range diags {
+		finding := Finding{
+			RuleID:   RuleInvalidConfig,
+			Severity: SeverityError,
+			Message:  diag.Summary,
+			Range:    hcl.Range{Filename: configPath},
+		}
+		if diag.Severity == hcl.DiagWarning {
+			finding.Severity = SeverityWarning
+		}
+		if diag.Detail != "" {
+			finding.Message += "; " + diag.Detail
+		}
+		if diag.Subject != nil {
+			finding.Range = *diag.Subject
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// FindingsJSONVersion is the version of the document written by WriteFindingsJSON. It only changes when the document
+// changes in a way that is not backward compatible.
+const FindingsJSONVersion = 1
+
+type jsonFindings struct {
+	Version  int           `json:"version"`
+	Findings []jsonFinding `json:"findings"`
+}
+
+type jsonFinding struct {
+	RuleID   string     `json:"rule_id"`
+	Severity Severity   `json:"severity"`
+	Message  string     `json:"message"`
+	File     string     `json:"file,omitempty"`
+	Range    *jsonRange `json:"range,omitempty"`
+}
+
+type jsonRange struct {
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Byte   int `json:"byte"`
+}
+
+// WriteFindingsJSON writes the given findings as a json document, with the rule id, severity, message, file and
+// range of each finding:
+//
+//	{"version": 1, "findings": [{"rule_id": "unused-local", "severity": "warning", "message": "...",
+//	  "file": "live/app/terragrunt.hcl", "range": {"start": {"line": 3, "column": 3, "byte": 20}, "end": {...}}}]}
+//
+// The files of the findings are reported relative to the given base directory when they are inside it. Findings
+// without a file, or without a position in it, have no file or range.
+func WriteFindingsJSON(w io.Writer, findings []Finding, baseDir string) error {
+	document := jsonFindings{Version: FindingsJSONVersion, Findings: []jsonFinding{}}
+	for _, finding := range findings {
+		rendered := jsonFinding{
+			RuleID:   finding.RuleID,
+			Severity: finding.Severity,
+			Message:  finding.Message,
+		}
+		if finding.Range.Filename != "" {
+			rendered.File = filepath.ToSlash(relativeFilename(finding.Range.Filename, baseDir))
+		}
+		if finding.Range.Start.Line > 0 {
+			rendered.Range = &jsonRange{
+				Start: jsonPos{Line: finding.Range.Start.Line, Column: finding.Range.Start.Column, Byte: finding.Range.Start.Byte},
+				End:   jsonPos{Line: finding.Range.End.Line, Column: finding.Range.End.Column, Byte: finding.Range.End.Byte},
+			}
+		}
+		document.Findings = append(document.Findings, rendered)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// SARIFToolName is the name of the tool reported in SARIF logs.
+const SARIFToolName = "terragrunt-utils"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// WriteFindingsSARIF writes the given findings as a SARIF 2.1.0 log, as ingested by GitHub code scanning. The files
+// of the findings are reported relative to the given base directory, which is usually the root of the repository.
+func WriteFindingsSARIF(w io.Writer, findings []Finding, baseDir string) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: SARIFToolName, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	ruleIDs := map[string]bool{}
+	for _, finding := range findings {
+		ruleIDs[finding.RuleID] = true
+
+		result := sarifResult{
+			RuleID:  finding.RuleID,
+			Level:   "warning",
+			Message: sarifMessage{Text: finding.Message},
+		}
+		if finding.Severity == SeverityError {
+			result.Level = "error"
+		}
+		if finding.Range.Filename != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(finding.Range.Filename, baseDir)},
+			}}
+			if finding.Range.Start.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   finding.Range.Start.Line,
+					StartColumn: finding.Range.Start.Column,
+					EndLine:     finding.Range.End.Line,
+					EndColumn:   finding.Range.End.Column,
+				}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	for ruleID := range ruleIDs {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: ruleID})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI returns the uri of the file at the given path: relative to the base directory when it is inside it, and a
+// file uri otherwise.
+func sarifURI(path string, baseDir string) string {
+	path = relativeFilename(path, baseDir)
+	if filepath.IsAbs(path) {
+		return "file://" + filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(path)
+}
+
+// relativeFilename returns the path relative to the base directory when it is inside it, and the path unchanged
+// otherwise.
+func relativeFilename(path string, baseDir string) string {
+	if baseDir == "" {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return path
+	}
+	relPath, err := filepath.Rel(absBase, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return relPath
+}
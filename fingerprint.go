@@ -0,0 +1,151 @@
+package terragrunt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Fingerprint returns a stable hash of everything the unit is built from: its configuration, the configurations it
+// includes and the other files read while parsing it (see Files), and the files of its terraform module when its
+// source is local, following the local sources of the module blocks of the module. The fingerprint changes whenever
+// one of these files changes, appears or disappears, so that CI systems can skip the units whose fingerprint did not
+// change since their last plan.
+//
+// The paths are hashed relative to the unit, so that the fingerprint does not depend on where the repository is
+// checked out. Remote module sources are covered through the configuration setting them, but their content is not
+// fetched, and neither are environment variables covered.
+func (unit *Unit) Fingerprint() (string, error) {
+	hasher := sha256.New()
+
+	for _, path := range unit.Files {
+		if err := hashFile(hasher, "file", unit.Path, path); err != nil {
+			return "", err
+		}
+	}
+
+	if unit.Config != nil {
+		moduleDirs := map[string]bool{}
+		moduleDir := unit.Path
+		if unit.Config.Terraform != nil && unit.Config.Terraform.Source != nil {
+			source := *unit.Config.Terraform.Source
+			moduleDir = ""
+			if isLocalSource(source) {
+				var err error
+				if moduleDir, err = (&SourceFetcher{}).Fetch(context.Background(), source, unit.Path); err != nil {
+					return "", err
+				}
+			}
+		}
+		if moduleDir != "" {
+			collectLocalModuleDirs(moduleDir, moduleDirs)
+		}
+
+		dirs := make([]string, 0, len(moduleDirs))
+		for dir := range moduleDirs {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			if err := hashModuleDir(hasher, unit.Path, dir); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// collectLocalModuleDirs adds the given module directory to the set, along with the directories of the modules it
+// calls with a local source, recursively. Modules that can not be parsed are not followed.
+func collectLocalModuleDirs(dir string, dirs map[string]bool) {
+	dir = filepath.Clean(dir)
+	if dirs[dir] {
+		return
+	}
+	dirs[dir] = true
+
+	module, err := ParseModule(dir)
+	if err != nil {
+		return
+	}
+	for _, call := range module.ModuleCalls {
+		if !isLocalSource(call.Source) {
+			continue
+		}
+		collectLocalModuleDirs(resolvePath(dir, call.Source), dirs)
+	}
+}
+
+// hashModuleDir hashes the files of the given module directory, skipping hidden directories (e.g. .terraform) and the
+// directories of other units.
+func hashModuleDir(hasher hash.Hash, unitDir string, dir string) error {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if isSkippedDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, DefaultConfigFilename)); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// The configuration files of the unit itself are hashed with the files read while parsing it.
+		if entry.Type().IsRegular() && entry.Name() != DefaultConfigFilename {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := hashFile(hasher, "module", unitDir, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashFile hashes the path of the given file relative to the unit directory along with its content, or with a marker
+// when it does not exist.
+func hashFile(hasher hash.Hash, kind string, unitDir string, path string) error {
+	relPath, err := relativeSlashPath(unitDir, path)
+	if err != nil {
+		relPath = filepath.ToSlash(path)
+	}
+	fmt.Fprintf(hasher, "%s\x00%s\x00", kind, relPath)
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprint(hasher, "missing\x00")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	contentHasher := sha256.New()
+	if _, err := io.Copy(contentHasher, file); err != nil {
+		return err
+	}
+	fmt.Fprintf(hasher, "%x\x00", contentHasher.Sum(nil))
+	return nil
+}
@@ -0,0 +1,91 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUnitFingerprint(t *testing.T) {
+	files := map[string]string{
+		"root.hcl": `
+remote_state {
+  backend = "s3"
+  config  = { bucket = "state" }
+}
+`,
+		"live/app/terragrunt.hcl": `
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+
+terraform {
+  source = "../../modules/app"
+}
+
+inputs = jsondecode(file("values.json"))
+`,
+		"live/app/values.json":    `{"replicas": 2}`,
+		"live/db/terragrunt.hcl":  `inputs = { engine = "postgres" }`,
+		"modules/app/main.tf":     "module \"network\" {\n  source = \"../network\"\n}\n",
+		"modules/network/main.tf": "variable \"cidr\" {}\n",
+	}
+	fingerprint := func(t *testing.T, files map[string]string) string {
+		t.Helper()
+		dir := writeFiles(t, files)
+		stack, err := ParseStack(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, unit := range stack.Units {
+			if unit.Path != filepath.Join(dir, "live", "app") {
+				continue
+			}
+			if unit.Err != nil {
+				t.Fatal(unit.Err)
+			}
+			fingerprint, err := unit.Fingerprint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return fingerprint
+		}
+		t.Fatal("unit live/app not found")
+		return ""
+	}
+
+	// The fingerprint does not depend on the directory the files are in.
+	base := fingerprint(t, files)
+	if again := fingerprint(t, files); again != base {
+		t.Fatalf("got fingerprints %s and %s for the same files, want them equal", base, again)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		changed bool
+	}{
+		{name: "configuration", path: "live/app/terragrunt.hcl", content: files["live/app/terragrunt.hcl"] + "\n# comment\n", changed: true},
+		{name: "included configuration", path: "root.hcl", content: files["root.hcl"] + "\n# comment\n", changed: true},
+		{name: "read file", path: "live/app/values.json", content: `{"replicas": 3}`, changed: true},
+		{name: "module", path: "modules/app/variables.tf", content: "variable \"name\" {}\n", changed: true},
+		{name: "module called by the module", path: "modules/network/main.tf", content: "variable \"cidr\" {\n  default = \"10.0.0.0/16\"\n}\n", changed: true},
+		{name: "other unit", path: "live/db/terragrunt.hcl", content: `inputs = { engine = "mysql" }`},
+		{name: "other module", path: "modules/other/main.tf", content: "variable \"name\" {}\n"},
+		{name: "hidden directory of the module", path: "modules/app/.terraform/modules.json", content: "{}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changedFiles := map[string]string{}
+			for path, content := range files {
+				changedFiles[path] = content
+			}
+			changedFiles[test.path] = test.content
+
+			if changed := fingerprint(t, changedFiles) != base; changed != test.changed {
+				t.Errorf("got fingerprint changed %v, want %v", changed, test.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package terragrunt
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// FormatOption configures Format.
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	filename       string
+	canonicalOrder bool
+}
+
+// WithFormatFilename sets the filename reported in the syntax errors of the content to format.
+func WithFormatFilename(name string) FormatOption {
+	return func(opts *formatOptions) {
+		opts.filename = name
+	}
+}
+
+// WithCanonicalOrder makes Format reorder the top level blocks and attributes of the configuration in the
+// conventional terragrunt order: include, locals, terraform, dependencies, dependency, generate, remote_state, the
+// other attributes, and inputs last. Items of the same kind keep their relative order, and the comments above an item
+// move with it.
+func WithCanonicalOrder() FormatOption {
+	return func(opts *formatOptions) {
+		opts.canonicalOrder = true
+	}
+}
+
+// canonicalOrder ranks the top level blocks and attributes for WithCanonicalOrder. Items not listed rank before
+// inputs.
+var canonicalOrder = map[string]int{
+	"include":      0,
+	"locals":       1,
+	"terraform":    2,
+	"dependencies": 3,
+	"dependency":   4,
+	"generate":     5,
+	"remote_state": 6,
+	"inputs":       8,
+}
+
+const canonicalOrderOther = 7
+
+// Format returns the canonically formatted form of the given terragrunt configuration content, as terragrunt hclfmt
+// does. The content must be syntactically valid.
+func Format(content []byte, opts ...FormatOption) ([]byte, error) {
+	options := &formatOptions{filename: filename}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(content, options.filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if options.canonicalOrder {
+		content = reorderTopLevelItems(content, file.Body.(*hclsyntax.Body))
+	}
+	return hclwrite.Format(content), nil
+}
+
+// formatItem is a top level block or attribute of a configuration, with the comments above it.
+type formatItem struct {
+	rank       int
+	start, end int
+}
+
+// reorderTopLevelItems returns the content with its top level items in the canonical order. The comments preceding
+// the first item are kept at the top when they are separated from it by a blank line, as a file header.
+func reorderTopLevelItems(content []byte, body *hclsyntax.Body) []byte {
+	var items []formatItem
+	for name, attribute := range body.Attributes {
+		items = append(items, formatItem{rank: canonicalRank(name), start: attribute.SrcRange.Start.Byte, end: attribute.SrcRange.End.Byte})
+	}
+	for _, block := range body.Blocks {
+		items = append(items, formatItem{rank: canonicalRank(block.Type), start: block.Range().Start.Byte, end: block.Range().End.Byte})
+	}
+	if len(items) < 2 {
+		return content
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].start < items[j].start
+	})
+
+	// Each item extends to the end of its last line, to keep trailing comments, and back to the end of the previous
+	// item, so that the comments between them move with it.
+	for i := range items {
+		items[i].end = lineEnd(content, items[i].end)
+		if i > 0 {
+			items[i].start = items[i-1].end
+		} else {
+			items[i].start = leadingCommentsStart(content, items[i].start)
+		}
+	}
+	header := bytes.TrimRight(content[:items[0].start], " \t\n")
+	trailer := bytes.TrimSpace(content[items[len(items)-1].end:])
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].rank < items[j].rank
+	})
+
+	var buffer bytes.Buffer
+	if len(header) > 0 {
+		buffer.Write(header)
+		buffer.WriteString("\n\n")
+	}
+	for i, item := range items {
+		if i > 0 {
+			buffer.WriteString("\n")
+			if !isSingleLine(content[item.start:item.end]) || !isSingleLine(content[items[i-1].start:items[i-1].end]) || item.rank != items[i-1].rank {
+				buffer.WriteString("\n")
+			}
+		}
+		buffer.Write(bytes.Trim(content[item.start:item.end], "\n"))
+	}
+	buffer.WriteString("\n")
+	if len(trailer) > 0 {
+		buffer.WriteString("\n")
+		buffer.Write(trailer)
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes()
+}
+
+func canonicalRank(name string) int {
+	if rank, found := canonicalOrder[name]; found {
+		return rank
+	}
+	return canonicalOrderOther
+}
+
+// lineEnd returns the offset of the end of the line containing the given offset, including a trailing comment.
+func lineEnd(content []byte, offset int) int {
+	if end := bytes.IndexByte(content[offset:], '\n'); end >= 0 {
+		return offset + end
+	}
+	return len(content)
+}
+
+// leadingCommentsStart returns the offset of the first of the comment lines right above the line containing the given
+// offset, or the start of that line when there are none.
+func leadingCommentsStart(content []byte, offset int) int {
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	for start > 0 {
+		previousStart := bytes.LastIndexByte(content[:start-1], '\n') + 1
+		line := bytes.TrimSpace(content[previousStart : start-1])
+		if !bytes.HasPrefix(line, []byte("#")) && !bytes.HasPrefix(line, []byte("//")) {
+			break
+		}
+		start = previousStart
+	}
+	return start
+}
+
+func isSingleLine(content []byte) bool {
+	return !bytes.Contains(bytes.TrimSpace(content), []byte("\n"))
+}
+
+// IsFormatted returns whether the given terragrunt configuration content is already formatted, as Format would.
+func IsFormatted(content []byte, opts ...FormatOption) (bool, error) {
+	formatted, err := Format(content, opts...)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(formatted, content), nil
+}
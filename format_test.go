@@ -0,0 +1,75 @@
+package terragrunt
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the tests")
+
+// TestFormatGolden formats the configurations of testdata/format, and compares them with their .golden file, and
+// with their .ordered.golden file when reordered.
+func TestFormatGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "format", "*.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no configuration in testdata/format")
+	}
+
+	for _, input := range inputs {
+		content, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range []struct {
+			golden string
+			opts   []FormatOption
+		}{
+			{golden: strings.TrimSuffix(input, ".hcl") + ".golden"},
+			{golden: strings.TrimSuffix(input, ".hcl") + ".ordered.golden", opts: []FormatOption{WithCanonicalOrder()}},
+		} {
+			t.Run(filepath.Base(test.golden), func(t *testing.T) {
+				formatted, err := Format(content, append(test.opts, WithFormatFilename(input))...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *updateGolden {
+					if err := os.WriteFile(test.golden, formatted, 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(test.golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(formatted) != string(want) {
+					t.Errorf("got:\n%s\nwant:\n%s", formatted, want)
+				}
+
+				// Formatting is idempotent.
+				formatted, err = Format(want, test.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(formatted) != string(want) {
+					t.Errorf("formatting %s again got:\n%s", test.golden, formatted)
+				}
+				if formatted, err := IsFormatted(want, test.opts...); err != nil || !formatted {
+					t.Errorf("got IsFormatted %v, %v, want true", formatted, err)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	_, err := Format([]byte("inputs = {\n"), WithFormatFilename("live/terragrunt.hcl"))
+	if err == nil || !strings.Contains(err.Error(), "live/terragrunt.hcl:2,1-1: Missing expression") {
+		t.Fatalf("got error %v, want a syntax error in live/terragrunt.hcl", err)
+	}
+}
@@ -0,0 +1,53 @@
+package terragrunt
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fuzzSeeds are the configurations the fuzz targets start from.
+var fuzzSeeds = []string{
+	``,
+	`inputs = { name = "vpc", cidrs = ["10.0.0.0/16"], nested = { count = 3 } }`,
+	"locals {\n  env = \"prod\"\n  name = \"${local.env}-vpc\"\n}\ninputs = { name = local.name }\n",
+	"terraform {\n  source = \"git::https://example.com/modules.git//vpc?ref=v1.0.0\"\n}\n",
+	"dependency \"vpc\" {\n  config_path = \"../vpc\"\n  mock_outputs = { vpc_id = \"vpc-123\" }\n}\ninputs = { vpc_id = dependency.vpc.outputs.vpc_id }\n",
+	"include \"root\" {\n  path = find_in_parent_folders(\"root.hcl\")\n}\n",
+	"remote_state {\n  backend = \"s3\"\n  config = { bucket = \"state\", key = \"${path_relative_to_include()}/tfstate\" }\n}\n",
+	"inputs = {\n  script = <<-EOT\n    echo hello\n  EOT\n}\n",
+	"inputs = { a = [for i in range(3) : i * 2], b = { for k, v in { x = 1 } : k => v } }",
+}
+
+// FuzzParseConfigSafe checks that no configuration makes ParseConfigSafe panic. The configurations are parsed without
+// access to the filesystem or to functions with side effects.
+func FuzzParseConfigSafe(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		_, err := ParseConfigSafe(content,
+			WithConfigPath("/live/unit/terragrunt.hcl"),
+			WithFS(fstest.MapFS{}),
+			WithHermeticMode(HermeticError),
+			WithParseTimeout(5*time.Second),
+		)
+		var panicErr *ParsePanicError
+		if errors.As(err, &panicErr) {
+			t.Fatalf("%s\n%s", panicErr, panicErr.Stack)
+		}
+	})
+}
+
+// FuzzHCLToJSON checks that no configuration makes the HCL to json conversion panic.
+func FuzzHCLToJSON(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		_, _ = HCLToJSON(content)
+	})
+}
@@ -0,0 +1,240 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// GCSStateOutputResolver is an OutputResolver reading the outputs of dependencies from their state in GCS, as
+// configured by their gcs remote_state block, instead of running terragrunt output. It reads the state of the
+// workspace of each dependency, <prefix>/<workspace>.tfstate, decrypting the states encrypted by OpenTofu.
+type GCSStateOutputResolver struct {
+	// TokenSource returns the OAuth2 access tokens requests are authorized with, unless the gcs config of the
+	// dependency has an access_token. Defaults to the GOOGLE_OAUTH_ACCESS_TOKEN environment variable, or else the
+	// token of the default service account from the metadata server.
+	TokenSource GCPTokenSource
+
+	// Encryption holds the keys to decrypt the states encrypted by OpenTofu with. Its passphrase and KMS client take
+	// precedence over the ones configured by the encryption attribute of the remote_state block of the dependency.
+	Encryption *StateEncryption
+
+	// Workspace selects the workspace of the dependencies. Defaults to the workspace selected by their configuration.
+	Workspace WorkspaceFunc
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// Options are the options the configurations of the dependencies are parsed with, to read their remote_state
+	// block.
+	Options []Option
+}
+
+// GCPTokenSource returns OAuth2 access tokens for the Google Cloud APIs.
+type GCPTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// gcsStateLocation is the location of the state of a unit in GCS.
+type gcsStateLocation struct {
+	Bucket      string
+	Object      string
+	AccessToken string
+}
+
+func (resolver *GCSStateOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	configPath = filepath.Join(configDir(configPath), DefaultConfigFilename)
+	opts := append(append([]Option(nil), resolver.Options...), WithContext(ctx))
+	config, err := ParseConfigFile(configPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := newGCSStateLocation(config, unitWorkspace(resolver.Workspace, configPath, config))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	var state []byte
+	err = resolver.Retry.Do(ctx, func() error {
+		var err error
+		state, err = resolver.getObject(ctx, location)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		// The dependency is not applied yet, so that its mock outputs apply.
+		return []byte("{}"), nil
+	}
+
+	encryption := newStateEncryption(config.RemoteState, resolver.Encryption, nil)
+	if state, err = encryption.Decrypt(ctx, state); err != nil {
+		return nil, fmt.Errorf("reading state gs://%s/%s: %w", location.Bucket, location.Object, err)
+	}
+	return stateOutputs(state)
+}
+
+// ResolveOutputKeys returns the given outputs of the state of the dependency at the given config path. The state is
+// still read whole, but only the given outputs are returned, to be cached and decoded.
+func (resolver *GCSStateOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	outputs, err := resolver.ResolveOutputs(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return filterOutputKeys(outputs, keys)
+}
+
+// getObject reads the object at the given location with the JSON API of GCS. It returns nil when the object does not
+// exist.
+func (resolver *GCSStateOutputResolver) getObject(ctx context.Context, location gcsStateLocation) ([]byte, error) {
+	objectURL := "https://storage.googleapis.com/storage/v1/b/" + url.PathEscape(location.Bucket) + "/o/" + url.PathEscape(location.Object) + "?alt=media"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := location.AccessToken
+	if token == "" {
+		tokenSource := resolver.TokenSource
+		if tokenSource == nil {
+			tokenSource = defaultGCPTokenSource{httpClient: resolver.HTTPClient}
+		}
+		if token, err = tokenSource.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	httpClient := resolver.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading state gs://%s/%s: %w", location.Bucket, location.Object, newHTTPStatusError(request, response))
+	}
+	return io.ReadAll(response.Body)
+}
+
+// newGCSStateLocation returns the location of the state of the given workspace of the unit with the given
+// configuration, from its gcs remote_state block.
+func newGCSStateLocation(config *TerragruntConfig, workspace string) (gcsStateLocation, error) {
+	if config.RemoteState == nil {
+		return gcsStateLocation{}, fmt.Errorf("no remote_state block")
+	}
+	if config.RemoteState.Backend != "gcs" {
+		return gcsStateLocation{}, fmt.Errorf("reading the state of the %s backend is not supported, only gcs", config.RemoteState.Backend)
+	}
+	backend, err := backendConfig(config.RemoteState)
+	if err != nil {
+		return gcsStateLocation{}, err
+	}
+
+	location := gcsStateLocation{
+		Bucket:      backendString(backend, "bucket"),
+		Object:      path.Join(backendString(backend, "prefix"), workspace+".tfstate"),
+		AccessToken: backendString(backend, "access_token"),
+	}
+	if location.Bucket == "" {
+		return gcsStateLocation{}, fmt.Errorf("the bucket of the gcs remote_state config must be set")
+	}
+	return location, nil
+}
+
+// gcpMetadataTokenURL is the endpoint of the metadata server returning the tokens of the default service account.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// defaultGCPTokenSource returns the GOOGLE_OAUTH_ACCESS_TOKEN environment variable, when set, or else the token of the
+// default service account from the metadata server.
+type defaultGCPTokenSource struct {
+	httpClient *http.Client
+}
+
+func (source defaultGCPTokenSource) Token(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	httpClient := source.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("no GCP access token: GOOGLE_OAUTH_ACCESS_TOKEN is not set, and the metadata server is unreachable: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcp metadata token: %w", newHTTPStatusError(request, response))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid gcp metadata token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// StateOutputResolver is an OutputResolver reading the outputs of dependencies from their state, with S3 or GCS
+// depending on the backend of their remote_state block. The backend is determined statically, as by
+// RateLimitedOutputResolver, and defaults to s3 when it is not known statically.
+type StateOutputResolver struct {
+	S3  *S3StateOutputResolver
+	GCS *GCSStateOutputResolver
+}
+
+func (resolver StateOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	backendResolver, err := resolver.backendResolver(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return backendResolver.ResolveOutputs(ctx, configPath)
+}
+
+func (resolver StateOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	backendResolver, err := resolver.backendResolver(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return resolveOutputKeys(ctx, backendResolver, configPath, keys)
+}
+
+// backendResolver returns the resolver of the backend of the dependency at the given config path.
+func (resolver StateOutputResolver) backendResolver(configPath string) (OutputResolver, error) {
+	backend := remoteStateBackend(configPath)
+	if backend == "" {
+		backend = "s3"
+	}
+	switch {
+	case backend == "gcs" && resolver.GCS != nil:
+		return resolver.GCS, nil
+	case backend != "gcs" && resolver.S3 != nil:
+		return resolver.S3, nil
+	}
+	return nil, fmt.Errorf("%s: no state resolver for the %s backend", configPath, backend)
+}
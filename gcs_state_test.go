@@ -0,0 +1,113 @@
+package terragrunt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// redirectTransport sends every request to the server at the given url, whatever their host.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (transport redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host = transport.target.Scheme, transport.target.Host
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestGCSStateOutputResolver(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   cty.Value
+		err    string
+	}{
+		{
+			name:   "applied",
+			status: http.StatusOK,
+			body:   `{"version": 4, "outputs": {"id": {"value": "vpc-1", "type": "string"}}}`,
+			want:   cty.StringVal("vpc-1"),
+		},
+		{
+			name:   "not applied",
+			status: http.StatusNotFound,
+			body:   `{"error": {"code": 404, "message": "No such object"}}`,
+			want:   cty.StringVal("mock-vpc"),
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			body:   `{"error": {"code": 403, "message": "Forbidden"}}`,
+			err:    "reading state gs://state/vpc/default.tfstate",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requestURI, authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestURI, authorization = r.RequestURI, r.Header.Get("Authorization")
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+			target, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := writeFiles(t, map[string]string{
+				"vpc/terragrunt.hcl": `
+remote_state {
+  backend = "gcs"
+  config = {
+    bucket       = "state"
+    prefix       = "vpc"
+    access_token = "token"
+  }
+}
+`,
+				"app/terragrunt.hcl": `
+dependency "vpc" {
+  config_path  = "../vpc"
+  mock_outputs = { id = "mock-vpc" }
+}
+
+inputs = { vpc_id = dependency.vpc.outputs.id }
+`,
+			})
+			resolver := &GCSStateOutputResolver{
+				HTTPClient: &http.Client{Transport: redirectTransport{target: target}},
+				Retry:      &RetryPolicy{MaxAttempts: 1},
+			}
+
+			config, err := ParseConfigFile(filepath.Join(dir, "app", DefaultConfigFilename), WithOutputResolver(resolver))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vpcID := config.InputsCty["vpc_id"]; !vpcID.RawEquals(test.want) {
+				t.Errorf("got vpc_id %#v, want %#v", vpcID, test.want)
+			}
+			if want := "/storage/v1/b/state/o/vpc%2Fdefault.tfstate?alt=media"; requestURI != want {
+				t.Errorf("got request URI %q, want %q", requestURI, want)
+			}
+			if authorization != "Bearer token" {
+				t.Errorf("got authorization %q, want the access token of the config", authorization)
+			}
+		})
+	}
+}
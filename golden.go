@@ -0,0 +1,75 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RenderUnitJSON renders the resolved configuration of the given unit as canonical json, for golden files: indented,
+// with sorted keys, a final newline, and the config_path of its dependencies relative to the unit directory, so that
+// the output does not depend on where the stack is checked out. Values are rendered as by RenderJSON.
+func RenderUnitJSON(unit *Unit) ([]byte, error) {
+	if unit.Err != nil {
+		return nil, unit.Err
+	}
+	if unit.Config == nil {
+		return nil, fmt.Errorf("unit %s has no configuration", unit.Path)
+	}
+
+	rendered := newRenderedConfig(unit.Config)
+	for i, dependency := range rendered.Dependencies {
+		configPath := filepath.FromSlash(dependency.ConfigPath)
+		if !filepath.IsAbs(configPath) {
+			continue
+		}
+		if relPath, err := filepath.Rel(unit.Path, configPath); err == nil {
+			rendered.Dependencies[i].ConfigPath = filepath.ToSlash(relPath)
+		}
+	}
+
+	content, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// GoldenMismatchError is the error of CompareGolden when the content differs from the golden file.
+type GoldenMismatchError struct {
+	Path string
+
+	// Diff is the unified diff from the golden file to the content.
+	Diff string
+}
+
+func (err *GoldenMismatchError) Error() string {
+	return fmt.Sprintf("%s does not match:\n%s", err.Path, err.Diff)
+}
+
+// CompareGolden compares the given content with the golden file at the given path, and returns a
+// *GoldenMismatchError when they differ. With update, the golden file is written with the content instead, along with
+// its parent directories. Line endings are normalized, so that golden files checked out with CRLF line endings match.
+func CompareGolden(path string, content []byte, update bool) error {
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, content, 0o644)
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	golden, content = normalizeLineEndings(golden), normalizeLineEndings(content)
+	if bytes.Equal(golden, content) {
+		return nil
+	}
+	return &GoldenMismatchError{
+		Path: path,
+		Diff: unifiedDiff("golden/"+filepath.Base(path), "actual/"+filepath.Base(path), string(golden), string(content)),
+	}
+}
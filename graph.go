@@ -0,0 +1,237 @@
+package terragrunt
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Graph is the dependency graph of a set of units, where each unit points to the units it depends on. Units are
+// identified by their absolute path.
+type Graph struct {
+	// Root is the directory the paths are shown relative to when rendering the graph.
+	Root string
+
+	paths          []string
+	external       []string
+	dependencies   map[string][]string
+	skipped        map[string]bool
+	excludes       map[string]*ExcludeConfig
+	preventDestroy map[string]bool
+}
+
+// NewGraph builds the dependency graph of the given units. Dependencies on paths that are not among the units are
+// kept as edges, so that they show up when rendering the graph, but these paths are not units of the graph: they are
+// listed by External, and left out of the batches.
+func NewGraph(root string, units []*Unit) *Graph {
+	graph := &Graph{
+		Root:           root,
+		dependencies:   map[string][]string{},
+		skipped:        map[string]bool{},
+		excludes:       map[string]*ExcludeConfig{},
+		preventDestroy: map[string]bool{},
+	}
+
+	for _, unit := range units {
+		if _, found := graph.dependencies[unit.Path]; !found {
+			graph.dependencies[unit.Path] = nil
+			graph.paths = append(graph.paths, unit.Path)
+		}
+	}
+	for _, unit := range units {
+		if unit.Skipped() {
+			graph.skipped[unit.Path] = true
+		}
+		if unit.Config != nil && unit.Config.Exclude != nil {
+			graph.excludes[unit.Path] = unit.Config.Exclude
+		}
+		if unit.Config != nil && unit.Config.PreventDestroy {
+			graph.preventDestroy[unit.Path] = true
+		}
+		for _, dependency := range unit.Dependencies {
+			if _, found := graph.dependencies[dependency]; !found && !containsString(graph.external, dependency) {
+				graph.external = append(graph.external, dependency)
+			}
+			if !containsString(graph.dependencies[unit.Path], dependency) {
+				graph.dependencies[unit.Path] = append(graph.dependencies[unit.Path], dependency)
+			}
+		}
+	}
+
+	sort.Strings(graph.paths)
+	sort.Strings(graph.external)
+	for _, dependencies := range graph.dependencies {
+		sort.Strings(dependencies)
+	}
+	return graph
+}
+
+// Paths returns the paths of every unit in the graph, sorted.
+func (graph *Graph) Paths() []string {
+	return append([]string(nil), graph.paths...)
+}
+
+// External returns the paths the units depend on that are not units of the graph, e.g. the units outside of a stack,
+// sorted. The run-all commands do not run them, so the units depending on them do not wait for them.
+func (graph *Graph) External() []string {
+	return append([]string(nil), graph.external...)
+}
+
+// Dependencies returns the paths of the units the given unit directly depends on.
+func (graph *Graph) Dependencies(path string) []string {
+	return append([]string(nil), graph.dependencies[path]...)
+}
+
+// Dependents returns the paths of the units that directly depend on the given unit, sorted.
+func (graph *Graph) Dependents(path string) []string {
+	var dependents []string
+	for _, unitPath := range graph.paths {
+		if containsString(graph.dependencies[unitPath], path) {
+			dependents = append(dependents, unitPath)
+		}
+	}
+	return dependents
+}
+
+// Batches groups the units of the graph in batches, such that every unit only depends on units of previous batches.
+// This is the order run-all commands apply units in. Skipped units are left out, as run-all commands do, and units
+// depending on them only wait for their other dependencies. It returns an error naming the units involved when the
+// graph has a cycle.
+func (graph *Graph) Batches() ([][]string, error) {
+	done := map[string]bool{}
+	for path := range graph.skipped {
+		done[path] = true
+	}
+	return graph.batches(done)
+}
+
+// BatchesFor returns the batches of the units run by the run-all commands running the given action (e.g. plan), as
+// Batches does, also leaving out the units excluded from the action by their exclude block, along with the units they
+// depend on, directly or not, when the block sets exclude_dependencies.
+func (graph *Graph) BatchesFor(action string) ([][]string, error) {
+	return graph.batches(graph.leftOut(action))
+}
+
+// leftOut returns the units the run-all commands running the given action leave out: the skipped units, and the ones
+// excluded from the action, along with the units they depend on when their exclude block sets exclude_dependencies.
+func (graph *Graph) leftOut(action string) map[string]bool {
+	leftOut := map[string]bool{}
+	for path := range graph.skipped {
+		leftOut[path] = true
+	}
+	for path, exclude := range graph.excludes {
+		if exclude.Excludes(action) {
+			leftOut[path] = true
+		}
+		if exclude.excludesDependencies(action) {
+			for dependency := range graph.transitiveDependencies(path, map[string]bool{}) {
+				leftOut[dependency] = true
+			}
+		}
+	}
+	return leftOut
+}
+
+// transitiveDependencies adds the units the given unit depends on, directly or not, to the given set, and returns it.
+func (graph *Graph) transitiveDependencies(path string, dependencies map[string]bool) map[string]bool {
+	for _, dependency := range graph.dependencies[path] {
+		if !dependencies[dependency] {
+			dependencies[dependency] = true
+			graph.transitiveDependencies(dependency, dependencies)
+		}
+	}
+	return dependencies
+}
+
+// batches groups the units of the graph that are not done in batches. The external paths are never waited for.
+func (graph *Graph) batches(done map[string]bool) ([][]string, error) {
+	var batches [][]string
+	for {
+		var batch, waiting []string
+		for _, path := range graph.paths {
+			if done[path] {
+				continue
+			}
+			ready := true
+			for _, dependency := range graph.dependencies[path] {
+				if !done[dependency] && !containsString(graph.external, dependency) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				batch = append(batch, path)
+			} else {
+				waiting = append(waiting, graph.relativePath(path))
+			}
+		}
+
+		if len(batch) == 0 {
+			if len(waiting) > 0 {
+				return nil, fmt.Errorf("dependency cycle detected between units: %s", strings.Join(waiting, ", "))
+			}
+			return batches, nil
+		}
+
+		for _, path := range batch {
+			done[path] = true
+		}
+		batches = append(batches, batch)
+	}
+}
+
+// DOT renders the graph in the graphviz dot format, including the external paths.
+func (graph *Graph) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph {\n")
+	for _, path := range graph.external {
+		builder.WriteString(fmt.Sprintf("\t%q [style=dashed] ;\n", graph.relativePath(path)))
+	}
+	for _, path := range graph.paths {
+		builder.WriteString(fmt.Sprintf("\t%q ;\n", graph.relativePath(path)))
+		for _, dependency := range graph.dependencies[path] {
+			builder.WriteString(fmt.Sprintf("\t%q -> %q;\n", graph.relativePath(path), graph.relativePath(dependency)))
+		}
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid renders the graph as a mermaid flowchart, including the external paths.
+func (graph *Graph) Mermaid() string {
+	ids := map[string]string{}
+	for i, path := range graph.paths {
+		ids[path] = fmt.Sprintf("u%d", i)
+	}
+	for i, path := range graph.external {
+		ids[path] = fmt.Sprintf("e%d", i)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("flowchart TD\n")
+	for _, path := range graph.paths {
+		builder.WriteString(fmt.Sprintf("    %s[%q]\n", ids[path], graph.relativePath(path)))
+	}
+	for _, path := range graph.external {
+		builder.WriteString(fmt.Sprintf("    %s[/%q/]\n", ids[path], graph.relativePath(path)))
+	}
+	for _, path := range graph.paths {
+		for _, dependency := range graph.dependencies[path] {
+			builder.WriteString(fmt.Sprintf("    %s --> %s\n", ids[path], ids[dependency]))
+		}
+	}
+	return builder.String()
+}
+
+// relativePath returns the given path relative to the graph root, when possible.
+func (graph *Graph) relativePath(path string) string {
+	if graph.Root == "" {
+		return path
+	}
+	relPath, err := filepath.Rel(graph.Root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
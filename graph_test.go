@@ -0,0 +1,153 @@
+package terragrunt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGraphExternalDependencies(t *testing.T) {
+	graph := NewGraph("/stack", []*Unit{
+		{Path: "/stack/app", Dependencies: []string{"/stack/vpc", "/shared/dns"}},
+		{Path: "/stack/vpc", Dependencies: []string{"/shared/dns"}},
+	})
+
+	if want := []string{"/stack/app", "/stack/vpc"}; !reflect.DeepEqual(graph.Paths(), want) {
+		t.Errorf("got paths %q, want %q", graph.Paths(), want)
+	}
+	if want := []string{"/shared/dns"}; !reflect.DeepEqual(graph.External(), want) {
+		t.Errorf("got external paths %q, want %q", graph.External(), want)
+	}
+	if want := []string{"/shared/dns", "/stack/vpc"}; !reflect.DeepEqual(graph.Dependencies("/stack/app"), want) {
+		t.Errorf("got dependencies %q, want %q", graph.Dependencies("/stack/app"), want)
+	}
+
+	batches, err := graph.Batches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"/stack/vpc"}, {"/stack/app"}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("got batches %q, want %q", batches, want)
+	}
+
+	if dot := graph.DOT(); !strings.Contains(dot, `"vpc" -> "../shared/dns";`) {
+		t.Errorf("got dot graph:\n%s\nwant the edge to the external path", dot)
+	}
+}
+
+func TestGraphDestroyPlan(t *testing.T) {
+	// app depends on db and vpc, and db and dns on vpc.
+	newUnits := func(configs map[string]*TerragruntConfig) []*Unit {
+		units := []*Unit{
+			{Path: "/stack/app", Dependencies: []string{"/stack/db", "/stack/vpc"}},
+			{Path: "/stack/db", Dependencies: []string{"/stack/vpc"}},
+			{Path: "/stack/dns", Dependencies: []string{"/stack/vpc"}},
+			{Path: "/stack/vpc"},
+		}
+		for _, unit := range units {
+			unit.Config = configs[unit.Path]
+		}
+		return units
+	}
+
+	tests := []struct {
+		name    string
+		configs map[string]*TerragruntConfig
+		opts    DestroyOptions
+		batches [][]string
+		blocked []BlockedDestroy
+		err     string
+	}{
+		{
+			name:    "reverse order",
+			batches: [][]string{{"/stack/app", "/stack/dns"}, {"/stack/db"}, {"/stack/vpc"}},
+		},
+		{
+			name:    "targets",
+			opts:    DestroyOptions{Targets: []string{"/stack/db", "/stack/app"}},
+			batches: [][]string{{"/stack/app"}, {"/stack/db"}},
+		},
+		{
+			name:    "target with dependents",
+			opts:    DestroyOptions{Targets: []string{"/stack/vpc", "/stack/dns"}},
+			batches: [][]string{{"/stack/dns"}},
+			blocked: []BlockedDestroy{
+				{Path: "/stack/vpc", Reason: DestroyBlockedByDependents, Dependents: []string{"/stack/app", "/stack/db"}},
+			},
+		},
+		{
+			name:    "prevent destroy",
+			configs: map[string]*TerragruntConfig{"/stack/db": {PreventDestroy: true}},
+			batches: [][]string{{"/stack/app", "/stack/dns"}},
+			blocked: []BlockedDestroy{
+				{Path: "/stack/db", Reason: DestroyBlockedByPreventDestroy},
+				{Path: "/stack/vpc", Reason: DestroyBlockedByDependents, Dependents: []string{"/stack/db"}},
+			},
+		},
+		{
+			name:    "prevent destroy blocking dependencies transitively",
+			configs: map[string]*TerragruntConfig{"/stack/app": {PreventDestroy: true}},
+			batches: [][]string{{"/stack/dns"}},
+			blocked: []BlockedDestroy{
+				{Path: "/stack/app", Reason: DestroyBlockedByPreventDestroy},
+				{Path: "/stack/db", Reason: DestroyBlockedByDependents, Dependents: []string{"/stack/app"}},
+				{Path: "/stack/vpc", Reason: DestroyBlockedByDependents, Dependents: []string{"/stack/app", "/stack/db"}},
+			},
+		},
+		{
+			name:    "allowed prevent destroy",
+			configs: map[string]*TerragruntConfig{"/stack/db": {PreventDestroy: true}},
+			opts:    DestroyOptions{AllowPreventDestroy: true},
+			batches: [][]string{{"/stack/app", "/stack/dns"}, {"/stack/db"}, {"/stack/vpc"}},
+		},
+		{
+			name:    "excluded from destroy",
+			configs: map[string]*TerragruntConfig{"/stack/dns": {Exclude: &ExcludeConfig{If: true, Actions: []string{"destroy"}}}},
+			batches: [][]string{{"/stack/app"}, {"/stack/db"}},
+			blocked: []BlockedDestroy{
+				{Path: "/stack/vpc", Reason: DestroyBlockedByDependents, Dependents: []string{"/stack/dns"}},
+			},
+		},
+		{
+			name:    "skipped dependent",
+			configs: map[string]*TerragruntConfig{"/stack/dns": {Skip: true}},
+			batches: [][]string{{"/stack/app"}, {"/stack/db"}, {"/stack/vpc"}},
+		},
+		{
+			name: "unknown target",
+			opts: DestroyOptions{Targets: []string{"/other/vpc"}},
+			err:  "unit ../other/vpc is not in the graph",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := NewGraph("/stack", newUnits(test.configs)).DestroyPlan(test.opts)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plan.Batches, test.batches) {
+				t.Errorf("got batches %q, want %q", plan.Batches, test.batches)
+			}
+			if !reflect.DeepEqual(plan.Blocked, test.blocked) {
+				t.Errorf("got blocked units %+v, want %+v", plan.Blocked, test.blocked)
+			}
+		})
+	}
+}
+
+func TestGraphDestroyPlanCycle(t *testing.T) {
+	graph := NewGraph("/stack", []*Unit{
+		{Path: "/stack/a", Dependencies: []string{"/stack/b"}},
+		{Path: "/stack/b", Dependencies: []string{"/stack/a"}},
+	})
+	if _, err := graph.DestroyPlan(DestroyOptions{}); err == nil || err.Error() != "dependency cycle detected between units: a, b" {
+		t.Errorf("got error %v, want the cycle", err)
+	}
+}
@@ -0,0 +1,568 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// jsonBlockSchema describes a block type of the terragrunt configuration, for JSONToHCL to tell blocks from attributes.
+type jsonBlockSchema struct {
+	labels int
+	blocks map[string]jsonBlockSchema
+}
+
+// terragruntJSONBlocks are the block types of the terragrunt configuration, with their nested block types.
+var terragruntJSONBlocks = map[string]jsonBlockSchema{
+	"terraform": {blocks: map[string]jsonBlockSchema{
+		"extra_arguments": {labels: 1},
+		"before_hook":     {labels: 1},
+		"after_hook":      {labels: 1},
+		"error_hook":      {labels: 1},
+	}},
+	"remote_state": {},
+	"include":      {labels: 1},
+	"locals":       {},
+	"dependency":   {labels: 1},
+	"dependencies": {},
+	"generate":     {labels: 1},
+	"feature":      {labels: 1},
+	"errors": {blocks: map[string]jsonBlockSchema{
+		"retry":  {labels: 1},
+		"ignore": {labels: 1},
+	}},
+	"engine":  {},
+	"exclude": {},
+	"catalog": {},
+}
+
+// jsonObject is a JSON object keeping the order of its keys, so that conversions keep the order of the configuration.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: map[string]interface{}{}}
+}
+
+func (object *jsonObject) set(key string, value interface{}) {
+	if _, found := object.values[key]; !found {
+		object.keys = append(object.keys, key)
+	}
+	object.values[key] = value
+}
+
+// HCLToJSON converts the given terragrunt configuration content to the HCL JSON syntax (terragrunt.hcl.json), keeping
+// the order of its attributes and blocks. Blocks are nested in an object per label, e.g. {"dependency": {"vpc": {...}}},
+// and blocks of the same type and labels become a list. Static values are converted to their JSON value, and the other
+// expressions to a template string, e.g. "${local.env}" or "eu-${local.env}". Comments are dropped.
+func HCLToJSON(content []byte) ([]byte, error) {
+	content = normalizeLineEndings(content)
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	object, err := hclBodyToJSON(content, file.Body.(*hclsyntax.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	var compact bytes.Buffer
+	if err := writeJSONValue(&compact, object); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// hclBodyToJSON returns the JSON object of the given body, its attributes and blocks in their order in the file.
+func hclBodyToJSON(content []byte, body *hclsyntax.Body) (*jsonObject, error) {
+	type item struct {
+		offset    int
+		attribute *hclsyntax.Attribute
+		blocks    []*hclsyntax.Block
+	}
+	var items []*item
+	blockTypes := map[string]*item{}
+	for _, attribute := range body.Attributes {
+		items = append(items, &item{offset: attribute.SrcRange.Start.Byte, attribute: attribute})
+	}
+	for _, block := range body.Blocks {
+		if blockType, found := blockTypes[block.Type]; found {
+			blockType.blocks = append(blockType.blocks, block)
+			continue
+		}
+		blockTypes[block.Type] = &item{offset: block.TypeRange.Start.Byte, blocks: []*hclsyntax.Block{block}}
+		items = append(items, blockTypes[block.Type])
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].offset < items[j].offset
+	})
+
+	object := newJSONObject()
+	for _, item := range items {
+		if item.attribute != nil {
+			value, err := hclExpressionToJSON(content, item.attribute.Expr)
+			if err != nil {
+				return nil, err
+			}
+			object.set(item.attribute.Name, value)
+			continue
+		}
+		value, err := hclBlocksToJSON(content, item.blocks, 0)
+		if err != nil {
+			return nil, err
+		}
+		object.set(item.blocks[0].Type, value)
+	}
+	return object, nil
+}
+
+// hclBlocksToJSON returns the JSON value of the given blocks of the same type, nested in an object per label from the
+// given label on.
+func hclBlocksToJSON(content []byte, blocks []*hclsyntax.Block, label int) (interface{}, error) {
+	if len(blocks[0].Labels) == label {
+		var bodies []interface{}
+		for _, block := range blocks {
+			if len(block.Labels) != label {
+				return nil, fmt.Errorf("%s: %s blocks must all have %d labels", block.DefRange(), block.Type, label)
+			}
+			body, err := hclBodyToJSON(content, block.Body)
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, body)
+		}
+		if len(bodies) == 1 {
+			return bodies[0], nil
+		}
+		return bodies, nil
+	}
+
+	var labels []string
+	byLabel := map[string][]*hclsyntax.Block{}
+	for _, block := range blocks {
+		if len(block.Labels) <= label {
+			return nil, fmt.Errorf("%s: %s blocks must all have %d labels", block.DefRange(), block.Type, len(blocks[0].Labels))
+		}
+		if _, found := byLabel[block.Labels[label]]; !found {
+			labels = append(labels, block.Labels[label])
+		}
+		byLabel[block.Labels[label]] = append(byLabel[block.Labels[label]], block)
+	}
+	object := newJSONObject()
+	for _, name := range labels {
+		value, err := hclBlocksToJSON(content, byLabel[name], label+1)
+		if err != nil {
+			return nil, err
+		}
+		object.set(name, value)
+	}
+	return object, nil
+}
+
+// hclExpressionToJSON returns the JSON value of the given expression: object and tuple constructors are converted
+// element by element, static values to their JSON value, and the other expressions to a template string.
+func hclExpressionToJSON(content []byte, expr hclsyntax.Expression) (interface{}, error) {
+	switch expr := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		object := newJSONObject()
+		for _, item := range expr.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || key.IsNull() || key.Type() != cty.String {
+				// Keys computed from variables can not be written as JSON keys.
+				return hclTemplateToJSON(content, expr), nil
+			}
+			value, err := hclExpressionToJSON(content, item.ValueExpr)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.AsString(), value)
+		}
+		return object, nil
+	case *hclsyntax.TupleConsExpr:
+		values := []interface{}{}
+		for _, element := range expr.Exprs {
+			value, err := hclExpressionToJSON(content, element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case *hclsyntax.TemplateWrapExpr:
+		return hclTemplateToJSON(content, expr.Wrapped), nil
+	case *hclsyntax.TemplateExpr:
+		return hclTemplateExprToJSON(content, expr), nil
+	}
+
+	if len(expr.Variables()) == 0 {
+		if value, diags := expr.Value(nil); !diags.HasErrors() && value.IsWhollyKnown() {
+			return ctyValueToJSON(value)
+		}
+	}
+	return hclTemplateToJSON(content, expr), nil
+}
+
+// hclTemplateExprToJSON returns the JSON template string of the given template expression, keeping its literal parts
+// and interpolations. Templates with directives are wrapped as a whole in an interpolation.
+func hclTemplateExprToJSON(content []byte, expr *hclsyntax.TemplateExpr) string {
+	var out strings.Builder
+	for _, part := range expr.Parts {
+		if literal, isLiteral := part.(*hclsyntax.LiteralValueExpr); isLiteral && literal.Val.Type() == cty.String {
+			out.WriteString(escapeJSONTemplate(literal.Val.AsString()))
+			continue
+		}
+		start := part.Range().Start.Byte
+		if start < 2 || string(content[start-2:start]) != "${" {
+			return hclTemplateToJSON(content, expr)
+		}
+		out.WriteString("${" + string(part.Range().SliceBytes(content)) + "}")
+	}
+	return out.String()
+}
+
+// hclTemplateToJSON returns the JSON template string interpolating the source of the given expression.
+func hclTemplateToJSON(content []byte, expr hclsyntax.Expression) string {
+	return "${" + string(expr.Range().SliceBytes(content)) + "}"
+}
+
+// escapeJSONTemplate escapes the template sequences of the given literal string.
+func escapeJSONTemplate(literal string) string {
+	literal = strings.ReplaceAll(literal, "${", "$${")
+	return strings.ReplaceAll(literal, "%{", "%%{")
+}
+
+// ctyValueToJSON returns the JSON value of the given static value, its strings escaped as literal templates.
+func ctyValueToJSON(value cty.Value) (interface{}, error) {
+	value, _ = value.UnmarkDeep()
+	switch {
+	case value.IsNull():
+		return nil, nil
+	case value.Type() == cty.String:
+		return escapeJSONTemplate(value.AsString()), nil
+	case value.Type() == cty.Bool:
+		return value.True(), nil
+	case value.Type() == cty.Number:
+		return json.Number(value.AsBigFloat().Text('f', -1)), nil
+	case value.Type().IsObjectType() || value.Type().IsMapType():
+		object := newJSONObject()
+		for it := value.ElementIterator(); it.Next(); {
+			key, element := it.Element()
+			converted, err := ctyValueToJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.AsString(), converted)
+		}
+		return object, nil
+	case value.CanIterateElements():
+		values := []interface{}{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			converted, err := ctyValueToJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", value.Type().FriendlyName())
+}
+
+// writeJSONValue writes the given value as compact JSON, without escaping HTML characters.
+func writeJSONValue(out *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case *jsonObject:
+		out.WriteString("{")
+		for i, key := range value.keys {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := writeJSONValue(out, key); err != nil {
+				return err
+			}
+			out.WriteString(":")
+			if err := writeJSONValue(out, value.values[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case []interface{}:
+		out.WriteString("[")
+		for i, element := range value {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := writeJSONValue(out, element); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	default:
+		encoder := json.NewEncoder(out)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		// Encode terminates the value with a newline.
+		out.Truncate(out.Len() - 1)
+	}
+	return nil
+}
+
+// JSONToHCL converts the given terragrunt configuration in the HCL JSON syntax (terragrunt.hcl.json) to the native
+// syntax, keeping the order of its keys. The block types of terragrunt (e.g. dependency, generate, or extra_arguments
+// in terraform) are written as blocks, with their labels, and the other keys as attributes. Strings are written as
+// templates, or as bare expressions when they are a single interpolation, e.g. "${local.env}". Comments, set as "//"
+// keys, are kept.
+func JSONToHCL(content []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	value, err := decodeOrderedJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected content after the JSON object")
+	}
+	object, isObject := value.(*jsonObject)
+	if !isObject {
+		return nil, fmt.Errorf("the configuration must be a JSON object")
+	}
+
+	var out strings.Builder
+	if err := writeHCLBody(&out, object, terragruntJSONBlocks); err != nil {
+		return nil, err
+	}
+	formatted, err := Format([]byte(out.String()))
+	if err != nil {
+		return nil, fmt.Errorf("the converted configuration is invalid: %w", err)
+	}
+	return formatted, nil
+}
+
+// decodeOrderedJSON decodes the next JSON value of the decoder, objects as jsonObject.
+func decodeOrderedJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := newJSONObject()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if existing, found := object.values[key.(string)]; found && key.(string) == "//" {
+				// Comments may be repeated.
+				value = fmt.Sprintf("%v\n%v", existing, value)
+			}
+			object.set(key.(string), value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		values := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		_, err := decoder.Token()
+		return values, err
+	}
+	return token, nil
+}
+
+// writeHCLBody writes the given object as a body, its keys with a schema in blocks as blocks.
+func writeHCLBody(out *strings.Builder, object *jsonObject, blocks map[string]jsonBlockSchema) error {
+	lastWasBlock := false
+	for _, key := range object.keys {
+		value := object.values[key]
+		if key == "//" {
+			for _, line := range strings.Split(fmt.Sprint(value), "\n") {
+				out.WriteString("# " + line + "\n")
+			}
+			continue
+		}
+
+		_, isBlock := blocks[key]
+		if isBlock || lastWasBlock {
+			separateHCLBlock(out)
+		}
+		lastWasBlock = isBlock
+		if schema, isBlock := blocks[key]; isBlock {
+			if key == "include" && isBareInclude(value) {
+				schema.labels = 0
+			}
+			if err := writeHCLBlocks(out, key, schema, value, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !hclsyntax.ValidIdentifier(key) {
+			return fmt.Errorf("%q is not a valid attribute name", key)
+		}
+		out.WriteString(key + " = ")
+		if err := writeHCLExpression(out, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		out.WriteString("\n")
+	}
+	return nil
+}
+
+// isBareInclude returns whether the given include value is a bare include block, i.e. sets its path directly.
+func isBareInclude(value interface{}) bool {
+	object, isObject := value.(*jsonObject)
+	if !isObject {
+		return false
+	}
+	_, isLabel := object.values["path"].(*jsonObject)
+	return object.values["path"] != nil && !isLabel
+}
+
+// writeHCLBlocks writes the blocks of the given type from the given value, nested in an object per remaining label, or
+// in lists of blocks.
+func writeHCLBlocks(out *strings.Builder, blockType string, schema jsonBlockSchema, value interface{}, labels []string) error {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, element := range value {
+			if err := writeHCLBlocks(out, blockType, schema, element, labels); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *jsonObject:
+		if len(labels) < schema.labels {
+			for _, label := range value.keys {
+				if err := writeHCLBlocks(out, blockType, schema, value.values[label], append(labels[:len(labels):len(labels)], label)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		separateHCLBlock(out)
+		out.WriteString(blockType)
+		for _, label := range labels {
+			out.WriteString(fmt.Sprintf(" %q", label))
+		}
+		out.WriteString(" {\n")
+		if err := writeHCLBody(out, value, schema.blocks); err != nil {
+			return err
+		}
+		out.WriteString("}\n")
+		return nil
+	}
+	return fmt.Errorf("%s must be an object", blockType)
+}
+
+// separateHCLBlock separates the block about to be written from the previous item of its body with an empty line,
+// unless it follows the opening brace of its body, or a comment.
+func separateHCLBlock(out *strings.Builder) {
+	written := strings.TrimSuffix(out.String(), "\n")
+	lastLine := strings.TrimSpace(written[strings.LastIndexByte(written, '\n')+1:])
+	if written != "" && !strings.HasSuffix(written, "{") && !strings.HasSuffix(written, "\n") && !strings.HasPrefix(lastLine, "#") {
+		out.WriteString("\n")
+	}
+}
+
+// writeHCLExpression writes the given JSON value as an expression.
+func writeHCLExpression(out *strings.Builder, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		out.WriteString("null")
+	case bool:
+		out.WriteString(fmt.Sprint(value))
+	case json.Number:
+		if _, _, err := big.ParseFloat(string(value), 10, 512, big.ToNearestEven); err != nil {
+			return fmt.Errorf("invalid number %s", value)
+		}
+		out.WriteString(string(value))
+	case string:
+		return writeHCLTemplate(out, value)
+	case []interface{}:
+		out.WriteString("[")
+		for i, element := range value {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			if err := writeHCLExpression(out, element); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case *jsonObject:
+		out.WriteString("{\n")
+		for _, key := range value.keys {
+			if key == "//" {
+				continue
+			}
+			if hclsyntax.ValidIdentifier(key) {
+				out.WriteString(key)
+			} else {
+				out.WriteString(fmt.Sprintf("%q", key))
+			}
+			out.WriteString(" = ")
+			if err := writeHCLExpression(out, value.values[key]); err != nil {
+				return err
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString("}")
+	}
+	return nil
+}
+
+// writeHCLTemplate writes the given JSON template string as a quoted template, or as a bare expression when it is a
+// single interpolation.
+func writeHCLTemplate(out *strings.Builder, template string) error {
+	parts, err := splitLegacyInterpolations(template)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 1 && parts[0].interpolation {
+		out.WriteString(parts[0].text)
+		return nil
+	}
+
+	out.WriteString(`"`)
+	for _, part := range parts {
+		if part.interpolation {
+			out.WriteString("${" + part.text + "}")
+			continue
+		}
+		literal := strings.ReplaceAll(part.text, `\`, `\\`)
+		literal = strings.ReplaceAll(literal, `"`, `\"`)
+		literal = strings.ReplaceAll(literal, "\n", `\n`)
+		literal = strings.ReplaceAll(literal, "\r", `\r`)
+		literal = strings.ReplaceAll(literal, "\t", `\t`)
+		out.WriteString(literal)
+	}
+	out.WriteString(`"`)
+	return nil
+}
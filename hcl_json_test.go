@@ -0,0 +1,224 @@
+package terragrunt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHCLToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{
+			name: "attributes and blocks",
+			src: `locals {
+  env = "prod"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+inputs = {
+  name    = "app-${local.env}"
+  vpc_id  = dependency.vpc.outputs.vpc_id
+  count   = 3
+  enabled = true
+  zones   = ["a", "b"]
+  escaped = "$${literal}"
+}
+`,
+			want: `{
+  "locals": {
+    "env": "prod"
+  },
+  "dependency": {
+    "vpc": {
+      "config_path": "../vpc"
+    }
+  },
+  "inputs": {
+    "name": "app-${local.env}",
+    "vpc_id": "${dependency.vpc.outputs.vpc_id}",
+    "count": 3,
+    "enabled": true,
+    "zones": [
+      "a",
+      "b"
+    ],
+    "escaped": "$${literal}"
+  }
+}
+`,
+		},
+		{
+			name: "repeated blocks",
+			src:  "generate \"provider\" {\n  path = \"a.tf\"\n}\n\ngenerate \"provider\" {\n  path = \"b.tf\"\n}\n",
+			want: `{
+  "generate": {
+    "provider": [
+      {
+        "path": "a.tf"
+      },
+      {
+        "path": "b.tf"
+      }
+    ]
+  }
+}
+`,
+		},
+		{
+			name: "invalid syntax",
+			src:  "inputs = {\n",
+			err:  "tmp.hcl",
+		},
+		{
+			name: "inconsistent labels",
+			src:  "dependency \"vpc\" {\n}\n\ndependency {\n}\n",
+			err:  "dependency blocks must all have 1 labels",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := HCLToJSON([]byte(test.src))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJSONToHCL(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{
+			name: "attributes, blocks and comments",
+			src: `{
+  "//": "Managed by the platform team",
+  "include": {"root": {"path": "${find_in_parent_folders()}"}},
+  "locals": {"env": "prod"},
+  "inputs": {"name": "app-${local.env}", "count": 3, "enabled": true, "zones": ["a", "b"], "tags": {"team name": "platform"}}
+}`,
+			want: `# Managed by the platform team
+include "root" {
+  path = find_in_parent_folders()
+}
+
+locals {
+  env = "prod"
+}
+
+inputs = {
+  name    = "app-${local.env}"
+  count   = 3
+  enabled = true
+  zones   = ["a", "b"]
+  tags = {
+    "team name" = "platform"
+  }
+}
+`,
+		},
+		{
+			name: "not an object",
+			src:  `[1]`,
+			err:  "the configuration must be a JSON object",
+		},
+		{
+			name: "trailing content",
+			src:  `{"inputs": {}} {}`,
+			err:  "unexpected content after the JSON object",
+		},
+		{
+			name: "invalid attribute name",
+			src:  `{"bad key": 1}`,
+			err:  `"bad key" is not a valid attribute name`,
+		},
+		{
+			name: "block not an object",
+			src:  `{"dependency": "vpc"}`,
+			err:  "dependency must be an object",
+		},
+		{
+			name: "unterminated interpolation",
+			src:  `{"name": "${local.env"}`,
+			err:  "name: unterminated interpolation",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := JSONToHCL([]byte(test.src))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Format([]byte(test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestHCLJSONRoundTrip(t *testing.T) {
+	src := []byte(`include "root" {
+  path = find_in_parent_folders()
+}
+
+locals {
+  env = "prod"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+inputs = {
+  name   = "app-${local.env}"
+  vpc_id = dependency.vpc.outputs.vpc_id
+  zones  = ["a", "b"]
+}
+`)
+	converted, err := HCLToJSON(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := JSONToHCL(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Format(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
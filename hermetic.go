@@ -0,0 +1,81 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// HermeticMode selects how functions with side effects or access to the environment behave, for services that
+// evaluate untrusted configurations.
+type HermeticMode int
+
+const (
+	// HermeticDisabled leaves every function available.
+	HermeticDisabled HermeticMode = iota
+
+	// HermeticUnknown makes the functions with side effects return unknown values, so that the rest of the
+	// configuration can still be evaluated.
+	HermeticUnknown
+
+	// HermeticError makes calling a function with side effects an error, reported at the position of the call.
+	HermeticError
+)
+
+// sideEffectFunctions are the built-in functions that run commands or read the environment, the filesystem or remote
+// services. They are the ones replaced in hermetic mode.
+var sideEffectFunctions = []string{
+	"aws_secretsmanager",
+	"file",
+	"fileexists",
+	"find_in_parent_folders",
+	"get_aws_account_id",
+	"get_aws_caller_identity_arn",
+	"get_aws_caller_identity_user_id",
+	"get_env",
+	"get_path_from_repo_root",
+	"get_path_to_repo_root",
+	"get_repo_root",
+	"read_tfvars_file",
+	"run_cmd",
+	"sops_decrypt_file",
+	"templatefile",
+	"ssm_parameter",
+	"vault_kv",
+}
+
+// applyHermeticMode replaces the functions with side effects of the given table according to the mode.
+func applyHermeticMode(functions map[string]function.Function, mode HermeticMode) {
+	if mode == HermeticDisabled {
+		return
+	}
+
+	for _, name := range sideEffectFunctions {
+		if _, found := functions[name]; !found {
+			continue
+		}
+		functions[name] = hermeticFunc(name, mode)
+	}
+}
+
+// hermeticFunc returns the replacement of the function with the given name in hermetic mode.
+func hermeticFunc(name string, mode HermeticMode) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{
+			Name:             "args",
+			Type:             cty.DynamicPseudoType,
+			AllowUnknown:     true,
+			AllowNull:        true,
+			AllowMarked:      true,
+			AllowDynamicType: true,
+		},
+		Type: function.StaticReturnType(cty.DynamicPseudoType),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if mode == HermeticError {
+				return cty.NilVal, fmt.Errorf("%s is not allowed in hermetic mode", name)
+			}
+			return cty.DynamicVal, nil
+		},
+	})
+}
@@ -0,0 +1,174 @@
+package terragrunt
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// assumeRoleExpiryWindow is how long before their expiration cached temporary credentials are renewed, so that they do
+// not expire while in use.
+const assumeRoleExpiryWindow = 5 * time.Minute
+
+// AssumeRoleCache assumes the IAM roles of units with a RoleAssumer, and caches the temporary credentials per unit and
+// role until shortly before they expire. It is safe for concurrent use, and meant to be shared by the parses and the
+// output resolvers of a stack.
+type AssumeRoleCache struct {
+	// Assumer assumes the roles. Defaults to an STSClient signing its requests with EnvAWSCredentials.
+	Assumer RoleAssumer
+
+	mu      sync.Mutex
+	entries map[assumeRoleKey]*assumeRoleEntry
+}
+
+// NewAssumeRoleCache returns an AssumeRoleCache assuming the roles with the given RoleAssumer.
+func NewAssumeRoleCache(assumer RoleAssumer) *AssumeRoleCache {
+	return &AssumeRoleCache{Assumer: assumer}
+}
+
+type assumeRoleKey struct {
+	unitDir string
+	role    AWSRole
+}
+
+// assumeRoleEntry holds the credentials of a role for a unit. Its mutex is held while the role is assumed, so that
+// concurrent requests for the same unit wait for a single call.
+type assumeRoleEntry struct {
+	mu      sync.Mutex
+	assumed AssumedRole
+}
+
+// AssumeRole returns the credentials of the given role for the unit with the given config path, assuming it when
+// they are not cached or about to expire. Failures are not cached.
+func (cache *AssumeRoleCache) AssumeRole(ctx context.Context, configPath string, role AWSRole) (AssumedRole, error) {
+	assumer := cache.Assumer
+	if assumer == nil {
+		assumer = STSClient{Credentials: EnvAWSCredentials{}}
+	}
+	return cache.assumeRoleWith(ctx, configPath, role, assumer)
+}
+
+// assumeRoleWith is like AssumeRole, but assumes the role with the given RoleAssumer, e.g. one signing its requests
+// with the credentials selected for the unit.
+func (cache *AssumeRoleCache) assumeRoleWith(ctx context.Context, configPath string, role AWSRole, assumer RoleAssumer) (AssumedRole, error) {
+	key := assumeRoleKey{unitDir: configDir(configPath), role: role}
+	if absDir, err := filepath.Abs(key.unitDir); err == nil {
+		key.unitDir = absDir
+	}
+
+	cache.mu.Lock()
+	if cache.entries == nil {
+		cache.entries = map[assumeRoleKey]*assumeRoleEntry{}
+	}
+	entry, found := cache.entries[key]
+	if !found {
+		entry = &assumeRoleEntry{}
+		cache.entries[key] = entry
+	}
+	cache.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if credentialsFresh(entry.assumed.Credentials) {
+		return entry.assumed, nil
+	}
+
+	assumed, err := assumer.AssumeRole(ctx, role)
+	if err != nil {
+		return AssumedRole{}, err
+	}
+	entry.assumed = assumed
+	return assumed, nil
+}
+
+// Credentials returns an AWSCredentialsProvider returning the credentials of the given role for the unit with the
+// given config path, through the cache.
+func (cache *AssumeRoleCache) Credentials(configPath string, role AWSRole) AWSCredentialsProvider {
+	return assumedRoleCredentials{cache: cache, configPath: configPath, role: role}
+}
+
+// credentialsWith is like Credentials, but the role is assumed with the given RoleAssumer.
+func (cache *AssumeRoleCache) credentialsWith(configPath string, role AWSRole, assumer RoleAssumer) AWSCredentialsProvider {
+	return assumedRoleCredentials{cache: cache, configPath: configPath, role: role, assumer: assumer}
+}
+
+type assumedRoleCredentials struct {
+	cache      *AssumeRoleCache
+	configPath string
+	role       AWSRole
+
+	// assumer, when set, assumes the role instead of the Assumer of the cache.
+	assumer RoleAssumer
+}
+
+func (credentials assumedRoleCredentials) Credentials(ctx context.Context) (AWSCredentials, error) {
+	if credentials.assumer != nil {
+		assumed, err := credentials.cache.assumeRoleWith(ctx, credentials.configPath, credentials.role, credentials.assumer)
+		return assumed.Credentials, err
+	}
+	assumed, err := credentials.cache.AssumeRole(ctx, credentials.configPath, credentials.role)
+	return assumed.Credentials, err
+}
+
+// credentialsFresh returns whether the given credentials are set, and do not expire soon.
+func credentialsFresh(credentials AWSCredentials) bool {
+	if credentials.AccessKeyID == "" {
+		return false
+	}
+	return credentials.Expiration.IsZero() || time.Until(credentials.Expiration) > assumeRoleExpiryWindow
+}
+
+// configAWSRole returns the role declared by the iam_role attributes of the given configuration, if any.
+func configAWSRole(config *TerragruntConfig) (AWSRole, bool) {
+	if config == nil || config.IamRole == "" {
+		return AWSRole{}, false
+	}
+	return AWSRole{
+		ARN:         config.IamRole,
+		SessionName: config.IamAssumeRoleSessionName,
+		Duration:    time.Duration(config.IamAssumeRoleDuration) * time.Second,
+	}, true
+}
+
+// iamRoleAttributes are the attributes of a configuration declaring the role to assume.
+type iamRoleAttributes struct {
+	IamRole               *string  `hcl:"iam_role,attr"`
+	IamAssumeRoleDuration *int64   `hcl:"iam_assume_role_duration,attr"`
+	IamAssumeRoleSession  *string  `hcl:"iam_assume_role_session_name,attr"`
+	Remain                hcl.Body `hcl:",remain"`
+}
+
+// decodeIAMRole evaluates the iam_role attributes of the given body, once its locals are evaluated, so that the AWS
+// identity functions called by the rest of the configuration return the identity of the role. The role of an
+// including configuration takes precedence over the one of the configurations it includes. It returns whether the
+// locals called the identity functions before the role was known, and should be evaluated again.
+func decodeIAMRole(body hcl.Body, opts *ParseOptions, extensions EvalContextExtensions) (bool, error) {
+	if opts.AssumeRoleCache == nil || opts.iamRoleDecoded {
+		return false, nil
+	}
+
+	var attributes iamRoleAttributes
+	if err := decodeHCL(body, &attributes, opts, extensions); err != nil {
+		return false, err
+	}
+	opts.iamRoleDecoded = true
+	if attributes.IamRole == nil || opts.iamRole != nil {
+		return false, nil
+	}
+
+	config := &TerragruntConfig{IamRole: *attributes.IamRole}
+	if attributes.IamAssumeRoleDuration != nil {
+		config.IamAssumeRoleDuration = *attributes.IamAssumeRoleDuration
+	}
+	if attributes.IamAssumeRoleSession != nil {
+		config.IamAssumeRoleSessionName = *attributes.IamAssumeRoleSession
+	}
+	if role, found := configAWSRole(config); found {
+		opts.iamRole = &role
+	}
+	return opts.iamRole != nil && opts.identityBeforeIAMRole, nil
+}
@@ -0,0 +1,190 @@
+package terragrunt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFilenames are the names of the files listing, with the gitignore syntax, the paths excluded from the discovery
+// of units, relative to the directory holding them. Like .gitignore files, they apply to the directory holding them
+// and to its subdirectories.
+var IgnoreFilenames = []string{".terragrunt-ignore", ".tgignore"}
+
+// ignorePattern is a pattern of an ignore file, or of WithExcludeGlobs.
+type ignorePattern struct {
+	// base is the path, relative to the root of the discovery and with forward slashes, of the directory the pattern
+	// is relative to, empty for the root itself.
+	base string
+
+	// anchored is set for the patterns holding a slash, which match the path relative to base, the other ones
+	// matching the name of the files and directories at any depth.
+	anchored bool
+	negated  bool
+	dirOnly  bool
+	regexp   *regexp.Regexp
+}
+
+// ignoreMatcher holds the patterns applying to a directory of the discovery, in order: the last pattern matching a
+// path decides whether it is ignored.
+type ignoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// newIgnoreMatcher returns the matcher of the given globs, relative to the root of the discovery.
+func newIgnoreMatcher(globs []string) (*ignoreMatcher, error) {
+	matcher := &ignoreMatcher{}
+	for _, glob := range globs {
+		pattern, ok, err := parseIgnorePattern(glob, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude glob %q: %w", glob, err)
+		}
+		if ok {
+			matcher.patterns = append(matcher.patterns, pattern)
+		}
+	}
+	return matcher, nil
+}
+
+// withIgnoreFiles returns the matcher applying to the given directory, at the given path relative to the root of the
+// discovery, with the patterns of its ignore files added to the ones of the matcher, which is left unchanged.
+func (matcher *ignoreMatcher) withIgnoreFiles(dir string, relDir string) (*ignoreMatcher, error) {
+	extended := matcher
+	for _, name := range IgnoreFilenames {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if extended == matcher {
+			extended = &ignoreMatcher{patterns: append([]ignorePattern(nil), matcher.patterns...)}
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for line := 1; scanner.Scan(); line++ {
+			pattern, ok, err := parseIgnorePattern(scanner.Text(), relDir)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", filepath.Join(dir, name), line, err)
+			}
+			if ok {
+				extended.patterns = append(extended.patterns, pattern)
+			}
+		}
+	}
+	return extended, nil
+}
+
+// ignored returns whether the file or directory at the given path, relative to the root of the discovery and with
+// forward slashes, is excluded.
+func (matcher *ignoreMatcher) ignored(relPath string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range matcher.patterns {
+		if pattern.matches(relPath, isDir) {
+			ignored = !pattern.negated
+		}
+	}
+	return ignored
+}
+
+func (pattern ignorePattern) matches(relPath string, isDir bool) bool {
+	if pattern.dirOnly && !isDir {
+		return false
+	}
+	if pattern.base != "" {
+		if !strings.HasPrefix(relPath, pattern.base+"/") {
+			return false
+		}
+		relPath = relPath[len(pattern.base)+1:]
+	}
+	if !pattern.anchored {
+		relPath = relPath[strings.LastIndexByte(relPath, '/')+1:]
+	}
+	return pattern.regexp.MatchString(relPath)
+}
+
+// parseIgnorePattern parses a line of an ignore file with the gitignore syntax, relative to the given directory. It
+// returns false for blank lines and comments.
+func parseIgnorePattern(line string, base string) (ignorePattern, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+	if trimmed := strings.TrimRight(line, " "); !strings.HasSuffix(trimmed, `\`) {
+		line = trimmed
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false, nil
+	}
+
+	pattern := ignorePattern{base: base}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		pattern.negated = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		pattern.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false, nil
+	}
+
+	expression, err := globExpression(line)
+	if err != nil {
+		return ignorePattern{}, false, err
+	}
+	if pattern.regexp, err = regexp.Compile("^" + expression + "$"); err != nil {
+		return ignorePattern{}, false, err
+	}
+	return pattern, true, nil
+}
+
+// globExpression returns the regular expression of the given glob with the gitignore syntax: * and ? match within a
+// path segment, ** across segments, and [...] matches a character class.
+func globExpression(glob string) (string, error) {
+	var expression strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			expression.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case c == '*':
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expression.String(), nil
+}
@@ -0,0 +1,435 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MergeStrategy is the merge_strategy of an include block, which selects how the included configuration is merged
+// with the including one.
+type MergeStrategy string
+
+const (
+	// MergeNoMerge does not merge the included configuration, which is then only used by the include related
+	// functions, such as path_relative_to_include().
+	MergeNoMerge MergeStrategy = "no_merge"
+
+	// MergeShallow merges the top level attributes and blocks: the ones of the including configuration replace the
+	// ones of the included configuration. Inputs, dependency blocks and generate blocks are merged by name. This is
+	// the default strategy.
+	MergeShallow MergeStrategy = "shallow"
+
+	// MergeDeep merges like MergeShallow, except that the inputs and the remote_state config are merged recursively:
+	// objects and maps are merged attribute by attribute, and lists are concatenated.
+	MergeDeep MergeStrategy = "deep"
+
+	// MergeDeepMapOnly merges like MergeDeep, except that only objects and maps are merged recursively: lists, and any
+	// other value, are replaced.
+	MergeDeepMapOnly MergeStrategy = "deep_map_only"
+)
+
+// deep returns whether the strategy merges the inputs and the remote_state config recursively.
+func (strategy MergeStrategy) deep() bool {
+	return strategy == MergeDeep || strategy == MergeDeepMapOnly
+}
+
+// terragruntIncludes is a struct that can be used to only decode the include blocks in the terragrunt config.
+type terragruntIncludes struct {
+	Include []struct {
+		Name          string   `hcl:"name,label"`
+		Path          string   `hcl:"path,attr"`
+		MergeStrategy *string  `hcl:"merge_strategy,attr"`
+		Remain        hcl.Body `hcl:",remain"`
+	} `hcl:"include,block"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// includeConfig is a decoded include block.
+type includeConfig struct {
+	Name string
+
+	// Path is the absolute path of the included configuration.
+	Path string
+
+	MergeStrategy MergeStrategy
+}
+
+// includeBlocksSchema selects the include blocks of a configuration, to locate them in diagnostics.
+var includeBlocksSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "include", LabelNames: []string{"name"}}},
+}
+
+func hasIncludeBlocks(body hcl.Body) bool {
+	content, _, _ := body.PartialContent(includeBlocksSchema)
+	return content != nil && len(content.Blocks) > 0
+}
+
+// checkNestedIncludes reports an error when the configuration with the given body is included by another one and has
+// include blocks itself, as only one level of includes is supported.
+func checkNestedIncludes(body hcl.Body, opts *ParseOptions) error {
+	if opts.originalConfigPath != "" && hasIncludeBlocks(body) {
+		return fmt.Errorf("%s is included by %s, and can not include other configurations", opts.ConfigPath, opts.originalConfigPath)
+	}
+	return nil
+}
+
+// decodeIncludes decodes the include blocks of the body, in declaration order. Include blocks sharing a label are
+// reported as errors.
+func decodeIncludes(body hcl.Body, opts *ParseOptions) ([]includeConfig, error) {
+	decoded := terragruntIncludes{}
+	if err := decodeHCL(body, &decoded, opts, EvalContextExtensions{}); err != nil {
+		return nil, err
+	}
+
+	var blocks hcl.Blocks
+	if content, _, _ := body.PartialContent(includeBlocksSchema); content != nil && len(content.Blocks) == len(decoded.Include) {
+		blocks = content.Blocks
+	}
+
+	var diags hcl.Diagnostics
+	declared := map[string]int{}
+	includes := make([]includeConfig, 0, len(decoded.Include))
+	for i, include := range decoded.Include {
+		if existing, found := declared[include.Name]; found {
+			diag := &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate include block",
+				Detail:   fmt.Sprintf("An include named %q was already declared. Include names must be unique, so rename one of the blocks.", include.Name),
+			}
+			if blocks != nil {
+				diag.Detail = fmt.Sprintf("An include named %q was already declared at %s. Include names must be unique, so "+
+					"rename one of the blocks.", include.Name, blocks[existing].DefRange)
+				diag.Subject = blocks[i].DefRange.Ptr()
+			}
+			diags = append(diags, diag)
+			continue
+		}
+		declared[include.Name] = i
+
+		path := resolvePath(opts.workingDir(), include.Path)
+
+		strategy := MergeShallow
+		if include.MergeStrategy != nil {
+			strategy = MergeStrategy(*include.MergeStrategy)
+		}
+		if strategy != MergeNoMerge && strategy != MergeShallow && strategy != MergeDeep && strategy != MergeDeepMapOnly {
+			diag := &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid merge strategy",
+				Detail: fmt.Sprintf("The merge_strategy of the include %q must be one of %s, %s, %s or %s, got %q.", include.Name,
+					MergeNoMerge, MergeShallow, MergeDeep, MergeDeepMapOnly, strategy),
+			}
+			if blocks != nil {
+				diag.Subject = blocks[i].DefRange.Ptr()
+			}
+			diags = append(diags, diag)
+			continue
+		}
+
+		includes = append(includes, includeConfig{Name: include.Name, Path: path, MergeStrategy: strategy})
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return includes, nil
+}
+
+// mergeIncludedConfigs parses the configurations included by the include blocks of the given body, and merges the
+// given configuration into them, as terragrunt does: the included configurations are merged in declaration order, so
+// that later include blocks take precedence over earlier ones, and the including configuration over all of them. Each
+// include block merges with its own merge strategy. Included configurations can not include other configurations
+// themselves. The attributes set by several included configurations are reported in the Warnings of the configuration,
+// or as errors with WithStrictIncludes: see includeOverlaps.
+func mergeIncludedConfigs(config *TerragruntConfig, body hcl.Body, opts *ParseOptions) (*TerragruntConfig, error) {
+	if !hasIncludeBlocks(body) || opts.originalConfigPath != "" {
+		return config, nil
+	}
+
+	includes, err := decodeIncludes(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The configuration is merged into the last included configuration first, and the result into the previous one,
+	// so that the bottom most include blocks override the earlier ones.
+	evalContext, evalOptions := config.evalContext, config.evalOptions
+	includeValues := map[string]cty.Value{}
+	overridden, setBy := config.provenance, map[string]includeOrigin{}
+	var warnings []Finding
+	for i := len(includes) - 1; i >= 0; i-- {
+		include := includes[i]
+		if include.MergeStrategy == MergeNoMerge {
+			includeValues[include.Name] = includeValue(include, nil)
+			continue
+		}
+
+		included, err := parseIncludedConfig(include, opts)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+		includeValues[include.Name] = includeValue(include, included)
+		warnings = append(includeOverlaps(include, included, overridden, setBy), warnings...)
+		config, err = mergeConfigs(included, config, include.MergeStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+	}
+	if opts.StrictIncludes && len(warnings) > 0 {
+		return nil, includeConflicts(warnings)
+	}
+	config.Warnings = append(config.Warnings, warnings...)
+	config.evalContext, config.evalOptions = withIncludeVariable(evalContext, includeValues), evalOptions
+	return config, nil
+}
+
+// includeValue returns the value of the given include block in the context of Eval: its path, along with the locals
+// and the inputs of the included configuration, when it is merged.
+func includeValue(include includeConfig, included *TerragruntConfig) cty.Value {
+	attributes := map[string]cty.Value{
+		"path":           cty.StringVal(include.Path),
+		"merge_strategy": cty.StringVal(string(include.MergeStrategy)),
+	}
+	if included != nil {
+		if included.evalContext != nil {
+			if locals, found := included.evalContext.Variables["local"]; found {
+				attributes["locals"] = locals
+			}
+		}
+		attributes["inputs"] = cty.EmptyObjectVal
+		if len(included.InputsCty) > 0 {
+			attributes["inputs"] = cty.ObjectVal(included.InputsCty)
+		}
+	}
+	return cty.ObjectVal(attributes)
+}
+
+// withIncludeVariable returns a copy of the given evaluation context, exposing the given include values as include.
+func withIncludeVariable(evalContext *hcl.EvalContext, includeValues map[string]cty.Value) *hcl.EvalContext {
+	if evalContext == nil {
+		return nil
+	}
+	withInclude := &hcl.EvalContext{Functions: evalContext.Functions, Variables: map[string]cty.Value{}}
+	for name, value := range evalContext.Variables {
+		withInclude.Variables[name] = value
+	}
+	withInclude.Variables["include"] = cty.ObjectVal(includeValues)
+	return withInclude
+}
+
+// mergeIncludedDependencies returns the given dependency blocks of the configuration with the given body, merged with
+// the dependency blocks of the configurations it includes, as mergeIncludedConfigs merges them. This makes the
+// dependencies declared in included configurations available to the including one. The config_path of the included
+// dependency blocks is made absolute, as it is relative to the included configuration.
+func mergeIncludedDependencies(body hcl.Body, dependencies []Dependency, opts *ParseOptions) ([]Dependency, error) {
+	if !hasIncludeBlocks(body) || opts.originalConfigPath != "" {
+		return dependencies, nil
+	}
+
+	includes, err := decodeIncludes(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &TerragruntConfig{TerragruntDependencies: dependencies}
+	for i := len(includes) - 1; i >= 0; i-- {
+		include := includes[i]
+		if include.MergeStrategy == MergeNoMerge {
+			continue
+		}
+
+		content, err := opts.readFile(include.Path)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+		includedOpts := includedParseOptions(include, opts)
+		includedDependencies, err := decodeDependencyBlocks(content, includedOpts)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.Name, err)
+		}
+		for i := range includedDependencies {
+			includedDependencies[i].ConfigPath = dependencyConfigPath(includedDependencies[i], includedOpts)
+		}
+
+		config, err = mergeConfigs(&TerragruntConfig{TerragruntDependencies: includedDependencies}, config, include.MergeStrategy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config.TerragruntDependencies, nil
+}
+
+// parseIncludedConfig parses the configuration included by the given include block of the configuration being parsed
+// with the given options.
+func parseIncludedConfig(include includeConfig, opts *ParseOptions) (*TerragruntConfig, error) {
+	content, err := opts.readFile(include.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseConfig(content, includedParseOptions(include, opts))
+}
+
+// includedParseOptions returns the options to parse the configuration included by the given include block of the
+// configuration being parsed with the given options.
+func includedParseOptions(include includeConfig, opts *ParseOptions) *ParseOptions {
+	includedOpts := *opts
+	includedOpts.ConfigPath = include.Path
+	includedOpts.originalConfigPath = opts.ConfigPath
+	includedOpts.includes = nil
+	includedOpts.functions = nil
+	includedOpts.iamRoleDecoded = false
+	includedOpts.identityBeforeIAMRole = false
+	return &includedOpts
+}
+
+// mergeTerraformConfigs returns the terraform block of the base configuration, if any, with the one of the overlay
+// merged into it: the source of the overlay replaces the one of the base when set, and extra_arguments blocks are
+// merged by name, keeping the order of the base configuration first.
+func mergeTerraformConfigs(base *TerraformConfig, overlay *TerraformConfig) *TerraformConfig {
+	if base == nil {
+		return overlay
+	}
+
+	merged := *base
+	if overlay.Source != nil {
+		merged.Source = overlay.Source
+	}
+	merged.ExtraArguments = nil
+	for _, extraArguments := range base.ExtraArguments {
+		if !hasExtraArguments(overlay.ExtraArguments, extraArguments.Name) {
+			merged.ExtraArguments = append(merged.ExtraArguments, extraArguments)
+		}
+	}
+	merged.ExtraArguments = append(merged.ExtraArguments, overlay.ExtraArguments...)
+	return &merged
+}
+
+func hasExtraArguments(extraArguments []TerraformExtraArguments, name string) bool {
+	for _, candidate := range extraArguments {
+		if candidate.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeConfigs returns the given base configuration with the overlay configuration merged into it with the given
+// strategy. Neither configuration is modified.
+func mergeConfigs(base *TerragruntConfig, overlay *TerragruntConfig, strategy MergeStrategy) (*TerragruntConfig, error) {
+	merged := *base
+
+	if overlay.Terraform != nil {
+		merged.Terraform = mergeTerraformConfigs(base.Terraform, overlay.Terraform)
+	}
+	if overlay.Errors != nil {
+		merged.Errors = mergeErrorsConfigs(base.Errors, overlay.Errors)
+	}
+	if overlay.Engine != nil {
+		merged.Engine = overlay.Engine
+	}
+	if overlay.Exclude != nil {
+		merged.Exclude = overlay.Exclude
+	}
+	if overlay.Catalog != nil {
+		merged.Catalog = overlay.Catalog
+	}
+	if overlay.TerraformBinary != "" {
+		merged.TerraformBinary = overlay.TerraformBinary
+	}
+	if overlay.IamRole != "" {
+		merged.IamRole = overlay.IamRole
+	}
+	if overlay.IamAssumeRoleDuration != 0 {
+		merged.IamAssumeRoleDuration = overlay.IamAssumeRoleDuration
+	}
+	if overlay.IamAssumeRoleSessionName != "" {
+		merged.IamAssumeRoleSessionName = overlay.IamAssumeRoleSessionName
+	}
+	if len(overlay.FeatureFlags) > 0 {
+		merged.FeatureFlags = map[string]cty.Value{}
+		for name, value := range base.FeatureFlags {
+			merged.FeatureFlags[name] = value
+		}
+		for name, value := range overlay.FeatureFlags {
+			merged.FeatureFlags[name] = value
+		}
+	}
+	if _, set := overlay.provenance["skip"]; set {
+		merged.Skip = overlay.Skip
+	}
+	if _, set := overlay.provenance["prevent_destroy"]; set {
+		merged.PreventDestroy = overlay.PreventDestroy
+	}
+
+	if overlay.RemoteState != nil {
+		merged.RemoteState = overlay.RemoteState
+		if strategy.deep() && base.RemoteState != nil && base.RemoteState.Backend == overlay.RemoteState.Backend {
+			remoteState := *overlay.RemoteState
+			remoteState.Config = mergeCtyValues(base.RemoteState.Config, overlay.RemoteState.Config, strategy, true)
+			if remoteState.Encryption == nil {
+				remoteState.Encryption = base.RemoteState.Encryption
+			}
+			merged.RemoteState = &remoteState
+		}
+	}
+
+	// Dependency blocks are merged by name, keeping the order of the base configuration first.
+	merged.TerragruntDependencies = nil
+	for _, dependency := range base.TerragruntDependencies {
+		if !hasDependency(overlay.TerragruntDependencies, dependency.Name) {
+			merged.TerragruntDependencies = append(merged.TerragruntDependencies, dependency)
+		}
+	}
+	merged.TerragruntDependencies = append(merged.TerragruntDependencies, overlay.TerragruntDependencies...)
+
+	if len(overlay.GenerateConfigs) > 0 {
+		merged.GenerateConfigs = map[string]GenerateConfig{}
+		for name, generate := range base.GenerateConfigs {
+			merged.GenerateConfigs[name] = generate
+		}
+		for name, generate := range overlay.GenerateConfigs {
+			merged.GenerateConfigs[name] = generate
+		}
+	}
+
+	if len(overlay.InputsCty) > 0 {
+		merged.InputsCty = map[string]cty.Value{}
+		for name, value := range base.InputsCty {
+			merged.InputsCty[name] = value
+		}
+		for name, value := range overlay.InputsCty {
+			if baseValue, found := merged.InputsCty[name]; found && strategy.deep() {
+				value = mergeCtyValues(baseValue, value, strategy, true)
+			}
+			merged.InputsCty[name] = value
+		}
+
+		inputs, err := parseCtyValueToMap(cty.ObjectVal(merged.InputsCty))
+		if err != nil {
+			return nil, err
+		}
+		merged.Inputs = inputs
+	}
+
+	merged.UnknownBlocks = append(append([]UnknownBlock(nil), base.UnknownBlocks...), overlay.UnknownBlocks...)
+
+	merged.provenance = map[string]hcl.Range{}
+	for path, origin := range base.provenance {
+		merged.provenance[path] = origin
+	}
+	for path, origin := range overlay.provenance {
+		merged.provenance[path] = origin
+	}
+	return &merged, nil
+}
+
+func hasDependency(dependencies []Dependency, name string) bool {
+	for _, dependency := range dependencies {
+		if dependency.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package terragrunt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// writeFiles writes the given files, keyed by slash separated path, under a temporary directory, and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMergeIncludedConfigs(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		inputs   map[string]cty.Value
+		warnings []string
+	}{
+		{
+			name: "later include takes precedence",
+			files: map[string]string{
+				"root.hcl":           `inputs = { region = "us-east-1", owner = "x" }`,
+				"env.hcl":            `inputs = { region = "eu-west-1" }`,
+				"app/terragrunt.hcl": "include \"root\" {\n  path = \"../root.hcl\"\n}\ninclude \"env\" {\n  path = \"../env.hcl\"\n}\n",
+			},
+			inputs: map[string]cty.Value{
+				"region": cty.StringVal("eu-west-1"),
+				"owner":  cty.StringVal("x"),
+			},
+			warnings: []string{`inputs.region is set by both the include "root", at `},
+		},
+		{
+			name: "including configuration overrides both",
+			files: map[string]string{
+				"root.hcl":           `inputs = { region = "us-east-1" }`,
+				"env.hcl":            `inputs = { region = "eu-west-1" }`,
+				"app/terragrunt.hcl": "include \"root\" {\n  path = \"../root.hcl\"\n}\ninclude \"env\" {\n  path = \"../env.hcl\"\n}\ninputs = { region = \"ap-south-1\" }\n",
+			},
+			inputs: map[string]cty.Value{"region": cty.StringVal("ap-south-1")},
+		},
+		{
+			name: "deep include merges",
+			files: map[string]string{
+				"root.hcl":           `inputs = { tags = { team = "a" } }`,
+				"app/terragrunt.hcl": "include \"root\" {\n  path           = \"../root.hcl\"\n  merge_strategy = \"deep\"\n}\ninputs = { tags = { env = \"prod\" } }\n",
+			},
+			inputs: map[string]cty.Value{
+				"tags": cty.ObjectVal(map[string]cty.Value{"team": cty.StringVal("a"), "env": cty.StringVal("prod")}),
+			},
+		},
+		{
+			name: "deep_map_only include replaces lists",
+			files: map[string]string{
+				"root.hcl":           `inputs = { tags = { team = "a" }, zones = ["a", "b"] }`,
+				"app/terragrunt.hcl": "include \"root\" {\n  path           = \"../root.hcl\"\n  merge_strategy = \"deep_map_only\"\n}\ninputs = { tags = { env = \"prod\" }, zones = [\"c\"] }\n",
+			},
+			inputs: map[string]cty.Value{
+				"tags":  cty.ObjectVal(map[string]cty.Value{"team": cty.StringVal("a"), "env": cty.StringVal("prod")}),
+				"zones": cty.TupleVal([]cty.Value{cty.StringVal("c")}),
+			},
+		},
+		{
+			name: "no_merge include is not merged",
+			files: map[string]string{
+				"root.hcl":           `inputs = { region = "us-east-1" }`,
+				"app/terragrunt.hcl": "include \"root\" {\n  path           = \"../root.hcl\"\n  merge_strategy = \"no_merge\"\n}\ninputs = { name = \"app\" }\n",
+			},
+			inputs: map[string]cty.Value{"name": cty.StringVal("app")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, test.files)
+			config, err := ParseConfigFile(filepath.Join(dir, "app", "terragrunt.hcl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(config.InputsCty) != len(test.inputs) {
+				t.Errorf("got inputs %#v, want %#v", config.InputsCty, test.inputs)
+			}
+			for name, want := range test.inputs {
+				if got, found := config.InputsCty[name]; !found || !got.RawEquals(want) {
+					t.Errorf("input %s: got %#v, want %#v", name, got, want)
+				}
+			}
+
+			if len(config.Warnings) != len(test.warnings) {
+				t.Fatalf("got warnings %v, want %v", config.Warnings, test.warnings)
+			}
+			for i, warning := range config.Warnings {
+				if warning.RuleID != RuleIncludeOverlap || warning.Severity != SeverityWarning || !strings.Contains(warning.Message, test.warnings[i]) {
+					t.Errorf("got warning %s, want %q", warning, test.warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeIncludedConfigsConflicts(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.hcl":           `inputs = { region = "us-east-1" }`,
+		"env.hcl":            `inputs = { region = "eu-west-1" }`,
+		"app/terragrunt.hcl": "include \"root\" {\n  path = \"../root.hcl\"\n}\ninclude \"env\" {\n  path = \"../env.hcl\"\n}\n",
+	})
+	configPath := filepath.Join(dir, "app", "terragrunt.hcl")
+	locations := []string{filepath.Join(dir, "root.hcl") + ":1,", filepath.Join(dir, "env.hcl") + ":1,"}
+
+	config, err := ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Warnings) != 1 {
+		t.Fatalf("got warnings %v, want a single one", config.Warnings)
+	}
+	for _, location := range locations {
+		if !strings.Contains(config.Warnings[0].Message, location) {
+			t.Errorf("got warning %q, want it to name %s", config.Warnings[0].Message, location)
+		}
+	}
+
+	_, err = ParseConfigFile(configPath, WithStrictIncludes())
+	if err == nil || !strings.Contains(err.Error(), "Conflicting include attribute") {
+		t.Fatalf("got error %v, want a conflicting include attribute", err)
+	}
+	for _, location := range locations {
+		if !strings.Contains(err.Error(), location) {
+			t.Errorf("got error %q, want it to name %s", err, location)
+		}
+	}
+}
+
+func TestDecodeIncludesInvalidMergeStrategy(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.hcl":           `inputs = {}`,
+		"app/terragrunt.hcl": "include \"root\" {\n  path           = \"../root.hcl\"\n  merge_strategy = \"deeper\"\n}\n",
+	})
+	_, err := ParseConfigFile(filepath.Join(dir, "app", "terragrunt.hcl"))
+	if err == nil || !strings.Contains(err.Error(), "Invalid merge strategy") {
+		t.Errorf("got error %v, want an invalid merge strategy", err)
+	}
+}
+
+func TestMergeIncludedRemoteState(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     cty.Value
+	}{
+		{
+			strategy: "shallow",
+			want:     cty.ObjectVal(map[string]cty.Value{"key": cty.StringVal("app/terraform.tfstate")}),
+		},
+		{
+			strategy: "deep",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"bucket": cty.StringVal("state"),
+				"key":    cty.StringVal("app/terraform.tfstate"),
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"root.hcl": "remote_state {\n  backend = \"s3\"\n  config = { bucket = \"state\", key = \"root/terraform.tfstate\" }\n}\n",
+				"app/terragrunt.hcl": "include \"root\" {\n  path           = \"../root.hcl\"\n  merge_strategy = \"" + test.strategy + "\"\n}\n" +
+					"remote_state {\n  backend = \"s3\"\n  config = { key = \"app/terraform.tfstate\" }\n}\n",
+			})
+			config, err := ParseConfigFile(filepath.Join(dir, "app", "terragrunt.hcl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.RemoteState == nil || !config.RemoteState.Config.RawEquals(test.want) {
+				t.Errorf("got remote_state %#v, want config %#v", config.RemoteState, test.want)
+			}
+		})
+	}
+}
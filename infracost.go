@@ -0,0 +1,251 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// CostEstimate is the estimated monthly cost of a unit or of a stack.
+type CostEstimate struct {
+	Currency string
+
+	// MonthlyCost is the estimated monthly cost, and PastMonthlyCost the one of the baseline it is compared to, 0
+	// when there is none, e.g. for new units.
+	MonthlyCost     float64
+	PastMonthlyCost float64
+}
+
+// Delta returns the difference of the monthly cost with the baseline.
+func (estimate CostEstimate) Delta() float64 {
+	return estimate.MonthlyCost - estimate.PastMonthlyCost
+}
+
+// InfracostProject is a project of the output of infracost, which is a unit when infracost runs against a working
+// directory prepared for it.
+type InfracostProject struct {
+	Name string
+
+	// Path is the path of the project, as given to infracost.
+	Path string
+
+	Estimate CostEstimate
+}
+
+// InfracostOutput is the json output of infracost breakdown or infracost diff.
+type InfracostOutput struct {
+	Currency string
+	Projects []InfracostProject
+
+	// Total is the estimate of every project.
+	Total CostEstimate
+}
+
+// Project returns the project of the output at the given path, comparing the paths once cleaned.
+func (output *InfracostOutput) Project(path string) (*InfracostProject, bool) {
+	for i := range output.Projects {
+		if filepath.Clean(output.Projects[i].Path) == filepath.Clean(path) {
+			return &output.Projects[i], true
+		}
+	}
+	return nil, false
+}
+
+// infracostCost is a cost of the output of infracost, which is a decimal string, or null when unknown.
+type infracostCost string
+
+func (cost infracostCost) value() (float64, error) {
+	if cost == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(string(cost), 64)
+}
+
+type infracostTotals struct {
+	TotalMonthlyCost     *infracostCost `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *infracostCost `json:"pastTotalMonthlyCost"`
+}
+
+func (totals infracostTotals) estimate(currency string) (CostEstimate, error) {
+	estimate := CostEstimate{Currency: currency}
+	var err error
+	if totals.TotalMonthlyCost != nil {
+		if estimate.MonthlyCost, err = totals.TotalMonthlyCost.value(); err != nil {
+			return estimate, fmt.Errorf("invalid monthly cost: %w", err)
+		}
+	}
+	if totals.PastTotalMonthlyCost != nil {
+		if estimate.PastMonthlyCost, err = totals.PastTotalMonthlyCost.value(); err != nil {
+			return estimate, fmt.Errorf("invalid past monthly cost: %w", err)
+		}
+	}
+	return estimate, nil
+}
+
+type infracostJSON struct {
+	Currency string `json:"currency"`
+	Projects []struct {
+		Name     string `json:"name"`
+		Metadata struct {
+			Path string `json:"path"`
+		} `json:"metadata"`
+		infracostTotals
+	} `json:"projects"`
+	infracostTotals
+}
+
+// ParseInfracostJSON parses the output of infracost breakdown --format json or infracost diff --format json, for the
+// estimates of infracost runs made outside of this package, such as in CI. The past costs of the output of infracost
+// diff are the ones of its baseline.
+func ParseInfracostJSON(data []byte) (*InfracostOutput, error) {
+	var document infracostJSON
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid infracost output: %w", err)
+	}
+
+	output := &InfracostOutput{Currency: document.Currency}
+	var err error
+	if output.Total, err = document.infracostTotals.estimate(document.Currency); err != nil {
+		return nil, err
+	}
+	for _, project := range document.Projects {
+		estimate, err := project.infracostTotals.estimate(document.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", project.Name, err)
+		}
+		output.Projects = append(output.Projects, InfracostProject{Name: project.Name, Path: project.Metadata.Path, Estimate: estimate})
+	}
+	return output, nil
+}
+
+type infracostJSONOutput struct {
+	Version  string                 `json:"version"`
+	Currency string                 `json:"currency"`
+	Projects []infracostJSONProject `json:"projects"`
+	infracostJSONTotals
+}
+
+type infracostJSONProject struct {
+	Name     string `json:"name"`
+	Metadata struct {
+		Path string `json:"path"`
+	} `json:"metadata"`
+	infracostJSONTotals
+}
+
+type infracostJSONTotals struct {
+	PastTotalMonthlyCost string `json:"pastTotalMonthlyCost"`
+	TotalMonthlyCost     string `json:"totalMonthlyCost"`
+	DiffTotalMonthlyCost string `json:"diffTotalMonthlyCost"`
+}
+
+func newInfracostJSONTotals(estimate CostEstimate) infracostJSONTotals {
+	format := func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	return infracostJSONTotals{
+		PastTotalMonthlyCost: format(estimate.PastMonthlyCost),
+		TotalMonthlyCost:     format(estimate.MonthlyCost),
+		DiffTotalMonthlyCost: format(estimate.Delta()),
+	}
+}
+
+// WriteInfracostJSON writes the given output in the json format of infracost diff, with the costs of its projects and
+// its totals, so that ParseInfracostJSON and infracost can read it back, e.g. as the baseline of a later estimate.
+func WriteInfracostJSON(w io.Writer, output *InfracostOutput) error {
+	document := infracostJSONOutput{
+		Version:             "0.2",
+		Currency:            output.Currency,
+		Projects:            []infracostJSONProject{},
+		infracostJSONTotals: newInfracostJSONTotals(output.Total),
+	}
+	for _, project := range output.Projects {
+		rendered := infracostJSONProject{Name: project.Name, infracostJSONTotals: newInfracostJSONTotals(project.Estimate)}
+		rendered.Metadata.Path = project.Path
+		document.Projects = append(document.Projects, rendered)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// Infracost estimates the monthly cost of units by running infracost breakdown against a working directory prepared
+// for each of them by PrepareWorkingDir. Infracost reads its api key from the environment, as INFRACOST_API_KEY.
+type Infracost struct {
+	// Command is the infracost binary to run. Defaults to infracost.
+	Command string
+
+	// Args are additional arguments of infracost breakdown, e.g. --usage-file.
+	Args []string
+
+	// Runner runs infracost. Defaults to ExecCommandRunner.
+	Runner CommandRunner
+
+	// Fetcher fetches the modules of the units. Defaults to a SourceFetcher with its default settings.
+	Fetcher *SourceFetcher
+
+	// TempDir is the directory the working directories are created in, and removed from once estimated. Defaults to
+	// the default directory for temporary files.
+	TempDir string
+}
+
+// EstimateUnit returns the estimated monthly cost of the given unit, as of its configuration and module. The estimate
+// has no past cost: compare it with the estimate of a baseline, e.g. the one of the base branch of a pull request,
+// with CompareCosts.
+func (infracost *Infracost) EstimateUnit(ctx context.Context, unit *Unit) (*CostEstimate, error) {
+	dir, err := os.MkdirTemp(infracost.TempDir, "infracost-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	fetcher := infracost.Fetcher
+	if fetcher == nil {
+		fetcher = &SourceFetcher{}
+	}
+	if err := PrepareWorkingDir(ctx, fetcher, unit, dir); err != nil {
+		return nil, err
+	}
+
+	command := infracost.Command
+	if command == "" {
+		command = "infracost"
+	}
+	args := append([]string{"breakdown", "--path", dir, "--format", "json", "--no-color"}, infracost.Args...)
+	output, err := commandRunner(infracost.Runner).Run(ctx, Command{Name: command, Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("infracost breakdown: %w: %s", err, output.Stderr)
+	}
+	parsed, err := ParseInfracostJSON(output.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed.Total, nil
+}
+
+// CompareCosts returns the given estimates, keyed by unit path, with the monthly costs of the given baseline, such as
+// the estimates of the base branch of a pull request, as their past costs. The units missing from the baseline are
+// new, with no past cost, and the units of the baseline that are missing from the estimates are removed, with no
+// monthly cost.
+func CompareCosts(estimates map[string]*CostEstimate, baseline map[string]*CostEstimate) map[string]*CostEstimate {
+	compared := map[string]*CostEstimate{}
+	for path, estimate := range estimates {
+		comparedEstimate := *estimate
+		comparedEstimate.PastMonthlyCost = 0
+		if base, found := baseline[path]; found {
+			comparedEstimate.PastMonthlyCost = base.MonthlyCost
+		}
+		compared[path] = &comparedEstimate
+	}
+	for path, base := range baseline {
+		if _, found := estimates[path]; !found {
+			compared[path] = &CostEstimate{Currency: base.Currency, PastMonthlyCost: base.MonthlyCost}
+		}
+	}
+	return compared
+}
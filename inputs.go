@@ -0,0 +1,129 @@
+package terragrunt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// InputDecodeError is returned by DecodeInputs when an input can not be decoded into the requested Go type. Key is the
+// dotted path of the offending input, relative to the inputs attribute.
+type InputDecodeError struct {
+	Key  string
+	Type reflect.Type
+	Err  error
+}
+
+func (err InputDecodeError) Error() string {
+	if err.Key == "" {
+		return fmt.Sprintf("failed to decode inputs into %s: %s", err.Type, err.Err)
+	}
+	return fmt.Sprintf("failed to decode input %q into %s: %s", err.Key, err.Type, err.Err)
+}
+
+func (err InputDecodeError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeInputs decodes the inputs of the given configuration into a value of type T, which is typically a struct with
+// cty tags. When keys are given, the input found by following them (e.g. "tags", "team") is decoded instead of the
+// whole inputs object. When decoding into a struct, inputs that have no matching cty tag are ignored, so that a struct
+// only needs to declare the inputs it is interested in.
+func DecodeInputs[T any](config *TerragruntConfig, keys ...string) (T, error) {
+	var out T
+	targetType := reflect.TypeOf(&out).Elem()
+
+	value := cty.EmptyObjectVal
+	if len(config.InputsCty) > 0 {
+		value = cty.ObjectVal(config.InputsCty)
+	}
+
+	for i, key := range keys {
+		value, _ = value.Unmark()
+		valueType := value.Type()
+		if value.IsNull() || !value.IsKnown() || !(valueType.IsObjectType() || valueType.IsMapType()) {
+			return out, InputDecodeError{Key: strings.Join(keys[:i+1], "."), Type: targetType, Err: fmt.Errorf("%s is not an object", strings.Join(keys[:i], "."))}
+		}
+		element, found := value.AsValueMap()[key]
+		if !found {
+			return out, InputDecodeError{Key: strings.Join(keys[:i+1], "."), Type: targetType, Err: fmt.Errorf("input not found")}
+		}
+		value = element
+	}
+
+	value = withoutUntaggedAttributes(value, targetType)
+
+	// HCL object literals are only decoded into Go maps and slices once converted to the type implied by the target,
+	// which also takes care of conversions such as numbers to strings. When the conversion is not possible, the
+	// value is decoded as is so that the error reported by gocty names the offending input.
+	if impliedType, err := gocty.ImpliedType(out); err == nil {
+		if converted, err := convert.Convert(value, impliedType); err == nil {
+			value = converted
+		}
+	}
+
+	if err := gocty.FromCtyValue(value, &out); err != nil {
+		key := strings.Join(keys, ".")
+		if pathErr, isPathErr := err.(cty.PathError); isPathErr {
+			key = joinInputKey(key, formatCtyPath(pathErr.Path))
+		}
+		return out, InputDecodeError{Key: key, Type: targetType, Err: err}
+	}
+
+	return out, nil
+}
+
+// withoutUntaggedAttributes returns the given object value without the attributes that have no matching cty tag on
+// the target struct type. Values that are not objects, or targets that are not structs, are returned as is.
+func withoutUntaggedAttributes(value cty.Value, targetType reflect.Type) cty.Value {
+	if targetType.Kind() != reflect.Struct || value.IsNull() || !value.IsKnown() || !value.Type().IsObjectType() {
+		return value
+	}
+
+	tags := map[string]bool{}
+	for i := 0; i < targetType.NumField(); i++ {
+		if tag := targetType.Field(i).Tag.Get("cty"); tag != "" {
+			tags[tag] = true
+		}
+	}
+
+	attributes := map[string]cty.Value{}
+	for name, attribute := range value.AsValueMap() {
+		if tags[name] {
+			attributes[name] = attribute
+		}
+	}
+	return cty.ObjectVal(attributes)
+}
+
+// formatCtyPath renders the given cty path using the dotted notation of DecodeInputs keys.
+func formatCtyPath(path cty.Path) string {
+	var parts []string
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			parts = append(parts, step.Name)
+		case cty.IndexStep:
+			if step.Key.Type() == cty.String {
+				parts = append(parts, step.Key.AsString())
+			} else {
+				parts = append(parts, step.Key.AsBigFloat().Text('f', -1))
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func joinInputKey(prefix string, suffix string) string {
+	switch {
+	case prefix == "":
+		return suffix
+	case suffix == "":
+		return prefix
+	}
+	return prefix + "." + suffix
+}
@@ -0,0 +1,698 @@
+package terragrunt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// LegacyKind is the kind of a LegacyValue.
+type LegacyKind string
+
+const (
+	LegacyString LegacyKind = "string"
+	LegacyNumber LegacyKind = "number"
+	LegacyBool   LegacyKind = "bool"
+	LegacyList   LegacyKind = "list"
+	LegacyObject LegacyKind = "object"
+)
+
+// LegacyValue is a value of a terraform.tfvars file in the HCL1 syntax.
+type LegacyValue struct {
+	Kind LegacyKind
+
+	// Scalar is the content of strings, with their escape sequences resolved but their interpolations (${...}) kept
+	// as written, or the text of numbers and bools.
+	Scalar string
+
+	// List holds the elements of lists, and Object the items of objects.
+	List   []LegacyValue
+	Object []LegacyItem
+}
+
+// LegacyItem is an item of an object of a terraform.tfvars file in the HCL1 syntax: a key, along with the labels
+// following it for blocks (e.g. extra_arguments "retry" { ... }), and its value.
+type LegacyItem struct {
+	Keys  []string
+	Value LegacyValue
+	Line  int
+}
+
+// LegacyTFVars is a terraform.tfvars file in the format used before terragrunt 0.19, where the configuration of
+// terragrunt is set by a terragrunt = { ... } variable, next to the inputs of the module.
+type LegacyTFVars struct {
+	// Terragrunt holds the items of the terragrunt variable, or is nil when the file has none.
+	Terragrunt []LegacyItem
+
+	// Inputs holds the other variables of the file.
+	Inputs []LegacyItem
+}
+
+// ParseLegacyTFVars parses the given terraform.tfvars content, written in the HCL1 syntax of terraform 0.11, with its
+// terragrunt configuration in a terragrunt variable.
+func ParseLegacyTFVars(content []byte) (*LegacyTFVars, error) {
+	tokens, err := lexLegacy(string(normalizeLineEndings(content)))
+	if err != nil {
+		return nil, err
+	}
+	parser := &legacyParser{tokens: tokens}
+	items, err := parser.parseObjectItems(legacyEOF)
+	if err != nil {
+		return nil, err
+	}
+
+	tfvars := &LegacyTFVars{}
+	for _, item := range items {
+		if len(item.Keys) == 1 && item.Keys[0] == "terragrunt" {
+			if item.Value.Kind != LegacyObject {
+				return nil, fmt.Errorf("line %d: terragrunt must be an object", item.Line)
+			}
+			tfvars.Terragrunt = append(tfvars.Terragrunt, item.Value.Object...)
+			continue
+		}
+		tfvars.Inputs = append(tfvars.Inputs, item)
+	}
+	return tfvars, nil
+}
+
+// ConvertLegacyTFVars converts the given terraform.tfvars content, in the format used before terragrunt 0.19, to the
+// equivalent terragrunt.hcl content, as described by the terragrunt 0.19 migration guide: the terragrunt variable
+// becomes the top-level configuration, remote_state config blocks become attributes, the other variables become
+// inputs, interpolations become expressions, and get_tfvars_dir() and get_parent_tfvars_dir() are renamed to
+// get_terragrunt_dir() and get_parent_terragrunt_dir(). Includes still point to the parent configuration with
+// find_in_parent_folders(), which must be migrated too.
+func ConvertLegacyTFVars(content []byte) ([]byte, error) {
+	tfvars, err := ParseLegacyTFVars(content)
+	if err != nil {
+		return nil, err
+	}
+	return tfvars.Convert()
+}
+
+// legacyBlocks are the keys of the terragrunt variable that are blocks in terragrunt.hcl, along with the keys of their
+// own items that are blocks.
+var legacyBlocks = map[string]map[string]bool{
+	"terraform":    {"extra_arguments": true, "before_hook": true, "after_hook": true},
+	"remote_state": {},
+	"include":      {},
+	"dependencies": {},
+}
+
+// Convert returns the terragrunt.hcl content equivalent to the legacy terraform.tfvars file (see ConvertLegacyTFVars).
+func (tfvars *LegacyTFVars) Convert() ([]byte, error) {
+	var out strings.Builder
+	for _, item := range tfvars.Terragrunt {
+		key := item.Keys[0]
+		nestedBlocks, isBlock := legacyBlocks[key]
+		if !isBlock {
+			if err := writeLegacyAttribute(&out, item); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(item.Keys) > 1 || item.Value.Kind != LegacyObject {
+			return nil, fmt.Errorf("line %d: %s must be a block", item.Line, key)
+		}
+
+		out.WriteString(key + " {\n")
+		for _, nested := range item.Value.Object {
+			nestedKey := nested.Keys[0]
+			switch {
+			case nestedBlocks[nestedKey]:
+				if len(nested.Keys) != 2 || nested.Value.Kind != LegacyObject {
+					return nil, fmt.Errorf("line %d: %s must be a block with a name", nested.Line, nestedKey)
+				}
+				out.WriteString(fmt.Sprintf("%s %q {\n", nestedKey, nested.Keys[1]))
+				for _, attribute := range nested.Value.Object {
+					if err := writeLegacyAttribute(&out, attribute); err != nil {
+						return nil, err
+					}
+				}
+				out.WriteString("}\n")
+			default:
+				if err := writeLegacyAttribute(&out, nested); err != nil {
+					return nil, err
+				}
+			}
+		}
+		out.WriteString("}\n\n")
+	}
+
+	if len(tfvars.Inputs) > 0 {
+		out.WriteString("\ninputs = ")
+		if err := writeLegacyObject(&out, tfvars.Inputs); err != nil {
+			return nil, err
+		}
+		out.WriteString("\n")
+	}
+
+	formatted, err := Format([]byte(out.String()))
+	if err != nil {
+		return nil, fmt.Errorf("the converted configuration is invalid: %w", err)
+	}
+	return formatted, nil
+}
+
+// writeLegacyAttribute writes the given item as an attribute. Items with labels (e.g. config "x" { ... }) become
+// nested objects.
+func writeLegacyAttribute(out *strings.Builder, item LegacyItem) error {
+	out.WriteString(item.Keys[0] + " = ")
+	if err := writeLegacyValue(out, nestLegacyLabels(item)); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	return nil
+}
+
+// nestLegacyLabels returns the value of the given item, wrapped in an object per label, as HCL1 decodes them.
+func nestLegacyLabels(item LegacyItem) LegacyValue {
+	value := item.Value
+	for i := len(item.Keys) - 1; i >= 1; i-- {
+		value = LegacyValue{Kind: LegacyObject, Object: []LegacyItem{{Keys: []string{item.Keys[i]}, Value: value, Line: item.Line}}}
+	}
+	return value
+}
+
+func writeLegacyValue(out *strings.Builder, value LegacyValue) error {
+	switch value.Kind {
+	case LegacyString:
+		return writeLegacyString(out, value.Scalar)
+	case LegacyNumber, LegacyBool:
+		out.WriteString(value.Scalar)
+	case LegacyList:
+		if len(value.List) == 1 && value.List[0].Kind == LegacyString && legacyListFunctionRegexp.MatchString(value.List[0].Scalar) {
+			// HCL1 flattened the lists returned by interpolations in lists, e.g. ["${get_terraform_commands_that_need_vars()}"].
+			return writeLegacyString(out, value.List[0].Scalar)
+		}
+		out.WriteString("[")
+		for i, element := range value.List {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			if err := writeLegacyValue(out, element); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case LegacyObject:
+		return writeLegacyObject(out, value.Object)
+	}
+	return nil
+}
+
+// writeLegacyObject writes the given items as an object. Keys repeated by several items become a list of their values,
+// as HCL1 decodes them.
+func writeLegacyObject(out *strings.Builder, items []LegacyItem) error {
+	var keys []string
+	values := map[string][]LegacyValue{}
+	for _, item := range items {
+		key := item.Keys[0]
+		if _, found := values[key]; !found {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], nestLegacyLabels(item))
+	}
+
+	out.WriteString("{\n")
+	for _, key := range keys {
+		if hclsyntax.ValidIdentifier(key) {
+			out.WriteString(key)
+		} else {
+			out.WriteString(fmt.Sprintf("%q", key))
+		}
+		out.WriteString(" = ")
+		value := values[key][0]
+		if len(values[key]) > 1 {
+			value = LegacyValue{Kind: LegacyList, List: values[key]}
+		}
+		if err := writeLegacyValue(out, value); err != nil {
+			return err
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("}")
+	return nil
+}
+
+// legacyFunctionRegexp matches the calls of the functions renamed by terragrunt 0.19.
+var legacyFunctionRegexp = regexp.MustCompile(`\bget_(parent_)?tfvars_dir\(`)
+
+// legacyListFunctionRegexp matches the strings calling a terragrunt function returning a list.
+var legacyListFunctionRegexp = regexp.MustCompile(`^\$\{\s*get_terraform_commands_that_need_[a-z_]+\(\)\s*\}$`)
+
+// convertLegacyExpression converts the given interpolation of HCL1 to an HCL2 expression.
+func convertLegacyExpression(expression string) string {
+	return legacyFunctionRegexp.ReplaceAllString(expression, "get_${1}terragrunt_dir(")
+}
+
+// writeLegacyString writes the given string as an HCL2 template, or as a bare expression when it is a single
+// interpolation (e.g. "${find_in_parent_folders()}").
+func writeLegacyString(out *strings.Builder, scalar string) error {
+	parts, err := splitLegacyInterpolations(scalar)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 1 && parts[0].interpolation {
+		out.WriteString(convertLegacyExpression(parts[0].text))
+		return nil
+	}
+
+	// Multi-line strings are written as heredocs, as they usually were in HCL1.
+	heredoc := strings.Contains(strings.TrimSuffix(scalar, "\n"), "\n") && strings.HasSuffix(scalar, "\n")
+	marker := "EOT"
+	for strings.Contains(scalar, marker) {
+		marker += "T"
+	}
+	if heredoc {
+		out.WriteString("<<" + marker + "\n")
+	} else {
+		out.WriteString(`"`)
+	}
+	for _, part := range parts {
+		if part.interpolation {
+			out.WriteString("${" + convertLegacyExpression(part.text) + "}")
+			continue
+		}
+		literal := strings.ReplaceAll(part.text, "%{", "%%{")
+		if !heredoc {
+			literal = strings.ReplaceAll(literal, `\`, `\\`)
+			literal = strings.ReplaceAll(literal, `"`, `\"`)
+			literal = strings.ReplaceAll(literal, "\n", `\n`)
+			literal = strings.ReplaceAll(literal, "\r", `\r`)
+			literal = strings.ReplaceAll(literal, "\t", `\t`)
+		}
+		out.WriteString(literal)
+	}
+	if heredoc {
+		out.WriteString(marker)
+	} else {
+		out.WriteString(`"`)
+	}
+	return nil
+}
+
+// legacyStringPart is a literal part of a string, or an interpolation, without its delimiters.
+type legacyStringPart struct {
+	text          string
+	interpolation bool
+}
+
+// splitLegacyInterpolations splits the given string in its literal parts and its interpolations. Escaped
+// interpolations ($${) are kept as is in the literal parts, as HCL2 escapes them the same way.
+func splitLegacyInterpolations(scalar string) ([]legacyStringPart, error) {
+	var parts []legacyStringPart
+	var literal strings.Builder
+	for i := 0; i < len(scalar); i++ {
+		switch {
+		case strings.HasPrefix(scalar[i:], "$${"):
+			literal.WriteString("$${")
+			i += 2
+		case strings.HasPrefix(scalar[i:], "${"):
+			end := legacyInterpolationEnd(scalar, i+2)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated interpolation in %q", scalar)
+			}
+			if literal.Len() > 0 {
+				parts = append(parts, legacyStringPart{text: literal.String()})
+				literal.Reset()
+			}
+			parts = append(parts, legacyStringPart{text: strings.TrimSpace(scalar[i+2 : end]), interpolation: true})
+			i = end
+		default:
+			literal.WriteByte(scalar[i])
+		}
+	}
+	if literal.Len() > 0 || len(parts) == 0 {
+		parts = append(parts, legacyStringPart{text: literal.String()})
+	}
+	return parts, nil
+}
+
+// legacyInterpolationEnd returns the index of the brace closing the interpolation starting at the given index, or -1.
+func legacyInterpolationEnd(text string, start int) int {
+	depth := 0
+	inString := false
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// legacyTokenKind is the kind of a token of the HCL1 syntax.
+type legacyTokenKind int
+
+const (
+	legacyEOF legacyTokenKind = iota
+	legacyIdent
+	legacyStringToken
+	legacyNumberToken
+	legacyBoolToken
+	legacyLBrace
+	legacyRBrace
+	legacyLBracket
+	legacyRBracket
+	legacyEqual
+	legacyComma
+)
+
+type legacyToken struct {
+	kind legacyTokenKind
+	text string
+	line int
+}
+
+var legacyPunctuation = map[byte]legacyTokenKind{
+	'{': legacyLBrace,
+	'}': legacyRBrace,
+	'[': legacyLBracket,
+	']': legacyRBracket,
+	'=': legacyEqual,
+	',': legacyComma,
+}
+
+var legacyNumberRegexp = regexp.MustCompile(`^-?(0x[0-9a-fA-F]+|[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?)`)
+
+// lexLegacy splits the given HCL1 content in tokens. Strings and heredocs are returned with their escape sequences
+// resolved, but their interpolations kept as written.
+func lexLegacy(src string) ([]legacyToken, error) {
+	var tokens []legacyToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == '=' || c == ',':
+			tokens = append(tokens, legacyToken{kind: legacyPunctuation[c], text: string(c), line: line})
+			i++
+		case c == '"':
+			text, end, err := lexLegacyString(src, i+1)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			tokens = append(tokens, legacyToken{kind: legacyStringToken, text: text, line: line})
+			line += strings.Count(src[i:end], "\n")
+			i = end
+		case strings.HasPrefix(src[i:], "<<"):
+			text, end, err := lexLegacyHeredoc(src, i+2)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			tokens = append(tokens, legacyToken{kind: legacyStringToken, text: text, line: line})
+			line += strings.Count(src[i:end], "\n")
+			i = end
+		case legacyNumberRegexp.MatchString(src[i:]) && (c == '-' || (c >= '0' && c <= '9')):
+			number := legacyNumberRegexp.FindString(src[i:])
+			tokens = append(tokens, legacyToken{kind: legacyNumberToken, text: number, line: line})
+			i += len(number)
+		case isLegacyIdentByte(c):
+			start := i
+			for i < len(src) && isLegacyIdentByte(src[i]) {
+				i++
+			}
+			kind := legacyIdent
+			if ident := src[start:i]; ident == "true" || ident == "false" {
+				kind = legacyBoolToken
+			}
+			tokens = append(tokens, legacyToken{kind: kind, text: src[start:i], line: line})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return append(tokens, legacyToken{kind: legacyEOF, line: line}), nil
+}
+
+func isLegacyIdentByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lexLegacyString reads the string starting after its opening quote at the given index, and returns its content and
+// the index following its closing quote. Quotes within interpolations do not close the string.
+func lexLegacyString(src string, start int) (string, int, error) {
+	var text strings.Builder
+	depth := 0
+	for i := start; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src):
+			i++
+			escaped := src[i]
+			if depth > 0 {
+				// Within interpolations, only the escaped quotes of old configurations are unescaped.
+				if escaped != '"' {
+					text.WriteByte('\\')
+				}
+				text.WriteByte(escaped)
+				continue
+			}
+			switch escaped {
+			case 'n':
+				text.WriteByte('\n')
+			case 't':
+				text.WriteByte('\t')
+			case 'r':
+				text.WriteByte('\r')
+			default:
+				text.WriteByte(escaped)
+			}
+		case depth == 0 && c == '"':
+			return text.String(), i + 1, nil
+		case depth == 0 && c == '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case depth > 0 && c == '"':
+			// A string within an interpolation, copied as is.
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			text.WriteString(src[i : end+1])
+			i = end
+		case strings.HasPrefix(src[i:], "$${") && depth == 0:
+			text.WriteString("$${")
+			i += 2
+		case strings.HasPrefix(src[i:], "${"):
+			depth++
+			text.WriteString("${")
+			i++
+		case depth > 0 && c == '{':
+			depth++
+			text.WriteByte(c)
+		case depth > 0 && c == '}':
+			depth--
+			text.WriteByte(c)
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexLegacyHeredoc reads the heredoc whose marker starts at the given index, and returns its content and the index
+// following its closing marker. The indentation of <<- heredocs is removed.
+func lexLegacyHeredoc(src string, start int) (string, int, error) {
+	indented := strings.HasPrefix(src[start:], "-")
+	if indented {
+		start++
+	}
+	lineEnd := strings.IndexByte(src[start:], '\n')
+	if lineEnd < 0 {
+		return "", 0, fmt.Errorf("unterminated heredoc")
+	}
+	marker := strings.TrimSpace(src[start : start+lineEnd])
+	if marker == "" {
+		return "", 0, fmt.Errorf("missing heredoc marker")
+	}
+
+	var lines []string
+	i := start + lineEnd + 1
+	for i <= len(src) {
+		end := strings.IndexByte(src[i:], '\n')
+		if end < 0 {
+			end = len(src) - i
+		}
+		current := src[i : i+end]
+		if strings.TrimSpace(current) == marker {
+			if indented {
+				lines = unindentLegacyLines(lines)
+			}
+			content := ""
+			if len(lines) > 0 {
+				content = strings.Join(lines, "\n") + "\n"
+			}
+			return content, i + end, nil
+		}
+		lines = append(lines, strings.TrimSuffix(current, "\r"))
+		i += end + 1
+	}
+	return "", 0, fmt.Errorf("unterminated heredoc %s", marker)
+}
+
+// unindentLegacyLines removes the indentation shared by the given non-blank lines.
+func unindentLegacyLines(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	unindented := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		unindented[i] = line
+	}
+	return unindented
+}
+
+type legacyParser struct {
+	tokens []legacyToken
+	pos    int
+}
+
+func (parser *legacyParser) peek() legacyToken {
+	return parser.tokens[parser.pos]
+}
+
+func (parser *legacyParser) next() legacyToken {
+	token := parser.tokens[parser.pos]
+	if token.kind != legacyEOF {
+		parser.pos++
+	}
+	return token
+}
+
+// parseObjectItems parses the items of an object, up to the given closing token, which is consumed.
+func (parser *legacyParser) parseObjectItems(closing legacyTokenKind) ([]LegacyItem, error) {
+	var items []LegacyItem
+	for {
+		token := parser.peek()
+		switch token.kind {
+		case closing:
+			parser.next()
+			return items, nil
+		case legacyComma:
+			parser.next()
+			continue
+		case legacyEOF:
+			return nil, fmt.Errorf("line %d: unexpected end of file", token.line)
+		}
+
+		item, err := parser.parseObjectItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// parseObjectItem parses an item of an object: keys, followed by = and a value, or by an object.
+func (parser *legacyParser) parseObjectItem() (LegacyItem, error) {
+	item := LegacyItem{Line: parser.peek().line}
+	for {
+		token := parser.peek()
+		if token.kind != legacyIdent && token.kind != legacyStringToken {
+			break
+		}
+		item.Keys = append(item.Keys, parser.next().text)
+	}
+	if len(item.Keys) == 0 {
+		return item, fmt.Errorf("line %d: expected a key, got %q", item.Line, parser.peek().text)
+	}
+
+	token := parser.peek()
+	switch token.kind {
+	case legacyEqual:
+		if len(item.Keys) > 1 {
+			return item, fmt.Errorf("line %d: unexpected = after the labels of %s", token.line, item.Keys[0])
+		}
+		parser.next()
+	case legacyLBrace:
+	default:
+		return item, fmt.Errorf("line %d: expected = or { after %s, got %q", token.line, item.Keys[0], token.text)
+	}
+
+	value, err := parser.parseValue()
+	if err != nil {
+		return item, err
+	}
+	item.Value = value
+	return item, nil
+}
+
+func (parser *legacyParser) parseValue() (LegacyValue, error) {
+	token := parser.next()
+	switch token.kind {
+	case legacyStringToken:
+		return LegacyValue{Kind: LegacyString, Scalar: token.text}, nil
+	case legacyNumberToken:
+		return LegacyValue{Kind: LegacyNumber, Scalar: token.text}, nil
+	case legacyBoolToken:
+		return LegacyValue{Kind: LegacyBool, Scalar: token.text}, nil
+	case legacyLBrace:
+		items, err := parser.parseObjectItems(legacyRBrace)
+		if err != nil {
+			return LegacyValue{}, err
+		}
+		return LegacyValue{Kind: LegacyObject, Object: items}, nil
+	case legacyLBracket:
+		value := LegacyValue{Kind: LegacyList}
+		for {
+			switch parser.peek().kind {
+			case legacyRBracket:
+				parser.next()
+				return value, nil
+			case legacyComma:
+				parser.next()
+				continue
+			}
+			element, err := parser.parseValue()
+			if err != nil {
+				return LegacyValue{}, err
+			}
+			value.List = append(value.List, element)
+		}
+	case legacyEOF:
+		return LegacyValue{}, fmt.Errorf("line %d: unexpected end of file", token.line)
+	}
+	return LegacyValue{}, fmt.Errorf("line %d: unexpected %q", token.line, token.text)
+}
@@ -0,0 +1,150 @@
+package terragrunt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestConvertLegacyTFVars(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{
+			name: "inputs",
+			src:  "region = \"us-east-1\"\ninstances = 3\nzones = [\"a\", \"b\"]\n",
+			want: "\ninputs = {\n  region    = \"us-east-1\"\n  instances = 3\n  zones     = [\"a\", \"b\"]\n}\n",
+		},
+		{
+			name: "terragrunt variable",
+			src: `terragrunt = {
+  terraform {
+    source = "git::https://example.com/modules.git//vpc"
+
+    extra_arguments "retry" {
+      commands  = ["${get_terraform_commands_that_need_locking()}"]
+      arguments = ["-lock-timeout=20m"]
+    }
+  }
+
+  include {
+    path = "${find_in_parent_folders()}"
+  }
+
+  remote_state {
+    backend = "s3"
+    config {
+      bucket = "state"
+      key    = "${path_relative_to_include()}/terraform.tfstate"
+    }
+  }
+}
+
+common = "${get_parent_tfvars_dir()}/common.tfvars"
+`,
+			want: `terraform {
+  source = "git::https://example.com/modules.git//vpc"
+  extra_arguments "retry" {
+    commands  = get_terraform_commands_that_need_locking()
+    arguments = ["-lock-timeout=20m"]
+  }
+}
+
+include {
+  path = find_in_parent_folders()
+}
+
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "state"
+    key    = "${path_relative_to_include()}/terraform.tfstate"
+  }
+}
+
+
+inputs = {
+  common = "${get_parent_terragrunt_dir()}/common.tfvars"
+}
+`,
+		},
+		{
+			name: "heredoc",
+			src:  "policy = <<EOF\n{\"Version\": \"2012-10-17\"}\nline\nEOF\n",
+			want: "\ninputs = {\n  policy = <<EOT\n{\"Version\": \"2012-10-17\"}\nline\nEOT\n}\n",
+		},
+		{
+			name: "comments and escapes",
+			src:  "# comment\n/* block\ncomment */\nname = \"a \\\"quoted\\\" name\" // trailing\n",
+			want: "\ninputs = {\n  name = \"a \\\"quoted\\\" name\"\n}\n",
+		},
+		{
+			name: "terragrunt not an object",
+			src:  "terragrunt = \"vpc\"\n",
+			err:  "line 1: terragrunt must be an object",
+		},
+		{
+			name: "remote_state not a block",
+			src:  "terragrunt = {\n  remote_state = \"s3\"\n}\n",
+			err:  "line 2: remote_state must be a block",
+		},
+		{
+			name: "unterminated string",
+			src:  "name = \"vpc\n",
+			err:  "line 1: unterminated string",
+		},
+		{
+			name: "unterminated comment",
+			src:  "/* comment\n",
+			err:  "line 1: unterminated comment",
+		},
+		{
+			name: "missing value",
+			src:  "name =",
+			err:  "line 1: unexpected end of file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ConvertLegacyTFVars([]byte(test.src))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Format([]byte(test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestConvertLegacyTFVarsParses(t *testing.T) {
+	converted, err := ConvertLegacyTFVars([]byte("region = \"us-east-1\"\nzones = [\"a\", \"b\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(converted)
+	if err != nil {
+		t.Fatalf("the converted configuration does not parse: %v", err)
+	}
+	if region := config.InputsCty["region"]; !region.RawEquals(cty.StringVal("us-east-1")) {
+		t.Errorf("got region %#v, want us-east-1", region)
+	}
+	if zones := config.InputsCty["zones"]; !zones.RawEquals(cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")})) {
+		t.Errorf("got zones %#v, want [a b]", zones)
+	}
+}
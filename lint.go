@@ -0,0 +1,145 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Severity is the severity of a Finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is an issue reported by a lint check, located in the configuration by its range.
+type Finding struct {
+	RuleID   string
+	Severity Severity
+	Message  string
+	Range    hcl.Range
+}
+
+func (finding Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", finding.Range, finding.Severity, finding.Message, finding.RuleID)
+}
+
+const (
+	RuleUnusedDependency        = "unused-dependency"
+	RuleUnusedLocal             = "unused-local"
+	RuleDuplicateDependencyPath = "duplicate-dependency-path"
+)
+
+// Lint statically checks the given terragrunt configuration content and returns the findings, ordered by position.
+// It reports dependency blocks and locals that are never referenced, and dependency blocks targeting the same unit as a
+// previous one. Use WithConfigPath to set the filename reported
+// in the finding ranges.
+func Lint(content []byte, opts ...Option) ([]Finding, error) {
+	parseOptions := newParseOptions(opts)
+
+	file, diags := hclparse.NewParser().ParseHCL(content, parseOptions.ConfigPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	findings := findUnusedDeclarations(body)
+	findings = append(findings, findDuplicateDependencyPaths(body, parseOptions)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Range.Start.Byte < findings[j].Range.Start.Byte
+	})
+	return findings, nil
+}
+
+// findUnusedDeclarations reports the dependency blocks and locals of the given body that are not referenced by any
+// expression, including the ones in generate blocks and other locals.
+func findUnusedDeclarations(body *hclsyntax.Body) []Finding {
+	usedDependencies, allDependenciesUsed := map[string]bool{}, false
+	usedLocals, allLocalsUsed := map[string]bool{}, false
+	for _, attribute := range analyzeReferences(body) {
+		for _, variable := range attribute.Variables {
+			name, isName := "", false
+			if len(variable.Traversal) > 1 {
+				name, isName = traversalStepName(variable.Traversal[1])
+			}
+
+			switch variable.Traversal.RootName() {
+			case "dependency":
+				usedDependencies[name] = true
+				allDependenciesUsed = allDependenciesUsed || !isName
+			case "local":
+				// A local referencing itself does not count as a use.
+				if attribute.Path != "locals."+name {
+					usedLocals[name] = true
+				}
+				allLocalsUsed = allLocalsUsed || !isName
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, block := range body.Blocks {
+		switch {
+		case block.Type == "dependency" && len(block.Labels) > 0 && !allDependenciesUsed && !usedDependencies[block.Labels[0]]:
+			findings = append(findings, Finding{
+				RuleID:   RuleUnusedDependency,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("dependency %q is never referenced", block.Labels[0]),
+				Range:    block.DefRange(),
+			})
+		case block.Type == "locals" && !allLocalsUsed:
+			for name, attribute := range block.Body.Attributes {
+				if usedLocals[name] {
+					continue
+				}
+				findings = append(findings, Finding{
+					RuleID:   RuleUnusedLocal,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("local %q is never referenced", name),
+					Range:    attribute.NameRange,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// findDuplicateDependencyPaths reports the dependency blocks of the given body whose config_path resolves to the same
+// unit as a previous dependency block. Only static config paths are compared.
+func findDuplicateDependencyPaths(body *hclsyntax.Body, opts *ParseOptions) []Finding {
+	var findings []Finding
+	declared := map[string]string{}
+	for _, block := range body.Blocks {
+		if block.Type != "dependency" || len(block.Labels) == 0 {
+			continue
+		}
+		attribute, found := block.Body.Attributes["config_path"]
+		if !found {
+			continue
+		}
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() || !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+			continue
+		}
+
+		path := configDir(dependencyConfigPath(Dependency{ConfigPath: value.AsString()}, opts))
+		if existing, found := declared[path]; found {
+			findings = append(findings, Finding{
+				RuleID:   RuleDuplicateDependencyPath,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("dependency %q targets the same unit as dependency %q, which can be referenced instead", block.Labels[0], existing),
+				Range:    block.DefRange(),
+			})
+			continue
+		}
+		declared[path] = block.Labels[0]
+	}
+	return findings
+}
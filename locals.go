@@ -0,0 +1,131 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// terragruntLocals is a struct that can be used to only decode the locals blocks in the terragrunt config
+type terragruntLocals struct {
+	Locals []struct {
+		Remain hcl.Body `hcl:",remain"`
+	} `hcl:"locals,block"`
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// decodeAndEvaluateLocals decodes the locals blocks of the body and evaluates them, returning the locals as a single
+// cty object along with the remaining body, which holds everything but the locals blocks. As locals can reference
+// each other, they are evaluated in passes: each pass evaluates the locals whose referenced locals are all known, until
+// every local is evaluated.
+func decodeAndEvaluateLocals(body hcl.Body, opts *ParseOptions) (*cty.Value, hcl.Body, error) {
+	decodedLocals := terragruntLocals{}
+	if diags := gohcl.DecodeBody(body, nil, &decodedLocals); diags.HasErrors() {
+		return nil, nil, diags
+	}
+
+	attributes := map[string]*hcl.Attribute{}
+	for _, block := range decodedLocals.Locals {
+		blockAttributes, diags := block.Remain.JustAttributes()
+		if diags.HasErrors() {
+			return nil, nil, diags
+		}
+		for name, attribute := range blockAttributes {
+			if previous, found := attributes[name]; found {
+				return nil, nil, hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate local",
+					Detail:   fmt.Sprintf("The local %q was already defined at %s.", name, previous.Range),
+					Subject:  attribute.NameRange.Ptr(),
+				}}
+			}
+			attributes[name] = attribute
+		}
+	}
+
+	evaluated := map[string]cty.Value{}
+	for len(attributes) > 0 {
+		evaluatedInPass := 0
+		for _, name := range sortedLocalNames(attributes) {
+			attribute := attributes[name]
+
+			ready, err := localDependenciesEvaluated(attribute, attributes, evaluated)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ready {
+				continue
+			}
+
+			evalContext, err := CreateTerragruntEvalContext(opts, EvalContextExtensions{Locals: localsValue(evaluated)})
+			if err != nil {
+				return nil, nil, err
+			}
+			value, diags := attribute.Expr.Value(evalContext)
+			if diags.HasErrors() {
+				return nil, nil, diags
+			}
+
+			evaluated[name] = value
+			delete(attributes, name)
+			evaluatedInPass++
+		}
+
+		if evaluatedInPass == 0 {
+			return nil, nil, fmt.Errorf("could not evaluate the locals %s: they reference each other in a cycle", strings.Join(sortedLocalNames(attributes), ", "))
+		}
+	}
+
+	return localsValue(evaluated), decodedLocals.Remain, nil
+}
+
+// localDependenciesEvaluated returns whether all the locals referenced by the attribute are evaluated. References to
+// locals that are not defined are reported as errors.
+func localDependenciesEvaluated(attribute *hcl.Attribute, pending map[string]*hcl.Attribute, evaluated map[string]cty.Value) (bool, error) {
+	for _, traversal := range attribute.Expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		name, isName := traversalStepName(traversal[1])
+		if !isName {
+			// The local is selected dynamically (e.g. local[var]), so every local must be evaluated first.
+			if len(pending) > 1 {
+				return false, nil
+			}
+			continue
+		}
+
+		if _, found := evaluated[name]; found {
+			continue
+		}
+		if _, found := pending[name]; !found {
+			return false, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Unknown local",
+				Detail:   fmt.Sprintf("The local %q is not defined.", name),
+				Subject:  traversal.SourceRange().Ptr(),
+			}}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// localsValue returns the given locals as the cty object exposed as local.
+func localsValue(locals map[string]cty.Value) *cty.Value {
+	value := cty.ObjectVal(locals)
+	return &value
+}
+
+func sortedLocalNames(attributes map[string]*hcl.Attribute) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,193 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+)
+
+// LockFilename is the name of the dependency lock file of terraform.
+const LockFilename = ".terraform.lock.hcl"
+
+// Lockfile is a parsed terraform dependency lock file.
+type Lockfile struct {
+	// Path is the path of the lock file.
+	Path string
+
+	// Providers are the locked providers, keyed by fully qualified source.
+	Providers map[string]*LockedProvider
+}
+
+// LockedProvider is a provider locked by a dependency lock file.
+type LockedProvider struct {
+	// Source is the fully qualified address of the provider (e.g. registry.terraform.io/hashicorp/aws).
+	Source string
+
+	// Version is the selected version, and Constraints the version constraints it was selected with.
+	Version     string
+	Constraints string
+
+	// Hashes are the checksums of the provider packages, sorted.
+	Hashes []string
+
+	// Range is the range of the provider block.
+	Range hcl.Range
+}
+
+var lockfileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "provider", LabelNames: []string{"source"}},
+	},
+}
+
+type lockedProviderContent struct {
+	Version     string   `hcl:"version"`
+	Constraints *string  `hcl:"constraints"`
+	Hashes      []string `hcl:"hashes,optional"`
+	Remain      hcl.Body `hcl:",remain"`
+}
+
+// ParseLockfile parses the content of a dependency lock file. The filename is used in error messages.
+func ParseLockfile(content []byte, filename string) (*Lockfile, error) {
+	file, err := parseHCL(content, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lockContent, _, diags := file.Body.PartialContent(lockfileSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	lockfile := &Lockfile{Path: filename, Providers: map[string]*LockedProvider{}}
+	for _, block := range lockContent.Blocks {
+		source := normalizeProviderSource(block.Labels[0])
+		if _, found := lockfile.Providers[source]; found {
+			return nil, fmt.Errorf("%s: provider %s is locked more than once", block.DefRange, source)
+		}
+
+		var decoded lockedProviderContent
+		if diags := gohcl.DecodeBody(block.Body, nil, &decoded); diags.HasErrors() {
+			return nil, diags
+		}
+
+		provider := &LockedProvider{
+			Source:  source,
+			Version: decoded.Version,
+			Hashes:  decoded.Hashes,
+			Range:   block.DefRange,
+		}
+		if decoded.Constraints != nil {
+			provider.Constraints = *decoded.Constraints
+		}
+		sort.Strings(provider.Hashes)
+		lockfile.Providers[source] = provider
+	}
+	return lockfile, nil
+}
+
+// ReadLockfile reads and parses the dependency lock file at the given path. When the path is a directory, the lock
+// file of that directory is read.
+func ReadLockfile(path string) (*Lockfile, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, LockFilename)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLockfile(content, path)
+}
+
+// LockDriftKind is the kind of a LockDrift.
+type LockDriftKind string
+
+const (
+	// LockDriftVersion is reported when a provider is locked to another version than in the baseline.
+	LockDriftVersion LockDriftKind = "version"
+
+	// LockDriftHashes is reported when a provider is locked to the same version as in the baseline, with other
+	// hashes, which is the case when the lock files were generated for other platforms.
+	LockDriftHashes LockDriftKind = "hashes"
+
+	// LockDriftMissing is reported when a provider is not locked by the baseline.
+	LockDriftMissing LockDriftKind = "missing"
+)
+
+// LockDrift is a difference between the lock file of a unit and the baseline lock file.
+type LockDrift struct {
+	// Unit is the unit whose lock file differs.
+	Unit *Unit
+
+	// Provider is the fully qualified address of the provider.
+	Provider string
+	Kind     LockDriftKind
+
+	// Baseline and Locked are the providers locked by the baseline and by the unit. Baseline is nil for providers that
+	// are missing from the baseline.
+	Baseline *LockedProvider
+	Locked   *LockedProvider
+}
+
+// String describes the drift, without the unit.
+func (drift LockDrift) String() string {
+	switch drift.Kind {
+	case LockDriftVersion:
+		return fmt.Sprintf("%s is locked to %s, the baseline to %s", drift.Provider, drift.Locked.Version, drift.Baseline.Version)
+	case LockDriftHashes:
+		return fmt.Sprintf("%s %s is locked with other hashes than the baseline", drift.Provider, drift.Locked.Version)
+	}
+	return fmt.Sprintf("%s is not locked by the baseline", drift.Provider)
+}
+
+// CompareLockfiles compares the lock file of every unit of the stack against the given baseline lock file (or the
+// directory holding it), and returns the providers whose version or hashes differ, ordered by unit and provider.
+// This checks that the lock files copied into the units, such as with copy_terraform_lock_file, are in sync. Units
+// without a lock file are skipped.
+func CompareLockfiles(stack *Stack, baseline string) ([]LockDrift, error) {
+	baselineLockfile, err := ReadLockfile(baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []LockDrift
+	for _, unit := range stack.Units {
+		lockfile, err := ReadLockfile(filepath.Join(unit.Path, LockFilename))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sources := make([]string, 0, len(lockfile.Providers))
+		for source := range lockfile.Providers {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+
+		for _, source := range sources {
+			locked, baselineProvider := lockfile.Providers[source], baselineLockfile.Providers[source]
+			drift := LockDrift{Unit: unit, Provider: source, Baseline: baselineProvider, Locked: locked}
+			switch {
+			case baselineProvider == nil:
+				drift.Kind = LockDriftMissing
+			case locked.Version != baselineProvider.Version:
+				drift.Kind = LockDriftVersion
+			case strings.Join(locked.Hashes, ",") != strings.Join(baselineProvider.Hashes, ","):
+				drift.Kind = LockDriftHashes
+			default:
+				continue
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
@@ -0,0 +1,44 @@
+package terragrunt
+
+// Logger receives the structured events emitted while parsing and resolving terragrunt configurations. Each event is
+// identified by one of the Event* constants and described by a list of alternating key/value pairs, which makes it
+// straightforward to adapt to most structured logging libraries.
+type Logger interface {
+	Log(event string, keysAndValues ...interface{})
+}
+
+const (
+	// EventFileParsed is emitted once the HCL content of a configuration file has been parsed.
+	EventFileParsed = "file_parsed"
+
+	// EventDependencyOutputFetched is emitted once the outputs of a dependency block have been rendered.
+	EventDependencyOutputFetched = "dependency_output_fetched"
+
+	// EventCacheHit is emitted when the outputs of a dependency are served from the cache.
+	EventCacheHit = "cache_hit"
+
+	// EventCacheMiss is emitted when the outputs of a dependency are not found in the cache.
+	EventCacheMiss = "cache_miss"
+
+	// EventParseCacheHit is emitted when a unit is served from the parse cache.
+	EventParseCacheHit = "parse_cache_hit"
+
+	// EventParseCacheMiss is emitted when a unit is not found in the parse cache, or its files changed.
+	EventParseCacheMiss = "parse_cache_miss"
+
+	// EventParseCacheStoreFailed is emitted when a parsed unit could not be stored in the parse cache.
+	EventParseCacheStoreFailed = "parse_cache_store_failed"
+
+	// EventSymlinkSkipped is emitted when a symlink to a directory is not followed while discovering units, with
+	// WithFollowSymlinks: its reason is "loop" when it points to a directory being walked, and "duplicate" when its
+	// directory was already walked through another path.
+	EventSymlinkSkipped = "symlink_skipped"
+
+	// EventPanicRecovered is emitted when ParseConfigSafe recovers a panic, with its value and the stack of the panic.
+	EventPanicRecovered = "panic_recovered"
+)
+
+// nopLogger is the Logger used when none is configured. It discards every event.
+type nopLogger struct{}
+
+func (nopLogger) Log(event string, keysAndValues ...interface{}) {}
@@ -0,0 +1,168 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// MatrixItem is an item of a matrix a unit is fanned out over, e.g. a region or an account.
+type MatrixItem struct {
+	// Key identifies the item among the items of the matrix. It suffixes the path of the virtual unit of the item.
+	Key string
+
+	// Inputs are the inputs of the virtual unit of the item, overriding the inputs of the unit.
+	Inputs map[string]cty.Value
+}
+
+// MatrixItems returns the items of the matrix given as a cty value, e.g. read from a locals list or a YAML file:
+//   - a list of strings (or other primitive values) yields one item per value, keyed by the value, and with an input
+//     named after the key attribute set to the value, e.g. ["us-east-1", "eu-west-1"] with the region key attribute.
+//   - a list of objects yields one item per object, keyed by its key attribute, and with its attributes as inputs.
+//   - a map or object yields one item per element, keyed by the element key, and with its attributes as inputs, or an
+//     input named after the key attribute when the element is not an object.
+//
+// Items sharing a key are rejected.
+func MatrixItems(value cty.Value, keyAttribute string) ([]MatrixItem, error) {
+	value, _ = value.UnmarkDeep()
+	if value.IsNull() || !value.IsWhollyKnown() || !value.CanIterateElements() {
+		return nil, fmt.Errorf("the matrix must be a known list or map, got %s", value.Type().FriendlyName())
+	}
+
+	var items []MatrixItem
+	keys := map[string]bool{}
+	isMap := value.Type().IsMapType() || value.Type().IsObjectType()
+	for i, it := 0, value.ElementIterator(); it.Next(); i++ {
+		elementKey, element := it.Element()
+
+		item := MatrixItem{Inputs: map[string]cty.Value{}}
+		if element.Type().IsObjectType() || element.Type().IsMapType() {
+			for name, input := range element.AsValueMap() {
+				item.Inputs[name] = input
+			}
+		}
+		switch {
+		case isMap:
+			item.Key = elementKey.AsString()
+		case len(item.Inputs) > 0:
+			key, found := item.Inputs[keyAttribute]
+			if !found {
+				return nil, fmt.Errorf("matrix item %d has no %s attribute", i, keyAttribute)
+			}
+			key, err := convert.Convert(key, cty.String)
+			if err != nil || key.IsNull() {
+				return nil, fmt.Errorf("the %s attribute of matrix item %d must be a string", keyAttribute, i)
+			}
+			item.Key = key.AsString()
+		default:
+			key, err := convert.Convert(element, cty.String)
+			if err != nil || key.IsNull() {
+				return nil, fmt.Errorf("matrix item %d must be a string or an object", i)
+			}
+			item.Key = key.AsString()
+		}
+		if len(item.Inputs) == 0 {
+			item.Inputs[keyAttribute] = element
+		}
+
+		if keys[item.Key] {
+			return nil, fmt.Errorf("multiple matrix items with the key %s", item.Key)
+		}
+		keys[item.Key] = true
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ParseMatrixYAML returns the items of the matrix given as a YAML document, as MatrixItems.
+func ParseMatrixYAML(content []byte, keyAttribute string) ([]MatrixItem, error) {
+	value, err := decodeYAML(string(content))
+	if err != nil {
+		return nil, err
+	}
+	return MatrixItems(value, keyAttribute)
+}
+
+// ExpandMatrix expands the given unit into one virtual unit per item of the matrix, so that fan-out stacks can be
+// modeled without being materialized on disk. The virtual unit of an item has the path of the unit suffixed with the
+// key of the item in brackets (e.g. /live/app[us-east-1]), and a copy of its configuration, the inputs of the item
+// overriding its inputs. Units that could not be parsed are expanded too, keeping their error.
+func ExpandMatrix(unit *Unit, items []MatrixItem) ([]*Unit, error) {
+	units := make([]*Unit, 0, len(items))
+	for _, item := range items {
+		virtual := *unit
+		virtual.Path = MatrixUnitPath(unit.Path, item.Key)
+		virtual.MatrixKey = item.Key
+		if unit.Config != nil {
+			config := *unit.Config
+			config.InputsCty = map[string]cty.Value{}
+			config.Inputs = map[string]interface{}{}
+			for name, value := range unit.Config.InputsCty {
+				config.InputsCty[name] = value
+			}
+			for name, value := range unit.Config.Inputs {
+				config.Inputs[name] = value
+			}
+			for name, value := range item.Inputs {
+				config.InputsCty[name] = value
+				converted, err := ctyValueToInterface(value)
+				if err != nil {
+					return nil, fmt.Errorf("matrix item %s: input %s: %w", item.Key, name, err)
+				}
+				config.Inputs[name] = converted
+			}
+			virtual.Config = &config
+		}
+		units = append(units, &virtual)
+	}
+	return units, nil
+}
+
+// MatrixUnitPath returns the path of the virtual unit of the item with the given key of the matrix the unit at the
+// given path is expanded over.
+func MatrixUnitPath(path string, key string) string {
+	return path + "[" + key + "]"
+}
+
+// ExpandMatrix replaces the unit of the stack at the given path with its virtual units for the given matrix items (see
+// ExpandMatrix), and makes the units depending on it depend on every virtual unit instead, so that the graph and the
+// run-all batches of the stack model the fan-out.
+func (stack *Stack) ExpandMatrix(path string, items []MatrixItem) error {
+	unit := stack.Unit(path)
+	if unit == nil {
+		return fmt.Errorf("no unit at %s", path)
+	}
+	virtualUnits, err := ExpandMatrix(unit, items)
+	if err != nil {
+		return err
+	}
+
+	var units []*Unit
+	for _, other := range stack.Units {
+		if other == unit {
+			units = append(units, virtualUnits...)
+			continue
+		}
+		if containsString(other.Dependencies, unit.Path) {
+			var dependencies []string
+			for _, dependency := range other.Dependencies {
+				if dependency != unit.Path {
+					dependencies = append(dependencies, dependency)
+					continue
+				}
+				for _, virtual := range virtualUnits {
+					dependencies = append(dependencies, virtual.Path)
+				}
+			}
+			other.Dependencies = dependencies
+		}
+		units = append(units, other)
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i].Path < units[j].Path
+	})
+	stack.Units = units
+	return nil
+}
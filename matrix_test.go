@@ -0,0 +1,180 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMatrixItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		value cty.Value
+		want  []MatrixItem
+		err   string
+	}{
+		{
+			name:  "list of strings",
+			value: cty.TupleVal([]cty.Value{cty.StringVal("us-east-1"), cty.StringVal("eu-west-1")}),
+			want: []MatrixItem{
+				{Key: "us-east-1", Inputs: map[string]cty.Value{"region": cty.StringVal("us-east-1")}},
+				{Key: "eu-west-1", Inputs: map[string]cty.Value{"region": cty.StringVal("eu-west-1")}},
+			},
+		},
+		{
+			name: "list of objects",
+			value: cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"region": cty.StringVal("us-east-1"), "replicas": cty.NumberIntVal(3)}),
+				cty.ObjectVal(map[string]cty.Value{"region": cty.StringVal("eu-west-1")}),
+			}),
+			want: []MatrixItem{
+				{Key: "us-east-1", Inputs: map[string]cty.Value{"region": cty.StringVal("us-east-1"), "replicas": cty.NumberIntVal(3)}},
+				{Key: "eu-west-1", Inputs: map[string]cty.Value{"region": cty.StringVal("eu-west-1")}},
+			},
+		},
+		{
+			name: "map of objects",
+			value: cty.ObjectVal(map[string]cty.Value{
+				"prod":    cty.ObjectVal(map[string]cty.Value{"account": cty.StringVal("111111111111")}),
+				"staging": cty.ObjectVal(map[string]cty.Value{"account": cty.StringVal("222222222222")}),
+			}),
+			want: []MatrixItem{
+				{Key: "prod", Inputs: map[string]cty.Value{"account": cty.StringVal("111111111111")}},
+				{Key: "staging", Inputs: map[string]cty.Value{"account": cty.StringVal("222222222222")}},
+			},
+		},
+		{
+			name:  "map of strings",
+			value: cty.MapVal(map[string]cty.Value{"prod": cty.StringVal("111111111111")}),
+			want: []MatrixItem{
+				{Key: "prod", Inputs: map[string]cty.Value{"region": cty.StringVal("111111111111")}},
+			},
+		},
+		{
+			name:  "not a collection",
+			value: cty.StringVal("us-east-1"),
+			err:   "the matrix must be a known list or map, got string",
+		},
+		{
+			name:  "object without the key attribute",
+			value: cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"zone": cty.StringVal("a")})}),
+			err:   "matrix item 0 has no region attribute",
+		},
+		{
+			name:  "duplicate keys",
+			value: cty.TupleVal([]cty.Value{cty.StringVal("us-east-1"), cty.StringVal("us-east-1")}),
+			err:   "multiple matrix items with the key us-east-1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items, err := MatrixItems(test.value, "region")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, test.want) {
+				t.Errorf("got items %#v, want %#v", items, test.want)
+			}
+		})
+	}
+}
+
+func TestStackExpandMatrix(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"live/vpc/terragrunt.hcl": `inputs = { cidr = "10.0.0.0/16" }`,
+		"live/app/terragrunt.hcl": `
+dependency "vpc" {
+  config_path = "../vpc"
+}
+
+inputs = {
+  name     = "app"
+  region   = "us-east-1"
+  replicas = 1
+}
+`,
+		"live/dns/terragrunt.hcl": `
+dependency "app" {
+  config_path = "../app"
+}
+
+dependency "vpc" {
+  config_path = "../vpc"
+}
+`,
+	})
+	stack, err := ParseStack(filepath.Join(dir, "live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := ParseMatrixYAML([]byte("- region: us-east-1\n- region: eu-west-1\n  replicas: 3\n"), "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := filepath.Join(dir, "live", "app")
+	if err := stack.ExpandMatrix(app, items); err != nil {
+		t.Fatal(err)
+	}
+
+	// The virtual units are named after the items, keep the dependencies of the unit, and replace it in the
+	// dependencies of its dependents, in the order of the items.
+	vpc, dns := filepath.Join(dir, "live", "vpc"), filepath.Join(dir, "live", "dns")
+	eu, us := app+"[eu-west-1]", app+"[us-east-1]"
+	want := map[string][]string{
+		eu:  {vpc},
+		us:  {vpc},
+		dns: {us, eu, vpc},
+		vpc: nil,
+	}
+	var paths []string
+	for _, unit := range stack.Units {
+		paths = append(paths, unit.Path)
+		if !reflect.DeepEqual(unit.Dependencies, want[unit.Path]) {
+			t.Errorf("got dependencies %q for %s, want %q", unit.Dependencies, unit.Path, want[unit.Path])
+		}
+	}
+	if want := []string{eu, us, dns, vpc}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+
+	inputs := map[string]map[string]cty.Value{
+		eu: {"name": cty.StringVal("app"), "region": cty.StringVal("eu-west-1"), "replicas": cty.NumberIntVal(3)},
+		us: {"name": cty.StringVal("app"), "region": cty.StringVal("us-east-1"), "replicas": cty.NumberIntVal(1)},
+	}
+	for path, want := range inputs {
+		unit := stack.Unit(path)
+		if unit == nil {
+			t.Fatalf("no unit at %s", path)
+		}
+		if unit.MatrixKey != strings.TrimSuffix(strings.TrimPrefix(path, app+"["), "]") || unit.ConfigPath != filepath.Join(app, DefaultConfigFilename) {
+			t.Errorf("got unit %s with key %q from %s", unit.Path, unit.MatrixKey, unit.ConfigPath)
+		}
+		for name, value := range want {
+			if got := unit.Config.InputsCty[name]; !got.RawEquals(value) {
+				t.Errorf("%s: got input %s %#v, want %#v", path, name, got, value)
+			}
+		}
+	}
+
+	batches, err := stack.Graph().Batches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{vpc}, {eu, us}, {dns}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("got batches %q, want %q", batches, want)
+	}
+
+	if err := stack.ExpandMatrix(app, items); err == nil || err.Error() != "no unit at "+app {
+		t.Errorf("got error %v expanding the unit again, want no unit", err)
+	}
+}
@@ -0,0 +1,76 @@
+package terragrunt
+
+import (
+	"fmt"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MergeCtyValues returns the override value merged into the base value with the given strategy, with the semantics of
+// the include blocks and of the mock outputs merged with the state of dependencies:
+//   - MergeNoMerge returns the override value.
+//   - MergeShallow merges the top level attributes of objects and maps, the ones of the override replacing the ones of
+//     the base.
+//   - MergeDeep merges objects and maps recursively, and concatenates lists and tuples.
+//   - MergeDeepMapOnly merges objects and maps recursively, and replaces lists and tuples.
+//
+// Any other value of the override replaces the base value, including null values, which can be used to unset an
+// attribute. The base value is returned when the override is cty.NilVal, and the override when the base is null.
+// Unknown values can not be merged, and replace the base value. The marks of merged values are kept.
+func MergeCtyValues(base cty.Value, override cty.Value, strategy MergeStrategy) (cty.Value, error) {
+	switch strategy {
+	case MergeNoMerge, MergeShallow, MergeDeep, MergeDeepMapOnly:
+	default:
+		return cty.NilVal, fmt.Errorf("unsupported merge strategy %q", strategy)
+	}
+	if override == cty.NilVal {
+		return base, nil
+	}
+	if strategy == MergeNoMerge {
+		return override, nil
+	}
+	return mergeCtyValues(base, override, strategy, true), nil
+}
+
+// mergeCtyValues merges the override value into the base value with the given strategy, top being set for the top
+// level values, which are the only ones merged by MergeShallow.
+func mergeCtyValues(base cty.Value, override cty.Value, strategy MergeStrategy, top bool) cty.Value {
+	if base == cty.NilVal {
+		return override
+	}
+	base, baseMarks := base.Unmark()
+	override, overrideMarks := override.Unmark()
+	if base.IsNull() || override.IsNull() || !base.IsKnown() || !override.IsKnown() {
+		return override.WithMarks(overrideMarks)
+	}
+
+	var merged cty.Value
+	baseType, overrideType := base.Type(), override.Type()
+	switch {
+	case (baseType.IsObjectType() || baseType.IsMapType()) && (overrideType.IsObjectType() || overrideType.IsMapType()):
+		if strategy == MergeShallow && !top {
+			return override.WithMarks(overrideMarks)
+		}
+		attributes := map[string]cty.Value{}
+		for name, value := range base.AsValueMap() {
+			attributes[name] = value
+		}
+		for name, value := range override.AsValueMap() {
+			if baseValue, found := attributes[name]; found && strategy != MergeShallow {
+				value = mergeCtyValues(baseValue, value, strategy, false)
+			}
+			attributes[name] = value
+		}
+		merged = cty.ObjectVal(attributes)
+	case strategy == MergeDeep && (baseType.IsTupleType() || baseType.IsListType()) && (overrideType.IsTupleType() || overrideType.IsListType()):
+		elements := append(base.AsValueSlice(), override.AsValueSlice()...)
+		if len(elements) == 0 {
+			merged = cty.EmptyTupleVal
+		} else {
+			merged = cty.TupleVal(elements)
+		}
+	default:
+		return override.WithMarks(overrideMarks)
+	}
+	return merged.WithMarks(baseMarks, overrideMarks)
+}
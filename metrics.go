@@ -0,0 +1,100 @@
+package terragrunt
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Metrics receives the counters and timings of parsing and resolving terragrunt configurations, for services running
+// this package for a long time. Each metric is identified by one of the Metric* constants and described by labels.
+// The interface maps directly onto Prometheus counter and histogram vectors:
+//
+//	func (metrics promMetrics) IncCounter(name string, labels map[string]string) {
+//		metrics.counters[name].With(labels).Inc()
+//	}
+//
+//	func (metrics promMetrics) ObserveHistogram(name string, value float64, labels map[string]string) {
+//		metrics.histograms[name].With(labels).Observe(value)
+//	}
+type Metrics interface {
+	// IncCounter increments the counter with the given name and labels.
+	IncCounter(name string, labels map[string]string)
+
+	// ObserveHistogram records a value of the histogram with the given name and labels.
+	ObserveHistogram(name string, value float64, labels map[string]string)
+}
+
+const (
+	// MetricFilesParsed counts the configuration files parsed, included ones counting. It has no labels.
+	MetricFilesParsed = "terragrunt_files_parsed_total"
+
+	// MetricParseDuration is the histogram of the time to parse a configuration, in seconds, its includes and the
+	// retrieval of its dependency outputs included. It has no labels.
+	MetricParseDuration = "terragrunt_parse_duration_seconds"
+
+	// MetricResolveDuration is the histogram of the time to retrieve the outputs of a dependency through the
+	// OutputResolver, in seconds. Its result label is success or error.
+	MetricResolveDuration = "terragrunt_output_resolve_duration_seconds"
+
+	// MetricCacheRequests counts the lookups of a cache. Its cache label is output or parse, and its result label is
+	// hit or miss.
+	MetricCacheRequests = "terragrunt_cache_requests_total"
+
+	// MetricErrors counts the configurations that failed to be read or parsed. Its type label classifies the error: see
+	// ErrorType.
+	MetricErrors = "terragrunt_errors_total"
+)
+
+// nopMetrics is the Metrics used when none is configured. It discards every metric.
+type nopMetrics struct{}
+
+func (nopMetrics) IncCounter(name string, labels map[string]string) {}
+
+func (nopMetrics) ObserveHistogram(name string, value float64, labels map[string]string) {}
+
+// ErrorType classifies the given error for the MetricErrors counter:
+//   - config: the configuration is invalid (HCL diagnostics).
+//   - io: a file could not be read.
+//   - remote: a remote operation failed (e.g. a registry request or retrieving outputs).
+//   - limit: a limit of ParseConfigSafe was exceeded (size or duration).
+//   - panic: ParseConfigSafe recovered a panic.
+//   - other: any other error.
+func ErrorType(err error) string {
+	var diags hcl.Diagnostics
+	var pathErr *fs.PathError
+	var retryErr *RetryError
+	var statusErr *HTTPStatusError
+	var resolutionErr *outputResolutionError
+	var tooLargeErr *ConfigTooLargeError
+	var timeoutErr *ParseTimeoutError
+	var panicErr *ParsePanicError
+	switch {
+	case errors.As(err, &panicErr):
+		return "panic"
+	case errors.As(err, &tooLargeErr) || errors.As(err, &timeoutErr):
+		return "limit"
+	case errors.As(err, &retryErr) || errors.As(err, &statusErr) || errors.As(err, &resolutionErr):
+		return "remote"
+	case errors.As(err, &pathErr):
+		return "io"
+	case errors.As(err, &diags):
+		return "config"
+	}
+	return "other"
+}
+
+// observeDuration records the time elapsed since the given start in the histogram with the given name.
+func observeDuration(metrics Metrics, name string, start time.Time, labels map[string]string) {
+	metrics.ObserveHistogram(name, time.Since(start).Seconds(), labels)
+}
+
+// cacheResult returns the result label of MetricCacheRequests for a lookup that found an entry or not.
+func cacheResult(found bool) string {
+	if found {
+		return "hit"
+	}
+	return "miss"
+}
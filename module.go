@@ -0,0 +1,420 @@
+package terragrunt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Module is the interface of a terraform module, as declared by the .tf and .tf.json files of its directory.
+type Module struct {
+	// Path is the directory of the module.
+	Path string
+
+	// Variables are the input variables of the module, keyed by name.
+	Variables map[string]*ModuleVariable
+
+	// Outputs are the output values of the module, keyed by name.
+	Outputs map[string]*ModuleOutput
+
+	// RequiredProviders are the providers required by the module, keyed by local name.
+	RequiredProviders map[string]*ProviderRequirement
+
+	// ModuleCalls are the module blocks of the module, keyed by name.
+	ModuleCalls map[string]*ModuleCall
+}
+
+// ModuleVariable is an input variable declared by a module.
+type ModuleVariable struct {
+	Name string
+
+	// Type is the type constraint of the variable. It is cty.DynamicPseudoType when the variable accepts any value,
+	// or when its constraint cannot be interpreted (e.g. it uses optional object attributes).
+	Type cty.Type
+
+	// Required is set when the variable has no default value.
+	Required bool
+
+	// Default is the default value of the variable. It is cty.NilVal for required variables, and unknown when it can
+	// not be evaluated statically.
+	Default cty.Value
+
+	Sensitive bool
+
+	// Range is the range of the variable block.
+	Range hcl.Range
+}
+
+// ModuleOutput is an output value declared by a module.
+type ModuleOutput struct {
+	Name      string
+	Sensitive bool
+
+	// Value is the expression of the value of the output.
+	Value hcl.Expression
+
+	// Range is the range of the output block.
+	Range hcl.Range
+}
+
+// ModuleCall is a module block, calling another module.
+type ModuleCall struct {
+	Name string
+
+	// Source is the source of the called module. It is empty when it is not a literal string.
+	Source string
+
+	// Range is the range of the module block.
+	Range hcl.Range
+}
+
+// moduleSchema selects the blocks of the module files that are decoded.
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "terraform"},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+		{Name: "sensitive"},
+	},
+}
+
+var moduleCallSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "source"},
+	},
+}
+
+var outputSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "value"},
+		{Name: "sensitive"},
+	},
+}
+
+// ParseModule parses the terraform files of the module in the given directory. Override files (e.g. override.tf) are
+// parsed like the others, and may redefine the variables and outputs of the module.
+func ParseModule(dir string) (*Module, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &Module{
+		Path:              dir,
+		Variables:         map[string]*ModuleVariable{},
+		Outputs:           map[string]*ModuleOutput{},
+		RequiredProviders: map[string]*ProviderRequirement{},
+		ModuleCalls:       map[string]*ModuleCall{},
+	}
+	parser := hclparse.NewParser()
+	var diags hcl.Diagnostics
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		var file *hcl.File
+		var fileDiags hcl.Diagnostics
+		if strings.HasSuffix(name, ".json") {
+			file, fileDiags = parser.ParseJSONFile(path)
+		} else {
+			file, fileDiags = parser.ParseHCLFile(path)
+		}
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+
+		diags = append(diags, module.decodeFile(file.Body, isOverrideFile(name))...)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return module, nil
+}
+
+// isOverrideFile returns whether the terraform file with the given name is an override file.
+func isOverrideFile(name string) bool {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".tf")
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
+
+func (module *Module) decodeFile(body hcl.Body, override bool) hcl.Diagnostics {
+	content, _, diags := body.PartialContent(moduleSchema)
+	for _, block := range content.Blocks {
+		if block.Type == "terraform" {
+			diags = append(diags, module.decodeRequiredProviders(block.Body)...)
+			continue
+		}
+		name := block.Labels[0]
+
+		var existing hcl.Range
+		found := false
+		switch block.Type {
+		case "variable":
+			if variable := module.Variables[name]; variable != nil {
+				existing, found = variable.Range, true
+			}
+		case "output":
+			if output := module.Outputs[name]; output != nil {
+				existing, found = output.Range, true
+			}
+		case "module":
+			if call := module.ModuleCalls[name]; call != nil {
+				existing, found = call.Range, true
+			}
+		}
+		if found && !override {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("Duplicate %s declaration", block.Type),
+				Detail:   fmt.Sprintf("A %s named %q was already declared at %s.", block.Type, name, existing),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+
+		switch block.Type {
+		case "variable":
+			variable, variableDiags := decodeModuleVariable(name, block)
+			diags = append(diags, variableDiags...)
+			if variable != nil {
+				module.Variables[name] = variable
+			}
+		case "output":
+			output, outputDiags := decodeModuleOutput(name, block)
+			diags = append(diags, outputDiags...)
+			if output != nil {
+				module.Outputs[name] = output
+			}
+		case "module":
+			call, callDiags := decodeModuleCall(name, block)
+			diags = append(diags, callDiags...)
+			if call != nil {
+				module.ModuleCalls[name] = call
+			}
+		}
+	}
+	return diags
+}
+
+func decodeModuleVariable(name string, block *hcl.Block) (*ModuleVariable, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(variableSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	variable := &ModuleVariable{Name: name, Type: cty.DynamicPseudoType, Required: true, Range: block.DefRange}
+	if attribute, found := content.Attributes["type"]; found {
+		if constraint, typeDiags := typeexpr.TypeConstraint(attribute.Expr); !typeDiags.HasErrors() {
+			variable.Type = constraint
+		}
+	}
+	if attribute, found := content.Attributes["default"]; found {
+		variable.Required = false
+		variable.Default = cty.DynamicVal
+		if value, valueDiags := attribute.Expr.Value(nil); !valueDiags.HasErrors() {
+			variable.Default = value
+		}
+	}
+	variable.Sensitive, diags = decodeSensitive(content, diags)
+	return variable, diags
+}
+
+func decodeModuleOutput(name string, block *hcl.Block) (*ModuleOutput, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(outputSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	output := &ModuleOutput{Name: name, Range: block.DefRange}
+	if attribute, found := content.Attributes["value"]; found {
+		output.Value = attribute.Expr
+	}
+	output.Sensitive, diags = decodeSensitive(content, diags)
+	return output, diags
+}
+
+func decodeModuleCall(name string, block *hcl.Block) (*ModuleCall, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(moduleCallSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	call := &ModuleCall{Name: name, Range: block.DefRange}
+	if attribute, found := content.Attributes["source"]; found {
+		if value, valueDiags := attribute.Expr.Value(nil); !valueDiags.HasErrors() && value.Type() == cty.String && !value.IsNull() {
+			call.Source = value.AsString()
+		}
+	}
+	return call, diags
+}
+
+// decodeSensitive returns whether the sensitive attribute of the given block content is set to true.
+func decodeSensitive(content *hcl.BodyContent, diags hcl.Diagnostics) (bool, hcl.Diagnostics) {
+	attribute, found := content.Attributes["sensitive"]
+	if !found {
+		return false, diags
+	}
+	value, valueDiags := attribute.Expr.Value(nil)
+	diags = append(diags, valueDiags...)
+	return !valueDiags.HasErrors() && value.Type() == cty.Bool && value.IsKnown() && value.True(), diags
+}
+
+const (
+	RuleUnknownInput      = "unknown-input"
+	RuleMissingInput      = "missing-input"
+	RuleInputTypeMismatch = "input-type-mismatch"
+)
+
+// ValidateInputs cross-checks the inputs of the given configuration against the variables declared by its module: it
+// reports inputs that are not variables of the module, required variables without an input, and inputs that cannot be
+// converted to the type of their variable. Findings are located in the terragrunt configuration.
+func ValidateInputs(config *TerragruntConfig, module *Module) []Finding {
+	var findings []Finding
+	for _, name := range sortedValueKeys(config.InputsCty) {
+		origin, _ := config.Origin("inputs." + name)
+		variable, found := module.Variables[name]
+		if !found {
+			findings = append(findings, Finding{
+				RuleID:   RuleUnknownInput,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("input %q is not a variable of the module %s", name, module.Path),
+				Range:    origin.Range,
+			})
+			continue
+		}
+
+		value, _ := config.InputsCty[name].UnmarkDeep()
+		if !value.IsWhollyKnown() {
+			continue
+		}
+		if _, err := convert.Convert(value, variable.Type); err != nil {
+			findings = append(findings, Finding{
+				RuleID:   RuleInputTypeMismatch,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("input %q is not a valid value for a variable of type %s: %s", name, typeexpr.TypeString(variable.Type), err),
+				Range:    origin.Range,
+			})
+		}
+	}
+
+	var missing []string
+	for name, variable := range module.Variables {
+		if _, found := config.InputsCty[name]; variable.Required && !found {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		findings = append(findings, Finding{
+			RuleID:   RuleMissingInput,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("required variable %q of the module %s has no input", name, module.Path),
+			Range:    config.inputsRange(),
+		})
+	}
+	return findings
+}
+
+// inputsRange returns the range of the inputs attribute of the configuration, or of its terraform block when there
+// is none, to report findings about the inputs as a whole.
+func (config *TerragruntConfig) inputsRange() hcl.Range {
+	for _, path := range []string{"inputs", "terraform"} {
+		if origin, found := config.provenance[path]; found {
+			return origin
+		}
+	}
+	return hcl.Range{}
+}
+
+const (
+	RuleUnknownMockOutput      = "unknown-mock-output"
+	RuleUnknownOutputReference = "unknown-output-reference"
+)
+
+// ValidateDependencyOutputs statically checks the dependency blocks of the given terragrunt configuration content
+// against the outputs declared by the modules of their target units, given keyed by dependency name: it reports
+// mock_outputs keys and dependency.<name>.outputs.<key> references that are not outputs of the module. Dependencies
+// without a module are not checked. Use WithConfigPath to set the filename reported in the finding ranges.
+func ValidateDependencyOutputs(content []byte, modules map[string]*Module, opts ...Option) ([]Finding, error) {
+	parseOptions := newParseOptions(opts)
+
+	file, diags := hclparse.NewParser().ParseHCL(content, parseOptions.ConfigPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	var findings []Finding
+	for _, block := range body.Blocks {
+		if block.Type != "dependency" || len(block.Labels) == 0 || modules[block.Labels[0]] == nil {
+			continue
+		}
+		module := modules[block.Labels[0]]
+
+		attribute, found := block.Body.Attributes["mock_outputs"]
+		if !found {
+			continue
+		}
+		for key, keyRange := range objectItemRanges(attribute.Expr) {
+			if _, found := module.Outputs[key]; !found {
+				findings = append(findings, Finding{
+					RuleID:   RuleUnknownMockOutput,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("mock output %q of dependency %q is not an output of the module %s", key, block.Labels[0], module.Path),
+					Range:    keyRange,
+				})
+			}
+		}
+	}
+
+	for _, attribute := range analyzeReferences(body) {
+		for _, variable := range attribute.Variables {
+			traversal := variable.Traversal
+			if traversal.RootName() != "dependency" || len(traversal) < 4 {
+				continue
+			}
+			name, isName := traversalStepName(traversal[1])
+			outputs, _ := traversalStepName(traversal[2])
+			key, isKey := traversalStepName(traversal[3])
+			module := modules[name]
+			if !isName || outputs != "outputs" || !isKey || module == nil {
+				continue
+			}
+
+			if _, found := module.Outputs[key]; !found {
+				findings = append(findings, Finding{
+					RuleID:   RuleUnknownOutputReference,
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("output %q of dependency %q is not an output of the module %s", key, name, module.Path),
+					Range:    variable.Range,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Range.Start.Byte < findings[j].Range.Start.Byte
+	})
+	return findings, nil
+}
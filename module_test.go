@@ -0,0 +1,144 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateInputs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"modules/vpc/variables.tf": `
+variable "name" {
+  type = string
+}
+
+variable "zones" {
+  type = list(string)
+}
+
+variable "cidr" {
+  type    = string
+  default = "10.0.0.0/16"
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "settings" {
+  default = null
+}
+`,
+	})
+	module, err := ParseModule(filepath.Join(dir, "modules/vpc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type wantFinding struct {
+		rule    string
+		line    int
+		message string
+	}
+	tests := []struct {
+		name string
+		src  string
+		want []wantFinding
+	}{
+		{
+			name: "valid inputs",
+			src: `
+inputs = {
+  name     = "vpc"
+  zones    = ["a", "b"]
+  tags     = { env = "prod" }
+  settings = { nat = true }
+}
+`,
+		},
+		{
+			name: "converted inputs",
+			src: `
+inputs = {
+  name  = 42
+  zones = ["a", 1]
+  cidr  = true
+}
+`,
+		},
+		{
+			name: "unknown input",
+			src: `
+inputs = {
+  name  = "vpc"
+  zones = ["a"]
+  nmae  = "vpc"
+}
+`,
+			want: []wantFinding{
+				{rule: RuleUnknownInput, line: 5, message: `input "nmae" is not a variable of the module`},
+			},
+		},
+		{
+			name: "missing required variable",
+			src: `
+inputs = {
+  name = "vpc"
+}
+`,
+			want: []wantFinding{
+				{rule: RuleMissingInput, line: 2, message: `required variable "zones" of the module`},
+			},
+		},
+		{
+			name: "missing inputs",
+			src: `
+terraform {
+  source = "../modules/vpc"
+}
+`,
+			want: []wantFinding{
+				{rule: RuleMissingInput, line: 2, message: `required variable "name" of the module`},
+				{rule: RuleMissingInput, line: 2, message: `required variable "zones" of the module`},
+			},
+		},
+		{
+			name: "type mismatches",
+			src: `
+inputs = {
+  name  = { first = "vpc" }
+  zones = "a"
+  tags  = { env = ["prod"] }
+}
+`,
+			want: []wantFinding{
+				{rule: RuleInputTypeMismatch, line: 3, message: `input "name" is not a valid value for a variable of type string`},
+				{rule: RuleInputTypeMismatch, line: 5, message: `input "tags" is not a valid value for a variable of type map(string)`},
+				{rule: RuleInputTypeMismatch, line: 4, message: `input "zones" is not a valid value for a variable of type list(string)`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, "live/vpc", DefaultConfigFilename)
+			config, err := ParseConfig([]byte(test.src), WithConfigPath(configPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			findings := ValidateInputs(config, module)
+			if len(findings) != len(test.want) {
+				t.Fatalf("got findings %v, want %d", findings, len(test.want))
+			}
+			for i, want := range test.want {
+				finding := findings[i]
+				if finding.RuleID != want.rule || finding.Range.Filename != configPath || finding.Range.Start.Line != want.line || !strings.Contains(finding.Message, want.message) {
+					t.Errorf("got finding %s, want %s at line %d: %s", finding, want.rule, want.line, want.message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package terragrunt
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// ParseOptions holds the settings that control how a terragrunt configuration is parsed and evaluated. It is built
+// from the list of Option values passed to ParseConfig.
+type ParseOptions struct {
+	// ConfigPath is the path of the configuration being parsed. It is used in diagnostics and to resolve relative
+	// paths, such as the config_path of dependency blocks.
+	ConfigPath string
+
+	// Context is used for the operations performed while parsing the configuration, such as retrieving dependency
+	// outputs.
+	Context context.Context
+
+	// OutputResolver retrieves the outputs of dependencies. When it is nil, only the mock outputs of the dependency
+	// blocks are used.
+	OutputResolver OutputResolver
+
+	// OnlyReferencedOutputKeys restricts the outputs exposed for each dependency to the keys that the configuration
+	// actually references.
+	OnlyReferencedOutputKeys bool
+
+	// SelectiveOutputKeys is the maximum number of referenced outputs of a dependency for which only these outputs are
+	// retrieved, or filtered when the OutputResolver is not a KeyedOutputResolver. Every output is retrieved when it is
+	// zero.
+	SelectiveOutputKeys int
+
+	// DefaultMockOutputs are the mock outputs of the dependencies whose block does not set mock_outputs. See
+	// WithDefaultMockOutputs.
+	DefaultMockOutputs map[string]cty.Value
+
+	// MockOutputOverrides are laid over the mock outputs of dependencies, keyed by dependency block name or by absolute
+	// path of the dependency unit. See WithMockOutputOverrides.
+	MockOutputOverrides map[string]map[string]cty.Value
+
+	// StrictDependencyOutputs makes referencing an output a dependency does not have an error. See
+	// WithStrictDependencyOutputs.
+	StrictDependencyOutputs bool
+
+	// StrictIncludes makes the attributes set by several included configurations errors. See WithStrictIncludes.
+	StrictIncludes bool
+
+	// SopsDecryptor decrypts the files read by the sops_decrypt_file function.
+	SopsDecryptor SopsDecryptor
+
+	// SecretsProviders back the secret lookup functions, which are only available when their provider is set.
+	SecretsProviders SecretsProviders
+
+	// RunCmd controls the run_cmd function, which is disabled by default.
+	RunCmd RunCmdOptions
+
+	// CommandRunner runs the commands of run_cmd() and ResolveBinary. Defaults to ExecCommandRunner.
+	CommandRunner CommandRunner
+
+	// AWSIdentityProvider backs the AWS identity functions, such as get_aws_account_id().
+	AWSIdentityProvider AWSIdentityProvider
+
+	// AssumeRoleCache, when set, assumes the role declared by the iam_role attribute of the configuration, so that
+	// the AWS identity functions return its identity.
+	AssumeRoleCache *AssumeRoleCache
+
+	// SearchRoot is the directory at which find_in_parent_folders() stops searching, when set.
+	SearchRoot string
+
+	// SearchStopAtRepoRoot makes find_in_parent_folders() stop searching at the root of the git repository containing
+	// the configuration.
+	SearchStopAtRepoRoot bool
+
+	// Deterministic, when set, freezes the nondeterministic functions (timestamp(), uuid() and get_env()) to the
+	// values it holds.
+	Deterministic *DeterministicValues
+
+	// HermeticMode selects how the functions with side effects or access to the environment behave.
+	HermeticMode HermeticMode
+
+	// Functions are additional HCL functions, keyed by name, that are made available to the configuration during
+	// evaluation.
+	Functions map[string]function.Function
+
+	// FeatureFlags override the values of the feature flags declared by feature blocks, keyed by name. Values are
+	// converted to the type of the default of their flag.
+	FeatureFlags map[string]cty.Value
+
+	// Variables are additional top level variables, keyed by name, that are made available to the configuration
+	// during evaluation. Variables managed by this package (e.g. dependency) take precedence over these.
+	Variables map[string]cty.Value
+
+	// Logger receives the events emitted while parsing and resolving the configuration.
+	Logger Logger
+
+	// Metrics receives the counters and timings of parsing and resolving the configuration. By default they are
+	// discarded.
+	Metrics Metrics
+
+	// Progress receives the progress events of long-running operations. See WithProgress.
+	Progress chan<- ProgressEvent
+
+	// FS is the filesystem the configuration and the files it references are read from. When it is nil, the
+	// filesystem of the operating system is used.
+	FS fs.FS
+
+	// ParseCache, when set, caches the units parsed by ParseStack. See DiskParseCache.
+	ParseCache *DiskParseCache
+
+	// FollowSymlinks makes the discovery of units follow the symlinks to directories. See WithFollowSymlinks.
+	FollowSymlinks bool
+
+	// ExcludeGlobs are the paths excluded from the discovery of units. See WithExcludeGlobs.
+	ExcludeGlobs []string
+
+	// MaxConfigSize is the maximum size, in bytes, of the files read while parsing, when positive. See
+	// WithMaxConfigSize.
+	MaxConfigSize int64
+
+	// ParseTimeout is the maximum duration of ParseConfigSafe. See WithParseTimeout.
+	ParseTimeout time.Duration
+
+	// originalConfigPath is the path of the configuration originally being parsed, when ConfigPath is a configuration
+	// included by it.
+	originalConfigPath string
+
+	// includes holds the include blocks of the configuration being parsed, for the include related functions.
+	includes *includePaths
+
+	// iamRole is the role declared by the iam_role attribute of the configuration, or of the configuration including
+	// it, once iamRoleDecoded. identityBeforeIAMRole records that the identity functions were called before.
+	iamRole               *AWSRole
+	iamRoleDecoded        bool
+	identityBeforeIAMRole bool
+
+	// accessedFiles records the absolute paths of the files read or looked up while parsing, when set.
+	accessedFiles map[string]bool
+
+	// unitDependencies records the dependency blocks of the configuration being parsed, and the dependency outputs it
+	// references, when set. See parseUnitContent.
+	unitDependencies *unitDependencies
+
+	// fileCache, when set, memoizes the files parsed, and the contents of the files read with cacheFileContents. See
+	// Parser.
+	fileCache         *fileCache
+	cacheFileContents bool
+
+	// functions is the table of functions of the configuration being parsed, built once for its evaluation contexts.
+	functions map[string]function.Function
+}
+
+// Option configures the ParseOptions used while parsing a terragrunt configuration.
+type Option func(*ParseOptions)
+
+// newParseOptions returns the ParseOptions resulting from applying the given options on top of the defaults.
+func newParseOptions(opts []Option) *ParseOptions {
+	parseOptions := &ParseOptions{
+		ConfigPath: filename,
+		Context:    context.Background(),
+		Functions:  map[string]function.Function{},
+		Variables:  map[string]cty.Value{},
+		Logger:     nopLogger{},
+		Metrics:    nopMetrics{},
+
+		SopsDecryptor: ExecSopsDecryptor{},
+	}
+	for _, opt := range opts {
+		opt(parseOptions)
+	}
+	return parseOptions
+}
+
+// workingDir returns the absolute path of the directory containing the configuration being parsed.
+func (opts *ParseOptions) workingDir() string {
+	dir := filepath.Dir(opts.ConfigPath)
+	if absDir, err := filepath.Abs(dir); err == nil {
+		return absDir
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		return cwd
+	}
+	return dir
+}
+
+// WithConfigPath sets the path of the configuration being parsed, which is used in diagnostics and to resolve relative
+// paths. ParseConfigFile sets it automatically.
+func WithConfigPath(configPath string) Option {
+	return func(opts *ParseOptions) {
+		opts.ConfigPath = configPath
+	}
+}
+
+// WithContext sets the context used for the operations performed while parsing, such as retrieving dependency outputs.
+func WithContext(ctx context.Context) Option {
+	return func(opts *ParseOptions) {
+		opts.Context = ctx
+	}
+}
+
+// WithOutputResolver sets the OutputResolver used to retrieve the outputs of dependencies. Wrap it with a
+// CachingOutputResolver to share outputs across parses.
+func WithOutputResolver(resolver OutputResolver) Option {
+	return func(opts *ParseOptions) {
+		opts.OutputResolver = resolver
+	}
+}
+
+// WithOnlyReferencedOutputKeys restricts the outputs exposed for each dependency to the keys that the configuration
+// actually references (e.g. dependency.vpc.outputs.vpc_id), rather than the full output set.
+func WithOnlyReferencedOutputKeys() Option {
+	return func(opts *ParseOptions) {
+		opts.OnlyReferencedOutputKeys = true
+	}
+}
+
+// WithSelectiveOutputKeys retrieves only the referenced outputs of the dependencies whose outputs the configuration
+// references at most maxKeys of (e.g. dependency.vpc.outputs.vpc_id), with `terragrunt output -json <key>` or by
+// filtering their state, depending on the OutputResolver (see KeyedOutputResolver), rather than the full output set,
+// which is large for some modules. Like with WithOnlyReferencedOutputKeys, the other outputs are then not exposed.
+func WithSelectiveOutputKeys(maxKeys int) Option {
+	return func(opts *ParseOptions) {
+		opts.SelectiveOutputKeys = maxKeys
+	}
+}
+
+// WithDefaultMockOutputs sets the mock outputs of the dependencies whose block does not set mock_outputs, so that CI
+// pipelines running validate can parse configurations whose dependencies are not applied and have no mock outputs.
+// They are used like the mock_outputs of the blocks: when the outputs of a dependency can not be retrieved, or are
+// merged with them with mock_outputs_merge_strategy_with_state. It can be used multiple times, with later values
+// replacing earlier ones of the same name.
+func WithDefaultMockOutputs(outputs map[string]cty.Value) Option {
+	return func(opts *ParseOptions) {
+		if opts.DefaultMockOutputs == nil {
+			opts.DefaultMockOutputs = map[string]cty.Value{}
+		}
+		for name, value := range outputs {
+			opts.DefaultMockOutputs[name] = value
+		}
+	}
+}
+
+// WithMockOutputOverrides lays the given outputs over the mock outputs of a dependency, replacing the outputs of the
+// same name of its mock_outputs, or of the default mock outputs when it has none. The dependency is either the name of
+// the dependency blocks it applies to, or the path of the dependency unit, which contains a path separator and is
+// relative to the current directory unless absolute. The overrides of a unit path are laid over the overrides of a
+// block name. It can be used multiple times, with later values replacing earlier ones of the same name.
+func WithMockOutputOverrides(dependency string, outputs map[string]cty.Value) Option {
+	if strings.ContainsRune(dependency, filepath.Separator) || strings.ContainsRune(dependency, '/') {
+		if absPath, err := filepath.Abs(dependency); err == nil {
+			dependency = absPath
+		}
+	}
+	return func(opts *ParseOptions) {
+		if opts.MockOutputOverrides == nil {
+			opts.MockOutputOverrides = map[string]map[string]cty.Value{}
+		}
+		if opts.MockOutputOverrides[dependency] == nil {
+			opts.MockOutputOverrides[dependency] = map[string]cty.Value{}
+		}
+		for name, value := range outputs {
+			opts.MockOutputOverrides[dependency][name] = value
+		}
+	}
+}
+
+// WithStrictDependencyOutputs makes referencing an output that a dependency does not have, in its outputs or its
+// mock outputs, fail with a MissingDependencyOutputsError naming the dependency, the output and the position of the
+// reference, instead of the unsupported attribute error of the evaluation. This typically happens when a dependency
+// is not applied yet, or its outputs are not retrieved, and it has no mock outputs, so that it has no outputs at all.
+// The references guarded by try() or can() are not checked.
+func WithStrictDependencyOutputs() Option {
+	return func(opts *ParseOptions) {
+		opts.StrictDependencyOutputs = true
+	}
+}
+
+// WithStrictIncludes makes the attributes set by several included configurations, which are reported in the Warnings
+// of the configuration by default, fail the parse instead, with an error naming the location of the attribute in both
+// included configurations. This keeps a later include block from overriding an earlier one by mistake.
+func WithStrictIncludes() Option {
+	return func(opts *ParseOptions) {
+		opts.StrictIncludes = true
+	}
+}
+
+// WithSopsDecryptor sets the SopsDecryptor used by the sops_decrypt_file function. By default files are decrypted
+// with the sops binary.
+func WithSopsDecryptor(decryptor SopsDecryptor) Option {
+	return func(opts *ParseOptions) {
+		opts.SopsDecryptor = decryptor
+	}
+}
+
+// WithSopsDecryptionDisabled makes the sops_decrypt_file function return the files with every encrypted value replaced
+// by SopsPlaceholder instead of decrypting them, for environments without access to the decryption keys.
+func WithSopsDecryptionDisabled() Option {
+	return WithSopsDecryptor(placeholderSopsDecryptor{})
+}
+
+// WithSecretsProviders enables the secret lookup functions backed by the given providers: vault_kv(path, key),
+// ssm_parameter(name) and aws_secretsmanager(name, key). The values they return are marked as sensitive.
+func WithSecretsProviders(providers SecretsProviders) Option {
+	return func(opts *ParseOptions) {
+		opts.SecretsProviders = providers
+	}
+}
+
+// WithRunCmd enables the run_cmd function with the given restrictions.
+func WithRunCmd(runCmdOptions RunCmdOptions) Option {
+	return func(opts *ParseOptions) {
+		opts.RunCmd = runCmdOptions
+	}
+}
+
+// WithAWSIdentityProvider sets the provider backing the AWS identity functions (get_aws_account_id(),
+// get_aws_caller_identity_arn() and get_aws_caller_identity_user_id()). Use an STSClient to retrieve the identity from
+// AWS, or a StaticAWSIdentity for offline evaluation. The identity is retrieved at most once per parse, failed
+// retrievals being retried.
+func WithAWSIdentityProvider(provider AWSIdentityProvider) Option {
+	return func(opts *ParseOptions) {
+		opts.AWSIdentityProvider = &memoizedAWSIdentity{provider: provider}
+	}
+}
+
+// WithAssumeRoleCache makes the AWS identity functions return the identity of the role declared by the iam_role
+// attribute of the configuration, assumed through the given cache. The identity functions of configurations without
+// a role are still backed by the AWSIdentityProvider.
+func WithAssumeRoleCache(cache *AssumeRoleCache) Option {
+	return func(opts *ParseOptions) {
+		opts.AssumeRoleCache = cache
+	}
+}
+
+// WithSearchRoot makes find_in_parent_folders() stop searching at the given directory, which is still searched. The
+// configuration must be within it.
+func WithSearchRoot(dir string) Option {
+	return func(opts *ParseOptions) {
+		opts.SearchRoot = dir
+	}
+}
+
+// WithSearchStopAtRepoRoot makes find_in_parent_folders() stop searching at the root of the git repository containing
+// the configuration, so that evaluation can not read files outside of the checkout.
+func WithSearchStopAtRepoRoot() Option {
+	return func(opts *ParseOptions) {
+		opts.SearchStopAtRepoRoot = true
+	}
+}
+
+// WithDeterministic freezes the nondeterministic functions to the given values: timestamp() returns values.Time, uuid()
+// returns values.UUID and get_env() only sees values.Env. Along with RenderJSON, whose output is sorted, it makes two
+// renders of the same configuration byte-identical, for drift detection.
+func WithDeterministic(values DeterministicValues) Option {
+	return func(opts *ParseOptions) {
+		opts.Deterministic = &values
+	}
+}
+
+// WithHermeticMode restricts the functions with side effects or access to the environment (run_cmd, get_env,
+// sops_decrypt_file, ...) to return unknown values or fail, depending on the mode. Custom functions registered with
+// WithFunction are left untouched.
+func WithHermeticMode(mode HermeticMode) Option {
+	return func(opts *ParseOptions) {
+		opts.HermeticMode = mode
+	}
+}
+
+// WithFunction registers a custom HCL function under the given name, so that embedders can expose organization
+// specific helpers (e.g. lookup_team_owner()) to the configuration without modifying this package.
+func WithFunction(name string, fn function.Function) Option {
+	return func(opts *ParseOptions) {
+		opts.Functions[name] = fn
+	}
+}
+
+// WithVariables injects extra top level variables into the evaluation context, so that configurations can reference
+// caller supplied data (e.g. a pipeline object with CI metadata). It can be used multiple times, with later values
+// replacing earlier ones of the same name.
+func WithVariables(variables map[string]cty.Value) Option {
+	return func(opts *ParseOptions) {
+		for name, value := range variables {
+			opts.Variables[name] = value
+		}
+	}
+}
+
+// WithFeatureFlags overrides the values of the feature flags declared by feature blocks, as the --feature flag of
+// terragrunt does. Values are converted to the type of the default of their flag, so that strings given on a command
+// line (e.g. "true") can override flags of any type. It can be used multiple times, with later values replacing
+// earlier ones of the same name.
+func WithFeatureFlags(flags map[string]cty.Value) Option {
+	return func(opts *ParseOptions) {
+		if opts.FeatureFlags == nil {
+			opts.FeatureFlags = map[string]cty.Value{}
+		}
+		for name, value := range flags {
+			opts.FeatureFlags[name] = value
+		}
+	}
+}
+
+// WithLogger sets the Logger that receives the structured events emitted while parsing and resolving the
+// configuration. By default events are discarded.
+func WithLogger(logger Logger) Option {
+	return func(opts *ParseOptions) {
+		opts.Logger = logger
+	}
+}
+
+// WithParseCache makes ParseStack reuse the units cached in the given cache when their files did not change, and cache
+// the units it parses.
+func WithParseCache(cache *DiskParseCache) Option {
+	return func(opts *ParseOptions) {
+		opts.ParseCache = cache
+	}
+}
+
+// WithMetrics sets the Metrics that receive the counters and timings of parsing and resolving the configuration, e.g.
+// to export them to Prometheus. By default they are discarded.
+func WithMetrics(metrics Metrics) Option {
+	return func(opts *ParseOptions) {
+		opts.Metrics = metrics
+	}
+}
+
+// WithFS makes the configuration and the files it references (e.g. through file() or read_tfvars_file()) be read from
+// the given filesystem instead of the one of the operating system. Absolute paths are rooted at the root of the
+// filesystem, so that /live/app/terragrunt.hcl is read as live/app/terragrunt.hcl.
+func WithFS(fsys fs.FS) Option {
+	return func(opts *ParseOptions) {
+		opts.FS = fsys
+	}
+}
+
+// WithFollowSymlinks makes ParseStack, DiscoverUnits and DiscoverStackFiles follow the symlinks to directories, such as
+// shared "envcommon" directories. A directory reachable through several paths is only walked once, at the first path in
+// lexical order, so that its units are not discovered twice, and symlink loops are skipped.
+func WithFollowSymlinks() Option {
+	return func(opts *ParseOptions) {
+		opts.FollowSymlinks = true
+	}
+}
+
+// WithExcludeGlobs excludes the paths matching the given globs from the discovery of units by ParseStack, DiscoverUnits
+// and DiscoverStackFiles, along with the ones excluded by the ignore files (see IgnoreFilenames). The globs have the
+// gitignore syntax, relative to the directory the units are discovered under: e.g. "examples/" excludes the examples
+// directories at any depth, "/scratch" only the one at the root, and "!" re-includes paths excluded by previous globs.
+func WithExcludeGlobs(globs ...string) Option {
+	return func(opts *ParseOptions) {
+		opts.ExcludeGlobs = append(opts.ExcludeGlobs, globs...)
+	}
+}
+
+// WithMaxConfigSize limits the size, in bytes, of the configuration and of the files read while parsing it, such as
+// its includes: larger files fail with a *ConfigTooLargeError. ParseConfigSafe defaults it to DefaultMaxConfigSize.
+func WithMaxConfigSize(size int64) Option {
+	return func(opts *ParseOptions) {
+		opts.MaxConfigSize = size
+	}
+}
+
+// WithParseTimeout limits the duration of ParseConfigSafe, which fails with a *ParseTimeoutError past it. Defaults to
+// DefaultParseTimeout.
+func WithParseTimeout(timeout time.Duration) Option {
+	return func(opts *ParseOptions) {
+		opts.ParseTimeout = timeout
+	}
+}
@@ -0,0 +1,175 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// OutputTypeSource is where the type of an output comes from.
+type OutputTypeSource string
+
+const (
+	// OutputTypeFromState is the source of the types of the outputs of a unit read from its state, as reported by
+	// terraform output -json.
+	OutputTypeFromState OutputTypeSource = "state"
+
+	// OutputTypeFromModule is the source of the types inferred from the output blocks of the module of a unit.
+	OutputTypeFromModule OutputTypeSource = "module"
+)
+
+// OutputSchema is the type of an output of a unit.
+type OutputSchema struct {
+	Name string
+
+	// Type is the type of the output. The types inferred from the module are cty.DynamicPseudoType, or contain it,
+	// where the value depends on resources, data sources, locals or modules, which are not known before apply.
+	Type cty.Type
+
+	Sensitive bool
+	Source    OutputTypeSource
+
+	// Range is the range of the output block in the module, when read from the module.
+	Range hcl.Range
+}
+
+// OutputSchemaOptions configures the extraction of output schemas.
+type OutputSchemaOptions struct {
+	// Resolver retrieves the outputs of the units along with their type, in the format of terraform output -json, such
+	// as an ExecOutputResolver or a StateOutputResolver. The types are only inferred from the modules when nil.
+	Resolver OutputResolver
+
+	// Fetcher fetches the modules of the units. Defaults to a SourceFetcher with its default settings.
+	Fetcher *SourceFetcher
+
+	// Options are the options the configurations of the dependencies outside of the stack are parsed with.
+	Options []Option
+}
+
+// UnitOutputSchemas returns the schema of the outputs of the given unit, keyed by output name: the outputs of its
+// state, retrieved with the Resolver, with the exact type terraform reports, and the outputs of its module that are
+// not in the state, e.g. because the unit is not applied yet, with the type of their value expression, evaluated
+// against the inputs of the unit.
+func UnitOutputSchemas(ctx context.Context, unit *Unit, opts OutputSchemaOptions) (map[string]*OutputSchema, error) {
+	if unit.Config == nil {
+		return nil, fmt.Errorf("%s: %w", unit.ConfigPath, unit.Err)
+	}
+
+	schemas := map[string]*OutputSchema{}
+	if opts.Resolver != nil {
+		outputs, err := opts.Resolver.ResolveOutputs(ctx, unit.ConfigPath)
+		if err != nil {
+			return nil, &outputResolutionError{dependency: unit.Path, err: err}
+		}
+		if schemas, err = stateOutputSchemas(unit.Path, outputs); err != nil {
+			return nil, err
+		}
+	}
+
+	fetcher := opts.Fetcher
+	if fetcher == nil {
+		fetcher = &SourceFetcher{}
+	}
+	moduleDir, err := fetcher.FetchUnit(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+	module, err := ParseModule(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	for name, output := range module.Outputs {
+		if _, found := schemas[name]; found {
+			continue
+		}
+		schemas[name] = &OutputSchema{
+			Name:      name,
+			Type:      moduleOutputType(module, output, unit.Config),
+			Sensitive: output.Sensitive,
+			Source:    OutputTypeFromModule,
+			Range:     output.Range,
+		}
+	}
+	return schemas, nil
+}
+
+// DependencyOutputSchemas returns the schema of the outputs of the dependencies of the given unit of the stack, keyed
+// by dependency block name, then by output name, so that tools can generate typed accessors for dependency outputs
+// or check the references to them before resolving them. The dependencies that are not units of the stack are parsed
+// with the Options. See UnitOutputSchemas.
+func (stack *Stack) DependencyOutputSchemas(ctx context.Context, unit *Unit, opts OutputSchemaOptions) (map[string]map[string]*OutputSchema, error) {
+	schemas := map[string]map[string]*OutputSchema{}
+	for name, path := range unit.DependencyPaths() {
+		dependency := stack.Unit(path)
+		if dependency == nil {
+			configPath := filepath.Join(path, DefaultConfigFilename)
+			config, err := ParseConfigFile(configPath, append(append([]Option(nil), opts.Options...), WithContext(ctx))...)
+			dependency = &Unit{Path: path, ConfigPath: configPath, Config: config, Err: err}
+		}
+
+		dependencySchemas, err := UnitOutputSchemas(ctx, dependency, opts)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %q: %w", name, err)
+		}
+		schemas[name] = dependencySchemas
+	}
+	return schemas, nil
+}
+
+// stateOutputSchemas returns the schema of the outputs in the given terraform output json of the unit at the given
+// path. The type of an output without one is inferred from its value.
+func stateOutputSchemas(path string, outputs []byte) (map[string]*OutputSchema, error) {
+	schemas := map[string]*OutputSchema{}
+	if len(outputs) == 0 {
+		return schemas, nil
+	}
+
+	var decoded map[string]struct {
+		Sensitive bool            `json:"sensitive"`
+		Type      json.RawMessage `json:"type"`
+		Value     json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(outputs, &decoded); err != nil {
+		return nil, &InvalidOutputsError{Dependency: path, Err: err}
+	}
+	for name, output := range decoded {
+		outputType := cty.DynamicPseudoType
+		var err error
+		switch {
+		case len(output.Type) > 0 && string(output.Type) != "null":
+			outputType, err = ctyjson.UnmarshalType(output.Type)
+		case len(output.Value) > 0 && string(output.Value) != "null":
+			outputType, err = ctyjson.ImpliedType(output.Value)
+		}
+		if err != nil {
+			return nil, &InvalidOutputsError{Dependency: path, Output: name, Err: err}
+		}
+		schemas[name] = &OutputSchema{Name: name, Type: outputType, Sensitive: output.Sensitive, Source: OutputTypeFromState}
+	}
+	return schemas, nil
+}
+
+// moduleOutputType returns the type of the value of the given output of the module, evaluated against the inputs of
+// the unit with the given configuration. The objects not known before apply, such as resources, are unknown values of
+// any type.
+func moduleOutputType(module *Module, output *ModuleOutput, config *TerragruntConfig) cty.Type {
+	if output.Value == nil {
+		return cty.DynamicPseudoType
+	}
+	evalContext := moduleEvalContext(module, config)
+	for _, traversal := range output.Value.Variables() {
+		if name := traversal.RootName(); name != "var" {
+			evalContext.Variables[name] = cty.DynamicVal
+		}
+	}
+	value, diags := output.Value.Value(evalContext)
+	if diags.HasErrors() {
+		return cty.DynamicPseudoType
+	}
+	return value.Type()
+}
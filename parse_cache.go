@@ -0,0 +1,135 @@
+package terragrunt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// DiskParseCache stores the parsed units of a stack as files in a directory, keyed by their fingerprint (see
+// Unit.Fingerprint), so that repeated runs over a large repository only parse again the units whose files changed.
+// Enable it with WithParseCache.
+//
+// Cached units are stored like in a snapshot (see Stack.Snapshot): their sensitive values are redacted, and their
+// unknown blocks are not kept. Only the files of a unit are part of its fingerprint: the cache is meant for parsing
+// with mock outputs, and should be cleared when the dependency outputs, the environment or the parse options change.
+// Units that fail to parse are not cached.
+type DiskParseCache struct {
+	Dir string
+}
+
+// NewDiskParseCache returns a DiskParseCache storing its entries in the given directory, which is created if needed.
+func NewDiskParseCache(dir string) (*DiskParseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskParseCache{Dir: dir}, nil
+}
+
+// parseCacheIndexEntry records the files the unit with a given configuration was last parsed from, and its terraform
+// source, which are needed to compute its fingerprint before parsing it again.
+type parseCacheIndexEntry struct {
+	Files           []string `json:"files"`
+	TerraformSource *string  `json:"terraform_source,omitempty"`
+}
+
+// get returns the cached unit with the given configuration, when its fingerprint did not change since it was stored.
+func (cache *DiskParseCache) get(configPath string) (*Unit, string, bool) {
+	var index parseCacheIndexEntry
+	if !cache.read(cache.indexPath(configPath), &index) {
+		return nil, "", false
+	}
+
+	// The fingerprint is computed from the files the unit was last parsed from: when one of them changes, so does the
+	// fingerprint, whether the change affects the files the unit is now parsed from or not.
+	indexedUnit := &Unit{Path: filepath.Dir(configPath), ConfigPath: configPath, Files: index.Files, Config: &TerragruntConfig{}}
+	if index.TerraformSource != nil {
+		indexedUnit.Config.Terraform = &TerraformConfig{Source: index.TerraformSource}
+	}
+	fingerprint, err := indexedUnit.Fingerprint()
+	if err != nil {
+		return nil, "", false
+	}
+
+	var snapshot unitSnapshot
+	if !cache.read(cache.entryPath(configPath, fingerprint), &snapshot) {
+		return nil, fingerprint, false
+	}
+	unit, err := snapshot.unit()
+	if err != nil {
+		return nil, fingerprint, false
+	}
+	return unit, fingerprint, true
+}
+
+// set stores the given unit, keyed by its fingerprint.
+func (cache *DiskParseCache) set(unit *Unit) error {
+	fingerprint, err := unit.Fingerprint()
+	if err != nil {
+		return err
+	}
+	snapshot, err := newUnitSnapshot(unit)
+	if err != nil {
+		return err
+	}
+	if err := cache.write(cache.entryPath(unit.ConfigPath, fingerprint), snapshot); err != nil {
+		return err
+	}
+
+	index := parseCacheIndexEntry{Files: unit.Files}
+	if unit.Config.Terraform != nil {
+		index.TerraformSource = unit.Config.Terraform.Source
+	}
+	return cache.write(cache.indexPath(unit.ConfigPath), index)
+}
+
+// read decodes the json file at the given path into out, and returns whether it succeeded. Missing and corrupted
+// entries are treated as misses, so that they get replaced.
+func (cache *DiskParseCache) read(path string, out interface{}) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(content, out) == nil
+}
+
+// write encodes the given value as json into the file at the given path, through a temporary file so that concurrent
+// readers never see a partially written entry.
+func (cache *DiskParseCache) write(path string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(cache.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func (cache *DiskParseCache) indexPath(configPath string) string {
+	hash := sha256.Sum256([]byte(configPath))
+	return filepath.Join(cache.Dir, "index", hex.EncodeToString(hash[:])+".json")
+}
+
+// entryPath returns the path of the entry of the unit with the given configuration and fingerprint. The configuration
+// path is part of the key, as units with the same files relative to their directory have the same fingerprint.
+func (cache *DiskParseCache) entryPath(configPath string, fingerprint string) string {
+	hash := sha256.Sum256([]byte(configPath + "\x00" + fingerprint))
+	return filepath.Join(cache.Dir, "units", hex.EncodeToString(hash[:])+".json")
+}
@@ -0,0 +1,165 @@
+package terragrunt
+
+import (
+	"crypto/sha256"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Parser parses terragrunt configurations with a fixed set of options, and memoizes the files it reads and parses
+// across calls: the root configurations included by the units of a repository are read and parsed once, instead of
+// once per unit. A Parser is safe for concurrent use, so that a single instance can serve the requests of an API.
+//
+// The contents of the files are memoized by path, and read again when their size or modification time changes. They
+// are not memoized for the calls given their own WithFS, the parsed files being memoized by content in any case. The
+// caches given through the options, such as WithParseCache or WithAssumeRoleCache, are shared by the calls.
+//
+// The tables of functions are not memoized across calls: they are built once per configuration parsed, as the
+// functions are bound to it (its path, its includes, the role it assumes, ...).
+type Parser struct {
+	opts  []Option
+	cache *fileCache
+}
+
+// NewParser returns a Parser with the given options, on top of which the options of each call are applied.
+func NewParser(opts ...Option) *Parser {
+	return &Parser{opts: opts, cache: newFileCache()}
+}
+
+// ParseConfig is like the ParseConfig function, with the options and the memoized files of the parser.
+func (parser *Parser) ParseConfig(content []byte, opts ...Option) (*TerragruntConfig, error) {
+	return ParseConfig(content, parser.options(opts)...)
+}
+
+// ParseConfigFile is like the ParseConfigFile function, with the options and the memoized files of the parser.
+func (parser *Parser) ParseConfigFile(configPath string, opts ...Option) (*TerragruntConfig, error) {
+	return ParseConfigFile(configPath, parser.options(opts)...)
+}
+
+// ParseConfigSafe is like the ParseConfigSafe function, with the options and the memoized files of the parser.
+func (parser *Parser) ParseConfigSafe(content []byte, opts ...Option) (*TerragruntConfig, error) {
+	return ParseConfigSafe(content, parser.options(opts)...)
+}
+
+// ParseConfigFileSafe is like the ParseConfigFileSafe function, with the options and the memoized files of the parser.
+func (parser *Parser) ParseConfigFileSafe(configPath string, opts ...Option) (*TerragruntConfig, error) {
+	return ParseConfigFileSafe(configPath, parser.options(opts)...)
+}
+
+// ParseStack is like the ParseStack function, with the options and the memoized files of the parser.
+func (parser *Parser) ParseStack(root string, opts ...Option) (*Stack, error) {
+	return ParseStack(root, parser.options(opts)...)
+}
+
+// Reset drops the files memoized by the parser.
+func (parser *Parser) Reset() {
+	parser.cache.reset()
+}
+
+// options returns the options of the parser followed by the given ones, with the memoization of the parser.
+func (parser *Parser) options(opts []Option) []Option {
+	cacheContents := newParseOptions(opts).FS == nil
+	options := make([]Option, 0, len(parser.opts)+len(opts)+1)
+	options = append(options, parser.opts...)
+	options = append(options, opts...)
+	return append(options, withFileCache(parser.cache, cacheContents))
+}
+
+// withFileCache memoizes the files read and parsed in the given cache. The contents of the files, which are keyed by
+// path, are only memoized with cacheContents.
+func withFileCache(cache *fileCache, cacheContents bool) Option {
+	return func(opts *ParseOptions) {
+		opts.fileCache = cache
+		opts.cacheFileContents = cacheContents
+	}
+}
+
+// fileCache memoizes the contents of the files read while parsing, and the files parsed. Both are keyed by path, so
+// that a file changing replaces its previous entries instead of adding to them.
+type fileCache struct {
+	mutex    sync.Mutex
+	contents map[string]cachedContent
+	parsed   map[string]cachedFile
+}
+
+// cachedContent is the content of a file, along with the size and modification time it was read at.
+type cachedContent struct {
+	content []byte
+	size    int64
+	modTime time.Time
+}
+
+// cachedFile is a parsed file, along with the hash of the content it was parsed from.
+type cachedFile struct {
+	sum  [sha256.Size]byte
+	file *hcl.File
+}
+
+func newFileCache() *fileCache {
+	cache := &fileCache{}
+	cache.reset()
+	return cache
+}
+
+func (cache *fileCache) reset() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.contents = map[string]cachedContent{}
+	cache.parsed = map[string]cachedFile{}
+}
+
+// readFile returns the content of the file at the given path, read with the given options unless it was already read
+// with the same size and modification time.
+func (cache *fileCache) readFile(path string, opts *ParseOptions) ([]byte, error) {
+	var info fs.FileInfo
+	var err error
+	if opts.FS == nil {
+		info, err = os.Stat(path)
+	} else {
+		info, err = fs.Stat(opts.FS, fsPath(path))
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return opts.readFileContent(path)
+	}
+
+	cache.mutex.Lock()
+	cached, found := cache.contents[path]
+	cache.mutex.Unlock()
+	if found && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.content, nil
+	}
+
+	content, err := opts.readFileContent(path)
+	if err != nil {
+		return nil, err
+	}
+	cache.mutex.Lock()
+	cache.contents[path] = cachedContent{content: content, size: info.Size(), modTime: info.ModTime()}
+	cache.mutex.Unlock()
+	return content, nil
+}
+
+// parseHCL is like the parseHCL function, returning the file already parsed from the same content with the same
+// filename, if any. The parsed files are shared: they must not be modified.
+func (cache *fileCache) parseHCL(content []byte, filename string) (*hcl.File, error) {
+	sum := sha256.Sum256(content)
+	cache.mutex.Lock()
+	cached, found := cache.parsed[filename]
+	cache.mutex.Unlock()
+	if found && cached.sum == sum {
+		return cached.file, nil
+	}
+
+	file, err := parseHCL(content, filename)
+	if err != nil {
+		return nil, err
+	}
+	cache.mutex.Lock()
+	cache.parsed[filename] = cachedFile{sum: sum, file: file}
+	cache.mutex.Unlock()
+	return file, nil
+}
@@ -0,0 +1,212 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// includePaths evaluates the path of the include blocks of the configuration being parsed, for the include related
+// functions. The paths are only evaluated when one of the functions is called, as they can be called while evaluating
+// the locals, before the included configurations are merged.
+type includePaths struct {
+	body hcl.Body
+
+	resolved  bool
+	resolving bool
+	paths     map[string]string
+	err       error
+}
+
+// path returns the absolute path of the configuration included by the include block with the given name. An empty
+// name selects the bare include block, or the only include block when there is just one. The returned path is empty
+// when there is no such include block.
+func (includes *includePaths) path(name string, opts *ParseOptions) (string, error) {
+	if includes == nil {
+		return "", nil
+	}
+
+	if !includes.resolved {
+		if includes.resolving {
+			return "", errors.New("the path of an include block can not depend on include related functions")
+		}
+		includes.resolving = true
+		includes.paths, includes.err = decodeIncludePaths(includes.body, opts)
+		includes.resolving, includes.resolved = false, true
+	}
+	if includes.err != nil {
+		return "", includes.err
+	}
+
+	if path, found := includes.paths[name]; found || name != "" {
+		if !found {
+			return "", fmt.Errorf("no include block named %s", name)
+		}
+		return path, nil
+	}
+	if len(includes.paths) == 1 {
+		for _, path := range includes.paths {
+			return path, nil
+		}
+	}
+	if len(includes.paths) > 1 {
+		return "", errors.New("the configuration has multiple include blocks, the name of the include must be given")
+	}
+	return "", nil
+}
+
+// decodeIncludePaths decodes the include blocks of the body, and returns their absolute path keyed by name.
+func decodeIncludePaths(body hcl.Body, opts *ParseOptions) (map[string]string, error) {
+	includes, err := decodeIncludes(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[string]string{}
+	for _, include := range includes {
+		paths[include.Name] = include.Path
+	}
+	return paths, nil
+}
+
+// originalDir returns the directory of the configuration originally being parsed. It differs from the working
+// directory when parsing a configuration included by another one.
+func (opts *ParseOptions) originalDir() string {
+	if opts.originalConfigPath == "" {
+		return opts.workingDir()
+	}
+	dir := filepath.Dir(opts.originalConfigPath)
+	if absDir, err := filepath.Abs(dir); err == nil {
+		return absDir
+	}
+	return dir
+}
+
+// includeRelativeDirs returns the directory of the including configuration and the directory of the included one,
+// for the given include name. Both are the working directory when there is no include.
+func includeRelativeDirs(name string, opts *ParseOptions) (child string, parent string, err error) {
+	if opts.originalConfigPath != "" {
+		return opts.originalDir(), opts.workingDir(), nil
+	}
+
+	includePath, err := opts.includes.path(name, opts)
+	if err != nil || includePath == "" {
+		return opts.workingDir(), opts.workingDir(), err
+	}
+	return opts.workingDir(), filepath.Dir(includePath), nil
+}
+
+// findRepoRoot returns the root of the git repository containing the given directory.
+func findRepoRoot(dir string, opts *ParseOptions) (string, error) {
+	for current := dir; ; {
+		if _, err := opts.stat(filepath.Join(current, ".git")); err == nil {
+			return current, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("%s is not in a git repository", dir)
+		}
+		current = parent
+	}
+}
+
+// relativeSlashPath returns the path of target relative to base, with forward slashes.
+func relativeSlashPath(base string, target string) (string, error) {
+	relPath, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+// pathFunctions returns the path related functions:
+//   - get_terragrunt_dir(): the directory of the configuration.
+//   - get_original_terragrunt_dir(): the directory of the configuration originally being parsed, which differs from
+//     get_terragrunt_dir() in included configurations.
+//   - get_parent_terragrunt_dir([name]): the directory of the included configuration.
+//   - path_relative_to_include([name]): the path of the including configuration relative to the included one.
+//   - path_relative_from_include([name]): the path of the included configuration relative to the including one.
+//   - get_repo_root(): the root of the git repository containing the configuration.
+//   - get_path_from_repo_root(): the path of the configuration relative to the repository root.
+//   - get_path_to_repo_root(): the path of the repository root relative to the configuration.
+func pathFunctions(opts *ParseOptions) map[string]function.Function {
+	stringFunc := func(impl func() (string, error)) function.Function {
+		return function.New(&function.Spec{
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				value, err := impl()
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(value), nil
+			},
+		})
+	}
+
+	includeFunc := func(impl func(child string, parent string) (string, error)) function.Function {
+		return function.New(&function.Spec{
+			VarParam: &function.Parameter{Name: "name", Type: cty.String},
+			Type:     function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				if len(args) > 1 {
+					return cty.NilVal, fmt.Errorf("expected at most 1 argument, got %d", len(args))
+				}
+				name := ""
+				if len(args) == 1 {
+					name = args[0].AsString()
+				}
+
+				child, parent, err := includeRelativeDirs(name, opts)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				value, err := impl(child, parent)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(value), nil
+			},
+		})
+	}
+
+	repoRelativeFunc := func(impl func(repoRoot string) (string, error)) function.Function {
+		return stringFunc(func() (string, error) {
+			repoRoot, err := findRepoRoot(opts.workingDir(), opts)
+			if err != nil {
+				return "", err
+			}
+			return impl(repoRoot)
+		})
+	}
+
+	return map[string]function.Function{
+		"get_terragrunt_dir": stringFunc(func() (string, error) {
+			return filepath.ToSlash(opts.workingDir()), nil
+		}),
+		"get_original_terragrunt_dir": stringFunc(func() (string, error) {
+			return filepath.ToSlash(opts.originalDir()), nil
+		}),
+		"get_parent_terragrunt_dir": includeFunc(func(child string, parent string) (string, error) {
+			return filepath.ToSlash(parent), nil
+		}),
+		"path_relative_to_include": includeFunc(func(child string, parent string) (string, error) {
+			return relativeSlashPath(parent, child)
+		}),
+		"path_relative_from_include": includeFunc(func(child string, parent string) (string, error) {
+			return relativeSlashPath(child, parent)
+		}),
+		"get_repo_root": repoRelativeFunc(func(repoRoot string) (string, error) {
+			return filepath.ToSlash(repoRoot), nil
+		}),
+		"get_path_from_repo_root": repoRelativeFunc(func(repoRoot string) (string, error) {
+			return relativeSlashPath(repoRoot, opts.workingDir())
+		}),
+		"get_path_to_repo_root": repoRelativeFunc(func(repoRoot string) (string, error) {
+			return relativeSlashPath(opts.workingDir(), repoRoot)
+		}),
+	}
+}
@@ -0,0 +1,188 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// PolicyResult is a message produced by a policy rule for the configuration of a unit.
+type PolicyResult struct {
+	// Rule is the name of the rule producing the message (e.g. deny or warn).
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// PolicyEvaluator evaluates policies against the json document describing a unit, and returns the resulting messages.
+// The document holds the path of the unit relative to the stack root, and its configuration as rendered by
+// RenderJSON (terraform, remote_state, dependencies and inputs).
+type PolicyEvaluator interface {
+	Evaluate(ctx context.Context, input []byte) ([]PolicyResult, error)
+}
+
+// PolicyViolation is a message produced by a policy rule for a unit of a stack.
+type PolicyViolation struct {
+	Unit *Unit
+	PolicyResult
+}
+
+// policyInput is the json document the policies are evaluated against.
+type policyInput struct {
+	Path string `json:"path"`
+	renderedConfig
+}
+
+// EvaluatePolicies evaluates the policies of the given evaluator against every unit of the stack, and returns the
+// resulting violations, ordered by unit. Units whose configuration could not be parsed are skipped.
+func EvaluatePolicies(ctx context.Context, stack *Stack, evaluator PolicyEvaluator) ([]PolicyViolation, error) {
+	var violations []PolicyViolation
+	for _, unit := range stack.Units {
+		if unit.Config == nil {
+			continue
+		}
+
+		path, err := filepath.Rel(stack.Root, unit.Path)
+		if err != nil {
+			path = unit.Path
+		}
+		input, err := json.Marshal(policyInput{Path: filepath.ToSlash(path), renderedConfig: newRenderedConfig(unit.Config)})
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := evaluator.Evaluate(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", unit.ConfigPath, err)
+		}
+		for _, result := range results {
+			violations = append(violations, PolicyViolation{Unit: unit, PolicyResult: result})
+		}
+	}
+	return violations, nil
+}
+
+// ExecOPAEvaluator evaluates Rego policies by running the opa binary. Following the conftest conventions, the deny
+// and violation rules of the policy package report errors, and the warn rule reports warnings. Their messages are
+// either strings, or objects with a msg attribute:
+//
+//	package terragrunt
+//
+//	deny[msg] {
+//		input.remote_state.backend == "s3"
+//		not allowed_buckets[input.remote_state.config.bucket]
+//		msg := sprintf("state bucket %s is not allowed", [input.remote_state.config.bucket])
+//	}
+type ExecOPAEvaluator struct {
+	// Command is the opa binary to run, by name or by path. It is required.
+	Command string
+
+	// Policies are the paths of the .rego files, or of the directories holding them, to evaluate. Data files (json
+	// or yaml) can be given too.
+	Policies []string
+
+	// Package is the package of the policy rules. Defaults to terragrunt.
+	Package string
+
+	// Runner runs the opa binary. Defaults to ExecCommandRunner.
+	Runner CommandRunner
+}
+
+// policyRuleSeverities are the severities of the rules evaluated by ExecOPAEvaluator.
+var policyRuleSeverities = map[string]Severity{
+	"deny":      SeverityError,
+	"violation": SeverityError,
+	"warn":      SeverityWarning,
+}
+
+func (evaluator ExecOPAEvaluator) Evaluate(ctx context.Context, input []byte) ([]PolicyResult, error) {
+	command := evaluator.Command
+	if command == "" {
+		return nil, errors.New("no opa binary is configured")
+	}
+	pkg := evaluator.Package
+	if pkg == "" {
+		pkg = "terragrunt"
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, policy := range evaluator.Policies {
+		args = append(args, "--data", policy)
+	}
+	args = append(args, "data."+pkg)
+
+	commandOutput, err := commandRunner(evaluator.Runner).Run(ctx, Command{Name: command, Args: args, Stdin: input})
+	if err != nil {
+		return nil, fmt.Errorf("%s %v: %w: %s", command, args, err, bytes.TrimSpace(commandOutput.Stderr))
+	}
+
+	var output struct {
+		Result []struct {
+			Expressions []struct {
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(commandOutput.Stdout, &output); err != nil {
+		return nil, fmt.Errorf("decoding the output of %s: %w", command, err)
+	}
+
+	var results []PolicyResult
+	for _, result := range output.Result {
+		for _, expression := range result.Expressions {
+			for _, rule := range sortedPolicyRules(expression.Value) {
+				messages, err := policyMessages(expression.Value[rule])
+				if err != nil {
+					return nil, fmt.Errorf("rule %s: %w", rule, err)
+				}
+				for _, message := range messages {
+					results = append(results, PolicyResult{Rule: rule, Severity: policyRuleSeverities[rule], Message: message})
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// sortedPolicyRules returns the rules of the given package value that report messages, sorted.
+func sortedPolicyRules(value map[string]json.RawMessage) []string {
+	var rules []string
+	for rule := range value {
+		if _, found := policyRuleSeverities[rule]; found {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// policyMessages decodes the messages of a rule, which is a set of strings or of objects with a msg attribute.
+func policyMessages(value json.RawMessage) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(value, &items); err != nil {
+		return nil, err
+	}
+
+	messages := make([]string, 0, len(items))
+	for _, item := range items {
+		var message string
+		if err := json.Unmarshal(item, &message); err == nil {
+			messages = append(messages, message)
+			continue
+		}
+
+		var object struct {
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal(item, &object); err != nil || object.Msg == "" {
+			messages = append(messages, string(item))
+			continue
+		}
+		messages = append(messages, object.Msg)
+	}
+	return messages, nil
+}
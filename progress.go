@@ -0,0 +1,175 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ProgressEventType identifies the kind of a ProgressEvent.
+type ProgressEventType string
+
+const (
+	// ProgressUnitStarted is sent when the parsing or the run of a unit starts.
+	ProgressUnitStarted ProgressEventType = "unit_started"
+
+	// ProgressUnitFinished is sent when the parsing or the run of a unit finishes, with its duration and its error,
+	// if any.
+	ProgressUnitFinished ProgressEventType = "unit_finished"
+
+	// ProgressOutputFetched is sent once the outputs of a dependency block of a unit have been retrieved.
+	ProgressOutputFetched ProgressEventType = "output_fetched"
+
+	// ProgressBatchCompleted is sent when every unit of a batch of RunBatches has run.
+	ProgressBatchCompleted ProgressEventType = "batch_completed"
+
+	// ProgressDiagnostics is sent when the configuration of a unit is invalid, with its diagnostics, before the
+	// unit_finished event.
+	ProgressDiagnostics ProgressEventType = "diagnostics"
+)
+
+// ProgressEvent is an event of a long-running operation, such as ParseStack or RunBatches, for CLIs and TUIs to render
+// live progress. The fields that do not apply to its type are left empty.
+type ProgressEvent struct {
+	Type ProgressEventType
+	Time time.Time
+
+	// Unit is the absolute path of the unit the event is about.
+	Unit string
+
+	// Dependency is the name of the dependency block whose outputs were retrieved, and DependencyPath the absolute
+	// path of its unit.
+	Dependency     string
+	DependencyPath string
+
+	// Batch is the index of the batch of RunBatches the event is about, starting at 1, or 0 outside of RunBatches.
+	Batch int
+
+	// Duration is the duration of the unit or of the batch that finished.
+	Duration time.Duration
+
+	// Err is the error of the unit or of the batch that finished, if any.
+	Err error
+
+	// Diagnostics are the diagnostics of the configuration of the unit.
+	Diagnostics hcl.Diagnostics
+}
+
+// WithProgress sends the progress events of ParseStack, of the parsing of configurations and of RunBatches to the given
+// channel. Sending blocks until the event is received or the context of the operation is done, so the channel must
+// be drained, e.g. by a goroutine rendering the progress, or buffered.
+func WithProgress(events chan<- ProgressEvent) Option {
+	return func(opts *ParseOptions) {
+		opts.Progress = events
+	}
+}
+
+// sendProgress sends the given event to the Progress channel, if any, unless the context is done first.
+func (opts *ParseOptions) sendProgress(event ProgressEvent) {
+	if opts.Progress == nil {
+		return
+	}
+	event.Time = time.Now()
+	select {
+	case opts.Progress <- event:
+	case <-opts.Context.Done():
+	}
+}
+
+// unitPath returns the absolute path of the unit being parsed, which is the one including the configuration being
+// parsed, if any.
+func (opts *ParseOptions) unitPath() string {
+	configPath := opts.ConfigPath
+	if opts.originalConfigPath != "" {
+		configPath = opts.originalConfigPath
+	}
+	if absPath, err := filepath.Abs(configPath); err == nil {
+		configPath = absPath
+	}
+	return filepath.Dir(configPath)
+}
+
+// sendUnitProgress sends the events of the given unit, once parsed since the given start.
+func (opts *ParseOptions) sendUnitProgress(unit *Unit, start time.Time) {
+	var diags hcl.Diagnostics
+	if errors.As(unit.Err, &diags) {
+		opts.sendProgress(ProgressEvent{Type: ProgressDiagnostics, Unit: unit.Path, Diagnostics: diags})
+	}
+	opts.sendProgress(ProgressEvent{Type: ProgressUnitFinished, Unit: unit.Path, Duration: time.Since(start), Err: unit.Err})
+}
+
+// UnitRunError is the error of RunBatches when running a unit fails.
+type UnitRunError struct {
+	Path string
+	Err  error
+}
+
+func (err *UnitRunError) Error() string {
+	return fmt.Sprintf("unit %s: %s", err.Path, err.Err)
+}
+
+func (err *UnitRunError) Unwrap() error {
+	return err.Err
+}
+
+// RunBatches runs the given function on the units of the given batches, as returned by Graph.Batches or
+// Graph.DestroyPlan, for tools running the units: a batch starts once every unit of the previous one ran, and the units
+// of a batch run concurrently, at most parallelism at once when positive. The batches following a failed unit are not
+// run, and the error of the first unit failing, in the order of its batch, is returned as a *UnitRunError. Pass
+// WithProgress to receive the unit_started, unit_finished and batch_completed events, and WithContext to cancel the
+// runs.
+func RunBatches(batches [][]string, parallelism int, run func(ctx context.Context, path string) error, opts ...Option) error {
+	parseOptions := newParseOptions(opts)
+	ctx := parseOptions.Context
+	if parallelism <= 0 {
+		parallelism = 1
+		for _, batch := range batches {
+			if len(batch) > parallelism {
+				parallelism = len(batch)
+			}
+		}
+	}
+	slots := make(chan struct{}, parallelism)
+
+	for i, batch := range batches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for j, path := range batch {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(j int, path string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				parseOptions.sendProgress(ProgressEvent{Type: ProgressUnitStarted, Unit: path, Batch: i + 1})
+				unitStart := time.Now()
+				errs[j] = run(ctx, path)
+				parseOptions.sendProgress(ProgressEvent{Type: ProgressUnitFinished, Unit: path, Batch: i + 1, Duration: time.Since(unitStart), Err: errs[j]})
+			}(j, path)
+		}
+		wg.Wait()
+
+		var batchErr error
+		for j, err := range errs {
+			if err != nil {
+				batchErr = &UnitRunError{Path: batch[j], Err: err}
+				break
+			}
+		}
+		parseOptions.sendProgress(ProgressEvent{Type: ProgressBatchCompleted, Batch: i + 1, Duration: time.Since(start), Err: batchErr})
+		if batchErr != nil {
+			return batchErr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package terragrunt
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Origin records where an attribute of the resolved configuration is set.
+type Origin struct {
+	// Path is the path of the attribute, such as inputs.region, terraform.source or dependency.vpc.
+	Path string
+
+	// Range is the range of the source setting the attribute, which also holds the name of its file.
+	Range hcl.Range
+}
+
+// provenanceSchema selects the attributes and blocks of a configuration that provenance is tracked for.
+var provenanceSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "inputs"},
+		{Name: "terraform_binary"},
+		{Name: "skip"},
+		{Name: "prevent_destroy"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "remote_state"},
+		{Type: "engine"},
+		{Type: "exclude"},
+		{Type: "dependency", LabelNames: []string{"name"}},
+		{Type: "generate", LabelNames: []string{"name"}},
+		{Type: "locals"},
+	},
+}
+
+// Provenance returns where every attribute of the configuration is set, sorted by path. Inputs are reported
+// individually when they are set by an object literal, and as a whole otherwise (e.g. when computed by merge()).
+func (config *TerragruntConfig) Provenance() []Origin {
+	origins := make([]Origin, 0, len(config.provenance))
+	for path, origin := range config.provenance {
+		origins = append(origins, Origin{Path: path, Range: origin})
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		return origins[i].Path < origins[j].Path
+	})
+	return origins
+}
+
+// Origin returns where the attribute with the given path is set. Attributes that are not tracked individually, such as
+// nested input attributes, are reported at the origin of their closest tracked parent.
+func (config *TerragruntConfig) Origin(path string) (Origin, bool) {
+	for current := path; current != ""; current = parentPath(current) {
+		origin, found := config.provenance[current]
+		if !found {
+			continue
+		}
+		// When the children of the parent are tracked individually, the attribute would have been one of them.
+		if current != path && config.hasTrackedChildren(current) {
+			return Origin{}, false
+		}
+		return Origin{Path: current, Range: origin}, true
+	}
+	return Origin{}, false
+}
+
+// parentPath returns the path of the parent of the attribute with the given path, or an empty string for top level
+// attributes.
+func parentPath(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// hasTrackedChildren returns whether attributes under the given path are tracked individually.
+func (config *TerragruntConfig) hasTrackedChildren(path string) bool {
+	for tracked := range config.provenance {
+		if strings.HasPrefix(tracked, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// configProvenance returns the ranges setting each attribute of the given configuration, keyed by path.
+func configProvenance(body hcl.Body, config *TerragruntConfig) map[string]hcl.Range {
+	provenance := map[string]hcl.Range{}
+
+	content, _, _ := body.PartialContent(provenanceSchema)
+	if content == nil {
+		return provenance
+	}
+
+	for _, name := range []string{"terraform_binary", "skip", "prevent_destroy"} {
+		if attribute, found := content.Attributes[name]; found {
+			provenance[name] = attribute.Range
+		}
+	}
+	if attribute, found := content.Attributes["inputs"]; found {
+		provenance["inputs"] = attribute.Range
+		for name, itemRange := range objectItemRanges(attribute.Expr) {
+			provenance["inputs."+name] = itemRange
+		}
+		for name := range config.InputsCty {
+			if _, found := provenance["inputs."+name]; !found {
+				provenance["inputs."+name] = attribute.Range
+			}
+		}
+	}
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "terraform", "remote_state", "engine", "exclude":
+			provenance[block.Type] = block.DefRange
+			attributes, _ := block.Body.JustAttributes()
+			for name, attribute := range attributes {
+				provenance[block.Type+"."+name] = attribute.Range
+			}
+		case "dependency", "generate":
+			provenance[block.Type+"."+block.Labels[0]] = block.DefRange
+		case "locals":
+			attributes, _ := block.Body.JustAttributes()
+			for name, attribute := range attributes {
+				provenance["locals."+name] = attribute.Range
+			}
+		}
+	}
+	return provenance
+}
+
+// objectItemRanges returns the range of each item of the given expression, keyed by name, when it is an object
+// literal with static keys.
+func objectItemRanges(expr hcl.Expression) map[string]hcl.Range {
+	items, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		return nil
+	}
+
+	ranges := map[string]hcl.Range{}
+	for _, item := range items {
+		key, diags := item.Key.Value(nil)
+		if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+			continue
+		}
+		ranges[key.AsString()] = hcl.RangeBetween(item.Key.Range(), item.Value.Range())
+	}
+	return ranges
+}
@@ -0,0 +1,296 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultProviderRegistryHost is the host of provider sources that do not specify one.
+const DefaultProviderRegistryHost = "registry.terraform.io"
+
+// ProviderRequirement is a provider required by a module, as declared in its required_providers block.
+type ProviderRequirement struct {
+	// Name is the local name of the provider in the module (e.g. aws).
+	Name string
+
+	// Source is the fully qualified address of the provider (e.g. registry.terraform.io/hashicorp/aws).
+	Source string
+
+	// VersionConstraints are the version constraints of the provider (e.g. ~> 5.0), one per declaration.
+	VersionConstraints []string
+
+	// Range is the range of the declaration of the provider.
+	Range hcl.Range
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "required_providers"},
+	},
+}
+
+// decodeRequiredProviders adds the providers of the required_providers blocks of the given terraform block body to
+// the module. Like terraform, it accepts both the object form and the legacy version string form.
+func (module *Module) decodeRequiredProviders(body hcl.Body) hcl.Diagnostics {
+	content, _, diags := body.PartialContent(terraformBlockSchema)
+	for _, block := range content.Blocks {
+		attributes, attributeDiags := block.Body.JustAttributes()
+		diags = append(diags, attributeDiags...)
+
+		for name, attribute := range attributes {
+			value, valueDiags := attribute.Expr.Value(nil)
+			diags = append(diags, valueDiags...)
+			if valueDiags.HasErrors() {
+				continue
+			}
+
+			requirement := module.RequiredProviders[name]
+			if requirement == nil {
+				requirement = &ProviderRequirement{Name: name, Source: normalizeProviderSource(name), Range: attribute.Range}
+				module.RequiredProviders[name] = requirement
+			}
+
+			var constraint cty.Value
+			switch {
+			case value.Type() == cty.String:
+				constraint = value
+			case value.Type().IsObjectType():
+				if value.Type().HasAttribute("source") {
+					source := value.GetAttr("source")
+					if source.Type() == cty.String && source.IsKnown() && !source.IsNull() {
+						requirement.Source = normalizeProviderSource(source.AsString())
+					}
+				}
+				if value.Type().HasAttribute("version") {
+					constraint = value.GetAttr("version")
+				}
+			default:
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid provider requirement",
+					Detail:   fmt.Sprintf("The requirement of provider %q must be an object or a version string.", name),
+					Subject:  attribute.Range.Ptr(),
+				})
+				continue
+			}
+
+			if constraint != cty.NilVal && constraint.Type() == cty.String && constraint.IsKnown() && !constraint.IsNull() {
+				requirement.VersionConstraints = append(requirement.VersionConstraints, constraint.AsString())
+			}
+		}
+	}
+	return diags
+}
+
+// normalizeProviderSource returns the fully qualified address of the given provider source: the default registry
+// host and the hashicorp namespace are added when missing, and the address is lower-cased.
+func normalizeProviderSource(source string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(source)), "/")
+	switch len(parts) {
+	case 1:
+		parts = []string{DefaultProviderRegistryHost, "hashicorp", parts[0]}
+	case 2:
+		parts = append([]string{DefaultProviderRegistryHost}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// ProviderUsage reports the units requiring a provider, and whether their version constraints conflict.
+type ProviderUsage struct {
+	// Source is the fully qualified address of the provider.
+	Source string
+
+	// Units are the requirements of the units using the provider, ordered by unit path.
+	Units []UnitProviderRequirement
+
+	// Conflict is set when no version of the provider satisfies the constraints of every unit, so that the units can
+	// not share a provider cache, or a lock file.
+	Conflict bool
+}
+
+// UnitProviderRequirement is the requirement of a provider by the module of a unit.
+type UnitProviderRequirement struct {
+	// Unit is the path of the unit.
+	Unit string
+
+	ProviderRequirement
+}
+
+// AggregateProviders reports the providers required by the given modules, keyed by the path of their unit, ordered
+// by source. Version constraints that can not be parsed are ignored when looking for conflicts.
+func AggregateProviders(modules map[string]*Module) []ProviderUsage {
+	usages := map[string]*ProviderUsage{}
+	for unit, module := range modules {
+		for _, requirement := range module.RequiredProviders {
+			usage := usages[requirement.Source]
+			if usage == nil {
+				usage = &ProviderUsage{Source: requirement.Source}
+				usages[requirement.Source] = usage
+			}
+			usage.Units = append(usage.Units, UnitProviderRequirement{Unit: unit, ProviderRequirement: *requirement})
+		}
+	}
+
+	report := make([]ProviderUsage, 0, len(usages))
+	for _, usage := range usages {
+		sort.Slice(usage.Units, func(i, j int) bool {
+			return usage.Units[i].Unit < usage.Units[j].Unit
+		})
+
+		var versions versionRange
+		for _, unit := range usage.Units {
+			for _, constraints := range unit.VersionConstraints {
+				versions.add(constraints)
+			}
+		}
+		usage.Conflict = versions.empty()
+		report = append(report, *usage)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Source < report[j].Source
+	})
+	return report
+}
+
+// versionBound is a lower or upper bound of a versionRange.
+type versionBound struct {
+	version   string
+	inclusive bool
+}
+
+// versionRange is the set of versions satisfying a list of version constraints, as an interval minus excluded
+// versions. A nil bound means that the range is not bounded on that side.
+type versionRange struct {
+	lower    *versionBound
+	upper    *versionBound
+	excluded []string
+}
+
+// versionConstraintOperators are the operators of version constraints, the two character ones first so that they are
+// matched as a whole.
+var versionConstraintOperators = []string{"!=", ">=", "<=", "~>", "=", ">", "<"}
+
+// add restricts the range to the versions satisfying the given comma separated version constraints (e.g.
+// ">= 4.0, < 6.0" or "~> 5.1"). Constraints that can not be parsed are ignored.
+func (versions *versionRange) add(constraints string) {
+	for _, constraint := range strings.Split(constraints, ",") {
+		constraint = strings.TrimSpace(constraint)
+		operator := ""
+		for _, candidate := range versionConstraintOperators {
+			if strings.HasPrefix(constraint, candidate) {
+				operator = candidate
+				break
+			}
+		}
+		version := strings.TrimSpace(constraint[len(operator):])
+		if _, ok := parseVersion(version); !ok {
+			continue
+		}
+
+		switch operator {
+		case "", "=":
+			versions.restrictLower(versionBound{version, true})
+			versions.restrictUpper(versionBound{version, true})
+		case "!=":
+			versions.excluded = append(versions.excluded, version)
+		case ">":
+			versions.restrictLower(versionBound{version, false})
+		case ">=":
+			versions.restrictLower(versionBound{version, true})
+		case "<":
+			versions.restrictUpper(versionBound{version, false})
+		case "<=":
+			versions.restrictUpper(versionBound{version, true})
+		case "~>":
+			versions.restrictLower(versionBound{version, true})
+			versions.restrictUpper(versionBound{pessimisticUpperBound(version), false})
+		}
+	}
+}
+
+// pessimisticUpperBound returns the exclusive upper bound of the ~> operator: only the rightmost version number given
+// may increase, so ~> 1.2.3 allows versions below 1.3.0, and ~> 1.2 versions below 2.0.0.
+func pessimisticUpperBound(version string) string {
+	parts := strings.Split(strings.SplitN(strings.TrimPrefix(version, "v"), "-", 2)[0], ".")
+	if len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	number, _ := strconv.Atoi(parts[len(parts)-1])
+	parts[len(parts)-1] = strconv.Itoa(number + 1)
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	return strings.Join(parts, ".")
+}
+
+func (versions *versionRange) restrictLower(bound versionBound) {
+	if versions.lower == nil {
+		versions.lower = &bound
+		return
+	}
+	switch comparison := CompareVersions(bound.version, versions.lower.version); {
+	case comparison > 0, comparison == 0 && !bound.inclusive:
+		versions.lower = &bound
+	}
+}
+
+func (versions *versionRange) restrictUpper(bound versionBound) {
+	if versions.upper == nil {
+		versions.upper = &bound
+		return
+	}
+	switch comparison := CompareVersions(bound.version, versions.upper.version); {
+	case comparison < 0, comparison == 0 && !bound.inclusive:
+		versions.upper = &bound
+	}
+}
+
+// empty returns whether no version satisfies the constraints of the range.
+func (versions *versionRange) empty() bool {
+	if versions.lower == nil || versions.upper == nil {
+		return false
+	}
+
+	switch comparison := CompareVersions(versions.lower.version, versions.upper.version); {
+	case comparison > 0:
+		return true
+	case comparison == 0:
+		if !versions.lower.inclusive || !versions.upper.inclusive {
+			return true
+		}
+		// The range is a single version, which may be excluded.
+		for _, excluded := range versions.excluded {
+			if CompareVersions(excluded, versions.lower.version) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contains returns whether the given version satisfies the constraints of the range.
+func (versions *versionRange) contains(version string) bool {
+	if versions.lower != nil {
+		comparison := CompareVersions(version, versions.lower.version)
+		if comparison < 0 || comparison == 0 && !versions.lower.inclusive {
+			return false
+		}
+	}
+	if versions.upper != nil {
+		comparison := CompareVersions(version, versions.upper.version)
+		if comparison > 0 || comparison == 0 && !versions.upper.inclusive {
+			return false
+		}
+	}
+	for _, excluded := range versions.excluded {
+		if CompareVersions(version, excluded) == 0 {
+			return false
+		}
+	}
+	return true
+}
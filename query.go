@@ -0,0 +1,64 @@
+package terragrunt
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Query evaluates the given HCL expression against the resolved configuration, which makes it possible to select
+// information from many units with the same expression (e.g. inputs.instance_type == "m5.large"). The expression can
+// reference the following variables, which mirror the json rendering of the configuration:
+// - inputs: The inputs of the configuration.
+// - terraform: The terraform block, with its source attribute.
+// - terraform_binary: The terraform binary.
+// - dependency: The dependency blocks, keyed by name, with their config_path and outputs attributes.
+// The terraform compatible standard library functions are available.
+func Query(config *TerragruntConfig, expression string) (cty.Value, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(expression), "query", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	ctx := &hcl.EvalContext{
+		Variables: queryVariables(config),
+		Functions: stdlibFunctions(),
+	}
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+	return value, nil
+}
+
+// queryVariables returns the variables the Query expressions are evaluated with.
+func queryVariables(config *TerragruntConfig) map[string]cty.Value {
+	inputs := cty.EmptyObjectVal
+	if len(config.InputsCty) > 0 {
+		inputs = cty.ObjectVal(config.InputsCty)
+	}
+
+	source := cty.NullVal(cty.String)
+	if config.Terraform != nil && config.Terraform.Source != nil {
+		source = cty.StringVal(*config.Terraform.Source)
+	}
+
+	dependencies := map[string]cty.Value{}
+	for _, dependency := range config.TerragruntDependencies {
+		outputs := cty.EmptyObjectVal
+		if dependency.RenderedOutputs != nil {
+			outputs = *dependency.RenderedOutputs
+		}
+		dependencies[dependency.Name] = cty.ObjectVal(map[string]cty.Value{
+			"config_path": cty.StringVal(dependency.ConfigPath),
+			"outputs":     outputs,
+		})
+	}
+
+	return map[string]cty.Value{
+		"inputs":           inputs,
+		"terraform":        cty.ObjectVal(map[string]cty.Value{"source": source}),
+		"terraform_binary": cty.StringVal(config.TerraformBinary),
+		"dependency":       cty.ObjectVal(dependencies),
+	}
+}
@@ -0,0 +1,190 @@
+package terragrunt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// RateLimiter is a token bucket limiting the rate of the calls to a remote service, such as a state backend or an AWS
+// API, so that many concurrent operations do not trip its throttling. The bucket holds up to burst tokens, and is
+// refilled at rate tokens per second. It is safe for concurrent use, and a nil RateLimiter does not limit anything.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing the given number of calls per second on average, and bursts of up to
+// the given number of calls. The bucket starts full.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until a call is allowed, and takes its token, or returns the error of the context when it is done first.
+func (limiter *RateLimiter) Wait(ctx context.Context) error {
+	if limiter == nil {
+		return nil
+	}
+
+	for {
+		delay := limiter.reserve(time.Now())
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token and returns 0 when one is available at the given time, or returns the delay until the next
+// one is otherwise.
+func (limiter *RateLimiter) reserve(now time.Time) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if !limiter.last.IsZero() {
+		limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+	}
+	limiter.last = now
+
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return 0
+	}
+	if limiter.rate <= 0 {
+		// A bucket that is never refilled is checked again from time to time, until the context is done.
+		return time.Second
+	}
+	return time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+// RateLimitedOutputResolver wraps an OutputResolver, limiting the rate at which it retrieves outputs, per type of
+// state backend: resolving hundreds of dependencies concurrently then stays under the throttling limits of each
+// backend (e.g. S3).
+type RateLimitedOutputResolver struct {
+	Resolver OutputResolver
+
+	// Limiters are the rate limiters of the state backends, keyed by backend type (e.g. s3 or gcs). The backend of
+	// a dependency is read from the literal backend attribute of the remote_state block of its configuration, or of
+	// the configurations it includes.
+	Limiters map[string]*RateLimiter
+
+	// Default limits the dependencies whose backend has no limiter, or is not known statically. They are not limited
+	// when it is nil.
+	Default *RateLimiter
+}
+
+func (resolver *RateLimitedOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	if err := resolver.wait(ctx, configPath); err != nil {
+		return nil, err
+	}
+	return resolver.Resolver.ResolveOutputs(ctx, configPath)
+}
+
+func (resolver *RateLimitedOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	if err := resolver.wait(ctx, configPath); err != nil {
+		return nil, err
+	}
+	return resolveOutputKeys(ctx, resolver.Resolver, configPath, keys)
+}
+
+// wait waits for the limiter of the backend of the dependency at the given config path.
+func (resolver *RateLimitedOutputResolver) wait(ctx context.Context, configPath string) error {
+	limiter := resolver.Default
+	if backendLimiter, found := resolver.Limiters[remoteStateBackend(configPath)]; found {
+		limiter = backendLimiter
+	}
+	return limiter.Wait(ctx)
+}
+
+// remoteStateBlockSchema selects the remote_state blocks of a configuration, and remoteStateBackendSchema their
+// backend attribute.
+var (
+	remoteStateBlockSchema   = &hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "remote_state"}}}
+	remoteStateBackendSchema = &hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "backend"}}}
+)
+
+// remoteStateBackend statically determines the backend type of the unit with the given config path: the literal
+// backend of the remote_state block of its configuration, or else of the last included configuration that has one.
+// It returns an empty string when the backend is not known statically.
+func remoteStateBackend(configPath string) string {
+	configPath = filepath.Join(configDir(configPath), DefaultConfigFilename)
+	body, found := readConfigBody(configPath)
+	if !found {
+		return ""
+	}
+	if backend := literalRemoteStateBackend(body); backend != "" {
+		return backend
+	}
+
+	includes, err := decodeIncludes(body, newParseOptions([]Option{WithConfigPath(configPath)}))
+	if err != nil {
+		return ""
+	}
+	for i := len(includes) - 1; i >= 0; i-- {
+		if includes[i].MergeStrategy == MergeNoMerge {
+			continue
+		}
+		if includedBody, found := readConfigBody(includes[i].Path); found {
+			if backend := literalRemoteStateBackend(includedBody); backend != "" {
+				return backend
+			}
+		}
+	}
+	return ""
+}
+
+func readConfigBody(configPath string) (hcl.Body, bool) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, false
+	}
+	file, err := parseHCL(content, configPath)
+	if err != nil {
+		return nil, false
+	}
+	return file.Body, true
+}
+
+func literalRemoteStateBackend(body hcl.Body) string {
+	content, _, _ := body.PartialContent(remoteStateBlockSchema)
+	if content == nil {
+		return ""
+	}
+	for _, block := range content.Blocks {
+		blockContent, _, _ := block.Body.PartialContent(remoteStateBackendSchema)
+		if blockContent == nil {
+			continue
+		}
+		attribute, found := blockContent.Attributes["backend"]
+		if !found {
+			continue
+		}
+		value, diags := attribute.Expr.Value(nil)
+		if !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+			return value.AsString()
+		}
+	}
+	return ""
+}
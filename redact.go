@@ -0,0 +1,50 @@
+package terragrunt
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// valueMark is the type of the cty marks set by this package.
+type valueMark string
+
+// SensitiveMark marks the values that must not be displayed, such as the dependency outputs declared sensitive in
+// terraform. The mark follows the values through expressions, so inputs computed from sensitive outputs are marked as
+// well.
+const SensitiveMark = valueMark("sensitive")
+
+// RedactedValue is the value that replaces the sensitive values in redacted output.
+const RedactedValue = "(sensitive)"
+
+// Redact returns the given value with every value marked as sensitive replaced by the "(sensitive)" string, so that it
+// can be safely rendered or logged. The remaining marks are removed. As a sensitive element of a list or map may not
+// have the type of its siblings anymore, collections containing sensitive values are returned as tuples and objects.
+func Redact(value cty.Value) cty.Value {
+	if value.HasMark(SensitiveMark) {
+		return cty.StringVal(RedactedValue)
+	}
+	value, _ = value.Unmark()
+
+	if !value.ContainsMarked() || value.IsNull() || !value.IsKnown() {
+		return value
+	}
+
+	valueType := value.Type()
+	switch {
+	case valueType.IsObjectType() || valueType.IsMapType():
+		attributes := map[string]cty.Value{}
+		for key, element := range value.AsValueMap() {
+			attributes[key] = Redact(element)
+		}
+		return cty.ObjectVal(attributes)
+	case valueType.IsTupleType() || valueType.IsListType() || valueType.IsSetType():
+		var elements []cty.Value
+		for _, element := range value.AsValueSlice() {
+			elements = append(elements, Redact(element))
+		}
+		if elements == nil {
+			return cty.EmptyTupleVal
+		}
+		return cty.TupleVal(elements)
+	}
+	return value
+}
@@ -0,0 +1,289 @@
+package terragrunt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedisPort is the port used for Redis servers whose address has none.
+const DefaultRedisPort = "6379"
+
+// redisMaxIdleConns is the number of connections a RedisOutputCache keeps open between commands.
+const redisMaxIdleConns = 4
+
+// RedisOutputCache is an OutputCache storing the outputs in a Redis server, so that a fleet of CI workers share the
+// outputs of dependencies instead of each retrieving them from the state backend. The expiration of the entries is
+// left to Redis. It is safe for concurrent use.
+type RedisOutputCache struct {
+	// Addr is the address of the Redis server, as host:port.
+	Addr string
+
+	// Username and Password authenticate to the server, when Password is set. Username is only needed with Redis ACLs.
+	Username string
+	Password string
+
+	// DB is the number of the database to use.
+	DB int
+
+	// KeyPrefix is prepended to the keys of the entries, to share a server with other applications.
+	KeyPrefix string
+
+	// TLSConfig, when set, makes connections to the server use TLS.
+	TLSConfig *tls.Config
+
+	// Timeout bounds the time to connect to the server and run each command. Defaults to 5 seconds.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	conns []*redisConn
+}
+
+// NewRedisOutputCache returns a RedisOutputCache configured from the given URL, in the redis://[user:password@]host
+// [:port][/db] format, or rediss:// to connect with TLS. The key_prefix query parameter sets the prefix of the keys.
+func NewRedisOutputCache(rawURL string) (*RedisOutputCache, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &RedisOutputCache{KeyPrefix: parsed.Query().Get("key_prefix")}
+	switch parsed.Scheme {
+	case "redis":
+	case "rediss":
+		cache.TLSConfig = &tls.Config{ServerName: parsed.Hostname()}
+	default:
+		return nil, fmt.Errorf("unsupported redis url scheme %q, expected redis or rediss", parsed.Scheme)
+	}
+
+	cache.Addr = parsed.Host
+	if parsed.Port() == "" {
+		cache.Addr = net.JoinHostPort(parsed.Hostname(), DefaultRedisPort)
+	}
+	if parsed.User != nil {
+		// redis://password@host carries the password alone, like redis://:password@host.
+		if password, hasPassword := parsed.User.Password(); hasPassword {
+			cache.Username, cache.Password = parsed.User.Username(), password
+		} else {
+			cache.Password = parsed.User.Username()
+		}
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" {
+		if cache.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return cache, nil
+}
+
+func (cache *RedisOutputCache) Get(key string) ([]byte, bool, error) {
+	reply, err := cache.do("GET", cache.KeyPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	outputs, isBulk := reply.([]byte)
+	if !isBulk {
+		return nil, false, fmt.Errorf("unexpected redis reply to GET: %v", reply)
+	}
+	return outputs, true, nil
+}
+
+func (cache *RedisOutputCache) Set(key string, outputs []byte, ttl time.Duration) error {
+	args := []string{"SET", cache.KeyPrefix + key, string(outputs)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := cache.do(args...)
+	return err
+}
+
+// do runs the given command on an idle connection, or a new one, and returns its reply: a string for status replies,
+// an int64 for integers, a []byte for bulk strings, nil for nil replies, and an []interface{} for arrays. Error
+// replies are returned as errors.
+func (cache *RedisOutputCache) do(args ...string) (interface{}, error) {
+	conn, err := cache.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(cache.timeout()))
+	reply, err := conn.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is in an unknown state after a network or protocol error.
+		conn.Close()
+		return nil, err
+	}
+	cache.release(conn)
+	return reply, err
+}
+
+// conn returns an idle connection, or a new authenticated connection when there is none.
+func (cache *RedisOutputCache) conn() (*redisConn, error) {
+	cache.mu.Lock()
+	if n := len(cache.conns); n > 0 {
+		conn := cache.conns[n-1]
+		cache.conns = cache.conns[:n-1]
+		cache.mu.Unlock()
+		return conn, nil
+	}
+	cache.mu.Unlock()
+
+	dialer := &net.Dialer{Timeout: cache.timeout()}
+	var netConn net.Conn
+	var err error
+	if cache.TLSConfig != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", cache.Addr, cache.TLSConfig)
+	} else {
+		netConn, err = dialer.Dial("tcp", cache.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	conn.SetDeadline(time.Now().Add(cache.timeout()))
+	if cache.Password != "" {
+		args := []string{"AUTH", cache.Password}
+		if cache.Username != "" {
+			args = []string{"AUTH", cache.Username, cache.Password}
+		}
+		if _, err := conn.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis authentication: %w", err)
+		}
+	}
+	if cache.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(cache.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// release makes the given connection available for the next commands, or closes it when enough are idle.
+func (cache *RedisOutputCache) release(conn *redisConn) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.conns) >= redisMaxIdleConns {
+		conn.Close()
+		return
+	}
+	cache.conns = append(cache.conns, conn)
+}
+
+// Close closes the idle connections to the server.
+func (cache *RedisOutputCache) Close() error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var err error
+	for _, conn := range cache.conns {
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	cache.conns = nil
+	return err
+}
+
+func (cache *RedisOutputCache) timeout() time.Duration {
+	if cache.Timeout > 0 {
+		return cache.Timeout
+	}
+	return 5 * time.Second
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+// redisConn is a connection to a Redis server, speaking the RESP protocol.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *redisConn) do(args ...string) (interface{}, error) {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(conn, command.String()); err != nil {
+		return nil, err
+	}
+	return conn.readReply()
+}
+
+func (conn *redisConn) readReply() (interface{}, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("invalid redis reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length %q", payload)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(conn.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length %q", payload)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		elements := make([]interface{}, count)
+		for i := range elements {
+			// Error elements are kept as values, so that the rest of the array is still read.
+			element, err := conn.readReply()
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				element, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	}
+	return nil, fmt.Errorf("invalid redis reply %q", line)
+}
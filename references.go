@@ -0,0 +1,291 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// dependencyReferences holds the dependency outputs referenced by a configuration: it maps each referenced dependency
+// name to the set of referenced output keys. A nil set means the outputs are referenced as a whole (e.g.
+// dependency.vpc.outputs), so that every key is used. A nil dependencyReferences means that the references are
+// unknown, and is treated as referencing every dependency.
+type dependencyReferences map[string]map[string]bool
+
+// findDependencyReferences statically analyzes the expressions of the given body, outside of the dependency blocks,
+// to find the referenced dependency outputs. It returns false when the body can not be analyzed, which is the case
+// for bodies not written in the native HCL syntax.
+func findDependencyReferences(body hcl.Body) (dependencyReferences, bool) {
+	syntaxBody, isSyntaxBody := body.(*hclsyntax.Body)
+	if !isSyntaxBody {
+		return nil, false
+	}
+
+	references := dependencyReferences{}
+	for _, traversal := range bodyVariables(syntaxBody, "dependency") {
+		if traversal.RootName() != "dependency" {
+			continue
+		}
+
+		// When the dependency is selected dynamically (e.g. dependency[local.name]), any of them can be referenced.
+		if len(traversal) < 2 {
+			return nil, true
+		}
+		name, isName := traversalStepName(traversal[1])
+		if !isName {
+			return nil, true
+		}
+
+		key, isKey := "", false
+		if len(traversal) >= 4 {
+			if outputs, _ := traversalStepName(traversal[2]); outputs == "outputs" {
+				key, isKey = traversalStepName(traversal[3])
+			}
+		}
+
+		keys, seen := references[name]
+		switch {
+		case !isKey:
+			references[name] = nil
+		case !seen:
+			references[name] = map[string]bool{key: true}
+		case keys != nil:
+			keys[key] = true
+		}
+	}
+	return references, true
+}
+
+// includes returns whether the outputs of the given dependency are referenced.
+func (references dependencyReferences) includes(name string) bool {
+	if references == nil {
+		return true
+	}
+	_, found := references[name]
+	return found
+}
+
+// outputKeys returns the sorted output keys of the given dependency that are referenced, or nil when every output is
+// used.
+func (references dependencyReferences) outputKeys(name string) []string {
+	keys := references[name]
+	if keys == nil {
+		return nil
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// filterOutputs returns the given outputs of the dependency restricted to the referenced keys.
+func (references dependencyReferences) filterOutputs(name string, outputs *cty.Value) *cty.Value {
+	keys := references[name]
+	if references == nil || keys == nil || outputs == nil || *outputs == cty.NilVal {
+		return outputs
+	}
+	unmarked, marks := outputs.Unmark()
+	if !unmarked.Type().IsObjectType() || !unmarked.IsKnown() || unmarked.IsNull() {
+		return outputs
+	}
+
+	filtered := map[string]cty.Value{}
+	for key, value := range unmarked.AsValueMap() {
+		if keys[key] {
+			filtered[key] = value
+		}
+	}
+	filteredOutputs := objectVal(filtered).WithMarks(marks)
+	return &filteredOutputs
+}
+
+// bodyVariables returns the variables referenced by the expressions of the given body and its nested blocks, skipping
+// the blocks of the given types.
+func bodyVariables(body *hclsyntax.Body, skipBlockTypes ...string) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	for _, attribute := range body.Attributes {
+		traversals = append(traversals, attribute.Expr.Variables()...)
+	}
+
+	for _, block := range body.Blocks {
+		if containsString(skipBlockTypes, block.Type) {
+			continue
+		}
+		traversals = append(traversals, bodyVariables(block.Body, skipBlockTypes...)...)
+	}
+	return traversals
+}
+
+// unguardedVariables returns the variables referenced by the expressions of the given body and its nested blocks,
+// skipping the blocks of the given types, like bodyVariables, but leaving out the variables referenced in the arguments
+// of try() and can(), which handle the references that can not be evaluated.
+func unguardedVariables(body *hclsyntax.Body, skipBlockTypes ...string) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	for _, attribute := range body.Attributes {
+		var guarded []hcl.Range
+		hclsyntax.VisitAll(attribute.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, isCall := node.(*hclsyntax.FunctionCallExpr); isCall && (call.Name == "try" || call.Name == "can") {
+				guarded = append(guarded, call.Range())
+			}
+			return nil
+		})
+
+	variables:
+		for _, traversal := range attribute.Expr.Variables() {
+			for _, guardedRange := range guarded {
+				if guardedRange.ContainsOffset(traversal.SourceRange().Start.Byte) {
+					continue variables
+				}
+			}
+			traversals = append(traversals, traversal)
+		}
+	}
+
+	for _, block := range body.Blocks {
+		if containsString(skipBlockTypes, block.Type) {
+			continue
+		}
+		traversals = append(traversals, unguardedVariables(block.Body, skipBlockTypes...)...)
+	}
+	return traversals
+}
+
+// traversalStepName returns the attribute name, or string index key, selected by the given traversal step.
+func traversalStepName(step hcl.Traverser) (string, bool) {
+	switch step := step.(type) {
+	case hcl.TraverseAttr:
+		return step.Name, true
+	case hcl.TraverseIndex:
+		if step.Key.Type() == cty.String && step.Key.IsKnown() && !step.Key.IsNull() {
+			return step.Key.AsString(), true
+		}
+	}
+	return "", false
+}
+
+func containsString(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeReferences holds what the expression of a single attribute references.
+type AttributeReferences struct {
+	// Path identifies the attribute by the types and labels of its enclosing blocks followed by its name, joined with
+	// dots (e.g. inputs, terraform.source, dependency.vpc.config_path or locals.env).
+	Path  string
+	Range hcl.Range
+
+	// Variables are the variables referenced by the expression, such as dependency.vpc.outputs.vpc_id or local.env.
+	Variables []VariableReference
+
+	// Functions are the functions called by the expression.
+	Functions []FunctionReference
+}
+
+// VariableReference is a variable referenced by an expression.
+type VariableReference struct {
+	// Name is the rendered traversal of the variable (e.g. dependency.vpc.outputs.vpc_id).
+	Name      string
+	Traversal hcl.Traversal
+	Range     hcl.Range
+}
+
+// FunctionReference is a function called by an expression.
+type FunctionReference struct {
+	Name  string
+	Range hcl.Range
+}
+
+// AnalyzeReferences statically analyzes the given terragrunt configuration content and returns, for each attribute,
+// the variables (dependency.*, local.*, include.*, ...) and functions its expression references. Attributes are
+// returned in the order they appear in the content. The configuration is not evaluated, so the content does not need
+// to be valid beyond its syntax.
+func AnalyzeReferences(content []byte) ([]AttributeReferences, error) {
+	file, diags := hclparse.NewParser().ParseHCL(content, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return analyzeReferences(file.Body.(*hclsyntax.Body)), nil
+}
+
+// analyzeReferences returns the references of every attribute of the given body, in the order they appear.
+func analyzeReferences(body *hclsyntax.Body) []AttributeReferences {
+	var references []AttributeReferences
+	analyzeBodyReferences(body, "", &references)
+
+	sort.SliceStable(references, func(i, j int) bool {
+		return references[i].Range.Start.Byte < references[j].Range.Start.Byte
+	})
+	return references
+}
+
+func analyzeBodyReferences(body *hclsyntax.Body, prefix string, references *[]AttributeReferences) {
+	for name, attribute := range body.Attributes {
+		attributeReferences := AttributeReferences{
+			Path:  prefix + name,
+			Range: attribute.SrcRange,
+		}
+
+		for _, traversal := range attribute.Expr.Variables() {
+			attributeReferences.Variables = append(attributeReferences.Variables, VariableReference{
+				Name:      formatTraversal(traversal),
+				Traversal: traversal,
+				Range:     traversal.SourceRange(),
+			})
+		}
+
+		hclsyntax.VisitAll(attribute.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, isCall := node.(*hclsyntax.FunctionCallExpr); isCall {
+				attributeReferences.Functions = append(attributeReferences.Functions, FunctionReference{
+					Name:  call.Name,
+					Range: call.Range(),
+				})
+			}
+			return nil
+		})
+
+		*references = append(*references, attributeReferences)
+	}
+
+	for _, block := range body.Blocks {
+		blockPrefix := prefix + strings.Join(append([]string{block.Type}, block.Labels...), ".") + "."
+		analyzeBodyReferences(block.Body, blockPrefix, references)
+	}
+}
+
+// formatTraversal renders the given traversal the way it would be written in an expression.
+func formatTraversal(traversal hcl.Traversal) string {
+	var builder strings.Builder
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			builder.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			builder.WriteString("." + step.Name)
+		case hcl.TraverseIndex:
+			switch {
+			case !step.Key.IsKnown() || step.Key.IsNull():
+				builder.WriteString("[?]")
+			case step.Key.Type() == cty.String:
+				builder.WriteString(fmt.Sprintf("[%q]", step.Key.AsString()))
+			case step.Key.Type() == cty.Number:
+				builder.WriteString("[" + step.Key.AsBigFloat().Text('f', -1) + "]")
+			}
+		case hcl.TraverseSplat:
+			builder.WriteString("[*]")
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,320 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RegistryClient looks up the versions of modules on terraform registries, including private ones, using the
+// registry's service discovery to locate its modules API.
+type RegistryClient struct {
+	// Tokens are the API tokens to authenticate with, keyed by registry host. When there is none for a host, the
+	// TF_TOKEN_<host> environment variable is used as terraform does (e.g. TF_TOKEN_app_terraform_io).
+	Tokens map[string]string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	mutex       sync.Mutex
+	modulesURLs map[string]*url.URL
+}
+
+// Versions returns the available versions of the given registry module source, sorted from the oldest to the newest.
+func (client *RegistryClient) Versions(ctx context.Context, source string) ([]string, error) {
+	parsed, err := ParseSource(source)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Kind != SourceRegistry {
+		return nil, fmt.Errorf("%s is not a registry module source", source)
+	}
+
+	modulesURL, err := client.modulesURL(ctx, parsed.Host)
+	if err != nil {
+		return nil, err
+	}
+	versionsURL, err := modulesURL.Parse(url.PathEscape(parsed.Namespace) + "/" + url.PathEscape(parsed.Name) + "/" + url.PathEscape(parsed.Provider) + "/versions")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	if err := client.get(ctx, parsed.Host, versionsURL, &response); err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, module := range response.Modules {
+		for _, version := range module.Versions {
+			versions = append(versions, version.Version)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// LatestVersion returns the newest version of the given registry module source, ignoring pre-releases.
+func (client *RegistryClient) LatestVersion(ctx context.Context, source string) (string, error) {
+	versions, err := client.Versions(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if version, ok := parseVersion(versions[i]); ok && version.prerelease == "" {
+			return versions[i], nil
+		}
+	}
+	return "", fmt.Errorf("no released version of %s", source)
+}
+
+// DownloadSource returns the source the given registry module is downloaded from (e.g. a git repository), as given by
+// the registry for the version of the source, or for the latest version when it is not pinned.
+func (client *RegistryClient) DownloadSource(ctx context.Context, source string) (string, error) {
+	parsed, err := ParseSource(source)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Kind != SourceRegistry {
+		return "", fmt.Errorf("%s is not a registry module source", source)
+	}
+
+	version := parsed.Version
+	if version == "" {
+		if version, err = client.LatestVersion(ctx, source); err != nil {
+			return "", err
+		}
+	}
+
+	modulesURL, err := client.modulesURL(ctx, parsed.Host)
+	if err != nil {
+		return "", err
+	}
+	downloadURL, err := modulesURL.Parse(url.PathEscape(parsed.Namespace) + "/" + url.PathEscape(parsed.Name) + "/" + url.PathEscape(parsed.Provider) + "/" + url.PathEscape(version) + "/download")
+	if err != nil {
+		return "", err
+	}
+
+	response, err := client.do(ctx, parsed.Host, downloadURL)
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	location := response.Header.Get("X-Terraform-Get")
+	if location == "" {
+		return "", fmt.Errorf("registry %s did not return the download location of %s", parsed.Host, source)
+	}
+
+	// The location may be relative to the download url.
+	if resolved, err := downloadURL.Parse(location); err == nil && (strings.HasPrefix(location, "/") || strings.HasPrefix(location, "./") || strings.HasPrefix(location, "../")) {
+		location = resolved.String()
+	}
+	return location, nil
+}
+
+// modulesURL returns the base url of the modules API of the registry with the given host, as advertised by its
+// service discovery document.
+func (client *RegistryClient) modulesURL(ctx context.Context, host string) (*url.URL, error) {
+	client.mutex.Lock()
+	modulesURL, found := client.modulesURLs[host]
+	client.mutex.Unlock()
+	if found {
+		return modulesURL, nil
+	}
+
+	discoveryURL := &url.URL{Scheme: "https", Host: host, Path: "/.well-known/terraform.json"}
+	var services map[string]interface{}
+	if err := client.get(ctx, host, discoveryURL, &services); err != nil {
+		return nil, err
+	}
+	modulesPath, isString := services["modules.v1"].(string)
+	if !isString {
+		return nil, fmt.Errorf("registry %s does not provide the modules.v1 service", host)
+	}
+	modulesURL, err := discoveryURL.Parse(modulesPath)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(modulesURL.Path, "/") {
+		modulesURL.Path += "/"
+	}
+
+	client.mutex.Lock()
+	if client.modulesURLs == nil {
+		client.modulesURLs = map[string]*url.URL{}
+	}
+	client.modulesURLs[host] = modulesURL
+	client.mutex.Unlock()
+	return modulesURL, nil
+}
+
+// get sends an authenticated GET request to the registry with the given host, and decodes the json response into out.
+func (client *RegistryClient) get(ctx context.Context, host string, requestURL *url.URL, out interface{}) error {
+	response, err := client.do(ctx, host, requestURL)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// do sends an authenticated GET request to the registry with the given host, retrying it on transient failures.
+// Responses other than 200 and 204 are returned as HTTPStatusError errors.
+func (client *RegistryClient) do(ctx context.Context, host string, requestURL *url.URL) (*http.Response, error) {
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	var response *http.Response
+	err := client.Retry.Do(ctx, func() error {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+		if err != nil {
+			return err
+		}
+		if token := client.token(host); token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err = httpClient.Do(request)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+			defer response.Body.Close()
+			return newHTTPStatusError(request, response)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// token returns the API token of the registry with the given host.
+func (client *RegistryClient) token(host string) string {
+	if token, found := client.Tokens[host]; found {
+		return token
+	}
+	envName := "TF_TOKEN_" + strings.NewReplacer(".", "_", "-", "__").Replace(host)
+	return os.Getenv(envName)
+}
+
+// version is a parsed semantic version.
+type version struct {
+	numbers    [3]int
+	prerelease string
+}
+
+// parseVersion parses a semantic version, with an optional v prefix. Missing minor and patch numbers are zero, and
+// build metadata is ignored.
+func parseVersion(text string) (version, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "v")
+	if i := strings.IndexByte(text, '+'); i >= 0 {
+		text = text[:i]
+	}
+
+	var parsed version
+	if i := strings.IndexByte(text, '-'); i >= 0 {
+		text, parsed.prerelease = text[:i], text[i+1:]
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 {
+		return version{}, false
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return version{}, false
+		}
+		parsed.numbers[i] = number
+	}
+	return parsed, true
+}
+
+// CompareVersions compares two semantic versions (e.g. v1.2.0 and 1.10.0-rc1), returning -1, 0 or 1 when a is older,
+// the same as or newer than b. Versions that can not be parsed are compared as strings, before the valid ones.
+func CompareVersions(a string, b string) int {
+	versionA, okA := parseVersion(a)
+	versionB, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	for i := range versionA.numbers {
+		if versionA.numbers[i] != versionB.numbers[i] {
+			if versionA.numbers[i] < versionB.numbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A pre-release comes before its release.
+	switch {
+	case versionA.prerelease == versionB.prerelease:
+		return 0
+	case versionA.prerelease == "":
+		return 1
+	case versionB.prerelease == "":
+		return -1
+	}
+	return comparePrerelease(versionA.prerelease, versionB.prerelease)
+}
+
+// comparePrerelease compares pre-release identifiers following the semver precedence rules: dot separated
+// identifiers are compared numerically when they are numbers, and as strings otherwise.
+func comparePrerelease(a string, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numberA, errA := strconv.Atoi(partsA[i])
+		numberB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil && numberA != numberB:
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		case errA == nil && errB != nil:
+			return -1
+		case errA != nil && errB == nil:
+			return 1
+		case errA != nil && errB != nil && partsA[i] != partsB[i]:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	switch {
+	case len(partsA) < len(partsB):
+		return -1
+	case len(partsA) > len(partsB):
+		return 1
+	}
+	return 0
+}
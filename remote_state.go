@@ -0,0 +1,140 @@
+package terragrunt
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// RemoteState is a remote_state block, configuring the backend where terraform stores the state of the unit.
+type RemoteState struct {
+	Backend                       string               `hcl:"backend,attr"`
+	DisableInit                   *bool                `hcl:"disable_init,attr"`
+	DisableDependencyOptimization *bool                `hcl:"disable_dependency_optimization,attr"`
+	Generate                      *RemoteStateGenerate `hcl:"generate,attr"`
+	Config                        cty.Value            `hcl:"config,attr"`
+
+	// Encryption configures the encryption of the state by OpenTofu: its key_provider (pbkdf2 or aws_kms) and the
+	// settings of the key provider, such as passphrase. See StateEncryption.
+	Encryption *cty.Value `hcl:"encryption,attr"`
+}
+
+// RemoteStateGenerate configures the file the backend block is generated into.
+type RemoteStateGenerate struct {
+	Path     string `cty:"path"`
+	IfExists string `cty:"if_exists"`
+}
+
+// terragruntOnlyBackendConfigs are the keys of the remote_state config, per backend, that terragrunt uses to manage
+// the state storage itself (e.g. to create the s3 bucket), and that are not passed to terraform.
+var terragruntOnlyBackendConfigs = map[string][]string{
+	"s3": {
+		"s3_bucket_tags",
+		"dynamodb_table_tags",
+		"accesslogging_bucket_tags",
+		"skip_bucket_versioning",
+		"skip_bucket_ssencryption",
+		"skip_bucket_accesslogging",
+		"skip_bucket_root_access",
+		"skip_bucket_enforced_tls",
+		"skip_bucket_public_access_blocking",
+		"enable_lock_table_ssencryption",
+		"disable_aws_client_checksums",
+		"accesslogging_bucket_name",
+		"accesslogging_target_prefix",
+		"bucket_sse_algorithm",
+		"bucket_sse_kms_key_id",
+	},
+	"gcs": {
+		"project",
+		"location",
+		"gcs_bucket_labels",
+		"skip_bucket_versioning",
+		"skip_bucket_creation",
+		"enable_bucket_policy_only",
+	},
+}
+
+// GenerateBackendConfig renders the terraform block declaring the backend configured by the remote_state block, as
+// terragrunt generates it. The keys of the config that are only used by terragrunt are left out.
+func GenerateBackendConfig(remoteState *RemoteState) ([]byte, error) {
+	config, err := backendConfig(remoteState)
+	if err != nil {
+		return nil, err
+	}
+
+	file := hclwrite.NewEmptyFile()
+	backendBody := file.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("backend", []string{remoteState.Backend}).Body()
+	for _, key := range sortedValueKeys(config) {
+		backendBody.SetAttributeValue(key, config[key])
+	}
+	return file.Bytes(), nil
+}
+
+// BackendConfigArgs returns the -backend-config arguments of terraform init configuring the backend of the
+// remote_state block, sorted by key. The keys of the config that are only used by terragrunt are left out.
+func BackendConfigArgs(remoteState *RemoteState) ([]string, error) {
+	config, err := backendConfig(remoteState)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, key := range sortedValueKeys(config) {
+		value := config[key]
+		if value.Type() == cty.String {
+			args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, value.AsString()))
+			continue
+		}
+		// Other values are passed as json, which is also valid HCL, so that they fit on a single line.
+		encoded, err := ctyjson.SimpleJSONValue{Value: value}.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, fmt.Sprintf("-backend-config=%s=%s", key, encoded))
+	}
+	return args, nil
+}
+
+// backendConfig returns the config of the remote_state block that is passed to terraform.
+func backendConfig(remoteState *RemoteState) (map[string]cty.Value, error) {
+	if remoteState == nil {
+		return nil, fmt.Errorf("no remote_state block")
+	}
+	if remoteState.Backend == "" {
+		return nil, fmt.Errorf("the backend of the remote_state block is not set")
+	}
+
+	config := map[string]cty.Value{}
+	if remoteState.Config.IsNull() {
+		return config, nil
+	}
+
+	value, _ := remoteState.Config.UnmarkDeep()
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("the config of the remote_state block is not known until apply")
+	}
+	if !value.Type().IsObjectType() && !value.Type().IsMapType() {
+		return nil, fmt.Errorf("the config of the remote_state block must be an object, got %s", value.Type().FriendlyName())
+	}
+
+	terragruntOnly := terragruntOnlyBackendConfigs[remoteState.Backend]
+	for key, element := range value.AsValueMap() {
+		if !containsString(terragruntOnly, key) && !element.IsNull() {
+			config[key] = element
+		}
+	}
+	return config, nil
+}
+
+func sortedValueKeys(values map[string]cty.Value) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// renderedConfig is the json representation of a TerragruntConfig.
+type renderedConfig struct {
+	Terraform       *renderedTerraform                 `json:"terraform,omitempty"`
+	TerraformBinary string                             `json:"terraform_binary,omitempty"`
+	Skip            bool                               `json:"skip,omitempty"`
+	PreventDestroy  bool                               `json:"prevent_destroy,omitempty"`
+	IamRole         *renderedIamRole                   `json:"iam_role,omitempty"`
+	RemoteState     *renderedRemoteState               `json:"remote_state,omitempty"`
+	FeatureFlags    map[string]ctyjson.SimpleJSONValue `json:"feature,omitempty"`
+	Errors          *renderedErrors                    `json:"errors,omitempty"`
+	Engine          *renderedEngine                    `json:"engine,omitempty"`
+	Exclude         *renderedExclude                   `json:"exclude,omitempty"`
+	Catalog         *renderedCatalog                   `json:"catalog,omitempty"`
+	Dependencies    []renderedDependency               `json:"dependencies,omitempty"`
+	Inputs          map[string]ctyjson.SimpleJSONValue `json:"inputs,omitempty"`
+}
+
+type renderedTerraform struct {
+	Source         *string                  `json:"source,omitempty"`
+	ExtraArguments []renderedExtraArguments `json:"extra_arguments,omitempty"`
+}
+
+type renderedExtraArguments struct {
+	Name             string             `json:"name"`
+	Commands         []string           `json:"commands"`
+	Arguments        *[]string          `json:"arguments,omitempty"`
+	RequiredVarFiles *[]string          `json:"required_var_files,omitempty"`
+	OptionalVarFiles *[]string          `json:"optional_var_files,omitempty"`
+	EnvVars          *map[string]string `json:"env_vars,omitempty"`
+}
+
+type renderedIamRole struct {
+	Arn         string `json:"arn"`
+	Duration    int64  `json:"duration,omitempty"`
+	SessionName string `json:"session_name,omitempty"`
+}
+
+type renderedErrors struct {
+	Retry  []renderedErrorsRetry  `json:"retry,omitempty"`
+	Ignore []renderedErrorsIgnore `json:"ignore,omitempty"`
+}
+
+type renderedErrorsRetry struct {
+	Label            string   `json:"label"`
+	RetryableErrors  []string `json:"retryable_errors"`
+	MaxAttempts      int      `json:"max_attempts"`
+	SleepIntervalSec int      `json:"sleep_interval_sec"`
+}
+
+type renderedErrorsIgnore struct {
+	Label           string                   `json:"label"`
+	IgnorableErrors []string                 `json:"ignorable_errors"`
+	Message         *string                  `json:"message,omitempty"`
+	Signals         *ctyjson.SimpleJSONValue `json:"signals,omitempty"`
+}
+
+type renderedEngine struct {
+	Source  string                   `json:"source"`
+	Version *string                  `json:"version,omitempty"`
+	Type    *string                  `json:"type,omitempty"`
+	Meta    *ctyjson.SimpleJSONValue `json:"meta,omitempty"`
+}
+
+type renderedExclude struct {
+	If                  bool     `json:"if"`
+	Actions             []string `json:"actions"`
+	ExcludeDependencies *bool    `json:"exclude_dependencies,omitempty"`
+}
+
+type renderedCatalog struct {
+	URLs            []string `json:"urls"`
+	DefaultTemplate *string  `json:"default_template,omitempty"`
+}
+
+type renderedRemoteState struct {
+	Backend                       string                       `json:"backend"`
+	DisableInit                   *bool                        `json:"disable_init,omitempty"`
+	DisableDependencyOptimization *bool                        `json:"disable_dependency_optimization,omitempty"`
+	Generate                      *renderedRemoteStateGenerate `json:"generate,omitempty"`
+	Config                        *ctyjson.SimpleJSONValue     `json:"config,omitempty"`
+	Encryption                    *ctyjson.SimpleJSONValue     `json:"encryption,omitempty"`
+}
+
+type renderedRemoteStateGenerate struct {
+	Path     string `json:"path"`
+	IfExists string `json:"if_exists"`
+}
+
+type renderedDependency struct {
+	Name       string                   `json:"name"`
+	ConfigPath string                   `json:"config_path"`
+	Enabled    *bool                    `json:"enabled,omitempty"`
+	Outputs    *ctyjson.SimpleJSONValue `json:"outputs,omitempty"`
+}
+
+// RenderJSON renders the given resolved configuration as json. Inputs and dependency outputs are rendered as plain
+// json values, without their type information, and sensitive values are redacted.
+func RenderJSON(config *TerragruntConfig) ([]byte, error) {
+	return json.Marshal(newRenderedConfig(config))
+}
+
+// RenderJSONIndent is like RenderJSON, but indents the output for readability.
+func RenderJSONIndent(config *TerragruntConfig) ([]byte, error) {
+	return json.MarshalIndent(newRenderedConfig(config), "", "  ")
+}
+
+func newRenderedConfig(config *TerragruntConfig) renderedConfig {
+	rendered := renderedConfig{
+		TerraformBinary: config.TerraformBinary,
+		Skip:            config.Skip,
+		PreventDestroy:  config.PreventDestroy,
+	}
+
+	if config.Terraform != nil {
+		rendered.Terraform = &renderedTerraform{Source: config.Terraform.Source}
+		for _, extraArguments := range config.Terraform.ExtraArguments {
+			rendered.Terraform.ExtraArguments = append(rendered.Terraform.ExtraArguments, renderedExtraArguments(extraArguments))
+		}
+	}
+
+	if config.IamRole != "" {
+		rendered.IamRole = &renderedIamRole{Arn: config.IamRole, Duration: config.IamAssumeRoleDuration, SessionName: config.IamAssumeRoleSessionName}
+	}
+
+	if remoteState := config.RemoteState; remoteState != nil {
+		rendered.RemoteState = &renderedRemoteState{
+			Backend:                       remoteState.Backend,
+			DisableInit:                   remoteState.DisableInit,
+			DisableDependencyOptimization: remoteState.DisableDependencyOptimization,
+		}
+		if remoteState.Generate != nil {
+			rendered.RemoteState.Generate = &renderedRemoteStateGenerate{Path: remoteState.Generate.Path, IfExists: remoteState.Generate.IfExists}
+		}
+		if remoteState.Config != cty.NilVal {
+			rendered.RemoteState.Config = &ctyjson.SimpleJSONValue{Value: renderableValue(remoteState.Config)}
+		}
+		if remoteState.Encryption != nil {
+			rendered.RemoteState.Encryption = &ctyjson.SimpleJSONValue{Value: renderableValue(redactPassphrase(*remoteState.Encryption))}
+		}
+	}
+
+	for _, dependency := range config.TerragruntDependencies {
+		renderedDep := renderedDependency{
+			Name:       dependency.Name,
+			ConfigPath: filepath.ToSlash(dependency.ConfigPath),
+			Enabled:    dependency.Enabled,
+		}
+		if dependency.RenderedOutputs != nil {
+			renderedDep.Outputs = &ctyjson.SimpleJSONValue{Value: renderableValue(*dependency.RenderedOutputs)}
+		}
+		rendered.Dependencies = append(rendered.Dependencies, renderedDep)
+	}
+
+	if len(config.FeatureFlags) > 0 {
+		rendered.FeatureFlags = map[string]ctyjson.SimpleJSONValue{}
+		for name, value := range config.FeatureFlags {
+			rendered.FeatureFlags[name] = ctyjson.SimpleJSONValue{Value: renderableValue(value)}
+		}
+	}
+
+	if config.Errors != nil {
+		rendered.Errors = &renderedErrors{}
+		for _, retry := range config.Errors.Retry {
+			rendered.Errors.Retry = append(rendered.Errors.Retry, renderedErrorsRetry(retry))
+		}
+		for _, ignore := range config.Errors.Ignore {
+			renderedIgnore := renderedErrorsIgnore{Label: ignore.Label, IgnorableErrors: ignore.IgnorableErrors, Message: ignore.Message}
+			if ignore.Signals != nil {
+				renderedIgnore.Signals = &ctyjson.SimpleJSONValue{Value: renderableValue(*ignore.Signals)}
+			}
+			rendered.Errors.Ignore = append(rendered.Errors.Ignore, renderedIgnore)
+		}
+	}
+
+	if engine := config.Engine; engine != nil {
+		rendered.Engine = &renderedEngine{Source: engine.Source, Version: engine.Version, Type: engine.Type}
+		if engine.Meta != nil {
+			rendered.Engine.Meta = &ctyjson.SimpleJSONValue{Value: renderableValue(*engine.Meta)}
+		}
+	}
+
+	if config.Exclude != nil {
+		exclude := renderedExclude(*config.Exclude)
+		rendered.Exclude = &exclude
+	}
+	if config.Catalog != nil {
+		catalog := renderedCatalog(*config.Catalog)
+		rendered.Catalog = &catalog
+	}
+
+	if len(config.InputsCty) > 0 {
+		rendered.Inputs = map[string]ctyjson.SimpleJSONValue{}
+		for name, value := range config.InputsCty {
+			rendered.Inputs[name] = ctyjson.SimpleJSONValue{Value: renderableValue(value)}
+		}
+	}
+
+	return rendered
+}
+
+// renderableValue returns the given value in a form that can be marshalled to json: sensitive values are redacted,
+// and unknown values are replaced by nulls.
+func renderableValue(value cty.Value) cty.Value {
+	value = Redact(value)
+	if value.IsWhollyKnown() {
+		return value
+	}
+
+	rendered, _ := cty.Transform(value, func(path cty.Path, v cty.Value) (cty.Value, error) {
+		if !v.IsKnown() {
+			return cty.NullVal(v.Type()), nil
+		}
+		return v, nil
+	})
+	return rendered
+}
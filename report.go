@@ -0,0 +1,462 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// PlanAction is the action a terraform plan takes on a resource.
+type PlanAction string
+
+const (
+	PlanCreate  PlanAction = "create"
+	PlanUpdate  PlanAction = "update"
+	PlanDelete  PlanAction = "delete"
+	PlanReplace PlanAction = "replace"
+	PlanImport  PlanAction = "import"
+)
+
+// PlannedResource is a resource a terraform plan changes.
+type PlannedResource struct {
+	Address string
+	Action  PlanAction
+}
+
+// PlanSummary summarizes the changes of a terraform plan, counting them as terraform does: a replaced resource is
+// both added and destroyed.
+type PlanSummary struct {
+	Add     int
+	Change  int
+	Destroy int
+	Import  int
+
+	// Resources are the resources the plan changes, in the order of the plan, when known from its json
+	// representation.
+	Resources []PlannedResource
+
+	// Drift are the resources that changed outside of terraform since the last apply, as detected by the refresh of
+	// the plan, when known from its json representation. They are not counted.
+	Drift []PlannedResource
+}
+
+// HasChanges reports whether the plan changes anything.
+func (summary *PlanSummary) HasChanges() bool {
+	return summary.Add+summary.Change+summary.Destroy+summary.Import > 0
+}
+
+func (summary *PlanSummary) String() string {
+	counts := fmt.Sprintf("%d to add, %d to change, %d to destroy", summary.Add, summary.Change, summary.Destroy)
+	if summary.Import > 0 {
+		counts = fmt.Sprintf("%d to import, %s", summary.Import, counts)
+	}
+	return counts
+}
+
+type jsonResourceChange struct {
+	Address string `json:"address"`
+	Change  struct {
+		Actions   []string        `json:"actions"`
+		Importing json.RawMessage `json:"importing"`
+	} `json:"change"`
+}
+
+type jsonPlan struct {
+	ResourceChanges []jsonResourceChange `json:"resource_changes"`
+	ResourceDrift   []jsonResourceChange `json:"resource_drift"`
+}
+
+// planAction returns the action of the given change, or false for the changes that do nothing, such as no-op and
+// read.
+func planAction(change jsonResourceChange) (PlanAction, bool) {
+	switch strings.Join(change.Change.Actions, ",") {
+	case "create":
+		return PlanCreate, true
+	case "update":
+		return PlanUpdate, true
+	case "delete":
+		return PlanDelete, true
+	case "delete,create", "create,delete":
+		return PlanReplace, true
+	}
+	return "", false
+}
+
+// ParsePlanJSON summarizes the given json representation of a plan, as printed by terraform show -json, along with
+// the resources that drifted.
+func ParsePlanJSON(data []byte) (*PlanSummary, error) {
+	var plan jsonPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid json plan: %w", err)
+	}
+
+	summary := &PlanSummary{}
+	for _, change := range plan.ResourceChanges {
+		importing := len(change.Change.Importing) > 0 && string(change.Change.Importing) != "null"
+		action, changed := planAction(change)
+		switch {
+		case !changed && !importing:
+			continue
+		case !changed:
+			action = PlanImport
+		}
+		switch action {
+		case PlanCreate:
+			summary.Add++
+		case PlanUpdate:
+			summary.Change++
+		case PlanDelete:
+			summary.Destroy++
+		case PlanReplace:
+			summary.Add++
+			summary.Destroy++
+		}
+		if importing {
+			summary.Import++
+		}
+		summary.Resources = append(summary.Resources, PlannedResource{Address: change.Address, Action: action})
+	}
+	for _, change := range plan.ResourceDrift {
+		if action, changed := planAction(change); changed {
+			summary.Drift = append(summary.Drift, PlannedResource{Address: change.Address, Action: action})
+		}
+	}
+	return summary, nil
+}
+
+var (
+	planSummaryLine = regexp.MustCompile(`Plan: (?:(\d+) to import, )?(\d+) to add, (\d+) to change, (\d+) to destroy`)
+	planNoChanges   = regexp.MustCompile(`No changes\.`)
+	ansiEscape      = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// ParsePlanOutput summarizes the plan printed by terraform plan or terragrunt plan from its last summary line, such as
+// "Plan: 1 to add, 0 to change, 0 to destroy.". It reports false when the output has no summary, e.g. when the plan
+// failed. The resources are not known from the output.
+func ParsePlanOutput(output []byte) (*PlanSummary, bool) {
+	output = ansiEscape.ReplaceAll(output, nil)
+	matches := planSummaryLine.FindAllSubmatch(output, -1)
+	if len(matches) == 0 {
+		if planNoChanges.Match(output) {
+			return &PlanSummary{}, true
+		}
+		return nil, false
+	}
+
+	match := matches[len(matches)-1]
+	count := func(group []byte) int {
+		value, _ := strconv.Atoi(string(group))
+		return value
+	}
+	return &PlanSummary{Import: count(match[1]), Add: count(match[2]), Change: count(match[3]), Destroy: count(match[4])}, true
+}
+
+// UnitReport is what a Report says about a unit.
+type UnitReport struct {
+	// Path is the absolute path of the unit directory.
+	Path string
+
+	// Plan is the summary of the plan of the unit, if planned.
+	Plan *PlanSummary
+
+	// Changes are the changes of the configuration of the unit, as returned by Diff.
+	Changes []Change
+
+	// Findings are the findings of the configuration of the unit.
+	Findings []Finding
+
+	// Err is the error of the unit, e.g. its configuration being invalid or its plan failing.
+	Err error
+
+	// Cost is the estimated monthly cost of the unit, if estimated, e.g. by Infracost.
+	Cost *CostEstimate
+
+	// Output is the output of the command run in the unit, such as its plan. Only its end is rendered when it is
+	// longer than ReportOutputLimit.
+	Output string
+}
+
+// ReportOutputLimit is the number of bytes of the output of a unit a report renders, keeping reports within the size
+// limits of pull request comments.
+const ReportOutputLimit = 8000
+
+// Report gathers the plan summaries, configuration changes, findings and cost estimates of the units of a stack, to
+// render them as markdown for pull request or merge request comments.
+type Report struct {
+	// Title is the heading of the report, "Terragrunt report" when empty.
+	Title string
+
+	// BaseDir is the directory the paths of the units and of the findings are rendered relative to, usually the root
+	// of the stack or of the repository.
+	BaseDir string
+
+	// Units are the reports of the units, rendered sorted by path.
+	Units []*UnitReport
+
+	// Findings are the findings that are not about a single unit, such as dependency cycles.
+	Findings []Finding
+}
+
+// Unit returns the report of the unit at the given path, adding it when missing.
+func (report *Report) Unit(path string) *UnitReport {
+	for _, unit := range report.Units {
+		if unit.Path == path {
+			return unit
+		}
+	}
+	unit := &UnitReport{Path: path}
+	report.Units = append(report.Units, unit)
+	return unit
+}
+
+// reportTotals are the totals rendered at the top of a report.
+type reportTotals struct {
+	units, failed, changed        int
+	add, change, destroy, imports int
+	errors, warnings              int
+
+	// cost is the sum of the cost estimates of the units, if any.
+	cost *CostEstimate
+}
+
+func (report *Report) totals() reportTotals {
+	var totals reportTotals
+	countFindings := func(findings []Finding) {
+		for _, finding := range findings {
+			if finding.Severity == SeverityError {
+				totals.errors++
+			} else {
+				totals.warnings++
+			}
+		}
+	}
+	countFindings(report.Findings)
+	for _, unit := range report.Units {
+		totals.units++
+		if unit.Err != nil {
+			totals.failed++
+		}
+		if unit.Plan != nil {
+			if unit.Plan.HasChanges() {
+				totals.changed++
+			}
+			totals.add += unit.Plan.Add
+			totals.change += unit.Plan.Change
+			totals.destroy += unit.Plan.Destroy
+			totals.imports += unit.Plan.Import
+		}
+		countFindings(unit.Findings)
+		if unit.Cost != nil {
+			if totals.cost == nil {
+				totals.cost = &CostEstimate{Currency: unit.Cost.Currency}
+			}
+			totals.cost.MonthlyCost += unit.Cost.MonthlyCost
+			totals.cost.PastMonthlyCost += unit.Cost.PastMonthlyCost
+		}
+	}
+	return totals
+}
+
+// hasPlans reports whether any unit of the report was planned.
+func (report *Report) hasPlans() bool {
+	for _, unit := range report.Units {
+		if unit.Plan != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteMarkdown writes the report as GitHub flavored markdown, which GitLab renders as well: the totals at the top,
+// summing the cost estimates of the units, and a collapsible section per unit with its plan summary, the resources
+// its plan changes and the ones that drifted, its cost estimate, its configuration changes, its findings, its error
+// and its output. The sections of the units failing or with errors are expanded. Units with nothing to report, no
+// error, finding, configuration change, planned change, drift nor cost change, are only counted in the totals.
+// Sensitive values are redacted.
+func (report *Report) WriteMarkdown(w io.Writer) error {
+	var out bytes.Buffer
+	title := report.Title
+	if title == "" {
+		title = "Terragrunt report"
+	}
+	fmt.Fprintf(&out, "## %s\n\n", title)
+
+	totals := report.totals()
+	units := strconv.Itoa(totals.units)
+	if totals.failed > 0 {
+		units += fmt.Sprintf(" (%d failed)", totals.failed)
+	}
+	headers, values := []string{"Units"}, []string{units}
+	if report.hasPlans() {
+		headers = append(headers, "Changed", "To add", "To change", "To destroy", "To import")
+		values = append(values, strconv.Itoa(totals.changed), strconv.Itoa(totals.add), strconv.Itoa(totals.change), strconv.Itoa(totals.destroy), strconv.Itoa(totals.imports))
+	}
+	if totals.cost != nil {
+		headers = append(headers, "Monthly cost", "Cost change")
+		values = append(values, formatCost(totals.cost.MonthlyCost, totals.cost.Currency), formatCostDelta(totals.cost.Delta(), totals.cost.Currency))
+	}
+	headers = append(headers, "Errors", "Warnings")
+	values = append(values, strconv.Itoa(totals.errors), strconv.Itoa(totals.warnings))
+	fmt.Fprintf(&out, "| %s |\n|%s\n| %s |\n\n", strings.Join(headers, " | "), strings.Repeat("---|", len(headers)), strings.Join(values, " | "))
+
+	if len(report.Findings) > 0 {
+		report.writeFindings(&out, report.Findings)
+		out.WriteString("\n")
+	}
+
+	sorted := make([]*UnitReport, len(report.Units))
+	copy(sorted, report.Units)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	for _, unit := range sorted {
+		if unit.Err == nil && len(unit.Findings) == 0 && len(unit.Changes) == 0 && (unit.Plan == nil || !unit.Plan.HasChanges() && len(unit.Plan.Drift) == 0) && (unit.Cost == nil || unit.Cost.Delta() == 0) {
+			continue
+		}
+		report.writeUnit(&out, unit)
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// writeUnit writes the collapsible section of the given unit.
+func (report *Report) writeUnit(out *bytes.Buffer, unit *UnitReport) {
+	icon, open := "✅", ""
+	hasErrors := unit.Err != nil
+	for _, finding := range unit.Findings {
+		hasErrors = hasErrors || finding.Severity == SeverityError
+	}
+	switch {
+	case hasErrors:
+		icon, open = "❌", " open"
+	case len(unit.Findings) > 0 || (unit.Plan != nil && (unit.Plan.Destroy > 0 || len(unit.Plan.Drift) > 0)):
+		icon = "⚠️"
+	}
+
+	summary := fmt.Sprintf("%s <code>%s</code>", icon, html.EscapeString(report.relativePath(unit.Path)))
+	if unit.Plan != nil {
+		summary += fmt.Sprintf(": +%d ~%d -%d", unit.Plan.Add, unit.Plan.Change, unit.Plan.Destroy)
+	}
+	if unit.Cost != nil {
+		summary += fmt.Sprintf(", %s/month (%s)", formatCost(unit.Cost.MonthlyCost, unit.Cost.Currency), formatCostDelta(unit.Cost.Delta(), unit.Cost.Currency))
+	}
+	if len(unit.Findings) > 0 {
+		summary += fmt.Sprintf(", %d findings", len(unit.Findings))
+	}
+	fmt.Fprintf(out, "<details%s>\n<summary>%s</summary>\n\n", open, summary)
+
+	if unit.Err != nil {
+		fmt.Fprintf(out, "**Error**\n\n```\n%s\n```\n\n", strings.TrimRight(unit.Err.Error(), "\n"))
+	}
+	if unit.Plan != nil {
+		fmt.Fprintf(out, "**Plan**: %s\n\n", unit.Plan)
+		if len(unit.Plan.Drift) > 0 {
+			out.WriteString("Changed outside of terraform:\n\n| Action | Resource |\n|---|---|\n")
+			for _, resource := range unit.Plan.Drift {
+				fmt.Fprintf(out, "| %s | %s |\n", resource.Action, markdownCode(resource.Address))
+			}
+			out.WriteString("\n")
+		}
+		if len(unit.Plan.Resources) > 0 {
+			if len(unit.Plan.Drift) > 0 {
+				out.WriteString("Planned changes:\n\n")
+			}
+			out.WriteString("| Action | Resource |\n|---|---|\n")
+			for _, resource := range unit.Plan.Resources {
+				fmt.Fprintf(out, "| %s | %s |\n", resource.Action, markdownCode(resource.Address))
+			}
+			out.WriteString("\n")
+		}
+	}
+	if unit.Cost != nil {
+		fmt.Fprintf(out, "**Monthly cost**: %s, %s from %s\n\n", formatCost(unit.Cost.MonthlyCost, unit.Cost.Currency), formatCostDelta(unit.Cost.Delta(), unit.Cost.Currency), formatCost(unit.Cost.PastMonthlyCost, unit.Cost.Currency))
+	}
+	if len(unit.Changes) > 0 {
+		out.WriteString("**Configuration changes**\n\n| Attribute | Change | Old | New |\n|---|---|---|---|\n")
+		for _, change := range unit.Changes {
+			fmt.Fprintf(out, "| %s | %s | %s | %s |\n", markdownCode(change.Path), change.Kind, markdownValue(change.Old), markdownValue(change.New))
+		}
+		out.WriteString("\n")
+	}
+	if len(unit.Findings) > 0 {
+		report.writeFindings(out, unit.Findings)
+		out.WriteString("\n")
+	}
+	if unit.Output != "" {
+		output := unit.Output
+		if len(output) > ReportOutputLimit {
+			output = "...\n" + output[len(output)-ReportOutputLimit:]
+		}
+		output = strings.ReplaceAll(ansiEscape.ReplaceAllString(output, ""), "```", "'''")
+		fmt.Fprintf(out, "<details>\n<summary>Output</summary>\n\n```\n%s\n```\n\n</details>\n\n", strings.TrimRight(output, "\n"))
+	}
+	out.WriteString("</details>\n\n")
+}
+
+// writeFindings writes the given findings as a list.
+func (report *Report) writeFindings(out *bytes.Buffer, findings []Finding) {
+	out.WriteString("**Findings**\n\n")
+	for _, finding := range findings {
+		icon := "⚠️"
+		if finding.Severity == SeverityError {
+			icon = "❌"
+		}
+		location := ""
+		if finding.Range.Filename != "" {
+			location = filepath.ToSlash(relativeFilename(finding.Range.Filename, report.BaseDir))
+			if finding.Range.Start.Line > 0 {
+				location += fmt.Sprintf(":%d:%d", finding.Range.Start.Line, finding.Range.Start.Column)
+			}
+			location = " " + markdownCode(location)
+		}
+		fmt.Fprintf(out, "- %s%s %s (`%s`)\n", icon, location, markdownText(finding.Message), finding.RuleID)
+	}
+}
+
+// relativePath returns the given unit path relative to the base directory of the report.
+func (report *Report) relativePath(path string) string {
+	return filepath.ToSlash(relativeFilename(path, report.BaseDir))
+}
+
+// formatCost formats the given amount of the given currency.
+func formatCost(amount float64, currency string) string {
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", amount, currency))
+}
+
+// formatCostDelta formats the given difference of cost, with its sign.
+func formatCostDelta(delta float64, currency string) string {
+	return strings.TrimSpace(fmt.Sprintf("%+.2f %s", delta, currency))
+}
+
+// markdownText escapes the given text for a line of markdown, leaving it on a single line.
+func markdownText(text string) string {
+	text = html.EscapeString(text)
+	return strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "<br>")
+}
+
+// markdownCode renders the given text as inline code, which can be used in table cells.
+func markdownCode(text string) string {
+	return "<code>" + strings.ReplaceAll(markdownText(text), "|", "&#124;") + "</code>"
+}
+
+// markdownValue renders the given value of a change for a table cell, redacting sensitive values.
+func markdownValue(value cty.Value) string {
+	switch {
+	case value == cty.NilVal:
+		return ""
+	case !value.IsWhollyKnown():
+		return "(known after apply)"
+	}
+	return markdownCode(string(hclwrite.TokensForValue(Redact(value)).Bytes()))
+}
@@ -0,0 +1,164 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// OutputResolver retrieves the outputs of the terragrunt module living at the given config path, in the json format
+// produced by `terraform output -json`. The config path is absolute, and points either to the module directory or to
+// its terragrunt configuration file.
+type OutputResolver interface {
+	ResolveOutputs(ctx context.Context, configPath string) ([]byte, error)
+}
+
+// OutputResolverFunc adapts a function to the OutputResolver interface.
+type OutputResolverFunc func(ctx context.Context, configPath string) ([]byte, error)
+
+func (fn OutputResolverFunc) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	return fn(ctx, configPath)
+}
+
+// KeyedOutputResolver is an OutputResolver that can also retrieve only some of the outputs of a module, in the same
+// format, so that the outputs of a dependency a configuration does not reference are not transferred, cached nor
+// decoded. The keys missing from the outputs of the module are left out. See WithSelectiveOutputKeys.
+type KeyedOutputResolver interface {
+	OutputResolver
+	ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error)
+}
+
+// resolveOutputKeys retrieves the given outputs of the module at the given config path with the given resolver, only
+// retrieving them when it is a KeyedOutputResolver, or else retrieving every output and filtering them.
+func resolveOutputKeys(ctx context.Context, resolver OutputResolver, configPath string, keys []string) ([]byte, error) {
+	if keyedResolver, isKeyed := resolver.(KeyedOutputResolver); isKeyed {
+		return keyedResolver.ResolveOutputKeys(ctx, configPath, keys)
+	}
+	outputs, err := resolver.ResolveOutputs(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return filterOutputKeys(outputs, keys)
+}
+
+// filterOutputKeys returns the given outputs, in the json format of terraform output -json, restricted to the given
+// keys.
+func filterOutputKeys(outputs []byte, keys []string) ([]byte, error) {
+	if len(bytes.TrimSpace(outputs)) == 0 {
+		return outputs, nil
+	}
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(outputs, &decoded); err != nil {
+		return nil, &InvalidOutputsError{Err: err}
+	}
+	filtered := map[string]json.RawMessage{}
+	for _, key := range keys {
+		if output, found := decoded[key]; found {
+			filtered[key] = output
+		}
+	}
+	return json.Marshal(filtered)
+}
+
+// outputKeysCacheKey returns the key of the given outputs of the module at the given config path in an OutputCache.
+func outputKeysCacheKey(configPath string, keys []string) string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	encoded, _ := json.Marshal(sorted)
+	return configPath + "#" + string(encoded)
+}
+
+// ExecOutputResolver retrieves outputs by running `terragrunt output -json` in the directory of the dependency.
+type ExecOutputResolver struct {
+	// Command is the binary to run. Defaults to terragrunt.
+	Command string
+
+	// Args are the arguments passed to the command. Defaults to output -json.
+	Args []string
+
+	// Workspace selects the workspace of the dependencies, through the TF_WORKSPACE environment variable. Defaults to
+	// the workspace selected by terragrunt, from the extra_arguments of their configuration.
+	Workspace WorkspaceFunc
+
+	// Retry is the policy failed commands are retried with, e.g. when the state backend throttles requests. Defaults
+	// to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// Runner runs the command. Defaults to ExecCommandRunner.
+	Runner CommandRunner
+}
+
+func (resolver ExecOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	output, err := resolver.run(ctx, configPath, resolver.args())
+	return output.Stdout, err
+}
+
+// missingOutputError matches the error of terraform output when the requested output is not in the state.
+var missingOutputError = regexp.MustCompile(`(?i)output "[^"]*" not found|no outputs found`)
+
+// ResolveOutputKeys runs `terragrunt output -json <key>` for each of the given keys. Terraform then only prints the
+// value of the output, without its type or whether it is sensitive: the outputs retrieved have the type implied by
+// their value, and are not sensitive. Prefer ResolveOutputs for the dependencies with sensitive outputs.
+func (resolver ExecOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	type outputJSON struct {
+		Value json.RawMessage `json:"value"`
+	}
+	outputs := map[string]outputJSON{}
+	for _, key := range keys {
+		output, err := resolver.run(ctx, configPath, append(resolver.args(), key))
+		if err != nil {
+			if missingOutputError.Match(output.Stderr) {
+				continue
+			}
+			return nil, err
+		}
+		outputs[key] = outputJSON{Value: bytes.TrimSpace(output.Stdout)}
+	}
+	return json.Marshal(outputs)
+}
+
+func (resolver ExecOutputResolver) args() []string {
+	if len(resolver.Args) == 0 {
+		return []string{"output", "-json"}
+	}
+	return append([]string(nil), resolver.Args...)
+}
+
+// run runs the command with the given arguments in the directory of the dependency at the given config path,
+// retrying it with the Retry policy.
+func (resolver ExecOutputResolver) run(ctx context.Context, configPath string, args []string) (CommandOutput, error) {
+	command := resolver.Command
+	if command == "" {
+		command = "terragrunt"
+	}
+
+	var output CommandOutput
+	err := resolver.Retry.Do(ctx, func() error {
+		cmd := Command{Name: command, Args: args, Dir: configDir(configPath)}
+		if resolver.Workspace != nil {
+			if workspace := resolver.Workspace(configPath); workspace != "" {
+				cmd.Env = []string{"TF_WORKSPACE=" + workspace}
+			}
+		}
+		var err error
+		output, err = commandRunner(resolver.Runner).Run(ctx, cmd)
+		if err != nil {
+			return fmt.Errorf("%s %v in %s: %w: %s", command, args, cmd.Dir, err, bytes.TrimSpace(output.Stderr))
+		}
+		return nil
+	})
+	return output, err
+}
+
+// configDir returns the directory of the module targeted by the given config path, which points either to the module
+// directory or to its terragrunt configuration file.
+func configDir(configPath string) string {
+	if filepath.Ext(configPath) == ".hcl" {
+		return filepath.Dir(configPath)
+	}
+	return configPath
+}
@@ -0,0 +1,212 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how remote operations (registry requests, module downloads, output retrieval, AWS API calls)
+// are retried when they fail with a transient error. Retries are spaced by an exponential backoff, with jitter so that
+// many clients failing at once do not retry in lockstep.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one. 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It is multiplied by Multiplier for each following retry, up
+	// to MaxBackoff. The actual delay is picked at random between half the backoff and the backoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Retryable classifies the errors that are worth retrying. Defaults to IsRetryable.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy is the RetryPolicy used when none is configured: 3 attempts, with a backoff starting at 500ms.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+// RetryError is returned when an operation still fails after being retried. It holds the number of attempts made and
+// the error of the last one.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (err *RetryError) Error() string {
+	return fmt.Sprintf("%s (after %d attempts)", err.Err, err.Attempts)
+}
+
+func (err *RetryError) Unwrap() error {
+	return err.Err
+}
+
+// Do runs fn until it succeeds, it fails with an error that is not retryable, the attempts are exhausted or the
+// context is done. When fn was attempted more than once, its last error is returned wrapped in a RetryError. A nil
+// policy is the DefaultRetryPolicy.
+func (policy *RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	settings := policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= settings.MaxAttempts || ctx.Err() != nil || !settings.Retryable(err) {
+			if attempt > 1 {
+				return &RetryError{Attempts: attempt, Err: err}
+			}
+			return err
+		}
+
+		timer := time.NewTimer(settings.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Attempts: attempt, Err: err}
+		case <-timer.C:
+		}
+	}
+}
+
+// withDefaults returns the policy with its unset fields set to the ones of DefaultRetryPolicy.
+func (policy *RetryPolicy) withDefaults() RetryPolicy {
+	settings := DefaultRetryPolicy
+	if policy != nil {
+		settings = *policy
+	}
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if settings.InitialBackoff <= 0 {
+		settings.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if settings.MaxBackoff <= 0 {
+		settings.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if settings.Multiplier < 1 {
+		settings.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	if settings.Retryable == nil {
+		settings.Retryable = IsRetryable
+	}
+	return settings
+}
+
+// backoff returns the delay before the attempt following the given one, which failed with the given error. The delay
+// asked by the server through a Retry-After header is honored, up to MaxBackoff.
+func (policy RetryPolicy) backoff(attempt int, err error) time.Duration {
+	backoff := float64(policy.InitialBackoff)
+	for i := 1; i < attempt && backoff < float64(policy.MaxBackoff); i++ {
+		backoff *= policy.Multiplier
+	}
+	if backoff > float64(policy.MaxBackoff) {
+		backoff = float64(policy.MaxBackoff)
+	}
+	delay := time.Duration(backoff/2 + jitter()*backoff/2)
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+		if delay > policy.MaxBackoff {
+			delay = policy.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// jitterRand is seeded explicitly, as the global source of math/rand always starts from the same seed, which would
+// make every process pick the same delays.
+var (
+	jitterMutex sync.Mutex
+	jitterRand  = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random number in [0, 1).
+func jitter() float64 {
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return jitterRand.Float64()
+}
+
+// transientCommandError matches the messages of commands (e.g. terragrunt output) failing because of a transient
+// condition: throttling, timeouts, connection failures and unavailable services.
+var transientCommandError = regexp.MustCompile(`(?i)(throttl|rate exceeded|too many requests|slow ?down|` +
+	`requestlimitexceeded|timeout|timed out|connection reset|connection refused|broken pipe|unexpected eof|` +
+	`service ?unavailable|internal ?error|bad gateway|\b50[0234]\b|\b429\b)`)
+
+// IsRetryable returns whether the given error is likely transient, so that the failed operation is worth retrying:
+// network errors, HTTP responses with a 429 or 5xx (but 501) status, and commands failing with a message reporting throttling,
+// a timeout or a connection failure. Context cancellations are not retryable.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || (statusErr.StatusCode >= 500 && statusErr.StatusCode != http.StatusNotImplemented)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return transientCommandError.MatchString(err.Error())
+	}
+	return false
+}
+
+// HTTPStatusError is returned when an HTTP request gets a response with an unexpected status.
+type HTTPStatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+
+	// Body is the beginning of the body of the response, if any.
+	Body string
+
+	// RetryAfter is the delay asked by the server through the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// newHTTPStatusError returns the HTTPStatusError for the given response to the given request, reading the beginning of
+// its body.
+func newHTTPStatusError(request *http.Request, response *http.Response) *HTTPStatusError {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	statusErr := &HTTPStatusError{
+		Method:     request.Method,
+		URL:        request.URL.String(),
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Body:       strings.TrimSpace(string(body)),
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return statusErr
+}
+
+func (err *HTTPStatusError) Error() string {
+	if err.Body == "" {
+		return fmt.Sprintf("%s %s returned %s", err.Method, err.URL, err.Status)
+	}
+	return fmt.Sprintf("%s %s returned %s: %s", err.Method, err.URL, err.Status, err.Body)
+}
@@ -0,0 +1,146 @@
+package terragrunt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule is a validation check of the units of a stack, written in Go, such as a naming convention or mandatory inputs.
+// It returns the findings for the given unit, whose configuration is always parsed.
+type Rule interface {
+	Check(unit *Unit) []Finding
+}
+
+// RuleFunc adapts a function to the Rule interface.
+type RuleFunc func(unit *Unit) []Finding
+
+func (fn RuleFunc) Check(unit *Unit) []Finding {
+	return fn(unit)
+}
+
+// RuleRunner applies a set of registered rules across the units of a stack.
+type RuleRunner struct {
+	rules []Rule
+}
+
+// NewRuleRunner returns a RuleRunner applying the given rules.
+func NewRuleRunner(rules ...Rule) *RuleRunner {
+	return &RuleRunner{rules: rules}
+}
+
+// Register adds the given rules to the rules applied by the runner.
+func (runner *RuleRunner) Register(rules ...Rule) {
+	runner.rules = append(runner.rules, rules...)
+}
+
+// Run applies the registered rules to every unit of the stack whose configuration could be parsed, and returns the
+// findings ordered by file and position. Findings without a filename are located in the configuration of their unit.
+func (runner *RuleRunner) Run(stack *Stack) []Finding {
+	var findings []Finding
+	for _, unit := range stack.Units {
+		if unit.Config == nil {
+			continue
+		}
+		for _, rule := range runner.rules {
+			for _, finding := range rule.Check(unit) {
+				if finding.Range.Filename == "" {
+					finding.Range.Filename = unit.ConfigPath
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Range.Filename != findings[j].Range.Filename {
+			return findings[i].Range.Filename < findings[j].Range.Filename
+		}
+		return findings[i].Range.Start.Byte < findings[j].Range.Start.Byte
+	})
+	return findings
+}
+
+const RuleRequiredInput = "required-input"
+
+// RequiredInputsRule returns a Rule reporting the units that do not set every one of the given inputs, such as tags
+// that every unit must set.
+func RequiredInputsRule(names ...string) Rule {
+	return RuleFunc(func(unit *Unit) []Finding {
+		var findings []Finding
+		for _, name := range names {
+			if _, found := unit.Config.InputsCty[name]; found {
+				continue
+			}
+			findings = append(findings, Finding{
+				RuleID:   RuleRequiredInput,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("input %q is required", name),
+				Range:    unit.Config.inputsRange(),
+			})
+		}
+		return findings
+	})
+}
+
+const RuleSourceNotAllowed = "source-not-allowed"
+
+// SourcePolicyRule returns a Rule reporting the units whose terraform source is not approved: sources must match one
+// of the allowed patterns, when any is given, and none of the denied patterns. Patterns are matched against the whole
+// source, with * matching any sequence of characters, slashes included (e.g. git::ssh://git@github.com/myorg/*).
+// Local sources are checked too, so they must be allowed explicitly (e.g. with ../*). Units without a source are not
+// checked.
+func SourcePolicyRule(allow []string, deny []string) Rule {
+	allowed := sourcePatterns(allow)
+	denied := sourcePatterns(deny)
+
+	return RuleFunc(func(unit *Unit) []Finding {
+		if unit.Config.Terraform == nil || unit.Config.Terraform.Source == nil {
+			return nil
+		}
+		source := *unit.Config.Terraform.Source
+
+		var message string
+		if pattern := matchingSourcePattern(denied, source); pattern != "" {
+			message = fmt.Sprintf("source %q is denied by the pattern %q", source, pattern)
+		} else if len(allowed) > 0 && matchingSourcePattern(allowed, source) == "" {
+			message = fmt.Sprintf("source %q does not match any of the allowed patterns", source)
+		} else {
+			return nil
+		}
+
+		origin, _ := unit.Config.Origin("terraform.source")
+		return []Finding{{
+			RuleID:   RuleSourceNotAllowed,
+			Severity: SeverityError,
+			Message:  message,
+			Range:    origin.Range,
+		}}
+	})
+}
+
+// sourcePattern is a compiled pattern of SourcePolicyRule.
+type sourcePattern struct {
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+func sourcePatterns(patterns []string) []sourcePattern {
+	compiled := make([]sourcePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		expression := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		compiled = append(compiled, sourcePattern{pattern: pattern, regexp: regexp.MustCompile("^" + expression + "$")})
+	}
+	return compiled
+}
+
+// matchingSourcePattern returns the first of the patterns matching the source, or an empty string when none does.
+func matchingSourcePattern(patterns []sourcePattern, source string) string {
+	for _, pattern := range patterns {
+		if pattern.regexp.MatchString(source) {
+			return pattern.pattern
+		}
+	}
+	return ""
+}
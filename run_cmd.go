@@ -0,0 +1,145 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// RunCmdOptions controls the run_cmd function, which is disabled unless commands are allowed or the record only mode
+// is enabled, so that untrusted configurations can be parsed safely.
+type RunCmdOptions struct {
+	// AllowedCommands are the commands run_cmd may run, e.g. git or /usr/bin/git. A command is allowed when it resolves
+	// to the same executable as one of these: names are looked up in the PATH, and paths are relative to the directory
+	// of the configuration. Allowing git thereby allows /usr/bin/git, but not a git of the checkout (./git).
+	AllowedCommands []string
+
+	// Jail is a directory that the configurations calling run_cmd must live in. Commands run in the directory of the
+	// configuration, so this keeps them within a checkout. Symlinks are resolved, so that a link of the jail pointing
+	// outside of it does not pass.
+	Jail string
+
+	// Timeout is the maximum duration of a command. No timeout applies when it is zero.
+	Timeout time.Duration
+
+	// RecordOnly disables running commands altogether: run_cmd returns the canned value from Responses for the command
+	// line (its arguments joined by spaces), or the empty string, and reports it to Record.
+	RecordOnly bool
+	Responses  map[string]string
+	Record     func(dir string, args []string)
+}
+
+// runCmdFunc returns the run_cmd(command, args...) function, which runs the given command in the directory of the
+// configuration and returns its trimmed stdout. Like in terragrunt, leading --terragrunt-quiet and
+// --terragrunt-global-cache flags are accepted and ignored.
+func runCmdFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Name: "args", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			var cmdArgs []string
+			for _, arg := range args {
+				cmdArgs = append(cmdArgs, arg.AsString())
+			}
+			for len(cmdArgs) > 0 && (cmdArgs[0] == "--terragrunt-quiet" || cmdArgs[0] == "--terragrunt-global-cache") {
+				cmdArgs = cmdArgs[1:]
+			}
+			if len(cmdArgs) == 0 {
+				return cty.NilVal, errors.New("run_cmd requires a command to run")
+			}
+
+			out, err := runCmd(opts, cmdArgs)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(out), nil
+		},
+	})
+}
+
+func runCmd(opts *ParseOptions, args []string) (string, error) {
+	runCmdOptions := opts.RunCmd
+	dir := opts.workingDir()
+
+	if runCmdOptions.Jail != "" {
+		jail, err := filepath.Abs(runCmdOptions.Jail)
+		if err != nil {
+			return "", err
+		}
+		realJail, err := filepath.EvalSymlinks(jail)
+		if err != nil {
+			return "", fmt.Errorf("resolving the run_cmd jail: %w", err)
+		}
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return "", fmt.Errorf("run_cmd is not allowed outside of %s: %w", jail, err)
+		}
+		if relPath, err := filepath.Rel(realJail, realDir); err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("run_cmd is not allowed outside of %s", jail)
+		}
+	}
+
+	if runCmdOptions.RecordOnly {
+		if runCmdOptions.Record != nil {
+			runCmdOptions.Record(dir, args)
+		}
+		return runCmdOptions.Responses[strings.Join(args, " ")], nil
+	}
+
+	runner := commandRunner(opts.CommandRunner)
+	if !allowedCommand(runner, runCmdOptions.AllowedCommands, args[0], dir) {
+		return "", fmt.Errorf("run_cmd is not allowed to run %q", args[0])
+	}
+
+	ctx := opts.Context
+	if runCmdOptions.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runCmdOptions.Timeout)
+		defer cancel()
+	}
+
+	output, err := runner.Run(ctx, Command{Name: args[0], Args: args[1:], Dir: dir})
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("run_cmd %s timed out after %s", strings.Join(args, " "), runCmdOptions.Timeout)
+		}
+		return "", fmt.Errorf("run_cmd %s: %w: %s", strings.Join(args, " "), err, bytes.TrimSpace(output.Stderr))
+	}
+	return strings.TrimSuffix(string(output.Stdout), "\n"), nil
+}
+
+// allowedCommand returns whether the given command, run in the given directory, resolves to the same executable as
+// one of the allowed commands.
+func allowedCommand(runner CommandRunner, allowed []string, command string, dir string) bool {
+	path, err := resolveCommand(runner, command, dir)
+	if err != nil {
+		return false
+	}
+	for _, name := range allowed {
+		if allowedPath, err := resolveCommand(runner, name, dir); err == nil && allowedPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveCommand returns the absolute path of the executable the given command runs in the given directory: the
+// names are looked up in the PATH, and the paths are relative to the directory. Symlinks are not resolved, as
+// multi-call binaries (e.g. busybox) run a different command depending on the name they are called with.
+func resolveCommand(runner CommandRunner, command string, dir string) (string, error) {
+	if strings.ContainsRune(command, '/') || strings.ContainsRune(command, filepath.Separator) {
+		command = resolvePath(dir, command)
+	}
+	path, err := runner.LookPath(command)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
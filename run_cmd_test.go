@@ -0,0 +1,64 @@
+package terragrunt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRunCmdRestrictions(t *testing.T) {
+	jail, outside := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(jail, "unit"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(jail, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		unitDir string
+		command string
+		err     string
+	}{
+		{name: "allowed command", unitDir: "unit", command: "git"},
+		{name: "allowed command by path", unitDir: "unit", command: "/usr/bin/git"},
+		{name: "command of the checkout", unitDir: "unit", command: "./git", err: `not allowed to run "./git"`},
+		{name: "command of another directory", unitDir: "unit", command: "/tmp/git", err: `not allowed to run "/tmp/git"`},
+		{name: "symlink outside of the jail", unitDir: "link", command: "git", err: "not allowed outside of " + jail},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &FakeCommandRunner{
+				Paths: map[string]string{"git": "/usr/bin/git"},
+				Handler: func(ctx context.Context, command Command) (CommandOutput, error) {
+					return CommandOutput{Stdout: []byte("abc\n")}, nil
+				},
+			}
+			config, err := ParseConfig([]byte(`inputs = { sha = run_cmd("`+test.command+`", "rev-parse", "HEAD") }`),
+				WithConfigPath(filepath.Join(jail, test.unitDir, DefaultConfigFilename)),
+				WithRunCmd(RunCmdOptions{AllowedCommands: []string{"git"}, Jail: jail}),
+				WithCommandRunner(runner))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				if commands := runner.Commands(); len(commands) > 0 {
+					t.Errorf("got commands %v, want none", commands)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sha := config.InputsCty["sha"]; !sha.RawEquals(cty.StringVal("abc")) {
+				t.Errorf("got sha %#v, want abc", sha)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// S3StateOutputResolver is an OutputResolver reading the outputs of dependencies from their state in s3, as
+// configured by their remote_state block, instead of running terragrunt output. It reads the state of the workspace
+// of each dependency, in the format of terraform 0.12 and later, decrypting the states encrypted by OpenTofu.
+//
+// The state is read with the credentials of the role of the dependency, when its s3 config has a role_arn (or an
+// assume_role block), or else its configuration declares an iam_role. Roles are assumed through Roles, which caches
+// their credentials per unit, with the credentials selected for the dependency by UnitCredentials, if any.
+type S3StateOutputResolver struct {
+	// Credentials sign the requests of the dependencies without a role, and assume the roles, unless UnitCredentials
+	// selects other credentials for the dependency. Defaults to EnvAWSCredentials.
+	Credentials AWSCredentialsProvider
+
+	// UnitCredentials selects the credentials of each dependency, by profile or web identity. Defaults to the
+	// profile or web identity of the s3 config of the dependency, if any.
+	UnitCredentials *UnitAWSCredentials
+
+	// Roles assumes the roles of the dependencies. Defaults to a cache assuming them with an STSClient signing its
+	// requests with Credentials.
+	Roles *AssumeRoleCache
+
+	// Encryption holds the keys to decrypt the states encrypted by OpenTofu with. Its passphrase and KMS client take
+	// precedence over the ones configured by the encryption attribute of the remote_state block of the dependency. The
+	// KMS client defaults to one signing requests with the credentials of the dependency.
+	Encryption *StateEncryption
+
+	// Workspace selects the workspace of the dependencies. Defaults to the workspace selected by their configuration.
+	// The states of the workspaces other than the default one are read under the workspace_key_prefix of the s3
+	// config (env: by default).
+	Workspace WorkspaceFunc
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+
+	// Options are the options the configurations of the dependencies are parsed with, to read their remote_state
+	// block. The AWS identity functions of the configurations declaring a role return the identity of the role.
+	Options []Option
+
+	defaultsOnce sync.Once
+}
+
+// s3StateLocation is the location of the state of a unit in s3, with the role to read it with, if any.
+type s3StateLocation struct {
+	Bucket    string
+	Key       string
+	Region    string
+	Endpoint  string
+	PathStyle bool
+	Role      *AWSRole
+}
+
+func (resolver *S3StateOutputResolver) ResolveOutputs(ctx context.Context, configPath string) ([]byte, error) {
+	configPath = filepath.Join(configDir(configPath), DefaultConfigFilename)
+	opts := append(append([]Option(nil), resolver.Options...), WithContext(ctx), WithAssumeRoleCache(resolver.roles()))
+	config, err := ParseConfigFile(configPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := newS3StateLocation(config, unitWorkspace(resolver.Workspace, configPath, config))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	credentials := resolver.credentials()
+	unitCredentials := resolver.unitCredentials().ForUnit(configPath, config)
+	switch {
+	case location.Role != nil && unitCredentials != nil:
+		sts := resolver.unitCredentials().STS
+		sts.Credentials = unitCredentials
+		credentials = resolver.roles().credentialsWith(configPath, *location.Role, sts)
+	case location.Role != nil:
+		credentials = resolver.roles().Credentials(configPath, *location.Role)
+	case unitCredentials != nil:
+		credentials = unitCredentials
+	}
+
+	var state []byte
+	err = resolver.Retry.Do(ctx, func() error {
+		var err error
+		state, err = resolver.getObject(ctx, location, credentials)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		// The dependency is not applied yet, so that its mock outputs apply.
+		return []byte("{}"), nil
+	}
+
+	encryption := newStateEncryption(config.RemoteState, resolver.Encryption, credentials)
+	if state, err = encryption.Decrypt(ctx, state); err != nil {
+		return nil, fmt.Errorf("reading state s3://%s/%s: %w", location.Bucket, location.Key, err)
+	}
+	return stateOutputs(state)
+}
+
+// ResolveOutputKeys returns the given outputs of the state of the dependency at the given config path. The state is
+// still read whole, but only the given outputs are returned, to be cached and decoded.
+func (resolver *S3StateOutputResolver) ResolveOutputKeys(ctx context.Context, configPath string, keys []string) ([]byte, error) {
+	outputs, err := resolver.ResolveOutputs(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return filterOutputKeys(outputs, keys)
+}
+
+func (resolver *S3StateOutputResolver) credentials() AWSCredentialsProvider {
+	if resolver.Credentials == nil {
+		return EnvAWSCredentials{}
+	}
+	return resolver.Credentials
+}
+
+func (resolver *S3StateOutputResolver) roles() *AssumeRoleCache {
+	resolver.defaultsOnce.Do(resolver.setDefaults)
+	return resolver.Roles
+}
+
+func (resolver *S3StateOutputResolver) unitCredentials() *UnitAWSCredentials {
+	resolver.defaultsOnce.Do(resolver.setDefaults)
+	return resolver.UnitCredentials
+}
+
+func (resolver *S3StateOutputResolver) setDefaults() {
+	if resolver.Roles == nil {
+		resolver.Roles = NewAssumeRoleCache(STSClient{Credentials: resolver.credentials()})
+	}
+	if resolver.UnitCredentials == nil {
+		resolver.UnitCredentials = &UnitAWSCredentials{}
+	}
+}
+
+// getObject reads the object at the given location, signing the request with the given credentials. It returns nil
+// when the object does not exist.
+func (resolver *S3StateOutputResolver) getObject(ctx context.Context, location s3StateLocation, credentialsProvider AWSCredentialsProvider) ([]byte, error) {
+	objectURL := &url.URL{Scheme: "https", Host: "s3." + location.Region + ".amazonaws.com"}
+	if location.Endpoint != "" {
+		endpoint, err := url.Parse(location.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid s3 endpoint %q: %w", location.Endpoint, err)
+		}
+		objectURL.Scheme, objectURL.Host = endpoint.Scheme, endpoint.Host
+	}
+	// Bucket names with dots do not match the wildcard certificate of the virtual hosted-style endpoints.
+	if location.PathStyle || strings.Contains(location.Bucket, ".") {
+		objectURL.Path = "/" + location.Bucket + "/" + location.Key
+	} else {
+		objectURL.Host = location.Bucket + "." + objectURL.Host
+		objectURL.Path = "/" + location.Key
+	}
+	// The path is sent encoded as it is signed, since url.URL leaves characters such as the ':' of workspace keys raw.
+	objectURL.RawPath = awsEscapePath(objectURL.Path)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := credentialsProvider.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signAWSRequest(request, nil, credentials, location.Region, "s3", time.Now())
+
+	httpClient := resolver.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading state s3://%s/%s: %w", location.Bucket, location.Key, newHTTPStatusError(request, response))
+	}
+	return io.ReadAll(response.Body)
+}
+
+// newS3StateLocation returns the location of the state of the given workspace of the unit with the given
+// configuration, from its s3 remote_state block.
+func newS3StateLocation(config *TerragruntConfig, workspace string) (s3StateLocation, error) {
+	if config.RemoteState == nil {
+		return s3StateLocation{}, fmt.Errorf("no remote_state block")
+	}
+	if config.RemoteState.Backend != "s3" {
+		return s3StateLocation{}, fmt.Errorf("reading the state of the %s backend is not supported, only s3", config.RemoteState.Backend)
+	}
+	backend, err := backendConfig(config.RemoteState)
+	if err != nil {
+		return s3StateLocation{}, err
+	}
+
+	location := s3StateLocation{
+		Bucket:    backendString(backend, "bucket"),
+		Key:       backendString(backend, "key"),
+		Region:    backendString(backend, "region"),
+		Endpoint:  backendString(backend, "endpoint"),
+		PathStyle: backendBool(backend, "use_path_style") || backendBool(backend, "force_path_style"),
+	}
+	if endpoints, found := backend["endpoints"]; found && endpoints.CanIterateElements() {
+		if endpoint := backendString(endpoints.AsValueMap(), "s3"); endpoint != "" {
+			location.Endpoint = endpoint
+		}
+	}
+	if location.Bucket == "" || location.Key == "" || location.Region == "" {
+		return s3StateLocation{}, fmt.Errorf("the bucket, key and region of the s3 remote_state config must be set")
+	}
+	if workspace != DefaultWorkspace {
+		prefix := backendString(backend, "workspace_key_prefix")
+		if prefix == "" {
+			prefix = "env:"
+		}
+		location.Key = prefix + "/" + workspace + "/" + location.Key
+	}
+
+	if roleARN := backendString(backend, "role_arn"); roleARN != "" {
+		location.Role = &AWSRole{ARN: roleARN, SessionName: backendString(backend, "session_name")}
+	} else if assumeRole, found := backend["assume_role"]; found && assumeRole.CanIterateElements() {
+		attributes := assumeRole.AsValueMap()
+		location.Role = &AWSRole{ARN: backendString(attributes, "role_arn"), SessionName: backendString(attributes, "session_name")}
+		if duration := backendString(attributes, "duration"); duration != "" {
+			if location.Role.Duration, err = time.ParseDuration(duration); err != nil {
+				return s3StateLocation{}, fmt.Errorf("invalid assume_role duration %q", duration)
+			}
+		}
+	} else if role, found := configAWSRole(config); found {
+		location.Role = &role
+	}
+	return location, nil
+}
+
+func backendString(config map[string]cty.Value, key string) string {
+	value, found := config[key]
+	if !found || value.IsNull() || value.Type() != cty.String {
+		return ""
+	}
+	return value.AsString()
+}
+
+func backendBool(config map[string]cty.Value, key string) bool {
+	value, found := config[key]
+	return found && !value.IsNull() && value.Type() == cty.Bool && value.True()
+}
+
+// stateOutputs returns the outputs of the given state, in the format of terraform output -json.
+func stateOutputs(state []byte) ([]byte, error) {
+	var decoded struct {
+		Version int `json:"version"`
+		Outputs map[string]struct {
+			Sensitive bool            `json:"sensitive"`
+			Type      json.RawMessage `json:"type"`
+			Value     json.RawMessage `json:"value"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(state, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid state: %w", err)
+	}
+	if decoded.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version %d, expected 4", decoded.Version)
+	}
+	if decoded.Outputs == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(decoded.Outputs)
+}
@@ -0,0 +1,118 @@
+package terragrunt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestAWSEscapePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "", want: "/"},
+		{path: "/", want: "/"},
+		{path: "/app/terraform.tfstate", want: "/app/terraform.tfstate"},
+		{path: "/env:/dev/app/terraform.tfstate", want: "/env%3A/dev/app/terraform.tfstate"},
+		{path: "/a=1/b+c/d@e/f g/-._~", want: "/a%3D1/b%2Bc/d%40e/f%20g/-._~"},
+		{path: "/ሴ", want: "/%E1%88%B4"},
+	}
+
+	for _, test := range tests {
+		if got := awsEscapePath(test.path); got != test.want {
+			t.Errorf("awsEscapePath(%q): got %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestS3StateOutputResolverWorkspaceKey(t *testing.T) {
+	var requestURI, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI, authorization = r.RequestURI, r.Header.Get("Authorization")
+		w.Write([]byte(`{"version": 4, "outputs": {"id": {"value": "vpc-1", "type": "string"}}}`))
+	}))
+	defer server.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"vpc/terragrunt.hcl": `
+remote_state {
+  backend = "s3"
+  config = {
+    bucket         = "state"
+    key            = "app=1/terraform.tfstate"
+    region         = "us-east-1"
+    endpoint       = "` + server.URL + `"
+    use_path_style = true
+  }
+}
+`,
+	})
+	resolver := &S3StateOutputResolver{
+		Credentials: StaticAWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+		Workspace:   func(configPath string) string { return "dev" },
+		HTTPClient:  server.Client(),
+	}
+
+	outputs, err := resolver.ResolveOutputs(context.Background(), filepath.Join(dir, "vpc", DefaultConfigFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(outputs), `"vpc-1"`) {
+		t.Errorf("got outputs %s, want the id output", outputs)
+	}
+	if want := "/state/env%3A/dev/app%3D1/terraform.tfstate"; requestURI != want {
+		t.Errorf("got request URI %q, want %q", requestURI, want)
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Errorf("got authorization %q, want a signature version 4", authorization)
+	}
+}
+
+func TestS3StateOutputResolverNotApplied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+	}))
+	defer server.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"vpc/terragrunt.hcl": `
+remote_state {
+  backend = "s3"
+  config = {
+    bucket         = "state"
+    key            = "vpc/terraform.tfstate"
+    region         = "us-east-1"
+    endpoint       = "` + server.URL + `"
+    use_path_style = true
+  }
+}
+`,
+		"app/terragrunt.hcl": `
+dependency "vpc" {
+  config_path  = "../vpc"
+  mock_outputs = { id = "mock-vpc" }
+}
+
+inputs = { vpc_id = dependency.vpc.outputs.id }
+`,
+	})
+	resolver := &S3StateOutputResolver{
+		Credentials: StaticAWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+		HTTPClient:  server.Client(),
+	}
+
+	config, err := ParseConfigFile(filepath.Join(dir, "app", DefaultConfigFilename), WithOutputResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vpcID := config.InputsCty["vpc_id"]; !vpcID.RawEquals(cty.StringVal("mock-vpc")) {
+		t.Errorf("got vpc_id %#v, want the mock output", vpcID)
+	}
+}
@@ -0,0 +1,135 @@
+package terragrunt
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// DefaultMaxConfigSize is the maximum size of the files ParseConfigSafe reads, when WithMaxConfigSize is not set.
+	DefaultMaxConfigSize = 1 << 20
+
+	// DefaultParseTimeout is the maximum duration of ParseConfigSafe, when WithParseTimeout is not set.
+	DefaultParseTimeout = 10 * time.Second
+)
+
+// ConfigTooLargeError is the error of a configuration, or of a file read while parsing it, larger than
+// MaxConfigSize.
+type ConfigTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (err *ConfigTooLargeError) Error() string {
+	return fmt.Sprintf("%s is too large: %d bytes, the limit is %d bytes", err.Path, err.Size, err.Limit)
+}
+
+// ParseTimeoutError is the error of ParseConfigSafe when parsing takes longer than its ParseTimeout.
+type ParseTimeoutError struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func (err *ParseTimeoutError) Error() string {
+	return fmt.Sprintf("parsing %s timed out after %s", err.Path, err.Timeout)
+}
+
+// ParsePanicError is the error of ParseConfigSafe when parsing panics, e.g. on an edge case of hcl or cty.
+type ParsePanicError struct {
+	Path string
+
+	// Value is the value the parsing panicked with, and Stack the stack trace of the panic.
+	Value interface{}
+	Stack []byte
+}
+
+func (err *ParsePanicError) Error() string {
+	return fmt.Sprintf("parsing %s panicked: %v", err.Path, err.Value)
+}
+
+// Unwrap returns the value the parsing panicked with, when it is an error.
+func (err *ParsePanicError) Unwrap() error {
+	if valueErr, ok := err.Value.(error); ok {
+		return valueErr
+	}
+	return nil
+}
+
+// ParseConfigSafe is like ParseConfig, for services parsing configurations from user input: a panic while parsing is
+// returned as a *ParsePanicError, and the size of the files read and the duration of parsing are limited by
+// WithMaxConfigSize and WithParseTimeout, DefaultMaxConfigSize and DefaultParseTimeout by default. The context of the
+// operations performed while parsing, such as run_cmd() or retrieving dependency outputs, is cancelled past the
+// timeout, but the evaluation of HCL expressions can not be interrupted: it goes on in the background until it
+// completes. Functions with side effects are still available: combine with WithHermeticMode for untrusted
+// configurations.
+func ParseConfigSafe(content []byte, opts ...Option) (*TerragruntConfig, error) {
+	parseOptions := newParseOptions(opts)
+	maxSize, timeout := parseOptions.MaxConfigSize, parseOptions.ParseTimeout
+	if maxSize <= 0 {
+		maxSize = DefaultMaxConfigSize
+	}
+	if timeout <= 0 {
+		timeout = DefaultParseTimeout
+	}
+
+	if size := int64(len(content)); size > maxSize {
+		err := &ConfigTooLargeError{Path: parseOptions.ConfigPath, Size: size, Limit: maxSize}
+		parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parseOptions.Context, timeout)
+	defer cancel()
+	opts = append(opts[:len(opts):len(opts)], WithContext(ctx), WithMaxConfigSize(maxSize))
+
+	type result struct {
+		config *TerragruntConfig
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				stack := debug.Stack()
+				parseOptions.Logger.Log(EventPanicRecovered, "filename", parseOptions.ConfigPath, "panic", value, "stack", string(stack))
+				err := &ParsePanicError{Path: parseOptions.ConfigPath, Value: value, Stack: stack}
+				parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+				results <- result{err: err}
+			}
+		}()
+		config, err := ParseConfig(content, opts...)
+		results <- result{config: config, err: err}
+	}()
+
+	select {
+	case result := <-results:
+		return result.config, result.err
+	case <-ctx.Done():
+		if err := parseOptions.Context.Err(); err != nil {
+			return nil, err
+		}
+		err := &ParseTimeoutError{Path: parseOptions.ConfigPath, Timeout: timeout}
+		parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+		return nil, err
+	}
+}
+
+// ParseConfigFileSafe is like ParseConfigFile, with the limits and the panic recovery of ParseConfigSafe.
+func ParseConfigFileSafe(configPath string, opts ...Option) (*TerragruntConfig, error) {
+	opts = append([]Option{WithConfigPath(configPath)}, opts...)
+
+	parseOptions := newParseOptions(opts)
+	if parseOptions.MaxConfigSize <= 0 {
+		parseOptions.MaxConfigSize = DefaultMaxConfigSize
+	}
+	content, err := parseOptions.readFile(configPath)
+	if err != nil {
+		parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+		return nil, err
+	}
+
+	return ParseConfigSafe(content, opts...)
+}
@@ -0,0 +1,301 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// BoilerplateConfigFilename is the name of the file declaring the variables of a scaffold template.
+const BoilerplateConfigFilename = "boilerplate.yml"
+
+// ScaffoldTemplate is a boilerplate template units are scaffolded from, as by terragrunt scaffold: a directory of files
+// rendered with text/template, along with the variables they are rendered with, declared by its boilerplate.yml.
+type ScaffoldTemplate struct {
+	Dir       string
+	Variables []ScaffoldVariable
+}
+
+// ScaffoldVariable is a variable declared by the boilerplate.yml of a template.
+type ScaffoldVariable struct {
+	Name        string
+	Description string
+
+	// Type is the boilerplate type of the variable: string, int, float, bool, list, map or enum. Defaults to string.
+	Type string
+
+	// Options are the allowed values of enum variables.
+	Options []string
+
+	// Default is the value of the variable when none is given. It is cty.NilVal for required variables.
+	Default cty.Value
+}
+
+// ParseScaffoldTemplate reads the variables of the template in the given directory from its boilerplate.yml. Templates
+// without one have no variables.
+func ParseScaffoldTemplate(dir string) (*ScaffoldTemplate, error) {
+	scaffoldTemplate := &ScaffoldTemplate{Dir: dir}
+	configPath := filepath.Join(dir, BoilerplateConfigFilename)
+	content, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return scaffoldTemplate, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeYAML(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	variables := objectAttribute(config, "variables")
+	if variables.IsNull() {
+		return scaffoldTemplate, nil
+	}
+	if !variables.CanIterateElements() {
+		return nil, fmt.Errorf("%s: variables must be a list", configPath)
+	}
+	for it := variables.ElementIterator(); it.Next(); {
+		_, value := it.Element()
+		variable, err := newScaffoldVariable(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", configPath, err)
+		}
+		scaffoldTemplate.Variables = append(scaffoldTemplate.Variables, variable)
+	}
+	return scaffoldTemplate, nil
+}
+
+func newScaffoldVariable(value cty.Value) (ScaffoldVariable, error) {
+	variable := ScaffoldVariable{Type: "string", Default: cty.NilVal}
+	if !value.Type().IsObjectType() {
+		return variable, fmt.Errorf("invalid variable %s", value.GoString())
+	}
+	var err error
+	if variable.Name, err = stringAttribute(value, "name"); err != nil || variable.Name == "" {
+		return variable, fmt.Errorf("variables must have a name")
+	}
+	if variable.Description, err = stringAttribute(value, "description"); err != nil {
+		return variable, fmt.Errorf("variable %s: %w", variable.Name, err)
+	}
+	if variableType, err := stringAttribute(value, "type"); err != nil {
+		return variable, fmt.Errorf("variable %s: %w", variable.Name, err)
+	} else if variableType != "" {
+		variable.Type = variableType
+	}
+	if options := objectAttribute(value, "options"); !options.IsNull() && options.CanIterateElements() {
+		for it := options.ElementIterator(); it.Next(); {
+			_, option := it.Element()
+			converted, err := convert.Convert(option, cty.String)
+			if err != nil || converted.IsNull() {
+				return variable, fmt.Errorf("variable %s: invalid option %s", variable.Name, option.GoString())
+			}
+			variable.Options = append(variable.Options, converted.AsString())
+		}
+	}
+	if value.Type().HasAttribute("default") {
+		if variable.Default, err = variable.convert(value.GetAttr("default")); err != nil {
+			return variable, fmt.Errorf("variable %s: invalid default: %w", variable.Name, err)
+		}
+	}
+	return variable, nil
+}
+
+// objectAttribute returns the given attribute of an object, or null when the object does not have it.
+func objectAttribute(object cty.Value, name string) cty.Value {
+	if object.IsNull() || !object.Type().IsObjectType() || !object.Type().HasAttribute(name) {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+	return object.GetAttr(name)
+}
+
+func stringAttribute(object cty.Value, name string) (string, error) {
+	value := objectAttribute(object, name)
+	if value.IsNull() {
+		return "", nil
+	}
+	value, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return value.AsString(), nil
+}
+
+// convert converts the given value to the type of the variable.
+func (variable ScaffoldVariable) convert(value cty.Value) (cty.Value, error) {
+	switch variable.Type {
+	case "string":
+		return convert.Convert(value, cty.String)
+	case "int", "float":
+		converted, err := convert.Convert(value, cty.Number)
+		if err == nil && variable.Type == "int" && !converted.IsNull() && !converted.AsBigFloat().IsInt() {
+			return cty.NilVal, fmt.Errorf("%s is not an integer", converted.AsBigFloat().String())
+		}
+		return converted, err
+	case "bool":
+		return convert.Convert(value, cty.Bool)
+	case "list":
+		if !value.Type().IsListType() && !value.Type().IsTupleType() && !value.Type().IsSetType() {
+			return cty.NilVal, fmt.Errorf("a list is required")
+		}
+		return value, nil
+	case "map":
+		if !value.Type().IsMapType() && !value.Type().IsObjectType() {
+			return cty.NilVal, fmt.Errorf("a map is required")
+		}
+		return value, nil
+	case "enum":
+		converted, err := convert.Convert(value, cty.String)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if !converted.IsNull() && !containsString(variable.Options, converted.AsString()) {
+			return cty.NilVal, fmt.Errorf("%q is not one of %s", converted.AsString(), strings.Join(variable.Options, ", "))
+		}
+		return converted, nil
+	}
+	return cty.NilVal, fmt.Errorf("unsupported variable type %s", variable.Type)
+}
+
+// Scaffold renders the template in the given directory into the target directory, as terragrunt scaffold does, and
+// returns the paths of the files it wrote. The variables of the template are set from the given values, converted to
+// their type, or else their default: the template fails to render when a required variable has no value. The paths of
+// the files are templates too, and the rendered .hcl files are formatted, so that the files are ready to commit.
+// Existing files are never overwritten.
+func Scaffold(templateDir string, targetDir string, values map[string]cty.Value) ([]string, error) {
+	scaffoldTemplate, err := ParseScaffoldTemplate(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := scaffoldTemplate.data(values)
+	if err != nil {
+		return nil, err
+	}
+
+	type renderedFile struct {
+		path    string
+		content []byte
+		mode    fs.FileMode
+	}
+	var files []renderedFile
+	err = filepath.WalkDir(templateDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(templateDir, filePath)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || relPath == BoilerplateConfigFilename {
+			return nil
+		}
+
+		targetPath, err := renderScaffoldTemplate(relPath, filepath.ToSlash(relPath), data)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		rendered, err := renderScaffoldTemplate(relPath, string(content), data)
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(targetPath) == ".hcl" {
+			formatted, err := Format([]byte(rendered), WithFormatFilename(targetPath))
+			if err != nil {
+				return fmt.Errorf("%s: rendered an invalid configuration: %w", relPath, err)
+			}
+			rendered = string(formatted)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, renderedFile{path: filepath.Join(targetDir, filepath.FromSlash(targetPath)), content: []byte(rendered), mode: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The files are only written once every template rendered, so that a failed scaffold leaves nothing behind.
+	for _, file := range files {
+		if _, err := os.Stat(file.path); err == nil {
+			return nil, fmt.Errorf("%s already exists", file.path)
+		}
+	}
+	var written []string
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(file.path, file.content, file.mode); err != nil {
+			return written, err
+		}
+		written = append(written, file.path)
+	}
+	return written, nil
+}
+
+// data returns the values of the variables of the template, as the data of text/template: cty values are converted to
+// their json counterpart (strings, float64 numbers, bools, slices and maps). Values of undeclared variables are passed
+// as is.
+func (scaffoldTemplate *ScaffoldTemplate) data(values map[string]cty.Value) (map[string]interface{}, error) {
+	resolved := map[string]cty.Value{}
+	for name, value := range values {
+		resolved[name] = value
+	}
+	for _, variable := range scaffoldTemplate.Variables {
+		value, found := values[variable.Name]
+		if !found {
+			if variable.Default.Type() == cty.NilType {
+				return nil, fmt.Errorf("missing value of the required variable %s", variable.Name)
+			}
+			resolved[variable.Name] = variable.Default
+			continue
+		}
+		converted, err := variable.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of the variable %s: %w", variable.Name, err)
+		}
+		resolved[variable.Name] = converted
+	}
+
+	data := map[string]interface{}{}
+	for name, value := range resolved {
+		encoded, err := json.Marshal(ctyjson.SimpleJSONValue{Value: value})
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return nil, err
+		}
+		data[name] = decoded
+	}
+	return data, nil
+}
+
+// renderScaffoldTemplate renders the given text of the template file with the given name.
+func renderScaffoldTemplate(name string, text string, data map[string]interface{}) (string, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := parsed.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,230 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// ConfigSchema describes the attributes and blocks of a body of the configuration, as decoded by this package. It is
+// derived from the structs the configuration is decoded into, so that editors and tools in other languages can
+// validate or complete configurations consistently with the parser.
+type ConfigSchema struct {
+	Attributes []SchemaAttribute `json:"attributes,omitempty"`
+	Blocks     []SchemaBlock     `json:"blocks,omitempty"`
+
+	// AnyAttributes is set for the bodies accepting attributes of any name, such as locals.
+	AnyAttributes bool `json:"any_attributes,omitempty"`
+}
+
+// SchemaAttribute describes an attribute of a body.
+type SchemaAttribute struct {
+	Name string `json:"name"`
+
+	// Type is the type the value of the attribute is converted to, cty.DynamicPseudoType when any value is accepted.
+	// It is encoded in json as cty types are, e.g. "string" or ["list","string"].
+	Type     cty.Type `json:"type"`
+	Required bool     `json:"required,omitempty"`
+}
+
+// SchemaBlock describes a block type of a body.
+type SchemaBlock struct {
+	Type   string   `json:"type"`
+	Labels []string `json:"labels,omitempty"`
+
+	// Multiple is set when the block can be repeated, e.g. with different labels.
+	Multiple bool `json:"multiple,omitempty"`
+	Required bool `json:"required,omitempty"`
+
+	Body *ConfigSchema `json:"body"`
+}
+
+// TerragruntSchema returns the schema of terragrunt.hcl, as decoded by ParseConfig.
+func TerragruntSchema() *ConfigSchema {
+	schema := structSchema(reflect.TypeOf(TerragruntConfigFile{}))
+	for i, block := range schema.Blocks {
+		if block.Type == "include" {
+			// Include blocks are decoded separately, before the rest of the configuration.
+			includeType := reflect.TypeOf(terragruntIncludes{}).Field(0).Type.Elem()
+			schema.Blocks[i].Body = structSchema(includeType)
+		}
+	}
+	// The locals are decoded separately too, and accept any attribute.
+	locals := SchemaBlock{Type: "locals", Body: &ConfigSchema{AnyAttributes: true}}
+	schema.Blocks = append([]SchemaBlock{locals}, schema.Blocks...)
+	return schema
+}
+
+// StackFileSchema returns the schema of terragrunt.stack.hcl, as decoded by ParseStackConfigFile.
+func StackFileSchema() *ConfigSchema {
+	schema := structSchema(reflect.TypeOf(StackConfig{}))
+	locals := SchemaBlock{Type: "locals", Body: &ConfigSchema{AnyAttributes: true}}
+	schema.Blocks = append([]SchemaBlock{locals}, schema.Blocks...)
+	return schema
+}
+
+// structSchema returns the schema of the body decoded into the given struct type by gohcl, from the hcl tags of its
+// fields.
+func structSchema(structType reflect.Type) *ConfigSchema {
+	schema := &ConfigSchema{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, kind, _ := strings.Cut(field.Tag.Get("hcl"), ",")
+		switch kind {
+		case "attr", "optional":
+			schema.Attributes = append(schema.Attributes, SchemaAttribute{
+				Name:     name,
+				Type:     impliedSchemaType(field.Type),
+				Required: kind == "attr" && field.Type.Kind() != reflect.Ptr,
+			})
+		case "block":
+			blockType := field.Type
+			block := SchemaBlock{Type: name, Required: blockType.Kind() == reflect.Struct}
+			if blockType.Kind() == reflect.Slice {
+				block.Multiple = true
+				blockType = blockType.Elem()
+			}
+			if blockType.Kind() == reflect.Ptr {
+				blockType = blockType.Elem()
+			}
+			block.Labels = structLabels(blockType)
+			block.Body = structSchema(blockType)
+			schema.Blocks = append(schema.Blocks, block)
+		}
+	}
+	return schema
+}
+
+// structLabels returns the names of the labels of the block decoded into the given struct type.
+func structLabels(structType reflect.Type) []string {
+	var labels []string
+	for i := 0; i < structType.NumField(); i++ {
+		name, kind, _ := strings.Cut(structType.Field(i).Tag.Get("hcl"), ",")
+		if kind != "label" {
+			continue
+		}
+		if name == "" {
+			name = "name"
+		}
+		labels = append(labels, name)
+	}
+	return labels
+}
+
+var (
+	ctyValueType = reflect.TypeOf(cty.Value{})
+	hclBodyType  = reflect.TypeOf((*hcl.Body)(nil)).Elem()
+)
+
+// impliedSchemaType returns the type the value of an attribute decoded into a field of the given type is converted
+// to. cty.Value fields accept any value.
+func impliedSchemaType(fieldType reflect.Type) cty.Type {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType == ctyValueType || fieldType.Implements(hclBodyType) {
+		return cty.DynamicPseudoType
+	}
+	impliedType, err := gocty.ImpliedType(reflect.Zero(fieldType).Interface())
+	if err != nil {
+		return cty.DynamicPseudoType
+	}
+	return impliedType
+}
+
+// JSONSchema returns the JSON Schema (draft-07) of the configuration files in the HCL JSON syntax described by the
+// given schema, e.g. terragrunt.hcl.json for TerragruntSchema. As in the HCL JSON syntax, blocks are objects nested in
+// an object per label, or lists of them, and attributes accept template strings (e.g. "${local.env}") besides values
+// of their type. The top level body accepts the blocks of types the schema does not declare, as the parser keeps them
+// in UnknownBlocks.
+func JSONSchema(schema *ConfigSchema) ([]byte, error) {
+	root := bodyJSONSchema(schema)
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	if !schema.AnyAttributes {
+		root["additionalProperties"] = map[string]interface{}{"type": []string{"object", "array"}}
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// bodyJSONSchema returns the JSON Schema of the JSON object of a body.
+func bodyJSONSchema(schema *ConfigSchema) map[string]interface{} {
+	// Comments are set as "//" keys in the HCL JSON syntax.
+	properties := map[string]interface{}{"//": map[string]interface{}{}}
+	var required []string
+	for _, attribute := range schema.Attributes {
+		properties[attribute.Name] = typeJSONSchema(attribute.Type)
+		if attribute.Required {
+			required = append(required, attribute.Name)
+		}
+	}
+	for _, block := range schema.Blocks {
+		properties[block.Type] = blockJSONSchema(block, len(block.Labels))
+		if block.Required {
+			required = append(required, block.Type)
+		}
+	}
+
+	body := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": schema.AnyAttributes,
+	}
+	if len(required) > 0 {
+		body["required"] = required
+	}
+	return body
+}
+
+// blockJSONSchema returns the JSON Schema of the blocks of the given type, with the given number of labels left.
+func blockJSONSchema(block SchemaBlock, labels int) map[string]interface{} {
+	nested := bodyJSONSchema(block.Body)
+	if labels > 0 {
+		nested = map[string]interface{}{"type": "object", "additionalProperties": blockJSONSchema(block, labels-1)}
+	}
+	return map[string]interface{}{"anyOf": []interface{}{
+		nested,
+		map[string]interface{}{"type": "array", "items": nested},
+	}}
+}
+
+// templateJSONSchema is the JSON Schema of the template strings, which evaluate to values of any type.
+var templateJSONSchema = map[string]interface{}{"type": "string"}
+
+// typeJSONSchema returns the JSON Schema of the values of the given type in the HCL JSON syntax.
+func typeJSONSchema(valueType cty.Type) map[string]interface{} {
+	var typed map[string]interface{}
+	switch {
+	case valueType == cty.DynamicPseudoType:
+		return map[string]interface{}{}
+	case valueType == cty.String:
+		return templateJSONSchema
+	case valueType == cty.Number:
+		typed = map[string]interface{}{"type": "number"}
+	case valueType == cty.Bool:
+		typed = map[string]interface{}{"type": "boolean"}
+	case valueType.IsListType() || valueType.IsSetType():
+		typed = map[string]interface{}{"type": "array", "items": typeJSONSchema(valueType.ElementType())}
+	case valueType.IsMapType():
+		typed = map[string]interface{}{"type": "object", "additionalProperties": typeJSONSchema(valueType.ElementType())}
+	case valueType.IsObjectType():
+		properties := map[string]interface{}{}
+		var required []string
+		for name, attributeType := range valueType.AttributeTypes() {
+			properties[name] = typeJSONSchema(attributeType)
+			required = append(required, name)
+		}
+		typed = map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 {
+			sort.Strings(required)
+			typed["required"] = required
+		}
+	default:
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{"anyOf": []interface{}{typed, templateJSONSchema}}
+}
@@ -0,0 +1,213 @@
+package terragrunt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const RuleHardcodedSecret = "hardcoded-secret"
+
+// secretPatterns are the patterns of well known secrets reported by ScanSecrets.
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key id", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+}
+
+// secretCandidate matches the strings checked for their entropy: long runs of the characters of base64 and hex
+// encoded values.
+var secretCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)
+
+// minSecretEntropy is the Shannon entropy, in bits per character, above which a candidate is reported as a secret.
+const minSecretEntropy = 3.5
+
+// ScanSecrets statically checks the literal strings of the inputs, the locals and the contents of the generate blocks
+// of the given terragrunt configuration content for hardcoded secrets, and returns the findings, ordered by position.
+// Strings matching well known secret formats (AWS access keys, private keys, bearer tokens, ...) are reported as
+// errors, and random looking strings (long, mixing upper case letters, lower case letters and digits, with a high
+// entropy) as warnings. The secrets are masked in the messages. Use WithConfigPath to set the filename reported in the
+// finding ranges.
+func ScanSecrets(content []byte, opts ...Option) ([]Finding, error) {
+	parseOptions := newParseOptions(opts)
+
+	file, diags := hclparse.NewParser().ParseHCL(content, parseOptions.ConfigPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	var findings []Finding
+	if attribute, found := body.Attributes["inputs"]; found {
+		findings = append(findings, scanInputsSecrets(content, attribute.Expr)...)
+	}
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "locals":
+			for name, attribute := range block.Body.Attributes {
+				findings = append(findings, scanExpressionSecrets(content, attribute.Expr, "locals."+name)...)
+			}
+		case "generate":
+			if attribute, found := block.Body.Attributes["contents"]; found && len(block.Labels) > 0 {
+				findings = append(findings, scanExpressionSecrets(content, attribute.Expr, "generate."+block.Labels[0]+".contents")...)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Range.Start.Byte < findings[j].Range.Start.Byte
+	})
+	return findings, nil
+}
+
+// scanInputsSecrets returns the findings for the literal strings of the inputs, reported by input when they are set
+// by an object literal.
+func scanInputsSecrets(content []byte, expr hclsyntax.Expression) []Finding {
+	object, isObject := expr.(*hclsyntax.ObjectConsExpr)
+	if !isObject {
+		return scanExpressionSecrets(content, expr, "inputs")
+	}
+
+	var findings []Finding
+	for _, item := range object.Items {
+		path := "inputs"
+		if key, diags := item.KeyExpr.Value(nil); !diags.HasErrors() && key.IsKnown() && !key.IsNull() && key.Type() == cty.String {
+			path += "." + key.AsString()
+		}
+		findings = append(findings, scanExpressionSecrets(content, item.KeyExpr, path)...)
+		findings = append(findings, scanExpressionSecrets(content, item.ValueExpr, path)...)
+	}
+	return findings
+}
+
+// scanExpressionSecrets returns the findings for the literal strings of the given expression, which sets the
+// attribute with the given path.
+func scanExpressionSecrets(content []byte, expr hclsyntax.Expression, path string) []Finding {
+	var findings []Finding
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		literal, isLiteral := node.(*hclsyntax.LiteralValueExpr)
+		if !isLiteral || literal.Val.Type() != cty.String || !literal.Val.IsKnown() || literal.Val.IsNull() {
+			return nil
+		}
+		findings = append(findings, scanLiteralSecrets(content, literal.Val.AsString(), literal.SrcRange, path)...)
+		return nil
+	})
+	return findings
+}
+
+func scanLiteralSecrets(content []byte, value string, valueRange hcl.Range, path string) []Finding {
+	var findings []Finding
+	for _, secretPattern := range secretPatterns {
+		for _, match := range secretPattern.pattern.FindAllString(value, -1) {
+			findings = append(findings, Finding{
+				RuleID:   RuleHardcodedSecret,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("%s holds what looks like a hardcoded %s (%s)", path, secretPattern.kind, maskSecret(match)),
+				Range:    substringRange(content, valueRange, match),
+			})
+		}
+	}
+	if len(findings) > 0 {
+		return findings
+	}
+
+	for _, candidate := range secretCandidate.FindAllString(value, -1) {
+		if !isMixedCase(candidate) || shannonEntropy(candidate) < minSecretEntropy {
+			continue
+		}
+		findings = append(findings, Finding{
+			RuleID:   RuleHardcodedSecret,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%s holds a random looking string that may be a hardcoded secret (%s)", path, maskSecret(candidate)),
+			Range:    substringRange(content, valueRange, candidate),
+		})
+	}
+	return findings
+}
+
+// isMixedCase returns whether the given string holds upper case letters, lower case letters and digits, as generated
+// secrets do, unlike identifiers and hex encoded hashes.
+func isMixedCase(value string) bool {
+	var upper, lower, digit bool
+	for _, char := range value {
+		upper = upper || unicode.IsUpper(char)
+		lower = lower || unicode.IsLower(char)
+		digit = digit || unicode.IsDigit(char)
+	}
+	return upper && lower && digit
+}
+
+// shannonEntropy returns the Shannon entropy of the given string, in bits per character.
+func shannonEntropy(value string) float64 {
+	counts := map[rune]int{}
+	length := 0
+	for _, char := range value {
+		counts[char]++
+		length++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		frequency := float64(count) / float64(length)
+		entropy -= frequency * math.Log2(frequency)
+	}
+	return entropy
+}
+
+// maskSecret returns the given secret with all but its first characters masked, to identify it in messages without
+// disclosing it.
+func maskSecret(secret string) string {
+	const visible = 4
+	if len(secret) <= visible {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:visible] + strings.Repeat("*", 8)
+}
+
+// substringRange returns the range of the given substring of the literal with the given range in the content, or the
+// range of the literal itself when the substring does not appear verbatim in its source (e.g. when it holds escape
+// sequences).
+func substringRange(content []byte, literalRange hcl.Range, substring string) hcl.Range {
+	if literalRange.End.Byte > len(content) {
+		return literalRange
+	}
+	offset := bytes.Index(content[literalRange.Start.Byte:literalRange.End.Byte], []byte(substring))
+	if offset < 0 {
+		return literalRange
+	}
+
+	start := advancePos(literalRange.Start, content[literalRange.Start.Byte:literalRange.Start.Byte+offset])
+	end := advancePos(start, []byte(substring))
+	return hcl.Range{Filename: literalRange.Filename, Start: start, End: end}
+}
+
+// advancePos returns the position following the given text, which starts at the given position.
+func advancePos(pos hcl.Pos, text []byte) hcl.Pos {
+	for len(text) > 0 {
+		char, size := utf8.DecodeRune(text)
+		if char == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+		pos.Byte += size
+		text = text[size:]
+	}
+	return pos
+}
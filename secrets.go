@@ -0,0 +1,264 @@
+package terragrunt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// SecretsProviders holds the clients backing the secret lookup functions. Each function is only made available when
+// its client is set, so that configurations can not reach secret stores unless the embedder explicitly allows it.
+type SecretsProviders struct {
+	// Vault backs the vault_kv(path, key) function.
+	Vault VaultKVReader
+
+	// SSM backs the ssm_parameter(name) function.
+	SSM SSMParameterReader
+
+	// SecretsManager backs the aws_secretsmanager(name, key) function.
+	SecretsManager SecretsManagerReader
+}
+
+// VaultKVReader reads the data of a secret from a Vault KV secrets engine.
+type VaultKVReader interface {
+	ReadKV(ctx context.Context, path string) (map[string]interface{}, error)
+}
+
+// SSMParameterReader reads the decrypted value of an AWS SSM parameter.
+type SSMParameterReader interface {
+	GetParameter(ctx context.Context, name string) (string, error)
+}
+
+// SecretsManagerReader reads the secret string of an AWS Secrets Manager secret.
+type SecretsManagerReader interface {
+	GetSecretValue(ctx context.Context, name string) (string, error)
+}
+
+// VaultClient is a VaultKVReader for the version 2 of the KV secrets engine, talking to the Vault HTTP API. Paths are
+// given as <mount>/<secret path>, e.g. secret/app/db.
+type VaultClient struct {
+	// Address is the address of the Vault server, e.g. https://vault.example.com:8200.
+	Address string
+	Token   string
+
+	// Namespace is the Vault enterprise namespace, if any.
+	Namespace string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (client VaultClient) ReadKV(ctx context.Context, path string) (map[string]interface{}, error) {
+	mount, secretPath, found := strings.Cut(strings.Trim(path, "/"), "/")
+	if !found {
+		return nil, fmt.Errorf("vault path %q must be of the form <mount>/<path>", path)
+	}
+
+	requestURL := strings.TrimSuffix(client.Address, "/") + "/v1/" + url.PathEscape(mount) + "/data/" + secretPath
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Vault-Token", client.Token)
+	if client.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", client.Namespace)
+	}
+
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s reading %s", response.Status, path)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+	return secret.Data.Data, nil
+}
+
+// SSMClient calls the AWS SSM API, signing requests with the given credentials. It is an SSMParameterReader.
+type SSMClient struct {
+	// Credentials default to EnvAWSCredentials.
+	Credentials AWSCredentialsProvider
+
+	// Region is the region of the parameters. Defaults to us-east-1.
+	Region string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+}
+
+// GetParameter reads the value of the given parameter with ssm:GetParameter, decrypting SecureString parameters.
+func (client SSMClient) GetParameter(ctx context.Context, name string) (string, error) {
+	input := struct {
+		Name           string `json:"Name"`
+		WithDecryption bool   `json:"WithDecryption"`
+	}{Name: name, WithDecryption: true}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	var output struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	err = client.Retry.Do(ctx, func() error {
+		return callAWSJSON(ctx, awsJSONRequest{
+			Service:     "ssm",
+			Region:      client.Region,
+			Target:      "AmazonSSM.GetParameter",
+			Credentials: client.Credentials,
+			HTTPClient:  client.HTTPClient,
+		}, body, &output)
+	})
+	return output.Parameter.Value, err
+}
+
+// SecretsManagerClient calls the AWS Secrets Manager API, signing requests with the given credentials. It is a
+// SecretsManagerReader.
+type SecretsManagerClient struct {
+	// Credentials default to EnvAWSCredentials.
+	Credentials AWSCredentialsProvider
+
+	// Region is the region of the secrets. Defaults to us-east-1.
+	Region string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+}
+
+// GetSecretValue reads the secret string of the current version of the given secret, by name or ARN, with
+// secretsmanager:GetSecretValue. Binary secrets are not supported.
+func (client SecretsManagerClient) GetSecretValue(ctx context.Context, name string) (string, error) {
+	input := struct {
+		SecretID string `json:"SecretId"`
+	}{SecretID: name}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	var output struct {
+		SecretString *string `json:"SecretString"`
+	}
+	err = client.Retry.Do(ctx, func() error {
+		return callAWSJSON(ctx, awsJSONRequest{
+			Service:     "secretsmanager",
+			Region:      client.Region,
+			Target:      "secretsmanager.GetSecretValue",
+			Credentials: client.Credentials,
+			HTTPClient:  client.HTTPClient,
+		}, body, &output)
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, only secret strings can be read", name)
+	}
+	return *output.SecretString, nil
+}
+
+// secretsFunctions returns the secret lookup functions backed by the configured providers.
+func secretsFunctions(opts *ParseOptions) map[string]function.Function {
+	functions := map[string]function.Function{}
+	providers := opts.SecretsProviders
+
+	if providers.Vault != nil {
+		functions["vault_kv"] = function.New(&function.Spec{
+			Params: []function.Parameter{
+				{Name: "path", Type: cty.String},
+				{Name: "key", Type: cty.String},
+			},
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				path, key := args[0].AsString(), args[1].AsString()
+				data, err := providers.Vault.ReadKV(opts.Context, path)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				value, found := data[key]
+				if !found {
+					return cty.NilVal, fmt.Errorf("vault secret %s has no key %q", path, key)
+				}
+				return cty.StringVal(fmt.Sprint(value)).Mark(SensitiveMark), nil
+			},
+		})
+	}
+
+	if providers.SSM != nil {
+		functions["ssm_parameter"] = function.New(&function.Spec{
+			Params: []function.Parameter{
+				{Name: "name", Type: cty.String},
+			},
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				value, err := providers.SSM.GetParameter(opts.Context, args[0].AsString())
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(value).Mark(SensitiveMark), nil
+			},
+		})
+	}
+
+	if providers.SecretsManager != nil {
+		functions["aws_secretsmanager"] = function.New(&function.Spec{
+			Params: []function.Parameter{
+				{Name: "name", Type: cty.String},
+				{Name: "key", Type: cty.String},
+			},
+			Type: function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+				name, key := args[0].AsString(), args[1].AsString()
+				secret, err := providers.SecretsManager.GetSecretValue(opts.Context, name)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				if key == "" {
+					return cty.StringVal(secret).Mark(SensitiveMark), nil
+				}
+
+				// Secrets holding several keys are stored as a json object.
+				var data map[string]interface{}
+				if err := json.Unmarshal([]byte(secret), &data); err != nil {
+					return cty.NilVal, fmt.Errorf("secret %s is not a json object, so key %q can not be read from it", name, key)
+				}
+				value, found := data[key]
+				if !found {
+					return cty.NilVal, fmt.Errorf("secret %s has no key %q", name, key)
+				}
+				return cty.StringVal(fmt.Sprint(value)).Mark(SensitiveMark), nil
+			},
+		})
+	}
+
+	return functions
+}
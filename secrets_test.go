@@ -0,0 +1,213 @@
+package terragrunt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestSecretsFunctionsOptIn(t *testing.T) {
+	for _, src := range []string{`vault_kv("secret/app", "password")`, `ssm_parameter("/app/password")`, `aws_secretsmanager("app", "password")`} {
+		name, _, _ := strings.Cut(src, "(")
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(`inputs = { secret = ` + src + ` }`))
+			if want := `There is no function named "` + name + `"`; err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestVaultKV(t *testing.T) {
+	var token, namespace string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, namespace = r.Header.Get("X-Vault-Token"), r.Header.Get("X-Vault-Namespace")
+		if r.URL.Path != "/v1/secret/data/app/db" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"errors": ["permission denied"]}`)
+			return
+		}
+		io.WriteString(w, `{"data": {"data": {"password": "hunter2", "port": 5432}, "metadata": {"version": 3}}}`)
+	}))
+	defer server.Close()
+	providers := SecretsProviders{Vault: VaultClient{Address: server.URL + "/", Token: "s.token", Namespace: "team"}}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{name: "string key", src: `vault_kv("secret/app/db", "password")`, want: "hunter2"},
+		{name: "number key", src: `vault_kv("/secret/app/db/", "port")`, want: "5432"},
+		{name: "missing key", src: `vault_kv("secret/app/db", "user")`, err: `vault secret secret/app/db has no key "user"`},
+		{name: "forbidden", src: `vault_kv("secret/app/other", "password")`, err: "vault returned 403 Forbidden reading secret/app/other"},
+		{name: "path without mount", src: `vault_kv("secret", "password")`, err: `vault path "secret" must be of the form <mount>/<path>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(`inputs = { secret = `+test.src+` }`), WithSecretsProviders(providers))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != "s.token" || namespace != "team" {
+				t.Errorf("got token %q and namespace %q, want s.token and team", token, namespace)
+			}
+			secret := config.InputsCty["secret"]
+			if !secret.HasMark(SensitiveMark) {
+				t.Errorf("got %#v, want a sensitive value", secret)
+			}
+			if secret, _ := secret.Unmark(); !secret.RawEquals(cty.StringVal(test.want)) {
+				t.Errorf("got %#v, want %q", secret, test.want)
+			}
+		})
+	}
+}
+
+func TestAWSSecretsFunctions(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		status int
+		body   string
+
+		// target, service and request are the expected X-Amz-Target header, signing service and request body.
+		target  string
+		service string
+		request string
+
+		want string
+		err  string
+	}{
+		{
+			name:    "ssm parameter",
+			src:     `ssm_parameter("/app/db/password")`,
+			body:    `{"Parameter": {"Name": "/app/db/password", "Type": "SecureString", "Value": "hunter2", "Version": 2}}`,
+			target:  "AmazonSSM.GetParameter",
+			service: "ssm",
+			request: `{"Name":"/app/db/password","WithDecryption":true}`,
+			want:    "hunter2",
+		},
+		{
+			name:    "missing ssm parameter",
+			src:     `ssm_parameter("/app/db/user")`,
+			status:  http.StatusBadRequest,
+			body:    `{"__type": "ParameterNotFound"}`,
+			target:  "AmazonSSM.GetParameter",
+			service: "ssm",
+			request: `{"Name":"/app/db/user","WithDecryption":true}`,
+			err:     `ssm GetParameter: POST https://ssm.eu-west-1.amazonaws.com/ returned 400 Bad Request: {"__type": "ParameterNotFound"}`,
+		},
+		{
+			name:    "secret string",
+			src:     `aws_secretsmanager("app/db", "")`,
+			body:    `{"Name": "app/db", "SecretString": "{\"password\": \"hunter2\", \"port\": 5432}"}`,
+			target:  "secretsmanager.GetSecretValue",
+			service: "secretsmanager",
+			request: `{"SecretId":"app/db"}`,
+			want:    `{"password": "hunter2", "port": 5432}`,
+		},
+		{
+			name:    "secret key",
+			src:     `aws_secretsmanager("app/db", "port")`,
+			body:    `{"Name": "app/db", "SecretString": "{\"password\": \"hunter2\", \"port\": 5432}"}`,
+			target:  "secretsmanager.GetSecretValue",
+			service: "secretsmanager",
+			request: `{"SecretId":"app/db"}`,
+			want:    "5432",
+		},
+		{
+			name:    "missing secret key",
+			src:     `aws_secretsmanager("app/db", "user")`,
+			body:    `{"Name": "app/db", "SecretString": "{\"password\": \"hunter2\"}"}`,
+			target:  "secretsmanager.GetSecretValue",
+			service: "secretsmanager",
+			request: `{"SecretId":"app/db"}`,
+			err:     `secret app/db has no key "user"`,
+		},
+		{
+			name:    "key of a plain secret",
+			src:     `aws_secretsmanager("app/token", "value")`,
+			body:    `{"Name": "app/token", "SecretString": "hunter2"}`,
+			target:  "secretsmanager.GetSecretValue",
+			service: "secretsmanager",
+			request: `{"SecretId":"app/token"}`,
+			err:     `secret app/token is not a json object, so key "value" can not be read from it`,
+		},
+		{
+			name:    "binary secret",
+			src:     `aws_secretsmanager("app/key", "")`,
+			body:    `{"Name": "app/key", "SecretBinary": "aHVudGVyMg=="}`,
+			target:  "secretsmanager.GetSecretValue",
+			service: "secretsmanager",
+			request: `{"SecretId":"app/key"}`,
+			err:     "secret app/key is binary, only secret strings can be read",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var target, authorization, token, date, request string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				target, authorization, token, date = r.Header.Get("X-Amz-Target"), r.Header.Get("Authorization"), r.Header.Get("X-Amz-Security-Token"), r.Header.Get("X-Amz-Date")
+				body, _ := io.ReadAll(r.Body)
+				request = string(body)
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+				}
+				io.WriteString(w, test.body)
+			}))
+			defer server.Close()
+			serverURL, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			httpClient := &http.Client{Transport: redirectTransport{target: serverURL}}
+			credentials := StaticAWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+			providers := SecretsProviders{
+				SSM:            SSMClient{Credentials: credentials, Region: "eu-west-1", HTTPClient: httpClient},
+				SecretsManager: SecretsManagerClient{Credentials: credentials, Region: "eu-west-1", HTTPClient: httpClient},
+			}
+			config, err := ParseConfig([]byte(`inputs = { secret = `+test.src+` }`), WithSecretsProviders(providers))
+
+			if target != test.target || request != test.request {
+				t.Errorf("got request %s %s, want %s %s", target, request, test.target, test.request)
+			}
+			wantAuthorization := "AWS4-HMAC-SHA256 Credential=AKID/" + date[:min(len(date), 8)] + "/eu-west-1/" + test.service +
+				"/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature="
+			if !strings.HasPrefix(authorization, wantAuthorization) || len(authorization) != len(wantAuthorization)+64 || token != "session" {
+				t.Errorf("got authorization %q and token %q, want %q followed by the signature and session", authorization, token, wantAuthorization)
+			}
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret := config.InputsCty["secret"]
+			if !secret.HasMark(SensitiveMark) {
+				t.Errorf("got %#v, want a sensitive value", secret)
+			}
+			if secret, _ := secret.Unmark(); !secret.RawEquals(cty.StringVal(test.want)) {
+				t.Errorf("got %#v, want %q", secret, test.want)
+			}
+		})
+	}
+}
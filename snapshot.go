@@ -0,0 +1,639 @@
+package terragrunt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// SnapshotVersion is the version of the document written by Stack.Snapshot. It is increased on incompatible changes
+// of the document, which LoadSnapshot then refuses to load.
+const SnapshotVersion = 1
+
+// stackSnapshot is the json representation of a Stack.
+type stackSnapshot struct {
+	Version int            `json:"version"`
+	Root    string         `json:"root"`
+	Units   []unitSnapshot `json:"units"`
+
+	// Graph maps the path of each unit to the paths of the units it depends on. It is derived from the units, and
+	// only written for consumers of the document.
+	Graph map[string][]string `json:"graph"`
+}
+
+type unitSnapshot struct {
+	Path             string              `json:"path"`
+	ConfigPath       string              `json:"config_path"`
+	Error            string              `json:"error,omitempty"`
+	Dependencies     []string            `json:"dependencies,omitempty"`
+	DependencyBlocks map[string]string   `json:"dependency_blocks,omitempty"`
+	OutputReferences map[string][]string `json:"output_references"`
+	Files            []string            `json:"files,omitempty"`
+	StackUnit        *stackUnitSnapshot  `json:"stack_unit,omitempty"`
+	MatrixKey        string              `json:"matrix_key,omitempty"`
+	Config           *configSnapshot     `json:"config,omitempty"`
+}
+
+type stackUnitSnapshot struct {
+	Name      string         `json:"name"`
+	StackFile string         `json:"stack_file"`
+	Source    string         `json:"source"`
+	Path      string         `json:"path"`
+	Values    *valueSnapshot `json:"values,omitempty"`
+}
+
+type configSnapshot struct {
+	TerraformSource *string                     `json:"terraform_source,omitempty"`
+	ExtraArguments  []extraArgumentsSnapshot    `json:"terraform_extra_arguments,omitempty"`
+	TerraformBinary string                      `json:"terraform_binary,omitempty"`
+	Skip            bool                        `json:"skip,omitempty"`
+	PreventDestroy  bool                        `json:"prevent_destroy,omitempty"`
+	IamRole         string                      `json:"iam_role,omitempty"`
+	IamRoleDuration int64                       `json:"iam_assume_role_duration,omitempty"`
+	IamRoleSession  string                      `json:"iam_assume_role_session_name,omitempty"`
+	RemoteState     *remoteStateSnapshot        `json:"remote_state,omitempty"`
+	Dependencies    []dependencySnapshot        `json:"dependencies,omitempty"`
+	GenerateConfigs map[string]generateSnapshot `json:"generate,omitempty"`
+	FeatureFlags    map[string]valueSnapshot    `json:"feature,omitempty"`
+	Errors          *errorsSnapshot             `json:"errors,omitempty"`
+	Engine          *engineSnapshot             `json:"engine,omitempty"`
+	Exclude         *excludeSnapshot            `json:"exclude,omitempty"`
+	Catalog         *catalogSnapshot            `json:"catalog,omitempty"`
+	Inputs          map[string]valueSnapshot    `json:"inputs,omitempty"`
+	Provenance      map[string]rangeSnapshot    `json:"provenance,omitempty"`
+}
+
+type extraArgumentsSnapshot struct {
+	Name             string             `json:"name"`
+	Commands         []string           `json:"commands"`
+	Arguments        *[]string          `json:"arguments,omitempty"`
+	RequiredVarFiles *[]string          `json:"required_var_files,omitempty"`
+	OptionalVarFiles *[]string          `json:"optional_var_files,omitempty"`
+	EnvVars          *map[string]string `json:"env_vars,omitempty"`
+}
+
+type remoteStateSnapshot struct {
+	Backend                       string               `json:"backend"`
+	DisableInit                   *bool                `json:"disable_init,omitempty"`
+	DisableDependencyOptimization *bool                `json:"disable_dependency_optimization,omitempty"`
+	Generate                      *RemoteStateGenerate `json:"generate,omitempty"`
+	Config                        *valueSnapshot       `json:"config,omitempty"`
+	Encryption                    *valueSnapshot       `json:"encryption,omitempty"`
+}
+
+type dependencySnapshot struct {
+	Name                                string         `json:"name"`
+	ConfigPath                          string         `json:"config_path"`
+	Enabled                             *bool          `json:"enabled,omitempty"`
+	SkipOutputs                         *bool          `json:"skip_outputs,omitempty"`
+	MockOutputs                         *valueSnapshot `json:"mock_outputs,omitempty"`
+	MockOutputsAllowedTerraformCommands *[]string      `json:"mock_outputs_allowed_terraform_commands,omitempty"`
+	MockOutputsMergeWithState           *bool          `json:"mock_outputs_merge_with_state,omitempty"`
+	MockOutputsMergeStrategyWithState   *string        `json:"mock_outputs_merge_strategy_with_state,omitempty"`
+	Outputs                             *valueSnapshot `json:"outputs,omitempty"`
+}
+
+type generateSnapshot struct {
+	Path             string  `json:"path"`
+	IfExists         string  `json:"if_exists"`
+	IfDisabled       *string `json:"if_disabled,omitempty"`
+	CommentPrefix    *string `json:"comment_prefix,omitempty"`
+	DisableSignature *bool   `json:"disable_signature,omitempty"`
+	Contents         string  `json:"contents"`
+	Disable          *bool   `json:"disable,omitempty"`
+}
+
+type errorsSnapshot struct {
+	Retry  []errorsRetrySnapshot  `json:"retry,omitempty"`
+	Ignore []errorsIgnoreSnapshot `json:"ignore,omitempty"`
+}
+
+type errorsRetrySnapshot struct {
+	Label            string   `json:"label"`
+	RetryableErrors  []string `json:"retryable_errors"`
+	MaxAttempts      int      `json:"max_attempts"`
+	SleepIntervalSec int      `json:"sleep_interval_sec"`
+}
+
+type errorsIgnoreSnapshot struct {
+	Label           string         `json:"label"`
+	IgnorableErrors []string       `json:"ignorable_errors"`
+	Message         *string        `json:"message,omitempty"`
+	Signals         *valueSnapshot `json:"signals,omitempty"`
+}
+
+type engineSnapshot struct {
+	Source  string         `json:"source"`
+	Version *string        `json:"version,omitempty"`
+	Type    *string        `json:"type,omitempty"`
+	Meta    *valueSnapshot `json:"meta,omitempty"`
+}
+
+type excludeSnapshot struct {
+	If                  bool     `json:"if"`
+	Actions             []string `json:"actions"`
+	ExcludeDependencies *bool    `json:"exclude_dependencies,omitempty"`
+}
+
+type catalogSnapshot struct {
+	URLs            []string `json:"urls"`
+	DefaultTemplate *string  `json:"default_template,omitempty"`
+}
+
+type rangeSnapshot struct {
+	File  string  `json:"file"`
+	Start jsonPos `json:"start"`
+	End   jsonPos `json:"end"`
+}
+
+// valueSnapshot is a cty value along with its type, so that it is loaded back with the same type.
+type valueSnapshot struct {
+	Type  json.RawMessage `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Snapshot serializes the units of the stack, with their resolved configuration and the dependency graph, to a
+// single versioned json document, which LoadSnapshot loads back. The document allows analyzing a stack offline, or
+// parsing it once and sharing the result between the stages of a pipeline.
+//
+// Values keep their type, but sensitive values are redacted and unknown values are replaced by nulls, as by
+// RenderJSON. The unknown blocks of the configurations are not part of the snapshot.
+func (stack *Stack) Snapshot() ([]byte, error) {
+	snapshot := stackSnapshot{
+		Version: SnapshotVersion,
+		Root:    stack.Root,
+		Units:   []unitSnapshot{},
+		Graph:   map[string][]string{},
+	}
+
+	for _, unit := range stack.Units {
+		unitSnap, err := newUnitSnapshot(unit)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", unit.ConfigPath, err)
+		}
+		snapshot.Units = append(snapshot.Units, unitSnap)
+	}
+
+	graph := stack.Graph()
+	for _, path := range graph.Paths() {
+		snapshot.Graph[path] = append([]string{}, graph.Dependencies(path)...)
+	}
+
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// LoadSnapshot loads a stack from the document written by Stack.Snapshot. The errors of the units are loaded back
+// as plain errors, holding their message only.
+func LoadSnapshot(data []byte) (*Stack, error) {
+	var snapshot stackSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, SnapshotVersion)
+	}
+
+	stack := &Stack{Root: snapshot.Root}
+	for _, unitSnap := range snapshot.Units {
+		unit, err := unitSnap.unit()
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot of %s: %w", unitSnap.ConfigPath, err)
+		}
+		stack.Units = append(stack.Units, unit)
+	}
+	sort.Slice(stack.Units, func(i, j int) bool {
+		return stack.Units[i].Path < stack.Units[j].Path
+	})
+	return stack, nil
+}
+
+func newUnitSnapshot(unit *Unit) (unitSnapshot, error) {
+	snapshot := unitSnapshot{
+		Path:             unit.Path,
+		ConfigPath:       unit.ConfigPath,
+		Dependencies:     unit.Dependencies,
+		DependencyBlocks: unit.dependencyBlocks,
+		Files:            unit.Files,
+		MatrixKey:        unit.MatrixKey,
+	}
+	if unit.Err != nil {
+		snapshot.Error = unit.Err.Error()
+	}
+
+	if unit.outputReferences != nil {
+		snapshot.OutputReferences = map[string][]string{}
+		for name, keys := range unit.outputReferences {
+			snapshot.OutputReferences[name] = nil
+			for key := range keys {
+				snapshot.OutputReferences[name] = append(snapshot.OutputReferences[name], key)
+			}
+			sort.Strings(snapshot.OutputReferences[name])
+		}
+	}
+
+	if stackUnit := unit.StackUnit; stackUnit != nil {
+		snapshot.StackUnit = &stackUnitSnapshot{Name: stackUnit.Name, StackFile: stackUnit.StackFile, Source: stackUnit.Source, Path: stackUnit.Path}
+		if stackUnit.Values.Type() != cty.NilType {
+			values, err := newValueSnapshot(stackUnit.Values)
+			if err != nil {
+				return unitSnapshot{}, err
+			}
+			snapshot.StackUnit.Values = &values
+		}
+	}
+
+	if unit.Config != nil {
+		config, err := newConfigSnapshot(unit.Config)
+		if err != nil {
+			return unitSnapshot{}, err
+		}
+		snapshot.Config = config
+	}
+	return snapshot, nil
+}
+
+func (snapshot unitSnapshot) unit() (*Unit, error) {
+	unit := &Unit{
+		Path:             snapshot.Path,
+		ConfigPath:       snapshot.ConfigPath,
+		Dependencies:     snapshot.Dependencies,
+		Files:            snapshot.Files,
+		MatrixKey:        snapshot.MatrixKey,
+		dependencyBlocks: snapshot.DependencyBlocks,
+	}
+	if snapshot.Error != "" {
+		unit.Err = errors.New(snapshot.Error)
+	}
+
+	if snapshot.OutputReferences != nil {
+		unit.outputReferences = dependencyReferences{}
+		for name, keys := range snapshot.OutputReferences {
+			unit.outputReferences[name] = nil
+			if keys != nil {
+				unit.outputReferences[name] = map[string]bool{}
+				for _, key := range keys {
+					unit.outputReferences[name][key] = true
+				}
+			}
+		}
+	}
+
+	if snapshot.StackUnit != nil {
+		unit.StackUnit = &StackUnit{
+			Name:      snapshot.StackUnit.Name,
+			StackFile: snapshot.StackUnit.StackFile,
+			Source:    snapshot.StackUnit.Source,
+			Path:      snapshot.StackUnit.Path,
+			Values:    cty.NilVal,
+		}
+		if snapshot.StackUnit.Values != nil {
+			values, err := snapshot.StackUnit.Values.value()
+			if err != nil {
+				return nil, err
+			}
+			unit.StackUnit.Values = values
+		}
+	}
+
+	if snapshot.Config != nil {
+		config, err := snapshot.Config.config()
+		if err != nil {
+			return nil, err
+		}
+		unit.Config = config
+	}
+	return unit, nil
+}
+
+func newConfigSnapshot(config *TerragruntConfig) (*configSnapshot, error) {
+	snapshot := &configSnapshot{
+		TerraformBinary: config.TerraformBinary,
+		Skip:            config.Skip,
+		PreventDestroy:  config.PreventDestroy,
+		IamRole:         config.IamRole,
+		IamRoleDuration: config.IamAssumeRoleDuration,
+		IamRoleSession:  config.IamAssumeRoleSessionName,
+	}
+	if config.Terraform != nil {
+		snapshot.TerraformSource = config.Terraform.Source
+		for _, extraArguments := range config.Terraform.ExtraArguments {
+			snapshot.ExtraArguments = append(snapshot.ExtraArguments, extraArgumentsSnapshot(extraArguments))
+		}
+	}
+	if len(config.provenance) > 0 {
+		snapshot.Provenance = map[string]rangeSnapshot{}
+		for path, origin := range config.provenance {
+			snapshot.Provenance[path] = rangeSnapshot{
+				File:  origin.Filename,
+				Start: jsonPos{Line: origin.Start.Line, Column: origin.Start.Column, Byte: origin.Start.Byte},
+				End:   jsonPos{Line: origin.End.Line, Column: origin.End.Column, Byte: origin.End.Byte},
+			}
+		}
+	}
+
+	if remoteState := config.RemoteState; remoteState != nil {
+		snapshot.RemoteState = &remoteStateSnapshot{
+			Backend:                       remoteState.Backend,
+			DisableInit:                   remoteState.DisableInit,
+			DisableDependencyOptimization: remoteState.DisableDependencyOptimization,
+			Generate:                      remoteState.Generate,
+		}
+		if remoteState.Config != cty.NilVal {
+			value, err := newValueSnapshot(remoteState.Config)
+			if err != nil {
+				return nil, fmt.Errorf("remote_state config: %w", err)
+			}
+			snapshot.RemoteState.Config = &value
+		}
+		if remoteState.Encryption != nil {
+			value, err := newValueSnapshot(*remoteState.Encryption)
+			if err != nil {
+				return nil, fmt.Errorf("remote_state encryption: %w", err)
+			}
+			snapshot.RemoteState.Encryption = &value
+		}
+	}
+
+	for _, dependency := range config.TerragruntDependencies {
+		dependencySnap := dependencySnapshot{
+			Name:                                dependency.Name,
+			ConfigPath:                          dependency.ConfigPath,
+			Enabled:                             dependency.Enabled,
+			SkipOutputs:                         dependency.SkipOutputs,
+			MockOutputsAllowedTerraformCommands: dependency.MockOutputsAllowedTerraformCommands,
+			MockOutputsMergeWithState:           dependency.MockOutputsMergeWithState,
+			MockOutputsMergeStrategyWithState:   dependency.MockOutputsMergeStrategyWithState,
+		}
+		var err error
+		if dependencySnap.MockOutputs, err = newOptionalValueSnapshot(dependency.MockOutputs); err != nil {
+			return nil, fmt.Errorf("dependency %s mock_outputs: %w", dependency.Name, err)
+		}
+		if dependencySnap.Outputs, err = newOptionalValueSnapshot(dependency.RenderedOutputs); err != nil {
+			return nil, fmt.Errorf("dependency %s outputs: %w", dependency.Name, err)
+		}
+		snapshot.Dependencies = append(snapshot.Dependencies, dependencySnap)
+	}
+
+	if len(config.GenerateConfigs) > 0 {
+		snapshot.GenerateConfigs = map[string]generateSnapshot{}
+		for name, generate := range config.GenerateConfigs {
+			snapshot.GenerateConfigs[name] = generateSnapshot{
+				Path:             generate.Path,
+				IfExists:         generate.IfExists,
+				IfDisabled:       generate.IfDisabled,
+				CommentPrefix:    generate.CommentPrefix,
+				DisableSignature: generate.DisableSignature,
+				Contents:         generate.Contents,
+				Disable:          generate.Disable,
+			}
+		}
+	}
+
+	if config.Errors != nil {
+		snapshot.Errors = &errorsSnapshot{}
+		for _, retry := range config.Errors.Retry {
+			snapshot.Errors.Retry = append(snapshot.Errors.Retry, errorsRetrySnapshot(retry))
+		}
+		for _, ignore := range config.Errors.Ignore {
+			ignoreSnap := errorsIgnoreSnapshot{Label: ignore.Label, IgnorableErrors: ignore.IgnorableErrors, Message: ignore.Message}
+			var err error
+			if ignoreSnap.Signals, err = newOptionalValueSnapshot(ignore.Signals); err != nil {
+				return nil, fmt.Errorf("errors ignore %s signals: %w", ignore.Label, err)
+			}
+			snapshot.Errors.Ignore = append(snapshot.Errors.Ignore, ignoreSnap)
+		}
+	}
+
+	if engine := config.Engine; engine != nil {
+		snapshot.Engine = &engineSnapshot{Source: engine.Source, Version: engine.Version, Type: engine.Type}
+		var err error
+		if snapshot.Engine.Meta, err = newOptionalValueSnapshot(engine.Meta); err != nil {
+			return nil, fmt.Errorf("engine meta: %w", err)
+		}
+	}
+	if config.Exclude != nil {
+		exclude := excludeSnapshot(*config.Exclude)
+		snapshot.Exclude = &exclude
+	}
+	if config.Catalog != nil {
+		catalog := catalogSnapshot(*config.Catalog)
+		snapshot.Catalog = &catalog
+	}
+
+	if len(config.FeatureFlags) > 0 {
+		snapshot.FeatureFlags = map[string]valueSnapshot{}
+		for name, value := range config.FeatureFlags {
+			flagSnap, err := newValueSnapshot(value)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %w", name, err)
+			}
+			snapshot.FeatureFlags[name] = flagSnap
+		}
+	}
+
+	if len(config.InputsCty) > 0 {
+		snapshot.Inputs = map[string]valueSnapshot{}
+		for name, value := range config.InputsCty {
+			inputSnap, err := newValueSnapshot(value)
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", name, err)
+			}
+			snapshot.Inputs[name] = inputSnap
+		}
+	}
+	return snapshot, nil
+}
+
+func (snapshot *configSnapshot) config() (*TerragruntConfig, error) {
+	config := &TerragruntConfig{
+		TerraformBinary: snapshot.TerraformBinary,
+		Skip:            snapshot.Skip,
+		PreventDestroy:  snapshot.PreventDestroy,
+
+		IamRole:                  snapshot.IamRole,
+		IamAssumeRoleDuration:    snapshot.IamRoleDuration,
+		IamAssumeRoleSessionName: snapshot.IamRoleSession,
+	}
+	if snapshot.TerraformSource != nil || len(snapshot.ExtraArguments) > 0 {
+		config.Terraform = &TerraformConfig{Source: snapshot.TerraformSource}
+		for _, extraArguments := range snapshot.ExtraArguments {
+			config.Terraform.ExtraArguments = append(config.Terraform.ExtraArguments, TerraformExtraArguments(extraArguments))
+		}
+	}
+	if len(snapshot.Provenance) > 0 {
+		config.provenance = map[string]hcl.Range{}
+		for path, origin := range snapshot.Provenance {
+			config.provenance[path] = hcl.Range{
+				Filename: origin.File,
+				Start:    hcl.Pos{Line: origin.Start.Line, Column: origin.Start.Column, Byte: origin.Start.Byte},
+				End:      hcl.Pos{Line: origin.End.Line, Column: origin.End.Column, Byte: origin.End.Byte},
+			}
+		}
+	}
+
+	if remoteStateSnap := snapshot.RemoteState; remoteStateSnap != nil {
+		config.RemoteState = &RemoteState{
+			Backend:                       remoteStateSnap.Backend,
+			DisableInit:                   remoteStateSnap.DisableInit,
+			DisableDependencyOptimization: remoteStateSnap.DisableDependencyOptimization,
+			Generate:                      remoteStateSnap.Generate,
+		}
+		if remoteStateSnap.Config != nil {
+			value, err := remoteStateSnap.Config.value()
+			if err != nil {
+				return nil, fmt.Errorf("remote_state config: %w", err)
+			}
+			config.RemoteState.Config = value
+		}
+		if remoteStateSnap.Encryption != nil {
+			value, err := remoteStateSnap.Encryption.value()
+			if err != nil {
+				return nil, fmt.Errorf("remote_state encryption: %w", err)
+			}
+			config.RemoteState.Encryption = &value
+		}
+	}
+
+	for _, dependencySnap := range snapshot.Dependencies {
+		dependency := Dependency{
+			Name:                                dependencySnap.Name,
+			ConfigPath:                          dependencySnap.ConfigPath,
+			Enabled:                             dependencySnap.Enabled,
+			SkipOutputs:                         dependencySnap.SkipOutputs,
+			MockOutputsAllowedTerraformCommands: dependencySnap.MockOutputsAllowedTerraformCommands,
+			MockOutputsMergeWithState:           dependencySnap.MockOutputsMergeWithState,
+			MockOutputsMergeStrategyWithState:   dependencySnap.MockOutputsMergeStrategyWithState,
+		}
+		var err error
+		if dependency.MockOutputs, err = dependencySnap.MockOutputs.optionalValue(); err != nil {
+			return nil, fmt.Errorf("dependency %s mock_outputs: %w", dependency.Name, err)
+		}
+		if dependency.RenderedOutputs, err = dependencySnap.Outputs.optionalValue(); err != nil {
+			return nil, fmt.Errorf("dependency %s outputs: %w", dependency.Name, err)
+		}
+		config.TerragruntDependencies = append(config.TerragruntDependencies, dependency)
+	}
+
+	if len(snapshot.GenerateConfigs) > 0 {
+		config.GenerateConfigs = map[string]GenerateConfig{}
+		for name, generateSnap := range snapshot.GenerateConfigs {
+			config.GenerateConfigs[name] = GenerateConfig{
+				Name:             name,
+				Path:             generateSnap.Path,
+				IfExists:         generateSnap.IfExists,
+				IfDisabled:       generateSnap.IfDisabled,
+				CommentPrefix:    generateSnap.CommentPrefix,
+				DisableSignature: generateSnap.DisableSignature,
+				Contents:         generateSnap.Contents,
+				Disable:          generateSnap.Disable,
+			}
+		}
+	}
+
+	if snapshot.Errors != nil {
+		config.Errors = &ErrorsConfig{}
+		for _, retrySnap := range snapshot.Errors.Retry {
+			config.Errors.Retry = append(config.Errors.Retry, ErrorsRetry(retrySnap))
+		}
+		for _, ignoreSnap := range snapshot.Errors.Ignore {
+			ignore := ErrorsIgnore{Label: ignoreSnap.Label, IgnorableErrors: ignoreSnap.IgnorableErrors, Message: ignoreSnap.Message}
+			var err error
+			if ignore.Signals, err = ignoreSnap.Signals.optionalValue(); err != nil {
+				return nil, fmt.Errorf("errors ignore %s signals: %w", ignore.Label, err)
+			}
+			config.Errors.Ignore = append(config.Errors.Ignore, ignore)
+		}
+	}
+
+	if engineSnap := snapshot.Engine; engineSnap != nil {
+		config.Engine = &EngineConfig{Source: engineSnap.Source, Version: engineSnap.Version, Type: engineSnap.Type}
+		var err error
+		if config.Engine.Meta, err = engineSnap.Meta.optionalValue(); err != nil {
+			return nil, fmt.Errorf("engine meta: %w", err)
+		}
+	}
+	if snapshot.Exclude != nil {
+		exclude := ExcludeConfig(*snapshot.Exclude)
+		config.Exclude = &exclude
+	}
+	if snapshot.Catalog != nil {
+		catalog := CatalogConfig(*snapshot.Catalog)
+		config.Catalog = &catalog
+	}
+
+	if len(snapshot.FeatureFlags) > 0 {
+		config.FeatureFlags = map[string]cty.Value{}
+		for name, flagSnap := range snapshot.FeatureFlags {
+			value, err := flagSnap.value()
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %w", name, err)
+			}
+			config.FeatureFlags[name] = value
+		}
+	}
+
+	if len(snapshot.Inputs) > 0 {
+		config.InputsCty = map[string]cty.Value{}
+		for name, inputSnap := range snapshot.Inputs {
+			value, err := inputSnap.value()
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", name, err)
+			}
+			config.InputsCty[name] = value
+		}
+		inputs, err := parseCtyValueToMap(cty.ObjectVal(config.InputsCty))
+		if err != nil {
+			return nil, err
+		}
+		config.Inputs = inputs
+	}
+	return config, nil
+}
+
+func newValueSnapshot(value cty.Value) (valueSnapshot, error) {
+	value = renderableValue(value)
+	valueType, err := ctyjson.MarshalType(value.Type())
+	if err != nil {
+		return valueSnapshot{}, err
+	}
+	valueJSON, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return valueSnapshot{}, err
+	}
+	return valueSnapshot{Type: valueType, Value: valueJSON}, nil
+}
+
+func newOptionalValueSnapshot(value *cty.Value) (*valueSnapshot, error) {
+	if value == nil {
+		return nil, nil
+	}
+	snapshot, err := newValueSnapshot(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (snapshot valueSnapshot) value() (cty.Value, error) {
+	valueType, err := ctyjson.UnmarshalType(snapshot.Type)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(snapshot.Value, valueType)
+}
+
+func (snapshot *valueSnapshot) optionalValue() (*cty.Value, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	value, err := snapshot.value()
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
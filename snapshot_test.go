@@ -0,0 +1,114 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"vpc/terragrunt.hcl": "terraform {\n  source = \"git::https://example.com/modules.git//vpc\"\n}\n" +
+			"remote_state {\n  backend = \"s3\"\n  config = { bucket = \"state\" }\n}\n" +
+			"inputs = { cidr = \"10.0.0.0/16\", zones = 3 }\n",
+		"app/terragrunt.hcl":    "dependency \"vpc\" {\n  config_path = \"../vpc\"\n  mock_outputs = { vpc_id = \"mock\" }\n}\ninputs = { vpc_id = dependency.vpc.outputs.vpc_id }\n",
+		"broken/terragrunt.hcl": "inputs = {\n",
+	})
+	stack, err := ParseStack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := stack.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := loaded.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(snapshot) {
+		t.Errorf("the snapshot of the loaded stack differs:\n%s\nwant:\n%s", again, snapshot)
+	}
+
+	units := map[string]*Unit{}
+	for _, unit := range loaded.Units {
+		units[filepath.Base(unit.Path)] = unit
+	}
+	if len(units) != 3 {
+		t.Fatalf("got %d units, want 3", len(units))
+	}
+
+	app := units["app"]
+	if want := []string{filepath.Join(dir, "vpc")}; !reflect.DeepEqual(app.Dependencies, want) {
+		t.Errorf("got dependencies %q, want %q", app.Dependencies, want)
+	}
+	if want := map[string]string{"vpc": filepath.Join(dir, "vpc")}; !reflect.DeepEqual(app.DependencyPaths(), want) {
+		t.Errorf("got dependency paths %q, want %q", app.DependencyPaths(), want)
+	}
+	if vpcID := app.Config.InputsCty["vpc_id"]; !vpcID.RawEquals(cty.StringVal("mock")) {
+		t.Errorf("got vpc_id %#v, want mock", vpcID)
+	}
+
+	vpc := units["vpc"]
+	if source := vpc.Config.Terraform.Source; source == nil || *source != "git::https://example.com/modules.git//vpc" {
+		t.Errorf("got source %v, want the source of the module", source)
+	}
+	if backend := vpc.Config.RemoteState.Backend; backend != "s3" {
+		t.Errorf("got backend %q, want s3", backend)
+	}
+	if zones := vpc.Config.InputsCty["zones"]; !zones.Equals(cty.NumberIntVal(3)).True() {
+		t.Errorf("got zones %#v, want 3", zones)
+	}
+
+	broken := units["broken"]
+	if broken.Err == nil || broken.Config != nil {
+		t.Fatalf("got config %v and error %v, want the parse error only", broken.Config, broken.Err)
+	}
+	for _, unit := range stack.Units {
+		if filepath.Base(unit.Path) == "broken" && unit.Err.Error() != broken.Err.Error() {
+			t.Errorf("got error %q, want %q", broken.Err, unit.Err)
+		}
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+		err      string
+	}{
+		{
+			name:     "invalid json",
+			snapshot: `{"version": 1,`,
+			err:      "invalid snapshot",
+		},
+		{
+			name:     "unsupported version",
+			snapshot: `{"version": 2, "units": []}`,
+			err:      "unsupported snapshot version 2, expected 1",
+		},
+		{
+			name: "invalid value",
+			snapshot: `{"version": 1, "units": [{"path": "/a", "config_path": "/a/terragrunt.hcl", "output_references": null,
+				"config": {"inputs": {"x": {"type": "\"bool\"", "value": {}}}}}]}`,
+			err: "invalid snapshot of /a/terragrunt.hcl: input x",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadSnapshot([]byte(test.snapshot))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// SopsDecryptor decrypts files encrypted with sops. The given format is one of yaml, json, dotenv, ini or binary, and
+// the decrypted content must be returned in that same format.
+type SopsDecryptor interface {
+	Decrypt(ctx context.Context, path string, format string) ([]byte, error)
+}
+
+// ExecSopsDecryptor decrypts files by running the sops binary, which supports every key type sops does (age, AWS KMS,
+// GCP KMS, PGP, ...). Keys can be supplied through the AgeKeys and Env fields, on top of the environment of the
+// current process.
+type ExecSopsDecryptor struct {
+	// Command is the sops binary to run. Defaults to sops.
+	Command string
+
+	// AgeKeys are age private keys (AGE-SECRET-KEY-...) to decrypt with, passed through SOPS_AGE_KEY.
+	AgeKeys []string
+
+	// Env holds additional environment variables (e.g. AWS_PROFILE=secrets) in the KEY=value form.
+	Env []string
+
+	// Runner runs the sops binary. Defaults to ExecCommandRunner.
+	Runner CommandRunner
+}
+
+func (decryptor ExecSopsDecryptor) Decrypt(ctx context.Context, path string, format string) ([]byte, error) {
+	command := decryptor.Command
+	if command == "" {
+		command = "sops"
+	}
+
+	cmd := Command{
+		Name: command,
+		Args: []string{"--decrypt", "--input-type", format, "--output-type", format, path},
+		Env:  append([]string(nil), decryptor.Env...),
+	}
+	if len(decryptor.AgeKeys) > 0 {
+		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY="+strings.Join(decryptor.AgeKeys, "\n"))
+	}
+	output, err := commandRunner(decryptor.Runner).Run(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%s --decrypt %s: %w: %s", command, path, err, bytes.TrimSpace(output.Stderr))
+	}
+	return output.Stdout, nil
+}
+
+// placeholderSopsDecryptor does not decrypt anything: it returns the encrypted files with every encrypted value
+// replaced by a placeholder, for environments without access to the decryption keys.
+type placeholderSopsDecryptor struct{}
+
+// SopsPlaceholder is the value encrypted values are replaced with when sops decryption is disabled.
+const SopsPlaceholder = "sops-encrypted"
+
+// sopsEncryptedValueRegexp matches the encrypted values of sops files.
+var sopsEncryptedValueRegexp = regexp.MustCompile(`ENC\[AES256_GCM,[^\]]*\]`)
+
+func (placeholderSopsDecryptor) Decrypt(ctx context.Context, path string, format string) ([]byte, error) {
+	if format == "binary" {
+		return []byte(SopsPlaceholder), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return sopsEncryptedValueRegexp.ReplaceAll(content, []byte(SopsPlaceholder)), nil
+}
+
+// sopsFormat returns the sops format of the file at the given path, based on its extension.
+func sopsFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	case ".env":
+		return "dotenv"
+	case ".ini":
+		return "ini"
+	}
+	return "binary"
+}
+
+// sopsDecryptFileFunc returns the sops_decrypt_file(path) function, which decrypts the file at the given path, relative
+// to the configuration being parsed, and returns its content.
+func sopsDecryptFileFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if opts.SopsDecryptor == nil {
+				return cty.NilVal, errors.New("no sops decryptor is configured")
+			}
+			path := opts.absolutePath(args[0].AsString())
+
+			content, err := opts.SopsDecryptor.Decrypt(opts.Context, path, sopsFormat(path))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(string(content)).Mark(SensitiveMark), nil
+		},
+	})
+}
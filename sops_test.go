@@ -0,0 +1,129 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// sopsDecryptorFunc is a SopsDecryptor calling the given function.
+type sopsDecryptorFunc func(ctx context.Context, path string, format string) ([]byte, error)
+
+func (decrypt sopsDecryptorFunc) Decrypt(ctx context.Context, path string, format string) ([]byte, error) {
+	return decrypt(ctx, path, format)
+}
+
+func TestSopsDecryptFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"secrets.yaml": "password: ENC[AES256_GCM,data:aGk=,iv:aXY=,tag:dGFn,type:str]\nuser: admin\n",
+		"key.bin":      "ENC[AES256_GCM,data:a2V5,type:str]",
+	})
+	decryptor := sopsDecryptorFunc(func(ctx context.Context, path string, format string) ([]byte, error) {
+		return []byte(format + " " + filepath.Base(path)), nil
+	})
+
+	tests := []struct {
+		name string
+		file string
+		opts []Option
+		want string
+		err  string
+	}{
+		{
+			name: "pluggable decryptor",
+			file: "secrets.yaml",
+			opts: []Option{WithSopsDecryptor(decryptor)},
+			want: "yaml secrets.yaml",
+		},
+		{
+			name: "pluggable decryptor of a binary file",
+			file: "key.bin",
+			opts: []Option{WithSopsDecryptor(decryptor)},
+			want: "binary key.bin",
+		},
+		{
+			name: "placeholder",
+			file: "secrets.yaml",
+			opts: []Option{WithSopsDecryptionDisabled()},
+			want: "password: " + SopsPlaceholder + "\nuser: admin\n",
+		},
+		{
+			name: "placeholder of a binary file",
+			file: "key.bin",
+			opts: []Option{WithSopsDecryptionDisabled()},
+			want: SopsPlaceholder,
+		},
+		{
+			name: "placeholder of a missing file",
+			file: "missing.yaml",
+			opts: []Option{WithSopsDecryptionDisabled()},
+			err:  "no such file or directory",
+		},
+		{
+			name: "no decryptor",
+			file: "secrets.yaml",
+			opts: []Option{WithSopsDecryptor(nil)},
+			err:  "no sops decryptor is configured",
+		},
+		{
+			name: "decryptor error",
+			file: "secrets.yaml",
+			opts: []Option{WithSopsDecryptor(sopsDecryptorFunc(func(ctx context.Context, path string, format string) ([]byte, error) {
+				return nil, errors.New("no key could decrypt the data key")
+			}))},
+			err: "no key could decrypt the data key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]Option{WithConfigPath(filepath.Join(dir, DefaultConfigFilename))}, test.opts...)
+			config, err := ParseConfig([]byte(`inputs = { secret = sops_decrypt_file("`+test.file+`") }`), opts...)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if secret, _ := config.InputsCty["secret"].Unmark(); !secret.RawEquals(cty.StringVal(test.want)) {
+				t.Errorf("got %#v, want %q", secret, test.want)
+			}
+		})
+	}
+}
+
+func TestExecSopsDecryptor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	runner := &FakeCommandRunner{Responses: map[string]FakeCommandResponse{
+		"sops --decrypt --input-type json --output-type json " + path: {Stdout: `{"password": "hunter2"}`},
+	}}
+	decryptor := ExecSopsDecryptor{AgeKeys: []string{"AGE-SECRET-KEY-1", "AGE-SECRET-KEY-2"}, Env: []string{"AWS_PROFILE=secrets"}, Runner: runner}
+
+	content, err := decryptor.Decrypt(context.Background(), path, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `{"password": "hunter2"}` {
+		t.Errorf("got %q, want the decrypted content", content)
+	}
+	commands := runner.Commands()
+	if len(commands) != 1 {
+		t.Fatalf("got commands %v, want one", commands)
+	}
+	wantEnv := []string{"AWS_PROFILE=secrets", "SOPS_AGE_KEY=AGE-SECRET-KEY-1\nAGE-SECRET-KEY-2"}
+	if env := commands[0].Env; strings.Join(env, ",") != strings.Join(wantEnv, ",") {
+		t.Errorf("got env %q, want %q", env, wantEnv)
+	}
+
+	runner.Responses["sops --decrypt --input-type json --output-type json "+path] = FakeCommandResponse{Stderr: "failed to get the data key\n", ExitCode: 128}
+	if _, err := decryptor.Decrypt(context.Background(), path, "json"); err == nil || !strings.Contains(err.Error(), "exit status 128: failed to get the data key") {
+		t.Errorf("got error %v, want the exit status and stderr of sops", err)
+	}
+}
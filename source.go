@@ -0,0 +1,295 @@
+package terragrunt
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// SourceKind is the kind of a terraform module source, which determines how it is retrieved.
+type SourceKind string
+
+const (
+	SourceLocal    SourceKind = "local"
+	SourceRegistry SourceKind = "registry"
+	SourceGit      SourceKind = "git"
+	SourceHg       SourceKind = "hg"
+	SourceS3       SourceKind = "s3"
+	SourceGCS      SourceKind = "gcs"
+	SourceHTTP     SourceKind = "http"
+)
+
+// DefaultRegistryHost is the host of registry module sources that do not specify one.
+const DefaultRegistryHost = "registry.terraform.io"
+
+// Source is a parsed terraform module source.
+type Source struct {
+	Kind SourceKind
+
+	// Host is the host serving the module: the git server, the registry, or the s3, gcs or http endpoint. It is
+	// empty for local sources.
+	Host string
+
+	// Path is the path of the module on its host: the repository path for git sources (without the .git suffix),
+	// namespace/name/provider for registry sources, or the local path.
+	Path string
+
+	// Namespace and Name are the owner and the name of git repositories and registry modules. Provider is the
+	// provider of registry modules.
+	Namespace string
+	Name      string
+	Provider  string
+
+	// Submodule is the directory of the module within the package, given after a double slash (e.g. modules/vpc).
+	Submodule string
+
+	// Version is the ref query parameter of git sources, the rev query parameter of mercurial sources, or the version
+	// query parameter of registry sources.
+	Version string
+
+	// URL is the normalized address of the package, without the submodule, the version and the other query
+	// parameters, in the go-getter form (e.g. git::https://github.com/org/repo.git).
+	URL string
+
+	// Query holds the query parameters of the source other than the version (e.g. depth).
+	Query url.Values
+}
+
+var (
+	forcedGetterRegexp   = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+	scpLikeGitRegexp     = regexp.MustCompile(`^([A-Za-z0-9_.-]+)@([A-Za-z0-9_.-]+):(.+)$`)
+	registryPartRegexp   = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z_-]{0,62}[0-9A-Za-z])?$`)
+	s3HostRegexp         = regexp.MustCompile(`(^|\.)s3([.-][a-z0-9-]+)?\.amazonaws\.com(\.cn)?$`)
+	gitShorthandPrefixes = []string{"github.com/", "bitbucket.org/", "gitlab.com/"}
+)
+
+// ParseSource parses and classifies the given terraform module source, following the go-getter conventions used by
+// terraform and terragrunt: local paths, registry modules (with or without the tfr:// scheme), git repositories over
+// ssh or https (forced with git:: or detected for github.com, bitbucket.org and gitlab.com), s3 and gcs buckets, and
+// http archives. The sources forced with another getter (e.g. hg::) are of the kind named after the getter.
+func ParseSource(src string) (*Source, error) {
+	rest := strings.TrimSpace(src)
+	if rest == "" {
+		return nil, fmt.Errorf("empty module source")
+	}
+
+	forced := ""
+	if match := forcedGetterRegexp.FindStringSubmatch(rest); match != nil {
+		forced, rest = match[1], match[2]
+	}
+
+	source := &Source{Query: url.Values{}}
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		query, err := url.ParseQuery(rest[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query in module source %s: %w", src, err)
+		}
+		rest = rest[:i]
+		source.Query = query
+	}
+	rest, source.Submodule = splitSubmodule(rest)
+
+	var err error
+	switch {
+	case forced == "" && isLocalSource(rest):
+		source.Kind, source.Path, source.URL = SourceLocal, rest, rest
+	case forced == "git" || (forced == "" && isGitSource(rest)):
+		err = source.parseGit(rest)
+	case forced == "s3" || (forced == "" && isBucketSource(rest, s3HostRegexp.MatchString)):
+		err = source.parseBucket(SourceS3, rest)
+	case forced == "gcs" || (forced == "" && isBucketSource(rest, func(host string) bool { return host == "www.googleapis.com" })):
+		err = source.parseBucket(SourceGCS, rest)
+	case forced == "" && strings.HasPrefix(rest, "tfr://"):
+		err = source.parseRegistry(strings.TrimPrefix(rest, "tfr://"), true)
+	case forced == "http" || forced == "https" || (forced == "" && (strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://"))):
+		err = source.parseHTTP(rest)
+	case forced == "":
+		err = source.parseRegistry(rest, false)
+	default:
+		err = source.parseForced(SourceKind(forced), rest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid module source %s: %w", src, err)
+	}
+
+	source.Version = source.Query.Get(source.versionParam())
+	source.Query.Del(source.versionParam())
+	return source, nil
+}
+
+// versionParam returns the query parameter holding the version of the source.
+func (source *Source) versionParam() string {
+	switch source.Kind {
+	case SourceRegistry:
+		return "version"
+	case SourceHg:
+		return "rev"
+	}
+	return "ref"
+}
+
+// String returns the normalized form of the source, so that sources written differently but pointing to the same
+// module and version compare equal.
+func (source *Source) String() string {
+	normalized := source.URL
+	if source.Submodule != "" {
+		normalized += "//" + source.Submodule
+	}
+
+	query := url.Values{}
+	for key, values := range source.Query {
+		query[key] = values
+	}
+	if source.Version != "" {
+		query.Set(source.versionParam(), source.Version)
+	}
+	if len(query) > 0 {
+		normalized += "?" + query.Encode()
+	}
+	return normalized
+}
+
+// splitSubmodule splits the package address from the submodule directory, given after a double slash that is not
+// part of the scheme.
+func splitSubmodule(src string) (string, string) {
+	start := 0
+	if i := strings.Index(src, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(src[start:], "//"); i >= 0 {
+		return src[:start+i], strings.Trim(src[start+i+2:], "/")
+	}
+	return src, ""
+}
+
+func isLocalSource(src string) bool {
+	return strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../") || strings.HasPrefix(src, "/") ||
+		src == "." || src == ".." || (len(src) > 2 && src[1] == ':' && (src[2] == '\\' || src[2] == '/'))
+}
+
+func isGitSource(src string) bool {
+	if scpLikeGitRegexp.MatchString(src) && !strings.Contains(src, "://") {
+		return true
+	}
+	if strings.HasPrefix(src, "ssh://") {
+		return true
+	}
+	for _, prefix := range gitShorthandPrefixes {
+		if strings.HasPrefix(src, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBucketSource returns whether the host of the given source, with or without a scheme, matches.
+func isBucketSource(src string, matchHost func(string) bool) bool {
+	host := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(src, "https://"), "http://"), "/", 2)[0]
+	return matchHost(host)
+}
+
+func (source *Source) parseGit(src string) error {
+	source.Kind = SourceGit
+
+	var gitURL *url.URL
+	if match := scpLikeGitRegexp.FindStringSubmatch(src); match != nil && !strings.Contains(src, "://") {
+		// scp-like addresses (git@github.com:org/repo.git) are normalized to ssh urls.
+		gitURL = &url.URL{Scheme: "ssh", User: url.User(match[1]), Host: match[2], Path: "/" + strings.TrimPrefix(match[3], "/")}
+	} else {
+		if !strings.Contains(src, "://") {
+			src = "https://" + src
+		}
+		parsed, err := url.Parse(src)
+		if err != nil {
+			return err
+		}
+		gitURL = parsed
+	}
+
+	source.Host = gitURL.Hostname()
+	source.Path = strings.TrimSuffix(strings.Trim(gitURL.Path, "/"), ".git")
+	if source.Host == "" || source.Path == "" {
+		return fmt.Errorf("missing git repository")
+	}
+	source.Namespace, source.Name = path.Split(source.Path)
+	source.Namespace = strings.TrimSuffix(source.Namespace, "/")
+
+	gitURL.Path = "/" + source.Path + ".git"
+	gitURL.RawQuery = ""
+	source.URL = "git::" + gitURL.String()
+	return nil
+}
+
+func (source *Source) parseBucket(kind SourceKind, src string) error {
+	source.Kind = kind
+	if !strings.Contains(src, "://") {
+		src = "https://" + src
+	}
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	source.Host = parsed.Host
+	source.Path = strings.Trim(parsed.Path, "/")
+	source.URL = string(kind) + "::" + parsed.String()
+	return nil
+}
+
+func (source *Source) parseHTTP(src string) error {
+	source.Kind = SourceHTTP
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	source.Host = parsed.Host
+	source.Path = strings.Trim(parsed.Path, "/")
+	source.URL = parsed.String()
+	return nil
+}
+
+// parseForced parses the address of a source forced with the getter of the given kind, which is kept as is.
+func (source *Source) parseForced(kind SourceKind, src string) error {
+	source.Kind = kind
+	parsed, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	source.Host = parsed.Host
+	source.Path = strings.Trim(parsed.Path, "/")
+	source.URL = string(kind) + "::" + parsed.String()
+	return nil
+}
+
+// parseRegistry parses a registry module address: [host/]namespace/name/provider. With the tfr:// scheme, an empty
+// host (tfr:///namespace/name/provider) selects the default registry.
+func (source *Source) parseRegistry(src string, scheme bool) error {
+	source.Kind = SourceRegistry
+
+	parts := strings.Split(src, "/")
+	switch {
+	case scheme && len(parts) == 4:
+		source.Host, parts = parts[0], parts[1:]
+	case !scheme && len(parts) == 4 && strings.Contains(parts[0], "."):
+		source.Host, parts = parts[0], parts[1:]
+	case len(parts) != 3:
+		return fmt.Errorf("unrecognized module source")
+	}
+	if source.Host == "" {
+		source.Host = DefaultRegistryHost
+	}
+	for _, part := range parts {
+		if !registryPartRegexp.MatchString(part) {
+			return fmt.Errorf("unrecognized module source")
+		}
+	}
+
+	source.Namespace, source.Name, source.Provider = parts[0], parts[1], parts[2]
+	source.Path = strings.Join(parts, "/")
+	source.URL = "tfr://" + source.Host + "/" + source.Path
+	return nil
+}
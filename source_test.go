@@ -0,0 +1,181 @@
+package terragrunt
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want Source
+
+		// normalized is the expected String of the source.
+		normalized string
+		err        string
+	}{
+		{
+			name:       "local",
+			src:        "../modules/vpc",
+			want:       Source{Kind: SourceLocal, Path: "../modules/vpc", URL: "../modules/vpc"},
+			normalized: "../modules/vpc",
+		},
+		{
+			name: "registry",
+			src:  "terraform-aws-modules/vpc/aws?version=5.0.0",
+			want: Source{
+				Kind: SourceRegistry, Host: DefaultRegistryHost, Path: "terraform-aws-modules/vpc/aws",
+				Namespace: "terraform-aws-modules", Name: "vpc", Provider: "aws", Version: "5.0.0",
+				URL: "tfr://registry.terraform.io/terraform-aws-modules/vpc/aws",
+			},
+			normalized: "tfr://registry.terraform.io/terraform-aws-modules/vpc/aws?version=5.0.0",
+		},
+		{
+			name: "private registry submodule",
+			src:  "tfr://app.terraform.io/org/vpc/aws//modules/endpoints?version=1.2.0",
+			want: Source{
+				Kind: SourceRegistry, Host: "app.terraform.io", Path: "org/vpc/aws", Namespace: "org", Name: "vpc",
+				Provider: "aws", Submodule: "modules/endpoints", Version: "1.2.0", URL: "tfr://app.terraform.io/org/vpc/aws",
+			},
+			normalized: "tfr://app.terraform.io/org/vpc/aws//modules/endpoints?version=1.2.0",
+		},
+		{
+			name: "registry scheme without host",
+			src:  "tfr:///org/vpc/aws",
+			want: Source{
+				Kind: SourceRegistry, Host: DefaultRegistryHost, Path: "org/vpc/aws", Namespace: "org", Name: "vpc",
+				Provider: "aws", URL: "tfr://registry.terraform.io/org/vpc/aws",
+			},
+			normalized: "tfr://registry.terraform.io/org/vpc/aws",
+		},
+		{
+			name: "git over https",
+			src:  "git::https://github.com/org/modules.git//vpc?ref=v1.2.0&depth=1",
+			want: Source{
+				Kind: SourceGit, Host: "github.com", Path: "org/modules", Namespace: "org", Name: "modules",
+				Submodule: "vpc", Version: "v1.2.0", URL: "git::https://github.com/org/modules.git",
+				Query: url.Values{"depth": {"1"}},
+			},
+			normalized: "git::https://github.com/org/modules.git//vpc?depth=1&ref=v1.2.0",
+		},
+		{
+			name: "git scp-like address",
+			src:  "git@gitlab.example.com:group/sub/modules.git//vpc/?ref=main",
+			want: Source{
+				Kind: SourceGit, Host: "gitlab.example.com", Path: "group/sub/modules", Namespace: "group/sub",
+				Name: "modules", Submodule: "vpc", Version: "main", URL: "git::ssh://git@gitlab.example.com/group/sub/modules.git",
+			},
+			normalized: "git::ssh://git@gitlab.example.com/group/sub/modules.git//vpc?ref=main",
+		},
+		{
+			name: "github shorthand",
+			src:  "github.com/org/modules//vpc",
+			want: Source{
+				Kind: SourceGit, Host: "github.com", Path: "org/modules", Namespace: "org", Name: "modules",
+				Submodule: "vpc", URL: "git::https://github.com/org/modules.git",
+			},
+			normalized: "git::https://github.com/org/modules.git//vpc",
+		},
+		{
+			name: "mercurial",
+			src:  "hg::https://hg.example.com/modules//vpc?rev=42",
+			want: Source{
+				Kind: SourceHg, Host: "hg.example.com", Path: "modules", Submodule: "vpc", Version: "42",
+				URL: "hg::https://hg.example.com/modules",
+			},
+			normalized: "hg::https://hg.example.com/modules//vpc?rev=42",
+		},
+		{
+			name: "other forced getter",
+			src:  "custom::https://example.com/modules?ref=v1",
+			want: Source{
+				Kind: SourceKind("custom"), Host: "example.com", Path: "modules", Version: "v1",
+				URL: "custom::https://example.com/modules",
+			},
+			normalized: "custom::https://example.com/modules?ref=v1",
+		},
+		{
+			name: "forced s3",
+			src:  "s3::https://s3-eu-west-1.amazonaws.com/bucket/modules/vpc.zip",
+			want: Source{
+				Kind: SourceS3, Host: "s3-eu-west-1.amazonaws.com", Path: "bucket/modules/vpc.zip",
+				URL: "s3::https://s3-eu-west-1.amazonaws.com/bucket/modules/vpc.zip",
+			},
+			normalized: "s3::https://s3-eu-west-1.amazonaws.com/bucket/modules/vpc.zip",
+		},
+		{
+			name: "detected s3",
+			src:  "bucket.s3.eu-west-1.amazonaws.com/vpc.zip//modules/vpc",
+			want: Source{
+				Kind: SourceS3, Host: "bucket.s3.eu-west-1.amazonaws.com", Path: "vpc.zip", Submodule: "modules/vpc",
+				URL: "s3::https://bucket.s3.eu-west-1.amazonaws.com/vpc.zip",
+			},
+			normalized: "s3::https://bucket.s3.eu-west-1.amazonaws.com/vpc.zip//modules/vpc",
+		},
+		{
+			name: "gcs",
+			src:  "gcs::https://www.googleapis.com/storage/v1/bucket/vpc.zip",
+			want: Source{
+				Kind: SourceGCS, Host: "www.googleapis.com", Path: "storage/v1/bucket/vpc.zip",
+				URL: "gcs::https://www.googleapis.com/storage/v1/bucket/vpc.zip",
+			},
+			normalized: "gcs::https://www.googleapis.com/storage/v1/bucket/vpc.zip",
+		},
+		{
+			name: "http archive",
+			src:  "https://example.com/modules/vpc.zip?archive=zip",
+			want: Source{
+				Kind: SourceHTTP, Host: "example.com", Path: "modules/vpc.zip", URL: "https://example.com/modules/vpc.zip",
+				Query: url.Values{"archive": {"zip"}},
+			},
+			normalized: "https://example.com/modules/vpc.zip?archive=zip",
+		},
+		{
+			name: "empty",
+			src:  " ",
+			err:  "empty module source",
+		},
+		{
+			name: "unrecognized",
+			src:  "org/vpc",
+			err:  "invalid module source org/vpc: unrecognized module source",
+		},
+		{
+			name: "git without repository",
+			src:  "git::https://github.com/",
+			err:  "missing git repository",
+		},
+		{
+			name: "invalid query",
+			src:  "org/vpc/aws?version=%zz",
+			err:  "invalid query in module source",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := ParseSource(test.src)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want.Query == nil {
+				test.want.Query = url.Values{}
+			}
+			if !reflect.DeepEqual(*source, test.want) {
+				t.Errorf("got %+v, want %+v", *source, test.want)
+			}
+			if normalized := source.String(); normalized != test.normalized {
+				t.Errorf("got %s, want %s", normalized, test.normalized)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package terragrunt
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultConfigFilename is the name of the terragrunt configuration file of a unit.
+const DefaultConfigFilename = "terragrunt.hcl"
+
+// Unit is a terragrunt module of a stack: a directory containing a terragrunt configuration file.
+type Unit struct {
+	// Path is the absolute path of the unit directory.
+	Path string
+
+	// ConfigPath is the absolute path of the terragrunt configuration file of the unit.
+	ConfigPath string
+
+	// Config is the parsed configuration of the unit. It is nil when the configuration could not be parsed, in which
+	// case Err holds the reason.
+	Config *TerragruntConfig
+	Err    error
+
+	// Dependencies are the absolute paths of the units this unit depends on, as declared by its enabled dependency
+	// blocks. They are known even when the rest of the configuration could not be parsed.
+	Dependencies []string
+
+	// Files are the absolute paths of the files the configuration of the unit depends on, sorted: the configuration
+	// itself, and the files read or looked up while parsing it, including the ones that did not exist.
+	Files []string
+
+	// StackUnit is the unit block of a stack file the unit is generated from, or nil for the other units. The
+	// configuration of such a unit is read from its source, as if it were generated.
+	StackUnit *StackUnit
+
+	// MatrixKey is the key of the matrix item a virtual unit is expanded for (see ExpandMatrix), or empty for the
+	// other units.
+	MatrixKey string
+
+	// dependencyBlocks maps the names of the enabled dependency blocks of the unit to the absolute path of their target unit.
+	dependencyBlocks map[string]string
+
+	// outputReferences are the dependency outputs referenced by the configuration of the unit.
+	outputReferences dependencyReferences
+}
+
+// DependentReference describes a dependency block of a unit that points to another unit.
+type DependentReference struct {
+	// Unit is the dependent unit.
+	Unit *Unit
+
+	// Dependency is the name of the dependency block.
+	Dependency string
+
+	// OutputKeys are the output keys the dependent unit references, sorted. AllOutputs is set instead when the
+	// outputs are referenced as a whole, or dynamically, so that any output may be used.
+	OutputKeys []string
+	AllOutputs bool
+}
+
+// Stack is a tree of units, discovered under a root directory.
+type Stack struct {
+	// Root is the absolute path of the directory the units were discovered under.
+	Root string
+
+	// Units are the units of the stack, ordered by path.
+	Units []*Unit
+}
+
+// ParseStack discovers every unit under the given root directory and parses its configuration with the given
+// options. Errors parsing a unit do not stop the discovery: they are recorded on the unit instead.
+func ParseStack(root string, opts ...Option) (*Stack, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := discoverUnitEntries(absRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parseOptions := newParseOptions(opts)
+	stack := &Stack{Root: absRoot}
+	for _, entry := range entries {
+		parseOptions.sendProgress(ProgressEvent{Type: ProgressUnitStarted, Unit: filepath.Dir(entry.configPath)})
+		start := time.Now()
+		unit := entry.parse(opts)
+		parseOptions.sendUnitProgress(unit, start)
+		stack.Units = append(stack.Units, unit)
+	}
+	return stack, nil
+}
+
+// unitEntry is a unit discovered under a root directory: a configuration file, a unit generated by a stack file, or
+// a stack file that could not be expanded, along with the reason.
+type unitEntry struct {
+	configPath string
+	stackUnit  *StackUnit
+	err        error
+}
+
+// discoverUnitEntries discovers the configuration files and the stack files under the given root directory, and
+// returns their units ordered by config path. The units generated by a stack file replace the configuration file
+// of the directory they are generated in, if any.
+func discoverUnitEntries(root string, opts []Option) ([]unitEntry, error) {
+	configPaths, err := DiscoverUnits(root, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stackFiles, err := DiscoverStackFiles(root, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]unitEntry{}
+	for _, configPath := range configPaths {
+		entries[configPath] = unitEntry{configPath: configPath}
+	}
+	for _, stackFile := range stackFiles {
+		stackUnits, err := ExpandStack(stackFile, opts...)
+		if err != nil {
+			entries[stackFile] = unitEntry{configPath: stackFile, err: err}
+			continue
+		}
+		for _, stackUnit := range stackUnits {
+			configPath := filepath.Join(stackUnit.Path, DefaultConfigFilename)
+			entries[configPath] = unitEntry{configPath: configPath, stackUnit: stackUnit}
+		}
+	}
+
+	sorted := make([]unitEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].configPath < sorted[j].configPath
+	})
+	return sorted, nil
+}
+
+// parse parses the unit of the entry, with the given options.
+func (entry unitEntry) parse(opts []Option) *Unit {
+	switch {
+	case entry.err != nil:
+		return &Unit{Path: filepath.Dir(entry.configPath), ConfigPath: entry.configPath, Err: entry.err, Files: []string{entry.configPath}}
+	case entry.stackUnit != nil:
+		return parseStackUnit(entry.stackUnit, opts)
+	default:
+		return parseUnit(entry.configPath, opts)
+	}
+}
+
+// parseUnit parses the unit whose configuration lives at the given absolute path, or returns it from the parse cache
+// when one is set and the files of the unit did not change.
+func parseUnit(configPath string, opts []Option) *Unit {
+	parseOptions := newParseOptions(opts)
+	parseCache := parseOptions.ParseCache
+	if parseCache == nil {
+		return parseUnitConfig(configPath, opts)
+	}
+
+	unit, fingerprint, found := parseCache.get(configPath)
+	parseOptions.Metrics.IncCounter(MetricCacheRequests, map[string]string{"cache": "parse", "result": cacheResult(found)})
+	if found {
+		parseOptions.Logger.Log(EventParseCacheHit, "config_path", configPath, "fingerprint", fingerprint)
+		return unit
+	}
+	parseOptions.Logger.Log(EventParseCacheMiss, "config_path", configPath)
+
+	unit = parseUnitConfig(configPath, opts)
+	if unit.Err == nil {
+		if err := parseCache.set(unit); err != nil {
+			parseOptions.Logger.Log(EventParseCacheStoreFailed, "config_path", configPath, "error", err)
+		}
+	}
+	return unit
+}
+
+// parseUnitConfig parses the configuration of the unit living at the given absolute path.
+func parseUnitConfig(configPath string, opts []Option) *Unit {
+	return parseUnitContent(configPath, configPath, map[string]bool{}, opts)
+}
+
+// parseUnitContent parses the configuration of the unit whose configuration lives at the given absolute path, reading
+// it from sourcePath. The given files are recorded as accessed, along with the ones accessed while parsing.
+func parseUnitContent(configPath string, sourcePath string, accessedFiles map[string]bool, opts []Option) *Unit {
+	unit := &Unit{
+		Path:       filepath.Dir(configPath),
+		ConfigPath: configPath,
+	}
+
+	// The files accessed are collected as the configuration is parsed, whether it succeeds or not.
+	accessedFiles[sourcePath] = true
+	defer func() {
+		for path := range accessedFiles {
+			unit.Files = append(unit.Files, path)
+		}
+		sort.Strings(unit.Files)
+	}()
+
+	unitOpts := append([]Option{WithConfigPath(configPath), withAccessedFiles(accessedFiles)}, opts...)
+	parseOptions := newParseOptions(unitOpts)
+	content, err := parseOptions.readFile(sourcePath)
+	if err != nil {
+		unit.Err = err
+		return unit
+	}
+
+	// The dependency blocks and references are recorded while the configuration is parsed, before the outputs of the
+	// dependencies are retrieved, so that they are known even when the rest of the configuration can not be parsed.
+	recorded := &unitDependencies{}
+	unit.Config, unit.Err = ParseConfig(content, append(unitOpts, withUnitDependencies(recorded))...)
+	if recorded.recorded {
+		unit.dependencyBlocks = map[string]string{}
+		for _, dependency := range recorded.dependencies {
+			if !dependency.IsEnabled() {
+				continue
+			}
+			dependencyPath := configDir(dependencyConfigPath(dependency, parseOptions))
+			unit.Dependencies = append(unit.Dependencies, dependencyPath)
+			unit.dependencyBlocks[dependency.Name] = dependencyPath
+		}
+		unit.outputReferences = recorded.references
+	}
+	return unit
+}
+
+// unitDependencies are the dependency blocks of a unit, including the ones of its includes, and the dependency
+// outputs its configuration references, recorded once they are decoded.
+type unitDependencies struct {
+	recorded     bool
+	dependencies []Dependency
+	references   dependencyReferences
+}
+
+func (unit *unitDependencies) record(dependencies []Dependency, references dependencyReferences) {
+	unit.recorded = true
+	unit.dependencies = dependencies
+	unit.references = references
+}
+
+// withUnitDependencies records the dependency blocks and references of the configuration parsed into the given
+// unitDependencies.
+func withUnitDependencies(unit *unitDependencies) Option {
+	return func(opts *ParseOptions) {
+		opts.unitDependencies = unit
+	}
+}
+
+// Skipped returns whether the unit is excluded from runs by its skip attribute. Units that could not be parsed are not
+// skipped.
+func (unit *Unit) Skipped() bool {
+	return unit.Config != nil && unit.Config.Skip
+}
+
+// withAccessedFiles records the files accessed while parsing into the given set.
+func withAccessedFiles(accessedFiles map[string]bool) Option {
+	return func(opts *ParseOptions) {
+		opts.accessedFiles = accessedFiles
+	}
+}
+
+// DependencyPaths returns the absolute paths of the units targeted by the dependency blocks of the unit, keyed by
+// block name. Like Dependencies, they are known even when the rest of the configuration could not be parsed.
+func (unit *Unit) DependencyPaths() map[string]string {
+	paths := make(map[string]string, len(unit.dependencyBlocks))
+	for name, path := range unit.dependencyBlocks {
+		paths[name] = path
+	}
+	return paths
+}
+
+// DiscoverUnits walks the given directory and returns the paths of the terragrunt configuration files found in it,
+// sorted. Hidden directories (e.g. .git) and terragrunt and terraform caches are skipped. Symlinks to directories are
+// only followed with WithFollowSymlinks.
+func DiscoverUnits(root string, opts ...Option) ([]string, error) {
+	return discoverFiles(root, DefaultConfigFilename, newParseOptions(opts))
+}
+
+// DiscoverStackFiles walks the given directory and returns the paths of the terragrunt.stack.hcl files found in it,
+// sorted. The directories skipped are the same as DiscoverUnits, including the .terragrunt-stack directories the
+// stacks are generated in.
+func DiscoverStackFiles(root string, opts ...Option) ([]string, error) {
+	return discoverFiles(root, DefaultStackFilename, newParseOptions(opts))
+}
+
+// discoverFiles walks the given directory and returns the paths of the files with the given name found in it, sorted.
+// The paths excluded by the ignore files (see IgnoreFilenames) and by WithExcludeGlobs are skipped. With
+// WithFollowSymlinks, the symlinks to directories are followed: each directory is only walked once, at the first path
+// it is reached through, so that the files reachable through several paths are only returned once, and the symlinks to
+// a directory being walked, which would loop, are skipped. Skipped symlinks are reported with EventSymlinkSkipped.
+func discoverFiles(root string, name string, opts *ParseOptions) ([]string, error) {
+	matcher, err := newIgnoreMatcher(opts.ExcludeGlobs)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	walked := map[string]string{}
+	walking := map[string]bool{}
+
+	var walk func(dir string, relDir string, matcher *ignoreMatcher) error
+	walk = func(dir string, relDir string, matcher *ignoreMatcher) error {
+		if opts.FollowSymlinks {
+			realDir, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return err
+			}
+			if realDir, err = filepath.Abs(realDir); err != nil {
+				return err
+			}
+			switch {
+			case walking[realDir]:
+				opts.Logger.Log(EventSymlinkSkipped, "path", dir, "target", realDir, "reason", "loop")
+				return nil
+			case walked[realDir] != "":
+				opts.Logger.Log(EventSymlinkSkipped, "path", dir, "target", realDir, "reason", "duplicate", "walked_path", walked[realDir])
+				return nil
+			}
+			walked[realDir], walking[realDir] = dir, true
+			defer delete(walking, realDir)
+		}
+
+		matcher, err := matcher.withIgnoreFiles(dir, relDir)
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryPath, relPath := filepath.Join(dir, entry.Name()), path.Join(relDir, entry.Name())
+			isDir := entry.IsDir()
+			if opts.FollowSymlinks && entry.Type()&fs.ModeSymlink != 0 {
+				info, err := os.Stat(entryPath)
+				if err != nil {
+					// Dangling symlinks are ignored, as when they are not followed.
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if matcher.ignored(relPath, isDir) {
+				continue
+			}
+
+			switch {
+			case isDir && !isSkippedDir(entry.Name()):
+				if err := walk(entryPath, relPath, matcher); err != nil {
+					return err
+				}
+			case !isDir && entry.Name() == name:
+				paths = append(paths, entryPath)
+			}
+		}
+		return nil
+	}
+	if err := walk(root, "", matcher); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// isSkippedDir returns whether the directory with the given name should not be searched for units.
+func isSkippedDir(name string) bool {
+	return name[0] == '.' || name == "node_modules"
+}
+
+// Unit returns the unit of the stack living in the given directory, or nil if there is none.
+func (stack *Stack) Unit(path string) *Unit {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for _, unit := range stack.Units {
+		if unit.Path == absPath {
+			return unit
+		}
+	}
+	return nil
+}
+
+// Dependents returns, for every unit of the stack with a dependency block pointing to the unit in the given directory,
+// which outputs of that unit it references. This helps assessing the impact of changing the outputs of a unit.
+func (stack *Stack) Dependents(path string) ([]DependentReference, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dependents []DependentReference
+	for _, unit := range stack.Units {
+		for _, name := range sortedDependencyNames(unit.dependencyBlocks) {
+			if unit.dependencyBlocks[name] != absPath {
+				continue
+			}
+
+			reference := DependentReference{Unit: unit, Dependency: name}
+			keys, referenced := unit.outputReferences[name]
+			switch {
+			case unit.outputReferences == nil || (referenced && keys == nil):
+				reference.AllOutputs = true
+			case referenced:
+				for key := range keys {
+					reference.OutputKeys = append(reference.OutputKeys, key)
+				}
+				sort.Strings(reference.OutputKeys)
+			}
+			dependents = append(dependents, reference)
+		}
+	}
+	return dependents, nil
+}
+
+func sortedDependencyNames(dependencyBlocks map[string]string) []string {
+	names := make([]string, 0, len(dependencyBlocks))
+	for name := range dependencyBlocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Graph returns the dependency graph of the units of the stack.
+func (stack *Stack) Graph() *Graph {
+	return NewGraph(stack.Root, stack.Units)
+}
+
+// parseDependencyBlocks decodes the dependency blocks of the given configuration, including the ones of the
+// configurations it includes, without resolving their outputs. Dependency blocks are merged by name as ParseConfig
+// does.
+func parseDependencyBlocks(content []byte, opts []Option) ([]Dependency, error) {
+	return decodeDependencyBlocks(content, newParseOptions(opts))
+}
+
+func decodeDependencyBlocks(content []byte, parseOptions *ParseOptions) ([]Dependency, error) {
+	file, err := parseOptions.parseHCL(content, parseOptions.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := exposeUnitValues(parseOptions); err != nil {
+		return nil, err
+	}
+	parseOptions.includes = &includePaths{body: file.Body}
+	if err := checkNestedIncludes(file.Body, parseOptions); err != nil {
+		return nil, err
+	}
+
+	locals, remain, err := decodeAndEvaluateLocals(file.Body, parseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	featureFlags, remain, err := decodeFeatureFlags(remain, parseOptions, EvalContextExtensions{Locals: locals})
+	if err != nil {
+		return nil, err
+	}
+
+	decodedDependency := terragruntDependency{}
+	if err := decodeHCL(remain, &decodedDependency, parseOptions, EvalContextExtensions{Locals: locals, FeatureFlags: featureFlagsValue(featureFlags)}); err != nil {
+		return nil, err
+	}
+	if err := checkDependencyBlocks(remain, decodedDependency.Dependencies, parseOptions); err != nil {
+		return nil, err
+	}
+	return mergeIncludedDependencies(remain, decodedDependency.Dependencies, parseOptions)
+}
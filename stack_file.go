@@ -0,0 +1,293 @@
+package terragrunt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultStackFilename is the name of the files declaring the units of a stack with unit and stack blocks.
+const DefaultStackFilename = "terragrunt.stack.hcl"
+
+// DefaultValuesFilename is the name of the file terragrunt stack generate writes the values of a generated unit to.
+const DefaultValuesFilename = "terragrunt.values.hcl"
+
+// stackDirName is the directory the units and stacks of a stack file are generated in, unless they set
+// no_dot_terragrunt_stack.
+const stackDirName = ".terragrunt-stack"
+
+// StackConfig is the parsed content of a terragrunt.stack.hcl file.
+type StackConfig struct {
+	Units  []StackConfigUnit  `hcl:"unit,block"`
+	Stacks []StackConfigStack `hcl:"stack,block"`
+}
+
+// StackConfigUnit is a unit block of a stack file: a unit generated from the configuration at Source into Path.
+type StackConfigUnit struct {
+	Name                 string     `hcl:",label"`
+	Source               string     `hcl:"source,attr"`
+	Path                 string     `hcl:"path,attr"`
+	NoDotTerragruntStack *bool      `hcl:"no_dot_terragrunt_stack,attr"`
+	Values               *cty.Value `hcl:"values,attr"`
+}
+
+// StackConfigStack is a stack block of a stack file: a nested stack generated from the stack file at Source into Path.
+type StackConfigStack struct {
+	Name                 string     `hcl:",label"`
+	Source               string     `hcl:"source,attr"`
+	Path                 string     `hcl:"path,attr"`
+	NoDotTerragruntStack *bool      `hcl:"no_dot_terragrunt_stack,attr"`
+	Values               *cty.Value `hcl:"values,attr"`
+}
+
+// ParseStackConfigFile reads and parses the stack file at the given path. Its locals are evaluated, and the values
+// passed to the stack by a parent stack file are exposed as values (see WithVariables).
+func ParseStackConfigFile(stackFile string, opts ...Option) (*StackConfig, error) {
+	opts = append([]Option{WithConfigPath(stackFile)}, opts...)
+	parseOptions := newParseOptions(opts)
+	content, err := parseOptions.readFile(stackFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseStackConfig(content, parseOptions)
+}
+
+func parseStackConfig(content []byte, parseOptions *ParseOptions) (*StackConfig, error) {
+	file, err := parseHCL(content, parseOptions.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	locals, remain, err := decodeAndEvaluateLocals(file.Body, parseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &StackConfig{}
+	if err := decodeHCL(remain, config, parseOptions, EvalContextExtensions{Locals: locals}); err != nil {
+		return nil, err
+	}
+	if err := checkStackBlockNames(config.Units, config.Stacks); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// checkStackBlockNames reports the unit blocks, and the stack blocks, sharing a name.
+func checkStackBlockNames(units []StackConfigUnit, stacks []StackConfigStack) error {
+	names := map[string]bool{}
+	for _, unit := range units {
+		if names[unit.Name] {
+			return fmt.Errorf("duplicate unit block %q", unit.Name)
+		}
+		names[unit.Name] = true
+	}
+	names = map[string]bool{}
+	for _, stack := range stacks {
+		if names[stack.Name] {
+			return fmt.Errorf("duplicate stack block %q", stack.Name)
+		}
+		names[stack.Name] = true
+	}
+	return nil
+}
+
+// StackUnit is a unit declared by a unit block of a stack file, expanded to the directory terragrunt stack generate
+// generates it in.
+type StackUnit struct {
+	// Name is the label of the unit block.
+	Name string
+
+	// StackFile is the absolute path of the stack file declaring the unit. For the units of nested stacks, it is the
+	// path of the stack file in the directory the nested stack is generated in.
+	StackFile string
+
+	// Source is the directory of the configuration the unit is generated from, or its source as written when it is
+	// not local.
+	Source string
+
+	// Path is the absolute path of the directory the unit is generated in.
+	Path string
+
+	// Values are the values of the unit block, exposed to the configuration of the unit as values. It is cty.NilVal
+	// when the block has none.
+	Values cty.Value
+
+	// stackFiles are the absolute paths of the stack files the unit was expanded from, from the outermost one.
+	stackFiles []string
+}
+
+// ExpandStack expands the unit blocks of the stack file at the given path, and recursively the ones of its stack
+// blocks, into the units terragrunt stack generate would generate, ordered by path. Local sources are resolved
+// against the directory of the stack file declaring them, nested stack files being read from their source. Sources
+// that are not local are only supported once generated: the generated copy is used.
+func ExpandStack(stackFile string, opts ...Option) ([]*StackUnit, error) {
+	absStackFile, err := filepath.Abs(stackFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []*StackUnit
+	if err := expandStack(absStackFile, absStackFile, nil, cty.NilVal, opts, &units); err != nil {
+		return nil, err
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].Path < units[j].Path
+	})
+	return units, nil
+}
+
+// expandStack expands the stack file read from sourceFile, and located at stackFile once generated, passing it the
+// given values. The stack files of the enclosing stacks are given, to report cycles.
+func expandStack(sourceFile string, stackFile string, parents []string, values cty.Value, opts []Option, units *[]*StackUnit) error {
+	for _, parent := range parents {
+		if parent == sourceFile {
+			return fmt.Errorf("%s: the stack includes itself", sourceFile)
+		}
+	}
+	parents = append(parents, sourceFile)
+
+	config, err := ParseStackConfigFile(sourceFile, append(append([]Option(nil), opts...), withValues(values))...)
+	if err != nil {
+		return err
+	}
+
+	sourceDir := filepath.Dir(sourceFile)
+	stackDir := filepath.Dir(stackFile)
+	for _, block := range config.Units {
+		path := generatedPath(stackDir, block.Path, block.NoDotTerragruntStack)
+		source, err := stackBlockSource(block.Source, sourceDir, path, DefaultConfigFilename)
+		if err != nil {
+			return fmt.Errorf("%s: unit %q: %w", sourceFile, block.Name, err)
+		}
+		unit := &StackUnit{
+			Name:       block.Name,
+			StackFile:  stackFile,
+			Source:     source,
+			Path:       path,
+			Values:     cty.NilVal,
+			stackFiles: append([]string(nil), parents...),
+		}
+		if block.Values != nil {
+			unit.Values = *block.Values
+		}
+		*units = append(*units, unit)
+	}
+
+	for _, block := range config.Stacks {
+		path := generatedPath(stackDir, block.Path, block.NoDotTerragruntStack)
+		source, err := stackBlockSource(block.Source, sourceDir, path, DefaultStackFilename)
+		if err != nil {
+			return fmt.Errorf("%s: stack %q: %w", sourceFile, block.Name, err)
+		}
+		stackValues := cty.NilVal
+		if block.Values != nil {
+			stackValues = *block.Values
+		}
+		nestedFile := filepath.Join(source, DefaultStackFilename)
+		if err := expandStack(nestedFile, filepath.Join(path, DefaultStackFilename), parents, stackValues, opts, units); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedPath returns the absolute path of the directory a unit or stack block with the given path is generated in.
+func generatedPath(stackDir string, path string, noDotTerragruntStack *bool) string {
+	if isAbsPath(path) {
+		return resolvePath(stackDir, path)
+	}
+	if noDotTerragruntStack != nil && *noDotTerragruntStack {
+		return filepath.Join(stackDir, path)
+	}
+	return filepath.Join(stackDir, stackDirName, path)
+}
+
+// stackBlockSource returns the directory of the given source of a unit or stack block: the local directory, resolved
+// against the given base directory, or else the generated directory, which must hold the given file.
+func stackBlockSource(source string, baseDir string, generatedDir string, filename string) (string, error) {
+	parsed, err := ParseSource(source)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Kind == SourceLocal {
+		return filepath.Join(resolvePath(baseDir, parsed.Path), filepath.FromSlash(parsed.Submodule)), nil
+	}
+	if _, err := os.Stat(filepath.Join(generatedDir, filename)); err != nil {
+		return "", fmt.Errorf("the %s source %s is not generated, run terragrunt stack generate first", parsed.Kind, source)
+	}
+	return generatedDir, nil
+}
+
+// parseStackUnit parses the configuration of the given unit of a stack file, read from its source as if it were
+// generated in its path, with its values.
+func parseStackUnit(stackUnit *StackUnit, opts []Option) *Unit {
+	opts = append(append([]Option(nil), opts...), withValues(stackUnit.Values))
+	accessedFiles := map[string]bool{}
+	for _, stackFile := range stackUnit.stackFiles {
+		accessedFiles[stackFile] = true
+	}
+	sourcePath := filepath.Join(stackUnit.Source, DefaultConfigFilename)
+	unit := parseUnitContent(filepath.Join(stackUnit.Path, DefaultConfigFilename), sourcePath, accessedFiles, opts)
+	unit.StackUnit = stackUnit
+	return unit
+}
+
+// withValues exposes the given values of a unit or stack block as values, unless they are cty.NilVal.
+func withValues(values cty.Value) Option {
+	return func(opts *ParseOptions) {
+		if values.Type() != cty.NilType {
+			opts.Variables["values"] = values
+		}
+	}
+}
+
+// exposeUnitValues exposes the values of a unit generated by terragrunt stack generate as values, unless they are
+// already set or the configuration is an included one: they are read from the terragrunt.values.hcl file next to its configuration, when there is one.
+func exposeUnitValues(parseOptions *ParseOptions) error {
+	if _, found := parseOptions.Variables["values"]; found || parseOptions.originalConfigPath != "" {
+		return nil
+	}
+	valuesPath := filepath.Join(filepath.Dir(parseOptions.ConfigPath), DefaultValuesFilename)
+	// The file is only looked up, without recording the access, as most units are not generated.
+	var err error
+	if parseOptions.FS == nil {
+		_, err = os.Stat(valuesPath)
+	} else {
+		_, err = fs.Stat(parseOptions.FS, fsPath(valuesPath))
+	}
+	if err != nil {
+		return nil
+	}
+
+	content, err := parseOptions.readFile(valuesPath)
+	if err != nil {
+		return err
+	}
+	file, err := parseHCL(content, valuesPath)
+	if err != nil {
+		return err
+	}
+	attributes, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return diags
+	}
+
+	evalContext, err := CreateTerragruntEvalContext(parseOptions, EvalContextExtensions{})
+	if err != nil {
+		return err
+	}
+	values := map[string]cty.Value{}
+	for name, attribute := range attributes {
+		value, diags := attribute.Expr.Value(evalContext)
+		if diags.HasErrors() {
+			return diags
+		}
+		values[name] = value
+	}
+	parseOptions.Variables["values"] = cty.ObjectVal(values)
+	return nil
+}
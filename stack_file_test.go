@@ -0,0 +1,206 @@
+package terragrunt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// stackFileFixture is a stack file declaring units, directly and through a stack nested twice, generated from the
+// configurations of the units directory.
+var stackFileFixture = map[string]string{
+	"live/terragrunt.stack.hcl": `
+locals {
+  env = "prod"
+}
+
+unit "vpc" {
+  source = "../units/vpc"
+  path   = "vpc"
+  values = { env = local.env, cidr = "10.0.0.0/16" }
+}
+
+unit "legacy" {
+  source                  = "../units/vpc"
+  path                    = "legacy"
+  no_dot_terragrunt_stack = true
+  values                  = { env = local.env, cidr = "10.1.0.0/16" }
+}
+
+stack "services" {
+  source = "../stacks/services"
+  path   = "services"
+  values = { env = local.env }
+}
+`,
+	"stacks/services/terragrunt.stack.hcl": `
+unit "api" {
+  source = "../../units/app"
+  path   = "api"
+  values = { name = "api", env = values.env }
+}
+
+stack "workers" {
+  source = "../workers"
+  path   = "workers"
+  values = { env = values.env }
+}
+`,
+	"stacks/workers/terragrunt.stack.hcl": `
+unit "queue" {
+  source = "../../units/app"
+  path   = "queue"
+  values = { name = "queue-${values.env}", env = values.env }
+}
+`,
+	"units/vpc/terragrunt.hcl": `inputs = { cidr = values.cidr, env = values.env }`,
+	"units/app/terragrunt.hcl": `inputs = { name = values.name, env = values.env }`,
+}
+
+func TestExpandStack(t *testing.T) {
+	dir := writeFiles(t, stackFileFixture)
+	units, err := ExpandStack(filepath.Join(dir, "live", DefaultStackFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := filepath.Join(dir, "live", stackDirName, "services")
+	want := []StackUnit{
+		{
+			Name:      "api",
+			StackFile: filepath.Join(services, DefaultStackFilename),
+			Source:    filepath.Join(dir, "units", "app"),
+			Path:      filepath.Join(services, stackDirName, "api"),
+			Values:    cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("api"), "env": cty.StringVal("prod")}),
+		},
+		{
+			Name:      "queue",
+			StackFile: filepath.Join(services, stackDirName, "workers", DefaultStackFilename),
+			Source:    filepath.Join(dir, "units", "app"),
+			Path:      filepath.Join(services, stackDirName, "workers", stackDirName, "queue"),
+			Values:    cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("queue-prod"), "env": cty.StringVal("prod")}),
+		},
+		{
+			Name:      "vpc",
+			StackFile: filepath.Join(dir, "live", DefaultStackFilename),
+			Source:    filepath.Join(dir, "units", "vpc"),
+			Path:      filepath.Join(dir, "live", stackDirName, "vpc"),
+			Values:    cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod"), "cidr": cty.StringVal("10.0.0.0/16")}),
+		},
+		{
+			Name:      "legacy",
+			StackFile: filepath.Join(dir, "live", DefaultStackFilename),
+			Source:    filepath.Join(dir, "units", "vpc"),
+			Path:      filepath.Join(dir, "live", "legacy"),
+			Values:    cty.ObjectVal(map[string]cty.Value{"env": cty.StringVal("prod"), "cidr": cty.StringVal("10.1.0.0/16")}),
+		},
+	}
+	if len(units) != len(want) {
+		t.Fatalf("got %d units, want %d", len(units), len(want))
+	}
+	for i, unit := range units {
+		if unit.Name != want[i].Name || unit.StackFile != want[i].StackFile || unit.Source != want[i].Source || unit.Path != want[i].Path || !unit.Values.RawEquals(want[i].Values) {
+			t.Errorf("got unit %+v, want %+v", *unit, want[i])
+		}
+	}
+}
+
+func TestExpandStackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			name: "duplicate unit blocks",
+			src:  "unit \"vpc\" {\n  source = \"../units/vpc\"\n  path = \"vpc\"\n}\nunit \"vpc\" {\n  source = \"../units/vpc\"\n  path = \"other\"\n}\n",
+			err:  `duplicate unit block "vpc"`,
+		},
+		{
+			name: "stack including itself",
+			src:  "stack \"self\" {\n  source = \".\"\n  path = \"self\"\n}\n",
+			err:  "the stack includes itself",
+		},
+		{
+			name: "remote source not generated",
+			src:  "unit \"vpc\" {\n  source = \"git::https://github.com/org/units.git//vpc?ref=v1.0.0\"\n  path = \"vpc\"\n}\n",
+			err:  `unit "vpc": the git source git::https://github.com/org/units.git//vpc?ref=v1.0.0 is not generated, run terragrunt stack generate first`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{DefaultStackFilename: test.src})
+			_, err := ExpandStack(filepath.Join(dir, DefaultStackFilename))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestParseStackWithStackFile(t *testing.T) {
+	dir := writeFiles(t, stackFileFixture)
+	stack, err := ParseStack(filepath.Join(dir, "live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := filepath.Join(dir, "live", stackDirName, "services")
+	want := map[string]struct {
+		inputs     map[string]cty.Value
+		stackFiles []string
+	}{
+		filepath.Join(services, stackDirName, "api"): {
+			inputs:     map[string]cty.Value{"name": cty.StringVal("api"), "env": cty.StringVal("prod")},
+			stackFiles: []string{"live", "stacks/services"},
+		},
+		filepath.Join(services, stackDirName, "workers", stackDirName, "queue"): {
+			inputs:     map[string]cty.Value{"name": cty.StringVal("queue-prod"), "env": cty.StringVal("prod")},
+			stackFiles: []string{"live", "stacks/services", "stacks/workers"},
+		},
+		filepath.Join(dir, "live", stackDirName, "vpc"): {
+			inputs:     map[string]cty.Value{"cidr": cty.StringVal("10.0.0.0/16"), "env": cty.StringVal("prod")},
+			stackFiles: []string{"live"},
+		},
+		filepath.Join(dir, "live", "legacy"): {
+			inputs:     map[string]cty.Value{"cidr": cty.StringVal("10.1.0.0/16"), "env": cty.StringVal("prod")},
+			stackFiles: []string{"live"},
+		},
+	}
+	if len(stack.Units) != len(want) {
+		t.Fatalf("got %d units, want %d", len(stack.Units), len(want))
+	}
+	for _, unit := range stack.Units {
+		want, found := want[unit.Path]
+		if !found {
+			t.Errorf("got unexpected unit %s", unit.Path)
+			continue
+		}
+		if unit.Err != nil {
+			t.Errorf("%s: %s", unit.Path, unit.Err)
+			continue
+		}
+		if unit.StackUnit == nil || unit.ConfigPath != filepath.Join(unit.Path, DefaultConfigFilename) {
+			t.Errorf("got unit %s at %s from %v, want it generated from its stack file", unit.Path, unit.ConfigPath, unit.StackUnit)
+		}
+		for name, value := range want.inputs {
+			if got := unit.Config.InputsCty[name]; !got.RawEquals(value) {
+				t.Errorf("%s: got input %s %#v, want %#v", unit.Path, name, got, value)
+			}
+		}
+
+		// The unit depends on the stack files it is expanded from, so that they are watched and fingerprinted.
+		files := map[string]bool{}
+		for _, path := range unit.Files {
+			files[path] = true
+		}
+		for _, stackDir := range want.stackFiles {
+			if stackFile := filepath.Join(dir, filepath.FromSlash(stackDir), DefaultStackFilename); !files[stackFile] {
+				t.Errorf("%s: got files %v, want %s among them", unit.Path, unit.Files, stackFile)
+			}
+		}
+	}
+}
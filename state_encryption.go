@@ -0,0 +1,277 @@
+package terragrunt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// StateEncryption holds the keys to decrypt the states encrypted by OpenTofu, with the aes_gcm method and a pbkdf2 or
+// aws_kms key provider. The settings the keys are derived with (salt, iterations, encrypted data key, ...) are read
+// from the metadata of the encrypted state.
+type StateEncryption struct {
+	// Passphrase is the passphrase of the pbkdf2 key provider.
+	Passphrase string
+
+	// KMS decrypts the data key of the aws_kms key provider.
+	KMS KMSDecrypter
+}
+
+// KMSDecrypter decrypts data keys encrypted with an AWS KMS key. KMSClient implements it with kms:Decrypt.
+type KMSDecrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte, keyID string) ([]byte, error)
+}
+
+// encryptedState is the format of the states encrypted by OpenTofu. The metadata of the key providers are keyed by
+// key_provider.<type>.<name>.
+type encryptedState struct {
+	Meta              map[string][]byte `json:"meta"`
+	EncryptedData     []byte            `json:"encrypted_data"`
+	EncryptionVersion string            `json:"encryption_version"`
+}
+
+// pbkdf2Metadata is the metadata of the pbkdf2 key provider.
+type pbkdf2Metadata struct {
+	Salt         []byte `json:"salt"`
+	Iterations   int    `json:"iterations"`
+	HashFunction string `json:"hash_function"`
+	KeyLength    int    `json:"key_length"`
+}
+
+// maxPBKDF2Iterations is the most iterations a pbkdf2 key is derived with, well above the default of OpenTofu (600000),
+// so that the settings of a state can not stall its decryption.
+const maxPBKDF2Iterations = 10000000
+
+// awsKMSMetadata is the metadata of the aws_kms key provider.
+type awsKMSMetadata struct {
+	CiphertextBlob []byte `json:"ciphertext_blob"`
+}
+
+// Decrypt returns the plain state of the given state, decrypting it when it was encrypted by OpenTofu. Plain states
+// are returned as is. A nil StateEncryption can only return plain states.
+func (encryption *StateEncryption) Decrypt(ctx context.Context, state []byte) ([]byte, error) {
+	var encrypted encryptedState
+	if err := json.Unmarshal(state, &encrypted); err != nil || encrypted.EncryptedData == nil {
+		return state, nil
+	}
+	if encrypted.EncryptionVersion != "v0" {
+		return nil, fmt.Errorf("unsupported state encryption version %q", encrypted.EncryptionVersion)
+	}
+	if encryption == nil {
+		return nil, fmt.Errorf("the state is encrypted, but no encryption is configured")
+	}
+
+	key, err := encryption.key(ctx, encrypted.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return decryptAESGCM(key, encrypted.EncryptedData)
+}
+
+// key returns the key of the state from the metadata of its key provider.
+func (encryption *StateEncryption) key(ctx context.Context, meta map[string][]byte) ([]byte, error) {
+	if len(meta) != 1 {
+		return nil, fmt.Errorf("expected the metadata of a single key provider in the encrypted state, got %d", len(meta))
+	}
+	for address, metadata := range meta {
+		parts := strings.Split(address, ".")
+		if len(parts) != 3 || parts[0] != "key_provider" {
+			return nil, fmt.Errorf("invalid key provider %q in the encrypted state", address)
+		}
+
+		switch parts[1] {
+		case "pbkdf2":
+			var settings pbkdf2Metadata
+			if err := json.Unmarshal(metadata, &settings); err != nil {
+				return nil, fmt.Errorf("invalid metadata of %s: %w", address, err)
+			}
+			if encryption.Passphrase == "" {
+				return nil, fmt.Errorf("the state is encrypted with %s, but no passphrase is configured", address)
+			}
+			var newHash func() hash.Hash
+			switch settings.HashFunction {
+			case "sha256":
+				newHash = sha256.New
+			case "sha512":
+				newHash = sha512.New
+			default:
+				return nil, fmt.Errorf("unsupported hash function %q of %s", settings.HashFunction, address)
+			}
+			// The settings are read from the state itself, and are checked before deriving the key with them.
+			if settings.KeyLength != 16 && settings.KeyLength != 24 && settings.KeyLength != 32 {
+				return nil, fmt.Errorf("invalid key length %d of %s, want 16, 24 or 32", settings.KeyLength, address)
+			}
+			if settings.Iterations <= 0 || settings.Iterations > maxPBKDF2Iterations {
+				return nil, fmt.Errorf("invalid iterations %d of %s, want between 1 and %d", settings.Iterations, address, maxPBKDF2Iterations)
+			}
+			return pbkdf2Key([]byte(encryption.Passphrase), settings.Salt, settings.Iterations, settings.KeyLength, newHash), nil
+
+		case "aws_kms":
+			var settings awsKMSMetadata
+			if err := json.Unmarshal(metadata, &settings); err != nil {
+				return nil, fmt.Errorf("invalid metadata of %s: %w", address, err)
+			}
+			if encryption.KMS == nil {
+				return nil, fmt.Errorf("the state is encrypted with %s, but no KMS client is configured", address)
+			}
+			key, err := encryption.KMS.Decrypt(ctx, settings.CiphertextBlob, "")
+			if err != nil {
+				return nil, fmt.Errorf("decrypting the key of %s: %w", address, err)
+			}
+			return key, nil
+
+		default:
+			return nil, fmt.Errorf("unsupported key provider %s", parts[1])
+		}
+	}
+	return nil, nil
+}
+
+// decryptAESGCM decrypts the given data, encrypted by the aes_gcm method: the nonce, followed by the sealed data.
+func decryptAESGCM(key []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state encryption key: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("the encrypted state is truncated")
+	}
+	state, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting the state, the key is likely wrong: %w", err)
+	}
+	return state, nil
+}
+
+// pbkdf2Key derives a key of the given length from the given password with PBKDF2 (RFC 8018).
+func pbkdf2Key(password []byte, salt []byte, iterations int, keyLength int, newHash func() hash.Hash) []byte {
+	prf := hmac.New(newHash, password)
+	hashLength := prf.Size()
+	blocks := (keyLength + hashLength - 1) / hashLength
+
+	key := make([]byte, 0, blocks*hashLength)
+	u := make([]byte, hashLength)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		var blockIndex [4]byte
+		binary.BigEndian.PutUint32(blockIndex[:], uint32(block))
+		prf.Write(blockIndex[:])
+		u = prf.Sum(u[:0])
+
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLength]
+}
+
+// newStateEncryption returns the StateEncryption configured by the encryption attribute of the given remote_state
+// block, with the passphrase and the KMS client of the given overrides, when set. The KMS client defaults to a
+// KMSClient in the region of the encryption, signing requests with the given credentials. It returns nil when there is
+// neither an encryption attribute nor overrides.
+func newStateEncryption(remoteState *RemoteState, overrides *StateEncryption, credentials AWSCredentialsProvider) *StateEncryption {
+	if (remoteState == nil || remoteState.Encryption == nil) && overrides == nil {
+		return nil
+	}
+
+	encryption := &StateEncryption{}
+	if remoteState != nil && remoteState.Encryption != nil {
+		settings, _ := remoteState.Encryption.UnmarkDeep()
+		if settings.IsKnown() && !settings.IsNull() && settings.CanIterateElements() {
+			attributes := settings.AsValueMap()
+			encryption.Passphrase = backendString(attributes, "passphrase")
+			if backendString(attributes, "key_provider") == "aws_kms" {
+				encryption.KMS = KMSClient{Credentials: credentials, Region: backendString(attributes, "region")}
+			}
+		}
+	}
+	if overrides != nil {
+		if overrides.Passphrase != "" {
+			encryption.Passphrase = overrides.Passphrase
+		}
+		if overrides.KMS != nil {
+			encryption.KMS = overrides.KMS
+		}
+	}
+	return encryption
+}
+
+// redactPassphrase returns the given encryption attribute of a remote_state block with its passphrase marked as
+// sensitive, so that it is redacted.
+func redactPassphrase(encryption cty.Value) cty.Value {
+	unmarked, marks := encryption.Unmark()
+	if !unmarked.IsKnown() || unmarked.IsNull() || !unmarked.Type().IsObjectType() || !unmarked.Type().HasAttribute("passphrase") {
+		return encryption
+	}
+	attributes := unmarked.AsValueMap()
+	attributes["passphrase"] = attributes["passphrase"].Mark(SensitiveMark)
+	return cty.ObjectVal(attributes).WithMarks(marks)
+}
+
+// KMSClient calls the AWS KMS API, signing requests with the given credentials. It is a KMSDecrypter.
+type KMSClient struct {
+	Credentials AWSCredentialsProvider
+
+	// Region is the region of the KMS key. Defaults to us-east-1.
+	Region string
+
+	// HTTPClient is the client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Retry is the policy failed requests are retried with. Defaults to DefaultRetryPolicy.
+	Retry *RetryPolicy
+}
+
+// Decrypt decrypts the given ciphertext with kms:Decrypt. The key ID is only needed for asymmetric keys.
+func (client KMSClient) Decrypt(ctx context.Context, ciphertext []byte, keyID string) ([]byte, error) {
+	input := struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		KeyID          string `json:"KeyId,omitempty"`
+	}{CiphertextBlob: ciphertext, KeyID: keyID}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err = client.Retry.Do(ctx, func() error {
+		return client.call(ctx, "TrentService.Decrypt", body, &output)
+	})
+	return output.Plaintext, err
+}
+
+// call sends a signed request for the given KMS action, and decodes the json response into out.
+func (client KMSClient) call(ctx context.Context, target string, body []byte, out interface{}) error {
+	return callAWSJSON(ctx, awsJSONRequest{
+		Service:     "kms",
+		Region:      client.Region,
+		Target:      target,
+		Credentials: client.Credentials,
+		HTTPClient:  client.HTTPClient,
+	}, body, out)
+}
@@ -0,0 +1,201 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+	"testing"
+)
+
+// TestPBKDF2Key checks the derived keys against the test vectors of RFC 6070 (sha1) and RFC 7914 (sha256).
+func TestPBKDF2Key(t *testing.T) {
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLength  int
+		newHash    func() hash.Hash
+		want       string
+	}{
+		{password: "password", salt: "salt", iterations: 1, keyLength: 20, newHash: sha1.New, want: "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{password: "password", salt: "salt", iterations: 2, keyLength: 20, newHash: sha1.New, want: "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{password: "password", salt: "salt", iterations: 4096, keyLength: 20, newHash: sha1.New, want: "4b007901b765489abead49d926f721d065a429c1"},
+		{
+			password:   "passwordPASSWORDpassword",
+			salt:       "saltSALTsaltSALTsaltSALTsaltSALTsalt",
+			iterations: 4096,
+			keyLength:  25,
+			newHash:    sha1.New,
+			want:       "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038",
+		},
+		{
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			keyLength:  64,
+			newHash:    sha256.New,
+			want:       "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+	}
+
+	for _, test := range tests {
+		got := hex.EncodeToString(pbkdf2Key([]byte(test.password), []byte(test.salt), test.iterations, test.keyLength, test.newHash))
+		if got != test.want {
+			t.Errorf("pbkdf2Key(%q, %q, %d, %d): got %s, want %s", test.password, test.salt, test.iterations, test.keyLength, got, test.want)
+		}
+	}
+}
+
+func TestStateEncryptionDecrypt(t *testing.T) {
+	plain := []byte(`{"version": 4, "outputs": {}}`)
+	pbkdf2Settings := pbkdf2Metadata{Salt: []byte("0123456789abcdef"), Iterations: 1000, HashFunction: "sha512", KeyLength: 32}
+	passphraseKey := pbkdf2Key([]byte("correct horse battery staple"), pbkdf2Settings.Salt, pbkdf2Settings.Iterations, pbkdf2Settings.KeyLength, sha512.New)
+	kmsKey := bytes.Repeat([]byte{7}, 32)
+	truncated := encryptedState{
+		Meta:              map[string][]byte{"key_provider.aws_kms.main": mustMarshalJSON(t, awsKMSMetadata{CiphertextBlob: []byte("encrypted key")})},
+		EncryptedData:     []byte{1, 2, 3, 4},
+		EncryptionVersion: "v0",
+	}
+
+	tests := []struct {
+		name       string
+		encryption *StateEncryption
+		state      []byte
+		err        string
+	}{
+		{
+			name:  "plain state",
+			state: plain,
+		},
+		{
+			name:       "pbkdf2",
+			encryption: &StateEncryption{Passphrase: "correct horse battery staple"},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Settings, passphraseKey, plain),
+		},
+		{
+			name:       "aws_kms",
+			encryption: &StateEncryption{KMS: fakeKMS{"encrypted key": kmsKey}},
+			state:      encryptTestState(t, "key_provider.aws_kms.main", awsKMSMetadata{CiphertextBlob: []byte("encrypted key")}, kmsKey, plain),
+		},
+		{
+			name:       "wrong passphrase",
+			encryption: &StateEncryption{Passphrase: "wrong"},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Settings, passphraseKey, plain),
+			err:        "the key is likely wrong",
+		},
+		{
+			name:       "no passphrase",
+			encryption: &StateEncryption{},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Settings, passphraseKey, plain),
+			err:        "no passphrase is configured",
+		},
+		{
+			name:  "no encryption",
+			state: encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Settings, passphraseKey, plain),
+			err:   "no encryption is configured",
+		},
+		{
+			name:       "unsupported hash function",
+			encryption: &StateEncryption{Passphrase: "correct horse battery staple"},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Metadata{HashFunction: "md5"}, passphraseKey, plain),
+			err:        `unsupported hash function "md5"`,
+		},
+		{
+			name:       "invalid key length",
+			encryption: &StateEncryption{Passphrase: "correct horse battery staple"},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Metadata{Iterations: 1000, HashFunction: "sha512", KeyLength: -1}, passphraseKey, plain),
+			err:        "invalid key length -1",
+		},
+		{
+			name:       "too many iterations",
+			encryption: &StateEncryption{Passphrase: "correct horse battery staple"},
+			state:      encryptTestState(t, "key_provider.pbkdf2.main", pbkdf2Metadata{Iterations: 1 << 30, HashFunction: "sha512", KeyLength: 32}, passphraseKey, plain),
+			err:        "invalid iterations 1073741824",
+		},
+		{
+			name:       "kms error",
+			encryption: &StateEncryption{KMS: fakeKMS{}},
+			state:      encryptTestState(t, "key_provider.aws_kms.main", awsKMSMetadata{CiphertextBlob: []byte("other key")}, kmsKey, plain),
+			err:        "decrypting the key of key_provider.aws_kms.main",
+		},
+		{
+			name:       "unsupported version",
+			encryption: &StateEncryption{Passphrase: "correct horse battery staple"},
+			state:      []byte(`{"meta": {}, "encrypted_data": "AAAA", "encryption_version": "v1"}`),
+			err:        `unsupported state encryption version "v1"`,
+		},
+		{
+			name:       "truncated",
+			encryption: &StateEncryption{KMS: fakeKMS{"encrypted key": kmsKey}},
+			state:      mustMarshalJSON(t, truncated),
+			err:        "the encrypted state is truncated",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.encryption.Decrypt(context.Background(), test.state)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("got state %s, want %s", got, plain)
+			}
+		})
+	}
+}
+
+// encryptTestState returns the given state encrypted with the aes_gcm method and the given key, in the format of
+// OpenTofu, with the given metadata of the key provider.
+func encryptTestState(t *testing.T, keyProvider string, metadata interface{}, key []byte, state []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{1}, gcm.NonceSize())
+	return mustMarshalJSON(t, encryptedState{
+		Meta:              map[string][]byte{keyProvider: mustMarshalJSON(t, metadata)},
+		EncryptedData:     gcm.Seal(nonce, nonce, state, nil),
+		EncryptionVersion: "v0",
+	})
+}
+
+// fakeKMS is a KMSDecrypter returning the data keys of the ciphertexts it holds.
+type fakeKMS map[string][]byte
+
+func (kms fakeKMS) Decrypt(ctx context.Context, ciphertext []byte, keyID string) ([]byte, error) {
+	key, found := kms[string(ciphertext)]
+	if !found {
+		return nil, errors.New("AccessDeniedException")
+	}
+	return key, nil
+}
+
+func mustMarshalJSON(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
@@ -0,0 +1,208 @@
+package terragrunt
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// SymbolKind is the kind of a Symbol.
+type SymbolKind string
+
+const (
+	SymbolBlock     SymbolKind = "block"
+	SymbolAttribute SymbolKind = "attribute"
+	SymbolVariable  SymbolKind = "variable"
+	SymbolFunction  SymbolKind = "function"
+)
+
+// Symbol is the element of a configuration under a position, for editor tooling.
+type Symbol struct {
+	Kind SymbolKind
+
+	// Name is the name of the symbol: the type and labels of blocks joined with dots (e.g. dependency.vpc), the name of
+	// attributes and functions, or the rendered traversal of variables (e.g. dependency.vpc.outputs.vpc_id).
+	Name string
+
+	// Path is the path of the attribute or block the symbol is part of, as in AttributeReferences (e.g.
+	// dependency.vpc.config_path or locals.env).
+	Path string
+
+	// Range is the range of the symbol: the header of blocks, the whole attribute, the traversal of variables or the
+	// name of functions.
+	Range hcl.Range
+
+	// Traversal is the traversal of variables.
+	Traversal hcl.Traversal
+}
+
+// SymbolAt returns the innermost symbol of the given configuration content under the given position, or nil when
+// there is none. The position is given either by its line and column (both starting at 1, with columns counted in
+// characters), or by its byte offset when its line is zero. Use WithConfigPath to set the filename of the ranges.
+func SymbolAt(content []byte, pos hcl.Pos, opts ...Option) (*Symbol, error) {
+	parseOptions := newParseOptions(opts)
+
+	file, diags := hclparse.NewParser().ParseHCL(content, parseOptions.ConfigPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return symbolAt(file.Body.(*hclsyntax.Body), "", positionOffset(content, pos)), nil
+}
+
+// positionOffset returns the byte offset of the given position in the content.
+func positionOffset(content []byte, pos hcl.Pos) int {
+	if pos.Line == 0 {
+		return pos.Byte
+	}
+
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		end := bytes.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			return len(content)
+		}
+		offset += end + 1
+	}
+	for column := 1; column < pos.Column && offset < len(content) && content[offset] != '\n'; column++ {
+		_, size := utf8.DecodeRune(content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func symbolAt(body *hclsyntax.Body, prefix string, offset int) *Symbol {
+	for name, attribute := range body.Attributes {
+		if attribute.SrcRange.ContainsOffset(offset) {
+			return expressionSymbolAt(attribute, prefix+name, offset)
+		}
+	}
+
+	for _, block := range body.Blocks {
+		if !block.Range().ContainsOffset(offset) {
+			continue
+		}
+
+		name := strings.Join(append([]string{block.Type}, block.Labels...), ".")
+		if symbol := symbolAt(block.Body, prefix+name+".", offset); symbol != nil {
+			return symbol
+		}
+		return &Symbol{Kind: SymbolBlock, Name: name, Path: prefix + name, Range: block.DefRange()}
+	}
+	return nil
+}
+
+// expressionSymbolAt returns the variable or function of the expression of the given attribute under the offset, or
+// the attribute itself.
+func expressionSymbolAt(attribute *hclsyntax.Attribute, path string, offset int) *Symbol {
+	for _, traversal := range attribute.Expr.Variables() {
+		if traversal.SourceRange().ContainsOffset(offset) {
+			return &Symbol{
+				Kind:      SymbolVariable,
+				Name:      formatTraversal(traversal),
+				Path:      path,
+				Range:     traversal.SourceRange(),
+				Traversal: traversal,
+			}
+		}
+	}
+
+	var function *Symbol
+	hclsyntax.VisitAll(attribute.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if call, isCall := node.(*hclsyntax.FunctionCallExpr); isCall && call.NameRange.ContainsOffset(offset) {
+			function = &Symbol{Kind: SymbolFunction, Name: call.Name, Path: path, Range: call.NameRange}
+		}
+		return nil
+	})
+	if function != nil {
+		return function
+	}
+
+	return &Symbol{Kind: SymbolAttribute, Name: attribute.Name, Path: path, Range: attribute.SrcRange}
+}
+
+// Definition returns where the symbol of the given configuration content under the given position is defined: local
+// and include references jump to their declaration, and dependency references, dependency blocks and their
+// config_path to the configuration of the dependency unit. It returns false when the symbol has no definition. Use
+// WithConfigPath to set the path of the configuration, which dependency paths are resolved against.
+func Definition(content []byte, pos hcl.Pos, opts ...Option) (hcl.Range, bool, error) {
+	parseOptions := newParseOptions(opts)
+
+	file, diags := hclparse.NewParser().ParseHCL(content, parseOptions.ConfigPath)
+	if diags.HasErrors() {
+		return hcl.Range{}, false, diags
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	symbol := symbolAt(body, "", positionOffset(content, pos))
+	if symbol == nil {
+		return hcl.Range{}, false, nil
+	}
+
+	var root, name string
+	switch symbol.Kind {
+	case SymbolVariable:
+		root = symbol.Traversal.RootName()
+		if len(symbol.Traversal) < 2 {
+			return hcl.Range{}, false, nil
+		}
+		name, _ = traversalStepName(symbol.Traversal[1])
+	case SymbolBlock, SymbolAttribute:
+		// Dependency blocks, and their config_path attribute, point to the dependency unit.
+		parts := strings.SplitN(symbol.Path, ".", 3)
+		if parts[0] != "dependency" || len(parts) < 2 || (len(parts) == 3 && parts[2] != "config_path") {
+			return hcl.Range{}, false, nil
+		}
+		root, name = "dependency", parts[1]
+	default:
+		return hcl.Range{}, false, nil
+	}
+
+	switch root {
+	case "local":
+		for _, block := range body.Blocks {
+			if attribute, found := block.Body.Attributes[name]; block.Type == "locals" && found {
+				return attribute.SrcRange, true, nil
+			}
+		}
+	case "include":
+		for _, block := range body.Blocks {
+			if block.Type == "include" && len(block.Labels) > 0 && block.Labels[0] == name {
+				return block.DefRange(), true, nil
+			}
+		}
+	case "dependency":
+		return dependencyDefinition(content, body, name, opts)
+	}
+	return hcl.Range{}, false, nil
+}
+
+// dependencyDefinition returns the start of the configuration of the unit targeted by the dependency block with the
+// given name, or the dependency block itself when its config_path can not be evaluated.
+func dependencyDefinition(content []byte, body *hclsyntax.Body, name string, opts []Option) (hcl.Range, bool, error) {
+	parseOptions := newParseOptions(opts)
+	if dependencies, err := parseDependencyBlocks(content, opts); err == nil {
+		for _, dependency := range dependencies {
+			if dependency.Name != name {
+				continue
+			}
+
+			configPath := dependencyConfigPath(dependency, parseOptions)
+			if filepath.Ext(configPath) != ".hcl" {
+				configPath = filepath.Join(configPath, DefaultConfigFilename)
+			}
+			return hcl.Range{Filename: configPath, Start: hcl.InitialPos, End: hcl.InitialPos}, true, nil
+		}
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type == "dependency" && len(block.Labels) > 0 && block.Labels[0] == name {
+			return block.DefRange(), true, nil
+		}
+	}
+	return hcl.Range{}, false, nil
+}
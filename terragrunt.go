@@ -1,15 +1,20 @@
 package terragrunt
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 )
 
+// filename is the name used in diagnostics when the path of the configuration being parsed is unknown.
 const filename = "tmp.hcl"
 
 // terragruntConfigFile represents the configuration supported in a Terragrunt configuration file
@@ -17,25 +22,69 @@ const filename = "tmp.hcl"
 type TerragruntConfigFile struct {
 	Terraform              *TerraformConfig          `hcl:"terraform,block"`
 	TerraformBinary        *string                   `hcl:"terraform_binary,attr"`
+	Skip                   *bool                     `hcl:"skip,attr"`
+	PreventDestroy         *bool                     `hcl:"prevent_destroy,attr"`
+	IamRole                *string                   `hcl:"iam_role,attr"`
+	IamAssumeRoleDuration  *int64                    `hcl:"iam_assume_role_duration,attr"`
+	IamAssumeRoleSession   *string                   `hcl:"iam_assume_role_session_name,attr"`
+	RemoteState            *RemoteState              `hcl:"remote_state,block"`
 	Inputs                 *cty.Value                `hcl:"inputs,attr"`
 	TerragruntDependencies []Dependency              `hcl:"dependency,block"`
+	GenerateBlocks         []GenerateConfig          `hcl:"generate,block"`
+	FeatureFlags           []FeatureFlag             `hcl:"feature,block"`
+	Errors                 *ErrorsConfig             `hcl:"errors,block"`
+	Engine                 *EngineConfig             `hcl:"engine,block"`
+	Exclude                *ExcludeConfig            `hcl:"exclude,block"`
+	Catalog                *CatalogConfig            `hcl:"catalog,block"`
 	Include                []terragruntIncludeIgnore `hcl:"include,block"`
 }
 
 type TerraformConfig struct {
-	Source *string `hcl:"source,attr"`
+	Source         *string                   `hcl:"source,attr"`
+	ExtraArguments []TerraformExtraArguments `hcl:"extra_arguments,block"`
+}
+
+// TerraformExtraArguments is an extra_arguments block, passing extra arguments, var files and environment variables
+// to the terraform commands it lists.
+type TerraformExtraArguments struct {
+	Name             string             `hcl:",label"`
+	Commands         []string           `hcl:"commands,attr"`
+	Arguments        *[]string          `hcl:"arguments,attr"`
+	RequiredVarFiles *[]string          `hcl:"required_var_files,attr"`
+	OptionalVarFiles *[]string          `hcl:"optional_var_files,attr"`
+	EnvVars          *map[string]string `hcl:"env_vars,attr"`
 }
 
 type Dependency struct {
 	Name                                string     `hcl:",label" cty:"name"`
 	ConfigPath                          string     `hcl:"config_path,attr" cty:"config_path"`
+	Enabled                             *bool      `hcl:"enabled,attr" cty:"enabled"`
 	SkipOutputs                         *bool      `hcl:"skip_outputs,attr" cty:"skip"`
 	MockOutputs                         *cty.Value `hcl:"mock_outputs,attr" cty:"mock_outputs"`
 	MockOutputsAllowedTerraformCommands *[]string  `hcl:"mock_outputs_allowed_terraform_commands,attr" cty:"mock_outputs_allowed_terraform_commands"`
 	MockOutputsMergeWithState           *bool      `hcl:"mock_outputs_merge_with_state,attr" cty:"mock_outputs_merge_with_state"`
+	MockOutputsMergeStrategyWithState   *string    `hcl:"mock_outputs_merge_strategy_with_state,attr" cty:"mock_outputs_merge_strategy_with_state"`
 	RenderedOutputs                     *cty.Value `cty:"outputs"`
 }
 
+// IsEnabled returns whether the dependency is enabled. Disabled dependencies (enabled = false) have no outputs, and are
+// not part of the dependency graph.
+func (dependency Dependency) IsEnabled() bool {
+	return dependency.Enabled == nil || *dependency.Enabled
+}
+
+// GenerateConfig is a generate block, describing a file terragrunt generates in the terraform working directory.
+type GenerateConfig struct {
+	Name             string  `hcl:",label"`
+	Path             string  `hcl:"path,attr"`
+	IfExists         string  `hcl:"if_exists,attr"`
+	IfDisabled       *string `hcl:"if_disabled,attr"`
+	CommentPrefix    *string `hcl:"comment_prefix,attr"`
+	DisableSignature *bool   `hcl:"disable_signature,attr"`
+	Contents         string  `hcl:"contents,attr"`
+	Disable          *bool   `hcl:"disable,attr"`
+}
+
 type terragruntIncludeIgnore struct {
 	Name   string   `hcl:"name,label"`
 	Remain hcl.Body `hcl:",remain"`
@@ -45,12 +94,107 @@ type terragruntIncludeIgnore struct {
 type TerragruntConfig struct {
 	Terraform              *TerraformConfig
 	TerraformBinary        string
+	Skip                   bool
+	PreventDestroy         bool
+	RemoteState            *RemoteState
 	Inputs                 map[string]interface{}
 	TerragruntDependencies []Dependency
+	GenerateConfigs        map[string]GenerateConfig
+
+	// IamRole is the ARN of the IAM role to assume for the unit, with the duration of its credentials, in seconds,
+	// and the name of its session. The duration and the session name are zero when not set.
+	IamRole                  string
+	IamAssumeRoleDuration    int64
+	IamAssumeRoleSessionName string
+
+	// FeatureFlags are the values of the feature flags declared by the feature blocks, keyed by name: the value they
+	// are overridden with (see WithFeatureFlags), or else their default.
+	FeatureFlags map[string]cty.Value
+
+	// Errors declares how the errors of the terraform commands are retried or ignored.
+	Errors *ErrorsConfig
+
+	// Engine is the IaC engine the terraform commands are run with, if any.
+	Engine *EngineConfig
+
+	// Exclude excludes the unit from the run-all commands running some actions, when its condition holds.
+	Exclude *ExcludeConfig
+
+	// Catalog lists the repositories of modules units can be scaffolded from (see SourceFetcher.CatalogModules).
+	Catalog *CatalogConfig
+
+	// UnknownBlocks holds the blocks of types this package does not model, in the order of the configuration, so
+	// that tools can handle terragrunt features not supported here. Their body is not evaluated.
+	UnknownBlocks []UnknownBlock
+
+	// Warnings are the issues found while parsing the configuration that do not make it invalid, such as attributes
+	// set by several included configurations (see RuleIncludeOverlap).
+	Warnings []Finding
+
+	// InputsCty holds the same inputs as Inputs, but as the evaluated cty values, so that type information (e.g.
+	// numbers vs strings, object attribute types) and marks are preserved.
+	InputsCty map[string]cty.Value
+
+	// provenance maps the path of each attribute to the range setting it. See Provenance.
+	provenance map[string]hcl.Range
+
+	// evalContext is the context the configuration was evaluated in, along with its includes, and evalOptions the
+	// options it was parsed with, to resolve the outputs of the dependencies it does not reference. See Eval.
+	evalContext *hcl.EvalContext
+	evalOptions *ParseOptions
+}
+
+// ParseConfig parses the given terragrunt configuration content, evaluating it with the given options. The
+// configurations included by its include blocks are parsed too, and merged into it: see mergeIncludedConfigs.
+func ParseConfig(content []byte, opts ...Option) (*TerragruntConfig, error) {
+	parseOptions := newParseOptions(opts)
+	start := time.Now()
+	config, err := parseConfig(content, parseOptions)
+	observeDuration(parseOptions.Metrics, MetricParseDuration, start, nil)
+	if err != nil {
+		parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+	}
+	return config, err
 }
 
-func ParseConfig(content []byte) (*TerragruntConfig, error) {
-	file, err := parseHCL(content)
+func parseConfig(content []byte, parseOptions *ParseOptions) (*TerragruntConfig, error) {
+	file, err := parseOptions.parseHCL(content, parseOptions.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	parseOptions.Logger.Log(EventFileParsed, "filename", parseOptions.ConfigPath, "size", len(content))
+	parseOptions.Metrics.IncCounter(MetricFilesParsed, nil)
+	parseOptions.includes = &includePaths{body: file.Body}
+	if err := checkDuplicateBlocks(file.Body); err != nil {
+		return nil, err
+	}
+	if err := checkNestedIncludes(file.Body, parseOptions); err != nil {
+		return nil, err
+	}
+
+	if err := exposeUnitValues(parseOptions); err != nil {
+		return nil, err
+	}
+
+	// The locals are evaluated first, as any other block can reference them.
+	locals, remain, err := decodeAndEvaluateLocals(file.Body, parseOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// The role to assume is decoded next, evaluating the locals again when they need its identity.
+	reevaluateLocals, err := decodeIAMRole(remain, parseOptions, EvalContextExtensions{Locals: locals})
+	if err != nil {
+		return nil, err
+	}
+	if reevaluateLocals {
+		if locals, remain, err = decodeAndEvaluateLocals(file.Body, parseOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// The feature flags are decoded next, as the rest of the configuration can reference them.
+	featureFlags, remain, err := decodeFeatureFlags(remain, parseOptions, EvalContextExtensions{Locals: locals})
 	if err != nil {
 		return nil, err
 	}
@@ -58,47 +202,102 @@ func ParseConfig(content []byte) (*TerragruntConfig, error) {
 	// Initialize evaluation context extensions from base blocks.
 	contextExtensions := EvalContextExtensions{
 		DecodedDependencies: nil,
+		Locals:              locals,
+		FeatureFlags:        featureFlagsValue(featureFlags),
 	}
 
-	retrievedOutputs, err := decodeAndRetrieveOutputs(file, contextExtensions)
+	// The dependency blocks are decoded next, as their outputs are needed to evaluate the rest of the configuration.
+	// The remaining body is then decoded on its own, so that each block is only decoded once.
+	dependencies, retrievedOutputs, remain, err := decodeAndRetrieveOutputs(remain, parseOptions, contextExtensions)
 	if err != nil {
 		return nil, err
 	}
 
 	contextExtensions.DecodedDependencies = retrievedOutputs
 
-	terragruntConfigFile, err := decodeAsTerragruntConfigFile(file, contextExtensions)
+	unknownBlocks, remain, err := extractUnknownBlocks(file.Body, remain)
 	if err != nil {
 		return nil, err
 	}
 
+	terragruntConfigFile, err := decodeAsTerragruntConfigFile(remain, parseOptions, contextExtensions)
+	if err != nil {
+		return nil, err
+	}
 	if terragruntConfigFile == nil {
 		err = errors.New("no terragrunt configuration found")
 		return nil, err
 	}
+	terragruntConfigFile.TerragruntDependencies = dependencies
 
 	config, err := convertToTerragruntConfig(terragruntConfigFile)
 	if err != nil {
 		return nil, err
 	}
+	config.UnknownBlocks = unknownBlocks
+	if len(featureFlags) > 0 {
+		config.FeatureFlags = featureFlags
+	}
+	config.provenance = configProvenance(file.Body, config)
+	if config.evalContext, err = CreateTerragruntEvalContext(parseOptions, contextExtensions); err != nil {
+		return nil, err
+	}
+	config.evalOptions = parseOptions
 
-	return config, nil
+	return mergeIncludedConfigs(config, file.Body, parseOptions)
 }
 
-// parseHCL parses the HCL file content and returns a simple data structure representing the file.
-func parseHCL(content []byte) (file *hcl.File, err error) {
+// ParseConfigFile reads and parses the terragrunt configuration at the given path. Relative paths in the
+// configuration are resolved against the directory containing it.
+func ParseConfigFile(configPath string, opts ...Option) (*TerragruntConfig, error) {
+	opts = append([]Option{WithConfigPath(configPath)}, opts...)
+
+	parseOptions := newParseOptions(opts)
+	content, err := parseOptions.readFile(configPath)
+	if err != nil {
+		parseOptions.Metrics.IncCounter(MetricErrors, map[string]string{"type": ErrorType(err)})
+		return nil, err
+	}
+
+	return ParseConfig(content, opts...)
+}
+
+// parseHCL parses the HCL file content and returns a simple data structure representing the file. Bare include blocks
+// are labeled during parsing, so that the returned file can be decoded as is. CRLF line endings are read as LF, so that
+// heredocs of files edited on Windows have the same value as on other platforms.
+func parseHCL(content []byte, filename string) (file *hcl.File, err error) {
+	content = normalizeLineEndings(content)
 	parser := hclparse.NewParser()
 
 	file, parseDiagnostics := parser.ParseHCL(content, filename)
 	if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
 		return nil, parseDiagnostics
 	}
+
+	// Only go through hclwrite when there is actually a bare include block to label, as it requires parsing the
+	// content again.
+	if !hasBareIncludeBlock(file) {
+		return file, nil
+	}
+
+	updatedBytes, _, err := updateBareIncludeBlock(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Code was updated, so we need to reparse the new updated contents. This is necessarily because the blocks
+	// returned by hclparse does not support editing, and so we have to go through hclwrite, which leads to a
+	// different AST representation. A new parser is used as the previous one caches the file by name.
+	file, parseDiagnostics = hclparse.NewParser().ParseHCL(updatedBytes, filename)
+	if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
+		return nil, parseDiagnostics
+	}
 	return file, nil
 }
 
-func decodeAsTerragruntConfigFile(file *hcl.File, extensions EvalContextExtensions) (*TerragruntConfigFile, error) {
+func decodeAsTerragruntConfigFile(body hcl.Body, opts *ParseOptions, extensions EvalContextExtensions) (*TerragruntConfigFile, error) {
 	terragruntConfig := TerragruntConfigFile{}
-	err := decodeHCL(file, &terragruntConfig, extensions)
+	err := decodeHCL(body, &terragruntConfig, opts, extensions)
 	if err != nil {
 		return nil, err
 	}
@@ -106,29 +305,14 @@ func decodeAsTerragruntConfigFile(file *hcl.File, extensions EvalContextExtensio
 	return &terragruntConfig, nil
 }
 
-// decodeHCL uses the HCL parser to decode the parsed HCL into the struct specified by out.
-func decodeHCL(file *hcl.File, out interface{}, extensions EvalContextExtensions) (err error) {
-	// Check if we need to update the file to label any bare include blocks.
-	updatedBytes, isUpdated, err := updateBareIncludeBlock(file, filename)
-	if err != nil {
-		return err
-	}
-	if isUpdated {
-		// Code was updated, so we need to reparse the new updated contents. This is necessarily because the blocks
-		// returned by hclparse does not support editing, and so we have to go through hclwrite, which leads to a
-		// different AST representation.
-		file, err = parseHCL(updatedBytes)
-		if err != nil {
-			return err
-		}
-	}
-
-	evalContext, err := CreateTerragruntEvalContext(extensions)
+// decodeHCL uses the HCL parser to decode the parsed HCL body into the struct specified by out.
+func decodeHCL(body hcl.Body, out interface{}, opts *ParseOptions, extensions EvalContextExtensions) (err error) {
+	evalContext, err := CreateTerragruntEvalContext(opts, extensions)
 	if err != nil {
 		return err
 	}
 
-	decodeDiagnostics := gohcl.DecodeBody(file.Body, evalContext, out)
+	decodeDiagnostics := gohcl.DecodeBody(body, evalContext, out)
 	if decodeDiagnostics != nil && decodeDiagnostics.HasErrors() {
 		return decodeDiagnostics
 	}
@@ -141,11 +325,47 @@ func convertToTerragruntConfig(configFromFile *TerragruntConfigFile) (*Terragrun
 	terragruntConfig := &TerragruntConfig{}
 
 	terragruntConfig.Terraform = configFromFile.Terraform
+	terragruntConfig.RemoteState = configFromFile.RemoteState
 	terragruntConfig.TerragruntDependencies = configFromFile.TerragruntDependencies
+	terragruntConfig.Errors = configFromFile.Errors
+	terragruntConfig.Engine = configFromFile.Engine
+	terragruntConfig.Exclude = configFromFile.Exclude
+	terragruntConfig.Catalog = configFromFile.Catalog
 
 	if configFromFile.TerraformBinary != nil {
 		terragruntConfig.TerraformBinary = *configFromFile.TerraformBinary
 	}
+	if configFromFile.Skip != nil {
+		terragruntConfig.Skip = *configFromFile.Skip
+	}
+	if configFromFile.PreventDestroy != nil {
+		terragruntConfig.PreventDestroy = *configFromFile.PreventDestroy
+	}
+	if configFromFile.IamRole != nil {
+		terragruntConfig.IamRole = *configFromFile.IamRole
+	}
+	if configFromFile.IamAssumeRoleDuration != nil {
+		terragruntConfig.IamAssumeRoleDuration = *configFromFile.IamAssumeRoleDuration
+	}
+	if configFromFile.IamAssumeRoleSession != nil {
+		terragruntConfig.IamAssumeRoleSessionName = *configFromFile.IamAssumeRoleSession
+	}
+
+	if configFromFile.Errors != nil {
+		if err := validateErrorsConfig(configFromFile.Errors); err != nil {
+			return nil, fmt.Errorf("errors: %w", err)
+		}
+	}
+
+	if len(configFromFile.GenerateBlocks) > 0 {
+		terragruntConfig.GenerateConfigs = map[string]GenerateConfig{}
+		for _, generateBlock := range configFromFile.GenerateBlocks {
+			if _, found := terragruntConfig.GenerateConfigs[generateBlock.Name]; found {
+				return nil, fmt.Errorf("multiple generate blocks named %s", generateBlock.Name)
+			}
+			terragruntConfig.GenerateConfigs[generateBlock.Name] = generateBlock
+		}
+	}
 
 	if configFromFile.Inputs != nil {
 		inputs, err := parseCtyValueToMap(*configFromFile.Inputs)
@@ -154,11 +374,36 @@ func convertToTerragruntConfig(configFromFile *TerragruntConfigFile) (*Terragrun
 		}
 
 		terragruntConfig.Inputs = inputs
+
+		// When the inputs object as a whole is marked (e.g. computed from a sensitive value), the marks are carried
+		// over to each input.
+		inputsCty, inputsMarks := configFromFile.Inputs.Unmark()
+		if inputsCty.IsKnown() && !inputsCty.IsNull() && inputsCty.CanIterateElements() {
+			terragruntConfig.InputsCty = map[string]cty.Value{}
+			for name, value := range inputsCty.AsValueMap() {
+				terragruntConfig.InputsCty[name] = value.WithMarks(inputsMarks)
+			}
+		}
 	}
 
 	return terragruntConfig, nil
 }
 
+// hasBareIncludeBlock returns whether the parsed terragrunt contents contain an include block without a label.
+func hasBareIncludeBlock(file *hcl.File) bool {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return false
+	}
+
+	for _, block := range body.Blocks {
+		if block.Type == "include" && len(block.Labels) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // updateBareIncludeBlock searches the parsed terragrunt contents for a bare include block (include without a label),
 // and convert it to one with empty string as the label. This is necessary because the hcl parser is strictly enforces
 // label counts when parsing out labels with a go struct.
@@ -184,3 +429,11 @@ func updateBareIncludeBlock(file *hcl.File, filename string) ([]byte, bool, erro
 	}
 	return hclFile.Bytes(), codeWasUpdated, nil
 }
+
+// normalizeLineEndings returns the given content with its CRLF line endings replaced by LF.
+func normalizeLineEndings(content []byte) []byte {
+	if !bytes.Contains(content, []byte("\r\n")) {
+		return content
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
@@ -0,0 +1,21 @@
+include "root" {
+path = find_in_parent_folders("root.hcl")
+expose=true
+}
+
+locals {
+  env = "prod"
+  region   = "eu-west-1"
+  tags = {Environment=local.env, Region = local.region}
+}
+
+inputs = {
+  count       = 1+2
+  enabled= !false
+  list = [ 1,2 , 3 ]
+  nested = {
+      deep = {
+        value = "x"
+      }
+  }
+}
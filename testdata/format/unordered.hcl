@@ -0,0 +1,46 @@
+# Header comment of the unit, kept at the top.
+
+inputs = {
+  name = "vpc"
+  cidr   = local.cidr
+  zones = dependency.network.outputs.zones
+}
+
+# The module of the unit.
+terraform {
+    source = "../modules/vpc"
+}
+
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "state"
+    key="${path_relative_to_include()}/terraform.tfstate"
+  }
+}
+prevent_destroy = true
+locals {
+  cidr = "10.0.0.0/16" # the range of the vpc
+}
+
+generate "provider" {
+  path      = "provider.tf"
+  if_exists = "overwrite"
+  contents  = <<PROVIDER
+provider "aws" {
+  region = "eu-west-1"
+}
+PROVIDER
+}
+
+dependency "network" {
+  config_path = "../network"
+  mock_outputs = {
+    zones = ["a"]
+  }
+}
+skip = false
+
+include "root" {
+  path = find_in_parent_folders()
+}
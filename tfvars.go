@@ -0,0 +1,151 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ParseTfvars parses the given tfvars content, in the HCL or JSON syntax, and returns the variables it sets as a cty
+// object. As in terraform, the values must be constants: they can not reference variables or call functions.
+func ParseTfvars(content []byte) (cty.Value, error) {
+	return parseTfvars(content, "terraform.tfvars")
+}
+
+// parseTfvars parses the given tfvars content, using filename in diagnostics. JSON content is detected from the
+// leading brace, which can not start a file in the HCL syntax.
+func parseTfvars(content []byte, filename string) (cty.Value, error) {
+	content = normalizeLineEndings(content)
+	parser := hclparse.NewParser()
+
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		file, diags = parser.ParseJSON(content, filename)
+	} else {
+		file, diags = parser.ParseHCL(content, filename)
+	}
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	attributes, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	variables := map[string]cty.Value{}
+	for name, attribute := range attributes {
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return cty.NilVal, diags
+		}
+		variables[name] = value
+	}
+	return cty.ObjectVal(variables), nil
+}
+
+// readTfvarsFileFunc returns the read_tfvars_file(path) function, which reads the given tfvars file and returns its
+// variables encoded as a json string, to be decoded with jsondecode() as in terragrunt.
+func readTfvarsFileFunc(opts *ParseOptions) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path := opts.absolutePath(args[0].AsString())
+			content, err := opts.readFile(path)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			variables, err := parseTfvars(content, path)
+			if err != nil {
+				return cty.NilVal, err
+			}
+
+			jsonBytes, err := ctyjson.Marshal(variables, variables.Type())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(string(jsonBytes)), nil
+		},
+	})
+}
+
+// TfvarsJSON serializes the resolved inputs of the configuration as a terraform.tfvars.json file, so that terraform can
+// be run against the unit without terragrunt. Sensitive values are written as is, and unknown values are an error.
+func TfvarsJSON(config *TerragruntConfig) ([]byte, error) {
+	variables, err := tfvarsInputs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := map[string]ctyjson.SimpleJSONValue{}
+	for name, value := range variables {
+		rendered[name] = ctyjson.SimpleJSONValue{Value: value}
+	}
+	out, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// Tfvars is like TfvarsJSON, but serializes the inputs in the HCL syntax of terraform.tfvars files.
+func Tfvars(config *TerragruntConfig) ([]byte, error) {
+	variables, err := tfvarsInputs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	file := hclwrite.NewEmptyFile()
+	for _, name := range names {
+		file.Body().SetAttributeValue(name, variables[name])
+	}
+	return file.Bytes(), nil
+}
+
+// WriteTfvars writes the resolved inputs of the configuration to the given tfvars file, in the JSON syntax when its
+// name ends with .json (e.g. terraform.tfvars.json) and in the HCL syntax otherwise.
+func WriteTfvars(config *TerragruntConfig, path string) error {
+	render := Tfvars
+	if filepath.Ext(path) == ".json" {
+		render = TfvarsJSON
+	}
+
+	content, err := render(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// tfvarsInputs returns the inputs of the configuration as plain values to write to a tfvars file.
+func tfvarsInputs(config *TerragruntConfig) (map[string]cty.Value, error) {
+	variables := map[string]cty.Value{}
+	for name, value := range config.InputsCty {
+		value, _ = value.UnmarkDeep()
+		if !value.IsWhollyKnown() {
+			return nil, fmt.Errorf("input %s is not known until apply, and can not be written to a tfvars file", name)
+		}
+		variables[name] = value
+	}
+	return variables, nil
+}
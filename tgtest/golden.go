@@ -0,0 +1,61 @@
+package tgtest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	terragrunt "terragrunt-utils"
+)
+
+// update is set by the -tgtest.update flag, or the TGTEST_UPDATE environment variable, to write the golden files
+// instead of comparing with them. The flag is only defined in the test binaries of the packages importing tgtest, and
+// the variable works with go test ./... across packages.
+var update = flag.Bool("tgtest.update", os.Getenv("TGTEST_UPDATE") != "", "update the golden files of tgtest")
+
+// AssertGolden compares the given content with the golden file at the given path, and fails the test with the diff
+// when they differ. The golden file is written instead with -tgtest.update.
+func AssertGolden(t testing.TB, path string, content []byte) {
+	t.Helper()
+	err := terragrunt.CompareGolden(path, content, *update)
+	var mismatch *terragrunt.GoldenMismatchError
+	switch {
+	case err == nil:
+	case errors.As(err, &mismatch):
+		t.Errorf("%s\nrun the tests with -tgtest.update to update the golden file", err)
+	case errors.Is(err, fs.ErrNotExist):
+		t.Errorf("golden file %s does not exist, run the tests with -tgtest.update to create it", path)
+	default:
+		t.Errorf("golden file %s: %s", path, err)
+	}
+}
+
+// AssertGoldenUnit renders the given unit with terragrunt.RenderUnitJSON, and compares it with the golden file at the
+// given path.
+func AssertGoldenUnit(t testing.TB, path string, unit *terragrunt.Unit) {
+	t.Helper()
+	content, err := terragrunt.RenderUnitJSON(unit)
+	if err != nil {
+		t.Errorf("rendering unit %s: %s", unit.Path, err)
+		return
+	}
+	AssertGolden(t, path, content)
+}
+
+// AssertGoldenStack compares every unit of the given stack with its golden file under the given directory, at the
+// path of the unit relative to the root of the stack with a .json extension, e.g. testdata/golden/prod/vpc.json. With
+// -tgtest.update, the golden files of the units no longer in the stack are left in place.
+func AssertGoldenStack(t testing.TB, dir string, stack *terragrunt.Stack) {
+	t.Helper()
+	for _, unit := range stack.Units {
+		relPath, err := filepath.Rel(stack.Root, unit.Path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			relPath = filepath.Base(unit.Path)
+		}
+		AssertGoldenUnit(t, filepath.Join(dir, relPath+".json"), unit)
+	}
+}
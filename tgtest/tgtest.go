@@ -0,0 +1,355 @@
+// Package tgtest builds terragrunt stacks for tests: units are described with a fluent builder, and written to a
+// temporary directory or to an fs.FS, to be parsed with the terragrunt-utils package.
+//
+//	dir := tgtest.Stack(
+//		tgtest.Unit("vpc").WithInput("cidr", "10.0.0.0/16"),
+//		tgtest.Unit("app").WithDependency(tgtest.Dependency("vpc", "vpc").WithMockOutput("vpc_id", "vpc-123")),
+//	).TempDir(t)
+//
+//	stack, err := terragrunt.ParseStack(dir)
+package tgtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// configFilename is the name of the configuration file of the units.
+const configFilename = "terragrunt.hcl"
+
+// Expr is an HCL expression, written as is, e.g. tgtest.Expr("local.env") or
+// tgtest.Expr(`find_in_parent_folders("root.hcl")`). The other values given to the builders are written as literals.
+type Expr string
+
+// attribute is an attribute of a builder, in declaration order.
+type attribute struct {
+	name  string
+	value interface{}
+}
+
+// UnitBuilder describes a unit, at a path of its stack. The values of its attributes are Go values, which are
+// converted as encoding/json converts them, cty values, or Expr.
+type UnitBuilder struct {
+	path         string
+	source       interface{}
+	includes     []attribute
+	locals       []attribute
+	dependencies []*DependencyBuilder
+	inputs       []attribute
+	hcl          []string
+	files        map[string]string
+}
+
+// Unit returns the builder of the unit at the given path of its stack, with forward slashes, e.g. "prod/vpc".
+func Unit(path string) *UnitBuilder {
+	return &UnitBuilder{path: path, files: map[string]string{}}
+}
+
+// Path returns the path of the unit in its stack.
+func (unit *UnitBuilder) Path() string {
+	return unit.path
+}
+
+// WithSource sets the source of the terraform block of the unit.
+func (unit *UnitBuilder) WithSource(source interface{}) *UnitBuilder {
+	unit.source = source
+	return unit
+}
+
+// WithInclude adds an include block with the given name and path, e.g. tgtest.Expr(`find_in_parent_folders("root.hcl")`).
+func (unit *UnitBuilder) WithInclude(name string, path interface{}) *UnitBuilder {
+	unit.includes = append(unit.includes, attribute{name: name, value: path})
+	return unit
+}
+
+// WithLocal adds a local to the locals block of the unit.
+func (unit *UnitBuilder) WithLocal(name string, value interface{}) *UnitBuilder {
+	unit.locals = append(unit.locals, attribute{name: name, value: value})
+	return unit
+}
+
+// WithInput adds an input to the inputs of the unit.
+func (unit *UnitBuilder) WithInput(name string, value interface{}) *UnitBuilder {
+	unit.inputs = append(unit.inputs, attribute{name: name, value: value})
+	return unit
+}
+
+// WithDependency adds a dependency block to the unit.
+func (unit *UnitBuilder) WithDependency(dependency *DependencyBuilder) *UnitBuilder {
+	unit.dependencies = append(unit.dependencies, dependency)
+	return unit
+}
+
+// WithHCL appends the given content to the configuration of the unit, for the blocks and attributes the builder does
+// not cover.
+func (unit *UnitBuilder) WithHCL(content string) *UnitBuilder {
+	unit.hcl = append(unit.hcl, content)
+	return unit
+}
+
+// WithFile adds a file to the directory of the unit, at the given path relative to it, e.g. a module or a tfvars file.
+func (unit *UnitBuilder) WithFile(name string, content string) *UnitBuilder {
+	unit.files[name] = content
+	return unit
+}
+
+// HCL returns the configuration of the unit. The config_path of its dependency blocks are relative to its directory.
+func (unit *UnitBuilder) HCL() ([]byte, error) {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	separate := func() {
+		if len(body.Attributes()) > 0 || len(body.Blocks()) > 0 {
+			body.AppendNewline()
+		}
+	}
+
+	for _, include := range unit.includes {
+		separate()
+		tokens, err := valueTokens(include.value)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", include.name, err)
+		}
+		body.AppendNewBlock("include", []string{include.name}).Body().SetAttributeRaw("path", tokens)
+	}
+
+	if len(unit.locals) > 0 {
+		separate()
+		locals := body.AppendNewBlock("locals", nil).Body()
+		for _, local := range unit.locals {
+			tokens, err := valueTokens(local.value)
+			if err != nil {
+				return nil, fmt.Errorf("local %q: %w", local.name, err)
+			}
+			locals.SetAttributeRaw(local.name, tokens)
+		}
+	}
+
+	if unit.source != nil {
+		separate()
+		tokens, err := valueTokens(unit.source)
+		if err != nil {
+			return nil, fmt.Errorf("source: %w", err)
+		}
+		body.AppendNewBlock("terraform", nil).Body().SetAttributeRaw("source", tokens)
+	}
+
+	for _, dependency := range unit.dependencies {
+		separate()
+		if err := dependency.write(body, unit.path); err != nil {
+			return nil, fmt.Errorf("dependency %q: %w", dependency.name, err)
+		}
+	}
+
+	if len(unit.inputs) > 0 {
+		separate()
+		tokens, err := objectTokens(unit.inputs)
+		if err != nil {
+			return nil, fmt.Errorf("inputs: %w", err)
+		}
+		body.SetAttributeRaw("inputs", tokens)
+	}
+
+	content := file.Bytes()
+	for _, hcl := range unit.hcl {
+		if len(content) > 0 {
+			content = append(content, '\n')
+		}
+		content = append(content, hcl...)
+		if len(hcl) > 0 && hcl[len(hcl)-1] != '\n' {
+			content = append(content, '\n')
+		}
+	}
+	return hclwrite.Format(content), nil
+}
+
+// DependencyBuilder describes a dependency block.
+type DependencyBuilder struct {
+	name        string
+	unit        string
+	mockOutputs []attribute
+	skipOutputs bool
+}
+
+// Dependency returns the builder of the dependency block with the given name, on the unit at the given path of the
+// stack.
+func Dependency(name string, unit string) *DependencyBuilder {
+	return &DependencyBuilder{name: name, unit: unit}
+}
+
+// WithMockOutput adds an output to the mock_outputs of the dependency.
+func (dependency *DependencyBuilder) WithMockOutput(name string, value interface{}) *DependencyBuilder {
+	dependency.mockOutputs = append(dependency.mockOutputs, attribute{name: name, value: value})
+	return dependency
+}
+
+// WithSkipOutputs sets skip_outputs, so that only the mock outputs are used.
+func (dependency *DependencyBuilder) WithSkipOutputs() *DependencyBuilder {
+	dependency.skipOutputs = true
+	return dependency
+}
+
+// write appends the dependency block to the given body of the configuration of the unit at the given path.
+func (dependency *DependencyBuilder) write(body *hclwrite.Body, unitPath string) error {
+	configPath, err := filepath.Rel(filepath.FromSlash(unitPath), filepath.FromSlash(dependency.unit))
+	if err != nil {
+		return err
+	}
+
+	block := body.AppendNewBlock("dependency", []string{dependency.name}).Body()
+	block.SetAttributeValue("config_path", cty.StringVal(filepath.ToSlash(configPath)))
+	if dependency.skipOutputs {
+		block.SetAttributeValue("skip_outputs", cty.True)
+	}
+	if len(dependency.mockOutputs) > 0 {
+		tokens, err := objectTokens(dependency.mockOutputs)
+		if err != nil {
+			return fmt.Errorf("mock_outputs: %w", err)
+		}
+		block.SetAttributeRaw("mock_outputs", tokens)
+	}
+	return nil
+}
+
+// StackBuilder describes a stack: its units, and the other files under its root, such as the configurations they
+// include.
+type StackBuilder struct {
+	units []*UnitBuilder
+	files map[string]string
+}
+
+// Stack returns the builder of the stack with the given units.
+func Stack(units ...*UnitBuilder) *StackBuilder {
+	return &StackBuilder{units: units, files: map[string]string{}}
+}
+
+// WithUnit adds units to the stack.
+func (stack *StackBuilder) WithUnit(units ...*UnitBuilder) *StackBuilder {
+	stack.units = append(stack.units, units...)
+	return stack
+}
+
+// WithFile adds a file to the stack, at the given path relative to its root, with forward slashes, e.g. "root.hcl".
+func (stack *StackBuilder) WithFile(path string, content string) *StackBuilder {
+	stack.files[path] = content
+	return stack
+}
+
+// Files returns the content of the files of the stack, keyed by their path relative to its root, with forward slashes.
+func (stack *StackBuilder) Files() (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for name, content := range stack.files {
+		files[path.Clean(name)] = []byte(content)
+	}
+	for _, unit := range stack.units {
+		content, err := unit.HCL()
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %w", unit.path, err)
+		}
+		files[path.Join(unit.path, configFilename)] = content
+		for name, content := range unit.files {
+			files[path.Join(unit.path, name)] = []byte(content)
+		}
+	}
+	return files, nil
+}
+
+// FS returns the files of the stack as an fs.FS, to be parsed with terragrunt.WithFS. Its root is the root of the
+// stack, so that the unit at "vpc" is parsed as /vpc/terragrunt.hcl.
+func (stack *StackBuilder) FS() (fstest.MapFS, error) {
+	files, err := stack.Files()
+	if err != nil {
+		return nil, err
+	}
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: content, Mode: 0o644}
+	}
+	return fsys, nil
+}
+
+// WriteDir writes the files of the stack under the given directory.
+func (stack *StackBuilder) WriteDir(dir string) error {
+	files, err := stack.Files()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TempDir writes the files of the stack in a temporary directory, removed when the test completes, and returns its
+// path. The test fails when the stack can not be written.
+func (stack *StackBuilder) TempDir(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := stack.WriteDir(dir); err != nil {
+		t.Fatalf("writing the stack: %s", err)
+	}
+	return dir
+}
+
+// objectTokens returns the tokens of the object literal with the given attributes.
+func objectTokens(attributes []attribute) (hclwrite.Tokens, error) {
+	items := make([]hclwrite.ObjectAttrTokens, 0, len(attributes))
+	for _, attribute := range attributes {
+		name := hclwrite.TokensForValue(cty.StringVal(attribute.name))
+		if hclsyntax.ValidIdentifier(attribute.name) {
+			name = hclwrite.TokensForIdentifier(attribute.name)
+		}
+		value, err := valueTokens(attribute.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", attribute.name, err)
+		}
+		items = append(items, hclwrite.ObjectAttrTokens{Name: name, Value: value})
+	}
+	return hclwrite.TokensForObject(items), nil
+}
+
+// valueTokens returns the tokens of the given value: an Expr as is, and the other values as literals.
+func valueTokens(value interface{}) (hclwrite.Tokens, error) {
+	switch value := value.(type) {
+	case Expr:
+		return hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(value)}}, nil
+	case cty.Value:
+		return hclwrite.TokensForValue(value), nil
+	}
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	valueType, err := ctyjson.ImpliedType(valueJSON)
+	if err != nil {
+		return nil, err
+	}
+	ctyValue, err := ctyjson.Unmarshal(valueJSON, valueType)
+	if err != nil {
+		return nil, err
+	}
+	return hclwrite.TokensForValue(ctyValue), nil
+}
@@ -0,0 +1,78 @@
+package terragrunt
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// UnknownBlock is a block of a type this package does not model, e.g. one introduced by a newer terragrunt release.
+type UnknownBlock struct {
+	Type   string
+	Labels []string
+
+	// Body is the raw body of the block. It can be decoded with the evaluation context returned by
+	// CreateTerragruntEvalContext.
+	Body hcl.Body
+
+	// Range is the range of the block header, i.e. its type and labels.
+	Range hcl.Range
+}
+
+// knownBlockTypes returns the types of the blocks decoded by ParseConfig.
+func knownBlockTypes() map[string]bool {
+	schema, _ := gohcl.ImpliedBodySchema(TerragruntConfigFile{})
+	known := map[string]bool{"locals": true}
+	for _, block := range schema.Blocks {
+		known[block.Type] = true
+	}
+	return known
+}
+
+// extractUnknownBlocks returns the blocks of the given file body whose type is unknown, along with the remaining
+// body, which holds everything but these blocks and can be decoded as a TerragruntConfigFile.
+func extractUnknownBlocks(fileBody hcl.Body, remain hcl.Body) ([]UnknownBlock, hcl.Body, error) {
+	syntaxBody, isSyntaxBody := fileBody.(*hclsyntax.Body)
+	if !isSyntaxBody {
+		return nil, remain, nil
+	}
+
+	known := knownBlockTypes()
+	schema := &hcl.BodySchema{}
+	declared := map[string]bool{}
+	for _, block := range syntaxBody.Blocks {
+		if known[block.Type] || declared[block.Type] {
+			continue
+		}
+		declared[block.Type] = true
+		schema.Blocks = append(schema.Blocks, hcl.BlockHeaderSchema{Type: block.Type, LabelNames: labelNames(len(block.Labels))})
+	}
+	if len(schema.Blocks) == 0 {
+		return nil, remain, nil
+	}
+
+	content, remain, diags := remain.PartialContent(schema)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+
+	unknownBlocks := make([]UnknownBlock, 0, len(content.Blocks))
+	for _, block := range content.Blocks {
+		unknownBlocks = append(unknownBlocks, UnknownBlock{
+			Type:   block.Type,
+			Labels: block.Labels,
+			Body:   block.Body,
+			Range:  block.DefRange,
+		})
+	}
+	return unknownBlocks, remain, nil
+}
+
+// labelNames returns placeholder names for the given number of block labels.
+func labelNames(count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = "label"
+	}
+	return names
+}
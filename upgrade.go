@@ -0,0 +1,434 @@
+package terragrunt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// The kinds of deprecated constructs reported by Upgrade.
+const (
+	UpgradeBareInclude               = "bare-include"
+	UpgradeDeprecatedFunction        = "deprecated-function"
+	UpgradeMockOutputsMergeWithState = "mock-outputs-merge-with-state"
+	UpgradeSkip                      = "skip"
+	UpgradeRetryAttributes           = "retry-attributes"
+	UpgradeRootTerragruntHCL         = "root-terragrunt-hcl"
+)
+
+// UpgradeNote describes a deprecated construct found by Upgrade.
+type UpgradeNote struct {
+	// Kind is the kind of the construct, one of the Upgrade constants.
+	Kind    string
+	Message string
+
+	// Range is the range of the construct in the original content.
+	Range hcl.Range
+
+	// Manual is set when the construct could not be rewritten mechanically, and must be upgraded by hand.
+	Manual bool
+}
+
+// UpgradeResult is the result of Upgrade.
+type UpgradeResult struct {
+	// Content is the upgraded content. It is the original content when nothing was rewritten.
+	Content []byte
+
+	// Diff is the unified diff between the original and the upgraded content, or empty when nothing was rewritten.
+	Diff string
+
+	// Notes describe the deprecated constructs found, sorted by position.
+	Notes []UpgradeNote
+}
+
+// deprecatedFunctions maps the deprecated functions to the functions replacing them.
+var deprecatedFunctions = map[string]string{
+	"get_tfvars_dir":        "get_terragrunt_dir",
+	"get_parent_tfvars_dir": "get_parent_terragrunt_dir",
+}
+
+// Upgrade rewrites the deprecated constructs of the given terragrunt configuration content through hclwrite, so that
+// its comments are preserved, and formats it as hclwrite does:
+//   - a bare include block is labeled root, and the include.<attribute> references become include.root.<attribute>.
+//   - the deprecated functions are renamed, e.g. get_tfvars_dir() to get_terragrunt_dir().
+//   - mock_outputs_merge_with_state becomes mock_outputs_merge_strategy_with_state, shallow or no_merge.
+//   - the skip attribute becomes an exclude block excluding the unit from all actions.
+//   - the retryable_errors, retry_max_attempts and retry_sleep_interval_sec attributes become an errors block.
+//
+// Constructs that can not be rewritten mechanically (e.g. a non-literal mock_outputs_merge_with_state, or an include of
+// the root terragrunt.hcl with find_in_parent_folders()) are reported as manual notes.
+func Upgrade(content []byte) (*UpgradeResult, error) {
+	syntaxFile, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	writeFile, diags := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := syntaxFile.Body.(*hclsyntax.Body)
+
+	var notes []UpgradeNote
+	notes = append(notes, upgradeFunctions(body, writeFile.Body())...)
+	notes = append(notes, upgradeBareInclude(body, writeFile.Body())...)
+	notes = append(notes, upgradeMockOutputsMergeWithState(body, writeFile.Body())...)
+	notes = append(notes, upgradeSkip(body, writeFile.Body())...)
+	notes = append(notes, upgradeRetryAttributes(body, writeFile.Body())...)
+	sortUpgradeNotes(notes)
+
+	result := &UpgradeResult{Content: content, Notes: notes}
+	if upgraded := writeFile.Bytes(); string(upgraded) != string(content) {
+		result.Content = upgraded
+		result.Diff = unifiedDiff("a/"+DefaultConfigFilename, "b/"+DefaultConfigFilename, string(content), string(upgraded))
+	}
+	return result, nil
+}
+
+// upgradeFunctions renames the calls of the deprecated functions, and reports the includes of the root configuration
+// with find_in_parent_folders() without arguments, which looks it up as terragrunt.hcl.
+func upgradeFunctions(body *hclsyntax.Body, writeBody *hclwrite.Body) []UpgradeNote {
+	var notes []UpgradeNote
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := node.(*hclsyntax.FunctionCallExpr)
+		if !ok {
+			return nil
+		}
+		if replacement, deprecated := deprecatedFunctions[call.Name]; deprecated {
+			notes = append(notes, UpgradeNote{
+				Kind:    UpgradeDeprecatedFunction,
+				Message: fmt.Sprintf("renamed %s() to %s()", call.Name, replacement),
+				Range:   call.NameRange,
+			})
+		}
+		if call.Name == "find_in_parent_folders" && len(call.Args) == 0 {
+			notes = append(notes, UpgradeNote{
+				Kind:    UpgradeRootTerragruntHCL,
+				Message: `find_in_parent_folders() looks up the root configuration as terragrunt.hcl: rename it to root.hcl and use find_in_parent_folders("root.hcl")`,
+				Range:   call.NameRange,
+				Manual:  true,
+			})
+		}
+		return nil
+	})
+
+	tokens := writeBody.BuildTokens(nil)
+	for i, token := range tokens {
+		replacement, deprecated := deprecatedFunctions[string(token.Bytes)]
+		if token.Type == hclsyntax.TokenIdent && deprecated && i+1 < len(tokens) && tokens[i+1].Type == hclsyntax.TokenOParen {
+			token.Bytes = []byte(replacement)
+		}
+	}
+	return notes
+}
+
+// upgradeBareInclude labels the bare include block root, and rewrites the references to it.
+func upgradeBareInclude(body *hclsyntax.Body, writeBody *hclwrite.Body) []UpgradeNote {
+	var bareInclude *hclsyntax.Block
+	for _, block := range body.Blocks {
+		if block.Type != "include" {
+			continue
+		}
+		if len(block.Labels) > 0 {
+			// Labeled and bare include blocks can not be mixed, which terragrunt reports.
+			return nil
+		}
+		bareInclude = block
+	}
+	if bareInclude == nil {
+		return nil
+	}
+
+	for _, block := range writeBody.Blocks() {
+		if block.Type() == "include" && len(block.Labels()) == 0 {
+			block.SetLabels([]string{"root"})
+		}
+	}
+	renameVariablePrefix(writeBody, "include", "include.root")
+	return []UpgradeNote{{
+		Kind:    UpgradeBareInclude,
+		Message: `labeled the bare include block "root", and rewrote its references as include.root`,
+		Range:   bareInclude.DefRange(),
+	}}
+}
+
+// renameVariablePrefix renames the root of the traversals of every expression of the body starting with the given
+// variable, including the ones of its nested blocks. Expression.RenameVariablePrefix can not be used as it only renames
+// traversals into traversals of the same length.
+func renameVariablePrefix(body *hclwrite.Body, search string, replacement string) {
+	tokens := body.BuildTokens(nil)
+	for i, token := range tokens {
+		if token.Type != hclsyntax.TokenIdent || string(token.Bytes) != search || i+1 == len(tokens) {
+			continue
+		}
+		if next := tokens[i+1].Type; next != hclsyntax.TokenDot && next != hclsyntax.TokenOBrack {
+			continue
+		}
+		if i > 0 && tokens[i-1].Type == hclsyntax.TokenDot {
+			continue
+		}
+		token.Bytes = []byte(replacement)
+	}
+}
+
+// upgradeMockOutputsMergeWithState replaces the mock_outputs_merge_with_state attributes of the dependency blocks
+// with mock_outputs_merge_strategy_with_state.
+func upgradeMockOutputsMergeWithState(body *hclsyntax.Body, writeBody *hclwrite.Body) []UpgradeNote {
+	const deprecated, replacement = "mock_outputs_merge_with_state", "mock_outputs_merge_strategy_with_state"
+
+	var notes []UpgradeNote
+	for _, block := range body.Blocks {
+		attribute, found := block.Body.Attributes[deprecated]
+		if block.Type != "dependency" || !found {
+			continue
+		}
+		writeBlock := writeBody.FirstMatchingBlock(block.Type, block.Labels)
+		if writeBlock == nil {
+			continue
+		}
+
+		if _, found := block.Body.Attributes[replacement]; found {
+			writeBlock.Body().RemoveAttribute(deprecated)
+			notes = append(notes, UpgradeNote{
+				Kind:    UpgradeMockOutputsMergeWithState,
+				Message: fmt.Sprintf("removed %s, overridden by %s", deprecated, replacement),
+				Range:   attribute.SrcRange,
+			})
+			continue
+		}
+
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() || value.Type() != cty.Bool || !value.IsKnown() || value.IsNull() {
+			notes = append(notes, UpgradeNote{
+				Kind:    UpgradeMockOutputsMergeWithState,
+				Message: fmt.Sprintf("replace %s with %s = \"shallow\" or \"no_merge\"", deprecated, replacement),
+				Range:   attribute.SrcRange,
+				Manual:  true,
+			})
+			continue
+		}
+		strategy := "no_merge"
+		if value.True() {
+			strategy = "shallow"
+		}
+
+		// The attribute is renamed in place, so that it keeps its position and comments.
+		for _, token := range writeBlock.Body().GetAttribute(deprecated).BuildTokens(nil) {
+			if token.Type == hclsyntax.TokenIdent && string(token.Bytes) == deprecated {
+				token.Bytes = []byte(replacement)
+				break
+			}
+		}
+		writeBlock.Body().SetAttributeValue(replacement, cty.StringVal(strategy))
+		notes = append(notes, UpgradeNote{
+			Kind:    UpgradeMockOutputsMergeWithState,
+			Message: fmt.Sprintf("replaced %s = %t with %s = %q", deprecated, value.True(), replacement, strategy),
+			Range:   attribute.SrcRange,
+		})
+	}
+	return notes
+}
+
+// upgradeSkip replaces the skip attribute with an exclude block excluding the unit from all actions.
+func upgradeSkip(body *hclsyntax.Body, writeBody *hclwrite.Body) []UpgradeNote {
+	attribute, found := body.Attributes["skip"]
+	if !found {
+		return nil
+	}
+	for _, block := range body.Blocks {
+		if block.Type == "exclude" {
+			return []UpgradeNote{{
+				Kind:    UpgradeSkip,
+				Message: "merge the skip attribute into the exclude block",
+				Range:   attribute.SrcRange,
+				Manual:  true,
+			}}
+		}
+	}
+
+	condition := writeBody.GetAttribute("skip").Expr().BuildTokens(nil)
+	writeBody.RemoveAttribute("skip")
+	writeBody.AppendNewline()
+	exclude := writeBody.AppendNewBlock("exclude", nil).Body()
+	exclude.SetAttributeRaw("if", condition)
+	exclude.SetAttributeValue("actions", cty.ListVal([]cty.Value{cty.StringVal(ExcludeAllActions)}))
+	return []UpgradeNote{{
+		Kind:    UpgradeSkip,
+		Message: `replaced the skip attribute with an exclude block excluding the unit from all actions`,
+		Range:   attribute.SrcRange,
+	}}
+}
+
+// upgradeRetryAttributes replaces the retry attributes with a retry block of an errors block, with the default
+// attempts and sleep interval of terragrunt when they are not set.
+func upgradeRetryAttributes(body *hclsyntax.Body, writeBody *hclwrite.Body) []UpgradeNote {
+	names := []string{"retryable_errors", "retry_max_attempts", "retry_sleep_interval_sec"}
+	var attributes []*hclsyntax.Attribute
+	for _, name := range names {
+		if attribute, found := body.Attributes[name]; found {
+			attributes = append(attributes, attribute)
+		}
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	hasErrorsBlock := false
+	for _, block := range body.Blocks {
+		hasErrorsBlock = hasErrorsBlock || block.Type == "errors"
+	}
+	if _, found := body.Attributes["retryable_errors"]; !found || hasErrorsBlock {
+		return []UpgradeNote{{
+			Kind:    UpgradeRetryAttributes,
+			Message: "replace the retry attributes with a retry block of the errors block",
+			Range:   attributes[0].SrcRange,
+			Manual:  true,
+		}}
+	}
+
+	expressions := map[string]hclwrite.Tokens{}
+	for _, name := range names {
+		if attribute := writeBody.GetAttribute(name); attribute != nil {
+			expressions[name] = attribute.Expr().BuildTokens(nil)
+			writeBody.RemoveAttribute(name)
+		}
+	}
+	writeBody.AppendNewline()
+	retry := writeBody.AppendNewBlock("errors", nil).Body().AppendNewBlock("retry", []string{"default"}).Body()
+	retry.SetAttributeRaw("retryable_errors", expressions["retryable_errors"])
+	if tokens, found := expressions["retry_max_attempts"]; found {
+		retry.SetAttributeRaw("max_attempts", tokens)
+	} else {
+		retry.SetAttributeValue("max_attempts", cty.NumberIntVal(3))
+	}
+	if tokens, found := expressions["retry_sleep_interval_sec"]; found {
+		retry.SetAttributeRaw("sleep_interval_sec", tokens)
+	} else {
+		retry.SetAttributeValue("sleep_interval_sec", cty.NumberIntVal(5))
+	}
+	return []UpgradeNote{{
+		Kind:    UpgradeRetryAttributes,
+		Message: `replaced the retry attributes with the retry "default" block of an errors block`,
+		Range:   attributes[0].SrcRange,
+	}}
+}
+
+func sortUpgradeNotes(notes []UpgradeNote) {
+	for i := 1; i < len(notes); i++ {
+		for j := i; j > 0 && notes[j].Range.Start.Byte < notes[j-1].Range.Start.Byte; j-- {
+			notes[j], notes[j-1] = notes[j-1], notes[j]
+		}
+	}
+}
+
+// unifiedDiff returns the unified diff between the given contents, with three lines of context, as diff -u.
+func unifiedDiff(oldName string, newName string, oldContent string, newContent string) string {
+	oldLines := strings.SplitAfter(oldContent, "\n")
+	newLines := strings.SplitAfter(newContent, "\n")
+	if oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+	if newLines[len(newLines)-1] == "" {
+		newLines = newLines[:len(newLines)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:].
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			switch {
+			case oldLines[i] == newLines[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// edits are the lines of both contents, prefixed with ' ', '-' or '+'.
+	type edit struct {
+		kind     byte
+		line     string
+		old, new int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			edits = append(edits, edit{' ', oldLines[i], i, j})
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', oldLines[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', newLines[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		if edits[start].kind == ' ' {
+			start++
+			continue
+		}
+		// A hunk spans the changes separated by at most twice the context, along with their context.
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+		end := start
+		for last := start; last < len(edits); last++ {
+			if edits[last].kind != ' ' {
+				end = last
+			} else if last-end > 2*context {
+				break
+			}
+		}
+		end += context + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		if out.Len() == 0 {
+			out.WriteString("--- " + oldName + "\n+++ " + newName + "\n")
+		}
+		oldCount, newCount := 0, 0
+		for _, e := range edits[first:end] {
+			if e.kind != '+' {
+				oldCount++
+			}
+			if e.kind != '-' {
+				newCount++
+			}
+		}
+		oldStart, newStart := edits[first].old+1, edits[first].new+1
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		out.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount))
+		for _, e := range edits[first:end] {
+			out.WriteByte(e.kind)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = end
+	}
+	return out.String()
+}
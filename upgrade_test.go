@@ -0,0 +1,121 @@
+package terragrunt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpgrade(t *testing.T) {
+	type note struct {
+		Kind   string
+		Manual bool
+	}
+	tests := []struct {
+		name  string
+		src   string
+		want  string
+		notes []note
+	}{
+		{
+			name:  "deprecated functions",
+			src:   "inputs = {\n  # the unit directory\n  dir    = get_tfvars_dir()\n  parent = get_parent_tfvars_dir()\n}\n",
+			want:  "inputs = {\n  # the unit directory\n  dir    = get_terragrunt_dir()\n  parent = get_parent_terragrunt_dir()\n}\n",
+			notes: []note{{Kind: UpgradeDeprecatedFunction}, {Kind: UpgradeDeprecatedFunction}},
+		},
+		{
+			name:  "bare include",
+			src:   "include {\n  path = find_in_parent_folders(\"root.hcl\")\n}\n\ninputs = {\n  env = include.locals.env\n}\n",
+			want:  "include \"root\" {\n  path = find_in_parent_folders(\"root.hcl\")\n}\n\ninputs = {\n  env = include.root.locals.env\n}\n",
+			notes: []note{{Kind: UpgradeBareInclude}},
+		},
+		{
+			name:  "mock_outputs_merge_with_state",
+			src:   "dependency \"vpc\" {\n  config_path                   = \"../vpc\"\n  mock_outputs_merge_with_state = true\n}\n",
+			want:  "dependency \"vpc\" {\n  config_path                            = \"../vpc\"\n  mock_outputs_merge_strategy_with_state = \"shallow\"\n}\n",
+			notes: []note{{Kind: UpgradeMockOutputsMergeWithState}},
+		},
+		{
+			name:  "skip",
+			src:   "inputs = {}\nskip = true\n",
+			want:  "inputs = {}\n\nexclude {\n  if      = true\n  actions = [\"all\"]\n}\n",
+			notes: []note{{Kind: UpgradeSkip}},
+		},
+		{
+			name: "retry attributes",
+			src:  "inputs = {}\nretryable_errors = [\"timeout\"]\nretry_max_attempts = 5\n",
+			want: "inputs = {}\n\nerrors {\n  retry \"default\" {\n    retryable_errors   = [\"timeout\"]\n" +
+				"    max_attempts       = 5\n    sleep_interval_sec = 5\n  }\n}\n",
+			notes: []note{{Kind: UpgradeRetryAttributes}},
+		},
+		{
+			name:  "manual constructs",
+			src:   "include \"root\" {\n  path = find_in_parent_folders()\n}\n\ndependency \"vpc\" {\n  config_path                   = \"../vpc\"\n  mock_outputs_merge_with_state = local.merge\n}\n",
+			notes: []note{{Kind: UpgradeRootTerragruntHCL, Manual: true}, {Kind: UpgradeMockOutputsMergeWithState, Manual: true}},
+		},
+		{
+			name:  "skip with an exclude block",
+			src:   "skip = true\n\nexclude {\n  if      = true\n  actions = [\"plan\"]\n}\n",
+			notes: []note{{Kind: UpgradeSkip, Manual: true}},
+		},
+		{
+			name: "up to date",
+			src:  "include \"root\" {\n  path = find_in_parent_folders(\"root.hcl\")\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Upgrade([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want == "" {
+				if string(result.Content) != test.src || result.Diff != "" {
+					t.Errorf("got content:\n%s\nand diff %q, want the content unchanged", result.Content, result.Diff)
+				}
+			} else {
+				got, err := Format(result.Content)
+				if err != nil {
+					t.Fatalf("the upgraded content is invalid: %v", err)
+				}
+				want, err := Format([]byte(test.want))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != string(want) {
+					t.Errorf("got content:\n%s\nwant:\n%s", got, want)
+				}
+				if !strings.HasPrefix(result.Diff, "--- a/terragrunt.hcl\n+++ b/terragrunt.hcl\n") {
+					t.Errorf("got diff %q, want a unified diff", result.Diff)
+				}
+			}
+
+			var notes []note
+			for _, upgradeNote := range result.Notes {
+				notes = append(notes, note{Kind: upgradeNote.Kind, Manual: upgradeNote.Manual})
+			}
+			if !reflect.DeepEqual(notes, test.notes) {
+				t.Errorf("got notes %+v, want %+v", notes, test.notes)
+			}
+		})
+	}
+}
+
+func TestUpgradeDiff(t *testing.T) {
+	result, err := Upgrade([]byte("locals {\n  dir = get_tfvars_dir()\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a/terragrunt.hcl\n+++ b/terragrunt.hcl\n@@ -1,3 +1,3 @@\n locals {\n-  dir = get_tfvars_dir()\n+  dir = get_terragrunt_dir()\n }\n"
+	if result.Diff != want {
+		t.Errorf("got diff:\n%s\nwant:\n%s", result.Diff, want)
+	}
+}
+
+func TestUpgradeInvalidSyntax(t *testing.T) {
+	if _, err := Upgrade([]byte("inputs = {\n")); err == nil {
+		t.Error("got no error for an invalid configuration")
+	}
+}
@@ -1,26 +1,74 @@
 package terragrunt
 
 import (
-	"encoding/json"
+	"fmt"
+	"math/big"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/zclconf/go-cty/cty"
-	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/function"
 )
 
 // Create an EvalContext for the HCL2 parser. We can define functions and variables in this context that the HCL2 parser
 // will make available to the Terragrunt configuration during parsing.
-func CreateTerragruntEvalContext(extensions EvalContextExtensions) (*hcl.EvalContext, error) {
+func CreateTerragruntEvalContext(opts *ParseOptions, extensions EvalContextExtensions) (*hcl.EvalContext, error) {
 	ctx := &hcl.EvalContext{}
+	if opts.functions == nil {
+		opts.functions = createTerragruntEvalFunctions(opts)
+	}
+	ctx.Functions = opts.functions
 	ctx.Variables = map[string]cty.Value{}
+	for name, value := range opts.Variables {
+		ctx.Variables[name] = value
+	}
 
+	if extensions.Locals != nil {
+		ctx.Variables["local"] = *extensions.Locals
+	}
 	if extensions.DecodedDependencies != nil {
 		ctx.Variables["dependency"] = *extensions.DecodedDependencies
 	}
+	if extensions.FeatureFlags != nil {
+		ctx.Variables["feature"] = *extensions.FeatureFlags
+	}
 
 	return ctx, nil
 }
 
+// createTerragruntEvalFunctions returns the table of functions available to the configuration during evaluation:
+// the terraform compatible standard library and the terragrunt built-ins, along with the custom functions registered
+// through the options, which take precedence.
+func createTerragruntEvalFunctions(opts *ParseOptions) map[string]function.Function {
+	functions := stdlibFunctions()
+	functions["yamldecode"] = yamlDecodeFunc()
+	functions["yamlencode"] = yamlEncodeFunc()
+	functions["sops_decrypt_file"] = sopsDecryptFileFunc(opts)
+	functions["run_cmd"] = runCmdFunc(opts)
+	functions["get_env"] = getEnvFunc(opts)
+	functions["timestamp"] = timestampFunc(opts)
+	functions["uuid"] = uuidFunc(opts)
+	functions["find_in_parent_folders"] = findInParentFoldersFunc(opts)
+	functions["read_tfvars_file"] = readTfvarsFileFunc(opts)
+	functions["file"] = fileFunc(opts)
+	functions["fileexists"] = fileExistsFunc(opts)
+	functions["templatefile"] = templateFileFunc(opts, func() map[string]function.Function { return functions })
+	for name, fn := range secretsFunctions(opts) {
+		functions[name] = fn
+	}
+	for name, fn := range pathFunctions(opts) {
+		functions[name] = fn
+	}
+	for name, fn := range awsIdentityFunctions(opts) {
+		functions[name] = fn
+	}
+	applyHermeticMode(functions, opts.HermeticMode)
+
+	for name, fn := range opts.Functions {
+		functions[name] = fn
+	}
+	return functions
+}
+
 // generateTypeFromValuesMap takes a values map and returns an object type that has the same number of fields, but
 // bound to each type of the underlying evaluated expression. This is the only way the HCL decoder will be happy, as
 // object type is the only map type that allows different types for each attribute (cty.Map requires all attributes to
@@ -33,30 +81,71 @@ func generateTypeFromValuesMap(valMap map[string]cty.Value) cty.Type {
 	return cty.Object(outType)
 }
 
-// When you convert a cty value to JSON, if any of that types are not yet known (i.e., are labeled as
-// DynamicPseudoType), cty's Marshall method will write the type information to a type field and the actual value to
-// a value field. This struct is used to capture that information so when we parse the JSON back into a Go struct, we
-// can pull out just the Value field we need.
-type CtyJsonOutput struct {
-	Value map[string]interface{}
-	Type  interface{}
-}
-
-// This is a hacky workaround to convert a cty Value to a Go map[string]interface{}. cty does not support this directly
-// (https://github.com/hashicorp/hcl2/issues/108) and doing it with gocty.FromCtyValue is nearly impossible, as cty
-// requires you to specify all the output types and will error out when it hits interface{}. So, as an ugly workaround,
-// we convert the given value to JSON using cty's JSON library and then convert the JSON back to a
-// map[string]interface{} using the Go json library.
+// parseCtyValueToMap converts an object or map cty Value to a Go map[string]interface{}, walking the value directly
+// rather than going through JSON, so that numbers keep their precision. See ctyValueToInterface for how each nested
+// value is converted. A null value results in a nil map.
 func parseCtyValueToMap(value cty.Value) (map[string]interface{}, error) {
-	jsonBytes, err := ctyjson.Marshal(value, cty.DynamicPseudoType)
+	converted, err := ctyValueToInterface(value)
 	if err != nil {
 		return nil, err
 	}
+	if converted == nil {
+		return nil, nil
+	}
 
-	var ctyJsonOutput CtyJsonOutput
-	if err := json.Unmarshal(jsonBytes, &ctyJsonOutput); err != nil {
-		return nil, err
+	valueMap, isMap := converted.(map[string]interface{})
+	if !isMap {
+		return nil, fmt.Errorf("expected an object or map value, got %s", value.Type().FriendlyName())
+	}
+	return valueMap, nil
+}
+
+// ctyValueToInterface converts the given cty Value to the equivalent Go value:
+// - null and unknown values are converted to nil.
+// - strings and bools are converted to string and bool.
+// - numbers are converted to float64 when they can be represented exactly, and to *big.Float otherwise.
+// - objects and maps are converted to map[string]interface{}.
+// - tuples, lists and sets are converted to []interface{}.
+// Marks (e.g. sensitive) can not be represented in Go values, so marked values are converted as if they were unmarked.
+func ctyValueToInterface(value cty.Value) (interface{}, error) {
+	value, _ = value.Unmark()
+	if value.IsNull() || !value.IsKnown() {
+		return nil, nil
+	}
+
+	valueType := value.Type()
+	switch {
+	case valueType == cty.String:
+		return value.AsString(), nil
+	case valueType == cty.Bool:
+		return value.True(), nil
+	case valueType == cty.Number:
+		bigFloat := value.AsBigFloat()
+		if float, accuracy := bigFloat.Float64(); accuracy == big.Exact {
+			return float, nil
+		}
+		return bigFloat, nil
+	case valueType.IsObjectType() || valueType.IsMapType():
+		valueMap := map[string]interface{}{}
+		for key, element := range value.AsValueMap() {
+			converted, err := ctyValueToInterface(element)
+			if err != nil {
+				return nil, err
+			}
+			valueMap[key] = converted
+		}
+		return valueMap, nil
+	case valueType.IsTupleType() || valueType.IsListType() || valueType.IsSetType():
+		valueList := []interface{}{}
+		for _, element := range value.AsValueSlice() {
+			converted, err := ctyValueToInterface(element)
+			if err != nil {
+				return nil, err
+			}
+			valueList = append(valueList, converted)
+		}
+		return valueList, nil
 	}
 
-	return ctyJsonOutput.Value, nil
+	return nil, fmt.Errorf("unsupported value type %s", valueType.FriendlyName())
 }
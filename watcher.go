@@ -0,0 +1,269 @@
+package terragrunt
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is the delay a Watcher waits for after a change by default, before parsing the units again.
+const DefaultWatchDebounce = 100 * time.Millisecond
+
+// WatchEvent reports a unit of a watched stack that was added, removed or changed.
+type WatchEvent struct {
+	Kind ChangeKind
+
+	// Path is the absolute path of the unit directory.
+	Path string
+
+	// Unit is the unit as parsed after the change, and Previous the unit before it. Unit is nil for removed units,
+	// and Previous for added ones.
+	Unit     *Unit
+	Previous *Unit
+
+	// Changes are the changes of the resolved configuration of changed units, when it could be parsed both before
+	// and after the change.
+	Changes []Change
+}
+
+// Watcher keeps the units of a stack up to date as their files change: only the units whose configuration, or one of
+// the files it depends on (such as included parents or files read with file()), changed are parsed again. Changes are
+// watched with fsnotify, on the directories of the stack and the directories of the files the units depend on, from
+// the creation of the Watcher until it is closed.
+type Watcher struct {
+	// Debounce is the delay Run waits for after a change before parsing the units again, so that the changes made at
+	// once (e.g. by a git checkout) are handled together. Defaults to DefaultWatchDebounce.
+	Debounce time.Duration
+
+	root     string
+	opts     []Option
+	notifier *fsnotify.Watcher
+
+	mutex sync.Mutex
+	units map[string]*Unit
+}
+
+// NewWatcher parses the stack under the given root directory with the given options, and returns a Watcher to keep
+// it up to date. The Watcher must be closed once done.
+func NewWatcher(root string, opts ...Option) (*Watcher, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &Watcher{
+		root:     absRoot,
+		opts:     opts,
+		notifier: notifier,
+		units:    map[string]*Unit{},
+	}
+	// The directories are watched before the units are parsed, so that no change made meanwhile is missed.
+	if err := watcher.watch(); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	if _, err := watcher.update(nil); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	if err := watcher.watch(); err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Close stops watching the files of the stack.
+func (watcher *Watcher) Close() error {
+	return watcher.notifier.Close()
+}
+
+// Stack returns the units of the stack as of the last update. The returned stack is not modified by later updates.
+func (watcher *Watcher) Stack() *Stack {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	stack := &Stack{Root: watcher.root}
+	for _, unit := range watcher.units {
+		stack.Units = append(stack.Units, unit)
+	}
+	sort.Slice(stack.Units, func(i, j int) bool {
+		return stack.Units[i].ConfigPath < stack.Units[j].ConfigPath
+	})
+	return stack
+}
+
+// Run waits for the files of the stack to change, parses again the units they change and passes the resulting events
+// to the given function, until the context is done. It returns the error of the context, or the first error watching
+// the files or discovering the units. When the operating system drops change notifications, every unit is parsed
+// again.
+func (watcher *Watcher) Run(ctx context.Context, handle func([]WatchEvent)) error {
+	debounce := watcher.Debounce
+	if debounce == 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	// changed holds the files changed since the last update, or is nil when they are not known.
+	changed := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err, ok := <-watcher.notifier.Errors:
+			if !ok {
+				return errors.New("the watcher is closed")
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return err
+			}
+			changed = nil
+			timer.Reset(debounce)
+
+		case event, ok := <-watcher.notifier.Events:
+			if !ok {
+				return errors.New("the watcher is closed")
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if changed != nil {
+				changed[event.Name] = true
+			}
+			timer.Reset(debounce)
+
+		case <-timer.C:
+			events, err := watcher.update(changed)
+			if err != nil {
+				return err
+			}
+			changed = map[string]bool{}
+			if err := watcher.watch(); err != nil {
+				return err
+			}
+			if len(events) > 0 {
+				handle(events)
+			}
+		}
+	}
+}
+
+// update discovers the units of the stack, parses the new ones and the ones depending on the given changed files, or
+// every unit when they are nil, and returns the resulting events ordered by unit path.
+func (watcher *Watcher) update(changed map[string]bool) ([]WatchEvent, error) {
+	entries, err := discoverUnitEntries(watcher.root, watcher.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	var events []WatchEvent
+	discovered := map[string]bool{}
+	for _, entry := range entries {
+		configPath := entry.configPath
+		discovered[configPath] = true
+
+		previous, found := watcher.units[configPath]
+		if found && changed != nil && !dependsOnAny(previous, changed) {
+			continue
+		}
+
+		unit := entry.parse(watcher.opts)
+		watcher.units[configPath] = unit
+
+		event := WatchEvent{Kind: ChangeAdded, Path: unit.Path, Unit: unit}
+		if found {
+			event.Kind, event.Previous = ChangeChanged, previous
+			if previous.Config != nil && unit.Config != nil {
+				event.Changes = Diff(previous.Config, unit.Config)
+				if len(event.Changes) == 0 {
+					continue
+				}
+			}
+		}
+		events = append(events, event)
+	}
+
+	for configPath, unit := range watcher.units {
+		if !discovered[configPath] {
+			delete(watcher.units, configPath)
+			events = append(events, WatchEvent{Kind: ChangeRemoved, Path: unit.Path, Previous: unit})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events, nil
+}
+
+// dependsOnAny returns whether the given unit depends on any of the given files.
+func dependsOnAny(unit *Unit, files map[string]bool) bool {
+	for _, path := range unit.Files {
+		if files[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// watch adds the directories of the stack that are searched for units, and the directories of the files the units
+// depend on, to the watched directories. The directories that do not exist are skipped, and the ones removed are
+// dropped by fsnotify.
+func (watcher *Watcher) watch() error {
+	dirs := map[string]bool{}
+	err := filepath.WalkDir(watcher.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path != watcher.root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != watcher.root && isSkippedDir(entry.Name()) {
+			return filepath.SkipDir
+		}
+		dirs[path] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	watcher.mutex.Lock()
+	for _, unit := range watcher.units {
+		for _, path := range unit.Files {
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+	watcher.mutex.Unlock()
+
+	for _, dir := range watcher.notifier.WatchList() {
+		delete(dirs, dir)
+	}
+	for dir := range dirs {
+		if err := watcher.notifier.Add(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
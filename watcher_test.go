@@ -0,0 +1,116 @@
+package terragrunt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"root.hcl": `
+inputs = {
+  region = "eu-west-1"
+}
+`,
+		"app/terragrunt.hcl": `
+include "root" {
+  path = find_in_parent_folders("root.hcl")
+}
+`,
+	})
+
+	watcher, err := NewWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watcher.Debounce = 10 * time.Millisecond
+
+	if units := watcher.Stack().Units; len(units) != 1 {
+		t.Fatalf("got %d units, want 1", len(units))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	received := make(chan []WatchEvent)
+	go watcher.Run(ctx, func(events []WatchEvent) {
+		select {
+		case received <- events:
+		case <-ctx.Done():
+		}
+	})
+	next := func() []WatchEvent {
+		t.Helper()
+		select {
+		case events := <-received:
+			return events
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for events")
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		src  string
+		kind ChangeKind
+		unit string
+	}{
+		{
+			name: "included file changed",
+			path: "root.hcl",
+			src: `
+inputs = {
+  region = "us-east-1"
+}
+`,
+			kind: ChangeChanged,
+			unit: "app",
+		},
+		{
+			name: "unit added",
+			path: "db/terragrunt.hcl",
+			src: `
+inputs = {
+  engine = "postgres"
+}
+`,
+			kind: ChangeAdded,
+			unit: "db",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, filepath.FromSlash(test.path))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(test.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			events := next()
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1: %v", len(events), events)
+			}
+			event := events[0]
+			if event.Kind != test.kind || event.Path != filepath.Join(dir, test.unit) {
+				t.Errorf("got %s %s, want %s %s", event.Kind, event.Path, test.kind, filepath.Join(dir, test.unit))
+			}
+		})
+	}
+
+	t.Run("unit removed", func(t *testing.T) {
+		if err := os.RemoveAll(filepath.Join(dir, "db")); err != nil {
+			t.Fatal(err)
+		}
+		events := next()
+		if len(events) != 1 || events[0].Kind != ChangeRemoved || events[0].Path != filepath.Join(dir, "db") {
+			t.Fatalf("got %v, want db removed", events)
+		}
+	})
+}
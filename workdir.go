@@ -0,0 +1,123 @@
+package terragrunt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WorkingDirTfvarsFilename is the name of the file PrepareWorkingDir writes the inputs of a unit to.
+const WorkingDirTfvarsFilename = "terraform.tfvars.json"
+
+// PrepareWorkingDir renders the given unit into the given directory, so that tools running terraform code, such as
+// infracost or tflint, can run against it without terragrunt: the module of the unit is fetched with the given
+// fetcher and copied into the directory, leaving out hidden files and directories such as .terraform, the enabled
+// generate blocks of the unit are written on top of it, as their if_exists attribute allows, and its inputs are
+// written to terraform.tfvars.json. The directory is created when missing. As with terragrunt, only the directory of
+// the module is copied, so modules referencing local files outside of it should be sourced with a // subdirectory.
+func PrepareWorkingDir(ctx context.Context, fetcher *SourceFetcher, unit *Unit, dir string) error {
+	if unit.Config == nil {
+		return fmt.Errorf("%s: %w", unit.ConfigPath, unit.Err)
+	}
+	moduleDir, err := fetcher.FetchUnit(ctx, unit)
+	if err != nil {
+		return err
+	}
+	if err := copyModuleDir(moduleDir, dir); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(unit.Config.GenerateConfigs))
+	for name := range unit.Config.GenerateConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeGeneratedFile(unit.Config.GenerateConfigs[name], dir); err != nil {
+			return fmt.Errorf("generate %q: %w", name, err)
+		}
+	}
+	return WriteTfvars(unit.Config, filepath.Join(dir, WorkingDirTfvarsFilename))
+}
+
+// copyModuleDir copies the files of the module in the given directory to the destination directory, leaving out the
+// hidden files and directories.
+func copyModuleDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if relPath != "." && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, relPath)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies the file at the given path, following symlinks, keeping its permissions.
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeGeneratedFile writes the file of the given generate block into the given directory, unless the block is
+// disabled or its if_exists attribute keeps an existing file.
+func writeGeneratedFile(generate GenerateConfig, dir string) error {
+	if generate.Disable != nil && *generate.Disable {
+		return nil
+	}
+	path := generate.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		switch generate.IfExists {
+		case "skip":
+			return nil
+		case "error":
+			return fmt.Errorf("%s already exists", generate.Path)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(generate.Contents), 0644)
+}
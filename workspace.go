@@ -0,0 +1,37 @@
+package terragrunt
+
+// WorkspaceFunc returns the terraform workspace of the unit with the given config path, or an empty string to use the
+// workspace configured by the unit itself. See TerragruntConfig.Workspace.
+type WorkspaceFunc func(configPath string) string
+
+// DefaultWorkspace is the name of the workspace terraform uses when none is selected.
+const DefaultWorkspace = "default"
+
+// Workspace returns the terraform workspace selected by the configuration: the TF_WORKSPACE environment variable set
+// by the env_vars of its extra_arguments blocks, the last block setting it winning, or else DefaultWorkspace.
+func (config *TerragruntConfig) Workspace() string {
+	workspace := DefaultWorkspace
+	if config == nil || config.Terraform == nil {
+		return workspace
+	}
+	for _, extraArguments := range config.Terraform.ExtraArguments {
+		if extraArguments.EnvVars == nil {
+			continue
+		}
+		if value, found := (*extraArguments.EnvVars)["TF_WORKSPACE"]; found && value != "" {
+			workspace = value
+		}
+	}
+	return workspace
+}
+
+// unitWorkspace returns the workspace of the unit with the given config path and configuration: the one returned by
+// the given function, when set and not empty, or else the one of the configuration.
+func unitWorkspace(workspaceFunc WorkspaceFunc, configPath string, config *TerragruntConfig) string {
+	if workspaceFunc != nil {
+		if workspace := workspaceFunc(configPath); workspace != "" {
+			return workspace
+		}
+	}
+	return config.Workspace()
+}
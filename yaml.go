@@ -0,0 +1,28 @@
+package terragrunt
+
+import (
+	yaml "github.com/zclconf/go-cty-yaml"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// yamlDecodeFunc returns the yamldecode(src) function, which parses a YAML document as terraform does: mappings
+// become objects, sequences become tuples, and scalars are resolved to strings, numbers, bools or null.
+func yamlDecodeFunc() function.Function {
+	return yaml.YAMLDecodeFunc
+}
+
+// yamlEncodeFunc returns the yamlencode(value) function, which encodes a value as a YAML document, in the same block
+// style as terraform.
+func yamlEncodeFunc() function.Function {
+	return yaml.YAMLEncodeFunc
+}
+
+// decodeYAML parses the given YAML document as the yamldecode function does.
+func decodeYAML(src string) (cty.Value, error) {
+	valueType, err := yaml.Standard.ImpliedType([]byte(src))
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return yaml.Standard.Unmarshal([]byte(src), valueType)
+}
@@ -0,0 +1,186 @@
+package terragrunt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want cty.Value
+		err  string
+	}{
+		{
+			name: "scalars",
+			src:  "string: hello\nint: 42\nfloat: 1.5\nbool: true\nnothing: ~\nquoted: \"42\"\nhex: 0x1f\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"string":  cty.StringVal("hello"),
+				"int":     cty.NumberIntVal(42),
+				"float":   cty.NumberFloatVal(1.5),
+				"bool":    cty.True,
+				"nothing": cty.NullVal(cty.DynamicPseudoType),
+				"quoted":  cty.StringVal("42"),
+				"hex":     cty.NumberIntVal(31),
+			}),
+		},
+		{
+			name: "nested collections",
+			src:  "# comment\nvpc:\n  cidr: 10.0.0.0/16 # trailing comment\n  zones:\n    - a\n    - b\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"vpc": cty.ObjectVal(map[string]cty.Value{
+					"cidr":  cty.StringVal("10.0.0.0/16"),
+					"zones": cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+				}),
+			}),
+		},
+		{
+			name: "sequence of mappings",
+			src:  "- name: a\n  port: 80\n- name: b\n",
+			want: cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("a"), "port": cty.NumberIntVal(80)}),
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("b")}),
+			}),
+		},
+		{
+			name: "flow collections",
+			src:  "list: [1, \"two\", {three: 3}]\nempty: {}\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"list": cty.TupleVal([]cty.Value{
+					cty.NumberIntVal(1),
+					cty.StringVal("two"),
+					cty.ObjectVal(map[string]cty.Value{"three": cty.NumberIntVal(3)}),
+				}),
+				"empty": cty.EmptyObjectVal,
+			}),
+		},
+		{
+			name: "block scalars",
+			src:  "literal: |\n  line 1\n  line 2\nfolded: >\n  line 1\n  line 2\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"literal": cty.StringVal("line 1\nline 2\n"),
+				"folded":  cty.StringVal("line 1 line 2\n"),
+			}),
+		},
+		{
+			name: "anchors, aliases and merge keys",
+			src:  "base: &base\n  region: us-east-1\n  env: dev\nprod:\n  <<: *base\n  env: prod\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"base": cty.ObjectVal(map[string]cty.Value{"region": cty.StringVal("us-east-1"), "env": cty.StringVal("dev")}),
+				"prod": cty.ObjectVal(map[string]cty.Value{"region": cty.StringVal("us-east-1"), "env": cty.StringVal("prod")}),
+			}),
+		},
+		{
+			name: "quoted escapes",
+			src:  "double: \"a\\tb\\u00e9\"\nsingle: 'it''s'\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"double": cty.StringVal("a\tbé"),
+				"single": cty.StringVal("it's"),
+			}),
+		},
+		{
+			name: "document markers",
+			src:  "%YAML 1.1\n---\nname: a\n...\n",
+			want: cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("a")}),
+		},
+		{
+			name: "explicit key",
+			src:  "? key\n? other\n: value\n",
+			want: cty.ObjectVal(map[string]cty.Value{
+				"key":   cty.NullVal(cty.DynamicPseudoType),
+				"other": cty.StringVal("value"),
+			}),
+		},
+		{
+			name: "multiple documents",
+			src:  "name: a\n---\nname: b\n",
+			err:  "on line 1, column 1: unexpected extra content after value",
+		},
+		{
+			name: "tab indentation",
+			src:  "vpc:\n\tcidr: 10.0.0.0/16\n",
+			err:  "on line 2, column 1: found character that cannot start any token",
+		},
+		{
+			name: "nested mapping on one line",
+			src:  "a: b: c\n",
+			err:  "mapping values are not allowed in this context",
+		},
+		{
+			name: "collection key",
+			src:  "? [a, b]\n: c\n",
+			err:  "only strings are allowed as mapping keys",
+		},
+		{
+			name: "unsupported YAML version",
+			src:  "%YAML 1.2\n---\nname: a\n",
+			err:  "found incompatible YAML document",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeYAML(test.src)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.RawEquals(test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value cty.Value
+		want  string
+	}{
+		{
+			name:  "scalar",
+			value: cty.StringVal("hello"),
+			want:  "\"hello\"\n",
+		},
+		{
+			name: "mapping",
+			value: cty.ObjectVal(map[string]cty.Value{
+				"count":   cty.NumberIntVal(3),
+				"enabled": cty.True,
+				"name":    cty.StringVal("vpc"),
+				"tags":    cty.EmptyObjectVal,
+				"zones":   cty.TupleVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			}),
+			want: "\"count\": 3\n\"enabled\": true\n\"name\": \"vpc\"\n\"tags\": {}\n\"zones\":\n- \"a\"\n- \"b\"\n",
+		},
+		{
+			name: "sequence of mappings",
+			value: cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("a"), "port": cty.NumberIntVal(80)}),
+				cty.NullVal(cty.String),
+			}),
+			want: "- \"name\": \"a\"\n  \"port\": 80\n- null\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := yamlEncodeFunc().Call([]cty.Value{test.value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AsString() != test.want {
+				t.Errorf("got %q, want %q", got.AsString(), test.want)
+			}
+		})
+	}
+}